@@ -0,0 +1,162 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Inharmonicity returns the ratio of frequency of partial n to the first of
+// a stiff string of inharmonicity coefficient b, such as of a piano, where
+// partial n sounds at n·f0·sqrt(1+b·n²) of f0 of the string without
+// stiffness. Zero b is harmonic.
+func Inharmonicity(n int, b float64) float64 {
+	x := float64(n)
+	return x * math.Sqrt((1+b*x*x)/(1+b))
+}
+
+// Additive is a polyphonic additive synthesis instrument of sine partials
+// decaying exponentially, upper partials sooner, for piano and bell tones.
+// Partials are stretched by an inharmonicity coefficient as of a stiff
+// string, so upper partials sound sharp of harmonic, and partials above
+// nyquist are silent.
+type Additive struct {
+	*Poly
+	amps    []float64     // of partials from the fundamental
+	decay   time.Duration // of fundamental
+	release time.Duration
+	inharm  float64
+	soft    bool
+}
+
+// NewAdditive returns Additive of voices with partials at amplitudes amps from
+// the fundamental and the fundamental ringing over decay, harmonic until
+// inharmonicity is set.
+func NewAdditive(voices int, amps []float64, decay time.Duration) *Additive {
+	ad := &Additive{amps: amps, decay: decay, release: 200 * time.Millisecond}
+	ad.Poly = NewPoly(voices, func() Voice { return newAdditiveVoice(ad) })
+	ad.Poly.SetGain(VoiceGain(voices, CrestSaw, DefaultHeadroom))
+	return ad
+}
+
+// Partials returns amplitudes of partials from the fundamental.
+func (ad *Additive) Partials() []float64 { return ad.amps }
+
+// SetPartials sets amplitudes of partials from the fundamental, taking effect
+// on the next note.
+func (ad *Additive) SetPartials(amps []float64) { ad.amps = amps }
+
+func (ad *Additive) Decay() time.Duration     { return ad.decay }
+func (ad *Additive) SetDecay(d time.Duration) { ad.decay = d }
+
+func (ad *Additive) Release() time.Duration     { return ad.release }
+func (ad *Additive) SetRelease(d time.Duration) { ad.release = d }
+
+// Soft reports whether notes are played una corda.
+func (ad *Additive) Soft() bool { return ad.soft }
+
+// SetSoft sets notes played from the next una corda, quieter and with upper
+// partials lowered, as by a soft pedal.
+func (ad *Additive) SetSoft(down bool) { ad.soft = down }
+
+// Inharmonicity returns the inharmonicity coefficient, typically 1e-4 to 1e-2
+// for strings of a piano from bass to treble.
+func (ad *Additive) Inharmonicity() float64 { return ad.inharm }
+
+// SetInharmonicity sets the inharmonicity coefficient, taking effect on the
+// next note.
+func (ad *Additive) SetInharmonicity(b float64) { ad.inharm = math.Max(0, b) }
+
+func (ad *Additive) Params() []*Param {
+	return []*Param{
+		NewParam("inharmonicity", ad.Inharmonicity, ad.SetInharmonicity).Range(0, 0.05, 0),
+		NewParam("decay",
+			func() float64 { return ad.decay.Seconds() },
+			func(x float64) { ad.decay = time.Duration(x * float64(time.Second)) }).Range(0.01, 20, ad.decay.Seconds()).In(UnitSeconds),
+		NewParam("release",
+			func() float64 { return ad.release.Seconds() },
+			func(x float64) { ad.release = time.Duration(x * float64(time.Second)) }).Range(0.001, 5, ad.release.Seconds()).In(UnitSeconds),
+	}
+}
+
+// additiveVoice is a Voice of an Additive.
+type additiveVoice struct {
+	*mono
+	ad     *Additive
+	phases []float64
+	incs   []float64
+	gains  []float64
+	falls  []float64 // decay of gains per frame
+	rel    float64   // decay of gains per frame once released
+	damp   float64   // of release
+	held   bool
+}
+
+func newAdditiveVoice(ad *Additive) *additiveVoice {
+	return &additiveVoice{mono: newmono(nil), ad: ad, damp: 1}
+}
+
+func (vc *additiveVoice) Inputs() []Sound { return nil }
+
+func (vc *additiveVoice) NoteOn(hz, vel float64) {
+	ad := vc.ad
+	n := len(ad.amps)
+	if len(vc.phases) != n {
+		vc.phases, vc.incs = make([]float64, n), make([]float64, n)
+		vc.gains, vc.falls = make([]float64, n), make([]float64, n)
+	}
+	for i, amp := range ad.amps {
+		f := hz * Inharmonicity(i+1, ad.inharm)
+		vc.phases[i], vc.incs[i], vc.gains[i] = 0, f/vc.sr, 0
+		if f < vc.sr/2 {
+			vc.gains[i] = vel * amp
+		}
+		if ad.soft {
+			vc.gains[i] *= unacorda(f)
+		}
+		// partial n rings over decay/n.
+		t60 := ad.decay.Seconds() / float64(i+1)
+		vc.falls[i] = math.Pow(0.001, 1/(math.Max(t60, 1e-3)*vc.sr))
+	}
+	vc.rel = math.Pow(0.001, 1/(math.Max(ad.release.Seconds(), 1e-3)*vc.sr))
+	vc.held = true
+}
+
+func (vc *additiveVoice) NoteOff() { vc.held = false }
+
+// SetDamping sets damping of release, partials ringing on undamped as held.
+func (vc *additiveVoice) SetDamping(x float64) { vc.damp = math.Max(0, math.Min(1, x)) }
+
+func (vc *additiveVoice) Done() bool {
+	if vc.held {
+		return false
+	}
+	for _, g := range vc.gains {
+		if g > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+func (vc *additiveVoice) Prepare(uint64) {
+	rel := math.Pow(vc.rel, vc.damp)
+	for i := range vc.out {
+		var y float64
+		for k := range vc.gains {
+			if vc.gains[k] == 0 {
+				continue
+			}
+			y += vc.gains[k] * math.Sin(twopi*vc.phases[k])
+			vc.phases[k] += vc.incs[k]
+			vc.phases[k] -= math.Floor(vc.phases[k])
+			vc.gains[k] *= vc.falls[k]
+			if !vc.held {
+				vc.gains[k] *= rel
+			}
+		}
+		if vc.off {
+			y = 0
+		}
+		vc.out[i] = y
+	}
+}
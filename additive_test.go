@@ -0,0 +1,63 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// crossings returns zero crossings of sig a second at DefaultSampleRate.
+func crossings(sig Discrete) float64 {
+	var n int
+	for i := 1; i < len(sig); i++ {
+		if (sig[i-1] < 0) != (sig[i] < 0) {
+			n++
+		}
+	}
+	return float64(n) * DefaultSampleRate / float64(len(sig))
+}
+
+func TestAdditive(t *testing.T) {
+	sr := DefaultSampleRate
+	ad := NewAdditive(1, []float64{0, 0, 1}, 10*time.Second)
+	ad.SetInharmonicity(0.01)
+	ad.NoteOn(57, 1) // 220Hz
+	out := Render(ad, Dtof(time.Second, sr))
+	want := 220 * Inharmonicity(3, 0.01)
+	if hz := crossings(out) / 2; math.Abs(hz-want) > 2 {
+		t.Fatalf("have third partial at %vHz, want %vHz", hz, want)
+	}
+	ad.NoteOff(57)
+	Render(ad, Dtof(2*time.Second, sr))
+	if n := ad.Active(); n != 0 {
+		t.Fatalf("have %v voices active after release, want 0", n)
+	}
+}
+
+func TestKarplus(t *testing.T) {
+	sr := DefaultSampleRate
+	for _, b := range []float64{0, 1e-3} {
+		a, n, c := design(220, b, sr)
+		w0 := twopi * 220 / sr
+		if w := looppartial(a, n, c, 1); !equaleps(w, w0, 1e-9) {
+			t.Fatalf("have fundamental %vHz, want 220Hz", w*sr/twopi)
+		}
+		if w, want := looppartial(a, n, c, 8), w0*Inharmonicity(8, b); math.Abs(w-want)/want > 1e-3 {
+			t.Fatalf("have eighth partial %vHz, want %vHz", w*sr/twopi, want*sr/twopi)
+		}
+	}
+
+	ks := NewKarplus(2, 2*time.Second)
+	ks.SetInharmonicity(1e-3)
+	ks.NoteOn(45, 1)
+	ks.NoteOn(69, 1)
+	if pk := Peak(Render(ks, Dtof(500*time.Millisecond, sr))); pk < 0.02 || pk > 1 {
+		t.Fatalf("have peak %v, want sound without clipping", pk)
+	}
+	ks.NoteOff(45)
+	ks.NoteOff(69)
+	Render(ks, Dtof(5*time.Second, sr))
+	if n := ks.Active(); n != 0 {
+		t.Fatalf("have %v voices active after release, want 0", n)
+	}
+}
@@ -1,10 +1,12 @@
 package al
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"math"
 	"strings"
+	"sync"
 	"time"
 
 	"dasa.cc/snd"
@@ -18,8 +20,6 @@ import (
 
 const maxbufs = 80 // arbitrary soft limit
 
-var hwa *openal
-
 // Buffer provides adaptive buffering for real-time responses that outpace
 // openal's ability to report a buffer as processed.
 // Constant-time synchronization is left up to the caller.
@@ -60,15 +60,23 @@ func (b *Buffer) Get() (bufs []al.Buffer) {
 	return bufs
 }
 
-type openal struct {
+// Engine plays a graph through its own OpenAL device and source, so a process
+// may play several graphs at once, such as a cue mix on headphones and a main
+// mix, each at its own sample rate and Dispatcher. Where devices may not be
+// opened by name, such as on android, engines share the default device.
+// Package level functions play through a default Engine.
+type Engine struct {
+	dev    *device // nil once closed
 	source al.Source
 	buf    *Buffer
 
 	format uint32
-	in     snd.Sound
-	out    []byte
+	src    snd.Sound // as started
+	in     snd.Sound // played, src remixed if need be
+	outs   [][]byte  // of each buffer queued at once
 
 	quit chan struct{}
+	done chan struct{} // closed once ticking stops
 
 	underruns uint64
 
@@ -78,46 +86,123 @@ type openal struct {
 	start time.Time
 
 	inputs []*snd.Input
+
+	dp snd.Dispatcher
+	rt *snd.Realtime
 }
 
-func OpenDevice(buflen int) error {
-	if err := al.OpenDevice(); err != nil {
-		return fmt.Errorf("snd/al: open device failed: %s", err)
-	}
+var (
+	// mu guards calls of al, which act on the device current.
+	mu sync.Mutex
+
+	hwa *Engine // default
+)
+
+// NewEngine opens the default device and returns Engine queueing buflen
+// buffers, a power of 2.
+func NewEngine(buflen int) (*Engine, error) { return NewEngineDevice("", buflen) }
+
+// NewEngineDevice opens the device of name, one of Devices or empty for the
+// default, and returns Engine queueing buflen buffers, a power of 2.
+func NewEngineDevice(name string, buflen int) (*Engine, error) {
 	if buflen == 0 || buflen&(buflen-1) != 0 {
-		return fmt.Errorf("snd/al: buflen(%v) not a power of 2", buflen)
+		return nil, fmt.Errorf("snd/al: buflen(%v) not a power of 2", buflen)
 	}
-	hwa = &openal{buf: &Buffer{size: buflen}}
+	mu.Lock()
+	defer mu.Unlock()
+	dev, err := opendevice(name)
+	if err != nil {
+		return nil, err
+	}
+	return &Engine{dev: dev, buf: &Buffer{size: buflen}}, nil
+}
+
+// NewEngineLatency returns Engine queueing buffers for a latency target, as
+// OpenLatency.
+func NewEngineLatency(target time.Duration) (*Engine, error) {
+	return NewEngine(snd.LatencyBuffers(target, snd.DefaultSampleRate))
+}
+
+// CloseDevice deletes the source and buffers of e and closes its device. It
+// may be called more than once.
+func (e *Engine) CloseDevice() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if e.dev == nil {
+		return nil
+	}
+	e.dev.use()
+	al.DeleteBuffers(e.buf.bufs...)
+	al.DeleteSources(e.source)
+	e.buf.bufs = nil
+	e.dev.close()
+	e.dev = nil
 	return nil
 }
 
+// use makes the device of e current for calls of al until mu is unlocked.
+func (e *Engine) use() {
+	mu.Lock()
+	e.dev.use()
+}
+
+func OpenDevice(buflen int) error {
+	e, err := NewEngine(buflen)
+	if err == nil {
+		hwa = e
+	}
+	return err
+}
+
+// OpenLatency opens the device queueing buffers for a latency target, such as
+// snd.LatencyNormal, instead of a count of buffers. Buffers are counted by
+// snd.LatencyBuffers, so a snd.Stream of the same target agrees.
+func OpenLatency(target time.Duration) error {
+	e, err := NewEngineLatency(target)
+	if err == nil {
+		hwa = e
+	}
+	return err
+}
+
 func CloseDevice() error {
-	al.DeleteBuffers(hwa.buf.bufs...)
-	al.DeleteSources(hwa.source)
-	al.CloseDevice()
+	if hwa == nil {
+		return nil
+	}
+	err := hwa.CloseDevice()
 	hwa = nil
-	return nil
+	return err
 }
 
-func setSource(in snd.Sound) error {
+func (e *Engine) setSource(in snd.Sound) error {
+	e.src = in
+	if in.Channels() > 2 {
+		// such as 5.1, downmixed to the stereo OpenAL plays.
+		in = snd.Conform(2, in)
+	}
 	switch in.Channels() {
 	case 1:
-		hwa.format = al.FormatMono16
+		e.format = al.FormatMono16
 	case 2:
-		hwa.format = al.FormatStereo16
+		e.format = al.FormatStereo16
 	default:
 		return fmt.Errorf("snd/al: can't handle input with channels(%v)", in.Channels())
 	}
-	hwa.in = in
-	hwa.out = make([]byte, len(in.Samples())*2)
+	e.in = in
+	e.outs = make([][]byte, e.buf.size)
+	for i := range e.outs {
+		e.outs[i] = make([]byte, len(in.Samples())*2)
+	}
 
+	e.use()
+	defer mu.Unlock()
 	s := al.GenSources(1)
 	if code := al.Error(); code != 0 {
 		return fmt.Errorf("snd/al: generate source failed [err=%v]", code)
 	}
 
-	hwa.source = s[0]
-	hwa.buf.src = s[0]
+	e.source = s[0]
+	e.buf.src = s[0]
 
 	// openal-soft doesn't spatialize multi-channel sources like mono sources
 	// but it does give each channel an angle where it will be placed in relation
@@ -126,84 +211,123 @@ func setSource(in snd.Sound) error {
 	// dependent on the AL_SOFT_direct_channels extension.
 	if strings.Contains(al.Extensions(), "AL_SOFT_direct_channels") {
 		const AL_DIRECT_CHANNELS_SOFT = 0x1033
-		hwa.source.Seti(AL_DIRECT_CHANNELS_SOFT, 1)
+		e.source.Seti(AL_DIRECT_CHANNELS_SOFT, 1)
 	} else {
 		log.Println("extension AL_SOFT_direct_channels not available")
 	}
 
-	hwa.inputs = snd.GetInputs(in)
+	e.inputs = snd.GetInputs(in)
 
 	return nil
 }
 
-func Notify() {
-	if hwa.in != nil {
-		hwa.inputs = snd.GetInputs(hwa.in)
+func (e *Engine) Notify() {
+	if e.in != nil {
+		e.inputs = snd.GetInputs(e.in)
 	}
 }
 
-func SoftLatency() time.Duration {
-	nframes := float64(len(hwa.in.Samples()) / hwa.in.Channels())
-	return time.Duration(nframes * float64(hwa.buf.size) / hwa.in.SampleRate() * float64(time.Second))
+func (e *Engine) SoftLatency() time.Duration {
+	nframes := float64(len(e.in.Samples()) / e.in.Channels())
+	return time.Duration(nframes * float64(e.buf.size) / e.in.SampleRate() * float64(time.Second))
 }
 
-func Start(in snd.Sound) {
-	if hwa.quit != nil {
-		panic("snd/al: hwa.quit not nil")
+// SetRealtime sets scheduling of the goroutine e prepares buffers on from the
+// next Start; nil schedules it as any other. Failures to schedule it, such as
+// lacking permission, are logged and playback carries on.
+func (e *Engine) SetRealtime(rt *snd.Realtime) { e.rt = rt }
+
+func (e *Engine) Start(in snd.Sound) {
+	if e.quit != nil {
+		panic("snd/al: e.quit not nil")
 	}
-	hwa.quit = make(chan struct{})
-	if err := setSource(in); err != nil {
+	e.quit, e.done = make(chan struct{}), make(chan struct{})
+	if err := e.setSource(in); err != nil {
 		panic(err)
 	}
+	rt := e.rt
 	go func() {
-		hwa.start = time.Now()
-		Tick()
-		refill := time.Tick(SoftLatency())
+		defer close(e.done)
+		if rt != nil {
+			unlock, err := rt.Lock()
+			if err != nil {
+				log.Printf("snd/al: realtime: %v\n", err)
+			}
+			defer unlock()
+		}
+		e.start = time.Now()
+		e.Tick()
+		refill := time.Tick(e.SoftLatency())
 		for {
 			select {
-			case <-hwa.quit:
+			case <-e.quit:
 				return
 			case <-refill:
-				Tick()
+				e.Tick()
 			}
 		}
 	}()
 }
 
-func Stop() { close(hwa.quit) }
+// Stop stops playback, waiting for the buffers being prepared.
+func (e *Engine) Stop() {
+	if e.quit == nil {
+		return
+	}
+	close(e.quit)
+	<-e.done
+	e.quit = nil
+}
+
+// Close stops playback and closes the source and device of e, as CloseDevice.
+// If the source started is a *snd.Drain, it is drained first until done or ctx is done, so effect tails
+// ring out and sound fades instead of being cut off.
+func (e *Engine) Close(ctx context.Context) error {
+	var err error
+	if dr, ok := e.src.(*snd.Drain); ok && e.quit != nil {
+		err = dr.Close(ctx)
+	}
+	if e.quit != nil {
+		e.Stop()
+	}
+	e.CloseDevice()
+	return err
+}
 
-var dp = new(snd.Dispatcher)
+// Dispatcher returns the Dispatcher preparing output, such as to add hooks.
+func (e *Engine) Dispatcher() *snd.Dispatcher { return &e.dp }
 
-func Tick() {
+func (e *Engine) Tick() {
 	start := time.Now()
 
-	if code := al.DeviceError(); code != 0 {
+	if len(e.inputs) == 0 {
+		log.Println("snd/al: inputs not ready")
+		return
+	}
+	e.use()
+	if code := e.dev.err(); code != 0 {
 		log.Printf("snd/al: unknown device error [err=%v]\n", code)
 	}
 	if code := al.Error(); code != 0 {
 		log.Printf("snd/al: unknown error [err=%v]\n", code)
 	}
-	if len(hwa.inputs) == 0 {
-		log.Println("snd/al: inputs not ready")
-		return
-	}
-
-	bufs := hwa.buf.Get()
-
-	for _, buf := range bufs {
-		hwa.tc++
-
-		// TODO the general idea here is that GetInputs is rather cheap to call, even with the
-		// current first-draft implementation, so it could only return inputs that are actually
-		// turned on. This would introduce software latency determined by snd.DefaultBufferLen
-		// as turning an input back on would not get picked up until the next iteration.
-		// if !realtime {
-		// hwa.inputs = snd.GetInputs(hwa.in)
-		// }
-
-		dp.Dispatch(hwa.tc, hwa.inputs...)
-
-		for i, x := range hwa.in.Samples() {
+	bufs := e.buf.Get()
+	mu.Unlock()
+
+	// TODO the general idea here is that GetInputs is rather cheap to call, even with the
+	// current first-draft implementation, so it could only return inputs that are actually
+	// turned on. This would introduce software latency determined by snd.DefaultBufferLen
+	// as turning an input back on would not get picked up until the next iteration.
+	// if !realtime {
+	// e.inputs = snd.GetInputs(e.in)
+	// }
+
+	// prepared without holding mu, so other engines play meanwhile.
+	next := 0
+	e.dp.DispatchN(e.tc+1, len(bufs), func(tc uint64) {
+		e.tc = tc
+		out := e.outs[next]
+		for i, x := range e.in.Samples() {
 			// clip
 			if x > 1 {
 				x = 1
@@ -211,55 +335,92 @@ func Tick() {
 				x = -1
 			}
 			n := int16(math.MaxInt16 * x)
-			hwa.out[2*i] = byte(n)
-			hwa.out[2*i+1] = byte(n >> 8)
+			out[2*i] = byte(n)
+			out[2*i+1] = byte(n >> 8)
 		}
-		buf.BufferData(hwa.format, hwa.out, int32(hwa.in.SampleRate()))
+		next++
+	}, e.inputs...)
+
+	e.use()
+	defer mu.Unlock()
+	for i, b := range bufs {
+		b.BufferData(e.format, e.outs[i], int32(e.in.SampleRate()))
 		if code := al.Error(); code != 0 {
 			log.Printf("snd/al: buffer data failed [err=%v]\n", code)
 		}
 	}
-
 	if len(bufs) != 0 {
-		hwa.source.QueueBuffers(bufs...)
+		e.source.QueueBuffers(bufs...)
 	}
 	if code := al.Error(); code != 0 {
 		log.Printf("snd/al: queue buffer failed [err=%v]\n", code)
 	}
 
-	switch hwa.source.State() {
+	switch e.source.State() {
 	case al.Initial:
-		al.PlaySources(hwa.source)
+		al.PlaySources(e.source)
 	case al.Playing:
 	case al.Paused:
 	case al.Stopped:
-		hwa.underruns++
-		al.PlaySources(hwa.source)
+		e.underruns++
+		al.PlaySources(e.source)
 	}
 
-	hwa.tdur += time.Now().Sub(start)
+	e.tdur += time.Now().Sub(start)
 }
 
-func BufLen() int {
-	return len(hwa.buf.bufs)
+func (e *Engine) BufLen() int {
+	return len(e.buf.bufs)
 }
 
-func Underruns() uint64 {
-	return hwa.underruns
+func (e *Engine) Underruns() uint64 {
+	return e.underruns
 }
 
-func TickAverge() time.Duration {
-	if hwa.tc == 0 || hwa.buf.size == 0 {
+func (e *Engine) TickAverge() time.Duration {
+	if e.tc == 0 || e.buf.size == 0 {
+		return 0
+	}
+	n := e.tc / uint64(e.buf.size)
+	if n == 0 {
 		return 0
 	}
-	return hwa.tdur / time.Duration(int(hwa.tc)/hwa.buf.size)
+	return e.tdur / time.Duration(n)
 }
 
-func DriftApprox() time.Duration {
-	if hwa.tc == 0 || hwa.buf.size == 0 {
+func (e *Engine) DriftApprox() time.Duration {
+	if e.tc == 0 || e.buf.size == 0 {
 		return 0
 	}
-	dt := int64(time.Now().Sub(hwa.start) / time.Duration(int(hwa.tc)/hwa.buf.size))
-	lt := int64(SoftLatency())
+	n := e.tc / uint64(e.buf.size)
+	if n == 0 {
+		return 0
+	}
+	dt := int64(time.Now().Sub(e.start) / time.Duration(n))
+	lt := int64(e.SoftLatency())
 	return time.Duration(lt - dt)
 }
+
+// Close closes the default Engine, as Engine.Close.
+func Close(ctx context.Context) error {
+	if hwa == nil {
+		return nil
+	}
+	err := hwa.Close(ctx)
+	hwa = nil
+	return err
+}
+
+// Dispatcher returns the Dispatcher of the default Engine, which must be open.
+func Dispatcher() *snd.Dispatcher { return hwa.Dispatcher() }
+
+func Start(in snd.Sound)           { hwa.Start(in) }
+func SetRealtime(rt *snd.Realtime) { hwa.SetRealtime(rt) }
+func Stop()                        { hwa.Stop() }
+func Notify()                      { hwa.Notify() }
+func Tick()                        { hwa.Tick() }
+func SoftLatency() time.Duration   { return hwa.SoftLatency() }
+func BufLen() int                  { return hwa.BufLen() }
+func Underruns() uint64            { return hwa.Underruns() }
+func TickAverge() time.Duration    { return hwa.TickAverge() }
+func DriftApprox() time.Duration   { return hwa.DriftApprox() }
@@ -0,0 +1,95 @@
+//go:build darwin || (linux && !android)
+// +build darwin linux,!android
+
+package al
+
+/*
+#cgo darwin LDFLAGS: -framework OpenAL
+#cgo linux LDFLAGS: -lopenal
+
+#include <stdlib.h>
+
+// declared as of alc.h, devices and contexts taken opaquely.
+void *alcOpenDevice(const char *name);
+char alcCloseDevice(void *dev);
+void *alcCreateContext(void *dev, const int *attrs);
+char alcMakeContextCurrent(void *ctx);
+void alcDestroyContext(void *ctx);
+int alcGetError(void *dev);
+const char *alcGetString(void *dev, int param);
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+const alcDeviceSpecifier = 0x1005
+
+// device is an OpenAL device and context of its own, one of each Engine.
+// Calls of al act on the context current, so engines take turns under mu.
+type device struct {
+	dev, ctx unsafe.Pointer
+}
+
+var current *device // of calls of al, guarded by mu
+
+// opendevice opens the device of name, the default if empty; mu must be held.
+func opendevice(name string) (*device, error) {
+	var cname *C.char
+	if name != "" {
+		cname = C.CString(name)
+		defer C.free(unsafe.Pointer(cname))
+	}
+	dev := C.alcOpenDevice(cname)
+	if dev == nil {
+		return nil, fmt.Errorf("snd/al: open device %q failed", name)
+	}
+	ctx := C.alcCreateContext(dev, nil)
+	if ctx == nil {
+		code := C.alcGetError(dev)
+		C.alcCloseDevice(dev)
+		return nil, fmt.Errorf("snd/al: create context of device %q failed [err=%v]", name, code)
+	}
+	return &device{dev: dev, ctx: ctx}, nil
+}
+
+// use makes the context of d current; mu must be held.
+func (d *device) use() {
+	if current != d {
+		C.alcMakeContextCurrent(d.ctx)
+		current = d
+	}
+}
+
+// close destroys the context of d and closes it; mu must be held.
+func (d *device) close() {
+	if current == d {
+		C.alcMakeContextCurrent(nil)
+		current = nil
+	}
+	C.alcDestroyContext(d.ctx)
+	C.alcCloseDevice(d.dev)
+}
+
+// err returns the last error of d.
+func (d *device) err() int32 { return int32(C.alcGetError(d.dev)) }
+
+// Devices returns names of devices an Engine may be opened on.
+func Devices() []string {
+	p := C.alcGetString(nil, alcDeviceSpecifier)
+	if p == nil {
+		return nil
+	}
+	// names are separated by a zero byte, and end with two.
+	var names []string
+	for {
+		s := C.GoString(p)
+		if s == "" {
+			return names
+		}
+		names = append(names, s)
+		p = (*C.char)(unsafe.Pointer(uintptr(unsafe.Pointer(p)) + uintptr(len(s)+1)))
+	}
+}
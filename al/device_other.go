@@ -0,0 +1,46 @@
+//go:build (!darwin && !linux) || android
+// +build !darwin,!linux android
+
+package al
+
+import (
+	"fmt"
+	"runtime"
+
+	"golang.org/x/mobile/exp/audio/al"
+)
+
+// device is the single device of al, shared by engines where devices may
+// not be opened by name.
+type device struct{}
+
+var shared int // engines open on the device, guarded by mu
+
+// opendevice opens the device, if not open; mu must be held.
+func opendevice(name string) (*device, error) {
+	if name != "" {
+		return nil, fmt.Errorf("snd/al: open device %q failed: devices by name not supported on %s", name, runtime.GOOS)
+	}
+	if shared == 0 {
+		if err := al.OpenDevice(); err != nil {
+			return nil, fmt.Errorf("snd/al: open device failed: %s", err)
+		}
+	}
+	shared++
+	return &device{}, nil
+}
+
+func (d *device) use() {}
+
+// close closes the device once no engine is open on it; mu must be held.
+func (d *device) close() {
+	if shared--; shared == 0 {
+		al.CloseDevice()
+	}
+}
+
+func (d *device) err() int32 { return al.DeviceError() }
+
+// Devices returns names of devices an Engine may be opened on, none where
+// only the default may be.
+func Devices() []string { return nil }
@@ -0,0 +1,223 @@
+package snd
+
+// Const outputs a constant value, such as a fixed modulation amount. Its
+// param declares no range, as a value may be of any unit, such as a frequency.
+type Const struct {
+	*mono
+	x float64
+}
+
+func NewConst(x float64) *Const { return &Const{mono: newmono(nil), x: x} }
+
+func (c *Const) Inputs() []Sound { return nil }
+
+func (c *Const) Value() float64     { return c.x }
+func (c *Const) SetValue(x float64) { c.x = x }
+
+func (c *Const) Params() []*Param {
+	return []*Param{NewParam("value", c.Value, c.SetValue)}
+}
+
+func (c *Const) Prepare(uint64) {
+	for i := range c.out {
+		if c.off {
+			c.out[i] = 0
+		} else {
+			c.out[i] = c.x
+		}
+	}
+}
+
+// Add outputs the sum of its inputs sample by sample.
+type Add struct {
+	*mono
+	ins []Sound
+}
+
+// NewAdd returns Add of ins, which must be of equal length.
+func NewAdd(ins ...Sound) *Add {
+	sd := newmono(nil)
+	if len(ins) > 0 {
+		sd.sr = ins[0].SampleRate()
+		sd.out = make(Discrete, len(ins[0].Samples()))
+	}
+	return &Add{mono: sd, ins: ins}
+}
+
+func (a *Add) Inputs() []Sound { return a.ins }
+
+func (a *Add) Prepare(uint64) {
+	for i := range a.out {
+		a.out[i] = 0
+	}
+	if a.off {
+		return
+	}
+	for _, in := range a.ins {
+		for i, x := range in.Samples() {
+			a.out[i] += x
+		}
+	}
+}
+
+// Mul outputs the product of its inputs sample by sample, such as ring
+// modulation of two signals or one modulation scaled by another.
+type Mul struct {
+	*mono
+	ins []Sound
+}
+
+// NewMul returns Mul of ins, which must be of equal length.
+func NewMul(ins ...Sound) *Mul {
+	sd := newmono(nil)
+	if len(ins) > 0 {
+		sd.sr = ins[0].SampleRate()
+		sd.out = make(Discrete, len(ins[0].Samples()))
+	}
+	return &Mul{mono: sd, ins: ins}
+}
+
+func (m *Mul) Inputs() []Sound { return m.ins }
+
+func (m *Mul) Prepare(uint64) {
+	v := 1.0
+	if m.off || len(m.ins) == 0 {
+		v = 0
+	}
+	for i := range m.out {
+		m.out[i] = v
+	}
+	if v == 0 {
+		return
+	}
+	for _, in := range m.ins {
+		for i, x := range in.Samples() {
+			m.out[i] *= x
+		}
+	}
+}
+
+// Scale multiplies its input by a factor.
+type Scale struct {
+	*mono
+	fac float64
+}
+
+func NewScale(fac float64, in Sound) *Scale { return &Scale{mono: newmono(in), fac: fac} }
+
+func (sc *Scale) Factor() float64     { return sc.fac }
+func (sc *Scale) SetFactor(x float64) { sc.fac = x }
+
+func (sc *Scale) Params() []*Param {
+	return []*Param{NewParam("factor", sc.Factor, sc.SetFactor)}
+}
+
+func (sc *Scale) Prepare(uint64) {
+	for i, x := range sc.in.Samples() {
+		if sc.off {
+			sc.out[i] = 0
+		} else {
+			sc.out[i] = sc.fac * x
+		}
+	}
+}
+
+// Offset adds a constant to its input, such as to make a bipolar LFO unipolar.
+type Offset struct {
+	*mono
+	x float64
+}
+
+func NewOffset(x float64, in Sound) *Offset { return &Offset{mono: newmono(in), x: x} }
+
+func (ofs *Offset) Offset() float64     { return ofs.x }
+func (ofs *Offset) SetOffset(x float64) { ofs.x = x }
+
+func (ofs *Offset) Params() []*Param {
+	return []*Param{NewParam("offset", ofs.Offset, ofs.SetOffset)}
+}
+
+func (ofs *Offset) Prepare(uint64) {
+	for i, x := range ofs.in.Samples() {
+		if ofs.off {
+			ofs.out[i] = 0
+		} else {
+			ofs.out[i] = x + ofs.x
+		}
+	}
+}
+
+// Clamp limits its input to [min..max].
+type Clamp struct {
+	*mono
+	min, max float64
+}
+
+func NewClamp(min, max float64, in Sound) *Clamp {
+	return &Clamp{mono: newmono(in), min: min, max: max}
+}
+
+func (cl *Clamp) Range() (min, max float64) { return cl.min, cl.max }
+func (cl *Clamp) SetRange(min, max float64) { cl.min, cl.max = min, max }
+
+func (cl *Clamp) Params() []*Param {
+	return []*Param{
+		NewParam("min", func() float64 { return cl.min }, func(x float64) { cl.min = x }),
+		NewParam("max", func() float64 { return cl.max }, func(x float64) { cl.max = x }),
+	}
+}
+
+func (cl *Clamp) Prepare(uint64) {
+	for i, x := range cl.in.Samples() {
+		if x < cl.min {
+			x = cl.min
+		} else if x > cl.max {
+			x = cl.max
+		}
+		if cl.off {
+			cl.out[i] = 0
+		} else {
+			cl.out[i] = x
+		}
+	}
+}
+
+// MapRange maps its input linearly from one range to another, such as an LFO
+// in [-1..1] to a cutoff in [200..2000]. Input outside its range maps outside
+// of the output range; follow with Clamp to limit it.
+type MapRange struct {
+	*mono
+	inlo, inhi   float64
+	outlo, outhi float64
+}
+
+// NewMapRange returns MapRange of in from [inlo..inhi] to [outlo..outhi].
+func NewMapRange(inlo, inhi, outlo, outhi float64, in Sound) *MapRange {
+	return &MapRange{mono: newmono(in), inlo: inlo, inhi: inhi, outlo: outlo, outhi: outhi}
+}
+
+// SetRanges sets input and output ranges.
+func (mr *MapRange) SetRanges(inlo, inhi, outlo, outhi float64) {
+	mr.inlo, mr.inhi, mr.outlo, mr.outhi = inlo, inhi, outlo, outhi
+}
+
+func (mr *MapRange) Params() []*Param {
+	return []*Param{
+		NewParam("outlo", func() float64 { return mr.outlo }, func(x float64) { mr.outlo = x }).Range(-20000, 20000, 0),
+		NewParam("outhi", func() float64 { return mr.outhi }, func(x float64) { mr.outhi = x }).Range(-20000, 20000, 1),
+	}
+}
+
+func (mr *MapRange) Prepare(uint64) {
+	fac := 0.0
+	if d := mr.inhi - mr.inlo; d != 0 {
+		fac = (mr.outhi - mr.outlo) / d
+	}
+	for i, x := range mr.in.Samples() {
+		if mr.off {
+			mr.out[i] = 0
+		} else {
+			mr.out[i] = mr.outlo + (x-mr.inlo)*fac
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package snd
+
+import "testing"
+
+func TestArith(t *testing.T) {
+	lfo := NewConst(0.5)
+	tests := []struct {
+		name string
+		sd   Sound
+		want float64
+	}{
+		{"const", lfo, 0.5},
+		{"add", NewAdd(lfo, NewConst(2)), 2.5},
+		{"mul", NewMul(lfo, NewConst(3), NewConst(2)), 3},
+		{"scale", NewScale(4, lfo), 2},
+		{"offset", NewOffset(1, lfo), 1.5},
+		{"clamp", NewClamp(-0.25, 0.25, lfo), 0.25},
+		{"map", NewMapRange(-1, 1, 200, 2000, lfo), 1550},
+	}
+	for _, tt := range tests {
+		if have := Render(tt.sd, 4)[2]; have != tt.want {
+			t.Errorf("%s: have %v, want %v", tt.name, have, tt.want)
+		}
+	}
+}
+
+func TestArithParams(t *testing.T) {
+	// signals of any unit, such as a frequency modulating an Oscil.
+	var ps Params
+	ps.Register("freq", NewConst(0))
+	ps.Register("ofs", NewOffset(0, NewConst(0)))
+	ps.Register("scale", NewScale(1, NewConst(0)))
+	ps.Register("clamp", NewClamp(-1, 1, NewConst(0)))
+	for _, p := range ps.List() {
+		if p.Set(1000); p.Value() != 1000 {
+			t.Errorf("have %v of %v set to 1000", p.Value(), p.Name)
+		}
+	}
+}
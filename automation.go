@@ -0,0 +1,197 @@
+package snd
+
+import (
+	"sort"
+	"sync"
+)
+
+// AutomationEvent is a change of a param at a beat of a Transport.
+type AutomationEvent struct {
+	Beat  float64
+	Param string
+	Value float64
+}
+
+// Modes of an Automation.
+const (
+	// AutomationOff neither plays nor records.
+	AutomationOff = iota
+
+	// AutomationRead plays events.
+	AutomationRead
+
+	// AutomationWrite records changes while playing events of params not
+	// yet changed. Once a param is changed, its events are replaced for the
+	// rest of the pass, until the transport stops or seeks back.
+	AutomationWrite
+)
+
+// Automation records changes of params of a Params against the beat of a
+// Transport, whatever makes them, such as a UI, OSC, or Learn of package
+// midi, and plays them back on later passes.
+//
+// Automation works by Tick, added as a hook of the Dispatcher playing the
+// transport, such as by Dispatcher.BeforeDispatch, including a Dispatcher
+// rendering offline by Dispatcher.Render. Params change on buffer boundaries,
+// so events play at the start of the buffer they fall in, and changes are
+// recorded at the beat of the start of the buffer after they are made.
+//
+// On playing from a position other than where the last buffer left off, such
+// as when looping or seeking, params are set to their values at the new
+// position. All methods are safe to call from any goroutine.
+type Automation struct {
+	mu     sync.Mutex
+	tp     *Transport
+	ps     *Params
+	mode   int
+	events []AutomationEvent // sorted by beat
+
+	last    Preset          // values of params as of the last tick
+	touched map[string]bool // params changed this pass while writing
+	next    float64         // beat the last buffer ended at
+	playing bool            // transport was playing at the last tick
+}
+
+// NewAutomation returns Automation of ps against tp, reading no events.
+func NewAutomation(tp *Transport, ps *Params) *Automation {
+	return &Automation{tp: tp, ps: ps, mode: AutomationRead, touched: make(map[string]bool)}
+}
+
+func (au *Automation) Mode() int {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	return au.mode
+}
+
+// SetMode sets mode such as AutomationWrite.
+func (au *Automation) SetMode(mode int) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	au.mode = mode
+	au.touched = make(map[string]bool)
+}
+
+// Events returns a copy of all events in order of beat.
+func (au *Automation) Events() []AutomationEvent {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	return append([]AutomationEvent(nil), au.events...)
+}
+
+// SetEvents replaces all events with a copy of evs, such as to edit or load
+// them.
+func (au *Automation) SetEvents(evs []AutomationEvent) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	au.events = append(au.events[:0], evs...)
+	sort.SliceStable(au.events, func(i, j int) bool { return au.events[i].Beat < au.events[j].Beat })
+}
+
+// Clear removes all events of param name, or of all params if name is empty.
+func (au *Automation) Clear(name string) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	evs := au.events[:0]
+	for _, ev := range au.events {
+		if name != "" && ev.Param != name {
+			evs = append(evs, ev)
+		}
+	}
+	au.events = evs
+}
+
+// Tick plays and records events for the buffer about to be prepared, and is
+// a Hook.
+func (au *Automation) Tick(tc, frame uint64) {
+	au.mu.Lock()
+	defer au.mu.Unlock()
+	if au.last == nil {
+		au.last = au.ps.Save()
+	}
+	playing := au.tp.Playing()
+	if !playing || au.mode == AutomationOff {
+		if au.playing {
+			au.touched = make(map[string]bool)
+		}
+		au.playing = false
+		au.save()
+		return
+	}
+
+	// eps absorbs round-off accumulated from summing fractional beats.
+	const eps = 1e-9
+	beat := au.tp.Beat()
+	end := beat + float64(au.tp.BPM())/(60*au.tp.SampleRate())*float64(len(au.tp.Samples()))
+	jumped := !au.playing || beat < au.next-eps || beat > au.next+eps
+	au.playing, au.next = true, end
+	if jumped {
+		au.touched = make(map[string]bool)
+	}
+
+	if au.mode == AutomationWrite {
+		var changes []AutomationEvent
+		for _, p := range au.ps.List() {
+			if x := p.Value(); x != au.last[p.Name] {
+				au.touched[p.Name] = true
+				changes = append(changes, AutomationEvent{Beat: beat, Param: p.Name, Value: x})
+			}
+		}
+		// touched params are overwritten until the end of this buffer.
+		evs := au.events[:0]
+		for _, ev := range au.events {
+			if !au.touched[ev.Param] || ev.Beat < beat-eps || ev.Beat >= end-eps {
+				evs = append(evs, ev)
+			}
+		}
+		au.events = evs
+		for _, ev := range changes {
+			au.insert(ev)
+		}
+	}
+
+	if jumped {
+		au.chase(beat)
+	}
+	i := sort.Search(len(au.events), func(i int) bool { return au.events[i].Beat >= beat-eps })
+	for ; i < len(au.events) && au.events[i].Beat < end-eps; i++ {
+		ev := au.events[i]
+		if au.touched[ev.Param] {
+			continue
+		}
+		if p := au.ps.Lookup(ev.Param); p != nil {
+			p.Set(ev.Value)
+		}
+	}
+	au.save()
+}
+
+// save stores current values of params as of the last tick.
+func (au *Automation) save() {
+	for _, p := range au.ps.List() {
+		au.last[p.Name] = p.Value()
+	}
+}
+
+// chase sets params to their values of the last events before beat.
+func (au *Automation) chase(beat float64) {
+	vals := make(Preset)
+	for _, ev := range au.events {
+		if ev.Beat >= beat {
+			break
+		}
+		vals[ev.Param] = ev.Value
+	}
+	for name, x := range vals {
+		if p := au.ps.Lookup(name); p != nil && !au.touched[name] {
+			p.Set(x)
+		}
+	}
+}
+
+// insert adds ev after events of the same beat.
+func (au *Automation) insert(ev AutomationEvent) {
+	i := sort.Search(len(au.events), func(i int) bool { return au.events[i].Beat > ev.Beat })
+	au.events = append(au.events, AutomationEvent{})
+	copy(au.events[i+1:], au.events[i:])
+	au.events[i] = ev
+}
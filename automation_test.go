@@ -0,0 +1,53 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutomation(t *testing.T) {
+	sr := DefaultSampleRate
+	tp := NewTransport(120) // a beat each half second
+	osc := NewOscil(Sine(), 440, nil)
+	var ps Params
+	ps.Register("osc", osc)
+	au := NewAutomation(tp, &ps)
+	var dp Dispatcher
+	dp.BeforeDispatch(au.Tick)
+	mix := NewMixer(tp, osc)
+
+	// record a change made at about beat 1 of the first pass.
+	au.SetMode(AutomationWrite)
+	tp.Play()
+	dp.Render(mix, Dtof(500*time.Millisecond, sr))
+	osc.SetFreq(880, nil)
+	dp.Render(mix, Dtof(time.Second, sr))
+	evs := au.Events()
+	if len(evs) != 1 || evs[0].Param != "osc.freq" || evs[0].Value != 880 || !equaleps(evs[0].Beat, 1, 0.02) {
+		t.Fatalf("have events %+v, want osc.freq 880 at beat 1", evs)
+	}
+
+	// play back from the start, changing at beat 1 again.
+	au.SetMode(AutomationRead)
+	osc.SetFreq(440, nil)
+	tp.Seek(0)
+	dp.Render(mix, Dtof(400*time.Millisecond, sr))
+	if osc.Freq() != 440 {
+		t.Fatalf("have freq %v before beat 1, want 440", osc.Freq())
+	}
+	dp.Render(mix, Dtof(200*time.Millisecond, sr))
+	if osc.Freq() != 880 {
+		t.Fatalf("have freq %v after beat 1, want 880", osc.Freq())
+	}
+
+	// overwrite from beat 0.5 on a second write pass.
+	au.SetMode(AutomationWrite)
+	tp.Seek(0)
+	dp.Render(mix, Dtof(250*time.Millisecond, sr))
+	osc.SetFreq(660, nil)
+	dp.Render(mix, Dtof(time.Second, sr))
+	evs = au.Events()
+	if len(evs) != 1 || evs[0].Value != 660 || !equaleps(evs[0].Beat, 0.5, 0.02) {
+		t.Fatalf("have events %+v, want only osc.freq 660 at beat 0.5", evs)
+	}
+}
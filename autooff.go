@@ -0,0 +1,129 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// switcher is a Sound that may be turned on and off.
+type switcher interface {
+	IsOff() bool
+	On()
+	Off()
+}
+
+// AutoOff passes its input through and turns the branch of the graph under
+// it off once silent for a while, so idle branches of a large graph, such as
+// an instrument not playing, cost little until woken. Sounds of the branch
+// that were on are turned back on when woken by a note, a trigger, or Wake.
+//
+// Sounds under AutoOff should not be shared with the rest of the graph, as
+// they are turned off for all that use them.
+type AutoOff struct {
+	*mono
+	chans int
+
+	threshold float64 // amplitude
+	hold      int     // frames of silence before sleeping
+	quiet     int     // frames of silence so far
+
+	edge
+	trig   Sound
+	nt     Noter
+	asleep bool
+	slept  []switcher // sounds turned off while asleep
+}
+
+// NewAutoOff returns AutoOff of in sleeping after hold below threshold.
+func NewAutoOff(threshold Decibel, hold time.Duration, in Sound) *AutoOff {
+	sd := newmono(in)
+	sd.sr = in.SampleRate()
+	sd.out = make(Discrete, len(in.Samples()))
+	return &AutoOff{
+		mono:      sd,
+		chans:     in.Channels(),
+		threshold: threshold.Amp(),
+		hold:      Dtof(hold, sd.sr),
+	}
+}
+
+func (ao *AutoOff) Channels() int   { return ao.chans }
+func (ao *AutoOff) Inputs() []Sound { return []Sound{ao.in, ao.trig} }
+
+// SetTrigger sets a trigger that wakes the branch on rising edges, taking
+// effect the following buffer. The trigger must not be part of the branch.
+func (ao *AutoOff) SetTrigger(trig Sound) { ao.trig = trig }
+
+// SetNoter plays notes on nt, waking the branch before each note on.
+func (ao *AutoOff) SetNoter(nt Noter) { ao.nt = nt }
+
+// NoteOn wakes the branch and plays key on the Noter.
+func (ao *AutoOff) NoteOn(key int, vel float64) {
+	ao.Wake()
+	if ao.nt != nil {
+		ao.nt.NoteOn(key, vel)
+	}
+}
+
+// NoteOff releases key on the Noter.
+func (ao *AutoOff) NoteOff(key int) {
+	if ao.nt != nil {
+		ao.nt.NoteOff(key)
+	}
+}
+
+// Asleep reports whether the branch is turned off for silence.
+func (ao *AutoOff) Asleep() bool { return ao.asleep }
+
+// Wake turns the branch back on if asleep. As with notes played on a Poly,
+// Wake must not be called while the graph is being prepared.
+func (ao *AutoOff) Wake() {
+	ao.quiet = 0
+	if !ao.asleep {
+		return
+	}
+	for _, sw := range ao.slept {
+		sw.On()
+	}
+	ao.slept, ao.asleep = ao.slept[:0], false
+}
+
+// sleep turns off every sound of the branch that is on.
+func (ao *AutoOff) sleep() {
+	for _, inp := range GetInputs(ao.in) {
+		if sw, ok := inp.sd.(switcher); ok && !sw.IsOff() {
+			sw.Off()
+			ao.slept = append(ao.slept, sw)
+		}
+	}
+	ao.asleep = true
+}
+
+func (ao *AutoOff) Prepare(uint64) {
+	wake := false
+	for i := 0; i < len(ao.out); i += ao.chans {
+		if ao.trig != nil {
+			if rise, _ := ao.step(ao.trig.Index(i / ao.chans)); rise {
+				wake = true
+			}
+		}
+		var peak float64
+		for c := 0; c < ao.chans; c++ {
+			x := ao.in.Samples()[i+c]
+			peak = math.Max(peak, math.Abs(x))
+			if ao.off {
+				x = 0
+			}
+			ao.out[i+c] = x
+		}
+		if ao.quiet++; peak >= ao.threshold {
+			ao.quiet = 0
+		}
+	}
+	switch {
+	case wake:
+		ao.Wake()
+	case !ao.asleep && ao.quiet >= ao.hold:
+		ao.sleep()
+	}
+}
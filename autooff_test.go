@@ -0,0 +1,33 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoOff(t *testing.T) {
+	sr := DefaultSampleRate
+	amp := newlevel()
+	amp.set(1)
+	osc := NewOscil(Sine(), 440, nil)
+	osc.SetAmp(1, amp)
+	ao := NewAutoOff(-60, 50*time.Millisecond, osc)
+
+	if p := Peak(Render(ao, Dtof(100*time.Millisecond, sr))); p < 0.5 || ao.Asleep() {
+		t.Fatalf("have peak %v asleep %v playing, want sound", p, ao.Asleep())
+	}
+	amp.set(0)
+	Render(ao, Dtof(100*time.Millisecond, sr))
+	if !ao.Asleep() || !osc.IsOff() {
+		t.Fatalf("have asleep %v osc off %v after silence, want both", ao.Asleep(), osc.IsOff())
+	}
+
+	amp.set(1)
+	if p := Peak(Render(ao, Dtof(100*time.Millisecond, sr))); p != 0 {
+		t.Fatalf("have peak %v asleep, want silence", p)
+	}
+	ao.Wake()
+	if p := Peak(Render(ao, Dtof(100*time.Millisecond, sr))); p < 0.5 || osc.IsOff() {
+		t.Fatalf("have peak %v osc off %v woken, want sound", p, osc.IsOff())
+	}
+}
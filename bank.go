@@ -0,0 +1,363 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// DrumChannel is the zero based MIDI channel of General MIDI percussion.
+const DrumChannel = 9
+
+// Program returns a new instrument of voices for General MIDI program number
+// prog, zero based. Programs are approximated by family from oscillators,
+// envelopes, and filters: pianos, organs, guitars, basses, strings and pads,
+// and brass, reeds, and leads. Electric pianos, chromatic percussion, and synth
+// basses are FM. Acoustic pianos are stretch tuned by Railsback.
+func Program(prog, voices int) *Poly {
+	var (
+		table  Discrete
+		crest  Decibel
+		atk    = 2 * time.Millisecond
+		dcy    = 50 * time.Millisecond
+		sus    = 8 * time.Second
+		rel    = 300 * time.Millisecond
+		susamp = 0.8
+		cutoff = 0.0
+		tuning *Tuning
+	)
+	switch prog = prog & 0x7F; {
+	case prog < 4: // acoustic pianos
+		table, crest = SawtoothSynthesis(6), CrestSaw
+		dcy, susamp, rel, cutoff = 1500*time.Millisecond, 0.15, 400*time.Millisecond, 3000
+		tuning = &Tuning{Offsets: Railsback(DefaultInharmonicity)}
+	case prog < 6: // electric pianos
+		return NewFM(voices, FMPatchEPiano).Poly
+	case prog < 8: // harpsichord and clavinet
+		table, crest = additive(1, 0, 0.2, 0, 0.05), CrestSine
+		dcy, susamp, rel = 1200*time.Millisecond, 0.25, 500*time.Millisecond
+	case prog < 16: // chromatic percussion
+		return NewFM(voices, FMPatchBell).Poly
+	case prog < 24: // organs
+		table, crest = additive(1, 0.8, 0.5, 0.6, 0, 0.4, 0, 0.3), CrestSquare
+		atk, dcy, susamp, rel = 5*time.Millisecond, 5*time.Millisecond, 1, 60*time.Millisecond
+	case prog < 32: // guitars
+		table, crest = SawtoothSynthesis(10), CrestSaw
+		dcy, susamp, rel, cutoff = 800*time.Millisecond, 0.1, 200*time.Millisecond, 2500
+	case prog == 38 || prog == 39: // synth basses
+		return NewFM(voices, FMPatchBass).Poly
+	case prog < 40: // basses
+		table, crest = Sawtooth(), CrestSaw
+		dcy, susamp, rel, cutoff = 400*time.Millisecond, 0.5, 120*time.Millisecond, 700
+	case prog < 56, prog >= 88 && prog < 96: // strings, ensembles, and pads
+		table, crest = SawtoothSynthesis(8), CrestSaw
+		atk, dcy, susamp, rel, cutoff = 300*time.Millisecond, 200*time.Millisecond, 0.8, time.Second, 2000
+	default: // brass, reeds, pipes, leads, and effects
+		table, crest = SquareSynthesis(9), CrestSquare
+		atk, dcy, susamp, rel, cutoff = 20*time.Millisecond, 150*time.Millisecond, 0.7, 150*time.Millisecond, 3500
+	}
+	p := NewPoly(voices, func() Voice {
+		env := NewADSR(atk, dcy, sus, rel, susamp, 1, nil)
+		if cutoff == 0 {
+			return NewOscVoice(table, env)
+		}
+		return NewOscVoice(table, env, func(in Sound) Sound { return NewLowPass(cutoff, in) })
+	})
+	p.SetGain(VoiceGain(voices, crest, DefaultHeadroom))
+	p.SetTuning(tuning)
+	return p
+}
+
+// additive returns a wave of harmonics at amplitudes amps, the first being
+// the fundamental.
+func additive(amps ...float64) Discrete {
+	sig := make(Discrete, 1024)
+	for i := range sig {
+		t := float64(i) / float64(len(sig))
+		for h, a := range amps {
+			sig[i] += a * SineFunc(float64(h+1)*t)
+		}
+	}
+	sig.Normalize()
+	return sig
+}
+
+// drum describes a percussive hit of a pitch swept tone and filtered noise.
+type drum struct {
+	hz0, hz1 float64       // tone at hit and after sweep
+	sweep    time.Duration // time constant of sweep
+	tone     time.Duration // time constant of tone decay
+	noise    float64       // level of noise relative to tone
+	ndecay   time.Duration // time constant of noise decay
+	typ      FilterType
+	cutoff   float64 // of noise
+	choke    int     // group choking others of the same group when struck
+}
+
+var (
+	kick    = drum{hz0: 150, hz1: 50, sweep: 30 * time.Millisecond, tone: 250 * time.Millisecond}
+	snare   = drum{hz0: 250, hz1: 180, sweep: 10 * time.Millisecond, tone: 60 * time.Millisecond, noise: 1, ndecay: 120 * time.Millisecond, typ: FilterHighPass, cutoff: 1500}
+	stick   = drum{hz0: 900, hz1: 700, sweep: 5 * time.Millisecond, tone: 15 * time.Millisecond, noise: 0.5, ndecay: 15 * time.Millisecond, typ: FilterBandPass, cutoff: 3000}
+	clap    = drum{noise: 1, ndecay: 150 * time.Millisecond, typ: FilterBandPass, cutoff: 1200}
+	hatc    = drum{noise: 1, ndecay: 40 * time.Millisecond, typ: FilterHighPass, cutoff: 7000, choke: 1}
+	hato    = drum{noise: 1, ndecay: 400 * time.Millisecond, typ: FilterHighPass, cutoff: 7000, choke: 1}
+	cymbal  = drum{noise: 1, ndecay: 1200 * time.Millisecond, typ: FilterHighPass, cutoff: 5000}
+	ride    = drum{hz0: 3200, hz1: 3200, tone: 600 * time.Millisecond, noise: 0.6, ndecay: 800 * time.Millisecond, typ: FilterHighPass, cutoff: 6000}
+	tomhz   = []float64{80, 100, 120, 145, 175, 210}
+	tomkeys = []int{41, 43, 45, 47, 48, 50}
+)
+
+// gmdrums returns drums of a General MIDI kit by key.
+func gmdrums() map[int]drum {
+	kit := map[int]drum{
+		35: kick, 36: kick,
+		37: stick, 38: snare, 39: clap, 40: snare,
+		42: hatc, 44: hatc, 46: hato,
+		49: cymbal, 52: cymbal, 55: cymbal, 57: cymbal,
+		51: ride, 53: ride, 59: ride,
+	}
+	for i, key := range tomkeys {
+		hz := tomhz[i]
+		kit[key] = drum{hz0: 1.5 * hz, hz1: hz, sweep: 40 * time.Millisecond, tone: 300 * time.Millisecond}
+	}
+	return kit
+}
+
+// hit is a drum sounding.
+type hit struct {
+	drum
+	vel, phase    float64
+	hz, tamp, nam float64
+	f             svf
+}
+
+// DrumKit plays General MIDI percussion by key, such as 36 for a kick and 38
+// for a snare, synthesizing each hit from a swept tone and filtered noise.
+// Keys not in the kit are ignored. Hits sound until decayed regardless of
+// note offs, and an open hi-hat is choked by a closed one.
+type DrumKit struct {
+	*mono
+	kit  map[int]drum
+	hits []*hit
+	rnd  *rand.Rand
+}
+
+func NewDrumKit() *DrumKit {
+	return &DrumKit{mono: newmono(nil), kit: gmdrums(), rnd: rand.New(rand.NewSource(1))}
+}
+
+func (dk *DrumKit) Inputs() []Sound { return nil }
+
+// NoteOn strikes the drum of key at velocity vel belonging to [0..1].
+func (dk *DrumKit) NoteOn(key int, vel float64) {
+	dr, ok := dk.kit[key]
+	if !ok {
+		return
+	}
+	if dr.choke != 0 {
+		for _, h := range dk.hits {
+			if h.choke == dr.choke {
+				h.tamp, h.nam = 0, 0
+			}
+		}
+	}
+	h := &hit{drum: dr, vel: vel, hz: dr.hz0, tamp: 1, nam: dr.noise}
+	if dr.hz0 == 0 {
+		h.tamp = 0
+	}
+	if dr.cutoff > 0 {
+		h.f.set(dr.cutoff, 1, dk.sr)
+	}
+	dk.hits = append(dk.hits, h)
+}
+
+func (dk *DrumKit) NoteOff(int) {}
+
+func (dk *DrumKit) Pressure(float64) {}
+func (dk *DrumKit) Bend(float64)     {}
+
+func (dk *DrumKit) Prepare(uint64) {
+	for i := range dk.out {
+		dk.out[i] = 0
+	}
+	const quiet = 1e-4
+	live := dk.hits[:0]
+	for _, h := range dk.hits {
+		ct, cn, cs := smoothcoef(h.tone, dk.sr), smoothcoef(h.ndecay, dk.sr), smoothcoef(h.sweep, dk.sr)
+		for i := range dk.out {
+			x := h.tamp * math.Sin(twopi*h.phase)
+			h.phase += h.hz / dk.sr
+			h.hz += cs * (h.hz1 - h.hz)
+			h.tamp -= ct * h.tamp
+			if h.nam > 0 {
+				n := h.nam * (2*dk.rnd.Float64() - 1)
+				if h.cutoff > 0 {
+					n = h.f.filter(n, h.typ)
+				}
+				x += n
+				h.nam -= cn * h.nam
+			}
+			if !dk.off {
+				dk.out[i] += 0.5 * h.vel * x
+			}
+		}
+		if h.tamp > quiet || h.nam > quiet {
+			live = append(live, h)
+		}
+	}
+	for i := len(live); i < len(dk.hits); i++ {
+		dk.hits[i] = nil
+	}
+	dk.hits = live
+}
+
+// Bank plays sixteen MIDI channels of ready-made instruments selected by
+// program number, with General MIDI percussion on DrumChannel, so that a MIDI
+// file or controller sounds musical without building instruments first.
+//
+// Instruments are created as channels are first played and prepared by the
+// Bank itself, so programs may change while running without notifying
+// backends. Bank is safe to play from any goroutine.
+type Bank struct {
+	*mono
+	voices int
+	chs    [16]*BankChannel
+
+	mu    sync.Mutex
+	mix   *Mixer
+	inps  []*Input
+	dirty bool
+	dp    Dispatcher
+}
+
+// NewBank returns Bank of instruments with voices each.
+func NewBank(voices int) *Bank {
+	bk := &Bank{mono: newmono(nil), voices: voices, mix: NewMixer(), dirty: true}
+	for i := range bk.chs {
+		bk.chs[i] = &BankChannel{bk: bk, drums: i == DrumChannel}
+	}
+	return bk
+}
+
+func (bk *Bank) Inputs() []Sound { return nil }
+
+// Panic silences the instruments of bk, as Dispatcher.Panic.
+func (bk *Bank) Panic() { bk.dp.Panic() }
+
+// Channel returns channel ch of bk, zero based.
+func (bk *Bank) Channel(ch int) *BankChannel { return bk.chs[ch&0x0F] }
+
+func (bk *Bank) Prepare(tc uint64) {
+	bk.mu.Lock()
+	defer bk.mu.Unlock()
+	for _, ch := range bk.chs {
+		ch.retire()
+	}
+	if bk.dirty {
+		bk.inps, bk.dirty = GetInputs(bk.mix), false
+	}
+	bk.dp.up = bk.st // instruments play as set of the graph of bk
+	bk.dp.Dispatch(tc, bk.inps...)
+	for i, x := range bk.mix.Samples() {
+		if bk.off {
+			x = 0
+		}
+		bk.out[i] = x
+	}
+}
+
+// BankChannel is an Expressive instrument of a Bank playing its current
+// program. A program change takes effect on the next note; notes held on the
+// previous program sound until released.
+type BankChannel struct {
+	bk    *Bank
+	drums bool
+	prog  int
+	inst  interface {
+		Sound
+		Expressive
+	}
+	old []*Poly // previous programs releasing notes
+}
+
+// Program returns the program number of ch, zero based.
+func (ch *BankChannel) Program() int { return ch.prog }
+
+// SetProgram selects program number prog, zero based, ignored on the
+// percussion channel.
+func (ch *BankChannel) SetProgram(prog int) {
+	ch.bk.mu.Lock()
+	defer ch.bk.mu.Unlock()
+	if ch.drums || prog == ch.prog {
+		return
+	}
+	ch.prog = prog
+	if p, ok := ch.inst.(*Poly); ok {
+		ch.old = append(ch.old, p)
+		ch.inst = nil
+	}
+}
+
+// instrument returns the instrument of ch, creating it as needed.
+func (ch *BankChannel) instrument() Expressive {
+	if ch.inst == nil {
+		if ch.drums {
+			ch.inst = NewDrumKit()
+		} else {
+			ch.inst = Program(ch.prog, ch.bk.voices)
+		}
+		ch.bk.mix.Append(ch.inst)
+		ch.bk.dirty = true
+	}
+	return ch.inst
+}
+
+// retire removes previous programs once all their notes are done.
+func (ch *BankChannel) retire() {
+	old := ch.old[:0]
+	for _, p := range ch.old {
+		if p.Active() > 0 {
+			old = append(old, p)
+			continue
+		}
+		ins := ch.bk.mix.ins[:0]
+		for _, in := range ch.bk.mix.ins {
+			if in != Sound(p) {
+				ins = append(ins, in)
+			}
+		}
+		ch.bk.mix.ins, ch.bk.dirty = ins, true
+	}
+	ch.old = old
+}
+
+func (ch *BankChannel) NoteOn(key int, vel float64) {
+	ch.bk.mu.Lock()
+	defer ch.bk.mu.Unlock()
+	ch.instrument().NoteOn(key, vel)
+}
+
+// NoteOff releases key on the current program and any previous.
+func (ch *BankChannel) NoteOff(key int) {
+	ch.bk.mu.Lock()
+	defer ch.bk.mu.Unlock()
+	for _, p := range ch.old {
+		p.NoteOff(key)
+	}
+	if ch.inst != nil {
+		ch.inst.NoteOff(key)
+	}
+}
+
+func (ch *BankChannel) Pressure(x float64) {
+	ch.bk.mu.Lock()
+	defer ch.bk.mu.Unlock()
+	ch.instrument().Pressure(x)
+}
+
+func (ch *BankChannel) Bend(semitones float64) {
+	ch.bk.mu.Lock()
+	defer ch.bk.mu.Unlock()
+	ch.instrument().Bend(semitones)
+}
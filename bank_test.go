@@ -0,0 +1,68 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgram(t *testing.T) {
+	sr := DefaultSampleRate
+	for _, prog := range []int{0, 4, 16, 24, 32, 48, 80, 127} {
+		p := Program(prog, 4)
+		p.NoteOn(60, 1)
+		p.NoteOn(64, 1)
+		p.NoteOn(67, 1)
+		out := Render(p, Dtof(500*time.Millisecond, sr))
+		if pk := Peak(out); pk < 0.05 || pk > 1 {
+			t.Fatalf("program %v: have peak %v, want sound without clipping", prog, pk)
+		}
+	}
+	if tn := Program(0, 1).Tuning(); tn == nil || tn.Freq(108, 440) <= KeyFreq(108) {
+		t.Fatal("have piano without stretch tuning")
+	}
+}
+
+func TestDrumKit(t *testing.T) {
+	sr := DefaultSampleRate
+	dk := NewDrumKit()
+	for _, key := range []int{36, 38, 42, 46, 49, 51, 45} {
+		dk.NoteOn(key, 1)
+		if pk := Peak(Render(dk, Dtof(50*time.Millisecond, sr))); pk < 0.05 {
+			t.Fatalf("key %v: have peak %v, want a hit", key, pk)
+		}
+	}
+	Render(dk, Dtof(15*time.Second, sr))
+	if n := len(dk.hits); n != 0 {
+		t.Fatalf("have %v hits after decay, want none", n)
+	}
+	dk.NoteOn(46, 1)
+	dk.NoteOn(42, 1)
+	if n := len(dk.hits); n != 2 || dk.hits[0].nam != 0 {
+		t.Fatalf("have open hat level %v, want choked by closed hat", dk.hits[0].nam)
+	}
+}
+
+func TestBank(t *testing.T) {
+	sr := DefaultSampleRate
+	bk := NewBank(4)
+	if pk := Peak(Render(bk, DefaultBufferLen)); pk != 0 {
+		t.Fatalf("have peak %v unplayed, want silence", pk)
+	}
+	bk.Channel(0).NoteOn(60, 1)
+	bk.Channel(DrumChannel).NoteOn(36, 1)
+	if pk := Peak(Render(bk, Dtof(100*time.Millisecond, sr))); pk < 0.05 {
+		t.Fatalf("have peak %v, want sound", pk)
+	}
+
+	ch := bk.Channel(0)
+	ch.SetProgram(32)
+	ch.NoteOn(40, 1)
+	if n := len(bk.mix.ins); n != 3 {
+		t.Fatalf("have %v instruments, want previous program kept while held", n)
+	}
+	ch.NoteOff(60)
+	Render(bk, Dtof(time.Second, sr))
+	if n := len(bk.mix.ins); n != 2 {
+		t.Fatalf("have %v instruments, want previous program retired once released", n)
+	}
+}
@@ -0,0 +1,187 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"dasa.cc/snd/wav"
+)
+
+// Normalization selects how each file of a Batch is leveled.
+type Normalization int
+
+const (
+	// NormalizeNone leaves level as processed.
+	NormalizeNone Normalization = iota
+
+	// NormalizePeak sets the peak of each file to the target in dBFS.
+	NormalizePeak
+
+	// NormalizeLoudness sets integrated loudness of each file to the target
+	// in LUFS, such as -23 for broadcast, reducing gain further if need be so
+	// the peak doesn't exceed full scale. Files shorter than a gating block
+	// of 400ms are normalized by peak.
+	NormalizeLoudness
+)
+
+// Batch processes WAVE files offline through a chain of effects, decoding
+// each, rendering it through the chain, and encoding the result, such as to
+// prepare a sample library. Files are processed concurrently.
+type Batch struct {
+	// Proc returns the chain applied to a Player of each file, called once
+	// for each so must return new sounds each call. A nil Proc passes files
+	// through, such as to only normalize.
+	Proc ProcFunc
+
+	// Tail is rendered after the end of each file for tails of effects such
+	// as reverb and delay to ring out.
+	Tail time.Duration
+
+	// Normalize selects how each file is leveled to Target.
+	Normalize Normalization
+	Target    Decibel
+
+	// Depth is the bit depth of files written, as given to wav.NewWriter. Zero
+	// keeps the depth of each file read where it can be written, else 32.
+	Depth int
+
+	// Workers is the number of files processed at once, runtime.NumCPU if
+	// not positive.
+	Workers int
+
+	// Progress, if not nil, is called after each file is processed with the
+	// number of files done of total and any error of name. Calls are made one
+	// at a time.
+	Progress func(done, total int, name string, err error)
+}
+
+// Dir processes every WAVE file of directory in, writing results of the same
+// names to directory out, which is created if need be.
+func (bt Batch) Dir(in, out string) error {
+	ents, err := os.ReadDir(in)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, ent := range ents {
+		if !ent.IsDir() && strings.EqualFold(filepath.Ext(ent.Name()), ".wav") {
+			names = append(names, filepath.Join(in, ent.Name()))
+		}
+	}
+	return bt.Run(out, names...)
+}
+
+// Run processes files of names, writing results of the same base names to
+// directory out, which is created if need be. Every file is attempted; the
+// first error is returned.
+func (bt Batch) Run(out string, names ...string) error {
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	workers := bt.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var (
+		mu    sync.Mutex
+		done  int
+		first error
+		wg    sync.WaitGroup
+	)
+	work := make(chan string)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				err := bt.file(name, filepath.Join(out, filepath.Base(name)))
+				mu.Lock()
+				done++
+				if err != nil && first == nil {
+					first = err
+				}
+				if bt.Progress != nil {
+					bt.Progress(done, len(names), name, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		work <- name
+	}
+	close(work)
+	wg.Wait()
+	return first
+}
+
+// file processes the file of name, writing the result to dst.
+func (bt Batch) file(name, dst string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	sig, format, err := wav.Decode(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("snd: %s: %v", name, err)
+	}
+	if format.Chans < 1 {
+		return fmt.Errorf("snd: %s: no channels", name)
+	}
+
+	// the graph runs at its own rate and the file is written at its rate.
+	srcsr := float64(format.Rate)
+	sr := float64(DefaultSampleRate)
+	in := Resample(sig, format.Chans, srcsr, sr)
+	var sd Sound = NewPlayer(in, format.Chans, sr)
+	if bt.Proc != nil {
+		sd = bt.Proc(sd)
+	}
+	chans := sd.Channels()
+	res := Render(sd, len(in)/format.Chans+Dtof(bt.Tail, sr))
+	res = Resample(res, chans, sd.SampleRate(), srcsr)
+
+	switch bt.Normalize {
+	case NormalizePeak:
+		res.level(bt.Target, Peak(res))
+	case NormalizeLoudness:
+		peak := Peak(res)
+		if lufs := Loudness(res, chans, srcsr); !math.IsInf(lufs, -1) {
+			gain := Decibel(float64(bt.Target) - lufs)
+			// no louder than full scale.
+			if hr := -DecibelOf(peak); gain > hr {
+				gain = hr
+			}
+			res.level(gain, 1)
+		} else {
+			res.level(bt.Target, peak)
+		}
+	}
+
+	depth := bt.Depth
+	if depth == 0 {
+		switch depth = format.Depth; {
+		case format.Float, depth != 16 && depth != 24:
+			depth = 32
+		}
+	}
+	return writestem(dst, res, chans, format.Rate, depth)
+}
+
+// level scales sig so amplitude ref is at db.
+func (sig Discrete) level(db Decibel, ref float64) {
+	if ref == 0 {
+		return
+	}
+	g := db.Amp() / ref
+	for i := range sig {
+		sig[i] *= g
+	}
+}
@@ -0,0 +1,71 @@
+package snd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dasa.cc/snd/wav"
+)
+
+func TestBatch(t *testing.T) {
+	in, out := t.TempDir(), filepath.Join(t.TempDir(), "out")
+	osc := NewOscil(Sine(), 440, nil)
+	for i, name := range []string{"a.wav", "b.wav"} {
+		sig := Render(osc, 4410*(i+1))
+		if err := writestem(filepath.Join(in, name), sig, 1, 48000, 16); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(in, "notes.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var done []string
+	bt := Batch{
+		Proc:      func(in Sound) Sound { return NewPan(0, NewGain(0.5, in)) },
+		Normalize: NormalizePeak,
+		Target:    -6,
+		Workers:   2,
+		Progress: func(n, total int, name string, err error) {
+			if err != nil {
+				t.Errorf("%s: %v", name, err)
+			}
+			if total != 2 {
+				t.Errorf("have total %v, want 2", total)
+			}
+			done = append(done, name)
+		},
+	}
+	if err := bt.Dir(in, out); err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != 2 {
+		t.Fatalf("have %v files done, want 2", len(done))
+	}
+	for i, name := range []string{"a.wav", "b.wav"} {
+		f, err := os.Open(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, format, err := wav.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format.Chans != 2 || format.Rate != 48000 || format.Depth != 16 {
+			t.Fatalf("%s: have format %+v, want stereo 16 bit at 48kHz", name, format)
+		}
+		if nfr, want := len(sig)/2, 4410*(i+1); nfr < want-2 || nfr > want+2 {
+			t.Fatalf("%s: have %v frames, want %v", name, nfr, want)
+		}
+		if db := DecibelOf(Peak(sig)); !equaleps(float64(db), -6, 0.01) {
+			t.Fatalf("%s: have peak %v, want -6dB", name, db)
+		}
+	}
+
+	bt.Progress = nil
+	if err := bt.Run(out, filepath.Join(in, "missing.wav")); err == nil {
+		t.Fatal("have nil error of missing file")
+	}
+}
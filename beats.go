@@ -0,0 +1,167 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Modes of Beats.
+const (
+	// BeatsBinaural plays carriers apart by the beat frequency, one to each
+	// ear, beating only as heard on headphones.
+	BeatsBinaural = iota
+
+	// BeatsMonaural plays both carriers to both ears, beating in the air.
+	BeatsMonaural
+
+	// BeatsIsochronic plays the carrier to both ears in pulses at the beat
+	// frequency, needing no headphones.
+	BeatsIsochronic
+)
+
+// BeatStage is a stage of a schedule of Beats: carrier and beat frequencies
+// ramp linearly from those of the stage before over Ramp, then hold over
+// Hold.
+type BeatStage struct {
+	Carrier, Beat float64 // Hz
+	Ramp, Hold    time.Duration
+}
+
+// Beats is a stereo generator of binaural, monaural, or isochronic beats, as
+// of apps of meditation and focus: a carrier tone beating at a low frequency,
+// such as 200Hz at 10Hz. A schedule of stages ramps carrier and beat over a
+// session, such as from 10Hz down to 4Hz over twenty minutes.
+type Beats struct {
+	*mono
+	mode          int
+	carrier, beat float64
+	amp           float64
+	duty          float64
+
+	stages      []BeatStage
+	stage       int     // of stages playing, len(stages) once done
+	at          int     // frames into stage
+	from, fromb float64 // carrier and beat at the start of stage
+
+	phl, phr, phb float64 // phases of left and right carriers and of beat
+}
+
+// NewBeats returns Beats of mode, such as BeatsBinaural, of carrier and beat
+// in Hz at -6dB, pulsing isochronic beats half of each period.
+func NewBeats(mode int, carrier, beat float64) *Beats {
+	bt := &Beats{mono: newmono(nil), mode: mode, carrier: carrier, beat: beat, amp: 0.5, duty: 0.5}
+	bt.out = make(Discrete, 2*len(bt.out))
+	return bt
+}
+
+func (bt *Beats) Channels() int   { return 2 }
+func (bt *Beats) Inputs() []Sound { return nil }
+
+func (bt *Beats) Mode() int        { return bt.mode }
+func (bt *Beats) SetMode(mode int) { bt.mode = mode }
+
+// Carrier returns frequency of the carrier in Hz, between those of both ears
+// of binaural and monaural beats.
+func (bt *Beats) Carrier() float64 { return bt.carrier }
+
+// SetCarrier sets frequency of the carrier, cancelling any schedule.
+func (bt *Beats) SetCarrier(hz float64) {
+	bt.carrier = hz
+	bt.stages = nil
+}
+
+// Beat returns frequency of beating in Hz.
+func (bt *Beats) Beat() float64 { return bt.beat }
+
+// SetBeat sets frequency of beating, cancelling any schedule.
+func (bt *Beats) SetBeat(hz float64) {
+	bt.beat = hz
+	bt.stages = nil
+}
+
+// Amp returns amplitude of each carrier.
+func (bt *Beats) Amp() float64     { return bt.amp }
+func (bt *Beats) SetAmp(x float64) { bt.amp = x }
+
+// Duty returns the part of each period isochronic beats pulse, belonging to
+// (0..1].
+func (bt *Beats) Duty() float64 { return bt.duty }
+
+func (bt *Beats) SetDuty(x float64) { bt.duty = math.Max(0.01, math.Min(1, x)) }
+
+// SetSchedule plays stages in order from the current carrier and beat,
+// holding those of the last stage once done.
+func (bt *Beats) SetSchedule(stages ...BeatStage) {
+	bt.stages = append([]BeatStage(nil), stages...)
+	bt.stage, bt.at = 0, 0
+	bt.from, bt.fromb = bt.carrier, bt.beat
+}
+
+// Scheduled reports whether a schedule is playing and not yet done.
+func (bt *Beats) Scheduled() bool { return bt.stage < len(bt.stages) }
+
+func (bt *Beats) Params() []*Param {
+	return []*Param{
+		NewParam("carrier", bt.Carrier, bt.SetCarrier).Range(20, 1000, 200).In(UnitHz),
+		NewParam("beat", bt.Beat, bt.SetBeat).Range(0.5, 40, 10).In(UnitHz),
+		NewParam("duty", bt.Duty, bt.SetDuty).Range(0.01, 1, 0.5).In(UnitPercent),
+	}
+}
+
+// schedule advances the schedule a frame, setting carrier and beat.
+func (bt *Beats) schedule() {
+	for bt.stage < len(bt.stages) {
+		st := bt.stages[bt.stage]
+		ramp, hold := Dtof(st.Ramp, bt.sr), Dtof(st.Hold, bt.sr)
+		if bt.at < ramp {
+			t := float64(bt.at) / float64(ramp)
+			bt.carrier = bt.from + t*(st.Carrier-bt.from)
+			bt.beat = bt.fromb + t*(st.Beat-bt.fromb)
+			bt.at++
+			return
+		}
+		bt.carrier, bt.beat = st.Carrier, st.Beat
+		if bt.at < ramp+hold {
+			bt.at++
+			return
+		}
+		bt.stage, bt.at = bt.stage+1, 0
+		bt.from, bt.fromb = st.Carrier, st.Beat
+	}
+}
+
+// osc returns the sine of phase ph advanced by hz.
+func (bt *Beats) osc(ph *float64, hz float64) float64 {
+	x := math.Sin(twopi * *ph)
+	*ph += hz / bt.sr
+	*ph -= math.Floor(*ph)
+	return x
+}
+
+func (bt *Beats) Prepare(uint64) {
+	for i := 0; i < len(bt.out); i += 2 {
+		bt.schedule()
+		var l, r float64
+		switch bt.mode {
+		case BeatsBinaural:
+			l = bt.osc(&bt.phl, bt.carrier-bt.beat/2)
+			r = bt.osc(&bt.phr, bt.carrier+bt.beat/2)
+		case BeatsMonaural:
+			l = (bt.osc(&bt.phl, bt.carrier-bt.beat/2) + bt.osc(&bt.phr, bt.carrier+bt.beat/2)) / 2
+			r = l
+		case BeatsIsochronic:
+			// pulses of raised cosine, not to click.
+			var g float64
+			if p := bt.phb / bt.duty; p < 1 {
+				g = 0.5 - 0.5*math.Cos(twopi*p)
+			}
+			bt.osc(&bt.phb, bt.beat)
+			l = g * bt.osc(&bt.phl, bt.carrier)
+			r = l
+		}
+		if bt.off {
+			l, r = 0, 0
+		}
+		bt.out[i], bt.out[i+1] = bt.amp*l, bt.amp*r
+	}
+}
@@ -0,0 +1,68 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBeatsBinaural(t *testing.T) {
+	bt := NewBeats(BeatsBinaural, 200, 10)
+	out := Render(bt, int(bt.SampleRate())) // a second
+	l, r := make(Discrete, len(out)/2), make(Discrete, len(out)/2)
+	for i := range l {
+		l[i], r[i] = out[2*i], out[2*i+1]
+	}
+	if lhz, rhz := crossings(l)/2, crossings(r)/2; math.Abs(lhz-195) > 1 || math.Abs(rhz-205) > 1 {
+		t.Errorf("have left %vHz right %vHz, want 195Hz 205Hz", lhz, rhz)
+	}
+	if pk := Peak(out); !equaleps(pk, 0.5, 0.01) {
+		t.Errorf("have peak %v, want 0.5", pk)
+	}
+}
+
+func TestBeatsIsochronic(t *testing.T) {
+	bt := NewBeats(BeatsIsochronic, 400, 10)
+	out := Render(bt, int(bt.SampleRate()))
+	// each pulse of half a period is followed by silence.
+	pulses, zeros := 0, 20
+	for i := 0; i < len(out); i += 2 {
+		if out[i] != out[i+1] {
+			t.Fatalf("have left %v right %v at %v, want equal", out[i], out[i+1], i/2)
+		}
+		if out[i] != 0 {
+			if zeros >= 20 {
+				pulses++
+			}
+			zeros = 0
+		} else {
+			zeros++
+		}
+	}
+	if pulses != 10 {
+		t.Errorf("have %v pulses, want 10", pulses)
+	}
+}
+
+func TestBeatsSchedule(t *testing.T) {
+	bt := NewBeats(BeatsBinaural, 200, 10)
+	bt.SetSchedule(
+		BeatStage{Carrier: 200, Beat: 4, Ramp: 100 * time.Millisecond, Hold: 50 * time.Millisecond},
+		BeatStage{Carrier: 300, Beat: 4, Ramp: 50 * time.Millisecond},
+	)
+	Render(bt, Dtof(50*time.Millisecond, bt.SampleRate()))
+	if b := bt.Beat(); b <= 4 || b >= 10 {
+		t.Errorf("have beat %v ramping, want between 4 and 10", b)
+	}
+	Render(bt, Dtof(250*time.Millisecond, bt.SampleRate()))
+	if bt.Scheduled() {
+		t.Error("have scheduled, want done")
+	}
+	if c, b := bt.Carrier(), bt.Beat(); c != 300 || b != 4 {
+		t.Errorf("have carrier %v beat %v, want 300 4", c, b)
+	}
+	bt.SetBeat(8)
+	if bt.Scheduled() {
+		t.Error("have scheduled after SetBeat, want cancelled")
+	}
+}
@@ -0,0 +1,76 @@
+package snd
+
+import "math"
+
+// BLIT is a band-limited impulse train, a pulse each cycle of every harmonic
+// below nyquist summed in closed form, so it has no aliasing at any frequency
+// without oversampling. It is a building block of band-limited waveforms: a
+// leaky integrator of a BLIT less its mean, the frequency over sample rate,
+// is a sawtooth, and of a BLIT less one delayed half a cycle a square.
+//
+// Each pulse has an area of one frame, peaking near one when all harmonics
+// below nyquist are summed.
+type BLIT struct {
+	*mono
+	freq    float64
+	freqmod Sound
+	max     int     // harmonics summed at most, zero for all below nyquist
+	phase   float64 // in cycles
+}
+
+// NewBLIT returns BLIT at freq multiplied by freqmod, if not nil.
+func NewBLIT(freq float64, freqmod Sound) *BLIT {
+	return &BLIT{mono: newmono(nil), freq: freq, freqmod: freqmod}
+}
+
+func (bl *BLIT) Freq() float64   { return bl.freq }
+func (bl *BLIT) FreqMod() Sound  { return bl.freqmod }
+func (bl *BLIT) Inputs() []Sound { return []Sound{bl.freqmod} }
+
+func (bl *BLIT) SetFreq(hz float64, mod Sound) { bl.freq, bl.freqmod = hz, mod }
+
+// SetHarmonics limits harmonics summed to n, such as for a duller pulse, or
+// with n of zero sums all below nyquist.
+func (bl *BLIT) SetHarmonics(n int) { bl.max = n }
+
+// Harmonics returns harmonics summed at most, zero for all below nyquist.
+func (bl *BLIT) Harmonics() int { return bl.max }
+
+func (bl *BLIT) Params() []*Param {
+	return []*Param{
+		NewParam("freq", bl.Freq, func(x float64) { bl.freq = x }).Range(0, 20000, 440).In(UnitHz),
+	}
+}
+
+// Panic resets phase.
+func (bl *BLIT) Panic() { bl.phase = 0 }
+
+func (bl *BLIT) Prepare(uint64) {
+	for i := range bl.out {
+		hz := bl.freq
+		if bl.freqmod != nil {
+			hz *= bl.freqmod.Index(i)
+		}
+		hz = math.Abs(hz)
+		if hz == 0 || bl.off {
+			bl.out[i] = 0
+			continue
+		}
+		// period p in frames sums m, an odd count, of harmonics and dc.
+		p := bl.sr / hz
+		n := int(p / 2)
+		if bl.max > 0 && bl.max < n {
+			n = bl.max
+		}
+		m := float64(2*n + 1)
+
+		den := p * math.Sin(math.Pi*bl.phase)
+		if math.Abs(den) < 1e-9 {
+			bl.out[i] = m / p
+		} else {
+			bl.out[i] = math.Sin(math.Pi*m*bl.phase) / den
+		}
+		bl.phase += 1 / p
+		bl.phase -= math.Floor(bl.phase)
+	}
+}
@@ -0,0 +1,62 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+// inharmonic returns the level of spectrum of sig at sample rate sr away from
+// harmonics of hz relative to all of it, as of aliases folded between them.
+func inharmonic(sig Discrete, hz, sr float64) Decibel {
+	n := len(sig)
+	re, im := make([]float64, n), make([]float64, n)
+	for i := range re {
+		re[i] = sig[i] * (0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n)))
+	}
+	fft(re, im, false)
+	var away, total float64
+	for i := 1; i <= n/2; i++ {
+		p := re[i]*re[i] + im[i]*im[i]
+		total += p
+		f := float64(i) * sr / float64(n)
+		if k := math.Round(f / hz); math.Abs(f-k*hz) > 8*sr/float64(n) {
+			away += p
+		}
+	}
+	return Decibel(10 * math.Log10(away/total))
+}
+
+func TestBLIT(t *testing.T) {
+	const sr, hz, n = DefaultSampleRate, 3001, 16384
+	bl := NewBLIT(hz, nil)
+	out := Render(bl, n)
+
+	// a pulse of unit area each period.
+	var sum float64
+	for _, x := range out {
+		sum += x
+	}
+	if mean := sum / n; !equaleps(mean, hz/sr, 1e-4) {
+		t.Fatalf("have mean %v, want %v", mean, hz/sr)
+	}
+
+	// a naive train rounds each pulse to the nearest frame, aliasing.
+	naive := make(Discrete, n)
+	for k := 0.0; ; k++ {
+		i := int(math.Round(k * sr / hz))
+		if i >= n {
+			break
+		}
+		naive[i] = 1
+	}
+	blit, alias := inharmonic(out, hz, sr), inharmonic(naive, hz, sr)
+	if blit > -60 || alias < -20 {
+		t.Fatalf("have inharmonic level %v of BLIT and %v of naive train, want BLIT under -60dB", blit, alias)
+	}
+
+	bl = NewBLIT(hz, nil)
+	bl.SetHarmonics(2)
+	if db := AliasLevel(Render(bl, n), sr, 2.5*hz); db > -80 {
+		t.Fatalf("have level %v above second harmonic, want none", db)
+	}
+}
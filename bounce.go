@@ -0,0 +1,112 @@
+package snd
+
+import "time"
+
+// Bounce renders a graph offline, limited to a range of beats of a Transport
+// and to selected sounds, such as to freeze a track or export stems from an
+// editor. The graph must not be playing while bounced.
+type Bounce struct {
+	// Sound is the output of the graph rendered.
+	Sound Sound
+
+	// Transport, if not nil, plays from beat From until beat To while
+	// rendering, and is returned to its prior position and state after.
+	// Without a Transport, Dur is rendered.
+	Transport *Transport
+	From, To  float64
+	Dur       time.Duration
+
+	// Solo, if not empty, mutes all sounds of the graph other than those
+	// selected, sounds they depend on, and sounds they pass through on the
+	// way to Sound, by turning off sounds that may be turned off. Sounds are
+	// returned to their prior state after.
+	Solo []Sound
+
+	// Dispatcher, if not nil, renders the graph calling its hooks, such as
+	// of an Automation.
+	Dispatcher *Dispatcher
+}
+
+// Render returns interleaved samples of the bounce.
+func (bc Bounce) Render() Discrete {
+	if len(bc.Solo) != 0 {
+		defer solo(bc.Sound, bc.Solo)()
+	}
+	dur := bc.Dur
+	if tp := bc.Transport; tp != nil {
+		beat, frame, playing := tp.beat, tp.frame, tp.playing
+		defer func() {
+			tp.frame, tp.playing = frame, playing
+			tp.Seek(beat)
+		}()
+		tp.Seek(bc.From)
+		tp.Play()
+		dur = time.Duration((bc.To - bc.From) / float64(tp.BPM()) * float64(time.Minute))
+	}
+	dp := bc.Dispatcher
+	if dp == nil {
+		dp = new(Dispatcher)
+	}
+	if dur <= 0 {
+		return nil
+	}
+	return dp.Render(bc.Sound, Dtof(dur, bc.Sound.SampleRate()))
+}
+
+// WriteFile renders the bounce and writes it to a WAVE file of name, with
+// sample depth as given to wav.NewWriter.
+func (bc Bounce) WriteFile(name string, depth int) error {
+	sd := bc.Sound
+	return writestem(name, bc.Render(), sd.Channels(), int(sd.SampleRate()), depth)
+}
+
+// solo turns off sounds of the graph of sd other than sel, their inputs, and
+// sounds between them and sd, returning a func turning them back on.
+func solo(sd Sound, sel []Sound) (restore func()) {
+	// upstream returns all sounds reached by inputs of x, including x.
+	upstream := func(x Sound) map[Sound]bool {
+		seen := map[Sound]bool{x: true}
+		stack := []Sound{x}
+		for len(stack) != 0 {
+			x := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, in := range x.Inputs() {
+				if in != nil && !seen[in] {
+					seen[in] = true
+					stack = append(stack, in)
+				}
+			}
+		}
+		return seen
+	}
+	keep := make(map[Sound]bool)
+	for _, s := range sel {
+		for x := range upstream(s) {
+			keep[x] = true
+		}
+	}
+	var muted []switcher
+	for _, inp := range GetInputs(sd) {
+		if keep[inp.sd] {
+			continue
+		}
+		// sounds selected sounds pass through are kept.
+		through := false
+		up := upstream(inp.sd)
+		for _, s := range sel {
+			if up[s] {
+				through = true
+				break
+			}
+		}
+		if sw, ok := inp.sd.(switcher); ok && !through && !sw.IsOff() {
+			sw.Off()
+			muted = append(muted, sw)
+		}
+	}
+	return func() {
+		for _, sw := range muted {
+			sw.On()
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package snd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBounceSolo(t *testing.T) {
+	sr := DefaultSampleRate
+	a := NewOscil(Sine(), 440, nil)
+	b := NewOscil(Sine(), 1000, nil)
+	ga, gb := NewGain(0.5, a), NewGain(0.5, b)
+	mix := NewMixer(ga, gb)
+
+	out := Bounce{Sound: mix, Dur: 100 * time.Millisecond, Solo: []Sound{ga}}.Render()
+	if n := len(out); n != Dtof(100*time.Millisecond, sr) {
+		t.Fatalf("have %v samples, want 100ms", n)
+	}
+	if x, y := goertzel(out, 440, sr), goertzel(out, 1000, sr); x < 0.1 || y > 1e-6 {
+		t.Fatalf("have energy %v at 440Hz and %v at 1000Hz, want only soloed 440Hz", x, y)
+	}
+	if b.IsOff() || gb.IsOff() || mix.IsOff() {
+		t.Fatal("have sounds off after bounce, want restored")
+	}
+}
+
+func TestBounceRange(t *testing.T) {
+	sr := DefaultSampleRate
+	tp := NewTransport(120)
+	tp.Seek(7)
+	mix := NewMixer(tp, NewOscil(Sine(), 440, nil))
+	bc := Bounce{Sound: mix, Transport: tp, From: 2, To: 4}
+	if out := bc.Render(); len(out) != Dtof(time.Second, sr) {
+		t.Fatalf("have %v samples, want a second of two beats", len(out))
+	}
+	if tp.Beat() != 7 || tp.Playing() || tp.Frame() != 0 {
+		t.Fatalf("have beat %v playing %v frame %v after bounce, want restored", tp.Beat(), tp.Playing(), tp.Frame())
+	}
+
+	name := filepath.Join(t.TempDir(), "bounce.wav")
+	if err := bc.WriteFile(name, 16); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(name); err != nil || fi.Size() < int64(2*Dtof(time.Second, sr)) {
+		t.Fatalf("have file %v, %v, want a second of 16 bit samples", fi, err)
+	}
+}
@@ -0,0 +1,52 @@
+package snd
+
+// Chainer builds a graph by applying processors in series to an input.
+//
+//  out := Chain(osc).Then(reverb).LowPass(800).Pan(-0.3).Out()
+//
+// Parallel branches are mixed with Par.
+type Chainer struct {
+	sd Sound
+}
+
+// Chain starts a chain from sd.
+func Chain(sd Sound) *Chainer { return &Chainer{sd} }
+
+// Then applies fn to the current end of the chain.
+func (ch *Chainer) Then(fn ProcFunc) *Chainer {
+	ch.sd = fn(ch.sd)
+	return ch
+}
+
+// Par applies each fn to the current end of the chain and mixes their results.
+// The dry signal is only included if one of fns passes it through, e.g. Dry.
+func (ch *Chainer) Par(fns ...ProcFunc) *Chainer {
+	mix := NewMixer()
+	for _, fn := range fns {
+		mix.Append(fn(ch.sd))
+	}
+	ch.sd = mix
+	return ch
+}
+
+func (ch *Chainer) Gain(a float64) *Chainer {
+	ch.sd = NewGain(a, ch.sd)
+	return ch
+}
+
+func (ch *Chainer) LowPass(freq float64) *Chainer {
+	ch.sd = NewLowPass(freq, ch.sd)
+	return ch
+}
+
+// Pan ends the mono portion of a chain by panning to stereo.
+func (ch *Chainer) Pan(xf float64) *Chainer {
+	ch.sd = NewPan(xf, ch.sd)
+	return ch
+}
+
+// Out returns the end of the chain.
+func (ch *Chainer) Out() Sound { return ch.sd }
+
+// Dry is a ProcFunc that returns its input unaltered for use with Par.
+func Dry(in Sound) Sound { return in }
@@ -0,0 +1,17 @@
+package snd
+
+import "testing"
+
+func TestChain(t *testing.T) {
+	half := func(in Sound) Sound { return NewGain(0.5, in) }
+	out := Chain(newunit()).Par(Dry, half).Then(half).Pan(0).Out()
+	if out.Channels() != 2 {
+		t.Fatalf("have channels %v, want 2", out.Channels())
+	}
+
+	new(Dispatcher).Dispatch(1, GetInputs(out)...)
+	want := 0.75 * DefaultAmpFac * getpanfac(0)
+	if x := out.Samples()[0]; !equals(x, want) {
+		t.Fatalf("have %v, want %v", x, want)
+	}
+}
@@ -0,0 +1,249 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Clip is a region of recorded samples placed on a Timeline.
+type Clip struct {
+	// Sig is interleaved samples of Chans channels recorded at SampleRate,
+	// such as a decoded audio file, and may be shared by several clips.
+	Sig        Discrete
+	Chans      int
+	SampleRate float64
+
+	// Start is the beat of the timeline the clip starts at.
+	Start float64
+
+	// Offset is the time into Sig the clip starts playing from, and Length
+	// is the time played, or until the end of Sig if zero.
+	Offset, Length time.Duration
+
+	// Gain is the level of the clip, zero for as recorded.
+	Gain Decibel
+
+	// FadeIn and FadeOut are times of linear fades at either end.
+	FadeIn, FadeOut time.Duration
+}
+
+// NewClip returns Clip of all of sig with chans channels recorded at sample
+// rate sr, starting at beat start.
+func NewClip(sig Discrete, chans int, sr float64, start float64) *Clip {
+	return &Clip{Sig: sig, Chans: chans, SampleRate: sr, Start: start}
+}
+
+// Dur returns the time clip plays for.
+func (c *Clip) Dur() time.Duration {
+	all := Ftod(len(c.Sig)/c.Chans, c.SampleRate) - c.Offset
+	if c.Length > 0 && c.Length < all {
+		return c.Length
+	}
+	if all < 0 {
+		return 0
+	}
+	return all
+}
+
+// End returns the beat clip ends at with tempo bpm.
+func (c *Clip) End(bpm BPM) float64 {
+	return c.Start + c.Dur().Minutes()*float64(bpm)
+}
+
+// read returns channel ch of c at t seconds into the clip, by linear
+// interpolation.
+func (c *Clip) read(t float64, ch int) float64 {
+	pos := (c.Offset.Seconds() + t) * c.SampleRate
+	j := int(pos)
+	frac := pos - float64(j)
+	nfr := len(c.Sig) / c.Chans
+	if j < 0 || j >= nfr {
+		return 0
+	}
+	ch %= c.Chans
+	x := c.Sig[j*c.Chans+ch]
+	if frac > 0 && j+1 < nfr {
+		x += frac * (c.Sig[(j+1)*c.Chans+ch] - x)
+	}
+	return x
+}
+
+// fade returns the gain of fades of c at t seconds into the clip of dur
+// seconds.
+func (c *Clip) fade(t, dur float64) float64 {
+	g := 1.0
+	if in := c.FadeIn.Seconds(); t < in {
+		g = t / in
+	}
+	if out := c.FadeOut.Seconds(); dur-t < out {
+		g = math.Min(g, (dur-t)/out)
+	}
+	return math.Max(0, g)
+}
+
+// Timeline plays clips of recorded samples at their beats of a Transport,
+// such as to combine sequenced synths with pre-recorded material. Clips
+// follow position and seeking of the transport, but not its tempo, playing
+// at their recorded speed from the beat they start at.
+//
+// Clips are edits of a single track: where a clip starts before another ends,
+// the two crossfade with equal power over their overlap, and every clip
+// fades in and out over a short declick time so cuts don't pop. A clip
+// wholly within another is layered without crossfading.
+//
+// Output has the number of channels given to NewTimeline; channels of clips
+// repeat across those of output, so a mono clip plays on all channels. Clips
+// may be added and removed from any goroutine.
+type Timeline struct {
+	*mono
+	tp    *Transport
+	chans int
+
+	mu      sync.Mutex
+	clips   []*Clip
+	declick time.Duration
+	xfade   bool
+	active  []*Clip      // clips of the buffer being prepared
+	fades   [][2]float64 // beats of crossfades of active clips, as of overlaps
+}
+
+// NewTimeline returns Timeline with chans channels following tp.
+func NewTimeline(tp *Transport, chans int) *Timeline {
+	sd := newmono(nil)
+	sd.out = make(Discrete, len(tp.Samples())*chans)
+	return &Timeline{mono: sd, tp: tp, chans: chans, declick: 5 * time.Millisecond, xfade: true}
+}
+
+func (tl *Timeline) Channels() int   { return tl.chans }
+func (tl *Timeline) Inputs() []Sound { return []Sound{tl.tp} }
+
+// Add places c on tl. Fields of c must not change while on tl.
+func (tl *Timeline) Add(c *Clip) {
+	tl.mu.Lock()
+	tl.clips = append(tl.clips, c)
+	tl.mu.Unlock()
+}
+
+// Remove takes c off tl.
+func (tl *Timeline) Remove(c *Clip) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	for i, x := range tl.clips {
+		if x == c {
+			tl.clips = append(tl.clips[:i], tl.clips[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clips returns clips of tl in the order added.
+func (tl *Timeline) Clips() []*Clip {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return append([]*Clip(nil), tl.clips...)
+}
+
+// Declick returns the time of fades at both ends of every clip.
+func (tl *Timeline) Declick() time.Duration {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.declick
+}
+
+// SetDeclick sets the time of fades at both ends of every clip, 5ms by
+// default, or none if zero.
+func (tl *Timeline) SetDeclick(d time.Duration) {
+	tl.mu.Lock()
+	tl.declick = d
+	tl.mu.Unlock()
+}
+
+// Crossfade reports whether overlapping clips crossfade.
+func (tl *Timeline) Crossfade() bool {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+	return tl.xfade
+}
+
+// SetCrossfade sets whether overlapping clips crossfade, as by default, or
+// are layered.
+func (tl *Timeline) SetCrossfade(b bool) {
+	tl.mu.Lock()
+	tl.xfade = b
+	tl.mu.Unlock()
+}
+
+// overlaps returns beats of c at which a crossfade in from an earlier clip
+// ends and a crossfade out to a later clip starts, as c.Start and the end of
+// c if none; tl.mu must be held.
+func (tl *Timeline) overlaps(c *Clip, bpm BPM) (in, out float64) {
+	end := c.End(bpm)
+	in, out = c.Start, end
+	if !tl.xfade {
+		return in, out
+	}
+	for _, o := range tl.clips {
+		oend := o.End(bpm)
+		if o.Start < c.Start && oend > c.Start && oend <= end {
+			in = math.Max(in, oend)
+		}
+		if o.Start > c.Start && o.Start < end && oend >= end {
+			out = math.Min(out, o.Start)
+		}
+	}
+	return in, out
+}
+
+func (tl *Timeline) Prepare(uint64) {
+	for i := range tl.out {
+		tl.out[i] = 0
+	}
+	if tl.off || !tl.tp.Playing() {
+		return
+	}
+	bpm := float64(tl.tp.BPM())
+	frames := len(tl.out) / tl.chans
+	step := bpm / (60 * tl.sr)
+	last := tl.tp.Beat()
+	beat := last - float64(frames)*step
+
+	tl.mu.Lock()
+	tl.active, tl.fades = tl.active[:0], tl.fades[:0]
+	for _, c := range tl.clips {
+		if c.Start < last && c.End(tl.tp.BPM()) > beat {
+			tl.active = append(tl.active, c)
+			in, out := tl.overlaps(c, tl.tp.BPM())
+			tl.fades = append(tl.fades, [2]float64{in, out})
+		}
+	}
+	declick := tl.declick.Seconds()
+	tl.mu.Unlock()
+
+	for k, c := range tl.active {
+		amp := c.Gain.Amp()
+		dur := c.Dur().Seconds()
+		in, out := tl.fades[k][0], tl.fades[k][1]
+		end := c.End(tl.tp.BPM())
+		for i := 0; i < frames; i++ {
+			b := beat + float64(i)*step
+			t := (b - c.Start) * 60 / bpm
+			if t < 0 || t >= dur {
+				continue
+			}
+			g := amp * c.fade(t, dur)
+			if declick > 0 {
+				g *= math.Min(1, math.Min(t, dur-t)/declick)
+			}
+			if b < in {
+				g *= math.Sin(math.Pi / 2 * (b - c.Start) / (in - c.Start))
+			}
+			if b > out {
+				g *= math.Cos(math.Pi / 2 * (b - out) / (end - out))
+			}
+			for ch := 0; ch < tl.chans; ch++ {
+				tl.out[i*tl.chans+ch] += g * c.read(t, ch)
+			}
+		}
+	}
+}
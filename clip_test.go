@@ -0,0 +1,91 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeline(t *testing.T) {
+	sr := DefaultSampleRate
+	tp := NewTransport(120) // a beat each half second
+	tl := NewTimeline(tp, 2)
+	sig := make(Discrete, int(sr))
+	for i := range sig {
+		sig[i] = 1
+	}
+	// half a second from a quarter second in, at beat 1.
+	c := NewClip(sig, 1, sr, 1)
+	c.Offset, c.Length = 250*time.Millisecond, 500*time.Millisecond
+	c.Gain = -6
+	tl.Add(c)
+	if have, want := c.End(tp.BPM()), 2.0; have != want {
+		t.Fatalf("have end %v, want %v", have, want)
+	}
+
+	tp.Play()
+	out := Render(tl, Dtof(1500*time.Millisecond, sr))
+	at := func(d time.Duration) float64 { return out[2*Dtof(d, sr)+1] }
+	if x := at(400 * time.Millisecond); x != 0 {
+		t.Fatalf("have %v before clip, want 0", x)
+	}
+	if x := at(700 * time.Millisecond); !equaleps(x, Decibel(-6).Amp(), 1e-9) {
+		t.Fatalf("have %v during clip, want -6dB", x)
+	}
+	if x := at(1100 * time.Millisecond); x != 0 {
+		t.Fatalf("have %v after clip, want 0", x)
+	}
+
+	c.FadeIn = 100 * time.Millisecond
+	tp.Seek(0)
+	out = Render(tl, Dtof(time.Second, sr))
+	if x, want := at(550*time.Millisecond), Decibel(-6).Amp()/2; !equaleps(x, want, 0.01) {
+		t.Fatalf("have %v half way through fade in, want %v", x, want)
+	}
+	tl.Remove(c)
+	if n := len(tl.Clips()); n != 0 {
+		t.Fatalf("have %v clips after remove, want 0", n)
+	}
+}
+
+func TestTimelineCrossfade(t *testing.T) {
+	sr := DefaultSampleRate
+	tp := NewTransport(120)
+	tl := NewTimeline(tp, 2)
+	// a clip on the left and a clip on the right, to tell their gains apart.
+	left, right := make(Discrete, 2*int(sr)), make(Discrete, 2*int(sr))
+	for i := 0; i < len(left); i += 2 {
+		left[i], right[i+1] = 1, 1
+	}
+	// beats 0 to 2 and 1.5 to 3.5, overlapping from 750ms to 1s.
+	tl.Add(NewClip(left, 2, sr, 0))
+	tl.Add(NewClip(right, 2, sr, 1.5))
+
+	tp.Play()
+	out := Render(tl, Dtof(2*time.Second, sr))
+	at := func(d time.Duration) (l, r float64) {
+		i := 2 * Dtof(d, sr)
+		return out[i], out[i+1]
+	}
+	if l, _ := at(0); l != 0 {
+		t.Fatalf("have %v at start, want declicked from 0", l)
+	}
+	if l, r := at(500 * time.Millisecond); l != 1 || r != 0 {
+		t.Fatalf("have %v and %v before overlap, want 1 and 0", l, r)
+	}
+	for _, d := range []time.Duration{800, 875, 950} {
+		l, r := at(d * time.Millisecond)
+		if p := l*l + r*r; !equaleps(p, 1, 1e-3) {
+			t.Fatalf("have power %v at %vms of crossfade, want 1", p, d)
+		}
+	}
+	if l, r := at(1500 * time.Millisecond); l != 0 || r != 1 {
+		t.Fatalf("have %v and %v after overlap, want 0 and 1", l, r)
+	}
+
+	tl.SetCrossfade(false)
+	tp.Seek(0)
+	out = Render(tl, Dtof(time.Second, sr))
+	if l, r := at(875 * time.Millisecond); l != 1 || r != 1 {
+		t.Fatalf("have %v and %v layered, want 1 and 1", l, r)
+	}
+}
@@ -0,0 +1,174 @@
+// Command faust2snd compiles a program of Faust to a Go file of a Sound,
+// using Faust's C backend and package dasa.cc/snd/ext.
+//
+//	faust2snd -pkg fx -o freeverb.go freeverb.dsp
+//
+// The file declares New followed by the class name, such as NewFreeverb,
+// returning an *ext.FaustSound with the program's controls as params. The
+// class name defaults to the program's file name. With -c, C already
+// generated by faust -lang c is wrapped instead of running faust.
+//
+// Building the file requires cgo and Faust's headers, whose directory is
+// found by faust --includedir unless given by -I.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var (
+	flagOut     = flag.String("o", "", "output file; defaults to the program's name with .go")
+	flagPkg     = flag.String("pkg", "main", "package of the output file")
+	flagClass   = flag.String("cn", "", "class name; defaults to the program's name")
+	flagC       = flag.Bool("c", false, "input is C generated by faust -lang c -cn class")
+	flagInclude = flag.String("I", "", "directory of Faust's headers")
+	flagFaust   = flag.String("faust", "faust", "faust command")
+)
+
+var tmpl = template.Must(template.New("").Parse(`// Code generated by faust2snd from {{.Src}}. DO NOT EDIT.
+
+package {{.Pkg}}
+
+{{if .Include}}// #cgo CFLAGS: -I{{.Include}}
+{{end}}// #cgo LDFLAGS: -lm
+// #include <faust/gui/CInterface.h>
+//
+{{.Code}}import "C"
+
+import (
+	"unsafe"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/ext"
+)
+
+// New{{.Name}} returns a Sound of {{.Src}} processing in, which may be nil for
+// a program without inputs. Close must be called to free it.
+func New{{.Name}}(in snd.Sound) *ext.FaustSound {
+	return ext.NewFaust(ext.Faust{
+		New:                unsafe.Pointer(C.new{{.Class}}),
+		Delete:             unsafe.Pointer(C.delete{{.Class}}),
+		Init:               unsafe.Pointer(C.init{{.Class}}),
+		Compute:            unsafe.Pointer(C.compute{{.Class}}),
+		Inputs:             unsafe.Pointer(C.getNumInputs{{.Class}}),
+		Outputs:            unsafe.Pointer(C.getNumOutputs{{.Class}}),
+		BuildUserInterface: unsafe.Pointer(C.buildUserInterface{{.Class}}),
+	}, in)
+}
+`))
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("faust2snd: ")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: faust2snd [flags] program.dsp")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	src := flag.Arg(0)
+	base := strings.TrimSuffix(filepath.Base(src), filepath.Ext(src))
+
+	class := *flagClass
+	if class == "" {
+		class = ident(base)
+	}
+	out := *flagOut
+	if out == "" {
+		out = base + ".go"
+	}
+
+	var code []byte
+	var err error
+	if *flagC {
+		code, err = os.ReadFile(src)
+	} else {
+		code, err = run(*flagFaust, "-lang", "c", "-cn", class, src)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	include := *flagInclude
+	if include == "" && !*flagC {
+		if b, err := run(*flagFaust, "--includedir"); err == nil {
+			include = strings.TrimSpace(string(b))
+		}
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct {
+		Src, Pkg, Include, Code, Class, Name string
+	}{
+		Src:     filepath.Base(src),
+		Pkg:     *flagPkg,
+		Include: include,
+		Code:    preamble(code),
+		Class:   class,
+		Name:    exported(class),
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, b, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// preamble returns code as line comments, as a block comment of cgo would
+// end at the first in the code.
+func preamble(code []byte) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(string(code), "\n"), "\n") {
+		b.WriteString(strings.TrimRight("// "+line, " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// run runs name with args, returning its output.
+func run(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v\n%s", name, err, stderr.Bytes())
+	}
+	return b, nil
+}
+
+// ident returns s with characters not allowed in identifiers of C dropped.
+func ident(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// exported returns s with its first letter upper case.
+func exported(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
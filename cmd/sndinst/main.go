@@ -0,0 +1,88 @@
+// Command sndinst plays a patch as an external instrument: raw MIDI read
+// from stdin plays its notes and audio is written to stdout as raw PCM, so
+// patches may be recorded inside a DAW that runs external processes, or
+// bridged to JACK.
+//
+//  sndinst -f32 synth.txt | aplay -f FLOAT_LE -c 2 -r 44100
+//
+// Patch files are described by package dasa.cc/snd/patch. Notes are played on
+// the node named by -keys, or the first node of the patch played by notes,
+// such as a poly node.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/patch"
+	"dasa.cc/snd/pipe"
+)
+
+var (
+	flagKeys = flag.String("keys", "", "name of the node played by notes")
+	flagF32  = flag.Bool("f32", false, "write 32-bit float samples rather than 16-bit")
+	flagTail = flag.Duration("tail", 2*time.Second, "duration rendered after stdin ends")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("sndinst: ")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: sndinst [flags] patch")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := patch.Parse(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	nt, err := keys(p)
+	if err != nil {
+		log.Fatal(err)
+	}
+	in := pipe.New(snd.NewMaster(p.Out), nt)
+	in.Tail = *flagTail
+	if *flagF32 {
+		in.Format = pipe.F32
+	}
+	log.Printf("%s: %vch %vHz", flag.Arg(0), p.Out.Channels(), p.Out.SampleRate())
+	// written unbuffered a buffer at a time, so the reader paces the graph.
+	if err := in.Serve(os.Stdin, os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// keys returns the Noter of p named by -keys, or else the first declared.
+func keys(p *patch.Patch) (snd.Noter, error) {
+	if *flagKeys != "" {
+		nt, ok := p.Nodes[*flagKeys].(snd.Noter)
+		if !ok {
+			return nil, fmt.Errorf("node %q not played by notes", *flagKeys)
+		}
+		return nt, nil
+	}
+	for _, line := range p.Lines() {
+		if f := strings.Fields(line); len(f) > 1 {
+			if nt, ok := p.Nodes[f[1]].(snd.Noter); ok {
+				return nt, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no node played by notes")
+}
@@ -0,0 +1,191 @@
+// Command sndplay plays a WAV or FLAC file or patch through the OpenAL backend.
+//
+//  sndplay [-loop] file.wav
+//  sndplay file.flac
+//  sndplay patch.txt
+//
+// Files not ending in .wav or .flac are parsed as patches described by package
+// dasa.cc/snd/patch. Other audio formats, such as OGG, are not supported.
+// Transport commands are read from stdin, each followed
+// by enter:
+//
+//  p  pause or resume
+//  r  restart from beginning
+//  f  seek forward five seconds
+//  b  seek backward five seconds
+//  l  toggle looping
+//  q  quit
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/al"
+	"dasa.cc/snd/flac"
+	"dasa.cc/snd/patch"
+	"dasa.cc/snd/wav"
+)
+
+var flagLoop = flag.Bool("loop", false, "loop playback of audio files")
+
+// load returns the sound to play and, for audio files, its player and sample rate.
+func load(name string) (snd.Sound, *snd.Player, float64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	defer f.Close()
+
+	var sig []float64
+	var chans, rate int
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".wav":
+		var format wav.Format
+		sig, format, err = wav.Decode(bufio.NewReader(f))
+		chans, rate = format.Chans, format.Rate
+	case ".flac":
+		var format flac.Format
+		sig, format, err = flac.Decode(f)
+		chans, rate = format.Chans, format.Rate
+	default:
+		p, err := patch.Parse(f)
+		if err != nil {
+			return nil, nil, 0, err
+		}
+		return p.Out, nil, 0, nil
+	}
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	sr := float64(rate)
+	pl := snd.NewPlayer(sig, chans, sr)
+	pl.SetLoop(*flagLoop)
+	if chans > 2 {
+		// such as 5.1, played in stereo.
+		return snd.Conform(2, pl), pl, sr, nil
+	}
+	return pl, pl, sr, nil
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: sndplay [flags] file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	sd, pl, sr, err := load(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	const buffers = 1
+	if err := al.OpenDevice(buffers); err != nil {
+		log.Fatal(err)
+	}
+	master := snd.NewMaster(sd)
+
+	// master and pl are prepared on the audio thread, so commands are sent
+	// to edit them before a buffer and the position of pl is published
+	// after one.
+	edits := make(chan func(), 8)
+	var pos int64
+	var done int32
+	dp := al.Dispatcher()
+	dp.BeforeDispatch(func(uint64, uint64) {
+		for {
+			select {
+			case fn := <-edits:
+				fn()
+			default:
+				return
+			}
+		}
+	})
+	if pl != nil {
+		dp.AfterDispatch(func(uint64, uint64) {
+			atomic.StoreInt64(&pos, int64(pl.Pos()))
+			var d int32
+			if pl.Done() {
+				d = 1
+			}
+			atomic.StoreInt32(&done, d)
+		})
+	}
+
+	al.Start(master)
+	defer al.CloseDevice()
+	defer al.Stop()
+
+	cmds := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			cmds <- strings.TrimSpace(sc.Text())
+		}
+		close(cmds)
+	}()
+
+	seek := snd.Dtof(5*time.Second, sr)
+	paused := false
+	status := time.NewTicker(time.Second)
+	defer status.Stop()
+	for {
+		select {
+		case cmd, ok := <-cmds:
+			if !ok {
+				return
+			}
+			switch cmd {
+			case "p":
+				if paused = !paused; paused {
+					edits <- master.Off
+				} else {
+					edits <- master.On
+				}
+			case "q":
+				return
+			}
+			if pl == nil {
+				continue
+			}
+			switch cmd {
+			case "r":
+				edits <- func() { pl.Seek(0) }
+			case "f":
+				edits <- func() { pl.Seek(pl.Pos() + seek) }
+			case "b":
+				edits <- func() { pl.Seek(pl.Pos() - seek) }
+			case "l":
+				*flagLoop = !*flagLoop
+				loop := *flagLoop
+				edits <- func() { pl.SetLoop(loop) }
+			}
+		case <-status.C:
+			if pl == nil {
+				continue
+			}
+			fmt.Printf("\r%s / %s underruns=%v ",
+				snd.Ftod(int(atomic.LoadInt64(&pos)), sr).Truncate(time.Second),
+				snd.Ftod(pl.Len(), sr).Truncate(time.Second), al.Underruns())
+			if atomic.LoadInt32(&done) == 1 {
+				fmt.Println()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Command sndrender renders a patch offline to a WAV or FLAC file.
+//
+//  sndrender -d 10s -rate 48000 -o out.flac patch.txt
+//  sndrender -d 30s -midi song.mid -bpm 96 -o song.wav synth.txt
+//
+// Patch files are described by package dasa.cc/snd/patch. Output is encoded
+// as FLAC if its name ends in .flac, or else as WAV.
+//
+// With -midi, notes of a standard MIDI file are played on the node named by
+// -keys, or the first node of the patch played by notes, such as a poly node.
+// Tempo changes of the file are not read; ticks are timed by -bpm instead.
+//
+// After rendering, peak level and integrated loudness of the output are
+// printed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/flac"
+	"dasa.cc/snd/midi"
+	"dasa.cc/snd/patch"
+	"dasa.cc/snd/wav"
+)
+
+var (
+	flagOut   = flag.String("o", "out.wav", "output file")
+	flagDur   = flag.Duration("d", 5*time.Second, "duration to render")
+	flagRate  = flag.Float64("rate", snd.DefaultSampleRate, "output sample rate")
+	flagDepth = flag.Int("depth", 16, "output bit depth; 16, 24, or 32 for float WAV")
+	flagMIDI  = flag.String("midi", "", "standard MIDI file playing notes of the patch")
+	flagKeys  = flag.String("keys", "", "name of the node played by -midi notes")
+	flagBPM   = flag.Float64("bpm", 120, "tempo of -midi")
+)
+
+// encoder is implemented by writers of package wav and flac.
+type encoder interface {
+	Write(xs []float64) error
+	Close() error
+}
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: sndrender [flags] patch")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	p, err := patch.Parse(f)
+	f.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sr := p.Out.SampleRate()
+	chans := p.Out.Channels()
+	dp := new(snd.Dispatcher)
+	if *flagMIDI != "" {
+		if err := play(dp, p); err != nil {
+			log.Fatal(err)
+		}
+	}
+	sig := dp.Render(p.Out, snd.Dtof(*flagDur, sr))
+	if *flagRate != sr {
+		sig = snd.Resample(sig, chans, sr, *flagRate)
+	}
+
+	out, err := os.Create(*flagOut)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var w encoder
+	if strings.EqualFold(filepath.Ext(*flagOut), ".flac") {
+		w, err = flac.NewWriter(out, chans, int(*flagRate), *flagDepth)
+	} else {
+		w, err = wav.NewWriter(out, chans, int(*flagRate), *flagDepth)
+	}
+	if err == nil {
+		err = w.Write(sig)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	peak := 20 * math.Log10(snd.Peak(sig))
+	lufs := snd.Loudness(sig, chans, *flagRate)
+	fmt.Printf("%s: %s %vch %vHz peak=%.2fdBFS loudness=%.2fLUFS\n",
+		*flagOut, *flagDur, chans, *flagRate, peak, lufs)
+}
+
+// play plays notes of the MIDI file of -midi on the Noter of p, each at the
+// start of the buffer dp prepares its frame in.
+func play(dp *snd.Dispatcher, p *patch.Patch) error {
+	nt, err := keys(p)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(*flagMIDI)
+	if err != nil {
+		return err
+	}
+	ppq, events, err := midi.ReadFile(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, *flagMIDI)
+	}
+
+	// frames per tick of ppq per beat.
+	frames := 60 * p.Out.SampleRate() / (*flagBPM * float64(ppq))
+	n := uint64(len(p.Out.Samples()) / p.Out.Channels())
+	dp.BeforeDispatch(func(_, frame uint64) {
+		for len(events) > 0 && uint64(float64(events[0].Tick)*frames) < frame+n {
+			midi.Play(nt, events[0].Msg)
+			events = events[1:]
+		}
+	})
+	return nil
+}
+
+// keys returns the Noter of p named by -keys, or else the first declared.
+func keys(p *patch.Patch) (snd.Noter, error) {
+	if *flagKeys != "" {
+		nt, ok := p.Nodes[*flagKeys].(snd.Noter)
+		if !ok {
+			return nil, fmt.Errorf("node %q not played by notes", *flagKeys)
+		}
+		return nt, nil
+	}
+	for _, line := range p.Lines() {
+		if f := strings.Fields(line); len(f) > 1 {
+			if nt, ok := p.Nodes[f[1]].(snd.Noter); ok {
+				return nt, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no node played by notes")
+}
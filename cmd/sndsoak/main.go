@@ -0,0 +1,69 @@
+// Command sndsoak plays a large graph without a device for hours, driving
+// random notes and changes of params through it, and reports underruns,
+// allocations, NaNs, and drift.
+//
+//  sndsoak -d 8h -realtime -report 5m
+//
+// A line is printed of each report. It exits 1 if any buffer was not finite,
+// or with -strict, if any was late or allocated.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"dasa.cc/snd/soak"
+)
+
+var (
+	flagDur      = flag.Duration("d", soak.DefaultConfig.Duration, "duration of audio to play; 0 until interrupted")
+	flagRealtime = flag.Bool("realtime", false, "pace buffers as a device would")
+	flagAhead    = flag.Duration("ahead", soak.DefaultConfig.Ahead, "time a buffer is started before due, with -realtime")
+	flagSeed     = flag.Int64("seed", soak.DefaultConfig.Seed, "seed of notes and changes")
+	flagVoices   = flag.Int("voices", 8, "voices of each instrument")
+	flagNotes    = flag.Float64("notes", soak.DefaultConfig.Notes, "notes started per second")
+	flagChanges  = flag.Float64("changes", soak.DefaultConfig.Changes, "changes of params per second")
+	flagChurn    = flag.Float64("churn", soak.DefaultConfig.Churn, "amount of range params move each change")
+	flagReport   = flag.Duration("report", soak.DefaultConfig.Report, "interval of audio between reports")
+	flagStrict   = flag.Bool("strict", false, "fail of underruns and allocations too")
+)
+
+func main() {
+	log.SetFlags(0)
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: sndsoak [flags]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg := soak.DefaultConfig
+	cfg.Duration = *flagDur
+	cfg.Realtime = *flagRealtime
+	cfg.Ahead = *flagAhead
+	cfg.Seed = *flagSeed
+	cfg.Notes = *flagNotes
+	cfg.Changes = *flagChanges
+	cfg.Churn = *flagChurn
+	cfg.Report = *flagReport
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	out, ps, nts := soak.Graph(*flagVoices)
+	r := soak.New(cfg, out, ps, nts...).Run(ctx, func(r soak.Report) { fmt.Println(r) })
+	fmt.Println("total", r)
+	if s := soak.Summary(r); s != "" {
+		fmt.Println(s)
+	}
+	if r.NaNs != 0 || *flagStrict && (r.Underruns != 0 || r.Allocs > 0) {
+		os.Exit(1)
+	}
+}
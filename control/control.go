@@ -0,0 +1,292 @@
+// Package control serves an HTTP API driving a running graph described by
+// package patch, such as a headless engine driven by a web UI or another
+// process. Bodies are JSON unless noted.
+//
+//  POST /exec            patch lines as text, executed in order
+//  GET  /graph           lines executed so far
+//  GET  /params          every param with its range, unit, and value
+//  GET  /params/{name}   a single param
+//  PUT  /params/{name}   {"value": 0.5} or {"text": "250 ms"}
+//  GET  /preset          values of every param by name
+//  PUT  /preset          values to load, as of GET /preset
+//  GET  /transport       {"bpm": 120, "beat": 4.5, "playing": true}
+//  PUT  /transport       any of bpm, beat, and playing to change
+//
+// Errors are returned with a status of 4xx as {"error": "..."}, such as of a
+// body beyond MaxBody. Requests editing the running graph or transport wait
+// at most ApplyTimeout for it to play a buffer, failing with a status of 503
+// otherwise, such as while the engine is stopped.
+package control // import "dasa.cc/snd/control"
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/patch"
+)
+
+// MaxBody is the most bytes of a request body read.
+const MaxBody = 1 << 20
+
+// ApplyTimeout is the longest a request waits for the graph to play a buffer.
+const ApplyTimeout = time.Second
+
+// Server is an http.Handler controlling a patch played through a Hotswap, such
+// as one started on an Engine of package al. When a patch line changes the
+// out node, it is swapped into the Hotswap from the next buffer. Params and
+// the transport are changed and read through Apply of the patch, so between
+// buffers if the patch is Live, as it should be if tp is set.
+// Requests are handled one at a time.
+//
+// Files of the patch must be set, such as to a directory of samples, as any
+// client may name files of it to be read.
+type Server struct {
+	mu sync.Mutex
+	p  *patch.Patch
+	hs *snd.Hotswap
+	tp *snd.Transport
+}
+
+// NewServer returns Server of p played through hs, controlling tp if not nil.
+// It panics if Files of p are not set.
+func NewServer(p *patch.Patch, hs *snd.Hotswap, tp *snd.Transport) *Server {
+	if p.Files == nil {
+		panic("snd/control: patch without Files")
+	}
+	return &Server{p: p, hs: hs, tp: tp}
+}
+
+// Param describes a param of the patch.
+type Param struct {
+	Name    string  `json:"name"`
+	Value   float64 `json:"value"`
+	Text    string  `json:"text"`
+	Unit    string  `json:"unit,omitempty"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Default float64 `json:"default"`
+}
+
+func describe(p *snd.Param) Param {
+	return Param{
+		Name:    p.Name,
+		Value:   p.Value(),
+		Text:    p.String(),
+		Unit:    p.Unit.String(),
+		Min:     p.Min,
+		Max:     p.Max,
+		Default: p.Default,
+	}
+}
+
+// Transport is the state of a transport; fields are optional when changing it.
+type Transport struct {
+	BPM     *float64 `json:"bpm,omitempty"`
+	Beat    *float64 `json:"beat,omitempty"`
+	Playing *bool    `json:"playing,omitempty"`
+}
+
+// statusError is an error of a request with its status.
+type statusError struct {
+	code int
+	err  error
+}
+
+func (e statusError) Error() string { return e.err.Error() }
+
+func badRequest(format string, args ...interface{}) error {
+	return statusError{http.StatusBadRequest, fmt.Errorf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return statusError{http.StatusNotFound, fmt.Errorf(format, args...)}
+}
+
+func (sv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBody)
+	v, err := sv.serve(r)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		code := http.StatusBadRequest
+		if se, ok := err.(statusError); ok {
+			code = se.code
+		}
+		w.WriteHeader(code)
+		v = map[string]string{"error": err.Error()}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	enc.Encode(v)
+}
+
+// serve handles r one request at a time.
+func (sv *Server) serve(r *http.Request) (interface{}, error) {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.handle(r)
+}
+
+// handle returns the response of r to encode.
+func (sv *Server) handle(r *http.Request) (interface{}, error) {
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "exec" && r.Method == http.MethodPost:
+		return sv.exec(r)
+	case path == "graph" && r.Method == http.MethodGet:
+		return sv.p.Lines(), nil
+	case path == "params" && r.Method == http.MethodGet:
+		ps := []Param{}
+		for _, p := range sv.p.Params.List() {
+			ps = append(ps, describe(p))
+		}
+		return ps, nil
+	case strings.HasPrefix(path, "params/"):
+		name := strings.TrimPrefix(path, "params/")
+		p := sv.p.Params.Lookup(name)
+		if p == nil {
+			return nil, notFound("undefined param %q", name)
+		}
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			var req struct {
+				Value *float64 `json:"value"`
+				Text  *string  `json:"text"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				return nil, badRequest("decode param: %v", err)
+			}
+			var x float64
+			switch {
+			case req.Value != nil:
+				x = *req.Value
+			case req.Text != nil:
+				var err error
+				if x, err = p.Unit.Parse(*req.Text); err != nil {
+					return nil, badRequest("param %q: %v", name, err)
+				}
+			default:
+				return nil, badRequest("param %q missing value or text", name)
+			}
+			if err := sv.apply(r, func() { p.Set(x) }); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, statusError{http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)}
+		}
+		return describe(p), nil
+	case path == "preset" && r.Method == http.MethodGet:
+		return sv.p.Params.Save(), nil
+	case path == "preset" && r.Method == http.MethodPut:
+		pre, err := snd.ReadPreset(r.Body)
+		if err != nil {
+			return nil, badRequest("%v", err)
+		}
+		var lerr error
+		if err := sv.apply(r, func() { lerr = sv.p.Params.Load(pre) }); err != nil {
+			return nil, err
+		}
+		if lerr != nil {
+			return nil, badRequest("%v", lerr)
+		}
+		return sv.p.Params.Save(), nil
+	case path == "transport":
+		return sv.transport(r)
+	}
+	return nil, notFound("no %s of /%s", r.Method, path)
+}
+
+// exec executes patch lines of the body of r, stopping at the first error.
+func (sv *Server) exec(r *http.Request) (interface{}, error) {
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, badRequest("read lines: %v", err)
+	}
+	out := sv.p.Out
+	defer func() {
+		// lines before an error still apply.
+		if sv.p.Out != out {
+			sv.hs.Swap(sv.p.Out)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(r.Context(), ApplyTimeout)
+	defer cancel()
+	for i, line := range strings.Split(string(b), "\n") {
+		if err := sv.p.ExecContext(ctx, line); err != nil {
+			err = fmt.Errorf("line %v: %v", i+1, err)
+			if ctx.Err() != nil || err == patch.ErrFull {
+				return nil, statusError{http.StatusServiceUnavailable, err}
+			}
+			return nil, statusError{http.StatusBadRequest, err}
+		}
+	}
+	return sv.p.Lines(), nil
+}
+
+// apply applies fn to the running graph, waiting until applied, r is
+// canceled, or ApplyTimeout passes, such as while the graph is not playing.
+func (sv *Server) apply(r *http.Request, fn func()) error {
+	done, err := sv.p.Apply(fn)
+	if err != nil {
+		return statusError{http.StatusServiceUnavailable, err}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), ApplyTimeout)
+	defer cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return statusError{http.StatusServiceUnavailable, fmt.Errorf("apply: %v", ctx.Err())}
+	}
+}
+
+func (sv *Server) transport(r *http.Request) (interface{}, error) {
+	tp := sv.tp
+	if tp == nil {
+		return nil, notFound("no transport")
+	}
+	var req Transport
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return nil, badRequest("decode transport: %v", err)
+		}
+		if req.BPM != nil && *req.BPM <= 0 {
+			return nil, badRequest("bpm %v not positive", *req.BPM)
+		}
+	default:
+		return nil, statusError{http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method)}
+	}
+	// tp is prepared on the audio thread, so it is changed and read there.
+	var bpm, beat float64
+	var playing bool
+	err := sv.apply(r, func() {
+		if req.BPM != nil {
+			tp.SetBPM(snd.BPM(*req.BPM))
+		}
+		if req.Beat != nil {
+			tp.Seek(*req.Beat)
+		}
+		if req.Playing != nil {
+			if *req.Playing {
+				tp.Play()
+			} else {
+				tp.Stop()
+			}
+		}
+		bpm, beat, playing = float64(tp.BPM()), tp.Beat(), tp.Playing()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return Transport{BPM: &bpm, Beat: &beat, Playing: &playing}, nil
+}
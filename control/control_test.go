@@ -0,0 +1,128 @@
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/patch"
+)
+
+func do(t *testing.T, sv http.Handler, method, path, body string, v interface{}) int {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	sv.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if v != nil && rec.Code == http.StatusOK {
+		if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestServer(t *testing.T) {
+	hs := snd.NewHotswap(1, nil)
+	tp := snd.NewTransport(120)
+	pt := patch.New()
+	pt.Files = fstest.MapFS{}
+	sv := NewServer(pt, hs, tp)
+
+	var lines []string
+	if code := do(t, sv, "POST", "/exec", "osc lfo freq=4\nosc a freq=220 freqmod=lfo\nout a", &lines); code != http.StatusOK {
+		t.Fatalf("have status %v of exec", code)
+	}
+	if len(lines) != 3 || hs.Current() == nil {
+		t.Fatalf("have lines %q, swapped %v", lines, hs.Current())
+	}
+	if code := do(t, sv, "POST", "/exec", strings.Repeat("#", MaxBody+1), nil); code != http.StatusBadRequest {
+		t.Fatalf("have status %v of body beyond MaxBody, want 400", code)
+	}
+	if code := do(t, sv, "POST", "/exec", "player x file=/etc/passwd", nil); code != http.StatusBadRequest {
+		t.Fatalf("have status %v of file outside Files, want 400", code)
+	}
+	if code := do(t, sv, "POST", "/exec", "osc b nope=1", nil); code != http.StatusBadRequest {
+		t.Fatalf("have status %v of bad line, want 400", code)
+	}
+
+	var p Param
+	if code := do(t, sv, "PUT", "/params/lfo.freq", `{"value": 0.5}`, &p); code != http.StatusOK || p.Value != 0.5 {
+		t.Fatalf("have status %v and param %+v", code, p)
+	}
+	if code := do(t, sv, "PUT", "/params/a.freq", `{"text": "440 Hz"}`, &p); code != http.StatusOK || p.Value != 440 {
+		t.Fatalf("have status %v and param %+v", code, p)
+	}
+	if code := do(t, sv, "GET", "/params/nope", "", nil); code != http.StatusNotFound {
+		t.Fatalf("have status %v of unknown param, want 404", code)
+	}
+
+	var pre snd.Preset
+	if code := do(t, sv, "PUT", "/preset", `{"lfo.freq": 2}`, &pre); code != http.StatusOK || pre["lfo.freq"] != 2 || pre["a.freq"] != 440 {
+		t.Fatalf("have status %v and preset %v", code, pre)
+	}
+
+	var st Transport
+	if code := do(t, sv, "PUT", "/transport", `{"bpm": 90, "playing": true}`, &st); code != http.StatusOK {
+		t.Fatalf("have status %v of transport", code)
+	}
+	if *st.BPM != 90 || !*st.Playing || !tp.Playing() || tp.BPM() != 90 {
+		t.Fatalf("have transport %v at %v", tp.Playing(), tp.BPM())
+	}
+}
+
+func TestServerLive(t *testing.T) {
+	hs := snd.NewHotswap(1, nil)
+	p := patch.New()
+	p.Files = fstest.MapFS{}
+	sv := NewServer(p, hs, nil)
+	dp := new(snd.Dispatcher)
+	defer p.Live(dp)()
+	if code := do(t, sv, "POST", "/exec", "osc a freq=220\nout a", nil); code != http.StatusOK {
+		t.Fatalf("have status %v of exec", code)
+	}
+
+	// params are set between buffers, the request waiting on them.
+	done := make(chan int)
+	var prm Param
+	go func() { done <- do(t, sv, "PUT", "/params/a.freq", `{"value": 330}`, &prm) }()
+	for {
+		select {
+		case code := <-done:
+			if code != http.StatusOK || prm.Value != 330 {
+				t.Fatalf("have status %v and param %+v", code, prm)
+			}
+			return
+		default:
+			dp.Render(hs, snd.DefaultBufferLen)
+		}
+	}
+}
+
+func TestServerStopped(t *testing.T) {
+	hs := snd.NewHotswap(1, nil)
+	tp := snd.NewTransport(120)
+	p := patch.New()
+	p.Files = fstest.MapFS{}
+	sv := NewServer(p, hs, tp)
+	defer p.Live(new(snd.Dispatcher))()
+	if code := do(t, sv, "POST", "/exec", "osc a freq=220\nmixer m in=a\nout m", nil); code != http.StatusOK {
+		t.Fatalf("have status %v of exec", code)
+	}
+
+	// no buffers are played, so edits time out rather than hang the server.
+	for _, req := range [][3]string{
+		{"POST", "/exec", "connect m in=a,a"},
+		{"PUT", "/transport", `{"playing": true}`},
+		{"GET", "/transport", ""},
+	} {
+		if code := do(t, sv, req[0], req[1], req[2], nil); code != http.StatusServiceUnavailable {
+			t.Fatalf("%s %s: have status %v, want 503", req[0], req[1], code)
+		}
+	}
+	if code := do(t, sv, "GET", "/graph", "", nil); code != http.StatusOK {
+		t.Fatalf("have status %v of graph", code)
+	}
+}
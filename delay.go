@@ -12,7 +12,13 @@ type bufc struct {
 }
 
 // newbufc returns buffer of length n with read offset r.
-func newbufc(n int, r int) *bufc { return &bufc{make([]float64, n), r, 0} }
+// newbufc returns bufc of n frames reading from r, at least r+1 frames.
+func newbufc(n int, r int) *bufc {
+	if n <= r {
+		n = r + 1
+	}
+	return &bufc{make([]float64, n), r, 0}
+}
 
 func (b *bufc) read() (x float64) {
 	x = b.xs[b.r]
@@ -49,6 +55,15 @@ func Dtof(d time.Duration, sr float64) (f int) {
 	return int(float64(d) / float64(time.Second) * sr)
 }
 
+// atleast returns frames f, or min if f is less, such as of a negative
+// duration.
+func atleast(min, f int) int {
+	if f < min {
+		return min
+	}
+	return f
+}
+
 // Ftod converts f, number of frames, to approximate time duration.
 func Ftod(f int, sr float64) (d time.Duration) {
 	return time.Duration(float64(f) / sr * float64(time.Second))
@@ -65,6 +80,16 @@ func NewDelay(d time.Duration, in Sound) *Delay {
 	return &Delay{newmono(in), newbufc(Dtof(d, in.SampleRate()), 1)}
 }
 
+// clear silences any echoes in b.
+func (b *bufc) clear() {
+	for i := range b.xs {
+		b.xs[i] = 0
+	}
+}
+
+// Panic silences the delay line.
+func (dly *Delay) Panic() { dly.line.clear() }
+
 func (dly *Delay) Prepare(uint64) {
 	for i := range dly.out {
 		if dly.off {
@@ -126,6 +151,16 @@ func NewComb(gain float64, d time.Duration, in Sound) *Comb {
 	return &Comb{newmono(in), newbufc(Dtof(d, in.SampleRate()), 1), gain}
 }
 
+func (cmb *Comb) Gain() float64     { return cmb.gain }
+func (cmb *Comb) SetGain(g float64) { cmb.gain = g }
+
+func (cmb *Comb) Params() []*Param {
+	return []*Param{NewParam("gain", cmb.Gain, cmb.SetGain).Range(-0.99, 0.99, 0.5)}
+}
+
+// Panic silences the delay line.
+func (cmb *Comb) Panic() { cmb.line.clear() }
+
 func (cmb *Comb) Prepare(uint64) {
 	for i := range cmb.out {
 		if cmb.off {
@@ -219,3 +254,224 @@ func (lp *Loop) Prepare(tc uint64) {
 		}
 	}
 }
+
+// maxDelay is the longest time of a PingPong or MultiTap.
+const maxDelay = 4 * time.Second
+
+// beatdur returns duration of beats at tempo bpm.
+func beatdur(bpm BPM, beats float64) time.Duration {
+	return time.Duration(beats * float64(bpm.Dur()))
+}
+
+// PingPong is a stereo feedback delay whose echoes alternate between left and
+// right channels, starting left. Output is the dry input centered, mixed with
+// echoes.
+type PingPong struct {
+	*mono
+	interpolators
+	l, r     []float64
+	w        int
+	d        float64 // frames
+	feedback float64
+	mix      float64
+}
+
+// NewPingPong returns PingPong of mono input in with echoes every d, up to 4s,
+// each reduced by feedback belonging to [0..1).
+func NewPingPong(d time.Duration, feedback float64, in Sound) *PingPong {
+	sd := newmono(in)
+	sd.out = make(Discrete, 2*len(in.Samples()))
+	n := Dtof(maxDelay, sd.sr) + 2
+	pp := &PingPong{
+		mono:          sd,
+		interpolators: newreaders(2),
+		l:             make([]float64, n),
+		r:             make([]float64, n),
+		feedback:      feedback,
+		mix:           0.5,
+	}
+	pp.SetTime(d)
+	return pp
+}
+
+func (pp *PingPong) Channels() int { return 2 }
+
+func (pp *PingPong) Time() time.Duration {
+	return time.Duration(pp.d / pp.sr * float64(time.Second))
+}
+
+// SetTime sets time between echoes, limited to 4s.
+func (pp *PingPong) SetTime(d time.Duration) {
+	if d > maxDelay {
+		d = maxDelay
+	}
+	pp.d = d.Seconds() * pp.sr
+	if pp.d < 1 {
+		pp.d = 1
+	}
+}
+
+// SetSync sets time between echoes to beats at tempo bpm.
+func (pp *PingPong) SetSync(bpm BPM, beats float64) { pp.SetTime(beatdur(bpm, beats)) }
+
+func (pp *PingPong) Feedback() float64     { return pp.feedback }
+func (pp *PingPong) SetFeedback(x float64) { pp.feedback = x }
+
+// Mix returns balance of dry and echoes where 0 is dry and 1 is only echoes.
+func (pp *PingPong) Mix() float64     { return pp.mix }
+func (pp *PingPong) SetMix(x float64) { pp.mix = x }
+
+// Params returns time in seconds, feedback, and mix.
+func (pp *PingPong) Params() []*Param {
+	return []*Param{
+		NewParam("time", func() float64 { return pp.Time().Seconds() },
+			func(x float64) { pp.SetTime(time.Duration(x * float64(time.Second))) }).Range(0.001, maxDelay.Seconds(), 0.25).In(UnitSeconds),
+		NewParam("feedback", pp.Feedback, pp.SetFeedback).Range(0, 0.95, 0.5).In(UnitPercent),
+		NewParam("mix", pp.Mix, pp.SetMix).Range(0, 1, 0.5).In(UnitPercent),
+	}
+}
+
+// Panic silences all echoes.
+func (pp *PingPong) Panic() {
+	for i := range pp.l {
+		pp.l[i], pp.r[i] = 0, 0
+	}
+}
+
+func (pp *PingPong) Prepare(uint64) {
+	pp.limit(pp.st)
+	for i, x := range pp.in.Samples() {
+		el := pp.interpolators[0].read(pp.l, pp.w, pp.d)
+		er := pp.interpolators[1].read(pp.r, pp.w, pp.d)
+		// input feeds left only; each side feeds the other.
+		pp.l[pp.w] = x + pp.feedback*er
+		pp.r[pp.w] = pp.feedback * el
+		pp.w++
+		if pp.w == len(pp.l) {
+			pp.w = 0
+		}
+
+		if pp.off {
+			pp.out[2*i], pp.out[2*i+1] = 0, 0
+			continue
+		}
+		dry := (1 - pp.mix) * onesqrt2 * x
+		pp.out[2*i] = dry + pp.mix*el
+		pp.out[2*i+1] = dry + pp.mix*er
+	}
+}
+
+// DelayTap is a single echo of a MultiTap.
+type DelayTap struct {
+	// Time of echo after input, up to 4s. If Beats is non-zero, Time is set
+	// from Beats by MultiTap.SetSync.
+	Time  time.Duration
+	Beats float64
+
+	Level float64 // amplitude multiplier
+	Pan   float64 // belongs to [-1..1]
+
+	// Cutoff is frequency of a lowpass filter applied to the echo; zero
+	// disables the filter.
+	Cutoff float64
+}
+
+// MultiTap is a stereo delay of independent echoes, each with its own time,
+// level, pan, and filter. Output is the dry input centered, mixed with echoes.
+type MultiTap struct {
+	*mono
+	interpolators
+	line []float64
+	w    int
+	taps []DelayTap
+	svfs []svf
+	mix  float64
+}
+
+// NewMultiTap returns MultiTap of mono input in with taps.
+func NewMultiTap(taps []DelayTap, in Sound) *MultiTap {
+	sd := newmono(in)
+	sd.out = make(Discrete, 2*len(in.Samples()))
+	mt := &MultiTap{
+		mono: sd,
+		line: make([]float64, Dtof(maxDelay, sd.sr)+2),
+		mix:  0.5,
+	}
+	mt.SetTaps(taps)
+	return mt
+}
+
+func (mt *MultiTap) Channels() int { return 2 }
+
+// Taps returns a copy of the current taps.
+func (mt *MultiTap) Taps() []DelayTap { return append([]DelayTap(nil), mt.taps...) }
+
+// SetTaps replaces all taps. Filter state is reset.
+func (mt *MultiTap) SetTaps(taps []DelayTap) {
+	ip := mt.Interpolation()
+	mt.taps = append(mt.taps[:0], taps...)
+	mt.svfs = make([]svf, len(taps))
+	mt.interpolators = newreaders(len(taps))
+	mt.SetInterpolation(ip)
+	for i, tap := range mt.taps {
+		if tap.Time > maxDelay {
+			mt.taps[i].Time = maxDelay
+		}
+		if tap.Cutoff > 0 {
+			mt.svfs[i].set(tap.Cutoff, 0.707, mt.sr)
+		}
+	}
+}
+
+// SetSync sets time of every tap with non-zero Beats from tempo bpm.
+func (mt *MultiTap) SetSync(bpm BPM) {
+	for i, tap := range mt.taps {
+		if tap.Beats != 0 {
+			d := beatdur(bpm, tap.Beats)
+			if d > maxDelay {
+				d = maxDelay
+			}
+			mt.taps[i].Time = d
+		}
+	}
+}
+
+// Mix returns balance of dry and echoes where 0 is dry and 1 is only echoes.
+func (mt *MultiTap) Mix() float64     { return mt.mix }
+func (mt *MultiTap) SetMix(x float64) { mt.mix = x }
+
+// Panic silences all echoes and resets filters.
+func (mt *MultiTap) Panic() {
+	for i := range mt.line {
+		mt.line[i] = 0
+	}
+	for i := range mt.svfs {
+		mt.svfs[i].ic1, mt.svfs[i].ic2 = 0, 0
+	}
+}
+
+func (mt *MultiTap) Prepare(uint64) {
+	mt.limit(mt.st)
+	for i, x := range mt.in.Samples() {
+		mt.line[mt.w] = x
+		dry := (1 - mt.mix) * onesqrt2 * x
+		l, r := dry, dry
+		for j, tap := range mt.taps {
+			e := mt.interpolators[j].read(mt.line, mt.w, tap.Time.Seconds()*mt.sr) * tap.Level
+			if tap.Cutoff > 0 {
+				e = mt.svfs[j].filter(e, FilterLowPass)
+			}
+			l += mt.mix * e * getpanfac(tap.Pan)
+			r += mt.mix * e * getpanfac(-tap.Pan)
+		}
+		mt.w++
+		if mt.w == len(mt.line) {
+			mt.w = 0
+		}
+
+		if mt.off {
+			l, r = 0, 0
+		}
+		mt.out[2*i], mt.out[2*i+1] = l, r
+	}
+}
@@ -72,3 +72,50 @@ func BenchmarkComb(b *testing.B) {
 		cmb.Prepare(uint64(n))
 	}
 }
+
+// echoes returns frames and channels of samples of interleaved stereo out
+// above threshold.
+func echoes(out Discrete) (frames, chans []int) {
+	for i, x := range out {
+		if x > 0.1 {
+			frames, chans = append(frames, i/2), append(chans, i%2)
+		}
+	}
+	return
+}
+
+func TestPingPong(t *testing.T) {
+	pp := NewPingPong(0, 0.5, newimpulse())
+	pp.SetSync(150, 0.25) // 100ms
+	pp.SetMix(1)
+	frames, chans := echoes(Render(pp, Dtof(500*time.Millisecond, DefaultSampleRate)))
+	d := Dtof(100*time.Millisecond, DefaultSampleRate)
+	want := []int{d, 2 * d, 3 * d, 4 * d}
+	if len(frames) != len(want) {
+		t.Fatalf("have echoes at %v, want %v", frames, want)
+	}
+	for i := range want {
+		if frames[i] != want[i] || chans[i] != i%2 {
+			t.Fatalf("have echoes at %v in %v, want %v alternating from left", frames, chans, want)
+		}
+	}
+}
+
+func TestMultiTap(t *testing.T) {
+	taps := []DelayTap{
+		{Beats: 0.5, Level: 1, Pan: -1},
+		{Time: 50 * time.Millisecond, Level: 1, Pan: 1},
+		{Time: 80 * time.Millisecond, Level: 1, Pan: 0, Cutoff: 100},
+	}
+	mt := NewMultiTap(taps, newimpulse())
+	mt.SetSync(300) // first tap at 100ms
+	mt.SetMix(1)
+	frames, chans := echoes(Render(mt, Dtof(500*time.Millisecond, DefaultSampleRate)))
+	want := []int{Dtof(50*time.Millisecond, DefaultSampleRate), Dtof(100*time.Millisecond, DefaultSampleRate)}
+	if len(frames) != 2 || frames[0] != want[0] || chans[0] != 1 || frames[1] != want[1] || chans[1] != 0 {
+		t.Fatalf("have echoes at %v in %v, want %v in [1 0]; filtered tap should be below threshold", frames, chans, want)
+	}
+	if mt.Taps()[0].Time != 100*time.Millisecond {
+		t.Fatalf("have synced time %v, want 100ms", mt.Taps()[0].Time)
+	}
+}
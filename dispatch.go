@@ -1,6 +1,7 @@
 package snd
 
 import (
+	"runtime"
 	"sort"
 	"sync"
 )
@@ -9,10 +10,27 @@ import (
 // but those details can be worked out once additional audio
 // drivers are supported.
 
-type Dispatcher struct{ sync.WaitGroup }
+type Dispatcher struct {
+	local uint64    // atomic; calls to dp.Panic, first for alignment
+	st    settings  // such as by SetQuality
+	up    *settings // of the Dispatcher preparing this one, if nested
+	sync.WaitGroup
+	panics uint64 // calls to Panic seen
+	hooks  hooks
+}
 
 // Dispatch blocks until all inputs are prepared.
 func (dp *Dispatcher) Dispatch(tc uint64, inps ...*Input) {
+	dp.hooks.call(&dp.hooks.before, tc, inps)
+	dp.checkpanic(tc, inps)
+	dp.settle(inps)
+	if dp.Exact() {
+		for _, inp := range inps {
+			prepare(inp.sd, tc)
+		}
+		dp.hooks.call(&dp.hooks.after, tc, inps)
+		return
+	}
 	wt := inps[0].wt
 	for _, inp := range inps {
 		if inp.wt != wt {
@@ -21,11 +39,53 @@ func (dp *Dispatcher) Dispatch(tc uint64, inps ...*Input) {
 		}
 		dp.Add(1)
 		go func(sd Sound, tc uint64) {
-			sd.Prepare(tc)
+			prepare(sd, tc)
 			dp.Done()
 		}(inp.sd, tc)
 	}
 	dp.Wait()
+	dp.hooks.call(&dp.hooks.after, tc, inps)
+}
+
+// DispatchN prepares inps for n consecutive buffers starting at tc, calling
+// fn, if not nil, after each buffer with its tc, such as to collect output.
+// Inputs must be sorted as returned by GetInputs.
+//
+// Levels of the graph are found once for all buffers, the last input of each
+// level is prepared on the calling goroutine, and with a single processor
+// no goroutines are started at all, so offline rendering and backends filling
+// several buffers at once spend less time on overhead than calling Dispatch
+// for each buffer. Neither are goroutines started if dp is Exact.
+func (dp *Dispatcher) DispatchN(tc uint64, n int, fn func(tc uint64), inps ...*Input) {
+	levels := ByWT(inps).Slice()
+	serial := runtime.GOMAXPROCS(0) == 1 || dp.Exact()
+	for end := tc + uint64(n); tc < end; tc++ {
+		dp.hooks.call(&dp.hooks.before, tc, inps)
+		dp.checkpanic(tc, inps)
+		dp.settle(inps)
+		for _, lvl := range levels {
+			if serial {
+				for _, inp := range lvl {
+					prepare(inp.sd, tc)
+				}
+				continue
+			}
+			last := len(lvl) - 1
+			for _, inp := range lvl[:last] {
+				dp.Add(1)
+				go func(sd Sound, tc uint64) {
+					prepare(sd, tc)
+					dp.Done()
+				}(inp.sd, tc)
+			}
+			prepare(lvl[last].sd, tc)
+			dp.Wait()
+		}
+		dp.hooks.call(&dp.hooks.after, tc, inps)
+		if fn != nil {
+			fn(tc)
+		}
+	}
 }
 
 type Input struct {
@@ -58,7 +118,7 @@ func (a ByWT) Slice() (sl [][]*Input) {
 func GetInputs(sd Sound) []*Input {
 	inps := []*Input{{sd, 0}}
 	getinputs(sd, 1, &inps)
-	sort.Sort(ByWT(inps))
+	sort.Stable(ByWT(inps))
 	return inps
 }
 
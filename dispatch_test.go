@@ -41,6 +41,47 @@ func BenchmarkDispatch(b *testing.B) {
 	}
 }
 
+func TestDispatchN(t *testing.T) {
+	c := &counter{mono: newmono(nil)}
+	inps := GetInputs(NewGain(1, c))
+	var tcs []uint64
+	new(Dispatcher).DispatchN(5, 3, func(tc uint64) {
+		tcs = append(tcs, tc)
+	}, inps...)
+	if len(tcs) != 3 || tcs[0] != 5 || tcs[2] != 7 {
+		t.Fatalf("have buffers %v, want [5 6 7]", tcs)
+	}
+	if n := int(c.n); n != 3*len(c.out) {
+		t.Fatalf("have %v frames prepared, want %v", n, 3*len(c.out))
+	}
+}
+
+// benchmarks of dispatch for 16 buffers at a time.
+
+func BenchmarkDispatch16(b *testing.B) {
+	sd := mksound()
+	inps := GetInputs(sd)
+	dp := new(Dispatcher)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < 16; i++ {
+			dp.Dispatch(uint64(16*n+i), inps...)
+		}
+	}
+}
+
+func BenchmarkDispatchN16(b *testing.B) {
+	sd := mksound()
+	inps := GetInputs(sd)
+	dp := new(Dispatcher)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		dp.DispatchN(uint64(16*n), 16, nil, inps...)
+	}
+}
+
 func TestGetInputs(t *testing.T) {
 	sd := mksound()
 	inps := GetInputs(sd)
@@ -0,0 +1,150 @@
+package snd
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Drain passes its input through until drained, for ending playback without
+// cutting sound off, such as on exit. Draining stops notes from being played,
+// releases those held, renders tails of effects such as reverb and delay
+// until silent or for at most a duration, and then fades out.
+//
+// Notes meant to stop on draining must be played through Drain as a Noter.
+type Drain struct {
+	*mono
+	chans int
+
+	mu   sync.Mutex // guards nt and held against notes from other goroutines
+	nt   Noter
+	held map[int]bool
+
+	draining int32 // atomic
+	tail     int   // frames of tail left
+	quiet    int   // frames of silence so far
+	fade     int   // frames of fade
+	gain     float64
+	done     chan struct{}
+	closed   bool
+}
+
+// NewDrain returns Drain of in rendering tails for at most tail and then
+// fading out over fade.
+func NewDrain(tail, fade time.Duration, in Sound) *Drain {
+	sd := newmono(in)
+	sd.sr = in.SampleRate()
+	sd.out = make(Discrete, len(in.Samples()))
+	return &Drain{
+		mono:  sd,
+		chans: in.Channels(),
+		held:  make(map[int]bool),
+		tail:  Dtof(tail, sd.sr),
+		fade:  Dtof(fade, sd.sr),
+		gain:  1,
+		done:  make(chan struct{}),
+	}
+}
+
+func (dr *Drain) Channels() int { return dr.chans }
+
+// SetNoter plays notes on nt until draining.
+func (dr *Drain) SetNoter(nt Noter) {
+	dr.mu.Lock()
+	dr.nt = nt
+	dr.mu.Unlock()
+}
+
+// NoteOn plays key on the Noter unless draining.
+func (dr *Drain) NoteOn(key int, vel float64) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if dr.nt == nil || dr.Draining() {
+		return
+	}
+	dr.nt.NoteOn(key, vel)
+	dr.held[key] = true
+}
+
+// NoteOff releases key on the Noter.
+func (dr *Drain) NoteOff(key int) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if dr.nt == nil || !dr.held[key] {
+		return
+	}
+	dr.nt.NoteOff(key)
+	delete(dr.held, key)
+}
+
+// Drain starts draining and releases all notes held. It is safe to call
+// more than once.
+func (dr *Drain) Drain() {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if !atomic.CompareAndSwapInt32(&dr.draining, 0, 1) {
+		return
+	}
+	for key := range dr.held {
+		dr.nt.NoteOff(key)
+		delete(dr.held, key)
+	}
+}
+
+func (dr *Drain) Draining() bool { return atomic.LoadInt32(&dr.draining) == 1 }
+
+// Done returns a channel closed once drained and faded out.
+func (dr *Drain) Done() <-chan struct{} { return dr.done }
+
+// Close drains and waits until faded out or ctx is done, returning the error
+// of ctx if it ended first. Backends should be stopped after Close returns.
+func (dr *Drain) Close(ctx context.Context) error {
+	dr.Drain()
+	select {
+	case <-dr.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (dr *Drain) Prepare(uint64) {
+	draining := dr.Draining()
+	// silent below -80dB for 100ms ends the tail early.
+	const quiet = 1e-4
+	hold := Dtof(100*time.Millisecond, dr.sr)
+	for i := 0; i < len(dr.out); i += dr.chans {
+		var peak float64
+		for c := 0; c < dr.chans; c++ {
+			x := dr.in.Samples()[i+c]
+			peak = math.Max(peak, math.Abs(x))
+			dr.out[i+c] = dr.gain * x
+			if dr.off {
+				dr.out[i+c] = 0
+			}
+		}
+		if !draining || dr.closed {
+			continue
+		}
+		if dr.tail > 0 {
+			dr.tail--
+			if dr.quiet++; peak >= quiet {
+				dr.quiet = 0
+			}
+			if dr.quiet >= hold {
+				dr.tail = 0
+			}
+			continue
+		}
+		if dr.fade > 0 {
+			dr.gain -= dr.gain / float64(dr.fade)
+			dr.fade--
+		}
+		if dr.fade == 0 {
+			dr.gain, dr.closed = 0, true
+			close(dr.done)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package snd
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	var nl noterlog
+	lvl := newlevel()
+	lvl.set(1)
+	dr := NewDrain(50*time.Millisecond, 10*time.Millisecond, lvl)
+	dr.SetNoter(&nl)
+	dr.NoteOn(60, 1)
+	dr.NoteOn(64, 1)
+	dr.NoteOff(64)
+
+	Render(dr, 1000)
+	dr.Drain()
+	dr.NoteOn(67, 1)
+	if len(nl) != 4 || nl[3] != "off 60" {
+		t.Fatalf("have notes %v, want held note released and none played", nl)
+	}
+
+	// a tail that never falls silent is cut at 50ms and then faded.
+	out := Render(dr, Dtof(80*time.Millisecond, DefaultSampleRate))
+	select {
+	case <-dr.Done():
+	default:
+		t.Fatal("not done after tail and fade")
+	}
+	tail, fade := Dtof(50*time.Millisecond, DefaultSampleRate), Dtof(10*time.Millisecond, DefaultSampleRate)
+	if out[tail-1] != 1 || !equaleps(out[tail+fade/2], 0.5, 0.01) || out[tail+fade] != 0 {
+		t.Fatalf("have %v, %v, %v through fade", out[tail-1], out[tail+fade/2], out[tail+fade])
+	}
+	if err := dr.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// silence ends the tail early.
+	lvl.set(0)
+	dr = NewDrain(time.Second, 0, lvl)
+	dr.Drain()
+	Render(dr, Dtof(150*time.Millisecond, DefaultSampleRate))
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if err := dr.Close(ctx); err != nil {
+		t.Fatal("not done after silence")
+	}
+}
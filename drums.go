@@ -0,0 +1,256 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DrumPart sets a drum of a DrumMachine.
+type DrumPart struct {
+	// Tune is hertz of the body of a kick or snare, of the lowest oscillator
+	// of a hi-hat, or of the band of a clap.
+	Tune float64
+
+	// Decay is the time for a drum to fall by 60dB.
+	Decay time.Duration
+
+	// Snap belongs to [0..1] and is the level of the attack transient, the
+	// click of a kick, the rattle of a snare, the sizzle of a hi-hat, and the
+	// slap of a clap.
+	Snap float64
+}
+
+// Drums of a DrumMachine by General MIDI key.
+const (
+	DrumKick      = 36
+	DrumSnare     = 38
+	DrumClap      = 39
+	DrumHatClosed = 42
+	DrumHatOpen   = 46
+)
+
+// drumparts are keys of drums of a DrumMachine in order, with their names
+// as prefixes of params and their stock settings.
+var drumparts = []struct {
+	key  int
+	name string
+	part DrumPart
+}{
+	{DrumKick, "kick", DrumPart{Tune: 50, Decay: 500 * time.Millisecond, Snap: 0.5}},
+	{DrumSnare, "snare", DrumPart{Tune: 180, Decay: 250 * time.Millisecond, Snap: 0.6}},
+	{DrumClap, "clap", DrumPart{Tune: 1100, Decay: 300 * time.Millisecond, Snap: 0.7}},
+	{DrumHatClosed, "hat", DrumPart{Tune: 205.3, Decay: 80 * time.Millisecond, Snap: 0.5}},
+	{DrumHatOpen, "openhat", DrumPart{Tune: 205.3, Decay: 600 * time.Millisecond, Snap: 0.5}},
+}
+
+// drumaliases are other General MIDI keys played by drums of a DrumMachine.
+var drumaliases = map[int]int{35: DrumKick, 40: DrumSnare, 44: DrumHatClosed}
+
+// hatratios are frequencies of the six square oscillators of a hi-hat
+// relative to the lowest, as of the classic analog cymbal circuit.
+var hatratios = [6]float64{1, 1.4828, 1.8003, 2.5464, 2.6304, 3.8970}
+
+// DrumMachine synthesizes analog drum machine style drums, a kick of a
+// swept sine and click, a snare of tone and filtered noise, hi-hats of a
+// cluster of metallic squares through a highpass filter, and a clap of
+// noise bursts, each with tuning, decay, and snap.
+//
+// As DrumKit, drums are struck by General MIDI key, such as DrumKick, and
+// sound until decayed regardless of note offs, and an open hi-hat is choked
+// by a closed one. Keys not in the machine are ignored.
+type DrumMachine struct {
+	*mono
+	parts map[int]*DrumPart
+	hits  []*machineHit
+	rnd   *rand.Rand
+}
+
+// NewDrumMachine returns DrumMachine with stock settings of all drums.
+func NewDrumMachine() *DrumMachine {
+	dm := &DrumMachine{mono: newmono(nil), parts: make(map[int]*DrumPart), rnd: rand.New(rand.NewSource(1))}
+	for _, dp := range drumparts {
+		part := dp.part
+		dm.parts[dp.key] = &part
+	}
+	return dm
+}
+
+func (dm *DrumMachine) Inputs() []Sound { return nil }
+
+// Part returns settings of drum of key, such as DrumKick.
+func (dm *DrumMachine) Part(key int) DrumPart {
+	if p, ok := dm.parts[key]; ok {
+		return *p
+	}
+	return DrumPart{}
+}
+
+// SetPart sets drum of key, such as DrumKick, taking effect on the next hit.
+func (dm *DrumMachine) SetPart(key int, part DrumPart) {
+	if p, ok := dm.parts[key]; ok {
+		*p = part
+	}
+}
+
+// Params returns "tune" in hertz, "decay" in seconds, and "snap" of each drum
+// prefixed by its name as "kick", "snare", "clap", "hat", and "openhat", such
+// as "kick.tune".
+func (dm *DrumMachine) Params() []*Param {
+	var ps []*Param
+	for _, dp := range drumparts {
+		p := dm.parts[dp.key]
+		ps = append(ps,
+			NewParam(dp.name+".tune", func() float64 { return p.Tune }, func(x float64) { p.Tune = x }).Range(20, 5000, dp.part.Tune).In(UnitHz),
+			NewParam(dp.name+".decay",
+				func() float64 { return p.Decay.Seconds() },
+				func(x float64) { p.Decay = time.Duration(x * float64(time.Second)) }).Range(0.01, 4, dp.part.Decay.Seconds()).In(UnitSeconds),
+			NewParam(dp.name+".snap", func() float64 { return p.Snap }, func(x float64) { p.Snap = x }).Range(0, 1, dp.part.Snap).In(UnitPercent),
+		)
+	}
+	return ps
+}
+
+// NoteOn strikes the drum of key at velocity vel belonging to [0..1].
+func (dm *DrumMachine) NoteOn(key int, vel float64) {
+	if k, ok := drumaliases[key]; ok {
+		key = k
+	}
+	p, ok := dm.parts[key]
+	if !ok {
+		return
+	}
+	if key == DrumHatClosed {
+		for _, h := range dm.hits {
+			if h.key == DrumHatOpen {
+				h.amp = 0
+			}
+		}
+	}
+	h := &machineHit{DrumPart: *p, key: key, vel: vel, amp: 1, click: 1}
+	if h.Decay <= 0 {
+		h.Decay = time.Millisecond
+	}
+	h.fall = math.Pow(0.001, 1/(h.Decay.Seconds()*dm.sr))
+	switch key {
+	case DrumSnare:
+		h.f1.set(2000, 0.7, dm.sr)
+	case DrumClap:
+		h.f1.set(h.Tune, 2, dm.sr)
+	case DrumHatClosed, DrumHatOpen:
+		h.f1.set(10000, 1, dm.sr)
+		h.f2.set(6000+3000*h.Snap, 0.707, dm.sr)
+	}
+	dm.hits = append(dm.hits, h)
+}
+
+func (dm *DrumMachine) NoteOff(int) {}
+
+func (dm *DrumMachine) Pressure(float64) {}
+func (dm *DrumMachine) Bend(float64)     {}
+
+// Panic silences all drums sounding.
+func (dm *DrumMachine) Panic() {
+	for i := range dm.hits {
+		dm.hits[i] = nil
+	}
+	dm.hits = dm.hits[:0]
+}
+
+func (dm *DrumMachine) Prepare(uint64) {
+	for i := range dm.out {
+		dm.out[i] = 0
+	}
+	live := dm.hits[:0]
+	for _, h := range dm.hits {
+		for i := range dm.out {
+			x := h.next(dm.rnd, dm.sr)
+			if !dm.off {
+				dm.out[i] += 0.5 * h.vel * x
+			}
+		}
+		if !h.done() {
+			live = append(live, h)
+		}
+	}
+	for i := len(live); i < len(dm.hits); i++ {
+		dm.hits[i] = nil
+	}
+	dm.hits = live
+}
+
+// machineHit is a drum of a DrumMachine sounding.
+type machineHit struct {
+	DrumPart
+	key    int
+	vel    float64
+	amp    float64 // of body, falling over decay
+	fall   float64 // multiplier of amp per frame
+	click  float64 // of transient
+	t      int     // frames since hit
+	phases [6]float64
+	f1, f2 svf
+}
+
+func (h *machineHit) done() bool {
+	const quiet = 1e-4
+	return h.amp < quiet && h.click < quiet
+}
+
+// next returns the next frame of h.
+func (h *machineHit) next(rnd *rand.Rand, sr float64) float64 {
+	noise := 2*rnd.Float64() - 1
+	var x float64
+	switch h.key {
+	case DrumKick:
+		// pitch falls from two octaves above tune within tens of ms.
+		sweep := math.Exp(-float64(h.t) / (0.015 * sr))
+		x = h.amp * math.Sin(twopi*h.phases[0])
+		h.phases[0] += h.Tune * (1 + 3*sweep) / sr
+		x += h.Snap * h.click * noise
+		h.click *= math.Exp(-1 / (0.001 * sr))
+	case DrumSnare:
+		// the body of two modes rings shorter than the rattle of snares.
+		body := h.amp * h.amp
+		x = 0.6 * body * (math.Sin(twopi*h.phases[0]) + 0.6*math.Sin(twopi*h.phases[1]))
+		h.phases[0] += h.Tune / sr
+		h.phases[1] += 1.63 * h.Tune / sr
+		x += h.Snap * h.amp * h.f1.filter(noise, FilterHighPass)
+		h.click = 0
+	case DrumClap:
+		// three quick bursts of hands before the tail of the room.
+		const burst, bursts = 0.010, 3
+		env := h.amp * (1 - h.Snap/2)
+		if n := int(burst * sr); h.t < bursts*n {
+			h.click = h.Snap * math.Exp(-float64(h.t%n)/(0.002*sr))
+			env = math.Max(env, h.click)
+		} else {
+			h.click = 0
+		}
+		x = 2 * env * h.f1.filter(noise, FilterBandPass)
+	case DrumHatClosed, DrumHatOpen:
+		var sq float64
+		for k, r := range hatratios {
+			if h.phases[k] < 0.5 {
+				sq++
+			} else {
+				sq--
+			}
+			if h.phases[k] += r * h.Tune / sr; h.phases[k] >= 1 {
+				h.phases[k]--
+			}
+		}
+		y := h.f2.filter(h.f1.filter(sq/6, FilterBandPass), FilterHighPass)
+		x = 2 * h.amp * y
+		h.click = 0
+	}
+	if h.phases[0] >= 1 {
+		h.phases[0] -= math.Floor(h.phases[0])
+	}
+	if h.phases[1] >= 1 {
+		h.phases[1] -= math.Floor(h.phases[1])
+	}
+	h.amp *= h.fall
+	h.t++
+	return x
+}
@@ -0,0 +1,45 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDrumMachine(t *testing.T) {
+	sr := DefaultSampleRate
+	dm := NewDrumMachine()
+	for _, key := range []int{DrumKick, DrumSnare, DrumClap, DrumHatClosed, DrumHatOpen, 35} {
+		dm.NoteOn(key, 1)
+		if pk := Peak(Render(dm, Dtof(50*time.Millisecond, sr))); pk < 0.05 || pk > 1 {
+			t.Fatalf("key %v: have peak %v, want a hit without clipping", key, pk)
+		}
+	}
+	Render(dm, Dtof(5*time.Second, sr))
+	if n := len(dm.hits); n != 0 {
+		t.Fatalf("have %v hits after decay, want none", n)
+	}
+	dm.NoteOn(DrumHatOpen, 1)
+	dm.NoteOn(DrumHatClosed, 1)
+	if n := len(dm.hits); n != 2 || dm.hits[0].amp != 0 {
+		t.Fatalf("have open hat level %v, want choked by closed hat", dm.hits[0].amp)
+	}
+}
+
+func TestDrumMachineParams(t *testing.T) {
+	sr := DefaultSampleRate
+	var ps Params
+	dm := NewDrumMachine()
+	ps.Register("drums", dm)
+	if err := ps.Load(Preset{"drums.kick.tune": 80, "drums.kick.decay": 1, "drums.kick.snap": 0}); err != nil {
+		t.Fatal(err)
+	}
+	if p := dm.Part(DrumKick); p.Tune != 80 || p.Decay != time.Second || p.Snap != 0 {
+		t.Fatalf("have kick %+v, want loaded from params", p)
+	}
+	dm.NoteOn(DrumKick, 1)
+	Render(dm, Dtof(100*time.Millisecond, sr))
+	out := Render(dm, Dtof(200*time.Millisecond, sr))
+	if at, off := goertzel(out, 80, sr), goertzel(out, 50, sr); at < 4*off {
+		t.Fatalf("have energy %v at 80Hz and %v at 50Hz, want kick tuned to 80Hz", at, off)
+	}
+}
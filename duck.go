@@ -0,0 +1,120 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// AutoMix mixes a music bus with a priority bus, such as speech of a podcast
+// or stream, ducking music by depth whenever priority is above threshold.
+// Ducking falls over attack, is held for hold after priority falls back below
+// threshold so music doesn't swell between words, and recovers over release.
+//
+// The level of priority is taken by a Follower and music is attenuated by a
+// VCA, both of mono buses; a stereo bus may be mixed down with Conform first.
+type AutoMix struct {
+	*Mixer
+	fol  *Follower
+	duck *ducker
+	vca  *VCA
+}
+
+// NewAutoMix returns AutoMix of music and priority ducking by 12dB above -40dB,
+// falling over 50ms, holding for 500ms, and recovering over 1s.
+func NewAutoMix(music, priority Sound) *AutoMix {
+	fol := NewFollower(time.Millisecond, 20*time.Millisecond, priority)
+	duck := &ducker{mono: newmono(fol), threshold: -40, depth: 12, g: 1}
+	duck.SetTimes(50*time.Millisecond, 500*time.Millisecond, time.Second)
+	vca := NewVCA(duck, music)
+	return &AutoMix{Mixer: NewMixer(vca, priority), fol: fol, duck: duck, vca: vca}
+}
+
+// Threshold returns level of priority above which music is ducked.
+func (am *AutoMix) Threshold() Decibel     { return am.duck.threshold }
+func (am *AutoMix) SetThreshold(x Decibel) { am.duck.threshold = x }
+
+// Depth returns decibels music is ducked by, positive.
+func (am *AutoMix) Depth() Decibel     { return am.duck.depth }
+func (am *AutoMix) SetDepth(x Decibel) { am.duck.depth = x }
+
+func (am *AutoMix) Attack() time.Duration  { return am.duck.atk }
+func (am *AutoMix) Hold() time.Duration    { return am.duck.hold }
+func (am *AutoMix) Release() time.Duration { return am.duck.rel }
+
+// SetTimes sets how fast music ducks, how long it stays ducked after priority
+// falls quiet, and how fast it recovers.
+func (am *AutoMix) SetTimes(attack, hold, release time.Duration) {
+	am.duck.SetTimes(attack, hold, release)
+}
+
+// Ducking returns how far music is ducked at the last prepared frame.
+func (am *AutoMix) Ducking() Decibel { return -DecibelOf(am.duck.g) }
+
+// Params returns threshold and depth in dB, and attack, hold and release in
+// milliseconds.
+func (am *AutoMix) Params() []*Param {
+	ms := func(name string, d *time.Duration, max, def float64) *Param {
+		return NewParam(name,
+			func() float64 { return float64(*d) / float64(time.Millisecond) },
+			func(x float64) {
+				*d = time.Duration(x * float64(time.Millisecond))
+				am.duck.SetTimes(am.duck.atk, am.duck.hold, am.duck.rel)
+			}).Range(0, max, def).In(UnitMilliseconds)
+	}
+	return []*Param{
+		NewParam("threshold",
+			func() float64 { return float64(am.duck.threshold) },
+			func(x float64) { am.duck.threshold = Decibel(x) }).Range(-80, 0, -40).In(UnitDecibel),
+		NewParam("depth",
+			func() float64 { return float64(am.duck.depth) },
+			func(x float64) { am.duck.depth = Decibel(x) }).Range(0, 60, 12).In(UnitDecibel),
+		ms("attack", &am.duck.atk, 1000, 50),
+		ms("hold", &am.duck.hold, 5000, 500),
+		ms("release", &am.duck.rel, 5000, 1000),
+	}
+}
+
+// Panic recovers music at once.
+func (am *AutoMix) Panic() {
+	am.fol.Panic()
+	am.duck.g, am.duck.left = 1, 0
+}
+
+// ducker outputs gain of music by the level of priority from a Follower.
+type ducker struct {
+	*mono
+	threshold, depth Decibel
+	atk, hold, rel   time.Duration
+
+	ca, cr float64 // smoothing coefficients
+	held   int     // frames of hold
+	left   int     // frames of hold remaining
+	g      float64 // gain of music
+}
+
+func (dk *ducker) SetTimes(attack, hold, release time.Duration) {
+	dk.atk, dk.hold, dk.rel = attack, hold, release
+	dk.ca, dk.cr = smoothcoef(attack, dk.sr), smoothcoef(release, dk.sr)
+	dk.held = Dtof(hold, dk.sr)
+}
+
+func (dk *ducker) Prepare(uint64) {
+	thr, low := dk.threshold.Amp(), (-dk.depth).Amp()
+	for i, x := range dk.in.Samples() {
+		if x > thr {
+			dk.left = dk.held
+		} else if dk.left > 0 {
+			dk.left--
+		}
+		if dk.left > 0 || x > thr {
+			dk.g += dk.ca * (low - dk.g)
+		} else {
+			dk.g += dk.cr * (1 - dk.g)
+		}
+		if dk.off {
+			dk.out[i] = 1
+		} else {
+			dk.out[i] = math.Min(dk.g, 1)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoMix(t *testing.T) {
+	music := newunit()
+	voice := NewGain(0, newunit())
+	am := NewAutoMix(music, voice)
+	am.SetTimes(10*time.Millisecond, 200*time.Millisecond, 100*time.Millisecond)
+	sr := am.SampleRate()
+
+	last := func(d time.Duration) float64 {
+		out := Render(am, Dtof(d, sr))
+		return out[len(out)-1]
+	}
+	if x := last(50 * time.Millisecond); !equaleps(x, DefaultAmpFac, 1e-9) {
+		t.Fatalf("have %v without priority, want music alone %v", x, DefaultAmpFac)
+	}
+
+	voice.SetAmp(1)
+	want := DefaultAmpFac*Decibel(-12).Amp() + DefaultAmpFac
+	if x := last(100 * time.Millisecond); !equaleps(x, want, 1e-3) {
+		t.Fatalf("have %v with priority, want music ducked by 12dB %v", x, want)
+	}
+	if db := am.Ducking(); !equaleps(float64(db), 12, 0.01) {
+		t.Fatalf("have ducking %v, want 12dB", db)
+	}
+
+	voice.SetAmp(0)
+	want = DefaultAmpFac * Decibel(-12).Amp()
+	if x := last(150 * time.Millisecond); !equaleps(x, want, 1e-3) {
+		t.Fatalf("have %v during hold, want music ducked %v", x, want)
+	}
+	if x := last(time.Second); !equaleps(x, DefaultAmpFac, 1e-3) {
+		t.Fatalf("have %v after release, want music recovered %v", x, DefaultAmpFac)
+	}
+}
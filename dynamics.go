@@ -0,0 +1,111 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// compressor computes gain of a feed forward compressor from the level of a
+// detector signal, smoothing gain reduction in decibels.
+type compressor struct {
+	threshold Decibel
+	ratio     float64
+	ca, cr    float64 // attack and release coefficients
+	red       float64 // gain reduction in dB, never positive
+}
+
+func (c *compressor) times(attack, release time.Duration, sr float64) {
+	c.ca, c.cr = smoothcoef(attack, sr), smoothcoef(release, sr)
+}
+
+// gain returns the amplitude multiplier for a detector level.
+func (c *compressor) gain(level float64) float64 {
+	var want float64
+	if level > 0 {
+		if over := 20*math.Log10(level) - float64(c.threshold); over > 0 && c.ratio > 1 {
+			want = -over * (1 - 1/c.ratio)
+		}
+	}
+	if want < c.red {
+		c.red += c.ca * (want - c.red)
+	} else {
+		c.red += c.cr * (want - c.red)
+	}
+	return Decibel(c.red).Amp()
+}
+
+// crossover splits a signal into a band around a center frequency and the
+// remainder, such that both parts sum back to the signal exactly.
+type crossover struct {
+	svf
+}
+
+func (xo *crossover) split(x float64) (band, rest float64) {
+	band = xo.filter(x, FilterBandPass)
+	return band, x - band
+}
+
+// DeEsser compresses a single band of its input, such as sibilance of
+// vocals around 6kHz, leaving the rest of the spectrum untouched.
+type DeEsser struct {
+	*mono
+	xo      crossover
+	comp    compressor
+	freq, q float64
+}
+
+// NewDeEsser returns DeEsser of band centered on freq with width q, reducing
+// the band by ratio wherever its level is above threshold.
+func NewDeEsser(freq, q float64, threshold Decibel, ratio float64, in Sound) *DeEsser {
+	ds := &DeEsser{mono: newmono(in), freq: freq, q: q}
+	ds.xo.set(freq, q, ds.sr)
+	ds.comp.threshold, ds.comp.ratio = threshold, ratio
+	ds.comp.times(time.Millisecond, 60*time.Millisecond, ds.sr)
+	return ds
+}
+
+func (ds *DeEsser) Freq() float64 { return ds.freq }
+func (ds *DeEsser) Q() float64    { return ds.q }
+
+// SetBand sets center frequency and width of the band compressed.
+func (ds *DeEsser) SetBand(freq, q float64) {
+	ds.freq, ds.q = freq, q
+	ds.xo.set(freq, q, ds.sr)
+}
+
+func (ds *DeEsser) Threshold() Decibel     { return ds.comp.threshold }
+func (ds *DeEsser) SetThreshold(x Decibel) { ds.comp.threshold = x }
+func (ds *DeEsser) Ratio() float64         { return ds.comp.ratio }
+func (ds *DeEsser) SetRatio(x float64)     { ds.comp.ratio = x }
+
+// SetTimes sets attack and release of gain reduction.
+func (ds *DeEsser) SetTimes(attack, release time.Duration) {
+	ds.comp.times(attack, release, ds.sr)
+}
+
+// Reduction returns gain reduction of the band at the last prepared frame.
+func (ds *DeEsser) Reduction() Decibel { return Decibel(ds.comp.red) }
+
+// Params returns freq, q, threshold in dB, and ratio.
+func (ds *DeEsser) Params() []*Param {
+	return []*Param{
+		NewParam("freq", ds.Freq, func(x float64) { ds.SetBand(x, ds.q) }).Range(2000, 12000, 6000).In(UnitHz),
+		NewParam("q", ds.Q, func(x float64) { ds.SetBand(ds.freq, x) }).Range(0.5, 10, 1),
+		NewParam("threshold",
+			func() float64 { return float64(ds.comp.threshold) },
+			func(x float64) { ds.comp.threshold = Decibel(x) }).Range(-60, 0, -20).In(UnitDecibel),
+		NewParam("ratio", ds.Ratio, ds.SetRatio).Range(1, 20, 4),
+	}
+}
+
+func (ds *DeEsser) Prepare(uint64) {
+	for i, x := range ds.in.Samples() {
+		band, rest := ds.xo.split(x)
+		y := rest + band*ds.comp.gain(math.Abs(band))
+		if ds.off {
+			ds.out[i] = 0
+		} else {
+			ds.out[i] = y
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeEsser(t *testing.T) {
+	n := Dtof(500*time.Millisecond, DefaultSampleRate)
+	peak := func(hz float64) float64 {
+		osc := NewOscil(Sine(), hz, nil)
+		osc.SetAmp(0.5, nil)
+		ds := NewDeEsser(6000, 2, -20, 4, osc)
+		return Peak(Render(ds, n)[n/2:])
+	}
+	// band above threshold by 14dB is reduced by about 10.5dB.
+	if x := peak(6000); x > 0.2 || x < 0.1 {
+		t.Errorf("have peak %v in band, want about 0.15", x)
+	}
+	if x := peak(200); x < 0.49 || x > 0.51 {
+		t.Errorf("have peak %v outside band, want 0.5", x)
+	}
+}
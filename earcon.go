@@ -0,0 +1,254 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Earcon is a short sound of feedback of a UI, such as a tone, a sweep, or a
+// click, for apps sounding feedback without patches crafted by hand, and for
+// accessibility, where cues stand in for what is seen.
+//
+// Each note rises over Attack and decays 60dB over the rest of Dur.
+type Earcon struct {
+	// Name identifies the earcon for rate limiting, such as "error".
+	Name string
+
+	// Freq is of the tone in Hz, at the start of a sweep, or the ring of a
+	// click. To is Hz at the end of a sweep, zero holding Freq.
+	Freq, To float64
+
+	Dur    time.Duration // of each note
+	Attack time.Duration
+
+	// Notes is the number of notes, such as 2 of a double beep, zero playing
+	// one, Gap is the silence between them, and Step transposes each by
+	// semitones, such as 4 rising a major third.
+	Notes int
+	Gap   time.Duration
+	Step  float64
+
+	// Click sounds a tick of noise ringing at Freq in place of a tone.
+	Click bool
+
+	Gain Decibel
+
+	// Priority orders earcons waiting to play; an earcon of higher priority
+	// than the one playing cuts it off.
+	Priority int
+}
+
+// Earcons of common feedback, distinct in contour as well as pitch so they
+// are told apart by listeners who hear pitch poorly.
+var (
+	EarconClick   = Earcon{Name: "click", Freq: 2000, Dur: 15 * time.Millisecond, Click: true, Gain: -12}
+	EarconFocus   = Earcon{Name: "focus", Freq: 880, Dur: 40 * time.Millisecond, Attack: 2 * time.Millisecond, Gain: -18}
+	EarconSuccess = Earcon{Name: "success", Freq: 660, Dur: 90 * time.Millisecond, Attack: 5 * time.Millisecond, Notes: 2, Gap: 20 * time.Millisecond, Step: 5, Gain: -12, Priority: 1}
+	EarconNotify  = Earcon{Name: "notify", Freq: 520, To: 1040, Dur: 150 * time.Millisecond, Attack: 10 * time.Millisecond, Gain: -12, Priority: 1}
+	EarconWarning = Earcon{Name: "warning", Freq: 440, Dur: 120 * time.Millisecond, Attack: 5 * time.Millisecond, Notes: 2, Gap: 60 * time.Millisecond, Gain: -9, Priority: 2}
+	EarconError   = Earcon{Name: "error", Freq: 440, To: 220, Dur: 250 * time.Millisecond, Attack: 5 * time.Millisecond, Gain: -9, Priority: 3}
+)
+
+// cueFade is how long a cue cut off fades out, not to click.
+const cueFade = 5 * time.Millisecond
+
+// cue is an Earcon playing.
+type cue struct {
+	ec    Earcon
+	note  int
+	f     int // frames into the note or gap
+	nf    int // frames of a note
+	gap   int
+	inc   float64 // of phase per frame, multiplied by sweep
+	sweep float64 // of inc per frame
+	phase float64
+	res   resonator
+	fade  float64 // gain while cut off, falling to zero
+}
+
+// start starts note n of cue.
+func (c *cue) start(n int, sr float64) {
+	c.note, c.f, c.phase = n, 0, 0
+	hz := c.ec.Freq * math.Pow(2, float64(n)*c.ec.Step/12)
+	c.inc, c.sweep = hz/sr, 1
+	if c.ec.To > 0 {
+		c.sweep = math.Pow(c.ec.To/c.ec.Freq, 1/float64(c.nf))
+	}
+	if c.ec.Click {
+		c.res = resonator{}
+		c.res.set(hz, Ftod(c.nf, sr), sr)
+	}
+}
+
+// Cues plays earcons, one at a time, as a Sound of a single channel to mix
+// into the output of an app. Earcons wait their turn by priority, an earcon
+// of higher priority cutting off the one playing, and each earcon is dropped
+// if played again sooner than a rate limit of 100ms, so feedback of rapid
+// events doesn't pile up. Play is safe to call from any goroutine.
+type Cues struct {
+	*mono
+	rnd *rand.Rand
+
+	mu      sync.Mutex
+	playing *cue
+	fading  *cue
+	queue   []*cue
+	maxq    int
+	limit   int               // frames
+	last    map[string]uint64 // frame each earcon last played
+	frame   uint64
+}
+
+// NewCues returns Cues at the default sample rate, holding up to 4 earcons
+// waiting.
+func NewCues() *Cues {
+	cs := &Cues{mono: newmono(nil), rnd: rand.New(rand.NewSource(1)), maxq: 4, last: make(map[string]uint64)}
+	cs.limit = Dtof(100*time.Millisecond, cs.sr)
+	return cs
+}
+
+func (cs *Cues) Inputs() []Sound { return nil }
+
+// SetRateLimit sets how soon an earcon may play again; sooner it is dropped.
+func (cs *Cues) SetRateLimit(d time.Duration) {
+	cs.mu.Lock()
+	cs.limit = Dtof(d, cs.sr)
+	cs.mu.Unlock()
+}
+
+// SetQueue sets how many earcons may wait to play; more drop those of lowest
+// priority.
+func (cs *Cues) SetQueue(n int) {
+	cs.mu.Lock()
+	cs.maxq = n
+	cs.trim()
+	cs.mu.Unlock()
+}
+
+// Play plays ec from the next buffer, or once earcons of its priority or
+// higher are done, and reports whether it will play, false if rate limited
+// or dropped from a full queue.
+func (cs *Cues) Play(ec Earcon) bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	if at, ok := cs.last[ec.Name]; ok && ec.Name != "" && cs.frame-at < uint64(cs.limit) {
+		return false
+	}
+	cs.last[ec.Name] = cs.frame
+	c := &cue{ec: ec, nf: Dtof(ec.Dur, cs.sr), gap: Dtof(ec.Gap, cs.sr), fade: 1}
+	if c.nf < 1 {
+		c.nf = 1
+	}
+	cs.queue = append(cs.queue, c)
+	// stable, first in first out among equal priority.
+	sort.SliceStable(cs.queue, func(i, j int) bool { return cs.queue[i].ec.Priority > cs.queue[j].ec.Priority })
+	cs.trim()
+	for _, x := range cs.queue {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
+
+// trim drops earcons of lowest priority, the latest first, beyond the queue.
+func (cs *Cues) trim() {
+	if len(cs.queue) > cs.maxq {
+		cs.queue = cs.queue[:cs.maxq]
+	}
+}
+
+// Playing reports whether an earcon is playing or waiting.
+func (cs *Cues) Playing() bool {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	return cs.playing != nil || len(cs.queue) != 0
+}
+
+// next starts the next earcon waiting, cutting off the one playing if of
+// lower priority.
+func (cs *Cues) next() {
+	if len(cs.queue) == 0 {
+		return
+	}
+	c := cs.queue[0]
+	if cs.playing != nil {
+		if c.ec.Priority <= cs.playing.ec.Priority {
+			return
+		}
+		cs.fading = cs.playing
+	}
+	cs.queue = cs.queue[1:]
+	cs.playing = c
+	c.start(0, cs.sr)
+}
+
+// sample returns the next frame of c, and false once c is done.
+func (cs *Cues) sample(c *cue) (float64, bool) {
+	if c.f >= c.nf {
+		// between notes.
+		if c.note+1 >= c.ec.Notes {
+			return 0, false
+		}
+		if c.f++; c.f >= c.nf+c.gap {
+			c.start(c.note+1, cs.sr)
+		}
+		return 0, true
+	}
+	t := float64(c.f)
+	var x float64
+	if c.ec.Click {
+		// a tick of noise over the first 2ms excites the ring.
+		var n float64
+		if t < 0.002*cs.sr {
+			n = 2*cs.rnd.Float64() - 1
+		}
+		x = c.res.process(n) + 0.3*n
+	} else {
+		atk := float64(Dtof(c.ec.Attack, cs.sr))
+		env := math.Pow(0.001, (t-atk)/math.Max(1, float64(c.nf)-atk))
+		if t < atk {
+			env = t / atk
+		}
+		x = env * math.Sin(twopi*c.phase)
+		c.phase += c.inc
+		c.phase -= math.Floor(c.phase)
+		c.inc *= c.sweep
+	}
+	c.f++
+	return c.ec.Gain.Amp() * x, true
+}
+
+func (cs *Cues) Prepare(uint64) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	fall := 1 / (cueFade.Seconds() * cs.sr)
+	for i := range cs.out {
+		if cs.playing == nil || len(cs.queue) != 0 {
+			cs.next()
+		}
+		var y float64
+		if c := cs.playing; c != nil {
+			x, ok := cs.sample(c)
+			if !ok {
+				cs.playing = nil
+			}
+			y += x
+		}
+		if c := cs.fading; c != nil {
+			x, ok := cs.sample(c)
+			if c.fade -= fall; !ok || c.fade <= 0 {
+				cs.fading = nil
+			}
+			y += c.fade * x
+		}
+		if cs.off {
+			y = 0
+		}
+		cs.out[i] = y
+	}
+	cs.frame += uint64(len(cs.out))
+}
@@ -0,0 +1,62 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCues(t *testing.T) {
+	cs := NewCues()
+	if !cs.Play(EarconFocus) {
+		t.Fatal("have focus dropped, want played")
+	}
+	if cs.Play(EarconFocus) {
+		t.Error("have focus played again at once, want rate limited")
+	}
+	out := Render(cs, Dtof(50*time.Millisecond, cs.SampleRate()))
+	if pk := Peak(out); pk < 0.05 || pk > EarconFocus.Gain.Amp() {
+		t.Errorf("have peak %v, want up to %v", pk, EarconFocus.Gain.Amp())
+	}
+	Render(cs, Dtof(100*time.Millisecond, cs.SampleRate()))
+	if cs.Playing() {
+		t.Error("have playing after focus done")
+	}
+	if !cs.Play(EarconFocus) {
+		t.Error("have focus dropped after rate limit, want played")
+	}
+
+	// an error cuts off a notification, which cuts off nothing of higher
+	// priority waiting behind it.
+	cs = NewCues()
+	cs.SetQueue(2)
+	cs.Play(EarconNotify)
+	Render(cs, DefaultBufferLen)
+	cs.Play(EarconError)
+	cs.Play(EarconSuccess)
+	if cs.Play(EarconClick) {
+		t.Error("have click queued beyond a full queue of higher priority, want dropped")
+	}
+	Render(cs, DefaultBufferLen)
+	cs.mu.Lock()
+	playing, waiting := cs.playing.ec.Name, len(cs.queue)
+	cs.mu.Unlock()
+	if playing != "error" || waiting != 1 {
+		t.Errorf("have %v playing and %v waiting, want error and 1", playing, waiting)
+	}
+	out = Render(cs, Dtof(time.Second, cs.SampleRate()))
+	if Peak(out) == 0 || cs.Playing() {
+		t.Error("have cues silent or still playing after a second")
+	}
+}
+
+func TestEarconClick(t *testing.T) {
+	cs := NewCues()
+	cs.Play(EarconClick)
+	out := Render(cs, Dtof(50*time.Millisecond, cs.SampleRate()))
+	if pk := Peak(out[:Dtof(15*time.Millisecond, cs.SampleRate())]); pk == 0 {
+		t.Error("have click silent")
+	}
+	if pk := Peak(out[Dtof(20*time.Millisecond, cs.SampleRate()):]); pk != 0 {
+		t.Errorf("have peak %v after click, want silence", pk)
+	}
+}
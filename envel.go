@@ -37,13 +37,25 @@ func LinearDrive() Discrete {
 	return sig
 }
 
+// Drawer is an envelope or low frequency oscillator drawing its shape, such
+// as for a UI to draw modulation, without affecting its state or output.
+type Drawer interface {
+	// Draw fills pts with the shape at len(pts) points evenly spaced over its
+	// duration or a period, from the start.
+	Draw(pts Discrete)
+}
+
 // TODO rename ...
 type timed struct {
 	sig Discrete
 	nfr float64
 }
 
+// newtimed returns timed of sig over nfr frames, at least one.
 func newtimed(sig Discrete, nfr int) *timed {
+	if nfr < 1 {
+		nfr = 1
+	}
 	return &timed{sig, float64(nfr)}
 }
 
@@ -55,16 +67,42 @@ type seq struct {
 	pn  float64
 
 	lk int
+
+	edge
+	gate           Sound
+	onrise, onfall func()
+	hold           bool // go silent at end instead of looping
+	idling         bool
+	lastrate       float64 // frames the last period advances per frame
+}
+
+// idle locks sq at its last frame and silences output.
+func (sq *seq) idle() {
+	sq.r = len(sq.tms) - 1
+	sq.pn = sq.tms[sq.r].nfr - 1
+	sq.lk = sq.r
+	sq.idling = true
 }
 
+func (sq *seq) Inputs() []Sound { return []Sound{sq.in, sq.gate} }
+
 func newseq(in Sound) *seq {
-	return &seq{mono: newmono(in), lk: -1}
+	return &seq{mono: newmono(in), lk: -1, lastrate: 1}
 }
 
 func (sq *seq) Prepare(uint64) {
 	for i := range sq.out {
+		if sq.gate != nil {
+			switch rise, fall := sq.step(sq.gate.Index(i)); {
+			case rise:
+				sq.onrise()
+			case fall:
+				sq.onfall()
+			}
+		}
+
 		tm := sq.tms[sq.r]
-		if sq.off {
+		if sq.off || sq.idling {
 			sq.out[i] = 0
 		} else if sq.in == nil {
 			sq.out[i] = tm.sig.At(sq.pn / tm.nfr)
@@ -77,12 +115,19 @@ func (sq *seq) Prepare(uint64) {
 			continue
 		}
 
-		sq.pn++
+		if sq.r == len(sq.tms)-1 {
+			sq.pn += sq.lastrate
+		} else {
+			sq.pn++
+		}
 		if sq.pn >= tm.nfr {
 			sq.pn = 0
 			sq.r++
 			if sq.r == len(sq.tms) {
 				sq.r = 0
+				if sq.hold {
+					sq.idle()
+				}
 			}
 		}
 	}
@@ -92,29 +137,90 @@ func (sq *seq) Prepare(uint64) {
 type ADSR struct {
 	*seq
 	sustaining bool
+
+	atk, dcy, sus, rel time.Duration
+	susamp, maxamp     float64
 }
 
 func NewADSR(attack, decay, sustain, release time.Duration, susamp, maxamp float64, in Sound) *ADSR {
-	adsr := &ADSR{newseq(in), false}
-	sr := adsr.SampleRate()
+	adsr := &ADSR{
+		seq: newseq(in),
+		atk: attack, dcy: decay, sus: sustain, rel: release,
+		susamp: susamp, maxamp: maxamp,
+	}
+	adsr.build()
+	return adsr
+}
+
+func (adsr *ADSR) build() {
+	// segments share a counter so the envelope is as long as its periods.
+	fc := NewFrameCounter(adsr.SampleRate())
 
 	atksig := LinearDrive()
-	atksig.NormalizeRange(0, maxamp)
-	atk := newtimed(atksig, Dtof(attack, sr))
+	atksig.NormalizeRange(0, adsr.maxamp)
+	atk := newtimed(atksig, fc.Dur(adsr.atk))
 
 	// dcysig := LinearDecay()
 	dcysig := ExpDecay()
-	dcysig.NormalizeRange(maxamp, susamp)
-	dcy := newtimed(dcysig, Dtof(decay, sr))
+	dcysig.NormalizeRange(adsr.maxamp, adsr.susamp)
+	dcy := newtimed(dcysig, fc.Dur(adsr.dcy))
 
-	sus := newtimed(Discrete{susamp, susamp}, Dtof(sustain, sr))
+	sus := newtimed(Discrete{adsr.susamp, adsr.susamp}, fc.Dur(adsr.sus))
 
 	relsig := ExpDecay()
-	relsig.NormalizeRange(susamp, 0)
-	rel := newtimed(relsig, Dtof(release, sr))
+	relsig.NormalizeRange(adsr.susamp, 0)
+	rel := newtimed(relsig, fc.Dur(adsr.rel))
 
 	adsr.tms = []*timed{atk, dcy, sus, rel}
-	return adsr
+}
+
+// Params returns attack, decay, sustain, and release periods in seconds and
+// sustain and max amplitudes. Setting a param rebuilds the envelope in place.
+func (adsr *ADSR) Params() []*Param {
+	dur := func(name string, d *time.Duration) *Param {
+		return NewParam(name,
+			func() float64 { return d.Seconds() },
+			func(x float64) {
+				*d = time.Duration(x * float64(time.Second))
+				adsr.build()
+			})
+	}
+	amp := func(name string, a *float64) *Param {
+		return NewParam(name,
+			func() float64 { return *a },
+			func(x float64) {
+				*a = x
+				adsr.build()
+			})
+	}
+	return []*Param{
+		dur("attack", &adsr.atk).Range(0, 10, 0.01).In(UnitSeconds),
+		dur("decay", &adsr.dcy).Range(0, 10, 0.1).In(UnitSeconds),
+		dur("sustain", &adsr.sus).Range(0, 10, 0.2).In(UnitSeconds),
+		dur("release", &adsr.rel).Range(0, 10, 0.3).In(UnitSeconds),
+		amp("susamp", &adsr.susamp).Range(0, 1, 0.5),
+		amp("maxamp", &adsr.maxamp).Range(0, 1, 1),
+	}
+}
+
+// Draw draws attack, decay, sustain, and release periods over Dur, of
+// maximum and sustain amplitudes, without input; release is drawn at its
+// duration undamped.
+func (adsr *ADSR) Draw(pts Discrete) {
+	var total float64
+	for _, tm := range adsr.tms {
+		total += tm.nfr
+	}
+	r, start := 0, 0.0 // segment and the frame it starts
+	for i := range pts {
+		f := float64(i) * total / float64(len(pts))
+		for r < len(adsr.tms)-1 && f >= start+adsr.tms[r].nfr {
+			start += adsr.tms[r].nfr
+			r++
+		}
+		tm := adsr.tms[r]
+		pts[i] = tm.sig.At((f - start) / tm.nfr)
+	}
 }
 
 func (adsr *ADSR) Dur() time.Duration {
@@ -126,7 +232,7 @@ func (adsr *ADSR) Dur() time.Duration {
 }
 
 // Restart resets envelope to start from attack period.
-func (adsr *ADSR) Restart() { adsr.r, adsr.pn, adsr.lk = 0, 0, -1 }
+func (adsr *ADSR) Restart() { adsr.r, adsr.pn, adsr.lk, adsr.idling = 0, 0, -1, false }
 
 // Sustain locks envelope when sustain period is reached.
 func (adsr *ADSR) Sustain() {
@@ -134,6 +240,29 @@ func (adsr *ADSR) Sustain() {
 	adsr.lk = 2
 }
 
+// SetGate sets a gate that restarts the envelope on rising edges, sustaining
+// while high, and releases on falling edges, remaining silent until the next
+// rising edge. A nil gate returns the envelope to looping and being controlled
+// by method calls.
+func (adsr *ADSR) SetGate(gate Sound) {
+	adsr.gate = gate
+	adsr.hold = gate != nil
+	if adsr.hold {
+		adsr.idle()
+	} else {
+		adsr.Restart()
+	}
+	adsr.onrise = func() {
+		adsr.Restart()
+		adsr.Sustain()
+	}
+	adsr.onfall = func() { adsr.Release() }
+}
+
+// Idle reports whether a gated envelope has completed its release period and
+// is silent until the next rising edge.
+func (adsr *ADSR) Idle() bool { return adsr.idling }
+
 // Release immediately releases envelope from anywhere and starts release period.
 func (adsr *ADSR) Release() (ok bool) {
 	adsr.sustaining = false
@@ -144,6 +273,27 @@ func (adsr *ADSR) Release() (ok bool) {
 	return
 }
 
+// Damping returns damping of the release period belonging to [0..1].
+func (adsr *ADSR) Damping() float64 { return adsr.lastrate }
+
+// SetDamping sets how fast the release period passes, belonging to [0..1]:
+// at 1 it passes over its duration, slower toward 0, and at 0 it stops,
+// holding the level reached. Changing damping while releasing takes effect
+// at once, as piano dampers lifted by a pedal catch strings still ringing.
+func (adsr *ADSR) SetDamping(x float64) { adsr.lastrate = math.Max(0, math.Min(1, x)) }
+
+// Panic silences a gated envelope until the next rising edge, or restarts an
+// envelope without a gate.
+func (adsr *ADSR) Panic() {
+	adsr.sustaining = false
+	if adsr.gate == nil {
+		adsr.Restart()
+		return
+	}
+	adsr.edge = edge{}
+	adsr.idle()
+}
+
 type Damp struct {
 	*mono
 	sig  Discrete
@@ -155,10 +305,13 @@ func NewDamp(d time.Duration, in Sound) *Damp {
 	return &Damp{
 		mono: sd,
 		sig:  ExpDecay(),
-		n:    float64(Dtof(d, sd.SampleRate())),
+		n:    float64(atleast(1, Dtof(d, sd.SampleRate()))),
 	}
 }
 
+// Draw draws a period of decay, without input.
+func (dmp *Damp) Draw(pts Discrete) { drawperiod(dmp.sig.At, pts) }
+
 func (dmp *Damp) Prepare(uint64) {
 	for i := range dmp.out {
 		if dmp.off {
@@ -186,10 +339,13 @@ func NewDrive(d time.Duration, in Sound) *Drive {
 	return &Drive{
 		mono: sd,
 		sig:  ExpDrive(),
-		n:    float64(Dtof(d, sd.SampleRate())),
+		n:    float64(atleast(1, Dtof(d, sd.SampleRate()))),
 	}
 }
 
+// Draw draws a period of drive, without input.
+func (drv *Drive) Draw(pts Discrete) { drawperiod(drv.sig.At, pts) }
+
 func (drv *Drive) Prepare(uint64) {
 	for i := range drv.out {
 		if drv.off {
@@ -205,3 +361,10 @@ func (drv *Drive) Prepare(uint64) {
 		}
 	}
 }
+
+// drawperiod fills pts with fn of a period belonging to [0..1), evenly.
+func drawperiod(fn func(t float64) float64, pts Discrete) {
+	for i := range pts {
+		pts[i] = fn(float64(i) / float64(len(pts)))
+	}
+}
@@ -1,6 +1,7 @@
 package snd
 
 import (
+	"math"
 	"testing"
 	"time"
 )
@@ -32,3 +33,25 @@ func BenchmarkDrive(b *testing.B) {
 		env.Prepare(uint64(n))
 	}
 }
+
+func TestADSRDraw(t *testing.T) {
+	ms := time.Millisecond
+	env := NewADSR(5*ms, 10*ms, 15*ms, 20*ms, 0.7, 1, nil)
+	Render(env, 100)
+	n := Dtof(env.Dur(), env.SampleRate())
+	pts := make(Discrete, n)
+	env.Draw(pts)
+
+	// drawn as played from the start, leaving the envelope as it was.
+	want := Render(NewADSR(5*ms, 10*ms, 15*ms, 20*ms, 0.7, 1, nil), n)
+	for i := range pts {
+		if math.Abs(pts[i]-want[i]) > 1e-9 {
+			t.Fatalf("point %v: have %v, want %v", i, pts[i], want[i])
+		}
+	}
+	// rendering goes on a buffer in.
+	k := len(env.Samples()) + 50
+	if have := Render(env, 100); math.Abs(have[50]-want[k]) > 1e-9 {
+		t.Errorf("have %v after drawing, want %v", have[50], want[k])
+	}
+}
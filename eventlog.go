@@ -0,0 +1,249 @@
+package snd
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventKind is the kind of an Event.
+type EventKind int
+
+const (
+	EventNoteOn EventKind = iota
+	EventNoteOff
+	EventParam
+	EventOn
+	EventOff
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventNoteOn:
+		return "noteon"
+	case EventNoteOff:
+		return "noteoff"
+	case EventParam:
+		return "param"
+	case EventOn:
+		return "on"
+	case EventOff:
+		return "off"
+	}
+	return fmt.Sprintf("EventKind(%d)", int(k))
+}
+
+// Event is an event logged by an EventLog.
+type Event struct {
+	Frame uint64    // of the graph the event takes effect
+	Time  time.Time // wall clock when logged
+	Kind  EventKind
+	Node  string  // name given when watched
+	Key   int     // of notes
+	Value float64 // velocity of notes on, or value of params
+}
+
+func (ev Event) String() string {
+	switch ev.Kind {
+	case EventNoteOn:
+		return fmt.Sprintf("%v %v %v key=%v vel=%.3g", ev.Frame, ev.Node, ev.Kind, ev.Key, ev.Value)
+	case EventNoteOff:
+		return fmt.Sprintf("%v %v %v key=%v", ev.Frame, ev.Node, ev.Kind, ev.Key)
+	case EventParam:
+		return fmt.Sprintf("%v %v %v %v", ev.Frame, ev.Node, ev.Kind, ev.Value)
+	}
+	return fmt.Sprintf("%v %v %v", ev.Frame, ev.Node, ev.Kind)
+}
+
+// EventLog logs notes played, params changed, and sounds turned on and off
+// with the frame of the graph each takes effect, such as to debug events
+// arriving late or out of order. Only what is watched is logged, keeping the
+// last events up to a limit.
+//
+// Events logged between buffers take effect at the start of the next buffer;
+// events logged by hooks of the Dispatcher take effect at the start of the
+// buffer being prepared, if the EventLog was created before those hooks were
+// added. Sounds scheduled by OnAt and OffAt are logged at the frame scheduled.
+type EventLog struct {
+	frame uint64 // atomic; of the buffer events take effect
+
+	out Sound
+
+	mu     sync.Mutex
+	max    int
+	events []Event
+	start  int // of the oldest event once full
+
+	watch  []*watched
+	remove []func()
+}
+
+// watched is a Sound watched for turning on and off.
+type watched struct {
+	name string
+	sd   switcher
+	off  bool
+	at   int // frame into the buffer scheduled, or -1
+}
+
+// NewEventLog returns EventLog of out, the output of a graph prepared by dp,
+// keeping the last max events, and logging turning on and off until Close.
+func NewEventLog(dp *Dispatcher, out Sound, max int) *EventLog {
+	el := &EventLog{out: out, max: max}
+	el.remove = []func(){
+		dp.BeforeDispatch(el.before),
+		dp.AfterDispatch(el.after),
+	}
+	return el
+}
+
+// Close stops logging turning on and off; events logged are kept.
+func (el *EventLog) Close() {
+	for _, fn := range el.remove {
+		fn()
+	}
+}
+
+// Log logs ev, setting its frame and time.
+func (el *EventLog) Log(ev Event) {
+	ev.Frame, ev.Time = atomic.LoadUint64(&el.frame), time.Now()
+	el.mu.Lock()
+	el.log(ev)
+	el.mu.Unlock()
+}
+
+func (el *EventLog) log(ev Event) {
+	if len(el.events) < el.max {
+		el.events = append(el.events, ev)
+		return
+	}
+	if el.max > 0 {
+		el.events[el.start] = ev
+		el.start = (el.start + 1) % el.max
+	}
+}
+
+// Noter returns a Noter logging notes played on nt under name before playing
+// them.
+func (el *EventLog) Noter(name string, nt Noter) Noter { return &loggedNoter{el, name, nt} }
+
+type loggedNoter struct {
+	el   *EventLog
+	name string
+	nt   Noter
+}
+
+func (nl *loggedNoter) NoteOn(key int, vel float64) {
+	nl.el.Log(Event{Kind: EventNoteOn, Node: nl.name, Key: key, Value: vel})
+	nl.nt.NoteOn(key, vel)
+}
+
+func (nl *loggedNoter) NoteOff(key int) {
+	nl.el.Log(Event{Kind: EventNoteOff, Node: nl.name, Key: key})
+	nl.nt.NoteOff(key)
+}
+
+// WatchParams logs changes of params of ps added so far, by their name, made
+// through Param.Set and anything built on it such as presets and History.
+func (el *EventLog) WatchParams(ps *Params) {
+	for _, p := range ps.List() {
+		p, set := p, p.set
+		p.set = func(x float64) {
+			el.Log(Event{Kind: EventParam, Node: p.Name, Value: x})
+			set(x)
+		}
+	}
+}
+
+// Watch logs sd under name as it turns on and off, if it may be.
+func (el *EventLog) Watch(name string, sd Sound) {
+	sw, ok := sd.(switcher)
+	if !ok {
+		return
+	}
+	el.mu.Lock()
+	el.watch = append(el.watch, &watched{name: name, sd: sw, off: sw.IsOff(), at: -1})
+	el.mu.Unlock()
+}
+
+func (el *EventLog) before(tc, frame uint64) {
+	atomic.StoreUint64(&el.frame, frame)
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	for _, w := range el.watch {
+		if off := w.sd.IsOff(); off != w.off {
+			// turned on or off between buffers.
+			w.off = off
+			el.log(w.event(frame))
+		}
+		w.at = -1
+		if b, ok := w.sd.(interface{ base() *mono }); ok {
+			sc := b.base().sched
+			sc.mu.Lock()
+			on, off := sc.on, sc.off
+			sc.mu.Unlock()
+			if w.off && on >= 0 {
+				w.at = on
+			} else if !w.off && off >= 0 {
+				w.at = off
+			}
+		}
+	}
+}
+
+func (el *EventLog) after(tc, frame uint64) {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	for _, w := range el.watch {
+		if off := w.sd.IsOff(); off != w.off {
+			w.off = off
+			f := frame
+			if w.at >= 0 {
+				f += uint64(w.at)
+			}
+			el.log(w.event(f))
+		}
+	}
+	n := len(el.out.Samples()) / el.out.Channels()
+	atomic.StoreUint64(&el.frame, frame+uint64(n))
+}
+
+func (w *watched) event(frame uint64) Event {
+	kind := EventOn
+	if w.off {
+		kind = EventOff
+	}
+	return Event{Frame: frame, Time: time.Now(), Kind: kind, Node: w.name}
+}
+
+// Events returns events logged in order logged.
+func (el *EventLog) Events() []Event {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	evs := make([]Event, 0, len(el.events))
+	evs = append(evs, el.events[el.start:]...)
+	return append(evs, el.events[:el.start]...)
+}
+
+// Clear discards events logged.
+func (el *EventLog) Clear() {
+	el.mu.Lock()
+	el.events, el.start = el.events[:0], 0
+	el.mu.Unlock()
+}
+
+// WriteTo writes events logged to w, one a line as of Event.String, such as
+// to dump for analysis.
+func (el *EventLog) WriteTo(w io.Writer) (int64, error) {
+	var n int64
+	for _, ev := range el.Events() {
+		m, err := fmt.Fprintln(w, ev)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package snd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventLog(t *testing.T) {
+	var dp Dispatcher
+	c := NewConst(1)
+	c.Off()
+	el := NewEventLog(&dp, c, 16)
+	el.Watch("const", c)
+	ps := new(Params)
+	ps.Add(NewParam("level", func() float64 { return 0 }, func(float64) {}))
+	el.WatchParams(ps)
+	var nl noterlog
+	nt := el.Noter("keys", &nl)
+
+	ps.Lookup("level").Set(0.5)
+	dp.BeforeDispatch(func(tc, frame uint64) {
+		if tc == 3 {
+			nt.NoteOn(60, 1)
+		}
+	})
+	c.OnAt(3 * time.Millisecond)
+	c.OffAt(30 * time.Millisecond)
+	dp.Render(c, 8*DefaultBufferLen)
+	nt.NoteOff(60)
+
+	want := []string{
+		"0 level param 0.5",
+		"132 const on",
+		"512 keys noteon key=60 vel=1",
+		"1323 const off",
+		"2048 keys noteoff key=60",
+	}
+	evs := el.Events()
+	if len(evs) != len(want) {
+		t.Fatalf("have %v", evs)
+	}
+	for i, ev := range evs {
+		if ev.String() != want[i] {
+			t.Errorf("have %q, want %q", ev, want[i])
+		}
+	}
+	if len(nl) != 2 {
+		t.Fatalf("have notes %v played, want 2", nl)
+	}
+
+	var b strings.Builder
+	if _, err := el.WriteTo(&b); err != nil || b.String() != strings.Join(want, "\n")+"\n" {
+		t.Fatalf("have dump %q, err %v", b.String(), err)
+	}
+
+	for i := 0; i < 20; i++ {
+		nt.NoteOn(i, 1)
+	}
+	if evs := el.Events(); len(evs) != 16 || evs[15].Key != 19 || evs[0].Key != 4 {
+		t.Fatalf("have %v events from key %v, want 16 from 4", len(evs), evs[0].Key)
+	}
+}
@@ -0,0 +1,82 @@
+package snd
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Exact reports whether dp renders the same on every platform, as set by
+// SetExact.
+func (dp *Dispatcher) Exact() bool { return dp.settings().isexact() }
+
+// SetExact sets whether dp renders the same on every platform, such as for
+// golden renders compared bit for bit by test suites on amd64 and arm64.
+// When set, dp prepares sounds one at a time in the order of GetInputs.
+// Random sounds are always seeded.
+//
+// Sounds must also be made of signals sampled the same on every platform,
+// such as ExactSine in place of Sine, which differ in the last bit between
+// platforms by fused multiply-add or assembly of the math package.
+// Arithmetic of sounds themselves is otherwise IEEE 754 and the same, but
+// for fused multiply-add the compiler may emit on arm64 and others. Such
+// sounds, including those calling functions of the math package per frame,
+// may still differ in the last bit.
+func (dp *Dispatcher) SetExact(on bool) {
+	var x int32
+	if on {
+		x = 1
+	}
+	atomic.StoreInt32(&dp.settings().exact, x)
+}
+
+// pio2 is pi/2 split in parts exact of a product with a small integer.
+const (
+	pio2a = 1.57079632673412561417e+00
+	pio2b = 6.07710050650619224932e-11
+	pio2c = 2.02226624879595063154e-21
+)
+
+// sinexact returns the sine of x, rounding each operation so no compiler may
+// fuse them, the same on every platform.
+func sinexact(x float64) float64 {
+	if math.IsInf(x, 0) || math.IsNaN(x) {
+		return math.NaN()
+	}
+	// reduce x to r within pi/4 of k quarter turns.
+	k := math.Round(x / (math.Pi / 2))
+	r := x - float64(k*pio2a)
+	r = r - float64(k*pio2b)
+	r = r - float64(k*pio2c)
+	z := float64(r * r)
+
+	var y float64
+	switch int64(math.Mod(k, 4)+4) % 4 {
+	case 0:
+		y = sinpoly(r, z)
+	case 1:
+		y = cospoly(z)
+	case 2:
+		y = -sinpoly(r, z)
+	case 3:
+		y = -cospoly(z)
+	}
+	return y
+}
+
+// sinpoly returns the Taylor series of sine of r within pi/4, given z = r*r.
+func sinpoly(r, z float64) float64 {
+	p := -1.0 / 1307674368000 // 1/15!
+	for _, c := range [...]float64{1.0 / 6227020800, -1.0 / 39916800, 1.0 / 362880, -1.0 / 5040, 1.0 / 120, -1.0 / 6} {
+		p = float64(p*z) + c
+	}
+	return r + float64(float64(r*z)*p)
+}
+
+// cospoly returns the Taylor series of cosine within pi/4 of r, given z = r*r.
+func cospoly(z float64) float64 {
+	p := 1.0 / 20922789888000 // 1/16!
+	for _, c := range [...]float64{-1.0 / 87178291200, 1.0 / 479001600, -1.0 / 3628800, 1.0 / 40320, -1.0 / 720, 1.0 / 24, -1.0 / 2} {
+		p = float64(p*z) + c
+	}
+	return 1 + float64(z*p)
+}
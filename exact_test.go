@@ -0,0 +1,37 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSinExact(t *testing.T) {
+	for x := -100.0; x < 100; x += 0.0137 {
+		if have, want := sinexact(x), math.Sin(x); math.Abs(have-want) > 1e-15 {
+			t.Fatalf("have sin(%v) = %v, want %v", x, have, want)
+		}
+	}
+}
+
+func TestExact(t *testing.T) {
+	// renders are the same each time, and a sine table matches bits
+	// computed on any platform.
+	render := func() Discrete {
+		dp := new(Dispatcher)
+		dp.SetExact(true)
+		osc := NewOscil(ExactSine(), 440, nil)
+		return dp.Render(NewReverb(0.5, 0.5, NewComb(0.5, 10*time.Millisecond, osc)), 4096)
+	}
+	a, b := render(), render()
+	for i := range a {
+		if math.Float64bits(a[i]) != math.Float64bits(b[i]) {
+			t.Fatalf("have %v then %v at %v, want same", a[i], b[i], i)
+		}
+	}
+	// bits of a golden sine table rendered on amd64.
+	sig := ExactSine()
+	if have := math.Float64bits(sig[333]); have != 0x3fec7e8e52233cf4 {
+		t.Fatalf("have %#x at 333 of sine table, want %#x", have, uint64(0x3fec7e8e52233cf4))
+	}
+}
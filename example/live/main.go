@@ -0,0 +1,53 @@
+// Command live reads patch lines from stdin and applies them to a running graph.
+//
+//  $ go run ./example/live
+//  > osc lfo freq=4
+//  > osc a harm=saw freq=220 freqmod=lfo amp=-12dB
+//  > out a
+//  > set lfo.freq 0.5
+//  > osc b harm=square freq=110 amp=-18dB
+//  > mixer mix in=a
+//  > out mix
+//  > connect mix in=a,b
+//
+// Edits apply to the running graph between buffers, and changes of output,
+// such as of out and connect lines, crossfade in.
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/al"
+	"dasa.cc/snd/patch"
+)
+
+func main() {
+	hs := snd.NewHotswap(2, nil)
+	gain := snd.NewGain(snd.Decibel(-6).Amp(), hs)
+	const buffers = 1
+	if err := al.OpenDevice(buffers); err != nil {
+		log.Fatal(err)
+	}
+	al.Start(gain)
+
+	p := patch.New()
+	stop := p.Live(al.Dispatcher())
+	sc := bufio.NewScanner(os.Stdin)
+	for fmt.Print("> "); sc.Scan(); fmt.Print("> ") {
+		out := p.Out
+		if err := p.Exec(sc.Text()); err != nil {
+			fmt.Println(err)
+			continue
+		}
+		if p.Out != out {
+			hs.Swap(p.Out)
+		}
+	}
+	al.Stop()
+	stop()
+	al.CloseDevice()
+}
@@ -6,14 +6,18 @@ import (
 	"log"
 	"os"
 	"runtime/pprof"
+	"sync"
 	"time"
 
 	"dasa.cc/material"
 	"dasa.cc/material/icon"
 	"dasa.cc/snd"
 	"dasa.cc/snd/al"
+	"dasa.cc/snd/midi"
+	"dasa.cc/snd/wav"
 
 	"golang.org/x/mobile/app"
+	"golang.org/x/mobile/event/key"
 	"golang.org/x/mobile/event/lifecycle"
 	"golang.org/x/mobile/event/paint"
 	"golang.org/x/mobile/event/size"
@@ -35,8 +39,67 @@ var (
 
 	fps       int
 	lastpaint = time.Now()
+
+	keyboard = midi.NewKeyboard()
+
+	// performance recording of notes and audio timestamped by frames of recorder.
+	recorder *snd.Recorder
+	perfmu   sync.Mutex
+	perf     []midi.Event
 )
 
+// press plays key i, recording its note if recording a performance.
+func press(i int) {
+	keys[i].Press()
+	recnote(midi.NoteOnMsg(0, 72+i, 100))
+}
+
+// release releases key i, recording its note if recording a performance.
+func release(i int) {
+	keys[i].Release()
+	recnote(midi.NoteOffMsg(0, 72+i, 0))
+}
+
+func recnote(m midi.Message) {
+	if recorder == nil || !recorder.Recording() {
+		return
+	}
+	perfmu.Lock()
+	perf = append(perf, midi.Event{Frame: recorder.Frame(), Msg: m})
+	perfmu.Unlock()
+}
+
+// saveperf writes notes and audio of a performance as perf.mid and perf.wav.
+func saveperf(events []midi.Event, sig snd.Discrete) {
+	f, err := os.Create("perf.mid")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if err := midi.WriteFile(f, events, recorder.SampleRate(), bpm, 480); err != nil {
+		log.Println(err)
+	}
+	f.Close()
+
+	f, err = os.Create("perf.wav")
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	defer f.Close()
+	w, err := wav.NewWriter(f, recorder.Channels(), int(recorder.SampleRate()), 16)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if err := w.Write(sig); err != nil {
+		log.Println(err)
+	}
+	if err := w.Close(); err != nil {
+		log.Println(err)
+	}
+}
+
 func init() {
 	env.SetPalette(material.Palette{
 		Primary: material.BlueGrey500,
@@ -80,6 +143,28 @@ func onStart(ctx gl.Context) {
 		}
 	}
 
+	btnRecord := env.NewButton(ctx)
+	toolbar.AddAction(btnRecord)
+	btnRecord.SetIcon(icon.AvFiberManualRecord)
+	btnRecord.SetIconColor(material.White)
+	btnRecord.OnPress = func() {
+		if recorder.Recording() {
+			btnRecord.SetIconColor(material.White)
+			sig := recorder.Stop()
+			perfmu.Lock()
+			events := perf
+			perf = nil
+			perfmu.Unlock()
+			go saveperf(events, sig)
+		} else {
+			btnRecord.SetIconColor(env.Palette().Accent)
+			perfmu.Lock()
+			perf = nil
+			perfmu.Unlock()
+			recorder.Record()
+		}
+	}
+
 	btnMetronome := env.NewButton(ctx)
 	toolbar.AddAction(btnMetronome)
 	btnMetronome.SetIcon(icon.AvSlowMotionVideo)
@@ -142,19 +227,19 @@ func onStart(ctx gl.Context) {
 		btnkeys[i].OnTouch = func(ev touch.Event) {
 			switch ev.Type {
 			case touch.TypeBegin:
-				keys[j].Press()
+				press(j)
 				tseq[ev.Sequence] = j
 			case touch.TypeMove:
 				// TODO drag finger off piano and it still plays, should stop
 				if last, ok := tseq[ev.Sequence]; ok {
 					if j != last {
-						keys[last].Release()
-						keys[j].Press()
+						release(last)
+						press(j)
 						tseq[ev.Sequence] = j
 					}
 				}
 			case touch.TypeEnd:
-				keys[j].Release()
+				release(j)
 				delete(tseq, ev.Sequence)
 			}
 		}
@@ -194,7 +279,8 @@ func onStart(ctx gl.Context) {
 	metronome.Off()
 	master.Append(metronome)
 
-	al.Start(pan)
+	recorder = snd.NewRecorder(pan)
+	al.Start(recorder)
 	al.Notify()
 }
 
@@ -209,6 +295,28 @@ func onPaint(ctx gl.Context) {
 	lastpaint = now
 }
 
+// onKey plays keys from a computer keyboard, folding notes into the single
+// octave of piano keys.
+func onKey(ev key.Event) {
+	var m midi.Message
+	var ok bool
+	switch ev.Direction {
+	case key.DirPress:
+		m, ok = keyboard.Press(ev.Rune)
+	case key.DirRelease:
+		m, ok = keyboard.Release(ev.Rune)
+	}
+	if !ok {
+		return
+	}
+	i := int(m.Data1) % 12
+	if m.IsNoteOn() {
+		press(i)
+	} else {
+		release(i)
+	}
+}
+
 func onLayout(sz size.Event) {
 	toolbar.Span(4, 4, 4)
 	env.SetOrtho(sz)
@@ -308,6 +416,8 @@ func main() {
 				}
 			case touch.Event:
 				env.Touch(ev)
+			case key.Event:
+				onKey(ev)
 			case size.Event:
 				if glctx == nil {
 					a.Send(ev)
@@ -0,0 +1,74 @@
+package snd
+
+import "math"
+
+// Exciter adds presence by distorting the high band of its input and mixing
+// the generated harmonics back in with the dry signal.
+type Exciter struct {
+	*mono
+	chans  int
+	freq   float64
+	amount float64
+	pre    []svf // isolates the band to distort
+	post   []svf // removes harmonics folded below freq
+}
+
+// NewExciter returns Exciter of band above freq mixed back by amount, where
+// an amount of 1 adds generated harmonics at about the level of the band.
+func NewExciter(freq, amount float64, in Sound) *Exciter {
+	sd := newmono(in)
+	sd.out = make(Discrete, len(in.Samples()))
+	ex := &Exciter{
+		mono:   sd,
+		chans:  in.Channels(),
+		amount: amount,
+		pre:    make([]svf, in.Channels()),
+		post:   make([]svf, in.Channels()),
+	}
+	ex.SetFreq(freq)
+	return ex
+}
+
+func (ex *Exciter) Channels() int { return ex.chans }
+
+func (ex *Exciter) Freq() float64 { return ex.freq }
+
+// SetFreq sets the frequency above which harmonics are generated.
+func (ex *Exciter) SetFreq(hz float64) {
+	ex.freq = hz
+	for i := range ex.pre {
+		ex.pre[i].set(hz, 0.707, ex.sr)
+		ex.post[i].set(hz, 0.707, ex.sr)
+	}
+}
+
+func (ex *Exciter) Amount() float64     { return ex.amount }
+func (ex *Exciter) SetAmount(x float64) { ex.amount = x }
+
+func (ex *Exciter) Params() []*Param {
+	return []*Param{
+		NewParam("freq", ex.Freq, ex.SetFreq).Range(1000, 16000, 3000).In(UnitHz),
+		NewParam("amount", ex.Amount, ex.SetAmount).Range(0, 1, 0.5).In(UnitPercent),
+	}
+}
+
+// excite returns harmonics of x by asymmetric saturation, generating both odd
+// and even harmonics, with the band's own level removed.
+func excite(x float64) float64 {
+	const drive = 4
+	y := math.Tanh(drive*x+0.3) - math.Tanh(0.3)
+	return y/drive - x
+}
+
+func (ex *Exciter) Prepare(uint64) {
+	for i, x := range ex.in.Samples() {
+		c := i % ex.chans
+		hi := ex.pre[c].filter(x, FilterHighPass)
+		h := ex.post[c].filter(excite(hi), FilterHighPass)
+		if ex.off {
+			ex.out[i] = 0
+		} else {
+			ex.out[i] = x + ex.amount*h
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExciter(t *testing.T) {
+	n := Dtof(time.Second, DefaultSampleRate)
+
+	// a low tone below freq passes unaltered.
+	ex := NewExciter(3000, 1, NewGain(0.5, NewOscil(Sine(), 200, nil)))
+	if x := Peak(Render(ex, n)[n/2:]); x < 0.49 || x > 0.51 {
+		t.Fatalf("have low tone peak %.3f, want 0.5", x)
+	}
+
+	// a tone in the band gains harmonics above it; measure energy above the
+	// tone with a highpass well beyond it.
+	hf := func(amount float64) float64 {
+		ex := NewExciter(3000, amount, NewGain(0.5, NewOscil(Sine(), 4000, nil)))
+		hp := NewSVF(FilterHighPass, 10000, 0.707, ex)
+		return Peak(Render(hp, n)[n/2:])
+	}
+	if dry, wet := hf(0), hf(1); wet < 1.5*dry {
+		t.Fatalf("have harmonics dry %.4f wet %.4f, want wet louder", dry, wet)
+	}
+}
@@ -0,0 +1,16 @@
+// Package ext calls DSP code of C from a graph through snd.External, such as
+// code generated by Faust, whose compute function and variables of controls
+// are passed as pointers:
+//
+//  dsp := C.newmydsp()
+//  C.initmydsp(dsp, 44100)
+//  pr := ext.New(unsafe.Pointer(C.computemydsp), unsafe.Pointer(dsp), 1, 2)
+//  defer pr.Close()
+//  sd := snd.NewExternal(2, pr.Process, in)
+//  sd.AddParams(ext.Zone("gain", unsafe.Pointer(&dsp.fHslider0)).Range(0, 1, 0.5))
+//
+// Programs of Faust are better wrapped by NewFaust, mapping their controls to
+// params, with Go generated by command faust2snd.
+//
+// The package requires cgo.
+package ext // import "dasa.cc/snd/ext"
@@ -0,0 +1,114 @@
+package ext
+
+/*
+#include <stdlib.h>
+
+typedef void (*ext_compute)(void *dsp, int count, float **in, float **out);
+
+static void ext_call(void *fn, void *dsp, int count, float **in, float **out) {
+	((ext_compute)fn)(dsp, count, in, out);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"dasa.cc/snd"
+)
+
+// Processor calls a compute function of C with the signature of Faust:
+//
+//	void compute(void *dsp, int count, float **inputs, float **outputs)
+//
+// processing count frames of planar buffers, one for each channel. Buffers
+// are allocated by C, as C may not hold memory of Go, and samples are copied
+// to and from them each block.
+type Processor struct {
+	fn, dsp   unsafe.Pointer
+	nin, nout int
+	frames    int
+	ins, outs **C.float // arrays of channel buffers
+	inp, outp []*C.float
+}
+
+// New returns Processor calling compute with dsp, the state of the code, for
+// nin channels of input and nout of output. Close must be called to free
+// buffers.
+func New(compute, dsp unsafe.Pointer, nin, nout int) *Processor {
+	return &Processor{fn: compute, dsp: dsp, nin: nin, nout: nout}
+}
+
+// alloc returns an array of n buffers of frames each, and its slice.
+func alloc(n, frames int) (**C.float, []*C.float) {
+	if n == 0 {
+		return nil, nil
+	}
+	arr := (**C.float)(C.calloc(C.size_t(n), C.size_t(unsafe.Sizeof((*C.float)(nil)))))
+	bufs := (*[1 << 20]*C.float)(unsafe.Pointer(arr))[:n:n]
+	for i := range bufs {
+		bufs[i] = (*C.float)(C.calloc(C.size_t(frames), C.sizeof_float))
+	}
+	return arr, bufs
+}
+
+func free(arr **C.float, bufs []*C.float) {
+	for _, p := range bufs {
+		C.free(unsafe.Pointer(p))
+	}
+	if arr != nil {
+		C.free(unsafe.Pointer(arr))
+	}
+}
+
+// Process processes in to out, and is a snd.BlockFunc. Channels beyond those
+// given to New are ignored on input and silent on output.
+func (pr *Processor) Process(in, out [][]float32) {
+	frames := 0
+	if len(out) > 0 {
+		frames = len(out[0])
+	}
+	if frames > pr.frames {
+		free(pr.ins, pr.inp)
+		free(pr.outs, pr.outp)
+		pr.ins, pr.inp = alloc(pr.nin, frames)
+		pr.outs, pr.outp = alloc(pr.nout, frames)
+		pr.frames = frames
+	}
+	for i, p := range pr.inp {
+		buf := (*[1 << 28]float32)(unsafe.Pointer(p))[:frames:frames]
+		if i < len(in) {
+			copy(buf, in[i])
+		} else {
+			for j := range buf {
+				buf[j] = 0
+			}
+		}
+	}
+	C.ext_call(pr.fn, pr.dsp, C.int(frames), pr.ins, pr.outs)
+	for i, buf := range out {
+		if i < len(pr.outp) {
+			copy(buf, (*[1 << 28]float32)(unsafe.Pointer(pr.outp[i]))[:frames:frames])
+		} else {
+			for j := range buf {
+				buf[j] = 0
+			}
+		}
+	}
+}
+
+// Close frees buffers; Process must not be called after.
+func (pr *Processor) Close() {
+	free(pr.ins, pr.inp)
+	free(pr.outs, pr.outp)
+	pr.ins, pr.inp, pr.outs, pr.outp, pr.frames = nil, nil, nil, nil, 0
+}
+
+// Zone returns a param by name of the float of C at zone, such as a control
+// of Faust code, read and written directly.
+func Zone(name string, zone unsafe.Pointer) *snd.Param {
+	p := (*C.float)(zone)
+	return snd.NewParam(name,
+		func() float64 { return float64(*p) },
+		func(x float64) { *p = C.float(x) })
+}
@@ -0,0 +1,66 @@
+package ext
+
+import (
+	"testing"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/ext/internal/cdsp"
+)
+
+func TestProcessor(t *testing.T) {
+	dsp, gain := cdsp.New()
+	defer cdsp.Free(dsp)
+	pr := New(cdsp.Compute(), dsp, 1, 2)
+	defer pr.Close()
+
+	in := snd.NewOscil(snd.Sine(), 440, nil)
+	sd := snd.NewExternal(2, pr.Process, in)
+	sd.AddParams(Zone("gain", gain).Range(0, 1, 1))
+	sd.Params()[0].Set(0.5)
+
+	out := snd.Render(sd, 2*snd.DefaultBufferLen)
+	want := snd.Render(snd.NewOscil(snd.Sine(), 440, nil), 2*snd.DefaultBufferLen)
+	for i, x := range want {
+		l, r := out[2*i], out[2*i+1]
+		if d := l - 0.5*float64(float32(x)); d > 1e-6 || d < -1e-6 || l != -r {
+			t.Fatalf("frame %v: have %v %v, want %v", i, l, r, x/2)
+		}
+	}
+	if v := sd.Params()[0].Value(); v != 0.5 {
+		t.Fatalf("have gain %v, want 0.5", v)
+	}
+}
+
+func TestFaust(t *testing.T) {
+	var fs Faust
+	fs.New, fs.Delete, fs.Init, fs.Compute, fs.Inputs, fs.Outputs, fs.BuildUserInterface = cdsp.Faust()
+	in := snd.NewOscil(snd.Sine(), 440, nil)
+	sd := NewFaust(fs, in)
+	defer sd.Close()
+	if sd.Channels() != 2 || float64(cdsp.SampleRate(sd.dsp)) != snd.DefaultSampleRate {
+		t.Fatalf("have %v channels at %v", sd.Channels(), cdsp.SampleRate(sd.dsp))
+	}
+
+	ps := sd.Params()
+	if len(ps) != 2 {
+		t.Fatalf("have %v params, want gain and mute", len(ps))
+	}
+	if p := ps[0]; p.Name != "gain" || p.Unit != snd.UnitDecibel || p.Max != 2 || p.Value() != 1 {
+		t.Fatalf("have %q in %v to %v at %v", p.Name, p.Unit, p.Max, p.Value())
+	}
+	if p := ps[1]; p.Name != "out/mute" || p.Max != 1 {
+		t.Fatalf("have %q to %v", p.Name, p.Max)
+	}
+
+	ps[0].Set(0.5)
+	out := snd.Render(sd, snd.DefaultBufferLen)
+	if x := float64(float32(in.Samples()[1])) / 2; out[2] != x || out[3] != -x {
+		t.Fatalf("have %v %v, want %v", out[2], out[3], x)
+	}
+	ps[1].Set(1)
+	for _, x := range snd.Render(sd, snd.DefaultBufferLen) {
+		if x != 0 {
+			t.Fatal("have output muted")
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package ext
+
+/*
+#include <stdlib.h>
+#include <string.h>
+
+// ext_uiglue has the layout of UIGlue of Faust's CInterface.h.
+typedef struct {
+	void *ui;
+	void *openTabBox, *openHorizontalBox, *openVerticalBox, *closeBox;
+	void *addButton, *addCheckButton, *addVerticalSlider, *addHorizontalSlider, *addNumEntry;
+	void *addHorizontalBargraph, *addVerticalBargraph, *addSoundfile;
+	void *declare;
+} ext_uiglue;
+
+enum { ext_kopen, ext_kclose, ext_kbutton, ext_kcheck, ext_kslider, ext_kbargraph, ext_kdeclare };
+
+// ext_item is a call of a UIGlue callback, walked by Go.
+typedef struct {
+	int kind;
+	char *label, *key, *value;
+	float *zone;
+	float init, min, max, step;
+} ext_item;
+
+typedef struct {
+	ext_item *items;
+	int n, cap;
+} ext_ui;
+
+static char *ext_dup(const char *s) { return s ? strdup(s) : NULL; }
+
+static ext_item *ext_add(void *p, int kind, const char *label, float *zone) {
+	ext_ui *ui = p;
+	if (ui->n == ui->cap) {
+		ui->cap = ui->cap ? 2*ui->cap : 16;
+		ui->items = realloc(ui->items, ui->cap * sizeof(ext_item));
+	}
+	ext_item *it = &ui->items[ui->n++];
+	memset(it, 0, sizeof(ext_item));
+	it->kind = kind;
+	it->label = ext_dup(label);
+	it->zone = zone;
+	return it;
+}
+
+static void ext_open_box(void *ui, const char *label) { ext_add(ui, ext_kopen, label, NULL); }
+static void ext_close_box(void *ui) { ext_add(ui, ext_kclose, NULL, NULL); }
+static void ext_add_button(void *ui, const char *label, float *zone) { ext_add(ui, ext_kbutton, label, zone); }
+static void ext_add_check(void *ui, const char *label, float *zone) { ext_add(ui, ext_kcheck, label, zone); }
+
+static void ext_add_slider(void *ui, const char *label, float *zone, float init, float min, float max, float step) {
+	ext_item *it = ext_add(ui, ext_kslider, label, zone);
+	it->init = init, it->min = min, it->max = max, it->step = step;
+}
+
+static void ext_add_bargraph(void *ui, const char *label, float *zone, float min, float max) {
+	ext_item *it = ext_add(ui, ext_kbargraph, label, zone);
+	it->min = min, it->max = max;
+}
+
+static void ext_add_soundfile(void *ui, const char *label, const char *url, void **zone) {}
+
+static void ext_declare(void *ui, float *zone, const char *key, const char *value) {
+	ext_item *it = ext_add(ui, ext_kdeclare, NULL, zone);
+	it->key = ext_dup(key);
+	it->value = ext_dup(value);
+}
+
+static ext_ui *ext_build(void *fn, void *dsp) {
+	ext_ui *ui = calloc(1, sizeof(ext_ui));
+	ext_uiglue glue = {
+		ui,
+		ext_open_box, ext_open_box, ext_open_box, ext_close_box,
+		ext_add_button, ext_add_check, ext_add_slider, ext_add_slider, ext_add_slider,
+		ext_add_bargraph, ext_add_bargraph, ext_add_soundfile,
+		ext_declare,
+	};
+	((void (*)(void*, ext_uiglue*))fn)(dsp, &glue);
+	return ui;
+}
+
+static ext_item *ext_item_at(ext_ui *ui, int i) { return &ui->items[i]; }
+
+static void ext_free_ui(ext_ui *ui) {
+	for (int i = 0; i < ui->n; i++) {
+		free(ui->items[i].label);
+		free(ui->items[i].key);
+		free(ui->items[i].value);
+	}
+	free(ui->items);
+	free(ui);
+}
+
+static void *ext_new(void *fn) { return ((void *(*)(void))fn)(); }
+static void ext_delete(void *fn, void *dsp) { ((void (*)(void*))fn)(dsp); }
+static void ext_init(void *fn, void *dsp, int sr) { ((void (*)(void*, int))fn)(dsp, sr); }
+static int ext_num(void *fn, void *dsp) { return ((int (*)(void*))fn)(dsp); }
+*/
+import "C"
+
+import (
+	"strings"
+	"unsafe"
+
+	"dasa.cc/snd"
+)
+
+// Faust holds pointers to functions of code generated by Faust with its C
+// backend, named by the class name given to the compiler, such as newmydsp.
+// Command faust2snd generates Go wrapping a program with these set.
+type Faust struct {
+	New, Delete        unsafe.Pointer // newmydsp, deletemydsp
+	Init               unsafe.Pointer // initmydsp
+	Compute            unsafe.Pointer // computemydsp
+	Inputs, Outputs    unsafe.Pointer // getNumInputsmydsp, getNumOutputsmydsp
+	BuildUserInterface unsafe.Pointer // buildUserInterfacemydsp
+}
+
+// FaustSound is a Sound of a program of Faust. Its controls of sliders,
+// number entries, buttons and checkboxes are its params, named by their path
+// of boxes below the outermost, such as "env/attack", and in units of
+// snd where declared by unit metadata Faust knows, such as [unit:Hz].
+type FaustSound struct {
+	*snd.External
+	fs  Faust
+	dsp unsafe.Pointer
+	pr  *Processor
+}
+
+// NewFaust returns FaustSound of a new instance of the program of fs
+// processing in, which may be nil for programs without inputs. The instance
+// is initialized at the sample rate of in, or the default. Close must be
+// called to free it.
+func NewFaust(fs Faust, in snd.Sound) *FaustSound {
+	sr := snd.DefaultSampleRate
+	if in != nil {
+		sr = in.SampleRate()
+	}
+	dsp := C.ext_new(fs.New)
+	C.ext_init(fs.Init, dsp, C.int(sr))
+	nin, nout := int(C.ext_num(fs.Inputs, dsp)), int(C.ext_num(fs.Outputs, dsp))
+	pr := New(fs.Compute, dsp, nin, nout)
+	sd := &FaustSound{External: snd.NewExternal(nout, pr.Process, in), fs: fs, dsp: dsp, pr: pr}
+	if fs.BuildUserInterface != nil {
+		sd.AddParams(controls(fs.BuildUserInterface, dsp)...)
+	}
+	return sd
+}
+
+// Close frees the instance; the sound must not be prepared after.
+func (sd *FaustSound) Close() {
+	sd.pr.Close()
+	C.ext_delete(sd.fs.Delete, sd.dsp)
+}
+
+// controls returns params of controls of dsp built by the user interface
+// function fn.
+func controls(fn, dsp unsafe.Pointer) []*snd.Param {
+	ui := C.ext_build(fn, dsp)
+	defer C.ext_free_ui(ui)
+
+	units := make(map[*C.float]snd.Unit)
+	var (
+		path []string
+		ps   []*snd.Param
+		zone []*C.float
+	)
+	for i := 0; i < int(ui.n); i++ {
+		it := C.ext_item_at(ui, C.int(i))
+		label := C.GoString(it.label)
+		switch it.kind {
+		case C.ext_kopen:
+			path = append(path, label)
+		case C.ext_kclose:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
+		case C.ext_kdeclare:
+			if it.zone != nil && C.GoString(it.key) == "unit" {
+				if u, ok := unitOf(C.GoString(it.value)); ok {
+					units[it.zone] = u
+				}
+			}
+		case C.ext_kbutton, C.ext_kcheck, C.ext_kslider:
+			name := label
+			if len(path) > 1 {
+				name = strings.Join(append(path[1:len(path):len(path)], label), "/")
+			}
+			p := Zone(name, unsafe.Pointer(it.zone))
+			if it.kind == C.ext_kslider {
+				p.Range(float64(it.min), float64(it.max), float64(it.init))
+			} else {
+				p.Range(0, 1, 0)
+			}
+			ps = append(ps, p)
+			zone = append(zone, it.zone)
+		}
+	}
+	// declarations precede the control they describe.
+	for i, p := range ps {
+		if u, ok := units[zone[i]]; ok {
+			p.In(u)
+		}
+	}
+	return ps
+}
+
+// unitOf returns the unit of snd of a unit of Faust metadata.
+func unitOf(s string) (snd.Unit, bool) {
+	switch s {
+	case "Hz", "hz":
+		return snd.UnitHz, true
+	case "dB", "db":
+		return snd.UnitDecibel, true
+	case "s", "sec":
+		return snd.UnitSeconds, true
+	case "ms":
+		return snd.UnitMilliseconds, true
+	case "st", "semitones":
+		return snd.UnitSemitones, true
+	}
+	return snd.UnitNone, false
+}
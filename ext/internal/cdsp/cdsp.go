@@ -0,0 +1,95 @@
+// Package cdsp is DSP code of C in the form generated by Faust with its C
+// backend, for testing package ext: a gain of one input to two outputs, the
+// second inverted.
+package cdsp
+
+/*
+#include <stdlib.h>
+
+// UIGlue as of Faust's CInterface.h.
+typedef void (*openBoxFun)(void *ui, const char *label);
+typedef void (*closeBoxFun)(void *ui);
+typedef void (*addButtonFun)(void *ui, const char *label, float *zone);
+typedef void (*addSliderFun)(void *ui, const char *label, float *zone, float init, float min, float max, float step);
+typedef void (*addBargraphFun)(void *ui, const char *label, float *zone, float min, float max);
+typedef void (*addSoundfileFun)(void *ui, const char *label, const char *url, void **zone);
+typedef void (*declareFun)(void *ui, float *zone, const char *key, const char *value);
+
+typedef struct {
+	void *uiInterface;
+	openBoxFun openTabBox;
+	openBoxFun openHorizontalBox;
+	openBoxFun openVerticalBox;
+	closeBoxFun closeBox;
+	addButtonFun addButton;
+	addButtonFun addCheckButton;
+	addSliderFun addVerticalSlider;
+	addSliderFun addHorizontalSlider;
+	addSliderFun addNumEntry;
+	addBargraphFun addHorizontalBargraph;
+	addBargraphFun addVerticalBargraph;
+	addSoundfileFun addSoundfile;
+	declareFun declare;
+} UIGlue;
+
+typedef struct {
+	float gain;
+	float mute;
+	float level;
+	int sr;
+} cdsp;
+
+cdsp *newcdsp(void) { return calloc(1, sizeof(cdsp)); }
+void deletecdsp(cdsp *dsp) { free(dsp); }
+void initcdsp(cdsp *dsp, int sr) { dsp->sr = sr, dsp->gain = 1; }
+int getNumInputscdsp(cdsp *dsp) { return 1; }
+int getNumOutputscdsp(cdsp *dsp) { return 2; }
+
+void buildUserInterfacecdsp(cdsp *dsp, UIGlue *ui) {
+	ui->openVerticalBox(ui->uiInterface, "cdsp");
+	ui->declare(ui->uiInterface, &dsp->gain, "unit", "dB");
+	ui->addHorizontalSlider(ui->uiInterface, "gain", &dsp->gain, 1, 0, 2, 0.01);
+	ui->openHorizontalBox(ui->uiInterface, "out");
+	ui->addCheckButton(ui->uiInterface, "mute", &dsp->mute);
+	ui->addHorizontalBargraph(ui->uiInterface, "level", &dsp->level, 0, 1);
+	ui->closeBox(ui->uiInterface);
+	ui->closeBox(ui->uiInterface);
+}
+
+void computecdsp(cdsp *dsp, int count, float **in, float **out) {
+	float g = dsp->mute ? 0 : dsp->gain;
+	for (int i = 0; i < count; i++) {
+		out[0][i] = g * in[0][i];
+		out[1][i] = -g * in[0][i];
+	}
+}
+
+int getSampleRatecdsp(cdsp *dsp) { return dsp->sr; }
+*/
+import "C"
+
+import "unsafe"
+
+// Faust returns functions of the code, as in order of fields of ext.Faust.
+func Faust() (new, delete, init, compute, inputs, outputs, ui unsafe.Pointer) {
+	return unsafe.Pointer(C.newcdsp), unsafe.Pointer(C.deletecdsp), unsafe.Pointer(C.initcdsp),
+		unsafe.Pointer(C.computecdsp), unsafe.Pointer(C.getNumInputscdsp), unsafe.Pointer(C.getNumOutputscdsp),
+		unsafe.Pointer(C.buildUserInterfacecdsp)
+}
+
+// New returns state of the code initialized with gain of one, and its gain
+// control.
+func New() (dsp, gain unsafe.Pointer) {
+	d := C.newcdsp()
+	C.initcdsp(d, 44100)
+	return unsafe.Pointer(d), unsafe.Pointer(&d.gain)
+}
+
+// Compute returns the compute function.
+func Compute() unsafe.Pointer { return unsafe.Pointer(C.computecdsp) }
+
+// SampleRate returns the sample rate dsp was initialized at.
+func SampleRate(dsp unsafe.Pointer) int { return int(C.getSampleRatecdsp((*C.cdsp)(dsp))) }
+
+// Free frees dsp.
+func Free(dsp unsafe.Pointer) { C.deletecdsp((*C.cdsp)(dsp)) }
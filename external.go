@@ -0,0 +1,76 @@
+package snd
+
+// BlockFunc processes a block of planar input to planar output, one slice of
+// equal length for each channel, such as code of C called by package ext or a
+// function of a WASM module. Output must be written in full.
+type BlockFunc func(in, out [][]float32)
+
+// External is a Sound processing its input, if any, by external DSP code
+// called a block at a time, so existing code such as generated by Faust can be
+// dropped into a graph. Samples are converted to and from planar float32, the
+// layout such code expects. Params declared by AddParams, such as of package
+// ext addressing variables of the code, are returned by Params for presets
+// and automation.
+type External struct {
+	*mono
+	chans     int
+	fn        BlockFunc
+	ins, outs [][]float32
+	params    []*Param
+}
+
+// NewExternal returns External of chans channels output by fn processing in,
+// which may be nil for code generating sound such as a synth.
+func NewExternal(chans int, fn BlockFunc, in Sound) *External {
+	ex := &External{mono: newmono(in), chans: chans, fn: fn}
+	if in != nil {
+		ex.sr = in.SampleRate()
+	}
+	frames := len(ex.out)
+	ex.out = make(Discrete, frames*chans)
+	ex.outs = planar(chans, frames)
+	if in != nil {
+		ex.ins = planar(in.Channels(), frames)
+	}
+	return ex
+}
+
+func planar(chans, frames int) [][]float32 {
+	bufs := make([][]float32, chans)
+	for i := range bufs {
+		bufs[i] = make([]float32, frames)
+	}
+	return bufs
+}
+
+func (ex *External) Channels() int { return ex.chans }
+
+func (ex *External) Inputs() []Sound {
+	if ex.in == nil {
+		return nil
+	}
+	return []Sound{ex.in}
+}
+
+// AddParams declares params of the code, such as its controls.
+func (ex *External) AddParams(ps ...*Param) { ex.params = append(ex.params, ps...) }
+
+// Params returns params declared.
+func (ex *External) Params() []*Param { return ex.params }
+
+func (ex *External) Prepare(uint64) {
+	if ex.in != nil {
+		n := len(ex.ins)
+		for i, x := range ex.in.Samples() {
+			ex.ins[i%n][i/n] = float32(x)
+		}
+	}
+	ex.fn(ex.ins, ex.outs)
+	for i := range ex.out {
+		if ex.off {
+			ex.out[i] = 0
+		} else {
+			ex.out[i] = float64(ex.outs[i%ex.chans][i/ex.chans])
+		}
+	}
+}
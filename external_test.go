@@ -0,0 +1,32 @@
+package snd
+
+import "testing"
+
+func TestExternal(t *testing.T) {
+	// swaps channels, halving the left.
+	swap := func(in, out [][]float32) {
+		for i := range out[0] {
+			out[0][i], out[1][i] = in[1][i], in[0][i]/2
+		}
+	}
+	in := NewPan(-1, newunit())
+	sd := NewExternal(2, swap, in)
+	out := Render(sd, DefaultBufferLen)
+	if l, r := in.Samples()[0], in.Samples()[1]; out[0] != float64(float32(r)) || out[1] != float64(float32(l)/2) {
+		t.Fatalf("have %v %v", out[0], out[1])
+	}
+
+	var n int
+	gen := NewExternal(1, func(in, out [][]float32) {
+		if in != nil {
+			t.Fatal("have input of generator")
+		}
+		for i := range out[0] {
+			n++
+			out[0][i] = float32(n)
+		}
+	}, nil)
+	if out := Render(gen, 2*DefaultBufferLen); out[len(out)-1] != float64(2*DefaultBufferLen) {
+		t.Fatalf("have last %v", out[len(out)-1])
+	}
+}
@@ -1,12 +1,16 @@
 package snd
 
-import "math"
+import (
+	"math"
+	"time"
+)
 
 // LowPass is a 3rd order IIR filter.
 //
 // Recursive implementation of the Gaussian filter.
 type LowPass struct {
 	*mono
+	freq float64
 
 	// normalization factor
 	b float64
@@ -23,8 +27,19 @@ func (lp *LowPass) SetPassthrough(b bool) { lp.passthrough = b }
 func (lp *LowPass) Passthrough() bool     { return lp.passthrough }
 
 func NewLowPass(freq float64, in Sound) *LowPass {
+	lp := &LowPass{mono: newmono(in)}
+	lp.SetFreq(freq)
+	return lp
+}
+
+func (lp *LowPass) Freq() float64 { return lp.freq }
+
+// SetFreq recalculates coefficients for cutoff frequency freq.
+func (lp *LowPass) SetFreq(freq float64) {
+	lp.freq = freq
+
 	q := 5.0
-	s := in.SampleRate() / freq / q
+	s := lp.in.SampleRate() / freq / q
 
 	if s > 2.5 {
 		q = 0.98711*s - 0.96330
@@ -46,7 +61,11 @@ func NewLowPass(freq float64, in Sound) *LowPass {
 	b2 *= b0
 	b3 *= b0
 
-	return &LowPass{mono: newmono(in), b: b, b0: b0, b1: b1, b2: b2, b3: b3}
+	lp.b, lp.b0, lp.b1, lp.b2, lp.b3 = b, b0, b1, b2, b3
+}
+
+func (lp *LowPass) Params() []*Param {
+	return []*Param{NewParam("freq", lp.Freq, lp.SetFreq).Range(20, 20000, 1000).In(UnitHz)}
 }
 
 func (lp *LowPass) Prepare(uint64) {
@@ -61,3 +80,190 @@ func (lp *LowPass) Prepare(uint64) {
 		lp.d3, lp.d2, lp.d1 = lp.d2, lp.d1, lp.out[i]
 	}
 }
+
+// biquad is a second order IIR section in direct form I with coefficients
+// normalized by a0. See the Audio EQ Cookbook by Robert Bristow-Johnson.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (bq *biquad) filter(x float64) float64 {
+	y := bq.b0*x + bq.b1*bq.x1 + bq.b2*bq.x2 - bq.a1*bq.y1 - bq.a2*bq.y2
+	bq.x2, bq.x1 = bq.x1, x
+	bq.y2, bq.y1 = bq.y1, y
+	return y
+}
+
+func (bq *biquad) set(b0, b1, b2, a0, a1, a2 float64) {
+	bq.b0, bq.b1, bq.b2 = b0/a0, b1/a0, b2/a0
+	bq.a1, bq.a2 = a1/a0, a2/a0
+}
+
+// FilterType selects the response of a state variable filter.
+type FilterType int
+
+const (
+	FilterLowPass FilterType = iota
+	FilterBandPass
+	FilterHighPass
+	FilterNotch
+)
+
+// svf is a state variable filter by the trapezoidal integration of Andrew
+// Simper, stable under fast modulation of frequency.
+type svf struct {
+	k, a1, a2, a3 float64
+	ic1, ic2      float64
+}
+
+func (f *svf) set(freq, q, sr float64) {
+	if max := 0.49 * sr; freq > max {
+		freq = max
+	}
+	g := math.Tan(math.Pi * freq / sr)
+	f.k = 1 / q
+	f.a1 = 1 / (1 + g*(g+f.k))
+	f.a2 = g * f.a1
+	f.a3 = g * f.a2
+}
+
+func (f *svf) filter(x float64, typ FilterType) float64 {
+	v3 := x - f.ic2
+	v1 := f.a1*f.ic1 + f.a2*v3
+	v2 := f.ic2 + f.a2*f.ic1 + f.a3*v3
+	f.ic1 = 2*v1 - f.ic1
+	f.ic2 = 2*v2 - f.ic2
+	switch typ {
+	case FilterBandPass:
+		return f.k * v1
+	case FilterHighPass:
+		return x - f.k*v1 - v2
+	case FilterNotch:
+		return x - f.k*v1
+	default:
+		return v2
+	}
+}
+
+// SVF is a resonant state variable filter with selectable response. Band pass
+// is normalized to unity gain at its center frequency.
+type SVF struct {
+	*mono
+	svf
+	typ     FilterType
+	freq, q float64
+}
+
+// NewSVF returns SVF of type typ at cutoff or center frequency freq and
+// resonance q, where q of 0.707 is a flat response.
+func NewSVF(typ FilterType, freq, q float64, in Sound) *SVF {
+	f := &SVF{mono: newmono(in), typ: typ, freq: freq, q: q}
+	f.set(freq, q, f.sr)
+	return f
+}
+
+func (f *SVF) Type() FilterType       { return f.typ }
+func (f *SVF) SetType(typ FilterType) { f.typ = typ }
+func (f *SVF) Freq() float64          { return f.freq }
+func (f *SVF) Q() float64             { return f.q }
+
+func (f *SVF) SetFreq(freq float64) {
+	f.freq = freq
+	f.set(f.freq, f.q, f.sr)
+}
+
+func (f *SVF) SetQ(q float64) {
+	f.q = q
+	f.set(f.freq, f.q, f.sr)
+}
+
+func (f *SVF) Params() []*Param {
+	return []*Param{
+		NewParam("freq", f.Freq, f.SetFreq).Range(20, 20000, 1000).In(UnitHz),
+		NewParam("q", f.Q, f.SetQ).Range(0.5, 20, 0.707),
+	}
+}
+
+func (f *SVF) Prepare(uint64) {
+	for i, x := range f.in.Samples() {
+		y := f.filter(x, f.typ)
+		if f.off {
+			f.out[i] = 0
+		} else {
+			f.out[i] = y
+		}
+	}
+}
+
+// AutoWah sweeps a resonant filter with the amplitude envelope of its input.
+// Louder input opens the filter toward the top of its range.
+type AutoWah struct {
+	*mono
+	fol *Follower
+	svf
+	typ      FilterType
+	sens     float64
+	min, max float64
+	q        float64
+	freq     float64 // last cutoff
+}
+
+// NewAutoWah returns a band pass AutoWah sweeping from min to max hertz with
+// resonance q. Sensitivity scales the envelope of in, such that an envelope
+// of 1/sens reaches max.
+func NewAutoWah(sens, min, max, q float64, in Sound) *AutoWah {
+	return &AutoWah{
+		mono: newmono(nil),
+		fol:  NewFollower(5*time.Millisecond, 80*time.Millisecond, in),
+		typ:  FilterBandPass,
+		sens: sens, min: min, max: max, q: q,
+		freq: min,
+	}
+}
+
+func (aw *AutoWah) Inputs() []Sound { return []Sound{aw.fol} }
+
+// Follower returns the envelope follower driving aw, e.g. to set its times.
+func (aw *AutoWah) Follower() *Follower { return aw.fol }
+
+// SetType sets the filter response, typically FilterBandPass or FilterLowPass.
+func (aw *AutoWah) SetType(typ FilterType) { aw.typ = typ }
+
+func (aw *AutoWah) Sensitivity() float64      { return aw.sens }
+func (aw *AutoWah) SetSensitivity(x float64)  { aw.sens = x }
+func (aw *AutoWah) Range() (min, max float64) { return aw.min, aw.max }
+func (aw *AutoWah) SetRange(min, max float64) { aw.min, aw.max = min, max }
+func (aw *AutoWah) Q() float64                { return aw.q }
+func (aw *AutoWah) SetQ(q float64)            { aw.q = q }
+
+// Freq returns the cutoff of the last prepared frame.
+func (aw *AutoWah) Freq() float64 { return aw.freq }
+
+func (aw *AutoWah) Params() []*Param {
+	return []*Param{
+		NewParam("sensitivity", aw.Sensitivity, aw.SetSensitivity).Range(0, 10, 1),
+		NewParam("min", func() float64 { return aw.min }, func(x float64) { aw.min = x }).Range(20, 20000, 200).In(UnitHz),
+		NewParam("max", func() float64 { return aw.max }, func(x float64) { aw.max = x }).Range(20, 20000, 2000).In(UnitHz),
+		NewParam("q", aw.Q, aw.SetQ).Range(0.5, 20, 4),
+	}
+}
+
+func (aw *AutoWah) Prepare(uint64) {
+	in := aw.fol.in.Samples()
+	for i, env := range aw.fol.Samples() {
+		t := env * aw.sens
+		if t > 1 {
+			t = 1
+		}
+		// sweep exponentially so equal changes in envelope are equal in pitch.
+		aw.freq = aw.min * math.Pow(aw.max/aw.min, t)
+		aw.set(aw.freq, aw.q, aw.sr)
+		y := aw.filter(in[i], aw.typ)
+		if aw.off {
+			aw.out[i] = 0
+		} else {
+			aw.out[i] = y
+		}
+	}
+}
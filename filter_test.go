@@ -1,6 +1,56 @@
 package snd
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
+
+func TestSVF(t *testing.T) {
+	sr := DefaultSampleRate
+	n := Dtof(time.Second, sr)
+	tests := []struct {
+		typ      FilterType
+		freq     float64
+		min, max float64 // expected peak
+	}{
+		{FilterLowPass, 100, 0.95, 1.05},
+		{FilterLowPass, 10000, 0, 0.02},
+		{FilterHighPass, 100, 0, 0.02},
+		{FilterHighPass, 10000, 0.95, 1.05},
+		{FilterBandPass, 1000, 0.95, 1.05},
+		{FilterBandPass, 10000, 0, 0.15},
+		{FilterNotch, 1000, 0, 0.01},
+	}
+	for _, tt := range tests {
+		f := NewSVF(tt.typ, 1000, 0.707, NewOscil(Sine(), tt.freq, nil))
+		out := Render(f, n)
+		if x := Peak(out[n/2:]); x < tt.min || x > tt.max {
+			t.Errorf("type %v at %vHz: have peak %.3f, want [%v..%v]", tt.typ, tt.freq, x, tt.min, tt.max)
+		}
+	}
+}
+
+func TestFollower(t *testing.T) {
+	fol := NewFollower(time.Millisecond, 10*time.Millisecond, NewOscil(Sine(), 441, nil))
+	out := Render(fol, Dtof(100*time.Millisecond, DefaultSampleRate))
+	if x := out[len(out)-1]; x < 0.5 || x > 1 {
+		t.Fatalf("have envelope %v, want near peak of input", x)
+	}
+}
+
+func TestAutoWah(t *testing.T) {
+	quiet := NewGain(0.05, NewOscil(Sine(), 441, nil))
+	loud := NewOscil(Sine(), 441, nil)
+	var freqs [2]float64
+	for i, in := range []Sound{quiet, loud} {
+		aw := NewAutoWah(2, 200, 2000, 2, in)
+		Render(aw, Dtof(100*time.Millisecond, DefaultSampleRate))
+		freqs[i] = aw.Freq()
+	}
+	if freqs[0] >= freqs[1] || freqs[1] > 2000 || freqs[0] < 200 {
+		t.Fatalf("have cutoff quiet %v loud %v, want rising with level in [200..2000]", freqs[0], freqs[1])
+	}
+}
 
 func BenchmarkLowPass(b *testing.B) {
 	lp := NewLowPass(500, newunit())
@@ -0,0 +1,86 @@
+// Package fixed processes sound in Q15 fixed point, for targets where float64
+// is slow or absent, such as ARMv6 of a Raspberry Pi Zero or a soft float
+// microcontroller. It is opt in: only programs importing it carry it, and
+// package snd is unchanged.
+//
+// A Q15 holds a sample in [-1..1) as a 16 bit integer scaled by 1<<15.
+// Products are accumulated in 32 or 64 bits and saturate when stored, so
+// overloads clip rather than wrap. Nodes process buffers in place and may be
+// run alone, such as straight into a 16 bit device, or inside a graph of
+// package snd by a Sound, converting at its edges.
+//
+// Benchmarks are best run on the target, such as built for a Pi Zero by
+//
+//  GOARCH=arm GOARM=6 go test -c dasa.cc/snd/fixed
+//
+// and run there with -test.bench=.
+package fixed // import "dasa.cc/snd/fixed"
+
+import "math"
+
+// Q15 is a sample of fixed point with 15 fractional bits.
+type Q15 int16
+
+// One is the largest Q15, just under 1.
+const One Q15 = math.MaxInt16
+
+// FromFloat returns x as Q15, saturating outside [-1..1).
+func FromFloat(x float64) Q15 { return sat(int32(math.Round(x * (1 << 15)))) }
+
+// Float returns q as a float64 in [-1..1).
+func (q Q15) Float() float64 { return float64(q) / (1 << 15) }
+
+// Mul returns q times r, rounded.
+func (q Q15) Mul(r Q15) Q15 { return sat((int32(q)*int32(r) + 1<<14) >> 15) }
+
+// Add returns q plus r, saturating.
+func (q Q15) Add(r Q15) Q15 { return sat(int32(q) + int32(r)) }
+
+// sat returns x clipped to the range of Q15.
+func sat(x int32) Q15 {
+	if x > math.MaxInt16 {
+		return math.MaxInt16
+	} else if x < math.MinInt16 {
+		return math.MinInt16
+	}
+	return Q15(x)
+}
+
+// sat64 returns x clipped to the range of Q15.
+func sat64(x int64) Q15 {
+	if x > math.MaxInt16 {
+		return math.MaxInt16
+	} else if x < math.MinInt16 {
+		return math.MinInt16
+	}
+	return Q15(x)
+}
+
+// FromFloats sets dst to src converted, as far as the shorter of both.
+func FromFloats(dst []Q15, src []float64) {
+	if len(src) < len(dst) {
+		dst = dst[:len(src)]
+	}
+	for i := range dst {
+		dst[i] = FromFloat(src[i])
+	}
+}
+
+// ToFloats sets dst to src converted, as far as the shorter of both.
+func ToFloats(dst []float64, src []Q15) {
+	if len(src) < len(dst) {
+		dst = dst[:len(src)]
+	}
+	for i := range dst {
+		dst[i] = src[i].Float()
+	}
+}
+
+// PCM16 encodes src as signed 16 bit little endian PCM into dst, which must
+// be twice as long, as a device such as of OpenAL takes it.
+func PCM16(dst []byte, src []Q15) {
+	for i, q := range src {
+		dst[2*i] = byte(q)
+		dst[2*i+1] = byte(uint16(q) >> 8)
+	}
+}
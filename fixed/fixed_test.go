@@ -0,0 +1,71 @@
+package fixed
+
+import (
+	"math"
+	"testing"
+
+	"dasa.cc/snd"
+)
+
+func TestQ15(t *testing.T) {
+	if q := FromFloat(0.5).Mul(FromFloat(0.5)); q != FromFloat(0.25) {
+		t.Fatalf("have %v, want %v", q, FromFloat(0.25))
+	}
+	if q := FromFloat(0.75).Add(FromFloat(0.75)); q != One {
+		t.Fatalf("have %v of overload, want saturated %v", q, One)
+	}
+	if q := FromFloat(-2); q != math.MinInt16 {
+		t.Fatalf("have %v, want %v", q, math.MinInt16)
+	}
+	b := make([]byte, 2)
+	PCM16(b, []Q15{-2})
+	if b[0] != 0xfe || b[1] != 0xff {
+		t.Fatalf("have %x, want fe ff", b)
+	}
+}
+
+func TestSound(t *testing.T) {
+	// a fixed point tone through a lowpass tracks the same chain in float
+	// within the noise of 16 bits.
+	const sr, hz, fc, q = snd.DefaultSampleRate, 441, 2000, 0.707
+	osc := NewOsc(Sine(4096), hz, sr)
+	sd := NewSound(nil, osc, &Gain{Amp: FromFloat(0.5)}, NewLowPass(fc, q, sr))
+	have := snd.Render(sd, 4096)
+
+	w := 2 * math.Pi * fc / sr
+	alpha, cos := math.Sin(w)/(2*q), math.Cos(w)
+	a0 := 1 + alpha
+	b0, b1, a1, a2 := (1-cos)/2/a0, (1-cos)/a0, -2*cos/a0, (1-alpha)/a0
+	want := make(snd.Discrete, len(have))
+	var x1, x2, y1, y2 float64
+	for i := range want {
+		x := 0.5 * math.Sin(2*math.Pi*hz*float64(i)/sr)
+		y := b0*x + b1*x1 + b0*x2 - a1*y1 - a2*y2
+		x2, x1, y2, y1 = x1, x, y1, y
+		want[i] = y
+	}
+	if snr := snd.SNR(have, want); snr < 60 {
+		t.Fatalf("have SNR %v against float, want at least 60dB", snr)
+	}
+}
+
+func BenchmarkBiquad(b *testing.B) {
+	bq := NewLowPass(1000, 0.707, snd.DefaultSampleRate)
+	buf := make([]Q15, snd.DefaultBufferLen)
+	NewOsc(Sine(4096), 440, snd.DefaultSampleRate).Process(buf)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		bq.Process(buf)
+	}
+}
+
+func BenchmarkOsc(b *testing.B) {
+	osc := NewOsc(Sine(4096), 440, snd.DefaultSampleRate)
+	buf := make([]Q15, snd.DefaultBufferLen)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		osc.Process(buf)
+	}
+}
@@ -0,0 +1,114 @@
+package fixed
+
+import "math"
+
+// Node processes a buffer of samples in place.
+type Node interface {
+	Process(buf []Q15)
+}
+
+// Gain scales by its amplitude.
+type Gain struct {
+	Amp Q15
+}
+
+func (gn *Gain) Process(buf []Q15) {
+	for i, q := range buf {
+		buf[i] = q.Mul(gn.Amp)
+	}
+}
+
+// Mix adds its source to buffers, such as to sum voices.
+type Mix struct {
+	Src []Q15
+}
+
+func (mx *Mix) Process(buf []Q15) {
+	for i := range buf {
+		if i < len(mx.Src) {
+			buf[i] = buf[i].Add(mx.Src[i])
+		}
+	}
+}
+
+// Osc adds a wave of a table at a frequency to buffers, stepping phase of 32
+// bits so frequency doesn't drift, such as for a silent buffer to become a
+// tone.
+type Osc struct {
+	table []Q15 // length a power of two
+	shift uint  // of phase to index table
+	phase uint32
+	inc   uint32
+}
+
+// NewOsc returns Osc of table, whose length is a power of two, at freq of
+// sample rate sr.
+func NewOsc(table []Q15, freq, sr float64) *Osc {
+	n := len(table)
+	if n == 0 || n&(n-1) != 0 {
+		panic("fixed: table length not a power of two")
+	}
+	osc := &Osc{table: table, shift: 32}
+	for ; n > 1; n >>= 1 {
+		osc.shift--
+	}
+	osc.SetFreq(freq, sr)
+	return osc
+}
+
+// SetFreq sets frequency of sample rate sr.
+func (osc *Osc) SetFreq(freq, sr float64) {
+	osc.inc = uint32(int64(math.Round(freq / sr * (1 << 32))))
+}
+
+func (osc *Osc) Process(buf []Q15) {
+	for i := range buf {
+		buf[i] = buf[i].Add(osc.table[osc.phase>>osc.shift])
+		osc.phase += osc.inc
+	}
+}
+
+// Sine returns a table of one cycle of sine of n samples, a power of two.
+func Sine(n int) []Q15 {
+	table := make([]Q15, n)
+	for i := range table {
+		table[i] = FromFloat(math.Sin(2 * math.Pi * float64(i) / float64(n)))
+	}
+	return table
+}
+
+// Biquad is a second order filter of coefficients with 29 fractional bits,
+// covering the range of [-4..4) of common filters, and a 64 bit accumulator.
+type Biquad struct {
+	b0, b1, b2, a1, a2 int32
+	x1, x2, y1, y2     int32
+}
+
+// NewBiquad returns Biquad of coefficients normalized so a0 is 1, as of the
+// cookbook filters of Robert Bristow-Johnson.
+func NewBiquad(b0, b1, b2, a1, a2 float64) *Biquad {
+	q := func(x float64) int32 { return int32(math.Round(x * (1 << 29))) }
+	return &Biquad{b0: q(b0), b1: q(b1), b2: q(b2), a1: q(a1), a2: q(a2)}
+}
+
+// NewLowPass returns Biquad passing frequencies below freq with resonance q
+// at sample rate sr.
+func NewLowPass(freq, q, sr float64) *Biquad {
+	w := 2 * math.Pi * freq / sr
+	alpha := math.Sin(w) / (2 * q)
+	cos := math.Cos(w)
+	a0 := 1 + alpha
+	return NewBiquad((1-cos)/2/a0, (1-cos)/a0, (1-cos)/2/a0, -2*cos/a0, (1-alpha)/a0)
+}
+
+func (bq *Biquad) Process(buf []Q15) {
+	for i, q := range buf {
+		x := int32(q)
+		acc := int64(bq.b0)*int64(x) + int64(bq.b1)*int64(bq.x1) + int64(bq.b2)*int64(bq.x2) -
+			int64(bq.a1)*int64(bq.y1) - int64(bq.a2)*int64(bq.y2)
+		y := sat64((acc + 1<<28) >> 29)
+		bq.x2, bq.x1 = bq.x1, x
+		bq.y2, bq.y1 = bq.y1, int32(y)
+		buf[i] = y
+	}
+}
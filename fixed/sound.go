@@ -0,0 +1,57 @@
+package fixed
+
+import "dasa.cc/snd"
+
+// Sound runs nodes inside a graph of package snd, converting samples of its
+// input to Q15 and results back, such as to compare a fixed point chain with
+// its float counterpart. With a nil input, nodes start from silence each
+// buffer of a single channel.
+type Sound struct {
+	in    snd.Sound
+	nodes []Node
+	buf   []Q15
+	out   snd.Discrete
+}
+
+// NewSound returns Sound of nodes processing in, which may be nil.
+func NewSound(in snd.Sound, nodes ...Node) *Sound {
+	n := snd.DefaultBufferLen
+	if in != nil {
+		n = len(in.Samples())
+	}
+	return &Sound{in: in, nodes: nodes, buf: make([]Q15, n), out: make(snd.Discrete, n)}
+}
+
+func (sd *Sound) Channels() int {
+	if sd.in == nil {
+		return 1
+	}
+	return sd.in.Channels()
+}
+
+func (sd *Sound) SampleRate() float64 {
+	if sd.in == nil {
+		return snd.DefaultSampleRate
+	}
+	return sd.in.SampleRate()
+}
+
+func (sd *Sound) Inputs() []snd.Sound      { return []snd.Sound{sd.in} }
+func (sd *Sound) Samples() snd.Discrete    { return sd.out }
+func (sd *Sound) Interp(t float64) float64 { return sd.out.Interp(t) }
+func (sd *Sound) At(t float64) float64     { return sd.out.At(t) }
+func (sd *Sound) Index(i int) float64      { return sd.out.Index(i) }
+
+func (sd *Sound) Prepare(uint64) {
+	if sd.in == nil {
+		for i := range sd.buf {
+			sd.buf[i] = 0
+		}
+	} else {
+		FromFloats(sd.buf, sd.in.Samples())
+	}
+	for _, nd := range sd.nodes {
+		nd.Process(sd.buf)
+	}
+	ToFloats(sd.out, sd.buf)
+}
@@ -0,0 +1,88 @@
+// Package flac reads and writes FLAC audio.
+//
+// Frames of any encoder are decoded. Frames are encoded with fixed predictors
+// of order up to four and Rice coded residuals, falling back to verbatim
+// subframes where prediction does not help, trading some compression for a
+// small encoder.
+package flac // import "dasa.cc/snd/flac"
+
+// crc8 returns the CRC-8 of b with polynomial 0x07, as of frame headers.
+func crc8(b []byte) byte {
+	var c byte
+	for _, x := range b {
+		c ^= x
+		for i := 0; i < 8; i++ {
+			if c&0x80 != 0 {
+				c = c<<1 ^ 0x07
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// crc16 returns the CRC-16 of b with polynomial 0x8005, as of whole frames.
+func crc16(b []byte) uint16 {
+	var c uint16
+	for _, x := range b {
+		c ^= uint16(x) << 8
+		for i := 0; i < 8; i++ {
+			if c&0x8000 != 0 {
+				c = c<<1 ^ 0x8005
+			} else {
+				c <<= 1
+			}
+		}
+	}
+	return c
+}
+
+// bitwriter appends bits most significant first.
+type bitwriter struct {
+	b   []byte
+	acc uint64 // pending bits, right aligned
+	n   uint   // count of pending bits
+}
+
+// write appends the low n bits of x, n at most 32.
+func (bw *bitwriter) write(x uint64, n uint) {
+	bw.acc = bw.acc<<n | x&(1<<n-1)
+	bw.n += n
+	for bw.n >= 8 {
+		bw.n -= 8
+		bw.b = append(bw.b, byte(bw.acc>>bw.n))
+	}
+}
+
+// unary appends x zeros and a one.
+func (bw *bitwriter) unary(x uint64) {
+	for ; x >= 32; x -= 32 {
+		bw.write(0, 32)
+	}
+	bw.write(1, uint(x)+1)
+}
+
+// align pads with zeros to a byte boundary.
+func (bw *bitwriter) align() {
+	if bw.n != 0 {
+		bw.write(0, 8-bw.n)
+	}
+}
+
+// utf8 appends x coded as of frame numbers, an extension of UTF-8 to 36 bits.
+func (bw *bitwriter) utf8(x uint64) {
+	if x < 0x80 {
+		bw.write(x, 8)
+		return
+	}
+	n := uint(2) // bytes, each following byte holding six bits
+	for x >= 1<<(5*n+1) {
+		n++
+	}
+	// n leading ones and a zero, then the highest bits.
+	bw.write(uint64(byte(0xFF<<(8-n)))|x>>(6*(n-1)), 8)
+	for i := int(n) - 2; i >= 0; i-- {
+		bw.write(0x80|x>>(6*uint(i))&0x3F, 8)
+	}
+}
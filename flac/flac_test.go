@@ -0,0 +1,161 @@
+package flac
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, 2, 44100, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const frames = 10000 // two whole blocks and a partial one
+	sig := make([]float64, 2*frames)
+	var raw []byte
+	for i := range sig {
+		sig[i] = 0.5 * math.Sin(float64(i/2)*0.01)
+		if i%2 == 1 {
+			sig[i] = 0.25 // constant channel
+		}
+		n := int16(sig[i] * math.MaxInt16)
+		raw = append(raw, byte(n), byte(n>>8))
+	}
+	for _, xs := range [][]float64{sig[:300], sig[300:]} {
+		if err := w.Write(xs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b[:4]) != "fLaC" {
+		t.Fatalf("have marker %q, want fLaC", b[:4])
+	}
+	info := b[8:42]
+	if n := binary.BigEndian.Uint64(info[10:]) & (1<<36 - 1); n != frames {
+		t.Fatalf("total samples have %v, want %v", n, frames)
+	}
+	if sum := md5.Sum(raw); !bytes.Equal(info[18:], sum[:]) {
+		t.Fatal("signature does not match samples")
+	}
+	if len(b) > len(raw)/2 {
+		t.Fatalf("have %v bytes, want at most %v", len(b), len(raw)/2)
+	}
+	// every frame ends in the checksum of the frame.
+	for i, at := 0, 42; at < len(b); i++ {
+		if b[at] != 0xFF || b[at+1] != 0xF8 {
+			t.Fatalf("frame %v: missing sync code", i)
+		}
+		end := len(b)
+		if next := bytes.Index(b[at+2:], []byte{0xFF, 0xF8, 0x70, 0x18}); next != -1 {
+			end = at + 2 + next
+		}
+		if crc16(b[at:end-2]) != binary.BigEndian.Uint16(b[end-2:]) {
+			t.Fatalf("frame %v: checksum mismatch", i)
+		}
+		at = end
+	}
+}
+
+func TestWriterDepth(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.flac"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := NewWriter(f, 1, 44100, 32); err == nil {
+		t.Fatal("expected error for unsupported depth")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, depth := range []int{16, 24} {
+		var buf seekbuf
+		w, err := NewWriter(&buf, 3, 48000, depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a sine, noise, and silence then constant, longer than a block.
+		want := make([]float64, 3*5000)
+		for i := 0; i < len(want); i += 3 {
+			want[i] = 0.5 * math.Sin(float64(i)*0.01)
+			want[i+1] = float64(i*7919%1000)/500 - 1
+			if i > 3*4500 {
+				want[i+2] = 0.25
+			}
+		}
+		w.Write(want)
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		sig, f, err := Decode(bytes.NewReader(buf.b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f != (Format{Chans: 3, Rate: 48000, Depth: depth}) {
+			t.Fatalf("have format %+v", f)
+		}
+		if len(sig) != len(want) {
+			t.Fatalf("have %v samples, want %v", len(sig), len(want))
+		}
+		for i, x := range want {
+			if d := sig[i] - x; d > 1e-4 || d < -1e-4 {
+				t.Fatalf("depth %v: sample %v have %v, want %v", depth, i, sig[i], x)
+			}
+		}
+
+		// corrupt and truncated data are reported, not decoded.
+		b := append([]byte(nil), buf.b...)
+		b[len(b)/2] ^= 0x10
+		if _, _, err := Decode(bytes.NewReader(b)); err == nil {
+			t.Fatal("expected error for corrupt frame")
+		}
+		if _, _, err := Decode(bytes.NewReader(buf.b[:len(buf.b)-3])); err == nil {
+			t.Fatal("expected error for truncated frame")
+		}
+	}
+}
+
+// seekbuf is an in-memory io.WriteSeeker.
+type seekbuf struct {
+	b   []byte
+	off int
+}
+
+func (sb *seekbuf) Write(p []byte) (int, error) {
+	if n := sb.off + len(p); n > len(sb.b) {
+		sb.b = append(sb.b, make([]byte, n-len(sb.b))...)
+	}
+	sb.off += copy(sb.b[sb.off:], p)
+	return len(p), nil
+}
+
+func (sb *seekbuf) Seek(off int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		off += int64(sb.off)
+	case io.SeekEnd:
+		off += int64(len(sb.b))
+	}
+	sb.off = int(off)
+	return off, nil
+}
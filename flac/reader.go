@@ -0,0 +1,430 @@
+package flac
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Format describes encoding of FLAC data.
+type Format struct {
+	Chans int
+	Rate  int
+	Depth int
+}
+
+// Decode reads FLAC data from r and returns interleaved samples belonging to
+// [-1..1]. All subframe types and stereo decorrelations are supported; frame
+// checksums are verified but the MD5 signature of stream info is not.
+func Decode(r io.Reader) ([]float64, Format, error) {
+	var f Format
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, f, fmt.Errorf("flac: read failed: %v", err)
+	}
+	if len(b) < 4 || string(b[:4]) != "fLaC" {
+		return nil, f, fmt.Errorf("flac: not a FLAC file")
+	}
+
+	// metadata blocks, stream info first.
+	var total uint64
+	pos := 4
+	for last, first := false, true; !last; first = false {
+		if pos+4 > len(b) {
+			return nil, f, fmt.Errorf("flac: read metadata failed: %v", io.ErrUnexpectedEOF)
+		}
+		last = b[pos]&0x80 != 0
+		typ := b[pos] & 0x7F
+		size := int(b[pos+1])<<16 | int(b[pos+2])<<8 | int(b[pos+3])
+		pos += 4
+		if pos+size > len(b) {
+			return nil, f, fmt.Errorf("flac: read metadata failed: %v", io.ErrUnexpectedEOF)
+		}
+		if first != (typ == 0) || typ == 0 && size < 34 {
+			return nil, f, fmt.Errorf("flac: missing stream info")
+		}
+		if typ == 0 {
+			br := &bitreader{b: b[pos : pos+size]}
+			br.read(16 + 16 + 24 + 24) // block and frame sizes
+			rate, _ := br.read(20)
+			chans, _ := br.read(3)
+			depth, _ := br.read(5)
+			total, _ = br.read(36)
+			f = Format{Chans: int(chans) + 1, Rate: int(rate), Depth: int(depth) + 1}
+		}
+		pos += size
+	}
+
+	var sig []float64
+	var dec decoder
+	for n := uint64(0); pos < len(b) && (total == 0 || n < total); {
+		frame, err := dec.frame(b[pos:], f)
+		if err != nil {
+			return nil, f, fmt.Errorf("flac: frame at byte %v: %v", pos, err)
+		}
+		pos += frame
+		if total != 0 && n+uint64(dec.n) > total {
+			dec.n = int(total - n) // stream info is exact
+		}
+		for i := 0; i < dec.n; i++ {
+			for c := 0; c < dec.chans; c++ {
+				sig = append(sig, float64(dec.out[c][i])/float64(int64(1)<<uint(dec.depth-1)))
+			}
+		}
+		n += uint64(dec.n)
+	}
+	return sig, f, nil
+}
+
+var errSync = errors.New("lost sync")
+
+// decoder decodes FLAC frames, reusing buffers of channels.
+type decoder struct {
+	n     int // samples per channel of the last frame
+	chans int
+	depth int
+	out   [8][]int64
+}
+
+// frame decodes the frame at the start of b, returning its length in bytes.
+func (dec *decoder) frame(b []byte, f Format) (int, error) {
+	br := &bitreader{b: b}
+	sync, err := br.read(15)
+	if err != nil || sync != 0x7FFC {
+		return 0, errSync
+	}
+	br.read(1) // blocking strategy
+	bs, _ := br.read(4)
+	sr, _ := br.read(4)
+	ca, _ := br.read(4)
+	ss, _ := br.read(3)
+	br.read(1)
+	if _, err := br.utf8(); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case bs == 0:
+		return 0, fmt.Errorf("reserved block size")
+	case bs == 1:
+		dec.n = 192
+	case bs <= 5:
+		dec.n = 576 << (bs - 2)
+	case bs == 6:
+		x, _ := br.read(8)
+		dec.n = int(x) + 1
+	case bs == 7:
+		x, _ := br.read(16)
+		dec.n = int(x) + 1
+	default:
+		dec.n = 256 << (bs - 8)
+	}
+	switch sr {
+	case 12:
+		br.read(8)
+	case 13, 14:
+		br.read(16)
+	case 15:
+		return 0, fmt.Errorf("invalid sample rate")
+	}
+	dec.depth = [...]int{f.Depth, 8, 12, 0, 16, 20, 24, 32}[ss]
+	if dec.depth == 0 || dec.depth > 32 {
+		return 0, fmt.Errorf("invalid sample size")
+	}
+	crc, err := br.read(8)
+	if err != nil {
+		return 0, err
+	}
+	if byte(crc) != crc8(b[:br.pos/8-1]) {
+		return 0, fmt.Errorf("header checksum mismatch")
+	}
+
+	dec.chans = int(ca) + 1
+	if ca >= 8 {
+		if ca > 10 {
+			return 0, fmt.Errorf("reserved channel assignment")
+		}
+		dec.chans = 2
+	}
+	for c := 0; c < dec.chans; c++ {
+		depth := dec.depth
+		if ca == 8 && c == 1 || ca == 9 && c == 0 || ca == 10 && c == 1 {
+			depth++ // side channel
+		}
+		if cap(dec.out[c]) < dec.n {
+			dec.out[c] = make([]int64, dec.n)
+		}
+		dec.out[c] = dec.out[c][:dec.n]
+		if err := br.subframe(dec.out[c], depth); err != nil {
+			return 0, fmt.Errorf("channel %v: %v", c, err)
+		}
+	}
+	br.align()
+	crc, err = br.read(16)
+	if err != nil {
+		return 0, err
+	}
+	if uint16(crc) != crc16(b[:br.pos/8-2]) {
+		return 0, fmt.Errorf("frame checksum mismatch")
+	}
+
+	l, r := dec.out[0], dec.out[1]
+	switch ca {
+	case 8: // left, side
+		for i := range r {
+			r[i] = l[i] - r[i]
+		}
+	case 9: // side, right
+		for i := range l {
+			l[i] += r[i]
+		}
+	case 10: // mid, side
+		for i := range l {
+			mid := l[i]<<1 | r[i]&1
+			l[i], r[i] = (mid+r[i])>>1, (mid-r[i])>>1
+		}
+	}
+	return br.pos / 8, nil
+}
+
+// bitreader reads bits most significant first.
+type bitreader struct {
+	b   []byte
+	pos int // in bits
+}
+
+// read reads n bits, n at most 64.
+func (br *bitreader) read(n uint) (uint64, error) {
+	if br.pos+int(n) > 8*len(br.b) {
+		br.pos = 8 * len(br.b)
+		return 0, io.ErrUnexpectedEOF
+	}
+	var x uint64
+	for n > 0 {
+		avail := 8 - uint(br.pos&7)
+		take := avail
+		if n < take {
+			take = n
+		}
+		x = x<<take | uint64(br.b[br.pos>>3]>>(avail-take))&(1<<take-1)
+		br.pos += int(take)
+		n -= take
+	}
+	return x, nil
+}
+
+// signed reads n bits as a two's complement integer.
+func (br *bitreader) signed(n uint) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+	x, err := br.read(n)
+	return int64(x<<(64-n)) >> (64 - n), err
+}
+
+// unary reads zeros up to a one, returning the count of zeros.
+func (br *bitreader) unary() (uint64, error) {
+	var x uint64
+	for {
+		if br.pos&7 == 0 && br.pos>>3 < len(br.b) && br.b[br.pos>>3] == 0 {
+			br.pos += 8
+			x += 8
+			continue
+		}
+		bit, err := br.read(1)
+		if err != nil {
+			return 0, err
+		}
+		if bit == 1 {
+			return x, nil
+		}
+		x++
+	}
+}
+
+// utf8 reads a frame or sample number as coded by bitwriter.utf8.
+func (br *bitreader) utf8() (uint64, error) {
+	x, err := br.read(8)
+	if err != nil || x < 0x80 {
+		return x, err
+	}
+	n := 0 // following bytes
+	for ; x&(0x40>>uint(n)) != 0; n++ {
+		if n == 6 {
+			return 0, fmt.Errorf("invalid frame number")
+		}
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("invalid frame number")
+	}
+	x &= 0x3F >> uint(n)
+	for ; n > 0; n-- {
+		y, err := br.read(8)
+		if err != nil || y&0xC0 != 0x80 {
+			return 0, fmt.Errorf("invalid frame number")
+		}
+		x = x<<6 | y&0x3F
+	}
+	return x, nil
+}
+
+func (br *bitreader) align() { br.pos = (br.pos + 7) &^ 7 }
+
+// fixed are coefficients of the fixed predictors by order.
+var fixed = [...][]int64{{}, {1}, {2, -1}, {3, -3, 1}, {4, -6, 4, -1}}
+
+// subframe decodes a subframe of samples x of bit depth.
+func (br *bitreader) subframe(x []int64, depth int) error {
+	hdr, err := br.read(8)
+	if err != nil {
+		return err
+	}
+	if hdr&0x80 != 0 {
+		return fmt.Errorf("invalid subframe")
+	}
+	var wasted uint
+	if hdr&1 != 0 {
+		k, err := br.unary()
+		if err != nil {
+			return err
+		}
+		if int(k)+1 >= depth {
+			return fmt.Errorf("invalid wasted bits")
+		}
+		wasted = uint(k) + 1
+		depth -= int(wasted)
+	}
+
+	typ := hdr >> 1 & 0x3F
+	switch {
+	case typ == 0: // constant
+		v, err := br.signed(uint(depth))
+		if err != nil {
+			return err
+		}
+		for i := range x {
+			x[i] = v
+		}
+	case typ == 1: // verbatim
+		for i := range x {
+			if x[i], err = br.signed(uint(depth)); err != nil {
+				return err
+			}
+		}
+	case typ >= 8 && typ <= 12:
+		if err := br.predicted(x, depth, fixed[typ-8], 0); err != nil {
+			return err
+		}
+	case typ >= 32:
+		if err := br.predicted(x, depth, nil, int(typ-31)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("reserved subframe type %v", typ)
+	}
+	if wasted != 0 {
+		for i := range x {
+			x[i] <<= wasted
+		}
+	}
+	return nil
+}
+
+// predicted decodes a subframe of fixed coefficients, or if nil, of linear
+// prediction coefficients of order read from the stream.
+func (br *bitreader) predicted(x []int64, depth int, coefs []int64, order int) error {
+	if coefs != nil {
+		order = len(coefs)
+	}
+	if order > len(x) {
+		return fmt.Errorf("predictor order %v exceeds block", order)
+	}
+	var err error
+	for i := 0; i < order; i++ {
+		if x[i], err = br.signed(uint(depth)); err != nil {
+			return err
+		}
+	}
+	var shift uint
+	if coefs == nil {
+		prec, err := br.read(4)
+		if err != nil || prec == 15 {
+			return fmt.Errorf("invalid coefficient precision")
+		}
+		sh, err := br.signed(5)
+		if err != nil || sh < 0 {
+			return fmt.Errorf("invalid coefficient shift")
+		}
+		shift = uint(sh)
+		coefs = make([]int64, order)
+		for i := range coefs {
+			if coefs[i], err = br.signed(uint(prec) + 1); err != nil {
+				return err
+			}
+		}
+	}
+	if err := br.residual(x, order); err != nil {
+		return err
+	}
+	for i := order; i < len(x); i++ {
+		var sum int64
+		for j, c := range coefs {
+			sum += c * x[i-1-j]
+		}
+		x[i] += sum >> shift
+	}
+	return nil
+}
+
+// residual decodes Rice coded residual into x following order warm up samples.
+func (br *bitreader) residual(x []int64, order int) error {
+	method, err := br.read(2)
+	if err != nil || method > 1 {
+		return fmt.Errorf("reserved residual coding")
+	}
+	pbits, escape := uint(4), uint64(15)
+	if method == 1 {
+		pbits, escape = 5, 31
+	}
+	po, err := br.read(4)
+	if err != nil {
+		return err
+	}
+	parts := 1 << po
+	if len(x)%parts != 0 || len(x)/parts < order {
+		return fmt.Errorf("invalid partition order %v", po)
+	}
+	i := order
+	for p := 0; p < parts; p++ {
+		end := (p + 1) * len(x) / parts
+		k, err := br.read(pbits)
+		if err != nil {
+			return err
+		}
+		if k == escape {
+			n, err := br.read(5)
+			if err != nil {
+				return err
+			}
+			for ; i < end; i++ {
+				if x[i], err = br.signed(uint(n)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		for ; i < end; i++ {
+			q, err := br.unary()
+			if err != nil {
+				return err
+			}
+			r, err := br.read(uint(k))
+			if err != nil {
+				return err
+			}
+			u := q<<k | r
+			x[i] = int64(u>>1) ^ -int64(u&1)
+		}
+	}
+	return nil
+}
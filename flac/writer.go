@@ -0,0 +1,267 @@
+package flac
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+	"math/bits"
+)
+
+// blocksize is frames per channel encoded in each FLAC frame.
+const blocksize = 4096
+
+// Writer encodes interleaved samples as FLAC. Stream info is written on Close.
+type Writer struct {
+	ws    io.WriteSeeker
+	chans int
+	sr    int
+	depth int
+
+	pend    []int64 // interleaved samples of a partial block
+	frames  uint64  // FLAC frames written
+	samples uint64  // samples per channel written
+	minsize int     // smallest FLAC frame in bytes
+	maxsize int     // largest FLAC frame in bytes
+	sum     hash.Hash
+	raw     []byte // samples as summed
+	bw      bitwriter
+	ch      []int64 // samples of a channel
+	res     []int64 // residual of a channel
+	err     error
+}
+
+// NewWriter writes a FLAC header to ws for the given channels, sample rate,
+// and bit depth. Channels may be one to eight and bit depth 16 or 24.
+func NewWriter(ws io.WriteSeeker, chans int, sr int, depth int) (*Writer, error) {
+	if depth != 16 && depth != 24 {
+		return nil, fmt.Errorf("flac: unsupported bit depth %v", depth)
+	}
+	if chans < 1 || chans > 8 {
+		return nil, fmt.Errorf("flac: unsupported channels %v", chans)
+	}
+	if sr < 1 || sr >= 1<<20 {
+		return nil, fmt.Errorf("flac: unsupported sample rate %v", sr)
+	}
+	w := &Writer{ws: ws, chans: chans, sr: sr, depth: depth, sum: md5.New()}
+	if _, err := ws.Write(append([]byte("fLaC"), w.info()...)); err != nil {
+		return nil, fmt.Errorf("flac: write header failed: %v", err)
+	}
+	return w, nil
+}
+
+// info returns the STREAMINFO metadata block of what was written so far.
+func (w *Writer) info() []byte {
+	var bw bitwriter
+	bw.write(1<<7, 8) // last metadata block, of type STREAMINFO
+	bw.write(34, 24)
+	bw.write(blocksize, 16)
+	bw.write(blocksize, 16)
+	bw.write(uint64(w.minsize), 24)
+	bw.write(uint64(w.maxsize), 24)
+	bw.write(uint64(w.sr), 20)
+	bw.write(uint64(w.chans-1), 3)
+	bw.write(uint64(w.depth-1), 5)
+	bw.write(w.samples>>32, 4)
+	bw.write(w.samples, 32)
+	return append(bw.b, w.sum.Sum(nil)...)
+}
+
+// Write encodes interleaved samples, clipping them to [-1..1]. Samples are
+// buffered until a whole block of every channel is written or Close.
+func (w *Writer) Write(xs []float64) error {
+	if w.err != nil {
+		return w.err
+	}
+	size := w.depth / 8
+	max := float64(int64(1)<<uint(w.depth-1) - 1)
+	w.raw = w.raw[:0]
+	for _, x := range xs {
+		n := int64(clip(x) * max)
+		w.pend = append(w.pend, n)
+		for i := 0; i < size; i++ {
+			w.raw = append(w.raw, byte(n>>(8*uint(i))))
+		}
+	}
+	w.sum.Write(w.raw)
+
+	n := blocksize * w.chans
+	i := 0
+	for ; len(w.pend)-i >= n; i += n {
+		if err := w.frame(w.pend[i : i+n]); err != nil {
+			return err
+		}
+	}
+	w.pend = w.pend[:copy(w.pend, w.pend[i:])]
+	return nil
+}
+
+// Close encodes samples pending and writes stream info; it does not close the
+// underlying writer. A trailing partial frame of channels is dropped.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if n := len(w.pend) / w.chans * w.chans; n > 0 {
+		if err := w.frame(w.pend[:n]); err != nil {
+			return err
+		}
+	}
+	w.pend = w.pend[:0]
+	if _, err := w.ws.Seek(4, io.SeekStart); err != nil {
+		return fmt.Errorf("flac: seek failed: %v", err)
+	}
+	if _, err := w.ws.Write(w.info()); err != nil {
+		return fmt.Errorf("flac: write header failed: %v", err)
+	}
+	_, err := w.ws.Seek(0, io.SeekEnd)
+	return err
+}
+
+// frame encodes interleaved samples xs as a FLAC frame.
+func (w *Writer) frame(xs []int64) error {
+	n := len(xs) / w.chans
+	bw := &w.bw
+	bw.b = bw.b[:0]
+	bw.write(0xFFF8, 16) // sync code, fixed block size
+	bw.write(7, 4)       // block size follows as 16 bits
+	bw.write(0, 4)       // sample rate of stream info
+	bw.write(uint64(w.chans-1), 4)
+	if w.depth == 16 {
+		bw.write(4, 3)
+	} else {
+		bw.write(6, 3)
+	}
+	bw.write(0, 1)
+	bw.utf8(w.frames)
+	bw.write(uint64(n-1), 16)
+	bw.write(uint64(crc8(bw.b)), 8)
+
+	for c := 0; c < w.chans; c++ {
+		w.ch = w.ch[:0]
+		for i := c; i < len(xs); i += w.chans {
+			w.ch = append(w.ch, xs[i])
+		}
+		w.subframe(w.ch)
+	}
+	bw.align()
+	var crc [2]byte
+	binary.BigEndian.PutUint16(crc[:], crc16(bw.b))
+	bw.b = append(bw.b, crc[:]...)
+
+	if _, err := w.ws.Write(bw.b); err != nil {
+		w.err = fmt.Errorf("flac: write failed: %v", err)
+		return w.err
+	}
+	if w.frames == 0 || len(bw.b) < w.minsize {
+		w.minsize = len(bw.b)
+	}
+	if len(bw.b) > w.maxsize {
+		w.maxsize = len(bw.b)
+	}
+	w.frames++
+	w.samples += uint64(n)
+	return nil
+}
+
+// subframe encodes samples x of a channel as the subframe of the fixed
+// predictor that codes it smallest, or else as constant or verbatim.
+func (w *Writer) subframe(x []int64) {
+	bw := &w.bw
+	depth := uint(w.depth)
+	constant := true
+	for _, v := range x {
+		constant = constant && v == x[0]
+	}
+	if constant {
+		bw.write(0, 8)
+		bw.write(uint64(x[0]), depth)
+		return
+	}
+
+	// residuals of each order are differences of those of the order below.
+	w.res = append(w.res[:0], x...)
+	best, order, k := len(x)*int(depth), -1, uint(0)
+	for o := 0; o <= 4 && o < len(x); o++ {
+		if o > 0 {
+			for i := len(x) - 1; i >= o; i-- {
+				w.res[i] -= w.res[i-1]
+			}
+		}
+		if kk, cost := rice(w.res[o:]); o*int(depth)+cost < best {
+			best, order, k = o*int(depth)+cost, o, kk
+		}
+	}
+	if order == -1 {
+		bw.write(1<<1, 8) // verbatim
+		for _, v := range x {
+			bw.write(uint64(v), depth)
+		}
+		return
+	}
+
+	bw.write(uint64(8|order)<<1, 8) // fixed predictor of order
+	for _, v := range x[:order] {
+		bw.write(uint64(v), depth)
+	}
+	w.res = append(w.res[:0], x...)
+	for o := 1; o <= order; o++ {
+		for i := len(x) - 1; i >= o; i-- {
+			w.res[i] -= w.res[i-1]
+		}
+	}
+	method, pbits := uint64(0), uint(4)
+	if k > 14 {
+		method, pbits = 1, 5
+	}
+	bw.write(method, 2)
+	bw.write(0, 4) // a single partition
+	bw.write(uint64(k), pbits)
+	for _, r := range w.res[order:] {
+		u := zigzag(r)
+		bw.unary(u >> k)
+		bw.write(u, k)
+	}
+}
+
+// rice returns the Rice parameter coding residual res smallest and the bits
+// it takes, including the residual header.
+func rice(res []int64) (k uint, cost int) {
+	var sum uint64
+	for _, r := range res {
+		sum += zigzag(r)
+	}
+	// the best parameter is near the log of the mean.
+	est := uint(1)
+	if mean := sum / uint64(len(res)+1); mean > 0 {
+		est = uint(bits.Len64(mean))
+	}
+	cost = -1
+	for kk := est - 1; kk <= est+1 && kk <= 30; kk++ {
+		c := len(res) * int(kk+1)
+		for _, r := range res {
+			c += int(zigzag(r) >> kk)
+		}
+		if cost == -1 || c < cost {
+			k, cost = kk, c
+		}
+	}
+	cost += 2 + 4 + 4
+	if k > 14 {
+		cost++
+	}
+	return k, cost
+}
+
+// zigzag maps signed r to unsigned, interleaving negative and positive values.
+func zigzag(r int64) uint64 { return uint64(r<<1 ^ r>>63) }
+
+func clip(x float64) float64 {
+	if x > 1 {
+		return 1
+	} else if x < -1 {
+		return -1
+	}
+	return x
+}
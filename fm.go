@@ -0,0 +1,204 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// FMAlgorithm routes four operators of FM synthesis, where element i is the
+// index of the operator modulated by operator i, or -1 if operator i is a
+// carrier heard at output. An operator may only modulate one of a lower index.
+type FMAlgorithm [4]int
+
+// Common algorithms.
+var (
+	FMStack    = FMAlgorithm{-1, 0, 1, 2}    // 4 to 3 to 2 to 1
+	FMPairs    = FMAlgorithm{-1, 0, -1, 2}   // 2 to 1 beside 4 to 3
+	FMBranch   = FMAlgorithm{-1, 0, 0, 0}    // 2, 3, and 4 to 1
+	FMAdditive = FMAlgorithm{-1, -1, -1, -1} // all carriers
+)
+
+// FMOperator is a sine oscillator of FM synthesis with its own envelope.
+type FMOperator struct {
+	Ratio  float64 // of frequency to that of the note
+	Detune float64 // hertz added to frequency
+
+	// Level is amplitude of a carrier, or for a modulator, the peak swing of
+	// phase it modulates in radians, brighter as it rises.
+	Level float64
+
+	// VelSens belongs to [0..1] and is how much velocity scales level, so
+	// harder notes are louder or, for a modulator, brighter.
+	VelSens float64
+
+	Attack, Decay time.Duration
+	Sustain       float64 // level of envelope while held
+	Release       time.Duration
+}
+
+// FMPatch is a voicing of four FM operators routed by an algorithm, with the
+// last operator modulating itself by feedback in radians.
+type FMPatch struct {
+	Algorithm FMAlgorithm
+	Feedback  float64
+	Ops       [4]FMOperator
+}
+
+// Patches of classic FM sounds.
+var (
+	// FMPatchEPiano is a tine electric piano, a bright bark on hard notes
+	// from a velocity sensitive modulator over a mellow body.
+	FMPatchEPiano = FMPatch{
+		Algorithm: FMPairs,
+		Ops: [4]FMOperator{
+			{Ratio: 1, Level: 0.5, VelSens: 0.5, Attack: time.Millisecond, Decay: 2 * time.Second, Sustain: 0.2, Release: 300 * time.Millisecond},
+			{Ratio: 1, Level: 1.2, VelSens: 0.8, Attack: time.Millisecond, Decay: time.Second, Sustain: 0.1, Release: 300 * time.Millisecond},
+			{Ratio: 1, Detune: 0.5, Level: 0.4, VelSens: 0.6, Attack: time.Millisecond, Decay: 800 * time.Millisecond, Sustain: 0, Release: 200 * time.Millisecond},
+			{Ratio: 14, Level: 2, VelSens: 1, Attack: time.Millisecond, Decay: 80 * time.Millisecond, Sustain: 0, Release: 100 * time.Millisecond},
+		},
+	}
+
+	// FMPatchBell is a bell of inharmonic partials ringing long.
+	FMPatchBell = FMPatch{
+		Algorithm: FMPairs,
+		Ops: [4]FMOperator{
+			{Ratio: 1, Level: 0.5, VelSens: 0.4, Attack: time.Millisecond, Decay: 4 * time.Second, Sustain: 0, Release: 2 * time.Second},
+			{Ratio: 3.5, Level: 2.5, VelSens: 0.7, Attack: time.Millisecond, Decay: 3 * time.Second, Sustain: 0, Release: 2 * time.Second},
+			{Ratio: 2, Level: 0.3, VelSens: 0.4, Attack: time.Millisecond, Decay: 2 * time.Second, Sustain: 0, Release: time.Second},
+			{Ratio: 5.19, Level: 1.5, VelSens: 0.7, Attack: time.Millisecond, Decay: time.Second, Sustain: 0, Release: time.Second},
+		},
+	}
+
+	// FMPatchBass is a punchy bass of a stacked modulator with feedback,
+	// growling harder with velocity.
+	FMPatchBass = FMPatch{
+		Algorithm: FMStack,
+		Feedback:  0.6,
+		Ops: [4]FMOperator{
+			{Ratio: 1, Level: 0.8, VelSens: 0.3, Attack: time.Millisecond, Decay: 600 * time.Millisecond, Sustain: 0.6, Release: 80 * time.Millisecond},
+			{Ratio: 1, Level: 1.8, VelSens: 0.8, Attack: time.Millisecond, Decay: 300 * time.Millisecond, Sustain: 0.3, Release: 80 * time.Millisecond},
+			{Ratio: 2, Level: 0.6, VelSens: 0.6, Attack: time.Millisecond, Decay: 200 * time.Millisecond, Sustain: 0.2, Release: 80 * time.Millisecond},
+			{Ratio: 1, Level: 0.5, VelSens: 0.5, Attack: time.Millisecond, Decay: 150 * time.Millisecond, Sustain: 0, Release: 80 * time.Millisecond},
+		},
+	}
+)
+
+// FM is a polyphonic FM synthesizer of four operators per voice.
+type FM struct {
+	*Poly
+	fp FMPatch
+}
+
+// NewFM returns FM of voices playing fp.
+func NewFM(voices int, fp FMPatch) *FM {
+	fm := &FM{}
+	fm.Poly = NewPoly(voices, func() Voice { return newFMVoice(fm) })
+	fm.Poly.SetGain(VoiceGain(voices, CrestSine, DefaultHeadroom))
+	fm.SetPatch(fp)
+	return fm
+}
+
+func (fm *FM) Patch() FMPatch { return fm.fp }
+
+// SetPatch sets the voicing of all voices, taking effect on notes sounding.
+func (fm *FM) SetPatch(fp FMPatch) {
+	fm.fp = fp
+	for _, vc := range fm.Voices() {
+		vc := vc.(*fmVoice)
+		for i, op := range fp.Ops {
+			setenv(vc.envs[i], [4]float64{op.Attack.Seconds(), op.Decay.Seconds(), op.Sustain, op.Release.Seconds()})
+		}
+	}
+}
+
+// fmVoice is a Voice of an FM.
+type fmVoice struct {
+	*mono
+	fm     *FM
+	gate   *level
+	envs   [4]*ADSR
+	phases [4]float64
+	levels [4]float64 // scaled by velocity
+	hz     float64
+	fb     [2]float64 // last outputs of operator 4
+}
+
+func newFMVoice(fm *FM) *fmVoice {
+	vc := &fmVoice{mono: newmono(nil), fm: fm, gate: newlevel()}
+	for i := range vc.envs {
+		vc.envs[i] = NewADSR(time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, 1, 1, nil)
+		vc.envs[i].SetGate(vc.gate)
+	}
+	return vc
+}
+
+func (vc *fmVoice) Inputs() []Sound {
+	return []Sound{vc.envs[0], vc.envs[1], vc.envs[2], vc.envs[3]}
+}
+
+func (vc *fmVoice) NoteOn(hz, vel float64) {
+	vc.hz = hz
+	for i, op := range vc.fm.fp.Ops {
+		vc.levels[i] = op.Level * (1 - op.VelSens*(1-vel))
+	}
+	vc.gate.set(1)
+}
+
+func (vc *fmVoice) NoteOff()           { vc.gate.set(0) }
+func (vc *fmVoice) SetFreq(hz float64) { vc.hz = hz }
+
+func (vc *fmVoice) Done() bool {
+	if vc.gate.x != 0 {
+		return false
+	}
+	for i, to := range vc.fm.fp.Algorithm {
+		if to == -1 && !vc.envs[i].Idle() {
+			return false
+		}
+	}
+	return true
+}
+
+func (vc *fmVoice) Prepare(uint64) {
+	if vc.off || vc.Done() {
+		for i := range vc.out {
+			vc.out[i] = 0
+		}
+		return
+	}
+	fp := &vc.fm.fp
+	// several carriers are averaged to keep level.
+	carriers := 0.0
+	for _, to := range fp.Algorithm {
+		if to == -1 {
+			carriers++
+		}
+	}
+	if carriers == 0 {
+		carriers = 1
+	}
+	var incs [4]float64
+	for k, op := range fp.Ops {
+		incs[k] = (vc.hz*op.Ratio + op.Detune) / vc.sr
+	}
+	for i := range vc.out {
+		var mods [4]float64 // phase modulation of each operator, radians
+		mods[3] = fp.Feedback * (vc.fb[0] + vc.fb[1]) / 2
+		var y float64
+		for k := 3; k >= 0; k-- {
+			x := vc.levels[k] * vc.envs[k].Index(i) * math.Sin(twopi*vc.phases[k]+mods[k])
+			if vc.phases[k] += incs[k]; vc.phases[k] >= 1 {
+				vc.phases[k] -= math.Floor(vc.phases[k])
+			}
+			if k == 3 {
+				vc.fb[0], vc.fb[1] = vc.fb[1], x
+			}
+			if to := fp.Algorithm[k]; to == -1 {
+				y += x
+			} else if to < k {
+				mods[to] += x
+			}
+		}
+		vc.out[i] = y / carriers
+	}
+}
@@ -0,0 +1,38 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFMVelocity(t *testing.T) {
+	sr := DefaultSampleRate
+	// ratio of energy at the 14th harmonic bark to the fundamental.
+	bark := func(vel float64) float64 {
+		fm := NewFM(1, FMPatchEPiano)
+		fm.NoteOn(45, vel) // 110Hz
+		out := Render(fm, Dtof(30*time.Millisecond, sr))
+		return goertzel(out, 15*110, sr) / goertzel(out, 110, sr)
+	}
+	if soft, hard := bark(0.2), bark(1); hard < 2*soft {
+		t.Fatalf("have bark %v soft and %v hard, want brighter hard", soft, hard)
+	}
+}
+
+func TestFMPatches(t *testing.T) {
+	sr := DefaultSampleRate
+	for _, fp := range []FMPatch{FMPatchEPiano, FMPatchBell, FMPatchBass} {
+		fm := NewFM(4, fp)
+		fm.NoteOn(60, 1)
+		fm.NoteOn(64, 0.5)
+		if pk := Peak(Render(fm, Dtof(500*time.Millisecond, sr))); pk < 0.02 || pk > 1 {
+			t.Fatalf("have peak %v, want sound without clipping", pk)
+		}
+		fm.NoteOff(60)
+		fm.NoteOff(64)
+		Render(fm, Dtof(10*time.Second, sr))
+		if n := fm.Active(); n != 0 {
+			t.Fatalf("have %v voices active after release, want 0", n)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package snd
+
+import "math"
+
+// folder folds signals back on themselves by a sine, the smooth folds of a
+// Buchla 259 timbre circuit, antialiased by its antiderivative so sharp folds
+// of high drive alias little.
+type folder struct {
+	v1 float64 // last input
+	aa bool    // antialiased, by Quality
+}
+
+// fold returns v folded, where v within [-1..1] is gently shaped and each
+// further unit folds once more. Without antialiasing by Quality, v is folded
+// directly.
+func (fd *folder) fold(v float64) float64 {
+	const h = math.Pi / 2
+	d := v - fd.v1
+	var y float64
+	if !fd.aa {
+		y = math.Sin(h * v)
+	} else if math.Abs(d) < 1e-6 {
+		y = math.Sin(h * (v + fd.v1) / 2)
+	} else {
+		// difference of antiderivative -cos(h*v)/h over the step.
+		y = (math.Cos(h*fd.v1) - math.Cos(h*v)) / (h * d)
+	}
+	fd.v1 = v
+	return y
+}
+
+// Wavefolder folds its input back on itself as it exceeds unity, adding
+// harmonics that sweep as amount rises, the timbre of West Coast synthesis.
+// Symmetry biases input before folding so even harmonics join the odd.
+type Wavefolder struct {
+	*mono
+	folder
+	amount, sym       float64
+	amountmod, symmod Sound
+}
+
+// NewWavefolder returns Wavefolder of in driven by amount, where 1 gently
+// shapes a full scale input and each further unit folds it once more.
+func NewWavefolder(amount float64, in Sound) *Wavefolder {
+	return &Wavefolder{mono: newmono(in), amount: amount}
+}
+
+func (wf *Wavefolder) Amount() float64   { return wf.amount }
+func (wf *Wavefolder) Symmetry() float64 { return wf.sym }
+
+// SetAmount sets amount of drive into the folder, multiplied by mod at each
+// frame if not nil, such as by an envelope for folds that open and decay.
+func (wf *Wavefolder) SetAmount(x float64, mod Sound) { wf.amount, wf.amountmod = x, mod }
+
+// SetSymmetry sets bias of input belonging to [-1..1], with mod added at each
+// frame if not nil.
+func (wf *Wavefolder) SetSymmetry(x float64, mod Sound) { wf.sym, wf.symmod = x, mod }
+
+func (wf *Wavefolder) Inputs() []Sound { return []Sound{wf.in, wf.amountmod, wf.symmod} }
+
+// Params returns amount and symmetry.
+func (wf *Wavefolder) Params() []*Param {
+	return []*Param{
+		NewParam("amount", wf.Amount, func(x float64) { wf.amount = x }).Range(0, 10, 1),
+		NewParam("symmetry", wf.Symmetry, func(x float64) { wf.sym = x }).Range(-1, 1, 0),
+	}
+}
+
+// Panic clears state of antialiasing.
+func (wf *Wavefolder) Panic() { wf.v1 = 0 }
+
+func (wf *Wavefolder) Prepare(uint64) {
+	wf.aa = wf.st.antialiased()
+	for i, x := range wf.in.Samples() {
+		amt, sym := wf.amount, wf.sym
+		if wf.amountmod != nil {
+			amt *= wf.amountmod.Index(i)
+		}
+		if wf.symmod != nil {
+			sym += wf.symmod.Index(i)
+		}
+		y := wf.fold(amt*x + sym)
+		if wf.off {
+			wf.out[i] = 0
+		} else {
+			wf.out[i] = y
+		}
+	}
+}
+
+// ComplexOsc is a West Coast complex oscillator, a primary sine oscillator
+// paired with a modulation oscillator at a ratio of its frequency that may
+// modulate primary frequency by through-zero FM, its amplitude by AM, and its
+// timbre through a wavefolder.
+type ComplexOsc struct {
+	*mono
+	folder
+	freq    float64
+	freqmod Sound
+
+	ratio    float64 // of modulator frequency to primary
+	fm       float64 // index of FM, in multiples of primary frequency
+	am       float64 // depth of AM belonging to [0..1]
+	timbre   float64 // amount of folding
+	foldmod  float64 // depth timbre is modulated
+	sym      float64 // symmetry of folding
+	pri, mod float64 // phases in cycles
+}
+
+// NewComplexOsc returns ComplexOsc at freq with modulation at ratio of freq,
+// modulating nothing until depths are set, and folding gently.
+func NewComplexOsc(freq, ratio float64, freqmod Sound) *ComplexOsc {
+	return &ComplexOsc{mono: newmono(nil), freq: freq, freqmod: freqmod, ratio: ratio, timbre: 1}
+}
+
+func (co *ComplexOsc) Freq() float64   { return co.freq }
+func (co *ComplexOsc) FreqMod() Sound  { return co.freqmod }
+func (co *ComplexOsc) Inputs() []Sound { return []Sound{co.freqmod} }
+
+func (co *ComplexOsc) SetFreq(hz float64, mod Sound) { co.freq, co.freqmod = hz, mod }
+
+// Ratio returns the frequency of the modulation oscillator relative to the
+// primary.
+func (co *ComplexOsc) Ratio() float64     { return co.ratio }
+func (co *ComplexOsc) SetRatio(x float64) { co.ratio = x }
+
+// SetModulation sets depths the modulation oscillator modulates the primary:
+// fm, the index of FM as multiples of primary frequency swung; am belonging
+// to [0..1], the depth of AM; and fold, the fraction of timbre swung.
+func (co *ComplexOsc) SetModulation(fm, am, fold float64) { co.fm, co.am, co.foldmod = fm, am, fold }
+
+// Modulation returns depths of FM, AM, and folding.
+func (co *ComplexOsc) Modulation() (fm, am, fold float64) { return co.fm, co.am, co.foldmod }
+
+// SetTimbre sets amount of folding of the primary, as of a Wavefolder, and
+// symmetry of folds belonging to [-1..1].
+func (co *ComplexOsc) SetTimbre(amount, symmetry float64) { co.timbre, co.sym = amount, symmetry }
+
+// Timbre returns amount of folding and symmetry.
+func (co *ComplexOsc) Timbre() (amount, symmetry float64) { return co.timbre, co.sym }
+
+// Params returns freq in hertz, ratio, fm, am, fold, timbre, and symmetry.
+func (co *ComplexOsc) Params() []*Param {
+	return []*Param{
+		NewParam("freq", co.Freq, func(x float64) { co.freq = x }).Range(0, 20000, 220).In(UnitHz),
+		NewParam("ratio", co.Ratio, co.SetRatio).Range(0, 16, 1),
+		NewParam("fm", func() float64 { return co.fm }, func(x float64) { co.fm = x }).Range(0, 10, 0),
+		NewParam("am", func() float64 { return co.am }, func(x float64) { co.am = x }).Range(0, 1, 0).In(UnitPercent),
+		NewParam("fold", func() float64 { return co.foldmod }, func(x float64) { co.foldmod = x }).Range(0, 1, 0).In(UnitPercent),
+		NewParam("timbre", func() float64 { return co.timbre }, func(x float64) { co.timbre = x }).Range(0, 10, 1),
+		NewParam("symmetry", func() float64 { return co.sym }, func(x float64) { co.sym = x }).Range(-1, 1, 0),
+	}
+}
+
+// Panic resets phases and state of antialiasing.
+func (co *ComplexOsc) Panic() { co.pri, co.mod, co.v1 = 0, 0, 0 }
+
+func (co *ComplexOsc) Prepare(uint64) {
+	co.aa = co.st.antialiased()
+	for i := range co.out {
+		hz := co.freq
+		if co.freqmod != nil {
+			hz *= co.freqmod.Index(i)
+		}
+		m := math.Sin(twopi * co.mod)
+		co.mod += hz * co.ratio / co.sr
+		co.mod -= math.Floor(co.mod)
+
+		p := math.Sin(twopi * co.pri)
+		// through-zero, so phase may run backward and must wrap both ways.
+		co.pri += hz * (1 + co.fm*m) / co.sr
+		co.pri -= math.Floor(co.pri)
+
+		y := co.fold(co.timbre*(1+co.foldmod*m)*p + co.sym)
+		y *= 1 - co.am*(1-m)/2
+		if co.off {
+			co.out[i] = 0
+		} else {
+			co.out[i] = y
+		}
+	}
+}
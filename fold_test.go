@@ -0,0 +1,56 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWavefolder(t *testing.T) {
+	const sr, hz = DefaultSampleRate, 441
+	harm := func(amount, sym float64, n int) float64 {
+		wf := NewWavefolder(amount, NewOscil(Sine(), hz, nil))
+		wf.SetSymmetry(sym, nil)
+		out := Render(wf, 4400)
+		if p := Peak(out); p > 1+1e-9 {
+			t.Fatalf("amount %v: have peak %v, want within unity", amount, p)
+		}
+		return goertzel(out, hz*float64(n), sr) / goertzel(out, hz, sr)
+	}
+	if gentle, folded := harm(0.2, 0, 3), harm(4, 0, 3); folded < 10*gentle {
+		t.Fatalf("have third harmonic %v folded, want well above %v gentle", folded, gentle)
+	}
+	if even := harm(4, 0, 2); even > 1e-3 {
+		t.Fatalf("have second harmonic %v symmetric, want none", even)
+	}
+	if even := harm(4, 0.5, 2); even < 0.05 {
+		t.Fatalf("have second harmonic %v asymmetric, want some", even)
+	}
+}
+
+func TestComplexOsc(t *testing.T) {
+	const sr = DefaultSampleRate
+	co := NewComplexOsc(441, 1, nil)
+	co.SetTimbre(0.01, 0)
+	out := Render(co, 4400)
+	// barely folded, the primary is a sine of amplitude about timbre.
+	if p := Peak(out); !equaleps(p, math.Sin(math.Pi/2*0.01), 1e-4) {
+		t.Fatalf("have peak %v unmodulated", p)
+	}
+
+	// full AM by a modulator at half the primary adds sidebands.
+	co = NewComplexOsc(441, 0.5, nil)
+	co.SetModulation(0, 1, 0)
+	out = Render(co, 4400)
+	if side := goertzel(out, 441*1.5, sr) / goertzel(out, 441, sr); side < 0.2 {
+		t.Fatalf("have sideband %v of AM, want some", side)
+	}
+
+	// FM through zero stays bounded.
+	co.SetModulation(3, 0, 0.5)
+	co.SetTimbre(3, 0.2)
+	for _, x := range Render(co, 4400) {
+		if math.IsNaN(x) || math.Abs(x) > 1+1e-9 {
+			t.Fatalf("have %v of FM and folding, want within unity", x)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Follower outputs the amplitude envelope of its input, rising toward the
+// absolute value of input at an attack rate and falling at a release rate.
+type Follower struct {
+	*mono
+	atk, rel time.Duration
+	ca, cr   float64 // smoothing coefficients
+	y        float64
+}
+
+// NewFollower returns Follower reaching about 63% of a step in input after
+// attack when rising and release when falling.
+func NewFollower(attack, release time.Duration, in Sound) *Follower {
+	fol := &Follower{mono: newmono(in)}
+	fol.SetTimes(attack, release)
+	return fol
+}
+
+// SetTimes sets attack and release times.
+func (fol *Follower) SetTimes(attack, release time.Duration) {
+	fol.atk, fol.rel = attack, release
+	fol.ca, fol.cr = smoothcoef(attack, fol.sr), smoothcoef(release, fol.sr)
+}
+
+func (fol *Follower) Attack() time.Duration  { return fol.atk }
+func (fol *Follower) Release() time.Duration { return fol.rel }
+
+// smoothcoef returns the coefficient of a one pole smoother with time constant d.
+func smoothcoef(d time.Duration, sr float64) float64 {
+	if d <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-1/(d.Seconds()*sr))
+}
+
+// Panic drops the envelope to zero.
+func (fol *Follower) Panic() { fol.y = 0 }
+
+func (fol *Follower) Prepare(uint64) {
+	for i, x := range fol.in.Samples() {
+		x = math.Abs(x)
+		if x > fol.y {
+			fol.y += fol.ca * (x - fol.y)
+		} else {
+			fol.y += fol.cr * (x - fol.y)
+		}
+		if fol.off {
+			fol.out[i] = 0
+		} else {
+			fol.out[i] = fol.y
+		}
+	}
+}
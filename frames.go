@@ -0,0 +1,86 @@
+package snd
+
+import (
+	"math"
+	"math/bits"
+	"time"
+)
+
+// FrameCounter converts a sequence of durations to whole frames, carrying the
+// fraction of a frame left by each conversion into the next, so frames summed
+// over any number of conversions stay within a frame of the sum of durations.
+// Where Dtof truncates each duration on its own, such as steps of a sequencer,
+// error would otherwise grow with every step.
+//
+// Durations of a sample rate of whole frames per second are converted exactly.
+type FrameCounter struct {
+	sr  float64
+	rem float64 // fraction of a frame carried, belonging to [0..1)
+}
+
+// NewFrameCounter returns FrameCounter at sample rate sr.
+func NewFrameCounter(sr float64) *FrameCounter { return &FrameCounter{sr: sr} }
+
+// Dur returns frames of d, a non-negative duration, with any fraction carried.
+func (fc *FrameCounter) Dur(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	if sr := uint64(fc.sr); float64(sr) == fc.sr {
+		// d*sr in nanoframes can overflow 64 bits for long durations.
+		hi, lo := bits.Mul64(uint64(d), sr)
+		if hi < uint64(time.Second) {
+			f, ns := bits.Div64(hi, lo, uint64(time.Second))
+			return int(f) + fc.carry(float64(ns)/float64(time.Second))
+		}
+	}
+	return fc.Frames(d.Seconds() * fc.sr)
+}
+
+// Beats returns frames of beats at tempo bpm with any fraction carried.
+func (fc *FrameCounter) Beats(bpm BPM, beats float64) int {
+	return fc.Frames(beats * 60 / float64(bpm) * fc.sr)
+}
+
+// Frames returns the whole part of x, a non-negative number of frames, with
+// any fraction carried.
+func (fc *FrameCounter) Frames(x float64) int {
+	if x <= 0 {
+		return 0
+	}
+	f := math.Floor(x)
+	return int(f) + fc.carry(x-f)
+}
+
+// carry adds fraction x to the remainder and returns any whole frame.
+func (fc *FrameCounter) carry(x float64) int {
+	fc.rem += x
+	if fc.rem >= 1 {
+		fc.rem--
+		return 1
+	}
+	return 0
+}
+
+// Remainder returns the fraction of a frame carried into the next conversion.
+func (fc *FrameCounter) Remainder() float64 { return fc.rem }
+
+// Reset discards any fraction carried, such as when a sequence restarts.
+func (fc *FrameCounter) Reset() { fc.rem = 0 }
+
+// TickFrame returns the position of the first frame of buffer tc, counting
+// from one as a Dispatcher does, of buffers n frames long.
+func TickFrame(tc uint64, n int) uint64 {
+	if tc == 0 {
+		return 0
+	}
+	return (tc - 1) * uint64(n)
+}
+
+// FrameSince returns frames from then until now, negative if now is before
+// then. Positions, or tc, may be any two less than 2^63 apart, so the result
+// holds even after a counter wraps.
+func FrameSince(now, then uint64) int64 { return int64(now - then) }
+
+// FrameBefore reports whether position a is before b, by FrameSince.
+func FrameBefore(a, b uint64) bool { return FrameSince(a, b) < 0 }
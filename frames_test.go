@@ -0,0 +1,53 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFrameCounter(t *testing.T) {
+	// a third of a millisecond is 14.7 frames; truncating drops 0.7 each step.
+	fc := NewFrameCounter(44100)
+	d := time.Second / 3000
+	var sum, trunc int
+	for i := 0; i < 3000*60; i++ {
+		sum += fc.Dur(d)
+		trunc += Dtof(d, 44100)
+	}
+	// d is rounded to whole nanoseconds, short of a third by 1/3ns each step.
+	want := int(int64(d) * 3000 * 60 * 44100 / int64(time.Second))
+	if sum != want {
+		t.Fatalf("have %v frames over a minute, want %v", sum, want)
+	}
+	if trunc >= want-1000 {
+		t.Fatalf("have %v frames truncated, want drift from %v", trunc, want)
+	}
+
+	fc = NewFrameCounter(DefaultSampleRate)
+	sum = 0
+	for i := 0; i < 1000; i++ {
+		sum += fc.Beats(140, 0.25)
+	}
+	// 250 beats at 140bpm.
+	if want := int(250.0 * 60 / 140 * DefaultSampleRate); sum != want && sum != want+1 {
+		t.Fatalf("have %v frames of beats, want %v", sum, want)
+	}
+	fc.Reset()
+	if fc.Remainder() != 0 {
+		t.Fatalf("have remainder %v after reset", fc.Remainder())
+	}
+}
+
+func TestFrameSince(t *testing.T) {
+	if have := TickFrame(3, 512); have != 1024 {
+		t.Fatalf("have frame %v of tc 3, want 1024", have)
+	}
+	var then uint64 = 1<<64 - 10
+	now := then + 25 // wraps
+	if have := FrameSince(now, then); have != 25 {
+		t.Fatalf("have %v frames since, want 25", have)
+	}
+	if !FrameBefore(then, now) || FrameBefore(now, then) {
+		t.Fatalf("have order of %v and %v reversed across wrap", then, now)
+	}
+}
@@ -12,7 +12,7 @@ type Freeze struct {
 }
 
 func NewFreeze(d time.Duration, in Sound) *Freeze {
-	f := Dtof(d, in.SampleRate())
+	f := atleast(1, Dtof(d, in.SampleRate()))
 
 	n := f
 	if n == 0 || n&(n-1) != 0 {
@@ -28,9 +28,9 @@ func NewFreeze(d time.Duration, in Sound) *Freeze {
 
 	// t := time.Now()
 	buflen := len(in.Samples())
-	for i := 0; i < n; i += buflen {
+	for i := 0; i < f; i += buflen {
 		dp.Dispatch(1, inps...)
-		ringcopy(frz.sig[i:i+buflen], in.Samples(), 0)
+		copy(frz.sig[i:], in.Samples())
 	}
 	// log.Println("freeze took", time.Now().Sub(t))
 	return frz
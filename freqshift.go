@@ -0,0 +1,100 @@
+package snd
+
+import "math"
+
+// Allpass coefficients, squared, of two chains whose outputs differ in phase
+// by 90 degrees across most of the audible range. See Olli Niemitalo's
+// hilbert transformer design.
+var (
+	hilbertI = [...]float64{0.6923878, 0.9360654322959, 0.9882295226860, 0.9987488452737}
+	hilbertQ = [...]float64{0.4021921162426, 0.8561710882420, 0.9722909545651, 0.9952884791278}
+)
+
+// allpass2 is a second order allpass section y[n] = a(x[n]+y[n-2]) - x[n-2].
+type allpass2 struct {
+	a              float64
+	x1, x2, y1, y2 float64
+}
+
+func (ap *allpass2) process(x float64) float64 {
+	y := ap.a*(x+ap.y2) - ap.x2
+	ap.x2, ap.x1 = ap.x1, x
+	ap.y2, ap.y1 = ap.y1, y
+	return y
+}
+
+// hilbert returns the analytic signal of a real input as in-phase and
+// quadrature parts.
+type hilbert struct {
+	i, q [4]allpass2
+	last float64 // in-phase output delayed by a frame
+}
+
+func newhilbert() *hilbert {
+	h := &hilbert{}
+	for n := range h.i {
+		h.i[n].a = hilbertI[n]
+		h.q[n].a = hilbertQ[n]
+	}
+	return h
+}
+
+func (h *hilbert) process(x float64) (re, im float64) {
+	a, b := x, x
+	for n := range h.i {
+		a = h.i[n].process(a)
+		b = h.q[n].process(b)
+	}
+	re, h.last = h.last, a
+	return re, b
+}
+
+// FreqShift shifts every partial of its input by a fixed number of hertz by
+// single sideband modulation. Unlike pitch shifting, harmonic ratios are not
+// kept, producing metallic and inharmonic tones. With feedback, repeats keep
+// shifting for endlessly rising or falling barberpole effects.
+type FreqShift struct {
+	*mono
+	hb       *hilbert
+	shift    float64
+	phase    float64
+	feedback float64
+	mix      float64
+	last     float64
+}
+
+// NewFreqShift returns FreqShift of in by hz, positive shifting up.
+func NewFreqShift(hz float64, in Sound) *FreqShift {
+	return &FreqShift{mono: newmono(in), hb: newhilbert(), shift: hz, mix: 1}
+}
+
+func (fs *FreqShift) Shift() float64        { return fs.shift }
+func (fs *FreqShift) SetShift(hz float64)   { fs.shift = hz }
+func (fs *FreqShift) Feedback() float64     { return fs.feedback }
+func (fs *FreqShift) SetFeedback(x float64) { fs.feedback = x }
+func (fs *FreqShift) Mix() float64          { return fs.mix }
+func (fs *FreqShift) SetMix(x float64)      { fs.mix = x }
+
+func (fs *FreqShift) Params() []*Param {
+	return []*Param{
+		NewParam("shift", fs.Shift, fs.SetShift).Range(-2000, 2000, 0).In(UnitHz),
+		NewParam("feedback", fs.Feedback, fs.SetFeedback).Range(0, 0.95, 0).In(UnitPercent),
+		NewParam("mix", fs.Mix, fs.SetMix).Range(0, 1, 1).In(UnitPercent),
+	}
+}
+
+func (fs *FreqShift) Prepare(uint64) {
+	for i, x := range fs.in.Samples() {
+		re, im := fs.hb.process(x + math.Tanh(fs.feedback*fs.last))
+		s, c := math.Sincos(2 * math.Pi * fs.phase)
+		wet := re*c + im*s
+		fs.last = wet
+		fs.phase += fs.shift / fs.sr
+		fs.phase -= math.Floor(fs.phase)
+		if fs.off {
+			fs.out[i] = 0
+		} else {
+			fs.out[i] = (1-fs.mix)*x + fs.mix*wet
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreqShift(t *testing.T) {
+	n := Dtof(time.Second, DefaultSampleRate)
+	for _, hz := range []float64{100, -100, 1000} {
+		fs := NewFreqShift(hz, NewOscil(Sine(), 1000, nil))
+		out := Render(fs, n)[n/2:]
+		want := 1000 + hz
+		a := goertzel(out, want, DefaultSampleRate)
+		b := goertzel(out, 1000-hz, DefaultSampleRate)
+		if a < 0.2 || a < 20*b {
+			t.Errorf("shift %v: have magnitude %.4f at %vHz and %.4f at image", hz, a, want, b)
+		}
+	}
+}
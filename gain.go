@@ -1,5 +1,7 @@
 package snd
 
+import "math"
+
 type Gain struct {
 	*mono
 	a float64
@@ -13,6 +15,12 @@ func (gn *Gain) SetAmp(a float64) {
 	gn.a = a
 }
 
+func (gn *Gain) Amp() float64 { return gn.a }
+
+func (gn *Gain) Params() []*Param {
+	return []*Param{NewParam("amp", gn.Amp, gn.SetAmp).Range(0, 4, 1)}
+}
+
 func (gn *Gain) Prepare(uint64) {
 	for i, x := range gn.in.Samples() {
 		if gn.off {
@@ -22,3 +30,189 @@ func (gn *Gain) Prepare(uint64) {
 		}
 	}
 }
+
+// Response selects how a control signal maps to gain.
+type Response int
+
+const (
+	// ResponseLinear uses the control signal as gain directly.
+	ResponseLinear Response = iota
+
+	// ResponseExp maps control in [0..1] across a range of decibels to
+	// unity gain, so equal changes in control are equal changes in loudness.
+	// Control at or below zero is silent.
+	ResponseExp
+)
+
+// VCA is a voltage controlled amplifier; its input is multiplied by a gain
+// driven by another Sound such as an envelope, LFO, or Follower.
+type VCA struct {
+	*mono
+	ctl  Sound
+	resp Response
+	db   float64 // range of exponential response
+}
+
+// NewVCA returns VCA of in by ctl with linear response.
+func NewVCA(ctl Sound, in Sound) *VCA {
+	return &VCA{mono: newmono(in), ctl: ctl, db: 60}
+}
+
+func (vca *VCA) Inputs() []Sound { return []Sound{vca.in, vca.ctl} }
+
+func (vca *VCA) Response() Response        { return vca.resp }
+func (vca *VCA) SetResponse(resp Response) { vca.resp = resp }
+
+// Range returns the decibels spanned by exponential response.
+func (vca *VCA) Range() Decibel { return Decibel(vca.db) }
+
+// SetRange sets the decibels spanned by exponential response, e.g. 60 for
+// control of 0.5 to reach -30dB.
+func (vca *VCA) SetRange(db Decibel) { vca.db = float64(db) }
+
+// gain returns gain of control value x.
+func (vca *VCA) gain(x float64) float64 {
+	if vca.resp == ResponseLinear {
+		return x
+	}
+	if x <= 0 {
+		return 0
+	}
+	if x > 1 {
+		x = 1
+	}
+	return Decibel(vca.db * (x - 1)).Amp()
+}
+
+func (vca *VCA) Prepare(uint64) {
+	ctl := vca.ctl.Samples()
+	for i, x := range vca.in.Samples() {
+		if vca.off {
+			vca.out[i] = 0
+		} else {
+			vca.out[i] = vca.gain(ctl[i]) * x
+		}
+	}
+}
+
+// Crest factors, the ratio of peak to RMS level, of common waveforms.
+const (
+	CrestSquare Decibel = 0
+	CrestSine   Decibel = 3.01
+	CrestSaw    Decibel = 4.77
+	CrestNoise  Decibel = 12 // about, for gaussian noise and dense mixes
+)
+
+// VoiceGain returns the gain of each voice of a mix of voices, each peaking at
+// full scale with crest factor crest, such that the mix peaks near headroom,
+// e.g. DefaultHeadroom, when every voice plays.
+//
+// Voices are taken as uncorrelated, as notes of differing pitch are, so the
+// level of the mix grows with the square root of voices while its crest grows
+// toward that of noise, and never beyond the sum of every voice peaking at
+// once. For 16 sawtooth voices and -3dB headroom, gain is about -22dB.
+func VoiceGain(voices int, crest, headroom Decibel) float64 {
+	if voices < 1 {
+		voices = 1
+	}
+	n := float64(voices)
+	mix := math.Max(float64(crest), float64(CrestNoise))
+	peak := math.Min(n, math.Sqrt(n)*Decibel(mix-float64(crest)).Amp())
+	return headroom.Amp() / peak
+}
+
+// TaperLaw is the curve of a Taper.
+type TaperLaw int
+
+const (
+	// TaperDecibel spaces decibels evenly from Min to Max.
+	TaperDecibel TaperLaw = iota
+
+	// TaperPower raises position to Exp as an amplitude scaled to Max,
+	// such as 2 or 3 for the feel of an audio taper pot.
+	TaperPower
+
+	// TaperConsole follows the scale of a mixing console fader, with 10dB
+	// over the top quarter of travel, 20dB over the next quarter, and the
+	// rest over the bottom half down to Min.
+	TaperConsole
+)
+
+// Taper maps positions of a fader or slider in [0..1] to gain, so that every
+// control of an application responds alike. Position zero is always silence.
+type Taper struct {
+	Law      TaperLaw
+	Min, Max Decibel // gain just above position zero, and at position one
+	Exp      float64 // exponent of TaperPower, or zero for 2
+}
+
+// DefaultTaper is a console taper from -60dB to +6dB.
+var DefaultTaper = Taper{Law: TaperConsole, Min: -60, Max: 6}
+
+// consoleTaper are positions and decibels below Max of TaperConsole.
+var consoleTaper = [...]struct{ pos, db float64 }{{1, 0}, {0.75, -10}, {0.5, -30}}
+
+func (tr Taper) exp() float64 {
+	if tr.Exp <= 0 {
+		return 2
+	}
+	return tr.Exp
+}
+
+// Decibel returns gain at pos.
+func (tr Taper) Decibel(pos float64) Decibel {
+	if pos <= 0 {
+		return Decibel(math.Inf(-1))
+	}
+	if pos > 1 {
+		pos = 1
+	}
+	lo, hi := float64(tr.Min), float64(tr.Max)
+	switch tr.Law {
+	case TaperPower:
+		return tr.Max + DecibelOf(math.Pow(pos, tr.exp()))
+	case TaperConsole:
+		for i := 1; i < len(consoleTaper); i++ {
+			a, b := consoleTaper[i-1], consoleTaper[i]
+			if pos >= b.pos {
+				return Decibel(hi + b.db + (pos-b.pos)/(a.pos-b.pos)*(a.db-b.db))
+			}
+		}
+		b := consoleTaper[len(consoleTaper)-1]
+		return Decibel(lo + pos/b.pos*(hi+b.db-lo))
+	default:
+		return Decibel(lo + pos*(hi-lo))
+	}
+}
+
+// Amp returns gain at pos as an amplitude multiplier.
+func (tr Taper) Amp(pos float64) float64 { return tr.Decibel(pos).Amp() }
+
+// Pos returns the position of gain db, the inverse of Decibel, such as to
+// place a slider for a gain set elsewhere. Gains out of range are clamped.
+func (tr Taper) Pos(db Decibel) float64 {
+	if db.IsInf() {
+		return 0
+	}
+	if db >= tr.Max {
+		return 1
+	}
+	x, lo, hi := float64(db), float64(tr.Min), float64(tr.Max)
+	var pos float64
+	switch tr.Law {
+	case TaperPower:
+		return math.Pow((db - tr.Max).Amp(), 1/tr.exp())
+	case TaperConsole:
+		for i := 1; i < len(consoleTaper); i++ {
+			a, b := consoleTaper[i-1], consoleTaper[i]
+			if x >= hi+b.db {
+				return b.pos + (x-hi-b.db)/(a.db-b.db)*(a.pos-b.pos)
+			}
+		}
+		b := consoleTaper[len(consoleTaper)-1]
+		pos = (x - lo) / (hi + b.db - lo) * b.pos
+	default:
+		pos = (x - lo) / (hi - lo)
+	}
+	return math.Max(0, pos)
+}
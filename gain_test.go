@@ -0,0 +1,71 @@
+package snd
+
+import "testing"
+
+func TestVCA(t *testing.T) {
+	in, ctl := newlevel(), newlevel()
+	in.set(1)
+	ctl.set(0.5)
+	vca := NewVCA(ctl, in)
+	if x := Render(vca, 8)[4]; x != 0.5 {
+		t.Fatalf("linear have %v, want 0.5", x)
+	}
+	vca.SetResponse(ResponseExp)
+	if have, want := Render(vca, 8)[4], Decibel(-30).Amp(); !equaleps(have, want, 1e-12) {
+		t.Fatalf("exponential have %v, want %v", have, want)
+	}
+	ctl.set(0)
+	if x := Render(vca, 8)[4]; x != 0 {
+		t.Fatalf("exponential have %v at zero control, want 0", x)
+	}
+}
+
+func TestTaper(t *testing.T) {
+	for _, tr := range []Taper{
+		{Law: TaperDecibel, Min: -60, Max: 0},
+		{Law: TaperPower, Min: -60, Max: 6, Exp: 3},
+		DefaultTaper,
+	} {
+		if db := tr.Decibel(0); !db.IsInf() {
+			t.Errorf("law %v: have %v at zero, want -inf", tr.Law, db)
+		}
+		if db := tr.Decibel(1); db != tr.Max {
+			t.Errorf("law %v: have %v at one, want %v", tr.Law, db, tr.Max)
+		}
+		prev := tr.Decibel(0)
+		for pos := 0.05; pos <= 1; pos += 0.05 {
+			db := tr.Decibel(pos)
+			if db <= prev {
+				t.Fatalf("law %v: have %v at %v not above %v", tr.Law, db, pos, prev)
+			}
+			if p := tr.Pos(db); !equaleps(p, pos, 1e-9) {
+				t.Fatalf("law %v: have position %v of %v, want %v", tr.Law, p, db, pos)
+			}
+			prev = db
+		}
+	}
+	if db := DefaultTaper.Decibel(0.75); db != -4 {
+		t.Fatalf("have %v at three quarters, want -4dB", db)
+	}
+}
+
+func TestVoiceGain(t *testing.T) {
+	if g := VoiceGain(1, CrestSine, 0); g != 1 {
+		t.Fatalf("have %v for one voice, want 1", g)
+	}
+	if g := VoiceGain(2, CrestSquare, 0); g != 0.5 {
+		t.Fatalf("have %v for two square voices, want 0.5", g)
+	}
+	g := VoiceGain(16, CrestSaw, DefaultHeadroom)
+	if db := DecibelOf(g); !equaleps(float64(db), -22.27, 0.01) {
+		t.Fatalf("have %v for 16 sawtooth voices, want -22.27dB", db)
+	}
+
+	var ins []Sound
+	for i := 0; i < 16; i++ {
+		ins = append(ins, NewGain(g, NewOscil(Sawtooth(), KeyFreq(48+i*2), nil)))
+	}
+	if p := Peak(Render(NewAdd(ins...), 44100)); p >= 1 {
+		t.Fatalf("have mix peak %v, want below full scale", p)
+	}
+}
@@ -0,0 +1,98 @@
+package snd
+
+import (
+	"encoding/xml"
+	"io"
+	"math"
+)
+
+// DrumGrid is a pattern of drum hits with a row of steps for each drum.
+//
+// Grids encode to JSON by encoding/json as a portable format for sharing
+// between applications.
+type DrumGrid struct {
+	Name string    `json:"name,omitempty"`
+	Div  float64   `json:"div"` // steps per beat
+	Rows []DrumRow `json:"rows"`
+}
+
+// DrumRow is the steps of a single drum of a DrumGrid.
+type DrumRow struct {
+	Name string    `json:"name,omitempty"`
+	Key  int       `json:"key"` // MIDI key, e.g. 36 for a General MIDI kick
+	Vel  []float64 `json:"vel"` // velocity of each step, zero for no hit
+}
+
+// Steps returns the length of g in steps, that of its longest row.
+func (g *DrumGrid) Steps() (n int) {
+	for _, row := range g.Rows {
+		if len(row.Vel) > n {
+			n = len(row.Vel)
+		}
+	}
+	return n
+}
+
+// Row returns the row of key, adding a row of steps if there is none.
+func (g *DrumGrid) Row(key, steps int) *DrumRow {
+	for i := range g.Rows {
+		if g.Rows[i].Key == key {
+			return &g.Rows[i]
+		}
+	}
+	g.Rows = append(g.Rows, DrumRow{Key: key, Vel: make([]float64, steps)})
+	return &g.Rows[len(g.Rows)-1]
+}
+
+// Pattern returns row i as a Pattern, such as to play by a Sequencer for
+// each row.
+func (g *DrumGrid) Pattern(i int) *Pattern {
+	row := g.Rows[i]
+	pat := &Pattern{Div: g.Div, Steps: make([]Step, g.Steps())}
+	for j := range pat.Steps {
+		pat.Steps[j] = Step{Key: Rest}
+		if j < len(row.Vel) && row.Vel[j] > 0 {
+			pat.Steps[j] = Step{Key: row.Key, Vel: row.Vel[j]}
+		}
+	}
+	return pat
+}
+
+// hydrogenTicks is ticks per beat of Hydrogen patterns.
+const hydrogenTicks = 48
+
+// ReadHydrogen reads a pattern exported by the Hydrogen drum machine, an
+// .h2pattern file, quantized to div steps per beat. Hydrogen instruments
+// are numbered; keys maps instrument numbers to MIDI keys or, if nil, each
+// maps to 36 plus its number, which matches the General MIDI layout of the
+// default GMkit drumkit for its first instruments.
+func ReadHydrogen(r io.Reader, div float64, keys map[int]int) (*DrumGrid, error) {
+	var doc struct {
+		Pattern struct {
+			Name  string `xml:"name"`
+			Size  int    `xml:"size"`
+			Notes []struct {
+				Position   int     `xml:"position"`
+				Velocity   float64 `xml:"velocity"`
+				Instrument int     `xml:"instrument"`
+			} `xml:"noteList>note"`
+		} `xml:"pattern"`
+	}
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	pat := doc.Pattern
+	steps := int(math.Round(float64(pat.Size) / hydrogenTicks * div))
+	g := &DrumGrid{Name: pat.Name, Div: div}
+	for _, note := range pat.Notes {
+		key, ok := keys[note.Instrument]
+		if !ok {
+			key = 36 + note.Instrument
+		}
+		row := g.Row(key, steps)
+		if i := int(math.Round(float64(note.Position) / hydrogenTicks * div)); i >= 0 && i < steps {
+			row.Vel[i] = note.Velocity
+		}
+	}
+	return g, nil
+}
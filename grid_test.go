@@ -0,0 +1,60 @@
+package snd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPatternJSON(t *testing.T) {
+	pat := &Pattern{Div: 4, Steps: []Step{
+		{Key: 60, Vel: 1, Auto: map[string]float64{"cutoff": 800}, Slide: true},
+		{Key: Rest},
+		{Key: 64, Vel: 0.5, Prob: 0.5, Ratchet: 2},
+	}}
+	b, err := json.Marshal(pat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var have Pattern
+	if err := json.Unmarshal(b, &have); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(&have, pat) {
+		t.Fatalf("have %+v, want %+v", have, pat)
+	}
+}
+
+const h2pattern = `<?xml version="1.0" encoding="UTF-8"?>
+<drumkit_pattern>
+ <drumkit_name>GMkit</drumkit_name>
+ <pattern>
+  <name>beat</name>
+  <size>192</size>
+  <noteList>
+   <note><position>0</position><velocity>0.8</velocity><instrument>0</instrument></note>
+   <note><position>96</position><velocity>0.8</velocity><instrument>0</instrument></note>
+   <note><position>48</position><velocity>1</velocity><instrument>2</instrument></note>
+   <note><position>144</position><velocity>1</velocity><instrument>2</instrument></note>
+   <note><position>24</position><velocity>0.5</velocity><instrument>6</instrument></note>
+  </noteList>
+ </pattern>
+</drumkit_pattern>`
+
+func TestReadHydrogen(t *testing.T) {
+	g, err := ReadHydrogen(strings.NewReader(h2pattern), 4, map[int]int{6: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Name != "beat" || g.Steps() != 16 || len(g.Rows) != 3 {
+		t.Fatalf("have %q of %v steps and %v rows, want beat of 16 steps and 3 rows", g.Name, g.Steps(), len(g.Rows))
+	}
+	kick := g.Pattern(0)
+	if kick.Steps[0].Key != 36 || kick.Steps[8].Vel != 0.8 || kick.Steps[4].Key != Rest {
+		t.Fatalf("have kick %+v", kick.Steps)
+	}
+	if hat := g.Row(42, 16); hat.Vel[2] != 0.5 {
+		t.Fatalf("have hat %v, want hit on step 2", hat.Vel)
+	}
+}
@@ -0,0 +1,68 @@
+package snd
+
+import "time"
+
+// Change is a recorded param mutation.
+type Change struct {
+	Param    *Param
+	Old, New float64
+	Time     time.Time
+}
+
+// History records param changes for undo and redo.
+//
+// Changes to the same param within Coalesce of each other are merged into a
+// single change, e.g. so dragging a slider is undone in one step.
+type History struct {
+	Coalesce time.Duration
+
+	undo, redo []Change
+}
+
+// Set sets p to x and records the change, discarding any changes available to Redo.
+func (h *History) Set(p *Param, x float64) {
+	now := time.Now()
+	old := p.Value()
+	p.Set(x)
+	h.redo = h.redo[:0]
+	if n := len(h.undo); n > 0 {
+		last := &h.undo[n-1]
+		if last.Param == p && now.Sub(last.Time) < h.Coalesce {
+			last.New, last.Time = x, now
+			return
+		}
+	}
+	h.undo = append(h.undo, Change{p, old, x, now})
+}
+
+// Undo reverts the last change and reports whether there was one.
+func (h *History) Undo() bool {
+	n := len(h.undo)
+	if n == 0 {
+		return false
+	}
+	c := h.undo[n-1]
+	h.undo = h.undo[:n-1]
+	c.Param.Set(c.Old)
+	h.redo = append(h.redo, c)
+	return true
+}
+
+// Redo reapplies the last undone change and reports whether there was one.
+func (h *History) Redo() bool {
+	n := len(h.redo)
+	if n == 0 {
+		return false
+	}
+	c := h.redo[n-1]
+	h.redo = h.redo[:n-1]
+	c.Param.Set(c.New)
+	h.undo = append(h.undo, c)
+	return true
+}
+
+// Changes returns changes available to Undo, oldest first.
+func (h *History) Changes() []Change { return h.undo }
+
+// Clear discards all recorded changes.
+func (h *History) Clear() { h.undo, h.redo = nil, nil }
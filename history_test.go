@@ -0,0 +1,52 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	gn := NewGain(1, newunit())
+	p := gn.Params()[0]
+
+	var h History
+	h.Set(p, 0.5)
+	h.Set(p, 0.25)
+	if n := len(h.Changes()); n != 2 {
+		t.Fatalf("have %v changes, want 2", n)
+	}
+
+	h.Undo()
+	if gn.Amp() != 0.5 {
+		t.Fatalf("undo have %v, want 0.5", gn.Amp())
+	}
+	h.Undo()
+	if gn.Amp() != 1 {
+		t.Fatalf("undo have %v, want 1", gn.Amp())
+	}
+	if h.Undo() {
+		t.Fatal("undo reported change on empty history")
+	}
+	h.Redo()
+	h.Redo()
+	if gn.Amp() != 0.25 {
+		t.Fatalf("redo have %v, want 0.25", gn.Amp())
+	}
+}
+
+func TestHistoryCoalesce(t *testing.T) {
+	gn := NewGain(1, newunit())
+	p := gn.Params()[0]
+
+	h := History{Coalesce: time.Minute}
+	for _, x := range []float64{0.9, 0.8, 0.7} {
+		h.Set(p, x)
+	}
+	if n := len(h.Changes()); n != 1 {
+		t.Fatalf("have %v changes, want 1", n)
+	}
+	h.Undo()
+	if gn.Amp() != 1 {
+		t.Fatalf("undo have %v, want 1", gn.Amp())
+	}
+}
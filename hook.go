@@ -0,0 +1,88 @@
+package snd
+
+import "sync"
+
+// Hook is called on the audio thread by a Dispatcher with tc of a buffer and
+// the frame at its start, counting frames of buffers from tc 1. A hook must
+// return quickly, as the buffer waits on it.
+type Hook func(tc uint64, frame uint64)
+
+// hooks are hooks registered with a Dispatcher.
+type hooks struct {
+	mu     sync.Mutex
+	id     int
+	before map[int]Hook
+	after  map[int]Hook
+	panic  map[int]Hook
+	order  []int  // ids in order of registration
+	fns    []Hook // reused by call
+}
+
+// add registers fn in m, returning a func removing it.
+func (hk *hooks) add(m *map[int]Hook, fn Hook) (remove func()) {
+	hk.mu.Lock()
+	defer hk.mu.Unlock()
+	if *m == nil {
+		*m = make(map[int]Hook)
+	}
+	hk.id++
+	id := hk.id
+	(*m)[id] = fn
+	hk.order = append(hk.order, id)
+	return func() {
+		hk.mu.Lock()
+		defer hk.mu.Unlock()
+		delete(*m, id)
+		for i, x := range hk.order {
+			if x == id {
+				hk.order = append(hk.order[:i], hk.order[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// call calls hooks of m in order of registration. Hooks are called without
+// holding the lock so they may add and remove hooks.
+func (hk *hooks) call(m *map[int]Hook, tc uint64, inps []*Input) {
+	hk.mu.Lock()
+	fns := hk.fns[:0]
+	for _, id := range hk.order {
+		if fn, ok := (*m)[id]; ok {
+			fns = append(fns, fn)
+		}
+	}
+	hk.fns = fns
+	hk.mu.Unlock()
+	if len(fns) == 0 || len(inps) == 0 {
+		return
+	}
+	// the output is the only input of weight zero, sorted last.
+	out := inps[len(inps)-1].sd
+	frame := TickFrame(tc, len(out.Samples())/out.Channels())
+	for _, fn := range fns {
+		fn(tc, frame)
+	}
+}
+
+// BeforeDispatch registers fn to be called before each buffer is prepared,
+// such as for a custom scheduler to start notes and change params, returning
+// a func removing it. A hook may call Panic to take effect this buffer.
+// Hooks may be added and removed from any goroutine, including from a hook.
+func (dp *Dispatcher) BeforeDispatch(fn Hook) (remove func()) {
+	return dp.hooks.add(&dp.hooks.before, fn)
+}
+
+// OnPanic registers fn to be called before preparing the first buffer after
+// Panic, once inputs are silenced, such as to zero meters, returning a func
+// removing it.
+func (dp *Dispatcher) OnPanic(fn Hook) (remove func()) {
+	return dp.hooks.add(&dp.hooks.panic, fn)
+}
+
+// AfterDispatch registers fn to be called after each buffer is prepared, such
+// as to aggregate meters or sync visuals to output, returning a func removing
+// it. With DispatchN, fn is called before the func given to DispatchN.
+func (dp *Dispatcher) AfterDispatch(fn Hook) (remove func()) {
+	return dp.hooks.add(&dp.hooks.after, fn)
+}
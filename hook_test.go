@@ -0,0 +1,38 @@
+package snd
+
+import "testing"
+
+func TestDispatchHooks(t *testing.T) {
+	osc := NewOscil(Sine(), 440, nil)
+	inps := GetInputs(osc)
+	var dp Dispatcher
+	var calls []string
+	var frames []uint64
+	removeBefore := dp.BeforeDispatch(func(tc, frame uint64) {
+		calls = append(calls, "before")
+		frames = append(frames, frame)
+	})
+	dp.AfterDispatch(func(tc, frame uint64) {
+		calls = append(calls, "after")
+	})
+	dp.DispatchN(1, 2, func(uint64) { calls = append(calls, "fn") }, inps...)
+	want := []string{"before", "after", "fn", "before", "after", "fn"}
+	if len(calls) != len(want) {
+		t.Fatalf("have calls %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("have calls %v, want %v", calls, want)
+		}
+	}
+	if n := uint64(len(osc.Samples())); frames[0] != 0 || frames[1] != n {
+		t.Fatalf("have frames %v, want 0 and %v", frames, n)
+	}
+
+	removeBefore()
+	calls = calls[:0]
+	dp.Dispatch(3, inps...)
+	if len(calls) != 1 || calls[0] != "after" {
+		t.Fatalf("have calls %v after removing, want only after", calls)
+	}
+}
@@ -0,0 +1,137 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Hotswap plays a graph that may be replaced while playing, such as by a
+// patch or preset built on another goroutine. A graph swapped in takes effect
+// at the start of the next buffer and is crossfaded with the graph playing,
+// so changing patches never glitches. Graphs are prepared by Hotswap itself,
+// so no backend needs to be notified of a swap.
+type Hotswap struct {
+	*mono
+	chans int
+	dp    Dispatcher
+	dg    float64 // fade change per frame
+
+	mu   sync.Mutex
+	next *swapped // waiting to fade in
+
+	cur, old *swapped // old is nil unless fading out
+	g        float64  // position of fade from old to cur
+}
+
+// swapped is a graph swapped in.
+type swapped struct {
+	sd   Sound
+	inps []*Input
+	done chan struct{} // closed once faded out
+}
+
+func newswapped(chans int, sd Sound) *swapped {
+	sw := &swapped{done: make(chan struct{})}
+	if sd != nil {
+		sw.sd = Conform(chans, sd)
+		sw.inps = GetInputs(sw.sd)
+	}
+	return sw
+}
+
+// NewHotswap returns Hotswap of chans channels playing sd, which may be nil
+// for silence until swapped, and fading over 20ms when swapped.
+func NewHotswap(chans int, sd Sound) *Hotswap {
+	hs := &Hotswap{mono: newmono(nil), chans: chans, g: 1}
+	hs.out = make(Discrete, len(hs.out)*chans)
+	hs.cur = newswapped(chans, sd)
+	hs.SetFade(20 * time.Millisecond)
+	return hs
+}
+
+func (hs *Hotswap) Channels() int   { return hs.chans }
+func (hs *Hotswap) Inputs() []Sound { return nil }
+
+// Panic silences the graphs of hs, as Dispatcher.Panic.
+func (hs *Hotswap) Panic() { hs.dp.Panic() }
+
+// SetFade sets the duration of crossfades; it must not be called while
+// playing.
+func (hs *Hotswap) SetFade(d time.Duration) {
+	hs.dg = math.Inf(1)
+	if n := Dtof(d, hs.sr); n > 0 {
+		hs.dg = 1 / float64(n)
+	}
+}
+
+// Swap swaps in sd, nil for silence, to fade in from the next buffer, remixed
+// to the channels of hs if they differ. Inputs of sd are found on the calling
+// goroutine, so large graphs are best built and swapped off the audio thread.
+// While a fade is in progress sd waits for it to finish; a graph swapped in
+// and waiting is replaced.
+//
+// The returned channel is closed once sd is swapped out in turn, faded out and
+// no longer prepared, or replaced while waiting, such as to free resources of
+// sd then.
+func (hs *Hotswap) Swap(sd Sound) <-chan struct{} {
+	sw := newswapped(hs.chans, sd)
+	hs.mu.Lock()
+	if hs.next != nil {
+		// replaced before taking effect; nothing faded out.
+		close(hs.next.done)
+	}
+	hs.next = sw
+	hs.mu.Unlock()
+	return sw.done
+}
+
+// Current returns the graph swapped in most recently, waiting or not.
+func (hs *Hotswap) Current() Sound {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	if hs.next != nil {
+		return hs.next.sd
+	}
+	return hs.cur.sd
+}
+
+func (hs *Hotswap) Prepare(tc uint64) {
+	if hs.old == nil {
+		hs.mu.Lock()
+		if next := hs.next; next != nil {
+			hs.next = nil
+			hs.old, hs.cur, hs.g = hs.cur, next, 0
+		}
+		hs.mu.Unlock()
+	}
+	hs.dp.up = hs.st // graphs play as set of the graph of hs
+	for _, sw := range [...]*swapped{hs.old, hs.cur} {
+		if sw != nil && sw.sd != nil {
+			hs.dp.Dispatch(tc, sw.inps...)
+		}
+	}
+	for i := 0; i < len(hs.out); i += hs.chans {
+		if hs.old != nil {
+			hs.g = math.Min(hs.g+hs.dg, 1)
+		}
+		ga, gb := math.Cos(hs.g*math.Pi/2), math.Sin(hs.g*math.Pi/2)
+		for c := 0; c < hs.chans; c++ {
+			var x float64
+			if hs.old != nil && hs.old.sd != nil {
+				x += ga * hs.old.sd.Samples()[i+c]
+			}
+			if hs.cur.sd != nil {
+				x += gb * hs.cur.sd.Samples()[i+c]
+			}
+			if hs.off {
+				x = 0
+			}
+			hs.out[i+c] = x
+		}
+	}
+	if hs.old != nil && hs.g == 1 {
+		close(hs.old.done)
+		hs.old = nil
+	}
+}
@@ -0,0 +1,49 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHotswap(t *testing.T) {
+	hs := NewHotswap(1, NewConst(1))
+	done := hs.Swap(NewConst(-1))
+	out := Render(hs, Dtof(50*time.Millisecond, DefaultSampleRate))
+	if out[0] < 0.99 {
+		t.Fatalf("have %v starting swap, want near 1", out[0])
+	}
+	for i := 1; i < len(out); i++ {
+		if math.Abs(out[i]-out[i-1]) > 0.01 {
+			t.Fatalf("have step %v at %v, want crossfade", out[i]-out[i-1], i)
+		}
+	}
+	if x := out[len(out)-1]; !equaleps(x, -1, 1e-9) {
+		t.Fatalf("have %v after swap, want -1", x)
+	}
+	select {
+	case <-done:
+		t.Fatal("done before swapped out")
+	default:
+	}
+
+	// swapped while fading waits; waiting swaps are replaced.
+	replaced := hs.Swap(nil)
+	hs.Swap(NewConst(1))
+	<-replaced
+	Render(hs, DefaultBufferLen)
+	hs.Swap(NewConst(2))
+	if x := Render(hs, DefaultBufferLen)[DefaultBufferLen-1]; x >= 1 {
+		t.Fatalf("have %v, want fading to 1 before 2", x)
+	}
+	select {
+	case <-done:
+		t.Fatal("done while fading out")
+	default:
+	}
+	Render(hs, Dtof(50*time.Millisecond, DefaultSampleRate))
+	<-done
+	if x := Render(hs, DefaultBufferLen)[DefaultBufferLen-1]; !equaleps(x, 2, 1e-9) {
+		t.Fatalf("have %v, want 2", x)
+	}
+}
@@ -0,0 +1,400 @@
+// Package inspect serves a read-only view of a running graph to external
+// visualization tools: its topology, values of params, and meters and scopes
+// of every node. It changes nothing, apart from the control API of package
+// control, so monitoring doesn't link UI code into the audio process nor
+// expose control of it. Bodies are JSON.
+//
+//  GET /graph         nodes of the graph with their inputs
+//  GET /params        values of params by name
+//  GET /meters        peak and RMS of every node by name
+//  GET /scope/{name}  recent frames of a node, mixed to mono
+//  GET /stream        a WebSocket of Messages
+//
+// The stream sends the graph first and again as it changes, then meters and
+// params at an interval of 50ms, or of the query rate=, such as
+// rate=100ms, with scopes of nodes named by the query scope=, such as
+// scope=out,lfo.
+package inspect // import "dasa.cc/snd/inspect"
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// ScopeLen is the number of frames kept of each node for scopes.
+const ScopeLen = 1024
+
+// Node describes a node of the graph.
+type Node struct {
+	Name       string   `json:"name"`
+	Kind       string   `json:"kind"` // type of the node, such as "Oscil"
+	Channels   int      `json:"channels"`
+	SampleRate float64  `json:"samplerate"`
+	Inputs     []string `json:"inputs,omitempty"` // names of nodes input
+}
+
+// Meter is the level of a node: Peak held and falling at 20dB a second, and
+// RMS over about 300ms, as amplitudes.
+type Meter struct {
+	Peak float64 `json:"peak"`
+	RMS  float64 `json:"rms"`
+}
+
+// Message is sent by the stream, with only fields that changed.
+type Message struct {
+	Graph  []Node               `json:"graph,omitempty"`
+	Time   time.Time            `json:"time"`
+	Meters map[string]Meter     `json:"meters,omitempty"`
+	Params snd.Preset           `json:"params,omitempty"`
+	Scopes map[string][]float64 `json:"scopes,omitempty"`
+}
+
+// meter measures a node on the audio thread, publishing what it measured to
+// readers whenever they aren't reading.
+type meter struct {
+	sd          snd.Sound
+	chans       int
+	peakf, msrc float64 // of peak falling and ms averaging per frame
+
+	// of the audio thread.
+	peak, ms float64
+	scope    []float64 // ring of mono frames
+	w        int
+
+	// published, under the lock of the Inspector.
+	ppeak, pms float64
+	pscope     []float64
+	pw         int
+}
+
+func newmeter(sd snd.Sound) *meter {
+	sr := sd.SampleRate()
+	return &meter{
+		sd:     sd,
+		chans:  sd.Channels(),
+		peakf:  math.Pow(10, -20.0/20/sr),
+		msrc:   1 - math.Exp(-1/(0.3*sr)),
+		scope:  make([]float64, ScopeLen),
+		pscope: make([]float64, ScopeLen),
+	}
+}
+
+// view is the graph as last found, replaced whole as it changes so the audio
+// thread reads it without locking.
+type view struct {
+	version int
+	nodes   []Node
+	meters  []*meter // of each node
+	byname  map[string]*meter
+}
+
+// Inspector measures a graph prepared by a Dispatcher, serving its view as
+// an http.Handler. Nodes are found from the output, named as given by Name
+// or by kind and order found otherwise, such as "Oscil#2".
+//
+// Nodes are found on the goroutine calling New, Name, or Notify, and measured
+// on the audio thread without locking or allocating; measures are published
+// each buffer unless a reader holds them, and then on the next.
+type Inspector struct {
+	remove []func()
+	reset  uint32 // atomic; Panic called since last buffer
+	pub    int32  // atomic; held by readers, tried by the audio thread
+	v      atomic.Value
+
+	mu     sync.Mutex // of fields below, never held on the audio thread
+	out    snd.Sound
+	names  map[snd.Sound]string
+	params *snd.Params
+}
+
+// New returns Inspector of out, the output of a graph prepared by dp,
+// measuring until Close.
+func New(dp *snd.Dispatcher, out snd.Sound) *Inspector {
+	ins := &Inspector{out: out, names: make(map[snd.Sound]string)}
+	ins.v.Store(&view{})
+	ins.Notify()
+	ins.remove = []func(){
+		dp.AfterDispatch(ins.after),
+		dp.OnPanic(func(uint64, uint64) { ins.Panic() }),
+	}
+	return ins
+}
+
+func (ins *Inspector) view() *view { return ins.v.Load().(*view) }
+
+// Name names sd, such as by names of nodes of a patch.
+func (ins *Inspector) Name(name string, sd snd.Sound) {
+	ins.mu.Lock()
+	ins.names[sd] = name
+	ins.mu.Unlock()
+	ins.Notify()
+}
+
+// SetParams sets params served, such as Params of a patch.
+func (ins *Inspector) SetParams(ps *snd.Params) {
+	ins.mu.Lock()
+	ins.params = ps
+	ins.mu.Unlock()
+}
+
+// Notify finds nodes again after the graph changed, as of Engine.Notify of
+// package al.
+func (ins *Inspector) Notify() {
+	ins.mu.Lock()
+	defer ins.mu.Unlock()
+	ins.v.Store(ins.walk(ins.view()))
+}
+
+// Close stops measuring.
+func (ins *Inspector) Close() {
+	for _, fn := range ins.remove {
+		fn()
+	}
+}
+
+// Panic zeroes meters and scopes, as the Dispatcher measured does when
+// panicked, once the next buffer is prepared. It is safe to call from any
+// goroutine.
+func (ins *Inspector) Panic() { atomic.StoreUint32(&ins.reset, 1) }
+
+// lock holds measures published, waiting for the audio thread publishing.
+func (ins *Inspector) lock() {
+	for !atomic.CompareAndSwapInt32(&ins.pub, 0, 1) {
+		runtime.Gosched()
+	}
+}
+
+func (ins *Inspector) unlock() { atomic.StoreInt32(&ins.pub, 0) }
+
+func (ins *Inspector) after(uint64, uint64) {
+	v := ins.view()
+	reset := atomic.CompareAndSwapUint32(&ins.reset, 1, 0)
+	for _, m := range v.meters {
+		if reset {
+			m.clear()
+		}
+		m.measure()
+	}
+	if atomic.CompareAndSwapInt32(&ins.pub, 0, 1) {
+		for _, m := range v.meters {
+			m.ppeak, m.pms, m.pw = m.peak, m.ms, m.w
+			copy(m.pscope, m.scope)
+		}
+		ins.unlock()
+	}
+}
+
+// walk returns the view of nodes found from the output depth first after
+// old, keeping meters of nodes found before.
+func (ins *Inspector) walk(old *view) *view {
+	meters := make(map[snd.Sound]*meter, len(old.meters))
+	for _, m := range old.meters {
+		meters[m.sd] = m
+	}
+	v := &view{version: old.version + 1, byname: make(map[string]*meter)}
+	kinds := make(map[string]int)
+	found := make(map[snd.Sound]string)
+	var visit func(sd snd.Sound) string
+	visit = func(sd snd.Sound) string {
+		if name, ok := found[sd]; ok {
+			return name
+		}
+		m := meters[sd]
+		if m == nil || m.chans != sd.Channels() {
+			m = newmeter(sd)
+		}
+		kind := fmt.Sprintf("%T", sd)
+		kind = kind[strings.LastIndexAny(kind, ".*")+1:]
+		name, ok := ins.names[sd]
+		if !ok {
+			kinds[kind]++
+			name = fmt.Sprintf("%s#%d", kind, kinds[kind])
+		}
+		found[sd] = name
+		i := len(v.nodes)
+		v.nodes = append(v.nodes, Node{Name: name, Kind: kind, Channels: sd.Channels(), SampleRate: sd.SampleRate()})
+		v.meters = append(v.meters, m)
+		v.byname[name] = m
+		var inputs []string
+		for _, in := range sd.Inputs() {
+			if in != nil {
+				inputs = append(inputs, visit(in))
+			}
+		}
+		v.nodes[i].Inputs = inputs
+		return name
+	}
+	visit(ins.out)
+	return v
+}
+
+// clear zeroes meters and the scope of m.
+func (m *meter) clear() {
+	m.peak, m.ms = 0, 0
+	for i := range m.scope {
+		m.scope[i] = 0
+	}
+}
+
+// measure updates meters and the scope of m from its output.
+func (m *meter) measure() {
+	xs := m.sd.Samples()
+	chans := m.chans
+	if chans < 1 {
+		chans = 1
+	}
+	for i := 0; i+chans <= len(xs); i += chans {
+		var sum float64
+		m.peak *= m.peakf
+		for _, x := range xs[i : i+chans] {
+			m.peak = math.Max(m.peak, math.Abs(x))
+			m.ms += m.msrc * (x*x - m.ms)
+			sum += x
+		}
+		m.scope[m.w] = sum / float64(chans)
+		m.w = (m.w + 1) % len(m.scope)
+	}
+}
+
+// Graph returns nodes of the graph in the order found from the output.
+func (ins *Inspector) Graph() []Node {
+	return append([]Node(nil), ins.view().nodes...)
+}
+
+// Meters returns meters of every node by name.
+func (ins *Inspector) Meters() map[string]Meter {
+	v := ins.view()
+	out := make(map[string]Meter, len(v.nodes))
+	ins.lock()
+	defer ins.unlock()
+	for i, m := range v.meters {
+		out[v.nodes[i].Name] = Meter{Peak: m.ppeak, RMS: math.Sqrt(m.pms)}
+	}
+	return out
+}
+
+// Scope returns the last ScopeLen frames of the node named, mixed to mono,
+// oldest first, or false if there is no such node.
+func (ins *Inspector) Scope(name string) ([]float64, bool) {
+	m, ok := ins.view().byname[name]
+	if !ok {
+		return nil, false
+	}
+	xs := make([]float64, 0, ScopeLen)
+	ins.lock()
+	defer ins.unlock()
+	return append(append(xs, m.pscope[m.pw:]...), m.pscope[:m.pw]...), true
+}
+
+// Params returns values of params by name, or nil if none were set.
+func (ins *Inspector) Params() snd.Preset {
+	ins.mu.Lock()
+	ps := ins.params
+	ins.mu.Unlock()
+	if ps == nil {
+		return nil
+	}
+	return ps.Save()
+}
+
+func (ins *Inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": fmt.Sprintf("method %s not allowed", r.Method)})
+		return
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	switch {
+	case path == "graph":
+		writeJSON(w, http.StatusOK, ins.Graph())
+	case path == "params":
+		writeJSON(w, http.StatusOK, ins.Params())
+	case path == "meters":
+		writeJSON(w, http.StatusOK, ins.Meters())
+	case strings.HasPrefix(path, "scope/"):
+		name := strings.TrimPrefix(path, "scope/")
+		xs, ok := ins.Scope(name)
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("undefined node %q", name)})
+			return
+		}
+		writeJSON(w, http.StatusOK, xs)
+	case path == "stream":
+		ins.stream(w, r)
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("no %s of /%s", r.Method, path)})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	enc.Encode(v)
+}
+
+// stream sends Messages over a WebSocket until the client closes it.
+func (ins *Inspector) stream(w http.ResponseWriter, r *http.Request) {
+	rate := 50 * time.Millisecond
+	if s := r.URL.Query().Get("rate"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("bad rate %q", s)})
+			return
+		}
+		rate = d
+	}
+	var scopes []string
+	if s := r.URL.Query().Get("scope"); s != "" {
+		scopes = strings.Split(s, ",")
+		sort.Strings(scopes)
+	}
+	ws, err := upgrade(w, r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	defer ws.Close()
+
+	tick := time.NewTicker(rate)
+	defer tick.Stop()
+	version := -1
+	for {
+		msg := Message{Time: time.Now()}
+		if v := ins.view(); version != v.version {
+			version = v.version
+			msg.Graph = append([]Node(nil), v.nodes...)
+		}
+		msg.Meters, msg.Params = ins.Meters(), ins.Params()
+		for _, name := range scopes {
+			if xs, ok := ins.Scope(name); ok {
+				if msg.Scopes == nil {
+					msg.Scopes = make(map[string][]float64)
+				}
+				msg.Scopes[name] = xs
+			}
+		}
+		b, err := json.Marshal(msg)
+		if err != nil {
+			return
+		}
+		if err := ws.WriteText(b); err != nil {
+			return
+		}
+		select {
+		case <-tick.C:
+		case <-ws.Done():
+			return
+		}
+	}
+}
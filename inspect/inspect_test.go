@@ -0,0 +1,177 @@
+package inspect
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dasa.cc/snd"
+)
+
+func newgraph(t *testing.T) (*Inspector, *httptest.Server) {
+	lfo := snd.NewOscil(snd.Sine(), 2, nil)
+	osc := snd.NewOscil(snd.Sine(), 440, lfo)
+	mix := snd.NewMixer(osc)
+	var dp snd.Dispatcher
+	ins := New(&dp, mix)
+	ins.Name("out", mix)
+	ins.Name("osc", osc)
+	var ps snd.Params
+	ps.Register("osc", osc)
+	ins.SetParams(&ps)
+	inps := snd.GetInputs(mix)
+	for tc := uint64(1); tc <= 20; tc++ {
+		dp.Dispatch(tc, inps...)
+	}
+	srv := httptest.NewServer(ins)
+	t.Cleanup(srv.Close)
+	return ins, srv
+}
+
+func get(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestInspector(t *testing.T) {
+	_, srv := newgraph(t)
+
+	var nodes []Node
+	get(t, srv.URL+"/graph", &nodes)
+	var have []string
+	for _, nd := range nodes {
+		have = append(have, nd.Name+"("+nd.Kind+")<"+strings.Join(nd.Inputs, ","))
+	}
+	if want := "out(Mixer)<osc osc(Oscil)<Oscil#1 Oscil#1(Oscil)<"; strings.Join(have, " ") != want {
+		t.Errorf("have graph %v, want %v", have, want)
+	}
+
+	var meters map[string]Meter
+	get(t, srv.URL+"/meters", &meters)
+	if m := meters["out"]; m.Peak < 0.5 || m.Peak > 1 || m.RMS < 0.3 || m.RMS > m.Peak {
+		t.Errorf("have meter %+v of out, want a sine near unity", m)
+	}
+
+	var params snd.Preset
+	get(t, srv.URL+"/params", &params)
+	if params["osc.freq"] != 440 {
+		t.Errorf("have params %v, want osc.freq of 440", params)
+	}
+
+	var scope []float64
+	if get(t, srv.URL+"/scope/osc", &scope); len(scope) != ScopeLen {
+		t.Errorf("have scope of %v frames, want %v", len(scope), ScopeLen)
+	}
+	var e map[string]string
+	if code := get(t, srv.URL+"/scope/nope", &e); code != http.StatusNotFound {
+		t.Errorf("have status %v of undefined node, want 404", code)
+	}
+}
+
+func TestStream(t *testing.T) {
+	_, srv := newgraph(t)
+	c, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "GET /stream?scope=out HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n"+
+		"Sec-WebSocket-Version: 13\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n\r\n")
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the example of RFC 6455.
+	if have, want := resp.Header.Get("Sec-WebSocket-Accept"), "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="; resp.StatusCode != 101 || have != want {
+		t.Fatalf("have status %v accepting %q, want 101 accepting %q", resp.StatusCode, have, want)
+	}
+
+	var hdr [2]byte
+	io.ReadFull(br, hdr[:])
+	n := int(hdr[1])
+	switch n {
+	case 126:
+		var b [2]byte
+		io.ReadFull(br, b[:])
+		n = int(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		io.ReadFull(br, b[:])
+		n = int(binary.BigEndian.Uint64(b[:]))
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(br, b); err != nil {
+		t.Fatal(err)
+	}
+	if hdr[0] != 0x80|opText {
+		t.Fatalf("have frame %x, want final text", hdr[0])
+	}
+	var msg Message
+	if err := json.Unmarshal(b, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Graph) != 3 || len(msg.Scopes["out"]) != ScopeLen || msg.Meters["osc"].Peak == 0 {
+		t.Errorf("have %v nodes, scope of %v frames, and meter %+v of osc, want 3 nodes, a full scope and level",
+			len(msg.Graph), len(msg.Scopes["out"]), msg.Meters["osc"])
+	}
+
+	// a masked close is answered and the connection closed.
+	c.Write([]byte{0x80 | opClose, 0x80, 1, 2, 3, 4})
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	ins, _ := newgraph(t)
+	ins.Panic()
+	ins.after(21, 0)
+	// of the buffer measured since, the rest zeroed.
+	scope, _ := ins.Scope("osc")
+	if pk := snd.Peak(scope[:ScopeLen-snd.DefaultBufferLen]); pk != 0 {
+		t.Errorf("have scope peak %v before panic, want zeroed", pk)
+	}
+	if pk := snd.Peak(scope[ScopeLen-snd.DefaultBufferLen:]); pk == 0 {
+		t.Error("have scope silent after panic")
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	ins, _ := newgraph(t)
+	if n := testing.AllocsPerRun(100, func() { ins.after(1, 0) }); n != 0 {
+		t.Errorf("have %v allocations measuring, want 0", n)
+	}
+
+	// measured on the audio thread while read and found again.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ins.Meters()
+			ins.Scope("osc")
+			ins.Notify()
+		}
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			ins.after(1, 0)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package inspect
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// wsGUID is appended to the key of a client to accept it, as of RFC 6455.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of frames of a WebSocket.
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+// wsconn is the server side of a WebSocket, as much of RFC 6455 as sending
+// text needs: messages received are discarded, pings answered, and the
+// connection closed as the client closes it.
+type wsconn struct {
+	c    net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex // held writing
+	done chan struct{}
+}
+
+// upgrade upgrades the connection of r to a WebSocket.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsconn, error) {
+	if !headerHas(r.Header, "Connection", "upgrade") || !headerHas(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("stream is of a websocket")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("missing Sec-WebSocket-Key")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("connection can't be hijacked")
+	}
+	c, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	h := sha1.Sum([]byte(key + wsGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	ws := &wsconn{c: c, rw: rw, done: make(chan struct{})}
+	go ws.read()
+	return ws, nil
+}
+
+// headerHas reports whether a comma separated header of h has token.
+func headerHas(h http.Header, key, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(key)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Done is closed once the client closes the connection or it fails.
+func (ws *wsconn) Done() <-chan struct{} { return ws.done }
+
+func (ws *wsconn) Close() error { return ws.c.Close() }
+
+// WriteText sends b as a text message.
+func (ws *wsconn) WriteText(b []byte) error { return ws.write(opText, b) }
+
+func (ws *wsconn) write(op byte, b []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	// servers send frames whole and unmasked.
+	hdr := []byte{0x80 | op, 0}
+	switch n := len(b); {
+	case n < 126:
+		hdr[1] = byte(n)
+	case n <= 0xffff:
+		hdr[1] = 126
+		hdr = append(hdr, 0, 0)
+		binary.BigEndian.PutUint16(hdr[2:], uint16(n))
+	default:
+		hdr[1] = 127
+		hdr = append(hdr, make([]byte, 8)...)
+		binary.BigEndian.PutUint64(hdr[2:], uint64(n))
+	}
+	ws.rw.Write(hdr)
+	ws.rw.Write(b)
+	return ws.rw.Flush()
+}
+
+// read reads frames of the client until it closes the connection.
+func (ws *wsconn) read() {
+	defer close(ws.done)
+	for {
+		var hdr [2]byte
+		if _, err := io.ReadFull(ws.rw, hdr[:]); err != nil {
+			return
+		}
+		op := hdr[0] & 0xf
+		n := uint64(hdr[1] & 0x7f)
+		switch n {
+		case 126:
+			var b [2]byte
+			if _, err := io.ReadFull(ws.rw, b[:]); err != nil {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(b[:]))
+		case 127:
+			var b [8]byte
+			if _, err := io.ReadFull(ws.rw, b[:]); err != nil {
+				return
+			}
+			n = binary.BigEndian.Uint64(b[:])
+		}
+		var mask [4]byte
+		if hdr[1]&0x80 != 0 {
+			if _, err := io.ReadFull(ws.rw, mask[:]); err != nil {
+				return
+			}
+		}
+		switch op {
+		case opClose:
+			ws.write(opClose, nil)
+			return
+		case opPing:
+			if n > 125 {
+				return
+			}
+			b := make([]byte, n)
+			if _, err := io.ReadFull(ws.rw, b); err != nil {
+				return
+			}
+			for i := range b {
+				b[i] ^= mask[i%4]
+			}
+			ws.write(opPong, b)
+		default:
+			if _, err := io.CopyN(io.Discard, ws.rw, int64(n)); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package snd
+
+import "math"
+
+// Interpolation selects how delay lines are read between frames, trading
+// quality of modulated delays against work per frame.
+type Interpolation int
+
+const (
+	// InterpNone reads the nearest frame, cheapest but zippering when
+	// delay time changes.
+	InterpNone Interpolation = iota
+
+	// InterpLinear blends two frames, dulling highs slightly at fractional
+	// delays. It is the default.
+	InterpLinear
+
+	// InterpAllpass passes all frequencies at unity by a first order allpass
+	// filter, best for fixed or slowly changing delays such as tuned strings,
+	// though fast changes may briefly ring.
+	InterpAllpass
+
+	// InterpCubic fits a hermite spline through four frames, cleanest for
+	// delays modulated quickly. Delays under two frames are read linearly.
+	InterpCubic
+)
+
+// delayreader is a read head of a circular delay line by a selected
+// interpolation, holding state of allpass interpolation.
+type delayreader struct {
+	interp Interpolation
+	q      Interpolation // read by, interp limited by Quality
+	y1     float64       // last output
+}
+
+// read returns the frame of circular buffer line read d frames behind write
+// position w, where d is at least one.
+func (dr *delayreader) read(line []float64, w int, d float64) (y float64) {
+	switch dr.q {
+	case InterpNone:
+		n := len(line)
+		r := w - int(math.Round(d))
+		for r < 0 {
+			r += n
+		}
+		y = line[r]
+	case InterpAllpass:
+		n := len(line)
+		r := float64(w) - d
+		for r < 0 {
+			r += float64(n)
+		}
+		j := int(r)
+		k := j + 1
+		if k == n {
+			k = 0
+		}
+		// the newer frame k is delayed by the remaining fraction.
+		frac := r - float64(j)
+		eta := frac / (2 - frac)
+		y = eta*line[k] + line[j] - eta*dr.y1
+	case InterpCubic:
+		if d < 2 {
+			y = readfrac(line, w, d)
+			break
+		}
+		n := len(line)
+		r := float64(w) - d
+		for r < 0 {
+			r += float64(n)
+		}
+		j := int(r)
+		at := func(i int) float64 { return line[(i+n)%n] }
+		y = hermite(at(j-1), at(j), at(j+1), at(j+2), r-float64(j))
+	default:
+		y = readfrac(line, w, d)
+	}
+	dr.y1 = y
+	return y
+}
+
+// interpolators is embedded by sounds reading delay lines, setting the
+// interpolation of all their read heads.
+type interpolators []*delayreader
+
+// Interpolation returns how delay lines are read.
+func (ips interpolators) Interpolation() Interpolation {
+	if len(ips) == 0 {
+		return InterpLinear
+	}
+	return ips[0].interp
+}
+
+// SetInterpolation sets how delay lines are read, such as InterpCubic for
+// quickly modulated delays, clearing state of allpass interpolation.
+func (ips interpolators) SetInterpolation(ip Interpolation) {
+	for _, dr := range ips {
+		dr.interp, dr.q, dr.y1 = ip, ip, 0
+	}
+}
+
+// limit limits the interpolation of read heads to the quality of st, at the
+// start of each buffer.
+func (ips interpolators) limit(st *settings) {
+	for _, dr := range ips {
+		dr.q = st.interpOf(dr.interp)
+	}
+}
+
+// newreaders returns n read heads of linear interpolation.
+func newreaders(n int) interpolators {
+	ips := make(interpolators, n)
+	for i := range ips {
+		ips[i] = &delayreader{interp: InterpLinear, q: InterpLinear}
+	}
+	return ips
+}
@@ -0,0 +1,52 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestInterpolation(t *testing.T) {
+	// a high tone read half a frame late is dulled least by allpass and cubic.
+	sr := DefaultSampleRate
+	level := func(ip Interpolation) Decibel {
+		dr := &delayreader{interp: ip, q: ip}
+		line := make([]float64, 64)
+		var p float64
+		for i, w := 0, 0; i < 8192; i++ {
+			line[w] = math.Sin(2 * math.Pi * 5000 * float64(i) / sr)
+			w++
+			if w == len(line) {
+				w = 0
+			}
+			y := dr.read(line, w, 10.5)
+			if i >= 4096 {
+				p += y * y
+			}
+		}
+		return DecibelOf(math.Sqrt(2 * p / 4096))
+	}
+	lin, ap, cub := level(InterpLinear), level(InterpAllpass), level(InterpCubic)
+	if !equaleps(float64(ap), 0, 0.05) {
+		t.Errorf("have allpass level %v, want 0dB", ap)
+	}
+	if lin > -0.3 || cub <= lin {
+		t.Errorf("have linear level %v and cubic %v, want cubic above linear", lin, cub)
+	}
+
+	dr := &delayreader{interp: InterpNone, q: InterpNone}
+	line := []float64{0, 1, 2, 3, 4}
+	if y := dr.read(line, 0, 2.4); y != 3 {
+		t.Errorf("have %v, want nearest frame 3", y)
+	}
+
+	ips := newreaders(2)
+	if ips.Interpolation() != InterpLinear {
+		t.Fatalf("have %v, want linear by default", ips.Interpolation())
+	}
+	ips.SetInterpolation(InterpCubic)
+	for _, dr := range ips {
+		if dr.interp != InterpCubic {
+			t.Fatalf("have %v, want all heads cubic", dr.interp)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package snd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// State is a snapshot of a live performance: params, transport, and
+// optionally lines describing the graph, such as of package patch, replayed
+// to rebuild the graph before params are restored.
+//
+// States are encoded as JSON.
+type State struct {
+	Time    time.Time `json:"time"`
+	Graph   []string  `json:"graph,omitempty"`
+	Params  Preset    `json:"params"`
+	BPM     BPM       `json:"bpm,omitempty"`
+	Beat    float64   `json:"beat,omitempty"`
+	Playing bool      `json:"playing,omitempty"`
+}
+
+// ReadState decodes the State of file name, such as written by a Journal.
+func ReadState(name string) (State, error) {
+	var st State
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return st, err
+	}
+	if err := json.Unmarshal(b, &st); err != nil {
+		return st, fmt.Errorf("snd: read state %s failed: %v", name, err)
+	}
+	return st, nil
+}
+
+// Restore sets params of ps and position, tempo and play state of tp, either
+// of which may be nil. Unknown params are reported as by Params.Load.
+func (st State) Restore(ps *Params, tp *Transport) error {
+	if tp != nil {
+		if st.BPM > 0 {
+			tp.SetBPM(st.BPM)
+		}
+		tp.Seek(st.Beat)
+		if st.Playing {
+			tp.Play()
+		} else {
+			tp.Stop()
+		}
+	}
+	if ps != nil {
+		return ps.Load(st.Params)
+	}
+	return nil
+}
+
+// WriteFile writes st to file name atomically, so a crash while writing leaves
+// the prior file intact.
+func (st State) WriteFile(name string) error {
+	b, err := json.MarshalIndent(st, "", "\t")
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Journal periodically snapshots a performance to a file so that after a
+// crash it may be restored with ReadState and State.Restore. Snapshots are
+// taken on the audio thread by Tick, added as a hook of the Dispatcher
+// playing the params such as by Dispatcher.AfterDispatch, so they are
+// consistent with the buffer just played, and written to disk on a goroutine
+// of the Journal. A snapshot due while the last is still writing is skipped.
+type Journal struct {
+	name  string
+	every int // frames between snapshots
+	ps    *Params
+	tp    *Transport
+	graph func() []string
+
+	last  uint64
+	taken bool
+
+	states chan State
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewJournal returns Journal writing file name every interval with params of
+// ps and state of tp, either of which may be nil, at sample rate sr.
+func NewJournal(name string, every time.Duration, sr float64, ps *Params, tp *Transport) *Journal {
+	j := &Journal{
+		name:   name,
+		every:  Dtof(every, sr),
+		ps:     ps,
+		tp:     tp,
+		states: make(chan State, 1),
+		done:   make(chan struct{}),
+	}
+	go j.write()
+	return j
+}
+
+// SetGraph sets fn returning lines describing the graph, such as Lines of a
+// Patch, called on the audio thread with each snapshot.
+func (j *Journal) SetGraph(fn func() []string) { j.graph = fn }
+
+// Snapshot returns the current state. It must not be called concurrently with
+// the graph playing, other than from a hook.
+func (j *Journal) Snapshot() State {
+	st := State{Time: time.Now()}
+	if j.graph != nil {
+		st.Graph = j.graph()
+	}
+	if j.ps != nil {
+		st.Params = j.ps.Save()
+	}
+	if tp := j.tp; tp != nil {
+		st.BPM, st.Beat, st.Playing = tp.BPM(), tp.Beat(), tp.Playing()
+	}
+	return st
+}
+
+// Tick takes a snapshot when one is due at frame, and is a Hook.
+func (j *Journal) Tick(tc, frame uint64) {
+	if j.taken && FrameSince(frame, j.last) < int64(j.every) {
+		return
+	}
+	j.last, j.taken = frame, true
+	select {
+	case j.states <- j.Snapshot():
+	default:
+	}
+}
+
+func (j *Journal) write() {
+	defer close(j.done)
+	for st := range j.states {
+		err := st.WriteFile(j.name)
+		j.mu.Lock()
+		j.err = err
+		j.mu.Unlock()
+	}
+}
+
+// Err returns the error of the last snapshot written, if any.
+func (j *Journal) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Close waits for any snapshot writing and stops the Journal, returning the
+// error of the last snapshot written. Tick must not be called after.
+func (j *Journal) Close() error {
+	close(j.states)
+	<-j.done
+	return j.Err()
+}
@@ -0,0 +1,47 @@
+package snd
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "state.json")
+	gn := NewGain(0.5, NewOscil(Sine(), 440, nil))
+	var ps Params
+	ps.Register("gain", gn)
+	tp := NewTransport(120)
+	tp.Play()
+	mix := NewMixer(gn, tp)
+
+	dp := new(Dispatcher)
+	j := NewJournal(name, 10*time.Millisecond, DefaultSampleRate, &ps, tp)
+	j.SetGraph(func() []string { return []string{"osc a freq=440"} })
+	dp.AfterDispatch(j.Tick)
+	dp.Render(mix, Dtof(time.Second, DefaultSampleRate))
+	if err := j.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := ReadState(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Params["gain.amp"] != 0.5 || st.BPM != 120 || !st.Playing || st.Beat <= 0 || st.Beat > 2 {
+		t.Fatalf("have state %+v", st)
+	}
+	if len(st.Graph) != 1 || st.Graph[0] != "osc a freq=440" {
+		t.Fatalf("have graph %q", st.Graph)
+	}
+
+	// as if restarted after a crash.
+	gn.SetAmp(1)
+	tp = NewTransport(90)
+	if err := st.Restore(&ps, tp); err != nil {
+		t.Fatal(err)
+	}
+	if gn.Amp() != 0.5 || tp.BPM() != 120 || tp.Beat() != st.Beat || !tp.Playing() {
+		t.Fatalf("have amp %v, transport %v at beat %v playing %v after restore", gn.Amp(), tp.BPM(), tp.Beat(), tp.Playing())
+	}
+}
@@ -0,0 +1,242 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// dispersion is the number of allpass sections of the dispersion filter of
+// a Karplus voice.
+const dispersion = 4
+
+// allpass1 is a first order allpass filter, delaying low frequencies by
+// (1-a)/(1+a) frames and high frequencies less for negative a.
+type allpass1 struct {
+	a      float64
+	x1, y1 float64
+}
+
+func (ap *allpass1) process(x float64) float64 {
+	y := ap.a*x + ap.x1 - ap.a*ap.y1
+	ap.x1, ap.y1 = x, y
+	return y
+}
+
+// lag returns the phase lag of an allpass of coefficient a at w radians per
+// frame.
+func lag(a, w float64) float64 {
+	return w - 2*math.Atan2(a*math.Sin(w), 1+a*math.Cos(w))
+}
+
+// Karplus is a polyphonic plucked and struck string instrument of
+// Karplus-Strong synthesis: a burst of noise circulating a delay line the
+// length of a period, losing high frequencies each trip. A dispersion filter
+// of allpass sections in the loop delays low frequencies more than high, so
+// partials of a stiff string are stretched by its inharmonicity coefficient
+// as of Inharmonicity, crucial to the sound of a piano.
+type Karplus struct {
+	*Poly
+	decay      time.Duration // of fundamental
+	brightness float64
+	inharm     float64
+	soft       bool
+	rnd        *rand.Rand
+}
+
+// NewKarplus returns Karplus of voices with the fundamental ringing over
+// decay, of medium brightness and harmonic until inharmonicity is set.
+func NewKarplus(voices int, decay time.Duration) *Karplus {
+	ks := &Karplus{decay: decay, brightness: 0.5, rnd: rand.New(rand.NewSource(1))}
+	ks.Poly = NewPoly(voices, func() Voice { return newKarplusVoice(ks) })
+	ks.Poly.SetGain(VoiceGain(voices, CrestSaw, DefaultHeadroom))
+	return ks
+}
+
+func (ks *Karplus) Decay() time.Duration     { return ks.decay }
+func (ks *Karplus) SetDecay(d time.Duration) { ks.decay = d }
+
+// Brightness returns brightness belonging to [0..1], where brighter strings
+// lose high frequencies slower.
+func (ks *Karplus) Brightness() float64     { return ks.brightness }
+func (ks *Karplus) SetBrightness(x float64) { ks.brightness = math.Max(0, math.Min(1, x)) }
+
+// Soft reports whether notes are played una corda.
+func (ks *Karplus) Soft() bool { return ks.soft }
+
+// SetSoft sets notes played from the next una corda, quieter and struck with
+// half the brightness, as by a soft pedal.
+func (ks *Karplus) SetSoft(down bool) { ks.soft = down }
+
+// Inharmonicity returns the inharmonicity coefficient of strings.
+func (ks *Karplus) Inharmonicity() float64 { return ks.inharm }
+
+// SetInharmonicity sets the inharmonicity coefficient of strings, taking
+// effect on the next note. Stretch is limited by the dispersion filter, so
+// low notes reach high coefficients at best in their lower partials.
+func (ks *Karplus) SetInharmonicity(b float64) { ks.inharm = math.Max(0, b) }
+
+func (ks *Karplus) Params() []*Param {
+	return []*Param{
+		NewParam("inharmonicity", ks.Inharmonicity, ks.SetInharmonicity).Range(0, 0.05, 0),
+		NewParam("brightness", ks.Brightness, ks.SetBrightness).Range(0, 1, 0.5).In(UnitPercent),
+		NewParam("decay",
+			func() float64 { return ks.decay.Seconds() },
+			func(x float64) { ks.decay = time.Duration(x * float64(time.Second)) }).Range(0.01, 20, ks.decay.Seconds()).In(UnitSeconds),
+	}
+}
+
+// karplusVoice is a Voice of a Karplus.
+type karplusVoice struct {
+	*mono
+	ks    *Karplus
+	line  Discrete // delay line
+	pos   int
+	disp  [dispersion]allpass1
+	tune  allpass1 // fractional delay
+	x1    float64  // last two into the loss filter
+	x2    float64
+	b, g  float64 // coefficients of the loss filter
+	damp  float64 // of release
+	held  bool
+	quiet int
+}
+
+func newKarplusVoice(ks *Karplus) *karplusVoice {
+	return &karplusVoice{mono: newmono(nil), ks: ks, damp: 1}
+}
+
+func (vc *karplusVoice) Inputs() []Sound { return nil }
+
+// looppartial returns partial k of the loop of a voice, in radians per frame,
+// of dispersion coefficient a, delay line length n, and fractional delay
+// coefficient c.
+func looppartial(a float64, n int, c float64, k int) float64 {
+	lo, hi := 0.0, math.Pi
+	for i := 0; i < 50; i++ {
+		w := (lo + hi) / 2
+		if w*float64(n+1)+dispersion*lag(a, w)+lag(c, w) < twopi*float64(k) {
+			lo = w
+		} else {
+			hi = w
+		}
+	}
+	return (lo + hi) / 2
+}
+
+// design returns the allpass coefficient of the dispersion filter stretching
+// partials of hz by inharmonicity b, and the length of the delay line and
+// coefficient of the fractional delay allpass of the rest of the loop tuning
+// it to hz. The loop of the delay line, the loss filter delaying a frame, and
+// the allpass filters sounds partial k where its phase lag totals 2πk.
+func design(hz, b, sr float64) (a float64, n int, c float64) {
+	w0 := twopi * hz / sr
+	tuning := func(a float64) (int, float64) {
+		r := sr/hz - 1 - dispersion*lag(a, w0)/w0
+		n := int(r - 0.5)
+		if n < 1 {
+			n = 1
+		}
+		// the lag left to the fractional delay inverts exactly.
+		phi := (w0 - (twopi - w0*float64(n+1) - dispersion*lag(a, w0))) / 2
+		return n, math.Sin(phi) / math.Sin(w0-phi)
+	}
+	// match the highest partial up to the eighth well below nyquist.
+	k := 8
+	for k > 1 && w0*Inharmonicity(k, b) > math.Pi/2 {
+		k--
+	}
+	if b == 0 || k < 2 {
+		n, c = tuning(0)
+		return 0, n, c
+	}
+	want := w0 * Inharmonicity(k, b)
+	lo, hi := -0.95, 0.0
+	for i := 0; i < 30; i++ {
+		a = (lo + hi) / 2
+		n, c = tuning(a)
+		if looppartial(a, n, c, k) < want {
+			hi = a // more negative stretches more
+		} else {
+			lo = a
+		}
+	}
+	a = (lo + hi) / 2
+	n, c = tuning(a)
+	return a, n, c
+}
+
+func (vc *karplusVoice) NoteOn(hz, vel float64) {
+	ks := vc.ks
+	a, n, c := design(hz, ks.inharm, vc.sr)
+	if cap(vc.line) < n {
+		vc.line = make(Discrete, n)
+	}
+	vc.line = vc.line[:n]
+	bright := ks.brightness
+	if ks.soft {
+		bright /= 2
+		vel *= unaCordaGain.Amp()
+	}
+	// excite with noise, darker for duller strings.
+	var lp float64
+	for i := range vc.line {
+		lp += (0.2 + 0.8*bright) * (vel*(2*ks.rnd.Float64()-1) - lp)
+		vc.line[i] = lp
+	}
+	vc.pos = 0
+	for i := range vc.disp {
+		vc.disp[i] = allpass1{a: a}
+	}
+	vc.tune = allpass1{a: c}
+	vc.x1, vc.x2 = 0, 0
+	// the loss filter averages neighbors by b, and g decays the fundamental
+	// over decay.
+	vc.b = 0.25 * (1 - bright)
+	vc.g = math.Pow(0.001, 1/(math.Max(ks.decay.Seconds(), 1e-3)*hz))
+	vc.held, vc.quiet = true, 0
+}
+
+// NoteOff damps the string.
+func (vc *karplusVoice) NoteOff() { vc.held = false }
+
+// SetDamping sets damping of the string once released, ringing on undamped
+// as held.
+func (vc *karplusVoice) SetDamping(x float64) { vc.damp = math.Max(0, math.Min(1, x)) }
+
+func (vc *karplusVoice) Done() bool {
+	return vc.line == nil || !vc.held && vc.quiet > len(vc.out)
+}
+
+func (vc *karplusVoice) Prepare(uint64) {
+	if vc.Done() {
+		for i := range vc.out {
+			vc.out[i] = 0
+		}
+		return
+	}
+	g := vc.g
+	if !vc.held {
+		g *= 1 - 0.1*vc.damp
+	}
+	for i := range vc.out {
+		x := vc.line[vc.pos]
+		y := g * (vc.b*x + (1-2*vc.b)*vc.x1 + vc.b*vc.x2)
+		vc.x2, vc.x1 = vc.x1, x
+		for k := range vc.disp {
+			y = vc.disp[k].process(y)
+		}
+		y = vc.tune.process(y)
+		vc.line[vc.pos] = y
+		vc.pos = (vc.pos + 1) % len(vc.line)
+		if math.Abs(x) < 1e-5 {
+			vc.quiet++
+		} else {
+			vc.quiet = 0
+		}
+		if vc.off {
+			x = 0
+		}
+		vc.out[i] = x
+	}
+}
@@ -0,0 +1,135 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pinglen is the length in frames of the ping of a LatencyProbe.
+const pinglen = 512
+
+// LatencyProbe measures round-trip latency of an audio interface: it plays a
+// short burst of noise, a ping, and listens for it on its mono input, such as
+// a Capture of a microphone or a cable looped from output to input. The delay
+// from playing to hearing it is found by cross-correlation, so a quiet ping
+// is found beneath noise, and is accurate to a frame.
+//
+// The measure is used to align overdubs with Recorder.SetLatency.
+type LatencyProbe struct {
+	*mono
+	ping   Discrete
+	listen int // frames
+
+	run int32 // atomic; whether probing
+
+	mu    sync.Mutex
+	at    int // frames of ping played
+	heard Discrete
+}
+
+// NewLatencyProbe returns LatencyProbe of in listening up to max for its ping.
+// The probe plays silence until started.
+func NewLatencyProbe(max time.Duration, in Sound) *LatencyProbe {
+	lp := &LatencyProbe{mono: newmono(in)}
+	lp.listen = Dtof(max, lp.sr)
+	lp.ping = make(Discrete, pinglen)
+	// xorshift noise, same on every run; a half amplitude ping is loud enough
+	// to be found and gentle on speakers.
+	x := uint32(2463534242)
+	for i := range lp.ping {
+		x ^= x << 13
+		x ^= x >> 17
+		x ^= x << 5
+		lp.ping[i] = 0.5 * (float64(x)/math.MaxUint32*2 - 1)
+	}
+	return lp
+}
+
+// Start discards any measure and plays the ping from the next buffer.
+func (lp *LatencyProbe) Start() {
+	lp.mu.Lock()
+	lp.at, lp.heard = 0, lp.heard[:0]
+	lp.mu.Unlock()
+	atomic.StoreInt32(&lp.run, 1)
+}
+
+// Done reports whether probing finished, listening its full duration.
+func (lp *LatencyProbe) Done() bool {
+	if atomic.LoadInt32(&lp.run) == 1 {
+		return false
+	}
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	return len(lp.heard) > 0
+}
+
+// Latency returns the round-trip latency in frames, and whether the ping was
+// heard clearly once done. It is safe to call from other goroutines and does
+// its work there rather than on the audio thread.
+func (lp *LatencyProbe) Latency() (frames int, ok bool) {
+	if !lp.Done() {
+		return 0, false
+	}
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+
+	var pe float64
+	for _, x := range lp.ping {
+		pe += x * x
+	}
+	best, lag := 0.0, -1
+	for l := 0; l+len(lp.ping) <= len(lp.heard); l++ {
+		var c float64
+		for i, x := range lp.ping {
+			c += x * lp.heard[l+i]
+		}
+		if math.Abs(c) > math.Abs(best) {
+			best, lag = c, l
+		}
+	}
+	if lag < 0 {
+		return 0, false
+	}
+	var he float64
+	for _, x := range lp.heard[lag : lag+len(lp.ping)] {
+		he += x * x
+	}
+	// normalized correlation; a ping heard clearly correlates strongly with
+	// what was heard in its place, even if inverted.
+	if r := math.Abs(best) / math.Sqrt(pe*he); he == 0 || r < 0.5 {
+		return 0, false
+	}
+	return lag, true
+}
+
+// Duration returns the round-trip latency as a duration, and whether the ping
+// was heard clearly.
+func (lp *LatencyProbe) Duration() (time.Duration, bool) {
+	n, ok := lp.Latency()
+	return Ftod(n, lp.sr), ok
+}
+
+func (lp *LatencyProbe) Prepare(uint64) {
+	if atomic.LoadInt32(&lp.run) == 0 {
+		for i := range lp.out {
+			lp.out[i] = 0
+		}
+		return
+	}
+	lp.mu.Lock()
+	defer lp.mu.Unlock()
+	for i := range lp.out {
+		if lp.at < len(lp.ping) && !lp.off {
+			lp.out[i] = lp.ping[lp.at]
+		} else {
+			lp.out[i] = 0
+		}
+		lp.at++
+	}
+	lp.heard = append(lp.heard, lp.in.Samples()...)
+	if len(lp.heard) >= lp.listen+len(lp.ping) {
+		atomic.StoreInt32(&lp.run, 0)
+	}
+}
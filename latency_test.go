@@ -0,0 +1,48 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+// roundtrip returns latency measured by a probe whose output returns to its
+// input delayed by n frames and by the buffering of a Capture.
+func roundtrip(t *testing.T, n int, gain float64) (int, bool) {
+	t.Helper()
+	cp := NewCapture(0)
+	lp := NewLatencyProbe(100*time.Millisecond, cp)
+	inps := GetInputs(lp)
+	var dp Dispatcher
+	line := make(Discrete, n)
+	lp.Start()
+	for tc := uint64(1); !lp.Done(); tc++ {
+		if tc > 1000 {
+			t.Fatal("probe never done")
+		}
+		dp.Dispatch(tc, inps...)
+		for _, x := range lp.Samples() {
+			line = append(line, gain*x)
+		}
+		cp.Write(line[:len(lp.Samples())])
+		line = line[len(lp.Samples()):]
+	}
+	return lp.Latency()
+}
+
+func TestLatencyProbe(t *testing.T) {
+	base, ok := roundtrip(t, 0, 1)
+	if !ok {
+		t.Fatal("ping not heard")
+	}
+	if base != DefaultBufferLen {
+		t.Fatalf("have latency %v of capture alone, want %v", base, DefaultBufferLen)
+	}
+	for _, n := range []int{1, 300, 2000} {
+		if have, ok := roundtrip(t, n, -0.01); !ok || have != base+n {
+			t.Fatalf("have latency %v ok %v, want %v", have, ok, base+n)
+		}
+	}
+	if _, ok := roundtrip(t, 10000, 1); ok {
+		t.Fatal("ping heard beyond max")
+	}
+}
@@ -0,0 +1,113 @@
+package snd
+
+import "fmt"
+
+// Layout describes the channels of interleaved samples by their number.
+// Channels of LayoutSurround are ordered left, right, center, low frequency
+// effects, left surround, and right surround, as in WAVE files.
+type Layout int
+
+const (
+	LayoutMono     Layout = 1
+	LayoutStereo   Layout = 2
+	LayoutSurround Layout = 6 // 5.1
+)
+
+func (lt Layout) String() string {
+	switch lt {
+	case LayoutMono:
+		return "mono"
+	case LayoutStereo:
+		return "stereo"
+	case LayoutSurround:
+		return "5.1"
+	}
+	return fmt.Sprintf("%v channels", int(lt))
+}
+
+// Channels returns the number of channels of lt.
+func (lt Layout) Channels() int { return int(lt) }
+
+// RemixMatrix returns gains of each channel of from in each channel of to,
+// indexed output first, by standard downmixes:
+//
+//	- stereo to mono sums channels at -3dB
+//	- 5.1 to stereo adds center and each surround to its side at -3dB, and
+//	  drops low frequency effects
+//	- 5.1 to mono is 5.1 to stereo to mono
+//
+// Fewer channels are sent up to more as mono to center, or otherwise to the
+// first channels alike, and other layouts down by dropping channels.
+func RemixMatrix(from, to Layout) [][]float64 {
+	g := onesqrt2
+	m := make([][]float64, to)
+	for i := range m {
+		m[i] = make([]float64, from)
+	}
+	switch {
+	case from == LayoutStereo && to == LayoutMono:
+		m[0][0], m[0][1] = g, g
+	case from == LayoutSurround && to == LayoutStereo:
+		m[0][0], m[0][2], m[0][4] = 1, g, g
+		m[1][1], m[1][2], m[1][5] = 1, g, g
+	case from == LayoutSurround && to == LayoutMono:
+		// sides at -3dB of L and R at -3dB.
+		m[0][0], m[0][1] = g, g
+		m[0][2] = 2 * g * g
+		m[0][4], m[0][5] = g*g, g*g
+	case from == LayoutMono && to == LayoutSurround:
+		m[2][0] = 1
+	case from == LayoutMono:
+		for i := range m {
+			m[i][0] = 1
+		}
+	default:
+		for i := 0; i < int(from) && i < int(to); i++ {
+			m[i][i] = 1
+		}
+	}
+	return m
+}
+
+// Remix converts the channel layout of its input by a RemixMatrix, such as
+// to play a 5.1 file in stereo.
+type Remix struct {
+	*mono
+	m     [][]float64
+	chans int
+}
+
+// NewRemix returns Remix of in to layout to.
+func NewRemix(to Layout, in Sound) *Remix {
+	sd := newmono(in)
+	sd.out = make(Discrete, len(in.Samples())/in.Channels()*to.Channels())
+	return &Remix{mono: sd, m: RemixMatrix(Layout(in.Channels()), to), chans: to.Channels()}
+}
+
+func (rm *Remix) Channels() int { return rm.chans }
+
+func (rm *Remix) Prepare(uint64) {
+	ic := len(rm.m[0])
+	for f := 0; f < len(rm.out)/rm.chans; f++ {
+		for c, gains := range rm.m {
+			var x float64
+			if !rm.off {
+				for k, g := range gains {
+					if g != 0 {
+						x += g * rm.in.Index(f*ic+k)
+					}
+				}
+			}
+			rm.out[f*rm.chans+c] = x
+		}
+	}
+}
+
+// Conform returns in with chans channels, remixed if it has other than chans,
+// such as for a consumer of a fixed layout.
+func Conform(chans int, in Sound) Sound {
+	if in.Channels() == chans {
+		return in
+	}
+	return NewRemix(Layout(chans), in)
+}
@@ -0,0 +1,38 @@
+package snd
+
+import "testing"
+
+func TestRemix(t *testing.T) {
+	// a frame of 5.1 of L, R, C, LFE, Ls, Rs.
+	sig := Discrete{1, 2, 3, 4, 5, 6}
+	pl := NewPlayer(sig, 6, DefaultSampleRate)
+	st := Conform(2, pl)
+	if st.Channels() != 2 {
+		t.Fatalf("have %v channels, want 2", st.Channels())
+	}
+	out := Render(st, 1)
+	if l, want := out[0], 1+onesqrt2*(3+5); !equaleps(l, want, 1e-12) {
+		t.Fatalf("have left %v, want %v", l, want)
+	}
+	if r, want := out[1], 2+onesqrt2*(3+6); !equaleps(r, want, 1e-12) {
+		t.Fatalf("have right %v, want %v", r, want)
+	}
+
+	pl = NewPlayer(Discrete{1, 1}, 2, DefaultSampleRate)
+	mono := Conform(1, pl)
+	if x := Render(mono, 1)[0]; !equaleps(x, 2*onesqrt2, 1e-12) {
+		t.Fatalf("have mono %v, want -3dB sum %v", x, 2*onesqrt2)
+	}
+	if sd := Conform(2, pl); sd != Sound(pl) {
+		t.Fatal("have stereo remixed to stereo, want as is")
+	}
+
+	// 5.1 to mono is 5.1 to stereo to mono.
+	m := RemixMatrix(LayoutSurround, LayoutMono)
+	s2 := RemixMatrix(LayoutSurround, LayoutStereo)
+	for k := 0; k < 6; k++ {
+		if want := onesqrt2 * (s2[0][k] + s2[1][k]); !equaleps(m[0][k], want, 1e-12) {
+			t.Fatalf("have gain %v of channel %v, want %v", m[0][k], k, want)
+		}
+	}
+}
@@ -0,0 +1,12 @@
+// Package link synchronizes a snd.Transport with other applications on the
+// local network using Ableton Link.
+//
+// The package requires the abl_link C library from the Link repository's
+// extensions/abl_link directory and is only built with the link tag:
+//
+//  go build -tags link
+//
+// With cgo flags pointing at the library, for example:
+//
+//  CGO_CFLAGS=-I$LINK/extensions/abl_link/include CGO_LDFLAGS=-L$LINK/build
+package link // import "dasa.cc/snd/link"
@@ -0,0 +1,112 @@
+// +build link
+
+package link
+
+/*
+#cgo LDFLAGS: -labl_link -lstdc++ -lm
+#include <stdbool.h>
+#include <abl_link.h>
+*/
+import "C"
+
+import (
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// Link is a session of Ableton Link driving a transport. Link outputs silence
+// and must be part of a graph to be prepared, e.g. appended to a mixer.
+//
+// On each prepare, the transport's tempo, play state, and beat position are
+// set from the session for the next buffer. Changes made locally to the
+// transport's tempo or play state between buffers are committed to the
+// session, so any peer may change them.
+type Link struct {
+	// Quantum is the number of beats over which phase is aligned with
+	// peers, typically the length of a bar.
+	Quantum float64
+
+	// Latency is added to the time at which beats are computed to account
+	// for output buffering of the audio backend.
+	Latency time.Duration
+
+	tp    *snd.Transport
+	link  C.abl_link
+	state C.abl_link_session_state
+	out   snd.Discrete
+
+	bpm     snd.BPM
+	playing bool
+}
+
+// New returns a Link for tp joined to the session. Close must be called to
+// leave the session and release resources.
+func New(tp *snd.Transport) *Link {
+	ln := &Link{
+		Quantum: 4,
+		tp:      tp,
+		link:    C.abl_link_create(C.double(tp.BPM())),
+		state:   C.abl_link_create_session_state(),
+		out:     make(snd.Discrete, len(tp.Samples())),
+		bpm:     tp.BPM(),
+		playing: tp.Playing(),
+	}
+	C.abl_link_enable_start_stop_sync(ln.link, true)
+	C.abl_link_enable(ln.link, true)
+	return ln
+}
+
+// Close leaves the session.
+func (ln *Link) Close() {
+	C.abl_link_enable(ln.link, false)
+	C.abl_link_destroy_session_state(ln.state)
+	C.abl_link_destroy(ln.link)
+}
+
+// Peers returns the number of other applications in the session.
+func (ln *Link) Peers() int { return int(C.abl_link_num_peers(ln.link)) }
+
+func (ln *Link) Channels() int            { return 1 }
+func (ln *Link) SampleRate() float64      { return ln.tp.SampleRate() }
+func (ln *Link) Inputs() []snd.Sound      { return []snd.Sound{ln.tp} }
+func (ln *Link) Samples() snd.Discrete    { return ln.out }
+func (ln *Link) Interp(t float64) float64 { return 0 }
+func (ln *Link) At(t float64) float64     { return 0 }
+func (ln *Link) Index(i int) float64      { return 0 }
+
+func (ln *Link) Prepare(uint64) {
+	C.abl_link_capture_audio_session_state(ln.link, ln.state)
+	now := C.abl_link_clock_micros(ln.link)
+
+	// commit local changes made since last prepare.
+	changed := false
+	if bpm := ln.tp.BPM(); bpm != ln.bpm {
+		C.abl_link_set_tempo(ln.state, C.double(bpm), now)
+		changed = true
+	}
+	if playing := ln.tp.Playing(); playing != ln.playing {
+		C.abl_link_set_is_playing(ln.state, C.bool(playing), C.uint64_t(now))
+		if playing {
+			C.abl_link_request_beat_at_time(ln.state, C.double(ln.tp.Beat()), now, C.double(ln.Quantum))
+		}
+		changed = true
+	}
+	if changed {
+		C.abl_link_commit_audio_session_state(ln.link, ln.state)
+	}
+
+	// position of next buffer as heard.
+	d := snd.Ftod(len(ln.out), ln.tp.SampleRate()) + ln.Latency
+	at := now + C.int64_t(d/time.Microsecond)
+
+	ln.bpm = snd.BPM(C.abl_link_tempo(ln.state))
+	ln.playing = bool(C.abl_link_is_playing(ln.state))
+	ln.tp.SetBPM(ln.bpm)
+	if ln.playing {
+		ln.tp.Play()
+		ln.tp.Seek(float64(C.abl_link_beat_at_time(ln.state, at, C.double(ln.Quantum))))
+	} else {
+		ln.tp.Stop()
+	}
+}
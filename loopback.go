@@ -0,0 +1,91 @@
+package snd
+
+import "sync/atomic"
+
+// Loopback is a sink playing a graph without a device, passing each buffer of
+// interleaved samples to another goroutine over a channel, such as for
+// integration tests or sending audio over a network. Unlike a device, the
+// graph waits on its reader, so no buffer is dropped or repeated and output
+// is the same on every run.
+type Loopback struct {
+	sd     Sound
+	inps   []*Input
+	dp     Dispatcher
+	tc     uint64
+	c      chan Discrete
+	rest   Discrete // of a buffer partly read
+	quit   chan struct{}
+	done   chan struct{}
+	notify int32 // atomic
+}
+
+// NewLoopback returns Loopback of sd queueing up to n buffers ahead of its
+// reader.
+func NewLoopback(sd Sound, n int) *Loopback {
+	return &Loopback{sd: sd, inps: GetInputs(sd), c: make(chan Discrete, n)}
+}
+
+// C returns the channel of buffers, each a copy the reader may keep, closed
+// once stopped.
+func (lb *Loopback) C() <-chan Discrete { return lb.c }
+
+// Dispatcher returns the Dispatcher preparing the graph, such as to add hooks.
+func (lb *Loopback) Dispatcher() *Dispatcher { return &lb.dp }
+
+// Panic silences the graph of lb alone, as Dispatcher.Panic.
+func (lb *Loopback) Panic() { lb.dp.Panic() }
+
+// Notify finds inputs of the graph again before the next buffer, such as
+// after a sound is added, as Dispatch requires of inputs changed while running.
+func (lb *Loopback) Notify() { atomic.StoreInt32(&lb.notify, 1) }
+
+// Start prepares buffers on a new goroutine until stopped.
+func (lb *Loopback) Start() {
+	lb.quit, lb.done = make(chan struct{}), make(chan struct{})
+	go lb.run()
+}
+
+// Stop stops preparing, waits for the buffer in progress to finish and
+// closes C. Buffers queued may still be read.
+func (lb *Loopback) Stop() {
+	close(lb.quit)
+	<-lb.done
+}
+
+func (lb *Loopback) run() {
+	defer close(lb.done)
+	defer close(lb.c)
+	for {
+		if atomic.CompareAndSwapInt32(&lb.notify, 1, 0) {
+			lb.inps = GetInputs(lb.sd)
+		}
+		lb.tc++
+		lb.dp.Dispatch(lb.tc, lb.inps...)
+		buf := make(Discrete, len(lb.sd.Samples()))
+		copy(buf, lb.sd.Samples())
+		select {
+		case lb.c <- buf:
+		case <-lb.quit:
+			return
+		}
+	}
+}
+
+// Read fills xs with interleaved samples in order, waiting for buffers not
+// yet prepared, and returns the number read, short of len(xs) only once
+// stopped. Read must not be mixed with receiving from C.
+func (lb *Loopback) Read(xs []float64) (n int) {
+	for n < len(xs) {
+		if len(lb.rest) == 0 {
+			buf, ok := <-lb.c
+			if !ok {
+				return n
+			}
+			lb.rest = buf
+		}
+		m := copy(xs[n:], lb.rest)
+		lb.rest = lb.rest[m:]
+		n += m
+	}
+	return n
+}
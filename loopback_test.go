@@ -0,0 +1,28 @@
+package snd
+
+import "testing"
+
+func TestLoopback(t *testing.T) {
+	lb := NewLoopback(&counter{mono: newmono(nil)}, 2)
+	lb.Start()
+	// reads of any size continue where the last left off, never skipping.
+	var want float64
+	for _, n := range []int{100, 441, 256, 700, 3} {
+		xs := make([]float64, n)
+		if have := lb.Read(xs); have != n {
+			t.Fatalf("have %v read, want %v", have, n)
+		}
+		for i, x := range xs {
+			if x != want {
+				t.Fatalf("read of %v: have %v at %v, want %v", n, x, i, want)
+			}
+			want++
+		}
+	}
+	lb.Stop()
+	// buffers queued before stopping are read, then reads fall short.
+	xs := make([]float64, 4*DefaultBufferLen)
+	if n := lb.Read(xs); n == len(xs) || xs[0] != want {
+		t.Fatalf("have %v read after stop starting at %v, want fewer from %v", n, xs[0], want)
+	}
+}
@@ -0,0 +1,17 @@
+// Package lv2 hosts LV2 plugins in a graph, mapping their control ports to
+// params, so native effects and instruments mix with Sounds of package snd.
+//
+// The package requires the lilv C library and is only built with the lv2 tag:
+//
+//  go build -tags lv2
+//
+// Plugins installed on the LV2_PATH are discovered, and loaded by URI:
+//
+//  w := lv2.NewWorld()
+//  defer w.Close()
+//  pl, err := w.Load("http://calf.sourceforge.net/plugins/Reverb", in)
+//
+// Plugins with ports other than audio and control, such as of atoms for MIDI,
+// are loaded only if those ports are optional. VST3 plugins are not hosted,
+// as their SDK has no C interface to call.
+package lv2 // import "dasa.cc/snd/lv2"
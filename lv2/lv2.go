@@ -0,0 +1,264 @@
+//go:build lv2
+// +build lv2
+
+package lv2
+
+/*
+#cgo pkg-config: lilv-0
+#include <stdlib.h>
+#include <lilv/lilv.h>
+
+static void lv2_connect(LilvInstance *in, uint32_t port, float *data) {
+	lilv_instance_connect_port(in, port, data);
+}
+
+static void lv2_run(LilvInstance *in, uint32_t n) { lilv_instance_run(in, n); }
+static void lv2_activate(LilvInstance *in) { lilv_instance_activate(in); }
+static void lv2_deactivate(LilvInstance *in) { lilv_instance_deactivate(in); }
+static void lv2_free(LilvInstance *in) { lilv_instance_free(in); }
+*/
+import "C"
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+
+	"dasa.cc/snd"
+)
+
+// LV2 URIs of port classes and properties.
+const (
+	uriInput    = "http://lv2plug.in/ns/lv2core#InputPort"
+	uriOutput   = "http://lv2plug.in/ns/lv2core#OutputPort"
+	uriAudio    = "http://lv2plug.in/ns/lv2core#AudioPort"
+	uriControl  = "http://lv2plug.in/ns/lv2core#ControlPort"
+	uriOptional = "http://lv2plug.in/ns/lv2core#connectionOptional"
+	uriToggled  = "http://lv2plug.in/ns/lv2core#toggled"
+	uriUnit     = "http://lv2plug.in/ns/extensions/units#unit"
+)
+
+// units of snd of LV2 units.
+var units = map[string]snd.Unit{
+	"http://lv2plug.in/ns/extensions/units#hz":            snd.UnitHz,
+	"http://lv2plug.in/ns/extensions/units#db":            snd.UnitDecibel,
+	"http://lv2plug.in/ns/extensions/units#s":             snd.UnitSeconds,
+	"http://lv2plug.in/ns/extensions/units#ms":            snd.UnitMilliseconds,
+	"http://lv2plug.in/ns/extensions/units#semitone12TET": snd.UnitSemitones,
+}
+
+// World is a collection of LV2 plugins installed. Close must be called to
+// free it, after closing plugins loaded.
+type World struct {
+	w    *C.LilvWorld
+	uris map[string]*C.LilvNode
+}
+
+// NewWorld returns World of plugins installed on the LV2_PATH.
+func NewWorld() *World {
+	w := &World{w: C.lilv_world_new(), uris: make(map[string]*C.LilvNode)}
+	C.lilv_world_load_all(w.w)
+	return w
+}
+
+// Close frees w.
+func (w *World) Close() {
+	for _, n := range w.uris {
+		C.lilv_node_free(n)
+	}
+	C.lilv_world_free(w.w)
+}
+
+// uri returns the node of uri, kept until w is closed.
+func (w *World) uri(uri string) *C.LilvNode {
+	if n, ok := w.uris[uri]; ok {
+		return n
+	}
+	cs := C.CString(uri)
+	defer C.free(unsafe.Pointer(cs))
+	n := C.lilv_new_uri(w.w, cs)
+	w.uris[uri] = n
+	return n
+}
+
+// Plugins returns URIs of plugins installed.
+func (w *World) Plugins() []string {
+	var uris []string
+	pls := C.lilv_world_get_all_plugins(w.w)
+	for it := C.lilv_plugins_begin(pls); !C.lilv_plugins_is_end(pls, it); it = C.lilv_plugins_next(pls, it) {
+		pl := C.lilv_plugins_get(pls, it)
+		uris = append(uris, C.GoString(C.lilv_node_as_uri(C.lilv_plugin_get_uri(pl))))
+	}
+	return uris
+}
+
+// port is a port of a plugin connected to buffers of C, as plugins hold
+// pointers to them between runs.
+type port struct {
+	index C.uint32_t
+	buf   *C.float
+}
+
+// Plugin is a Sound of an LV2 plugin instance processing its input, with
+// control inputs as params named by their symbol and control outputs, such
+// as meters, read by Output.
+type Plugin struct {
+	*snd.External
+	inst      *C.LilvInstance
+	ins, outs []port // audio
+	controls  []port // control inputs
+	meters    map[string]port
+	frames    int
+	name      string
+}
+
+// Load returns Plugin of a new instance of the plugin of uri processing in,
+// which may be nil for instruments and generators. Input is conformed to the
+// audio inputs of the plugin as by snd.Conform. The plugin runs at the sample
+// rate of in, or the default. Close must be called to free it.
+func (w *World) Load(uri string, in snd.Sound) (*Plugin, error) {
+	pls := C.lilv_world_get_all_plugins(w.w)
+	pl := C.lilv_plugins_get_by_uri(pls, w.uri(uri))
+	if pl == nil {
+		return nil, fmt.Errorf("lv2: plugin %s not found", uri)
+	}
+	sr := snd.DefaultSampleRate
+	if in != nil {
+		sr = in.SampleRate()
+	}
+
+	n := int(C.lilv_plugin_get_num_ports(pl))
+	mins := make([]C.float, n)
+	maxs := make([]C.float, n)
+	defs := make([]C.float, n)
+	if n > 0 {
+		C.lilv_plugin_get_port_ranges_float(pl, &mins[0], &maxs[0], &defs[0])
+	}
+
+	inst := C.lilv_plugin_instantiate(pl, C.double(sr), nil)
+	if inst == nil {
+		return nil, fmt.Errorf("lv2: instantiate %s failed", uri)
+	}
+	p := &Plugin{
+		inst:   inst,
+		meters: make(map[string]port),
+		frames: snd.DefaultBufferLen,
+	}
+	if name := C.lilv_plugin_get_name(pl); name != nil {
+		p.name = C.GoString(C.lilv_node_as_string(name))
+		C.lilv_node_free(name)
+	}
+	var params []*snd.Param
+	for i := 0; i < n; i++ {
+		pt := C.lilv_plugin_get_port_by_index(pl, C.uint32_t(i))
+		is := func(uri string) bool { return bool(C.lilv_port_is_a(pl, pt, w.uri(uri))) }
+		has := func(uri string) bool { return bool(C.lilv_port_has_property(pl, pt, w.uri(uri))) }
+		sym := C.GoString(C.lilv_node_as_string(C.lilv_port_get_symbol(pl, pt)))
+
+		switch {
+		case is(uriAudio):
+			pp := port{C.uint32_t(i), (*C.float)(C.calloc(C.size_t(p.frames), C.sizeof_float))}
+			C.lv2_connect(inst, pp.index, pp.buf)
+			if is(uriInput) {
+				p.ins = append(p.ins, pp)
+			} else {
+				p.outs = append(p.outs, pp)
+			}
+		case is(uriControl):
+			pp := port{C.uint32_t(i), (*C.float)(C.calloc(1, C.sizeof_float))}
+			C.lv2_connect(inst, pp.index, pp.buf)
+			if is(uriOutput) {
+				p.meters[sym] = pp
+				continue
+			}
+			p.controls = append(p.controls, pp)
+			min, max, def := float64(mins[i]), float64(maxs[i]), float64(defs[i])
+			if has(uriToggled) {
+				min, max = 0, 1
+			}
+			if math.IsNaN(def) {
+				def = 0
+				if !math.IsNaN(min) {
+					def = min
+				}
+			}
+			*pp.buf = C.float(def)
+			buf := pp.buf
+			prm := snd.NewParam(sym,
+				func() float64 { return float64(*buf) },
+				func(x float64) { *buf = C.float(x) })
+			if !math.IsNaN(min) && !math.IsNaN(max) {
+				prm.Range(min, max, def)
+			}
+			if u := C.lilv_port_get(pl, pt, w.uri(uriUnit)); u != nil {
+				if unit, ok := units[C.GoString(C.lilv_node_as_uri(u))]; ok {
+					prm.In(unit)
+				}
+				C.lilv_node_free(u)
+			}
+			params = append(params, prm)
+		case has(uriOptional):
+			C.lv2_connect(inst, C.uint32_t(i), nil)
+		default:
+			p.free()
+			return nil, fmt.Errorf("lv2: plugin %s has port %s of a type unsupported", uri, sym)
+		}
+	}
+
+	if in != nil && len(p.ins) > 0 {
+		in = snd.Conform(len(p.ins), in)
+	} else {
+		in = nil
+	}
+	p.External = snd.NewExternal(len(p.outs), p.process, in)
+	p.AddParams(params...)
+	C.lv2_activate(inst)
+	return p, nil
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string { return p.name }
+
+// Output returns the value of the control output of symbol, such as a meter.
+func (p *Plugin) Output(symbol string) (float64, bool) {
+	pp, ok := p.meters[symbol]
+	if !ok {
+		return 0, false
+	}
+	return float64(*pp.buf), true
+}
+
+func (p *Plugin) process(in, out [][]float32) {
+	frames := p.frames
+	if len(out) > 0 && len(out[0]) < frames {
+		frames = len(out[0])
+	}
+	for i, pp := range p.ins {
+		buf := (*[1 << 28]float32)(unsafe.Pointer(pp.buf))[:frames:frames]
+		if i < len(in) {
+			copy(buf, in[i])
+		}
+	}
+	C.lv2_run(p.inst, C.uint32_t(frames))
+	for i, pp := range p.outs {
+		copy(out[i], (*[1 << 28]float32)(unsafe.Pointer(pp.buf))[:frames:frames])
+	}
+}
+
+// Close deactivates and frees the plugin; it must not be prepared after.
+func (p *Plugin) Close() {
+	C.lv2_deactivate(p.inst)
+	p.free()
+}
+
+func (p *Plugin) free() {
+	C.lv2_free(p.inst)
+	for _, ports := range [][]port{p.ins, p.outs, p.controls} {
+		for _, pp := range ports {
+			C.free(unsafe.Pointer(pp.buf))
+		}
+	}
+	for _, pp := range p.meters {
+		C.free(unsafe.Pointer(pp.buf))
+	}
+}
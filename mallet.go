@@ -0,0 +1,174 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Material sets partials of a Mallet, ratios of frequency to the fundamental
+// and decay times relative to that of the fundamental.
+type Material struct {
+	Ratios []float64
+	Decays []float64
+}
+
+// Materials of tuned percussion. Bars of marimba and vibraphone are tuned so
+// upper partials fall on harmonics, while a bell is inharmonic.
+var (
+	MaterialMarimba    = Material{Ratios: []float64{1, 3.99, 10.65}, Decays: []float64{1, 0.3, 0.1}}
+	MaterialVibraphone = Material{Ratios: []float64{1, 4, 10.2}, Decays: []float64{1, 0.5, 0.2}}
+	MaterialBell       = Material{Ratios: []float64{0.5, 1, 1.2, 1.5, 2, 2.5, 3}, Decays: []float64{1, 0.8, 0.7, 0.6, 0.5, 0.4, 0.3}}
+)
+
+// resonator is a two pole filter ringing at a frequency, a single mode of a
+// struck body.
+type resonator struct {
+	a1, a2, g float64
+	y1, y2    float64
+}
+
+// set tunes res to hz decaying by 60dB over t60.
+func (res *resonator) set(hz float64, t60 time.Duration, sr float64) {
+	r := math.Pow(0.001, 1/(t60.Seconds()*sr))
+	if hz >= sr/2 {
+		r = 0 // above nyquist is silent
+	}
+	res.a1, res.a2 = 2*r*math.Cos(twopi*hz/sr), -r*r
+	// unity gain at resonance for an impulse.
+	res.g = math.Sin(twopi * hz / sr)
+}
+
+func (res *resonator) process(x float64) float64 {
+	y := res.g*x + res.a1*res.y1 + res.a2*res.y2
+	res.y1, res.y2 = y, res.y1
+	return y
+}
+
+// Mallet is a polyphonic modal synthesis instrument of banded resonators
+// excited by a mallet strike, for marimba, vibraphone, and bell tones.
+//
+// Strike position belongs to [0..1] from the center of a bar to its end;
+// striking off center excites upper partials more. Damping shortens all
+// partials, upper partials the most, as of a softer material.
+type Mallet struct {
+	*Poly
+	mat      Material
+	decay    time.Duration // of fundamental
+	position float64
+	damping  float64
+	hardness float64
+}
+
+// NewMallet returns Mallet of voices of material mat with fundamental ringing
+// over decay, struck at the center without damping by a medium mallet.
+func NewMallet(voices int, mat Material, decay time.Duration) *Mallet {
+	ml := &Mallet{mat: mat, decay: decay, hardness: 0.5}
+	ml.Poly = NewPoly(voices, func() Voice { return newMalletVoice(ml) })
+	ml.Poly.SetGain(VoiceGain(voices, CrestSine, DefaultHeadroom))
+	return ml
+}
+
+func (ml *Mallet) Material() Material     { return ml.mat }
+func (ml *Mallet) SetMaterial(m Material) { ml.mat = m }
+
+func (ml *Mallet) Position() float64     { return ml.position }
+func (ml *Mallet) SetPosition(x float64) { ml.position = math.Max(0, math.Min(1, x)) }
+
+func (ml *Mallet) Damping() float64     { return ml.damping }
+func (ml *Mallet) SetDamping(x float64) { ml.damping = math.Max(0, math.Min(1, x)) }
+
+// Hardness returns hardness of the mallet belonging to [0..1], where harder
+// mallets strike shorter and brighter.
+func (ml *Mallet) Hardness() float64     { return ml.hardness }
+func (ml *Mallet) SetHardness(x float64) { ml.hardness = math.Max(0, math.Min(1, x)) }
+
+func (ml *Mallet) Params() []*Param {
+	return []*Param{
+		NewParam("position", ml.Position, ml.SetPosition).Range(0, 1, 0.5).In(UnitPercent),
+		NewParam("damping", ml.Damping, ml.SetDamping).Range(0, 1, 0).In(UnitPercent),
+		NewParam("hardness", ml.Hardness, ml.SetHardness).Range(0, 1, 0.5).In(UnitPercent),
+	}
+}
+
+// malletVoice is a Voice of a Mallet.
+type malletVoice struct {
+	*mono
+	ml     *Mallet
+	res    []resonator
+	amps   []float64
+	strike int // frames of strike left
+	pulse  int // frames of strike
+	vel    float64
+	held   bool
+	quiet  int
+}
+
+func newMalletVoice(ml *Mallet) *malletVoice {
+	return &malletVoice{mono: newmono(nil), ml: ml}
+}
+
+func (vc *malletVoice) Inputs() []Sound { return nil }
+
+func (vc *malletVoice) NoteOn(hz, vel float64) {
+	ml := vc.ml
+	n := len(ml.mat.Ratios)
+	if len(vc.res) != n {
+		vc.res, vc.amps = make([]resonator, n), make([]float64, n)
+	}
+	for i, ratio := range ml.mat.Ratios {
+		decay := 1.0
+		if i < len(ml.mat.Decays) {
+			decay = ml.mat.Decays[i]
+		}
+		// damping shortens upper partials the most.
+		decay *= math.Pow(1-0.9*ml.damping, 1+float64(i))
+		vc.res[i].set(hz*ratio, time.Duration(decay*float64(ml.decay)), vc.sr)
+		// the center of a bar is a node of alternate modes, left silent unless
+		// struck off center, while all modes sound near the end.
+		vc.amps[i] = math.Abs(math.Cos(math.Pi * float64(i) * (1 - ml.position) / 2))
+		if i == 0 {
+			vc.amps[i] = 1
+		}
+	}
+	// a harder mallet strikes shorter, exciting higher partials.
+	vc.pulse = 1 + Dtof(time.Duration(float64(2*time.Millisecond)*(1-ml.hardness)), vc.sr)
+	vc.strike, vc.vel, vc.held, vc.quiet = vc.pulse, vel, true, 0
+}
+
+// NoteOff lets the voice finish once quiet, as a struck bar rings on.
+func (vc *malletVoice) NoteOff() { vc.held = false }
+
+func (vc *malletVoice) Done() bool {
+	return vc.res == nil || !vc.held && vc.strike == 0 && vc.quiet > len(vc.out)
+}
+
+func (vc *malletVoice) Prepare(uint64) {
+	if vc.Done() {
+		for i := range vc.out {
+			vc.out[i] = 0
+		}
+		return
+	}
+	for i := range vc.out {
+		var x float64
+		if vc.strike > 0 {
+			// raised cosine pulse of the mallet in contact.
+			t := float64(vc.pulse-vc.strike) / float64(vc.pulse)
+			x = vc.vel * (1 - math.Cos(twopi*t)) / float64(vc.pulse)
+			vc.strike--
+		}
+		var y float64
+		for k := range vc.res {
+			y += vc.amps[k] * vc.res[k].process(x)
+		}
+		if math.Abs(y) < 1e-5 {
+			vc.quiet++
+		} else {
+			vc.quiet = 0
+		}
+		if vc.off {
+			y = 0
+		}
+		vc.out[i] = y
+	}
+}
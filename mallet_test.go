@@ -0,0 +1,43 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMallet(t *testing.T) {
+	sr := DefaultSampleRate
+	for _, mat := range []Material{MaterialMarimba, MaterialVibraphone, MaterialBell} {
+		ml := NewMallet(4, mat, time.Second)
+		ml.NoteOn(60, 1)
+		ml.NoteOn(67, 0.5)
+		if pk := Peak(Render(ml, Dtof(500*time.Millisecond, sr))); pk < 0.02 || pk > 1 {
+			t.Fatalf("have peak %v, want sound without clipping", pk)
+		}
+		ml.NoteOff(60)
+		ml.NoteOff(67)
+		Render(ml, Dtof(10*time.Second, sr))
+		if n := ml.Active(); n != 0 {
+			t.Fatalf("have %v voices active after ringing out, want 0", n)
+		}
+	}
+}
+
+func TestMalletPosition(t *testing.T) {
+	sr := DefaultSampleRate
+	// ratio of energy at the second partial to the fundamental.
+	partial := func(pos, damping float64) float64 {
+		ml := NewMallet(1, MaterialVibraphone, time.Second)
+		ml.SetPosition(pos)
+		ml.SetDamping(damping)
+		ml.NoteOn(45, 1) // 110Hz
+		out := Render(ml, Dtof(100*time.Millisecond, sr))
+		return goertzel(out, 440, sr) / goertzel(out, 110, sr)
+	}
+	if center, end := partial(0, 0), partial(1, 0); end < 2*center {
+		t.Fatalf("have partial %v at center and %v at end, want brighter at end", center, end)
+	}
+	if open, damped := partial(1, 0), partial(1, 0.8); damped >= open {
+		t.Fatalf("have partial %v open and %v damped, want duller damped", open, damped)
+	}
+}
@@ -0,0 +1,109 @@
+package snd
+
+import "math/rand"
+
+// ProcFunc returns a Sound that processes in.
+type ProcFunc func(in Sound) Sound
+
+// Insert slots of a Master in the order they are applied.
+const (
+	InsertEQ = iota
+	InsertComp
+	InsertLimit
+	InsertDither
+	NumInserts
+)
+
+// Master is the final mix ahead of an output backend with ordered insert slots.
+//
+// Setting an insert rebuilds that slot and all following slots from their ProcFunc,
+// so any state held by those sounds is reset. Backends must be notified after
+// changing inserts while running so new inputs are discovered, e.g. al.Notify.
+type Master struct {
+	*mono
+	chans int
+	procs [NumInserts]ProcFunc
+	ins   [NumInserts]Sound
+	last  Sound
+}
+
+// NewMaster returns a Master with no inserts set, passing in through unaltered.
+func NewMaster(in Sound) *Master {
+	sd := newmono(in)
+	sd.sr = in.SampleRate()
+	sd.out = make(Discrete, len(in.Samples()))
+	m := &Master{mono: sd, chans: in.Channels()}
+	m.build(0)
+	return m
+}
+
+func (m *Master) Channels() int   { return m.chans }
+func (m *Master) Inputs() []Sound { return []Sound{m.last} }
+
+// SetInsert sets fn as the insert for slot; a nil fn clears the slot.
+func (m *Master) SetInsert(slot int, fn ProcFunc) {
+	m.procs[slot] = fn
+	m.build(slot)
+}
+
+// Insert returns the sound built for slot or nil if the slot is empty.
+func (m *Master) Insert(slot int) Sound { return m.ins[slot] }
+
+func (m *Master) build(slot int) {
+	prev := m.in
+	for i := slot - 1; i >= 0; i-- {
+		if m.ins[i] != nil {
+			prev = m.ins[i]
+			break
+		}
+	}
+	for i := slot; i < NumInserts; i++ {
+		m.ins[i] = nil
+		if m.procs[i] != nil {
+			m.ins[i] = m.procs[i](prev)
+			prev = m.ins[i]
+		}
+	}
+	m.last = prev
+}
+
+func (m *Master) Prepare(uint64) {
+	if m.off {
+		for i := range m.out {
+			m.out[i] = 0
+		}
+		return
+	}
+	copy(m.out, m.last.Samples())
+}
+
+// Dither adds triangular probability density noise scaled to the least significant
+// bit of the given bit depth. Intended as the last insert of a Master.
+type Dither struct {
+	*mono
+	lsb float64
+	rnd *rand.Rand
+}
+
+// NewDither returns Dither for bit depth, e.g. DefaultSampleBitDepth.
+func NewDither(depth int, in Sound) *Dither {
+	sd := newmono(in)
+	sd.out = make(Discrete, len(in.Samples()))
+	return &Dither{
+		mono: sd,
+		lsb:  1 / float64(int(1)<<uint(depth-1)),
+		rnd:  rand.New(rand.NewSource(1)),
+	}
+}
+
+func (dth *Dither) Channels() int { return dth.in.Channels() }
+
+func (dth *Dither) Prepare(uint64) {
+	for i, x := range dth.in.Samples() {
+		if dth.off {
+			dth.out[i] = 0
+		} else {
+			dth.out[i] = x + dth.lsb*(dth.rnd.Float64()-dth.rnd.Float64())
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package snd
+
+import "testing"
+
+func TestMasterInserts(t *testing.T) {
+	m := NewMaster(newunit())
+	m.SetInsert(InsertLimit, func(in Sound) Sound { return NewGain(0.5, in) })
+	m.SetInsert(InsertEQ, func(in Sound) Sound { return NewGain(2, in) })
+
+	inps := GetInputs(m)
+	new(Dispatcher).Dispatch(1, inps...)
+	if x, want := m.Samples()[0], DefaultAmpFac; !equals(x, want) {
+		t.Fatalf("have %v, want %v", x, want)
+	}
+	if m.Insert(InsertComp) != nil {
+		t.Fatal("empty slot returned sound")
+	}
+
+	m.SetInsert(InsertEQ, nil)
+	inps = GetInputs(m)
+	new(Dispatcher).Dispatch(2, inps...)
+	if x, want := m.Samples()[0], 0.5*DefaultAmpFac; !equals(x, want) {
+		t.Fatalf("have %v, want %v", x, want)
+	}
+}
+
+func TestMasterChannels(t *testing.T) {
+	m := NewMaster(NewPan(0, newunit()))
+	m.SetInsert(InsertDither, func(in Sound) Sound { return NewDither(DefaultSampleBitDepth, in) })
+	if m.Channels() != 2 || len(m.Samples()) != 2*DefaultBufferLen {
+		t.Fatalf("have channels %v len %v, want stereo", m.Channels(), len(m.Samples()))
+	}
+}
+
+func BenchmarkMaster(b *testing.B) {
+	m := NewMaster(newunit())
+	m.SetInsert(InsertDither, func(in Sound) Sound { return NewDither(DefaultSampleBitDepth, in) })
+	inps := GetInputs(m)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, inp := range inps {
+			inp.sd.Prepare(uint64(n))
+		}
+	}
+}
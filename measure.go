@@ -0,0 +1,108 @@
+package snd
+
+import "math"
+
+// fitsine returns sig less its best fit sine of hz at sample rate sr and any
+// offset, by least squares.
+func fitsine(sig Discrete, hz, sr float64) Discrete {
+	// solve normal equations for x = a*cos + b*sin + c.
+	var m [3][4]float64
+	w := 2 * math.Pi * hz / sr
+	for i, x := range sig {
+		s, c := math.Sincos(w * float64(i))
+		v := [3]float64{c, s, 1}
+		for r := 0; r < 3; r++ {
+			for k := 0; k < 3; k++ {
+				m[r][k] += v[r] * v[k]
+			}
+			m[r][3] += v[r] * x
+		}
+	}
+	for p := 0; p < 3; p++ {
+		for r := p + 1; r < 3; r++ {
+			f := m[r][p] / m[p][p]
+			for k := p; k < 4; k++ {
+				m[r][k] -= f * m[p][k]
+			}
+		}
+	}
+	var coef [3]float64
+	for r := 2; r >= 0; r-- {
+		x := m[r][3]
+		for k := r + 1; k < 3; k++ {
+			x -= m[r][k] * coef[k]
+		}
+		coef[r] = x / m[r][r]
+	}
+	res := make(Discrete, len(sig))
+	for i, x := range sig {
+		s, c := math.Sincos(w * float64(i))
+		res[i] = x - coef[0]*c - coef[1]*s - coef[2]
+	}
+	return res
+}
+
+// power returns the mean square of sig.
+func power(sig Discrete) float64 {
+	var p float64
+	for _, x := range sig {
+		p += x * x
+	}
+	return p / float64(len(sig))
+}
+
+// THDN returns total harmonic distortion plus noise of sig, a tone of hz at
+// sample rate sr, as the level of all but the tone relative to all of sig,
+// such as -40dB for a tone with 1% of distortion and noise. DC is excluded.
+// Frequency must be exact, so sig is best generated rather than recorded.
+func THDN(sig Discrete, hz, sr float64) Decibel {
+	res := fitsine(sig, hz, sr)
+	var mean float64
+	for _, x := range sig {
+		mean += x
+	}
+	mean /= float64(len(sig))
+	var total float64
+	for _, x := range sig {
+		total += (x - mean) * (x - mean)
+	}
+	return Decibel(10 * math.Log10(power(res)*float64(len(sig))/total))
+}
+
+// SNR returns the ratio of sig to its difference from ref, a clean signal of
+// the same length, such as output of a resampler to the ideal, as the level
+// of ref relative to the difference. Identical signals return infinity.
+func SNR(sig, ref Discrete) Decibel {
+	var noise, p float64
+	for i, x := range ref {
+		d := sig[i] - x
+		noise += d * d
+		p += x * x
+	}
+	return Decibel(10 * math.Log10(p/noise))
+}
+
+// AliasLevel returns the level of spectrum of sig at sample rate sr above
+// cutoff relative to all of it, such as of a band-limited oscillator whose
+// harmonics stop below cutoff, measured with a Hann window over the largest
+// power of two frames of sig.
+func AliasLevel(sig Discrete, sr, cutoff float64) Decibel {
+	n := 1
+	for n*2 <= len(sig) {
+		n *= 2
+	}
+	re, im := make([]float64, n), make([]float64, n)
+	for i := range re {
+		re[i] = sig[i] * (0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n)))
+	}
+	fft(re, im, false)
+	var above, total float64
+	for i := 1; i <= n/2; i++ {
+		p := re[i]*re[i] + im[i]*im[i]
+		total += p
+		if float64(i)*sr/float64(n) > cutoff {
+			above += p
+		}
+	}
+	return Decibel(10 * math.Log10(above/total))
+}
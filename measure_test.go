@@ -0,0 +1,57 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTHDN(t *testing.T) {
+	sr := DefaultSampleRate
+	n := 8192
+	sig := make(Discrete, n)
+	for i := range sig {
+		sig[i] = math.Sin(2*math.Pi*1000*float64(i)/sr + 0.3)
+	}
+	if db := THDN(sig, 1000, sr); db > -100 {
+		t.Fatalf("have THD+N %v of a pure tone, want none", db)
+	}
+	// a third harmonic at 1% of the tone is -40dB.
+	for i := range sig {
+		sig[i] += 0.01 * math.Sin(2*math.Pi*3000*float64(i)/sr)
+	}
+	if db := THDN(sig, 1000, sr); !equaleps(float64(db), -40, 0.1) {
+		t.Fatalf("have THD+N %v, want -40dB", db)
+	}
+}
+
+func TestSNR(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ref, sig := make(Discrete, 8192), make(Discrete, 8192)
+	for i := range ref {
+		ref[i] = math.Sin(float64(i) / 10)
+		sig[i] = ref[i] + 0.001*(2*rnd.Float64()-1)
+	}
+	// uniform noise of 0.001 has rms of 0.001/sqrt(3), a sine 1/sqrt(2).
+	want := 20 * math.Log10((1/math.Sqrt2)/(0.001/math.Sqrt(3)))
+	if db := SNR(sig, ref); !equaleps(float64(db), want, 0.5) {
+		t.Fatalf("have SNR %v, want %v", db, want)
+	}
+}
+
+func TestAliasLevel(t *testing.T) {
+	sr := DefaultSampleRate
+	sig := make(Discrete, 8192)
+	for i := range sig {
+		sig[i] = math.Sin(2*math.Pi*1000*float64(i)/sr) + 0.01*math.Sin(2*math.Pi*15000*float64(i)/sr)
+	}
+	if db := AliasLevel(sig, sr, 10000); !equaleps(float64(db), -40, 0.5) {
+		t.Fatalf("have alias level %v, want -40dB", db)
+	}
+	// harmonics of a sawtooth are well above those of a sine.
+	sine := AliasLevel(Render(NewOscil(Sine(), 3000, nil), 8192), sr, 10000)
+	saw := AliasLevel(Render(NewOscil(Sawtooth(), 3000, nil), 8192), sr, 10000)
+	if saw < sine+20 {
+		t.Fatalf("have level %v of sawtooth and %v of sine above cutoff, want sawtooth louder", saw, sine)
+	}
+}
@@ -0,0 +1,187 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// thump is a burst of noise of the action of a piano, lowpassed noise over a
+// low thud decaying together.
+type thump struct {
+	amp   float64
+	fall  float64 // of amp per frame
+	inc   float64 // of phase of the thud per frame
+	phase float64
+	noise float64 // of noise against the thud, belonging to [0..1]
+	lpc   float64 // coefficient of lowpass of noise
+	lp    float64
+}
+
+// Mechanics adds noise of the action of a piano to in, the output of an
+// instrument: the thump of each key returning and its damper falling as it
+// is released, the swish of all dampers lifting as the sustain pedal is
+// pressed, and their thump falling as it is let up. Levels are zero, silent,
+// until set, so layers are optional.
+//
+// Mechanics is a Noter passing notes on to the instrument, if any, and
+// passes the sustain pedal on as Sympathy does, so the two may be chained.
+type Mechanics struct {
+	*mono
+	nt      Noter
+	level   float64 // of sustain
+	keys    float64 // level of key releases
+	dampers float64 // level of the pedal lifting and dropping dampers
+	thumps  []thump
+	rnd     *rand.Rand
+}
+
+// NewMechanics returns Mechanics of in played by notes passed on to nt, which
+// may be nil.
+func NewMechanics(in Sound, nt Noter) *Mechanics {
+	mc := &Mechanics{mono: newmono(in), nt: nt, rnd: rand.New(rand.NewSource(1))}
+	mc.sr = in.SampleRate()
+	mc.out = make(Discrete, len(in.Samples()))
+	return mc
+}
+
+func (mc *Mechanics) Channels() int { return mc.in.Channels() }
+
+// KeyLevel returns the level of thumps of keys released belonging to [0..1].
+func (mc *Mechanics) KeyLevel() float64     { return mc.keys }
+func (mc *Mechanics) SetKeyLevel(x float64) { mc.keys = math.Max(0, math.Min(1, x)) }
+
+// DamperLevel returns the level of dampers lifted and dropped by the sustain
+// pedal belonging to [0..1].
+func (mc *Mechanics) DamperLevel() float64     { return mc.dampers }
+func (mc *Mechanics) SetDamperLevel(x float64) { mc.dampers = math.Max(0, math.Min(1, x)) }
+
+// Seed seeds random noise, so a performance renders the same every time.
+func (mc *Mechanics) Seed(seed int64) { mc.rnd.Seed(seed) }
+
+func (mc *Mechanics) Params() []*Param {
+	return []*Param{
+		NewParam("keys", mc.KeyLevel, mc.SetKeyLevel).Range(0, 1, 0).In(UnitPercent),
+		NewParam("dampers", mc.DamperLevel, mc.SetDamperLevel).Range(0, 1, 0).In(UnitPercent),
+	}
+}
+
+func (mc *Mechanics) NoteOn(key int, vel float64) {
+	if mc.nt != nil {
+		mc.nt.NoteOn(key, vel)
+	}
+}
+
+// NoteOff thumps as the key returns, its damper falling too unless the
+// sustain pedal holds it up.
+func (mc *Mechanics) NoteOff(key int) {
+	amp := 0.5
+	if mc.level < halfPedalHi {
+		amp = 1
+	}
+	// lower keys have heavier dampers, thudding lower.
+	hz := 80 + 40*float64(key-21)/88
+	mc.thump(mc.keys*amp, 40*time.Millisecond, hz, 0.4, 1500)
+	if mc.nt != nil {
+		mc.nt.NoteOff(key)
+	}
+}
+
+// Sustain reports whether the sustain pedal is down far enough to raise
+// dampers clear of all strings.
+func (mc *Mechanics) Sustain() bool { return mc.level >= halfPedalHi }
+
+// SetSustain sets the sustain pedal down or up.
+func (mc *Mechanics) SetSustain(down bool) {
+	if down {
+		mc.SetSustainLevel(1)
+	} else {
+		mc.SetSustainLevel(0)
+	}
+}
+
+// SustainLevel returns the position of the sustain pedal belonging to [0..1].
+func (mc *Mechanics) SustainLevel() float64 { return mc.level }
+
+// SetSustainLevel sets the sustain pedal to position x belonging to [0..1],
+// from up to down, swishing as dampers lift off the strings and thumping as
+// they drop back, and passes it on to the instrument as Sympathy does.
+func (mc *Mechanics) SetSustainLevel(x float64) {
+	x = math.Max(0, math.Min(1, x))
+	switch lifted := x > halfPedalLo; {
+	case lifted && mc.level <= halfPedalLo:
+		mc.thump(mc.dampers, 200*time.Millisecond, 50, 0.9, 4000)
+	case !lifted && mc.level > halfPedalLo:
+		mc.thump(mc.dampers, 80*time.Millisecond, 70, 0.5, 1500)
+	}
+	mc.level = x
+	switch pd := mc.nt.(type) {
+	case interface{ SetSustainLevel(float64) }:
+		pd.SetSustainLevel(x)
+	case interface{ SetSustain(bool) }:
+		pd.SetSustain(mc.Sustain())
+	}
+}
+
+// Control handles a MIDI control change of the sustain pedal as
+// SetSustainLevel, from 0 up to 127 down, passing others on to the
+// instrument if it handles control changes, and reports whether cc was
+// handled.
+func (mc *Mechanics) Control(cc, val int) bool {
+	if cc == CtrlSustain {
+		mc.SetSustainLevel(float64(val) / 127)
+		return true
+	}
+	if ct, ok := mc.nt.(interface{ Control(cc, val int) bool }); ok {
+		return ct.Control(cc, val)
+	}
+	return false
+}
+
+// thump starts a thump at amp decaying over t60 of noise, lowpassed at fc,
+// mixed by noise over a thud at hz, varied at random as no two are alike.
+func (mc *Mechanics) thump(amp float64, t60 time.Duration, hz, noise, fc float64) {
+	if amp == 0 {
+		return
+	}
+	vary := 1 + 0.2*(2*mc.rnd.Float64()-1)
+	mc.thumps = append(mc.thumps, thump{
+		amp:   0.1 * amp * vary,
+		fall:  math.Pow(0.001, 1/(t60.Seconds()*vary*mc.sr)),
+		inc:   hz * vary / mc.sr,
+		noise: noise,
+		lpc:   1 - math.Exp(-twopi*fc/mc.sr),
+	})
+}
+
+func (mc *Mechanics) Prepare(uint64) {
+	in := mc.in.Samples()
+	chans := mc.in.Channels()
+	for i := 0; i < len(mc.out); i += chans {
+		var y float64
+		for k := range mc.thumps {
+			th := &mc.thumps[k]
+			th.lp += th.lpc * (2*mc.rnd.Float64() - 1 - th.lp)
+			y += th.amp * (th.noise*th.lp + (1-th.noise)*math.Sin(twopi*th.phase))
+			th.phase += th.inc
+			th.phase -= math.Floor(th.phase)
+			th.amp *= th.fall
+		}
+		for c := 0; c < chans; c++ {
+			if mc.off {
+				mc.out[i+c] = 0
+			} else {
+				mc.out[i+c] = in[i+c] + y
+			}
+		}
+	}
+	// thumps stop once quiet.
+	n := 0
+	for _, th := range mc.thumps {
+		if th.amp > 1e-6 {
+			mc.thumps[n] = th
+			n++
+		}
+	}
+	mc.thumps = mc.thumps[:n]
+}
@@ -0,0 +1,54 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMechanics(t *testing.T) {
+	// noise returns peak of output of mc less that of its input.
+	noise := func(mc *Mechanics, d time.Duration) float64 {
+		var pk float64
+		for n := Dtof(d, DefaultSampleRate); n > 0; n -= len(mc.out) {
+			mc.in.Prepare(1)
+			mc.Prepare(1)
+			for i, x := range mc.Samples() {
+				pk = math.Max(pk, math.Abs(x-mc.in.Samples()[i]))
+			}
+		}
+		return pk
+	}
+	var nl noteLog
+	mc := NewMechanics(NewOscil(Sine(), 440, nil), &nl)
+	mc.NoteOn(60, 1)
+	mc.NoteOff(60)
+	mc.SetSustain(true)
+	if pk := noise(mc, 100*time.Millisecond); pk != 0 {
+		t.Fatalf("have noise %v at zero levels, want 0", pk)
+	}
+	if s, want := nl.String(), "+60 -60 "; s != want {
+		t.Fatalf("have %q, want %q", s, want)
+	}
+
+	mc.SetKeyLevel(1)
+	mc.NoteOn(60, 1)
+	mc.NoteOff(60)
+	if pk := noise(mc, 100*time.Millisecond); pk == 0 {
+		t.Fatal("have no thump of key released")
+	}
+	if pk := noise(mc, 500*time.Millisecond); pk > 1e-3 {
+		t.Fatalf("have noise %v after thump, want quiet", pk)
+	}
+
+	mc.SetDamperLevel(1)
+	mc.Control(CtrlSustain, 0)
+	if pk := noise(mc, 100*time.Millisecond); pk == 0 {
+		t.Fatal("have no thump of dampers dropped")
+	}
+	noise(mc, 500*time.Millisecond)
+	mc.Control(CtrlSustain, 20) // dampers still resting
+	if pk := noise(mc, 100*time.Millisecond); pk > 1e-3 {
+		t.Fatalf("have noise %v before dampers lift, want quiet", pk)
+	}
+}
@@ -0,0 +1,90 @@
+package snd
+
+import "math"
+
+// Peak returns the max absolute value of sig.
+func Peak(sig Discrete) float64 {
+	var max float64
+	for _, x := range sig {
+		if a := math.Abs(x); max < a {
+			max = a
+		}
+	}
+	return max
+}
+
+// Loudness returns integrated loudness in LUFS of interleaved sig with chans
+// channels at sample rate sr, as specified by ITU-R BS.1770. All channels are
+// weighted equally as for left and right channels. Returns -Inf if sig is
+// silent or shorter than a single 400ms gating block.
+func Loudness(sig Discrete, chans int, sr float64) float64 {
+	n := len(sig) / chans
+
+	// k-weighted squares summed across channels
+	sq := make([]float64, n)
+	for c := 0; c < chans; c++ {
+		shelf, hp := kweight(sr)
+		for i := 0; i < n; i++ {
+			y := hp.filter(shelf.filter(sig[i*chans+c]))
+			sq[i] += y * y
+		}
+	}
+
+	size, hop := int(0.4*sr), int(0.1*sr)
+	var zs []float64
+	for i := 0; i+size <= n; i += hop {
+		var z float64
+		for _, x := range sq[i : i+size] {
+			z += x
+		}
+		z /= float64(size)
+		if lufs(z) > -70 {
+			zs = append(zs, z)
+		}
+	}
+	if len(zs) == 0 {
+		return math.Inf(-1)
+	}
+
+	rel := lufs(mean(zs)) - 10
+	var gated []float64
+	for _, z := range zs {
+		if lufs(z) > rel {
+			gated = append(gated, z)
+		}
+	}
+	return lufs(mean(gated))
+}
+
+// kweight returns the pre-filter and high-pass stages of the k-weighting
+// filter for sample rate sr. Coefficients are derived as in libebur128 so
+// that response is correct for any sample rate.
+func kweight(sr float64) (shelf, hp biquad) {
+	const (
+		f0 = 1681.974450955533
+		g  = 3.999843853973347
+		q  = 0.7071752369554196
+	)
+	k := math.Tan(math.Pi * f0 / sr)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	shelf.set(vh+vb*k/q+k*k, 2*(k*k-vh), vh-vb*k/q+k*k, 1+k/q+k*k, 2*(k*k-1), 1-k/q+k*k)
+
+	const (
+		f1 = 38.13547087602444
+		q1 = 0.5003270373238773
+	)
+	k = math.Tan(math.Pi * f1 / sr)
+	hp.set(1, -2, 1, 1+k/q1+k*k, 2*(k*k-1), 1-k/q1+k*k)
+	return
+}
+
+func lufs(z float64) float64 { return -0.691 + 10*math.Log10(z) }
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
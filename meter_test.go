@@ -0,0 +1,26 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoudness(t *testing.T) {
+	// full scale 1kHz sine in a single channel reads -3.01 LUFS.
+	for _, sr := range []float64{44100, 48000} {
+		sig := make(Discrete, int(2*sr))
+		sig.Sample(SineFunc, 1000/sr, 0)
+		if x := Loudness(sig, 1, sr); !equaleps(x, -3.01, 0.05) {
+			t.Errorf("%vHz sample rate have %.3f LUFS, want -3.01", sr, x)
+		}
+	}
+	if x := Loudness(make(Discrete, 44100), 1, 44100); !math.IsInf(x, -1) {
+		t.Errorf("silence have %v, want -Inf", x)
+	}
+}
+
+func TestPeak(t *testing.T) {
+	if x := Peak(Discrete{0.1, -0.7, 0.5}); x != 0.7 {
+		t.Fatalf("have %v, want 0.7", x)
+	}
+}
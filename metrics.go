@@ -0,0 +1,173 @@
+package snd
+
+import (
+	"expvar"
+	"fmt"
+	"math"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are measures of a graph as it plays.
+type Stats struct {
+	Buffers   uint64        // buffers prepared
+	Underruns uint64        // of the output, if known
+	Tick      time.Duration // to prepare the last buffer
+	TickMax   time.Duration // to prepare the slowest buffer
+	Voices    int           // playing, if known
+	Peak      Decibel       // of output over the last second or so
+	GCs       int64         // garbage collections while measured
+	GCPause   time.Duration // total pause of those collections
+}
+
+// Metrics measures a graph as it plays through a Dispatcher, such as to
+// monitor long running installations: time preparing each buffer, peak level
+// of output, voices playing, underruns of the output, and pauses of garbage
+// collection. Metrics may be published with expvar by Publish, or served in
+// the text format of Prometheus as an http.Handler.
+type Metrics struct {
+	// atomic, first for alignment; written on the audio thread.
+	buffers, under, tick, tickmax uint64
+	nvoices                       int64
+	peak                          uint64 // bits of amplitude
+	reset                         uint32 // Panic called since last buffer
+
+	out    Sound
+	remove []func()
+
+	// read on the audio thread.
+	mu        sync.Mutex
+	underruns func() uint64
+	voices    func() int
+
+	start      time.Time // of the buffer being prepared
+	held, cur  float64   // peak of the last and the current window
+	window, at int       // frames of a window and into it
+
+	gc debug.GCStats // when measuring started
+}
+
+// NewMetrics returns Metrics of out, the output of a graph prepared by dp,
+// measuring until Close.
+func NewMetrics(dp *Dispatcher, out Sound) *Metrics {
+	m := &Metrics{out: out, window: int(out.SampleRate())}
+	debug.ReadGCStats(&m.gc)
+	m.remove = []func(){
+		dp.BeforeDispatch(func(uint64, uint64) { m.start = time.Now() }),
+		dp.AfterDispatch(m.after),
+		dp.OnPanic(func(uint64, uint64) { m.Panic() }),
+	}
+	return m
+}
+
+// SetUnderruns sets fn counting underruns of the output, such as
+// Engine.Underruns of package al. It is called on the audio thread.
+func (m *Metrics) SetUnderruns(fn func() uint64) {
+	m.mu.Lock()
+	m.underruns = fn
+	m.mu.Unlock()
+}
+
+// SetVoices sets fn counting voices playing, such as Poly.Active. It is
+// called on the audio thread.
+func (m *Metrics) SetVoices(fn func() int) {
+	m.mu.Lock()
+	m.voices = fn
+	m.mu.Unlock()
+}
+
+// Close stops measuring.
+func (m *Metrics) Close() {
+	for _, fn := range m.remove {
+		fn()
+	}
+}
+
+// Panic zeroes the peak held, as the Dispatcher measured does when panicked.
+// It is safe to call from any goroutine.
+func (m *Metrics) Panic() {
+	atomic.StoreUint32(&m.reset, 1)
+	atomic.StoreUint64(&m.peak, 0)
+}
+
+func (m *Metrics) after(uint64, uint64) {
+	d := uint64(time.Since(m.start))
+	atomic.StoreUint64(&m.tick, d)
+	if d > atomic.LoadUint64(&m.tickmax) {
+		atomic.StoreUint64(&m.tickmax, d)
+	}
+	atomic.AddUint64(&m.buffers, 1)
+
+	if atomic.CompareAndSwapUint32(&m.reset, 1, 0) {
+		m.held, m.cur, m.at = 0, 0, 0
+	}
+	for _, x := range m.out.Samples() {
+		if a := math.Abs(x); a > m.cur {
+			m.cur = a
+		}
+	}
+	if m.at += len(m.out.Samples()) / m.out.Channels(); m.at >= m.window {
+		m.held, m.cur, m.at = m.cur, 0, 0
+	}
+	atomic.StoreUint64(&m.peak, math.Float64bits(math.Max(m.held, m.cur)))
+
+	m.mu.Lock()
+	if m.underruns != nil {
+		atomic.StoreUint64(&m.under, m.underruns())
+	}
+	if m.voices != nil {
+		atomic.StoreInt64(&m.nvoices, int64(m.voices()))
+	}
+	m.mu.Unlock()
+}
+
+// Stats returns measures so far. Stats is safe to call from other goroutines.
+func (m *Metrics) Stats() Stats {
+	var gc debug.GCStats
+	debug.ReadGCStats(&gc)
+	return Stats{
+		Buffers:   atomic.LoadUint64(&m.buffers),
+		Underruns: atomic.LoadUint64(&m.under),
+		Tick:      time.Duration(atomic.LoadUint64(&m.tick)),
+		TickMax:   time.Duration(atomic.LoadUint64(&m.tickmax)),
+		Voices:    int(atomic.LoadInt64(&m.nvoices)),
+		Peak:      DecibelOf(math.Float64frombits(atomic.LoadUint64(&m.peak))),
+		GCs:       gc.NumGC - m.gc.NumGC,
+		GCPause:   gc.PauseTotal - m.gc.PauseTotal,
+	}
+}
+
+// Publish publishes Stats with expvar under name, such as "snd", served by
+// the default http.ServeMux at /debug/vars. Like expvar.Publish, it panics if
+// name is already published.
+func (m *Metrics) Publish(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} { return m.Stats() }))
+}
+
+// ServeHTTP writes Stats in the text format of Prometheus.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	st := m.Stats()
+	peak := float64(st.Peak)
+	if math.IsInf(peak, -1) {
+		peak = -200 // silence, as exposition of -Inf isn't widely parsed
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	for _, x := range []struct {
+		name, typ, help string
+		v               interface{}
+	}{
+		{"snd_buffers_total", "counter", "Buffers prepared.", st.Buffers},
+		{"snd_underruns_total", "counter", "Underruns of the output.", st.Underruns},
+		{"snd_tick_seconds", "gauge", "Time to prepare the last buffer.", st.Tick.Seconds()},
+		{"snd_tick_max_seconds", "gauge", "Time to prepare the slowest buffer.", st.TickMax.Seconds()},
+		{"snd_voices", "gauge", "Voices playing.", st.Voices},
+		{"snd_peak_dbfs", "gauge", "Peak level of output over the last second.", peak},
+		{"snd_gc_total", "counter", "Garbage collections while measured.", st.GCs},
+		{"snd_gc_pause_seconds_total", "counter", "Pause of garbage collections while measured.", st.GCPause.Seconds()},
+	} {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", x.name, x.help, x.name, x.typ, x.name, x.v)
+	}
+}
@@ -0,0 +1,43 @@
+package snd
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	osc := NewOscil(Sine(), 440, nil)
+	out := NewGain(0.5, osc)
+	dp := new(Dispatcher)
+	m := NewMetrics(dp, out)
+	m.SetVoices(func() int { return 3 })
+	m.SetUnderruns(func() uint64 { return 2 })
+	dp.Render(out, 10*DefaultBufferLen)
+
+	st := m.Stats()
+	if st.Buffers != 10 {
+		t.Fatalf("have %v buffers, want 10", st.Buffers)
+	}
+	if st.Voices != 3 || st.Underruns != 2 {
+		t.Fatalf("have %v voices and %v underruns, want 3 and 2", st.Voices, st.Underruns)
+	}
+	if !equaleps(float64(st.Peak), float64(DecibelOf(0.5)), 0.01) {
+		t.Fatalf("have peak %v, want %v", st.Peak, DecibelOf(0.5))
+	}
+	if st.Tick <= 0 || st.TickMax < st.Tick {
+		t.Fatalf("have tick %v of max %v", st.Tick, st.TickMax)
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if body := rec.Body.String(); !strings.Contains(body, "\nsnd_buffers_total 10\n") || !strings.Contains(body, "\nsnd_voices 3\n") {
+		t.Fatalf("have exposition\n%s", body)
+	}
+
+	m.Close()
+	dp.Render(out, DefaultBufferLen)
+	if st := m.Stats(); st.Buffers != 10 {
+		t.Fatalf("have %v buffers after close, want 10", st.Buffers)
+	}
+}
@@ -0,0 +1,146 @@
+package midi
+
+import (
+	"math"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// PPQN is pulses per quarter note of MIDI clock.
+const PPQN = 24
+
+// Event is a message timestamped by frame of a snd.Transport.
+type Event struct {
+	Frame uint64
+	Msg   Message
+}
+
+// ClockOut sends MIDI clock and start, stop, and continue messages following
+// a transport. ClockOut outputs silence and must be part of a graph to be
+// prepared, e.g. appended to a mixer.
+//
+// Events are sent on C without blocking the audio thread and are dropped
+// if C is full; a consumer should write them to a device as received.
+type ClockOut struct {
+	C <-chan Event
+
+	c       chan Event
+	tp      *snd.Transport
+	out     snd.Discrete
+	last    float64
+	frame   uint64
+	playing bool
+}
+
+// NewClockOut returns ClockOut for tp buffering up to n events.
+func NewClockOut(tp *snd.Transport, n int) *ClockOut {
+	c := make(chan Event, n)
+	return &ClockOut{
+		C:   c,
+		c:   c,
+		tp:  tp,
+		out: make(snd.Discrete, len(tp.Samples())),
+	}
+}
+
+func (co *ClockOut) Channels() int            { return 1 }
+func (co *ClockOut) SampleRate() float64      { return co.tp.SampleRate() }
+func (co *ClockOut) Inputs() []snd.Sound      { return []snd.Sound{co.tp} }
+func (co *ClockOut) Samples() snd.Discrete    { return co.out }
+func (co *ClockOut) Interp(t float64) float64 { return 0 }
+func (co *ClockOut) At(t float64) float64     { return 0 }
+func (co *ClockOut) Index(i int) float64      { return 0 }
+
+func (co *ClockOut) send(frame uint64, status byte) {
+	select {
+	case co.c <- Event{frame, Message{Status: status}}:
+	default:
+	}
+}
+
+func (co *ClockOut) Prepare(uint64) {
+	beat, frame := co.tp.Beat(), co.tp.Frame()
+	playing := co.tp.Playing()
+
+	if playing && !co.playing {
+		if co.last == 0 {
+			co.send(co.frame, Start)
+		} else {
+			co.send(co.frame, Continue)
+		}
+	} else if !playing && co.playing {
+		co.send(frame, Stop)
+	}
+	co.playing = playing
+
+	// pulses between last and current position placed linearly over frames played.
+	if playing && beat > co.last {
+		p0 := math.Ceil(co.last * PPQN)
+		if co.last == 0 {
+			p0 = 0
+		}
+		for p := p0; p < beat*PPQN; p++ {
+			u := (p/PPQN - co.last) / (beat - co.last)
+			co.send(co.frame+uint64(u*float64(frame-co.frame)), Clock)
+		}
+	}
+	co.last, co.frame = beat, frame
+}
+
+// ClockIn follows received MIDI clock, driving a transport's tempo, position,
+// and play state. Tempo is smoothed against jitter of message arrival times.
+type ClockIn struct {
+	// Smooth is the weight of each new interval against the running average
+	// and belongs to (0..1]; lower values smooth more.
+	Smooth float64
+
+	tp     *snd.Transport
+	last   time.Time
+	avg    float64 // seconds between pulses
+	pulses int
+}
+
+func NewClockIn(tp *snd.Transport) *ClockIn {
+	return &ClockIn{Smooth: 0.1, tp: tp}
+}
+
+// Receive handles m received at t; messages other than clock, start,
+// stop, and continue are ignored.
+func (ci *ClockIn) Receive(m Message, t time.Time) {
+	switch m.Status {
+	case Start:
+		ci.pulses = 0
+		ci.last = time.Time{}
+		ci.tp.Seek(0)
+		ci.tp.Play()
+	case Continue:
+		ci.last = time.Time{}
+		ci.tp.Play()
+	case Stop:
+		ci.tp.Stop()
+	case Clock:
+		if !ci.last.IsZero() {
+			dt := t.Sub(ci.last).Seconds()
+			if ci.avg == 0 {
+				ci.avg = dt
+			} else {
+				ci.avg += ci.Smooth * (dt - ci.avg)
+			}
+			ci.tp.SetBPM(snd.BPM(60 / (ci.avg * PPQN)))
+		}
+		ci.last = t
+		ci.pulses++
+	}
+}
+
+// BPM returns tempo as measured from clock; zero until two pulses are received.
+func (ci *ClockIn) BPM() snd.BPM {
+	if ci.avg == 0 {
+		return 0
+	}
+	return snd.BPM(60 / (ci.avg * PPQN))
+}
+
+// Pulses returns pulses received since start.
+func (ci *ClockIn) Pulses() int { return ci.pulses }
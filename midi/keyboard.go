@@ -0,0 +1,116 @@
+package midi
+
+// Keyboard maps computer keyboard keys to note messages so applications are
+// playable without MIDI hardware. Keys are identified by codes supplied by the
+// caller, typically the rune of a key event.
+//
+// The default layout follows common practice of music software: the home row
+// from 'a' plays white keys starting at C with the row above playing sharps,
+// 'z' and 'x' shift octave down and up, and 'c' and 'v' lower and raise velocity.
+type Keyboard struct {
+	Channel int
+
+	notes        map[rune]int // key code to semitone above base octave
+	octdn, octup rune
+	veldn, velup rune
+	octave, vel  int
+	down         map[rune]int // key code to note sent
+}
+
+// NewKeyboard returns Keyboard with the default layout at octave 4 and velocity 100.
+func NewKeyboard() *Keyboard {
+	kb := &Keyboard{
+		notes:  make(map[rune]int),
+		octdn:  'z',
+		octup:  'x',
+		veldn:  'c',
+		velup:  'v',
+		octave: 4,
+		vel:    100,
+		down:   make(map[rune]int),
+	}
+	for i, r := range "awsedftgyhujkolp;'" {
+		kb.notes[r] = i
+	}
+	return kb
+}
+
+// Map sets code to play semitone above C of the current octave.
+func (kb *Keyboard) Map(code rune, semitone int) { kb.notes[code] = semitone }
+
+// Unmap removes any note mapped to code.
+func (kb *Keyboard) Unmap(code rune) { delete(kb.notes, code) }
+
+// MapControls sets codes that shift octave and change velocity.
+func (kb *Keyboard) MapControls(octdn, octup, veldn, velup rune) {
+	kb.octdn, kb.octup, kb.veldn, kb.velup = octdn, octup, veldn, velup
+}
+
+// Octave returns the octave of the first mapped C, where middle C is octave 4.
+func (kb *Keyboard) Octave() int { return kb.octave }
+
+// SetOctave sets octave clamped to [-1..9].
+func (kb *Keyboard) SetOctave(n int) {
+	if n < -1 {
+		n = -1
+	} else if n > 9 {
+		n = 9
+	}
+	kb.octave = n
+}
+
+func (kb *Keyboard) Velocity() int { return kb.vel }
+
+// SetVelocity sets velocity of notes clamped to [1..127].
+func (kb *Keyboard) SetVelocity(v int) {
+	if v < 1 {
+		v = 1
+	} else if v > 127 {
+		v = 127
+	}
+	kb.vel = v
+}
+
+// Press handles a key press returning a note on message if code plays a note.
+// Control keys change octave or velocity and repeated presses of a held key,
+// such as from key repeat, are ignored.
+func (kb *Keyboard) Press(code rune) (Message, bool) {
+	switch code {
+	case kb.octdn:
+		kb.SetOctave(kb.octave - 1)
+		return Message{}, false
+	case kb.octup:
+		kb.SetOctave(kb.octave + 1)
+		return Message{}, false
+	case kb.veldn:
+		kb.SetVelocity(kb.vel - 20)
+		return Message{}, false
+	case kb.velup:
+		kb.SetVelocity(kb.vel + 20)
+		return Message{}, false
+	}
+	semi, ok := kb.notes[code]
+	if !ok {
+		return Message{}, false
+	}
+	if _, ok := kb.down[code]; ok {
+		return Message{}, false
+	}
+	note := 12*(kb.octave+1) + semi
+	if note < 0 || note > 127 {
+		return Message{}, false
+	}
+	kb.down[code] = note
+	return NoteOnMsg(kb.Channel, note, kb.vel), true
+}
+
+// Release handles a key release returning a note off message for the note
+// played by code's press, regardless of octave changes while held.
+func (kb *Keyboard) Release(code rune) (Message, bool) {
+	note, ok := kb.down[code]
+	if !ok {
+		return Message{}, false
+	}
+	delete(kb.down, code)
+	return NoteOffMsg(kb.Channel, note, 0), true
+}
@@ -0,0 +1,255 @@
+package midi
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"dasa.cc/snd"
+)
+
+// Mapping binds a control change or key of a channel to a param.
+type Mapping struct {
+	Param string
+
+	// Status is ControlChange or NoteOn with the channel in the low nibble.
+	Status byte
+
+	// Number is the controller of a control change or the key of a note.
+	Number int
+
+	// Min and Max are values of the param at the bottom and top of the
+	// control, or at note off and note on of a key.
+	Min, Max float64
+
+	// Curve is the exponent shaping the control between Min and Max, where
+	// 1 is linear and larger values change slowly near Min, as suits params
+	// such as frequency.
+	Curve float64
+}
+
+// value returns the param value of m at x belonging to [0..1].
+func (mp Mapping) value(x float64) float64 {
+	c := mp.Curve
+	if c <= 0 {
+		c = 1
+	}
+	return mp.Min + math.Pow(x, c)*(mp.Max-mp.Min)
+}
+
+// Learn maps incoming MIDI messages to params of a snd.Params. While armed
+// for a param, the next control change or note on received is bound to it,
+// so hardware controllers map to a patch without editing code. Control
+// changes set params across their range, and keys set Max on note on and Min
+// on note off.
+//
+// Mappings are persisted with presets by Save and Load. All methods are safe
+// to call from any goroutine.
+type Learn struct {
+	mu    sync.Mutex
+	ps    *snd.Params
+	maps  []Mapping
+	armed *Mapping
+}
+
+// NewLearn returns Learn of params ps without mappings.
+func NewLearn(ps *snd.Params) *Learn { return &Learn{ps: ps} }
+
+// Arm binds the next control change or note on to param name ranging over
+// [min..max] shaped by curve, as of Mapping. Arm returns an error if ps has no
+// param name.
+func (ln *Learn) Arm(name string, min, max, curve float64) error {
+	if ln.ps.Lookup(name) == nil {
+		return fmt.Errorf("midi: learn unknown param %q", name)
+	}
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	ln.armed = &Mapping{Param: name, Min: min, Max: max, Curve: curve}
+	return nil
+}
+
+// Armed returns the param waiting to be bound, if any.
+func (ln *Learn) Armed() (name string, ok bool) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	if ln.armed == nil {
+		return "", false
+	}
+	return ln.armed.Param, true
+}
+
+// Disarm cancels waiting to bind a param.
+func (ln *Learn) Disarm() {
+	ln.mu.Lock()
+	ln.armed = nil
+	ln.mu.Unlock()
+}
+
+// Bind adds mp, replacing any mapping of the same param or control.
+func (ln *Learn) Bind(mp Mapping) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	ln.bind(mp)
+}
+
+func (ln *Learn) bind(mp Mapping) {
+	maps := ln.maps[:0]
+	for _, x := range ln.maps {
+		if x.Param != mp.Param && (x.Status != mp.Status || x.Number != mp.Number) {
+			maps = append(maps, x)
+		}
+	}
+	ln.maps = append(maps, mp)
+}
+
+// Unbind removes the mapping of param name.
+func (ln *Learn) Unbind(name string) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	for i, x := range ln.maps {
+		if x.Param == name {
+			ln.maps = append(ln.maps[:i], ln.maps[i+1:]...)
+			return
+		}
+	}
+}
+
+// Mappings returns a copy of all mappings in order bound.
+func (ln *Learn) Mappings() []Mapping {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	return append([]Mapping(nil), ln.maps...)
+}
+
+// Handle binds m to an armed param or sets params mapped to m, reporting
+// whether m was handled. Messages not handled may be passed on, such as to
+// Play.
+func (ln *Learn) Handle(m Message) bool {
+	typ := m.Type()
+	var x float64
+	switch {
+	case typ == ControlChange:
+		x = float64(m.Data2) / 127
+	case m.IsNoteOn():
+		x = 1
+	case m.IsNoteOff():
+		typ = NoteOn
+	default:
+		return false
+	}
+	return ln.handle(typ|byte(m.Channel()), int(m.Data1), x, typ == ControlChange || m.IsNoteOn())
+}
+
+// HandlePacket handles p as Handle, setting params by control changes of
+// MIDI 2.0 at their full resolution of 32 bits rather than 128 steps.
+// Mappings bind channels of all groups alike.
+func (ln *Learn) HandlePacket(p Packet) bool {
+	if p.Type() != UMPMIDI2 {
+		m, ok := p.Message()
+		return ok && ln.Handle(m)
+	}
+	status := NoteOn | byte(p.Channel())
+	switch p.Status() {
+	case ControlChange:
+		return ln.handle(ControlChange|byte(p.Channel()), p.Number(), p.Value(), true)
+	case NoteOn:
+		return ln.handle(status, p.Number(), 1, true)
+	case NoteOff:
+		return ln.handle(status, p.Number(), 0, false)
+	}
+	return false
+}
+
+// handle binds control num of status to an armed param if bind, or sets
+// params mapped to it at x belonging to [0..1].
+func (ln *Learn) handle(status byte, num int, x float64, bind bool) bool {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	if ln.armed != nil && bind {
+		mp := *ln.armed
+		mp.Status, mp.Number = status, num
+		ln.bind(mp)
+		ln.armed = nil
+		return true
+	}
+	handled := false
+	for _, mp := range ln.maps {
+		if mp.Status == status && mp.Number == num {
+			if p := ln.ps.Lookup(mp.Param); p != nil {
+				p.Set(mp.value(x))
+				handled = true
+			}
+		}
+	}
+	return handled
+}
+
+// learnprefix prefixes names of mapping fields in presets.
+const learnprefix = "midi:"
+
+// Save adds mappings to pre, such as one returned by snd.Params.Save, under
+// names prefixed by "midi:".
+func (ln *Learn) Save(pre snd.Preset) {
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	for _, mp := range ln.maps {
+		key := learnprefix + mp.Param + ":"
+		pre[key+"status"] = float64(mp.Status)
+		pre[key+"number"] = float64(mp.Number)
+		pre[key+"min"] = mp.Min
+		pre[key+"max"] = mp.Max
+		pre[key+"curve"] = mp.Curve
+	}
+}
+
+// Load replaces mappings with those saved in pre and returns pre without
+// them, to be loaded by snd.Params.Load.
+func (ln *Learn) Load(pre snd.Preset) snd.Preset {
+	rest := make(snd.Preset, len(pre))
+	byparam := make(map[string]*Mapping)
+	for name, x := range pre {
+		if !strings.HasPrefix(name, learnprefix) {
+			rest[name] = x
+			continue
+		}
+		s := strings.TrimPrefix(name, learnprefix)
+		i := strings.LastIndex(s, ":")
+		if i == -1 {
+			continue
+		}
+		param, field := s[:i], s[i+1:]
+		mp, ok := byparam[param]
+		if !ok {
+			mp = &Mapping{Param: param}
+			byparam[param] = mp
+		}
+		switch field {
+		case "status":
+			mp.Status = byte(x)
+		case "number":
+			mp.Number = int(x)
+		case "min":
+			mp.Min = x
+		case "max":
+			mp.Max = x
+		case "curve":
+			mp.Curve = x
+		}
+	}
+	// presets are maps, so mappings are restored in order of param name.
+	params := make([]string, 0, len(byparam))
+	for param := range byparam {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	ln.mu.Lock()
+	defer ln.mu.Unlock()
+	ln.maps = ln.maps[:0]
+	for _, param := range params {
+		ln.bind(*byparam[param])
+	}
+	return rest
+}
@@ -0,0 +1,209 @@
+// Package midi provides MIDI messages, and Universal MIDI Packets of MIDI 2.0,
+// and their use with package snd.
+package midi // import "dasa.cc/snd/midi"
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"dasa.cc/snd"
+)
+
+// Status bytes of channel messages with the channel in the low nibble.
+const (
+	NoteOff         = 0x80
+	NoteOn          = 0x90
+	PolyPressure    = 0xA0
+	ControlChange   = 0xB0
+	ProgramChange   = 0xC0
+	ChannelPressure = 0xD0
+	PitchBend       = 0xE0
+)
+
+// Status bytes of system messages.
+const (
+	SysEx       = 0xF0
+	SysExEnd    = 0xF7
+	Clock       = 0xF8
+	Start       = 0xFA
+	Continue    = 0xFB
+	Stop        = 0xFC
+	ActiveSense = 0xFE
+	Reset       = 0xFF
+)
+
+// Message is a MIDI message of a status byte and up to two data bytes.
+type Message struct {
+	Status       byte
+	Data1, Data2 byte
+}
+
+// Type returns status without channel for channel messages.
+func (m Message) Type() byte {
+	if m.Status < SysEx {
+		return m.Status & 0xF0
+	}
+	return m.Status
+}
+
+// Channel returns the zero based channel of a channel message.
+func (m Message) Channel() int { return int(m.Status & 0x0F) }
+
+// IsNoteOn reports whether m is a note on with non-zero velocity.
+func (m Message) IsNoteOn() bool { return m.Type() == NoteOn && m.Data2 != 0 }
+
+// IsNoteOff reports whether m is a note off or note on with zero velocity.
+func (m Message) IsNoteOff() bool {
+	return m.Type() == NoteOff || (m.Type() == NoteOn && m.Data2 == 0)
+}
+
+// Bend returns pitch bend of m belonging to [-1..1].
+func (m Message) Bend() float64 {
+	return float64(int(m.Data1)|int(m.Data2)<<7-8192) / 8192
+}
+
+// Bytes returns encoded m.
+func (m Message) Bytes() []byte {
+	switch n := datalen(m.Status); n {
+	case 0:
+		return []byte{m.Status}
+	case 1:
+		return []byte{m.Status, m.Data1}
+	default:
+		return []byte{m.Status, m.Data1, m.Data2}
+	}
+}
+
+func (m Message) String() string { return fmt.Sprintf("% X", m.Bytes()) }
+
+func NoteOnMsg(ch, key, vel int) Message {
+	return Message{byte(NoteOn | ch), byte(key), byte(vel)}
+}
+
+func NoteOffMsg(ch, key, vel int) Message {
+	return Message{byte(NoteOff | ch), byte(key), byte(vel)}
+}
+
+func ControlMsg(ch, cc, val int) Message {
+	return Message{byte(ControlChange | ch), byte(cc), byte(val)}
+}
+
+func ProgramMsg(ch, prog int) Message {
+	return Message{Status: byte(ProgramChange | ch), Data1: byte(prog)}
+}
+
+// datalen returns number of data bytes following status.
+func datalen(status byte) int {
+	switch {
+	case status < SysEx:
+		switch status & 0xF0 {
+		case ProgramChange, ChannelPressure:
+			return 1
+		default:
+			return 2
+		}
+	case status == 0xF1 || status == 0xF3: // time code quarter frame, song select
+		return 1
+	case status == 0xF2: // song position
+		return 2
+	default:
+		return 0
+	}
+}
+
+// Reader reads messages from a byte stream such as a raw MIDI device,
+// handling running status and real-time messages interleaved with others.
+// System exclusive data is skipped.
+type Reader struct {
+	r       *bufio.Reader
+	running byte
+
+	// partial message interrupted by real-time messages.
+	m    Message
+	data [2]byte
+	n    int
+}
+
+func NewReader(r io.Reader) *Reader { return &Reader{r: bufio.NewReader(r)} }
+
+// Read returns the next message.
+func (rd *Reader) Read() (Message, error) {
+	for {
+		b, err := rd.r.ReadByte()
+		if err != nil {
+			return Message{}, err
+		}
+		switch {
+		case b >= Clock: // real-time, may occur anywhere
+			return Message{Status: b}, nil
+		case b == SysEx:
+			if _, err := rd.r.ReadBytes(SysExEnd); err != nil {
+				return Message{}, err
+			}
+			rd.running, rd.m.Status, rd.n = 0, 0, 0
+			continue
+		case b&0x80 != 0:
+			rd.running, rd.m.Status, rd.n = b, b, 0
+			if b >= SysEx {
+				rd.running = 0 // system common cancels running status
+				if datalen(b) == 0 {
+					rd.m.Status = 0
+					return Message{Status: b}, nil
+				}
+			}
+			continue
+		}
+
+		if rd.m.Status == 0 {
+			if rd.running == 0 {
+				continue // stray data byte
+			}
+			rd.m.Status = rd.running
+		}
+		rd.data[rd.n] = b
+		rd.n++
+		if rd.n == datalen(rd.m.Status) {
+			m := Message{rd.m.Status, rd.data[0], rd.data[1]}
+			rd.m.Status, rd.data, rd.n = 0, [2]byte{}, 0
+			return m, nil
+		}
+	}
+}
+
+// BendRange is the range in semitones either way of pitch bend played by
+// Play, the General MIDI default.
+const BendRange = 2
+
+// Play plays note on and off messages of m on nt and passes control changes to
+// nt if it has a Control method, such as *snd.Pedals. Program changes are
+// passed to nt if it has a SetProgram method, such as *snd.BankChannel, and
+// channel pressure and pitch bend are played if nt is snd.Expressive. Play
+// reports whether m was handled.
+func Play(nt snd.Noter, m Message) bool {
+	ex, expressive := nt.(snd.Expressive)
+	switch {
+	case m.IsNoteOn():
+		nt.NoteOn(int(m.Data1), float64(m.Data2)/127)
+	case m.IsNoteOff():
+		nt.NoteOff(int(m.Data1))
+	case m.Type() == ControlChange:
+		if c, ok := nt.(interface{ Control(cc, val int) bool }); ok {
+			return c.Control(int(m.Data1), int(m.Data2))
+		}
+		return false
+	case m.Type() == ProgramChange:
+		p, ok := nt.(interface{ SetProgram(prog int) })
+		if ok {
+			p.SetProgram(int(m.Data1))
+		}
+		return ok
+	case m.Type() == ChannelPressure && expressive:
+		ex.Pressure(float64(m.Data1) / 127)
+	case m.Type() == PitchBend && expressive:
+		ex.Bend(BendRange * m.Bend())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,388 @@
+package midi
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"testing"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+func TestReader(t *testing.T) {
+	// note on, running status note on interleaved with clock, sysex, program change.
+	b := []byte{0x90, 60, 100, 62, 0xF8, 90, 0xF0, 1, 2, 0xF7, 0xC1, 5}
+	rd := NewReader(bytes.NewReader(b))
+	want := []Message{
+		NoteOnMsg(0, 60, 100),
+		{Status: Clock},
+		NoteOnMsg(0, 62, 90),
+		ProgramMsg(1, 5),
+	}
+	for i, w := range want {
+		m, err := rd.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m != w {
+			t.Fatalf("message %v: have %v, want %v", i, m, w)
+		}
+	}
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("have %v, want EOF", err)
+	}
+}
+
+func TestClockOut(t *testing.T) {
+	tp := snd.NewTransport(120)
+	co := NewClockOut(tp, 1024)
+	tp.Play()
+	snd.Render(co, snd.Dtof(2*time.Second, tp.SampleRate()))
+	tp.Stop()
+	snd.Render(co, 1)
+
+	var clocks int
+	var first, last Event
+	for len(co.C) > 0 {
+		ev := <-co.C
+		switch ev.Msg.Status {
+		case Start:
+			first = ev
+		case Clock:
+			clocks++
+		}
+		last = ev
+	}
+	if first.Msg.Status != Start || first.Frame != 0 {
+		t.Fatalf("have first %+v, want start at 0", first)
+	}
+	if last.Msg.Status != Stop {
+		t.Fatalf("have last %+v, want stop", last)
+	}
+	// 4 beats played plus a partial buffer.
+	if want := int(math.Ceil(tp.Beat() * PPQN)); clocks != want {
+		t.Fatalf("have %v clocks, want %v", clocks, want)
+	}
+}
+
+func TestClockIn(t *testing.T) {
+	tp := snd.NewTransport(90)
+	ci := NewClockIn(tp)
+	t0 := time.Now()
+	ci.Receive(Message{Status: Start}, t0)
+	if !tp.Playing() {
+		t.Fatal("transport not playing after start")
+	}
+
+	// 120 bpm is 20.8ms per pulse; alternate early and late by 2ms.
+	period := time.Minute / 120 / PPQN
+	for i := 0; i < 10*PPQN; i++ {
+		jit := 2 * time.Millisecond
+		if i%2 == 0 {
+			jit = -jit
+		}
+		ci.Receive(Message{Status: Clock}, t0.Add(time.Duration(i)*period+jit))
+	}
+	if bpm := tp.BPM(); math.Abs(float64(bpm)-120) > 2 {
+		t.Fatalf("have %v bpm, want 120", bpm)
+	}
+	ci.Receive(Message{Status: Stop}, time.Now())
+	if tp.Playing() {
+		t.Fatal("transport playing after stop")
+	}
+}
+
+type held map[int]float64
+
+func (h held) NoteOn(key int, vel float64) { h[key] = vel }
+func (h held) NoteOff(key int)             { delete(h, key) }
+
+func TestPlay(t *testing.T) {
+	h := make(held)
+	pd := snd.NewPedals(h)
+	msgs := []Message{
+		NoteOnMsg(0, 60, 127),
+		ControlMsg(0, snd.CtrlSustain, 127),
+		NoteOnMsg(0, 60, 0), // note off as zero velocity
+		NoteOffMsg(0, 62, 0),
+	}
+	for _, m := range msgs {
+		Play(pd, m)
+	}
+	if vel, ok := h[60]; !ok || vel != 1 {
+		t.Fatalf("have %v, want key 60 sustained at velocity 1", h)
+	}
+	Play(pd, ControlMsg(0, snd.CtrlSustain, 0))
+	if len(h) != 0 {
+		t.Fatalf("have %v after sustain up, want none", h)
+	}
+}
+
+type expressive struct {
+	held
+	press, bend float64
+}
+
+func (ex *expressive) Pressure(x float64)     { ex.press = x }
+func (ex *expressive) Bend(semitones float64) { ex.bend = semitones }
+
+func TestPlayExpressive(t *testing.T) {
+	ex := &expressive{held: make(held)}
+	Play(ex, Message{ChannelPressure, 127, 0})
+	Play(ex, Message{PitchBend, 0, 0x40})
+	if ex.press != 1 || ex.bend != 0 {
+		t.Fatalf("have pressure %v bend %v, want 1 and 0", ex.press, ex.bend)
+	}
+	Play(ex, Message{PitchBend, 0, 0})
+	if ex.bend != -BendRange {
+		t.Fatalf("have bend %v, want %v", ex.bend, -BendRange)
+	}
+	if Play(make(held), Message{PitchBend, 0, 0}) {
+		t.Fatal("have pitch bend played on Noter, want not handled")
+	}
+}
+
+func TestKeyboard(t *testing.T) {
+	kb := NewKeyboard()
+	m, ok := kb.Press('a')
+	if !ok || m != NoteOnMsg(0, 60, 100) {
+		t.Fatalf("have %v %v, want middle C", m, ok)
+	}
+	if _, ok := kb.Press('a'); ok {
+		t.Fatal("key repeat played note")
+	}
+
+	kb.Press('x') // octave up while held
+	kb.Press('v')
+	if m, _ := kb.Press('w'); m != NoteOnMsg(0, 73, 120) {
+		t.Fatalf("have %v, want C#5 at 120", m)
+	}
+	if m, _ := kb.Release('a'); m != NoteOffMsg(0, 60, 0) {
+		t.Fatalf("have %v, want note off of middle C", m)
+	}
+	if _, ok := kb.Release('a'); ok {
+		t.Fatal("released key not held")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	// at 120bpm and 96 ppq, half a second is 96 ticks.
+	sr := snd.DefaultSampleRate
+	events := []Event{
+		{0, NoteOnMsg(0, 60, 100)},
+		{uint64(sr / 2), NoteOffMsg(0, 60, 0)},
+		{uint64(sr), Message{Status: Clock}},
+	}
+	var buf bytes.Buffer
+	if err := WriteFile(&buf, events, sr, 120, 96); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0, 96,
+		'M', 'T', 'r', 'k', 0, 0, 0, 19,
+		0, 0xFF, 0x51, 3, 0x07, 0xA1, 0x20,
+		0, 0x90, 60, 100,
+		0x60, 0x80, 60, 0,
+		0, 0xFF, 0x2F, 0,
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Fatalf("have\n% X\nwant\n% X", buf.Bytes(), want)
+	}
+
+	var vlq bytes.Buffer
+	writevlq(&vlq, 0x0FFFFFFF)
+	if !bytes.Equal(vlq.Bytes(), []byte{0xFF, 0xFF, 0xFF, 0x7F}) {
+		t.Fatalf("have vlq % X", vlq.Bytes())
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	sr := snd.DefaultSampleRate
+	beat := uint64(sr / 2) // at 120bpm
+	events := []Event{
+		{0, NoteOnMsg(9, 36, 127)},
+		{beat / 2, NoteOnMsg(9, 42, 64)},
+		{beat, NoteOnMsg(9, 38, 127)},
+		{beat, NoteOffMsg(9, 36, 0)},
+		{3*beat + beat/2, NoteOnMsg(9, 36, 127)},
+	}
+	var buf bytes.Buffer
+	if err := WriteFile(&buf, events, sr, 120, 96); err != nil {
+		t.Fatal(err)
+	}
+	ppq, have, err := ReadFile(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ppq != 96 || len(have) != len(events) {
+		t.Fatalf("have ppq %v and %v events, want 96 and %v", ppq, len(have), len(events))
+	}
+	for i, ev := range have {
+		if want := events[i].Frame * 192 / uint64(sr); ev.Tick != want || ev.Msg != events[i].Msg {
+			t.Errorf("event %v: have %v at %v, want %v at %v", i, ev.Msg, ev.Tick, events[i].Msg, want)
+		}
+	}
+
+	g, err := ReadDrums(bytes.NewReader(buf.Bytes()), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Steps() != 16 || len(g.Rows) != 3 {
+		t.Fatalf("have %v steps and %v rows, want 16 and 3", g.Steps(), len(g.Rows))
+	}
+	if kick := g.Row(36, 16); kick.Vel[0] != 1 || kick.Vel[14] != 1 {
+		t.Fatalf("have kick %v", kick.Vel)
+	}
+	if hat := g.Row(42, 16); !(hat.Vel[2] > 0.5 && hat.Vel[2] < 0.51) {
+		t.Fatalf("have hat %v", hat.Vel)
+	}
+}
+
+func TestLearn(t *testing.T) {
+	osc := snd.NewOscil(snd.Sine(), 440, nil)
+	lp := snd.NewLowPass(1000, osc)
+	var ps snd.Params
+	ps.Register("osc", osc)
+	ps.Register("lp", lp)
+
+	ln := NewLearn(&ps)
+	if err := ln.Arm("nope", 0, 1, 1); err == nil {
+		t.Fatal("have no error arming unknown param")
+	}
+	if err := ln.Arm("lp.freq", 100, 1000, 2); err != nil {
+		t.Fatal(err)
+	}
+	if ln.Handle(ControlMsg(2, 74, 0)); lp.Freq() != 1000 {
+		t.Fatalf("have freq %v after learning, want unchanged 1000", lp.Freq())
+	}
+	if _, ok := ln.Armed(); ok {
+		t.Fatal("have armed after learning, want disarmed")
+	}
+	if ln.Handle(ControlMsg(2, 74, 127)); lp.Freq() != 1000 {
+		t.Fatalf("have freq %v at top, want 1000", lp.Freq())
+	}
+	if ln.Handle(ControlMsg(2, 74, 0)); lp.Freq() != 100 {
+		t.Fatalf("have freq %v at bottom, want 100", lp.Freq())
+	}
+	if ln.Handle(ControlMsg(1, 74, 127)) || lp.Freq() != 100 {
+		t.Fatalf("have freq %v from another channel, want unhandled", lp.Freq())
+	}
+
+	ln.Arm("osc.freq", 220, 880, 1)
+	ln.Handle(NoteOnMsg(0, 36, 100))
+	if ln.Handle(NoteOnMsg(0, 36, 100)); osc.Freq() != 880 {
+		t.Fatalf("have freq %v on note on, want 880", osc.Freq())
+	}
+	if ln.Handle(NoteOffMsg(0, 36, 0)); osc.Freq() != 220 {
+		t.Fatalf("have freq %v on note off, want 220", osc.Freq())
+	}
+
+	pre := ps.Save()
+	ln.Save(pre)
+	ln2 := NewLearn(&ps)
+	if err := ps.Load(ln2.Load(pre)); err != nil {
+		t.Fatal(err)
+	}
+	// mappings are restored in order of param name.
+	if have, want := ln2.Mappings(), ln.Mappings(); len(have) != 2 || have[0] != want[0] || have[1] != want[1] {
+		t.Fatalf("have mappings %+v loaded, want %+v", have, want)
+	}
+}
+
+type noteExpressive struct {
+	expressive
+	notePress map[int]float64
+	noteBend  map[int]float64
+}
+
+func (nx *noteExpressive) NotePressure(key int, x float64)     { nx.notePress[key] = x }
+func (nx *noteExpressive) NoteBend(key int, semitones float64) { nx.noteBend[key] = semitones }
+
+func TestPacket(t *testing.T) {
+	var b bytes.Buffer
+	pkts := []Packet{
+		NoteOnPacket(1, 2, 60, 0.5),
+		MessagePacket(0, ControlMsg(3, 7, 100)),
+		BendPacket(0, 0, -1),
+		MessagePacket(0, Message{Status: Clock}),
+		NoteControlPacket(0, 0, 60, 74, 1),
+	}
+	for _, p := range pkts {
+		b.Write(p.Bytes())
+	}
+	if b.Len() != 4*(2+1+2+1+2) {
+		t.Fatalf("have %v bytes, want 32", b.Len())
+	}
+	rd := NewPacketReader(&b)
+	for i, want := range pkts {
+		p, err := rd.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != want {
+			t.Fatalf("packet %v: have %v, want %v", i, p, want)
+		}
+	}
+	if _, err := rd.Read(); err != io.EOF {
+		t.Fatalf("have %v at end, want EOF", err)
+	}
+
+	p := pkts[0]
+	if p.Group() != 1 || p.Channel() != 2 || p.Number() != 60 || math.Abs(p.Velocity()-0.5) > 1e-4 {
+		t.Errorf("have group %v channel %v key %v velocity %v, want 1 2 60 0.5", p.Group(), p.Channel(), p.Number(), p.Velocity())
+	}
+	// values of MIDI 2.0 scale down to MIDI 1.0.
+	for _, x := range []struct {
+		p    Packet
+		want Message
+	}{
+		{p, NoteOnMsg(2, 60, 64)},
+		{NoteOnPacket(0, 0, 60, 0), NoteOnMsg(0, 60, 1)},
+		{ControlPacket(0, 1, 74, 1), ControlMsg(1, 74, 127)},
+		{BendPacket(0, 0, 0), Message{PitchBend, 0, 0x40}},
+		{ProgramPacket(0, 0, 5), ProgramMsg(0, 5)},
+		{pkts[1], ControlMsg(3, 7, 100)},
+	} {
+		if m, ok := x.p.Message(); !ok || m != x.want {
+			t.Errorf("have %v of %v, want %v", m, x.p, x.want)
+		}
+	}
+	if _, ok := pkts[4].Message(); ok {
+		t.Error("have message of per-note controller, want none")
+	}
+}
+
+func TestPlayPacket(t *testing.T) {
+	nx := &noteExpressive{expressive{held: make(held)}, make(map[int]float64), make(map[int]float64)}
+	PlayPacket(nx, NoteOnPacket(0, 0, 60, 0.25))
+	PlayPacket(nx, NotePressurePacket(0, 0, 60, 0.75))
+	PlayPacket(nx, NoteBendPacket(0, 0, 60, 0.5))
+	PlayPacket(nx, BendPacket(0, 0, 1))
+	if math.Abs(nx.held[60]-0.25) > 1e-4 || math.Abs(nx.notePress[60]-0.75) > 1e-6 || math.Abs(nx.noteBend[60]-NoteBendRange/2) > 1e-6 || nx.bend != BendRange {
+		t.Errorf("have velocity %v note pressure %v note bend %v bend %v", nx.held[60], nx.notePress[60], nx.noteBend[60], nx.bend)
+	}
+	PlayPacket(nx, MessagePacket(0, NoteOffMsg(0, 60, 0)))
+	if len(nx.held) != 0 {
+		t.Errorf("have %v held after note off of MIDI 1.0, want none", nx.held)
+	}
+	if PlayPacket(make(held), NoteBendPacket(0, 0, 60, 1)) {
+		t.Error("have per-note bend played on Noter, want not handled")
+	}
+}
+
+func TestLearnPacket(t *testing.T) {
+	lp := snd.NewLowPass(1000, snd.NewConst(0))
+	var ps snd.Params
+	ps.Register("lp", lp)
+	ln := NewLearn(&ps)
+	ln.Arm("lp.freq", 100, 1100, 1)
+	ln.HandlePacket(ControlPacket(0, 0, 74, 0))
+	// a step finer than of MIDI 1.0.
+	if ln.HandlePacket(ControlPacket(0, 0, 74, 0.001)); math.Abs(lp.Freq()-101) > 1e-3 {
+		t.Errorf("have freq %v, want 101", lp.Freq())
+	}
+	if ln.HandlePacket(MessagePacket(0, ControlMsg(0, 74, 127))); lp.Freq() != 1100 {
+		t.Errorf("have freq %v by MIDI 1.0, want 1100", lp.Freq())
+	}
+}
@@ -0,0 +1,214 @@
+package midi
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"sort"
+
+	"dasa.cc/snd"
+)
+
+// WriteFile writes events as a format 0 standard MIDI file. Events must be
+// ordered by frame and are converted from frames at sample rate sr to ticks
+// of ppq per beat at tempo bpm, the tempo also written to the file.
+func WriteFile(w io.Writer, events []Event, sr float64, bpm snd.BPM, ppq int) error {
+	var trk bytes.Buffer
+
+	// tempo in microseconds per quarter note
+	us := int(math.Round(60e6 / float64(bpm)))
+	trk.Write([]byte{0, 0xFF, 0x51, 3, byte(us >> 16), byte(us >> 8), byte(us)})
+
+	ticks := float64(ppq) * float64(bpm) / (60 * sr)
+	var last uint64
+	for _, ev := range events {
+		if ev.Msg.Status >= SysEx {
+			continue // real-time and system messages are not stored
+		}
+		tick := uint64(math.Round(float64(ev.Frame) * ticks))
+		if tick < last {
+			tick = last
+		}
+		writevlq(&trk, tick-last)
+		trk.Write(ev.Msg.Bytes())
+		last = tick
+	}
+	trk.Write([]byte{0, 0xFF, 0x2F, 0}) // end of track
+
+	var hdr bytes.Buffer
+	hdr.WriteString("MThd")
+	binary.Write(&hdr, binary.BigEndian, []uint32{6})
+	binary.Write(&hdr, binary.BigEndian, []uint16{0, 1, uint16(ppq)})
+	hdr.WriteString("MTrk")
+	binary.Write(&hdr, binary.BigEndian, uint32(trk.Len()))
+
+	if _, err := w.Write(hdr.Bytes()); err != nil {
+		return err
+	}
+	_, err := w.Write(trk.Bytes())
+	return err
+}
+
+// writevlq writes x as a variable length quantity.
+func writevlq(buf *bytes.Buffer, x uint64) {
+	var b [10]byte
+	i := len(b) - 1
+	b[i] = byte(x & 0x7F)
+	for x >>= 7; x > 0; x >>= 7 {
+		i--
+		b[i] = byte(x&0x7F) | 0x80
+	}
+	buf.Write(b[i:])
+}
+
+// TickEvent is a message at a time in ticks of a standard MIDI file.
+type TickEvent struct {
+	Tick uint64
+	Msg  Message
+}
+
+// ReadFile reads a standard MIDI file of format 0 or 1, returning its
+// division in ticks per beat and the channel messages of all tracks merged in
+// order of time. Meta and system exclusive events are skipped.
+func ReadFile(r io.Reader) (ppq int, events []TickEvent, err error) {
+	br := bufio.NewReader(r)
+	var hdr struct {
+		ID             [4]byte
+		Len            uint32
+		Format, Tracks uint16
+		Division       uint16
+	}
+	if err := binary.Read(br, binary.BigEndian, &hdr); err != nil {
+		return 0, nil, err
+	}
+	if string(hdr.ID[:]) != "MThd" || hdr.Len < 6 {
+		return 0, nil, errors.New("midi: not a standard midi file")
+	}
+	if hdr.Division&0x8000 != 0 {
+		return 0, nil, errors.New("midi: smpte time division not supported")
+	}
+	if _, err := br.Discard(int(hdr.Len - 6)); err != nil {
+		return 0, nil, err
+	}
+	for i := 0; i < int(hdr.Tracks); i++ {
+		var chunk struct {
+			ID  [4]byte
+			Len uint32
+		}
+		if err := binary.Read(br, binary.BigEndian, &chunk); err != nil {
+			return 0, nil, err
+		}
+		data := make([]byte, chunk.Len)
+		if _, err := io.ReadFull(br, data); err != nil {
+			return 0, nil, err
+		}
+		if string(chunk.ID[:]) != "MTrk" {
+			continue // unknown chunks are ignored
+		}
+		if events, err = readtrack(data, events); err != nil {
+			return 0, nil, fmt.Errorf("midi: track %v: %v", i, err)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Tick < events[j].Tick })
+	return int(hdr.Division), events, nil
+}
+
+// readtrack appends channel messages of track data to events.
+func readtrack(data []byte, events []TickEvent) ([]TickEvent, error) {
+	br := bytes.NewReader(data)
+	var tick uint64
+	var status byte
+	for br.Len() > 0 {
+		delta, err := readvlq(br)
+		if err != nil {
+			return nil, err
+		}
+		tick += delta
+		b, err := br.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case b == 0xFF: // meta
+			if _, err := br.ReadByte(); err != nil {
+				return nil, err
+			}
+			fallthrough
+		case b == SysEx || b == SysExEnd:
+			n, err := readvlq(br)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := io.CopyN(ioutil.Discard, br, int64(n)); err != nil {
+				return nil, err
+			}
+			continue
+		case b&0x80 != 0:
+			status = b
+		default:
+			// running status; b is the first data byte.
+			if status == 0 {
+				return nil, errors.New("data without status")
+			}
+			br.UnreadByte()
+		}
+		m := Message{Status: status}
+		var d [2]byte
+		if _, err := io.ReadFull(br, d[:datalen(status)]); err != nil {
+			return nil, err
+		}
+		m.Data1, m.Data2 = d[0], d[1]
+		events = append(events, TickEvent{tick, m})
+	}
+	return events, nil
+}
+
+// readvlq reads a variable length quantity.
+func readvlq(br io.ByteReader) (x uint64, err error) {
+	for i := 0; i < 4; i++ {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		x = x<<7 | uint64(b&0x7F)
+		if b&0x80 == 0 {
+			return x, nil
+		}
+	}
+	return 0, errors.New("variable length quantity too long")
+}
+
+// ReadDrums reads a standard MIDI file of drums as a DrumGrid quantized to div
+// steps per beat. Note on messages of all channels become hits, a row for
+// each key, and the grid is as long as the whole beats spanning all notes.
+func ReadDrums(r io.Reader, div float64) (*snd.DrumGrid, error) {
+	ppq, events, err := ReadFile(r)
+	if err != nil {
+		return nil, err
+	}
+	var last uint64
+	for _, ev := range events {
+		if ev.Msg.IsNoteOn() && ev.Tick > last {
+			last = ev.Tick
+		}
+	}
+	beats := math.Floor(float64(last)/float64(ppq)) + 1
+	steps := int(math.Round(beats * div))
+	g := &snd.DrumGrid{Div: div}
+	for _, ev := range events {
+		if !ev.Msg.IsNoteOn() {
+			continue
+		}
+		i := int(math.Round(float64(ev.Tick) / float64(ppq) * div))
+		if i >= steps {
+			i = steps - 1
+		}
+		g.Row(int(ev.Msg.Data1), steps).Vel[i] = float64(ev.Msg.Data2) / 127
+	}
+	return g, nil
+}
@@ -0,0 +1,301 @@
+package midi
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
+	"dasa.cc/snd"
+)
+
+// Message types of Universal MIDI Packets of MIDI 2.0, in the high nibble of
+// the first word.
+const (
+	UMPUtility = 0x0
+	UMPSystem  = 0x1
+	UMPMIDI1   = 0x2 // channel voice messages of MIDI 1.0
+	UMPData64  = 0x3 // system exclusive of 7 bit data
+	UMPMIDI2   = 0x4 // channel voice messages of MIDI 2.0
+	UMPData128 = 0x5
+)
+
+// Status bytes of channel voice messages of MIDI 2.0 beyond those of MIDI
+// 1.0, with the channel in the low nibble.
+const (
+	NoteController    = 0x00 // registered per-note controller
+	NoteAssignable    = 0x10 // assignable per-note controller
+	RegisteredControl = 0x20 // registered controller, as RPN
+	AssignableControl = 0x30 // assignable controller, as NRPN
+	NoteBend          = 0x60 // per-note pitch bend
+	NoteManagement    = 0xF0 // per-note management
+)
+
+// NoteBendRange is the range in semitones either way of per-note pitch bend
+// played by PlayPacket, the default of MIDI 2.0 and MPE.
+const NoteBendRange = 48
+
+// bendCenter is pitch bend of 32 bits at rest.
+const bendCenter = 1 << 31
+
+// Packet is a Universal MIDI Packet of MIDI 2.0, of one to four 32 bit words
+// by its type; words past its length are zero. Channel voice messages of
+// MIDI 2.0 carry velocity of 16 bits and controllers of 32 bits, and
+// pressure, pitch bend, and controllers of single notes.
+type Packet [4]uint32
+
+// Type returns the message type of p, such as UMPMIDI2.
+func (p Packet) Type() int { return int(p[0] >> 28) }
+
+// Group returns the group of p, one of sixteen of a stream, each of sixteen
+// channels.
+func (p Packet) Group() int { return int(p[0] >> 24 & 0xF) }
+
+// Len returns the number of words of p by its type.
+func (p Packet) Len() int { return umplen(p.Type()) }
+
+// umplen returns the number of words of packets of message type t.
+func umplen(t int) int {
+	switch {
+	case t <= UMPMIDI1, t == 0x6, t == 0x7:
+		return 1
+	case t <= 0xA:
+		return 2
+	case t <= 0xC:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// Status returns the status byte of a channel voice message without the
+// channel, such as NoteOn or NoteBend.
+func (p Packet) Status() byte { return byte(p[0]>>16) & 0xF0 }
+
+// Channel returns the zero based channel of a channel voice message.
+func (p Packet) Channel() int { return int(p[0] >> 16 & 0xF) }
+
+// Number returns the key of a note message, or the number of a controller.
+func (p Packet) Number() int { return int(p[0] >> 8 & 0x7F) }
+
+// Index returns the index of a per-note controller.
+func (p Packet) Index() int { return int(p[0] & 0xFF) }
+
+// Velocity returns velocity of a note message of MIDI 2.0 belonging to
+// [0..1].
+func (p Packet) Velocity() float64 { return float64(p[1]>>16) / math.MaxUint16 }
+
+// Value returns the value of a controller or pressure of MIDI 2.0 belonging
+// to [0..1].
+func (p Packet) Value() float64 { return float64(p[1]) / math.MaxUint32 }
+
+// Bend returns pitch bend of MIDI 2.0 belonging to [-1..1].
+func (p Packet) Bend() float64 {
+	return math.Max(-1, (float64(p[1])-bendCenter)/(bendCenter-1))
+}
+
+// Bytes returns encoded p, its words big endian.
+func (p Packet) Bytes() []byte {
+	b := make([]byte, 4*p.Len())
+	for i := 0; i < len(b); i += 4 {
+		binary.BigEndian.PutUint32(b[i:], p[i/4])
+	}
+	return b
+}
+
+func (p Packet) String() string { return fmt.Sprintf("% X", p.Bytes()) }
+
+// Message returns p as a message of MIDI 1.0, scaling values of MIDI 2.0
+// down as MIDI 2.0 translates them, and reports whether p has one.
+func (p Packet) Message() (Message, bool) {
+	m := Message{Status: byte(p[0] >> 16), Data1: byte(p[0] >> 8 & 0x7F), Data2: byte(p[0] & 0x7F)}
+	switch p.Type() {
+	case UMPMIDI1:
+		return m, m.Status >= NoteOff && m.Status < SysEx
+	case UMPSystem:
+		return m, m.Status >= 0xF1
+	case UMPMIDI2:
+	default:
+		return Message{}, false
+	}
+	switch p.Status() {
+	case NoteOn:
+		// a note on of velocity 0 is a note on of MIDI 2.0.
+		m.Data2 = byte(math.Max(1, float64(p[1]>>25)))
+	case NoteOff:
+		m.Data2 = byte(p[1] >> 25)
+	case PolyPressure, ControlChange:
+		m.Data2 = byte(p[1] >> 25)
+	case ChannelPressure:
+		m.Data1, m.Data2 = byte(p[1]>>25), 0
+	case ProgramChange:
+		m.Data1, m.Data2 = byte(p[1]>>24&0x7F), 0
+	case PitchBend:
+		m.Data1, m.Data2 = byte(p[1]>>18&0x7F), byte(p[1]>>25)
+	default:
+		return Message{}, false
+	}
+	return m, true
+}
+
+// MessagePacket returns m of group as a packet of MIDI 1.0 channel voice or
+// system messages.
+func MessagePacket(group int, m Message) Packet {
+	t := uint32(UMPMIDI1)
+	if m.Status >= SysEx {
+		t = UMPSystem
+	}
+	w := t<<28 | uint32(group&0xF)<<24 | uint32(m.Status)<<16
+	switch datalen(m.Status) {
+	case 2:
+		w |= uint32(m.Data1)<<8 | uint32(m.Data2)
+	case 1:
+		w |= uint32(m.Data1) << 8
+	}
+	return Packet{w}
+}
+
+// midi2 returns a packet of a channel voice message of MIDI 2.0.
+func midi2(group, status, ch, num, idx int, data uint32) Packet {
+	return Packet{UMPMIDI2<<28 | uint32(group&0xF)<<24 | uint32(status|ch&0xF)<<16 | uint32(num&0x7F)<<8 | uint32(idx&0xFF), data}
+}
+
+// unit32 returns x belonging to [0..1] scaled to 32 bits.
+func unit32(x float64) uint32 {
+	return uint32(math.Round(math.Max(0, math.Min(1, x)) * math.MaxUint32))
+}
+
+// bend32 returns x belonging to [-1..1] as pitch bend of 32 bits.
+func bend32(x float64) uint32 {
+	return uint32(math.Round(bendCenter + math.Max(-1, math.Min(1, x))*(bendCenter-1)))
+}
+
+// NoteOnPacket returns a note on of MIDI 2.0 of key at velocity vel
+// belonging to [0..1], of 16 bits.
+func NoteOnPacket(group, ch, key int, vel float64) Packet {
+	return midi2(group, NoteOn, ch, key, 0, unit32(vel)&0xFFFF0000)
+}
+
+// NoteOffPacket returns a note off of MIDI 2.0 of key at release velocity
+// vel belonging to [0..1].
+func NoteOffPacket(group, ch, key int, vel float64) Packet {
+	return midi2(group, NoteOff, ch, key, 0, unit32(vel)&0xFFFF0000)
+}
+
+// ControlPacket returns a control change of MIDI 2.0 of controller cc to x
+// belonging to [0..1], of 32 bits.
+func ControlPacket(group, ch, cc int, x float64) Packet {
+	return midi2(group, ControlChange, ch, cc, 0, unit32(x))
+}
+
+// ProgramPacket returns a program change of MIDI 2.0 without bank.
+func ProgramPacket(group, ch, prog int) Packet {
+	return midi2(group, ProgramChange, ch, 0, 0, uint32(prog&0x7F)<<24)
+}
+
+// PressurePacket returns channel pressure of MIDI 2.0 of x belonging to
+// [0..1].
+func PressurePacket(group, ch int, x float64) Packet {
+	return midi2(group, ChannelPressure, ch, 0, 0, unit32(x))
+}
+
+// NotePressurePacket returns pressure of MIDI 2.0 on key of x belonging to
+// [0..1].
+func NotePressurePacket(group, ch, key int, x float64) Packet {
+	return midi2(group, PolyPressure, ch, key, 0, unit32(x))
+}
+
+// BendPacket returns pitch bend of MIDI 2.0 of x belonging to [-1..1].
+func BendPacket(group, ch int, x float64) Packet {
+	return midi2(group, PitchBend, ch, 0, 0, bend32(x))
+}
+
+// NoteBendPacket returns pitch bend of MIDI 2.0 of key of x belonging to
+// [-1..1], of NoteBendRange as played by PlayPacket.
+func NoteBendPacket(group, ch, key int, x float64) Packet {
+	return midi2(group, NoteBend, ch, key, 0, bend32(x))
+}
+
+// NoteControlPacket returns registered per-note controller index of key to
+// x belonging to [0..1].
+func NoteControlPacket(group, ch, key, index int, x float64) Packet {
+	return midi2(group, NoteController, ch, key, index, unit32(x))
+}
+
+// PacketReader reads packets from a byte stream of words big endian, such as
+// a device of Universal MIDI Packets.
+type PacketReader struct {
+	r   io.Reader
+	buf [16]byte
+}
+
+func NewPacketReader(r io.Reader) *PacketReader { return &PacketReader{r: r} }
+
+// Read returns the next packet.
+func (rd *PacketReader) Read() (Packet, error) {
+	var p Packet
+	if _, err := io.ReadFull(rd.r, rd.buf[:4]); err != nil {
+		return p, err
+	}
+	p[0] = binary.BigEndian.Uint32(rd.buf[:4])
+	n := p.Len()
+	if n == 1 {
+		return p, nil
+	}
+	if _, err := io.ReadFull(rd.r, rd.buf[4:4*n]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return p, err
+	}
+	for i := 1; i < n; i++ {
+		p[i] = binary.BigEndian.Uint32(rd.buf[4*i:])
+	}
+	return p, nil
+}
+
+// PlayPacket plays p on nt at the resolution of MIDI 2.0: velocity,
+// pressure, and pitch bend at full resolution, and pressure and pitch bend of
+// single notes if nt is snd.NoteExpressive. Control and program changes are
+// passed on as Play does, and messages of MIDI 1.0 are played by Play.
+// PlayPacket reports whether p was handled.
+func PlayPacket(nt snd.Noter, p Packet) bool {
+	if p.Type() != UMPMIDI2 {
+		m, ok := p.Message()
+		return ok && Play(nt, m)
+	}
+	ex, expressive := nt.(snd.Expressive)
+	nx, noteExpressive := nt.(snd.NoteExpressive)
+	switch p.Status() {
+	case NoteOn:
+		nt.NoteOn(p.Number(), p.Velocity())
+	case NoteOff:
+		nt.NoteOff(p.Number())
+	case ChannelPressure:
+		if !expressive {
+			return false
+		}
+		ex.Pressure(p.Value())
+	case PitchBend:
+		if !expressive {
+			return false
+		}
+		ex.Bend(BendRange * p.Bend())
+	case PolyPressure:
+		if !noteExpressive {
+			return false
+		}
+		nx.NotePressure(p.Number(), p.Value())
+	case NoteBend:
+		if !noteExpressive {
+			return false
+		}
+		nx.NoteBend(p.Number(), NoteBendRange*p.Bend())
+	case ControlChange, ProgramChange:
+		m, _ := p.Message()
+		return Play(nt, m)
+	default:
+		return false
+	}
+	return true
+}
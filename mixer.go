@@ -1,26 +1,99 @@
 package snd
 
-// TODO should mixer be stereo out?
 // TODO perhaps this class is unnecessary, any sound could be a mixer
 // if you can set multiple inputs, but might get confusing.
 // TODO consider embedding Gain type
+
+// Mixer sums its inputs. A Mixer has as many channels as its widest input, so
+// stereo inputs, such as a Player of a stereo file, are mixed channel for
+// channel and mono inputs are sounded in every channel alike.
 type Mixer struct {
 	*mono
-	ins []Sound
+	ins   []Sound
+	chans int
+
+	law    PanLaw
+	lawset bool
+}
+
+func NewMixer(ins ...Sound) *Mixer {
+	mix := &Mixer{mono: newmono(nil)}
+	mix.Append(ins...)
+	return mix
+}
+
+func (mix *Mixer) Append(s ...Sound) {
+	mix.ins = append(mix.ins, s...)
+	if mix.lawset {
+		mix.setlaw(s)
+	}
+	mix.layout()
 }
 
-func NewMixer(ins ...Sound) *Mixer   { return &Mixer{newmono(nil), ins} }
-func (mix *Mixer) Append(s ...Sound) { mix.ins = append(mix.ins, s...) }
-func (mix *Mixer) Empty()            { mix.ins = nil }
-func (mix *Mixer) Inputs() []Sound   { return mix.ins }
+// SetPanLaw sets the pan law of every Pan input, those appended after included,
+// such as PanLinear for headphones or to match a broadcast spec. Unless set,
+// each Pan keeps its own.
+func (mix *Mixer) SetPanLaw(law PanLaw) {
+	mix.law, mix.lawset = law, true
+	mix.setlaw(mix.ins)
+}
+
+// PanLaw returns the pan law set of Pan inputs and whether one is set.
+func (mix *Mixer) PanLaw() (PanLaw, bool) { return mix.law, mix.lawset }
+
+func (mix *Mixer) setlaw(ins []Sound) {
+	for _, in := range ins {
+		if pan, ok := in.(*Pan); ok {
+			pan.SetLaw(mix.law)
+		}
+	}
+}
+
+func (mix *Mixer) Empty() {
+	mix.ins = nil
+	mix.layout()
+}
+
+func (mix *Mixer) Inputs() []Sound { return mix.ins }
+func (mix *Mixer) Channels() int   { return mix.chans }
+
+// layout sizes the output for the widest input.
+func (mix *Mixer) layout() {
+	mix.chans = 1
+	for _, in := range mix.ins {
+		if c := in.Channels(); c > mix.chans {
+			mix.chans = c
+		}
+	}
+	if n := DefaultBufferLen * mix.chans; len(mix.out) != n {
+		mix.out = make(Discrete, n)
+	}
+}
 
 func (mix *Mixer) Prepare(uint64) {
 	for i := range mix.out {
 		mix.out[i] = 0
-		if !mix.off {
-			for _, in := range mix.ins {
+	}
+	if mix.off {
+		return
+	}
+	for _, in := range mix.ins {
+		switch c := in.Channels(); {
+		case c == mix.chans:
+			for i := range mix.out {
 				mix.out[i] += in.Index(i)
 			}
+		case c == 1:
+			for i := range mix.out {
+				mix.out[i] += in.Index(i / mix.chans)
+			}
+		default:
+			// fewer channels than the widest fill the first of each frame.
+			for i := range mix.out {
+				if k := i % mix.chans; k < c {
+					mix.out[i] += in.Index(i/mix.chans*c + k)
+				}
+			}
 		}
 	}
 }
@@ -0,0 +1,66 @@
+//go:build android
+// +build android
+
+#include <stdint.h>
+#include <time.h>
+#include <aaudio/AAudio.h>
+#include "_cgo_export.h"
+
+// SND_DEVICE_LOST is DeviceLost of Change.
+#define SND_DEVICE_LOST 1
+
+static aaudio_data_callback_result_t snd_aaudio_callback(AAudioStream *s, void *user, void *data, int32_t frames) {
+	sndMobileRender((uintptr_t)user, (float *)data, frames * AAudioStream_getChannelCount(s));
+	return AAUDIO_CALLBACK_RESULT_CONTINUE;
+}
+
+// the device lost, such as headphones unplugged, disconnects the stream,
+// reopened by the engine as of DeviceLost.
+static void snd_aaudio_error(AAudioStream *s, void *user, aaudio_result_t err) {
+	if (err == AAUDIO_ERROR_DISCONNECTED) {
+		sndMobileChange((uintptr_t)user, SND_DEVICE_LOST);
+	}
+}
+
+aaudio_result_t snd_aaudio_open(uintptr_t id, int32_t rate, int32_t chans, int32_t frames, AAudioStream **out) {
+	AAudioStreamBuilder *b;
+	aaudio_result_t r = AAudio_createStreamBuilder(&b);
+	if (r != AAUDIO_OK) {
+		return r;
+	}
+	AAudioStreamBuilder_setDirection(b, AAUDIO_DIRECTION_OUTPUT);
+	AAudioStreamBuilder_setSampleRate(b, rate);
+	AAudioStreamBuilder_setChannelCount(b, chans);
+	AAudioStreamBuilder_setFormat(b, AAUDIO_FORMAT_PCM_FLOAT);
+	AAudioStreamBuilder_setPerformanceMode(b, AAUDIO_PERFORMANCE_MODE_LOW_LATENCY);
+	AAudioStreamBuilder_setSharingMode(b, AAUDIO_SHARING_MODE_EXCLUSIVE);
+	AAudioStreamBuilder_setDataCallback(b, snd_aaudio_callback, (void *)id);
+	AAudioStreamBuilder_setErrorCallback(b, snd_aaudio_error, (void *)id);
+	r = AAudioStreamBuilder_openStream(b, out);
+	AAudioStreamBuilder_delete(b);
+	if (r != AAUDIO_OK) {
+		return r;
+	}
+	// at least two bursts so the device is never starved between callbacks.
+	int32_t burst = AAudioStream_getFramesPerBurst(*out);
+	if (frames < 2 * burst) {
+		frames = 2 * burst;
+	}
+	AAudioStream_setBufferSizeInFrames(*out, frames);
+	return AAUDIO_OK;
+}
+
+// snd_aaudio_latency returns nanoseconds until the last frame written is
+// heard, by the timestamp of the stream, or -1 if it has none yet.
+int64_t snd_aaudio_latency(AAudioStream *s) {
+	int64_t pos, ns;
+	if (AAudioStream_getTimestamp(s, CLOCK_MONOTONIC, &pos, &ns) != AAUDIO_OK) {
+		return -1;
+	}
+	struct timespec now;
+	clock_gettime(CLOCK_MONOTONIC, &now);
+	// frames written after pos are heard after it at the rate of the stream.
+	int64_t heard = ns + (AAudioStream_getFramesWritten(s) - pos) * 1000000000 / AAudioStream_getSampleRate(s);
+	int64_t l = heard - ((int64_t)now.tv_sec * 1000000000 + now.tv_nsec);
+	return l < 0 ? 0 : l;
+}
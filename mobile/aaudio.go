@@ -0,0 +1,115 @@
+//go:build android
+// +build android
+
+package mobile
+
+/*
+#cgo LDFLAGS: -laaudio
+#include <stdint.h>
+#include <aaudio/AAudio.h>
+
+aaudio_result_t snd_aaudio_open(uintptr_t id, int32_t rate, int32_t chans, int32_t frames, AAudioStream **out);
+int64_t snd_aaudio_latency(AAudioStream *s);
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+func init() {
+	newdriver = func(e *Engine) (driver, error) { return &aaudio{e: e}, nil }
+}
+
+// aaudio is the driver of AAudio, of Android 8.1 and later, opening a stream
+// of low latency performance and exclusive sharing where the device allows.
+// Streams disconnect as their device is lost, reported as DeviceLost; AAudio
+// reports no other changes, nor interruptions, as audio focus is of Java.
+type aaudio struct {
+	e      *Engine
+	id     uintptr
+	stream *C.AAudioStream
+	sr     float64
+}
+
+// aaudioerr returns an error of AAudio result r.
+func aaudioerr(r C.aaudio_result_t) error {
+	return fmt.Errorf("%s", C.GoString(C.AAudio_convertResultToText(r)))
+}
+
+func (d *aaudio) open(sr float64, chans int, target time.Duration) error {
+	d.id, d.sr = register(d.e), sr
+	frames := snd.Dtof(target, sr)
+	if r := C.snd_aaudio_open(C.uintptr_t(d.id), C.int32_t(sr), C.int32_t(chans), C.int32_t(frames), &d.stream); r != C.AAUDIO_OK {
+		unregister(d.id)
+		return aaudioerr(r)
+	}
+	if got := float64(C.AAudioStream_getSampleRate(d.stream)); got != sr {
+		d.close()
+		return fmt.Errorf("device opened at %vHz, want %vHz", got, sr)
+	}
+	return nil
+}
+
+func (d *aaudio) start() error {
+	if r := C.AAudioStream_requestStart(d.stream); r != C.AAUDIO_OK {
+		return aaudioerr(r)
+	}
+	return nil
+}
+
+func (d *aaudio) pause() error {
+	if r := C.AAudioStream_requestPause(d.stream); r != C.AAUDIO_OK {
+		return aaudioerr(r)
+	}
+	return nil
+}
+
+func (d *aaudio) close() error {
+	if d.stream == nil {
+		return nil
+	}
+	r := C.AAudioStream_close(d.stream)
+	d.stream = nil
+	unregister(d.id)
+	if r != C.AAUDIO_OK {
+		return aaudioerr(r)
+	}
+	return nil
+}
+
+// latency is by timestamps of the stream, including latency of Bluetooth and
+// other devices, or while there are none, of the buffer.
+func (d *aaudio) latency() time.Duration {
+	if d.stream == nil {
+		return 0
+	}
+	if ns := C.snd_aaudio_latency(d.stream); ns >= 0 {
+		return time.Duration(ns)
+	}
+	return snd.Ftod(int(C.AAudioStream_getBufferSizeInFrames(d.stream)), d.sr)
+}
+
+func (d *aaudio) xruns() (uint64, bool) {
+	if d.stream == nil {
+		return 0, true
+	}
+	return uint64(C.AAudioStream_getXRunCount(d.stream)), true
+}
+
+func (d *aaudio) route() string {
+	if d.stream == nil {
+		return ""
+	}
+	return fmt.Sprintf("device %d", C.AAudioStream_getDeviceId(d.stream))
+}
+
+func (d *aaudio) rate() float64 {
+	if d.stream == nil {
+		return 0
+	}
+	return float64(C.AAudioStream_getSampleRate(d.stream))
+}
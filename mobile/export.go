@@ -0,0 +1,67 @@
+//go:build android || ios
+// +build android ios
+
+package mobile
+
+// #include <stdint.h>
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+)
+
+// engines are Engines by id, passed to native callbacks in place of Go
+// pointers, which C may not keep.
+var engines struct {
+	sync.RWMutex
+	m    map[uintptr]*Engine
+	next uintptr
+}
+
+// register returns the id of e for native callbacks.
+func register(e *Engine) uintptr {
+	engines.Lock()
+	defer engines.Unlock()
+	if engines.m == nil {
+		engines.m = make(map[uintptr]*Engine)
+	}
+	engines.next++
+	engines.m[engines.next] = e
+	return engines.next
+}
+
+func unregister(id uintptr) {
+	engines.Lock()
+	delete(engines.m, id)
+	engines.Unlock()
+}
+
+func lookup(id C.uintptr_t) *Engine {
+	engines.RLock()
+	defer engines.RUnlock()
+	return engines.m[uintptr(id)]
+}
+
+//export sndMobileRender
+func sndMobileRender(id C.uintptr_t, data *C.float, n C.int32_t) {
+	out := (*[1 << 28]float32)(unsafe.Pointer(data))[:n:n]
+	e := lookup(id)
+	if e == nil {
+		for i := range out {
+			out[i] = 0
+		}
+		return
+	}
+	e.render(out)
+}
+
+// sndMobileChange reports Change c of the audio session. Streams may not be
+// closed from native callbacks, so it is handled on a goroutine of its own.
+//
+//export sndMobileChange
+func sndMobileChange(id C.uintptr_t, c C.int) {
+	if e := lookup(id); e != nil {
+		go e.Changed(Change(c))
+	}
+}
@@ -0,0 +1,364 @@
+// Package mobile plays graphs through the native low latency audio of
+// phones, in place of OpenAL of package al: AAudio on Android and a RemoteIO
+// audio unit under an AVAudioSession on iOS, built with gomobile.
+//
+// The device pulls frames on its own real time thread, which prepares the
+// graph as frames are needed, so latency is that of the device's buffers
+// rather than of buffers queued ahead. Pause and Resume, or Lifecycle, stop
+// and restart the device as the app goes to the background and back, and
+// Changed reconfigures it as routes, sample rates and interruptions change.
+//
+// On other platforms NewEngine reports ErrUnsupported.
+package mobile // import "dasa.cc/snd/mobile"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"dasa.cc/snd"
+
+	"golang.org/x/mobile/event/lifecycle"
+)
+
+// ErrUnsupported is reported by NewEngine on platforms other than Android and
+// iOS.
+var ErrUnsupported = errors.New("snd/mobile: platform not supported")
+
+// driver is the native audio of a platform pulling frames from an Engine.
+type driver interface {
+	// open opens a stream of chans channels at sr Hz with buffers of about
+	// target, pulling frames by Engine.render.
+	open(sr float64, chans int, target time.Duration) error
+	start() error
+	pause() error
+	close() error
+
+	// latency returns output latency reported by the device.
+	latency() time.Duration
+
+	// route returns the name of the device played through, and rate its
+	// sample rate.
+	route() string
+	rate() float64
+
+	// xruns returns underruns reported by the device, and whether it
+	// reports them.
+	xruns() (uint64, bool)
+}
+
+// newdriver returns the driver of the platform for e.
+var newdriver = func(e *Engine) (driver, error) { return nil, ErrUnsupported }
+
+// Change is a change of the audio session of the device, reported by the
+// platform or by Engine.Changed.
+type Change int
+
+// Changes are passed by value from native code, so their order is fixed.
+const (
+	// RouteChanged reports output moved to another device, such as headphones
+	// or Bluetooth plugged in or connected.
+	RouteChanged Change = iota
+
+	// DeviceLost reports the device played through went away, such as
+	// headphones unplugged, output moving to the speaker; apps commonly
+	// Pause on it.
+	DeviceLost
+
+	// RateChanged reports the sample rate of the device changed, such as of a
+	// Bluetooth headset switching profiles.
+	RateChanged
+
+	// InterruptBegan reports another app took the device, such as for a phone
+	// call, and InterruptEnded that it gave it back.
+	InterruptBegan
+	InterruptEnded
+)
+
+func (c Change) String() string {
+	switch c {
+	case RouteChanged:
+		return "route changed"
+	case DeviceLost:
+		return "device lost"
+	case RateChanged:
+		return "rate changed"
+	case InterruptBegan:
+		return "interrupt began"
+	case InterruptEnded:
+		return "interrupt ended"
+	}
+	return fmt.Sprintf("Change(%d)", int(c))
+}
+
+// Engine plays a graph through the native audio of the device.
+type Engine struct {
+	late uint64 // atomic, first for alignment; renders slower than real time
+
+	target time.Duration
+	drv    driver
+
+	ctl         sync.Mutex // held controlling the device
+	onchange    func(Change, error)
+	interrupted bool
+
+	mu     sync.Mutex // held by render on the device's thread
+	src    snd.Sound  // as started
+	in     snd.Sound  // played, src remixed if need be
+	inputs []*snd.Input
+	dp     snd.Dispatcher
+	tc     uint64
+	pos    int // of samples of in not yet played
+	paused bool
+	silent bool // paused or interrupted
+}
+
+// NewEngine returns Engine of the device with buffers of about target, such
+// as snd.LatencyLow, or ErrUnsupported if the platform has no native driver.
+// The device may choose larger buffers; see Latency.
+func NewEngine(target time.Duration) (*Engine, error) {
+	e := &Engine{target: target}
+	drv, err := newdriver(e)
+	if err != nil {
+		return nil, err
+	}
+	e.drv = drv
+	return e, nil
+}
+
+// Start opens a stream of the device at the sample rate and channels of in
+// and plays it; inputs of more than two channels are remixed to stereo.
+func (e *Engine) Start(in snd.Sound) error {
+	e.ctl.Lock()
+	defer e.ctl.Unlock()
+	if e.in != nil {
+		return fmt.Errorf("snd/mobile: already started")
+	}
+	e.src = in
+	if in.Channels() > 2 {
+		in = snd.Conform(2, in)
+	}
+	e.mu.Lock()
+	e.in, e.inputs, e.pos = in, snd.GetInputs(in), len(in.Samples())
+	e.paused, e.interrupted, e.silent = false, false, false
+	e.mu.Unlock()
+	if err := e.drv.open(in.SampleRate(), in.Channels(), e.target); err != nil {
+		e.in = nil
+		return fmt.Errorf("snd/mobile: open stream: %v", err)
+	}
+	if err := e.drv.start(); err != nil {
+		e.drv.close()
+		e.in = nil
+		return fmt.Errorf("snd/mobile: start stream: %v", err)
+	}
+	return nil
+}
+
+// Notify finds inputs of the graph again after it changed while playing.
+func (e *Engine) Notify() {
+	e.mu.Lock()
+	if e.in != nil {
+		e.inputs = snd.GetInputs(e.in)
+	}
+	e.mu.Unlock()
+}
+
+// Pause stops the device, keeping the stream open to Resume, such as while
+// the app is in the background. The graph isn't prepared while paused.
+func (e *Engine) Pause() error {
+	e.ctl.Lock()
+	defer e.ctl.Unlock()
+	return e.set(true, e.interrupted)
+}
+
+// Resume restarts the device after Pause, or once an interruption ends if
+// interrupted.
+func (e *Engine) Resume() error {
+	e.ctl.Lock()
+	defer e.ctl.Unlock()
+	return e.set(false, e.interrupted)
+}
+
+// set sets the engine paused and interrupted, starting or pausing the device
+// as it comes to play or stops. The caller holds ctl.
+func (e *Engine) set(paused, interrupted bool) error {
+	if e.in == nil {
+		return nil
+	}
+	was := !e.silent
+	e.mu.Lock()
+	e.paused, e.interrupted = paused, interrupted
+	e.silent = paused || interrupted
+	e.mu.Unlock()
+	switch {
+	case !e.silent && !was:
+		if err := e.drv.start(); err != nil {
+			return fmt.Errorf("snd/mobile: resume stream: %v", err)
+		}
+	case e.silent && was:
+		if err := e.drv.pause(); err != nil {
+			return fmt.Errorf("snd/mobile: pause stream: %v", err)
+		}
+	}
+	return nil
+}
+
+// Lifecycle pauses the device as the app stops being visible and resumes it
+// as it is visible again, for events of an app of golang.org/x/mobile.
+func (e *Engine) Lifecycle(ev lifecycle.Event) error {
+	switch ev.Crosses(lifecycle.StageVisible) {
+	case lifecycle.CrossOff:
+		return e.Pause()
+	case lifecycle.CrossOn:
+		return e.Resume()
+	}
+	return nil
+}
+
+// SetOnChange sets fn called after each change of the audio session is
+// handled, with the error handling it if any, such as to Pause on
+// DeviceLost. It is called on a goroutine of its own, not the app's.
+func (e *Engine) SetOnChange(fn func(c Change, err error)) {
+	e.ctl.Lock()
+	e.onchange = fn
+	e.ctl.Unlock()
+}
+
+// Changed handles change c of the audio session: the stream is reopened on
+// the new route or rate, continuing as it was, and the device stops while
+// interrupted, resuming once the interruption ends unless paused meanwhile.
+//
+// Changes are reported by the platform where it can: by AVAudioSession on
+// iOS, and by AAudio on Android for devices lost. Android apps report
+// interruptions themselves, losing and regaining audio focus, and may
+// report other changes they observe.
+func (e *Engine) Changed(c Change) error {
+	e.ctl.Lock()
+	var err error
+	switch c {
+	case RouteChanged, DeviceLost, RateChanged:
+		err = e.reopen()
+	case InterruptBegan:
+		err = e.set(e.paused, true)
+	case InterruptEnded:
+		err = e.set(e.paused, false)
+	}
+	fn := e.onchange
+	e.ctl.Unlock()
+	if fn != nil {
+		fn(c, err)
+	}
+	return err
+}
+
+// reopen closes the stream and opens it again on the device as it is now,
+// starting it unless paused or interrupted. The caller holds ctl.
+func (e *Engine) reopen() error {
+	if e.in == nil {
+		return nil
+	}
+	e.drv.close()
+	if err := e.drv.open(e.in.SampleRate(), e.in.Channels(), e.target); err != nil {
+		return fmt.Errorf("snd/mobile: reopen stream: %v", err)
+	}
+	if !e.silent {
+		if err := e.drv.start(); err != nil {
+			return fmt.Errorf("snd/mobile: restart stream: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close stops the device and closes its stream. If the graph started is a
+// *snd.Drain, it is drained first, as of Engine.Close of package al.
+func (e *Engine) Close(ctx context.Context) error {
+	e.ctl.Lock()
+	defer e.ctl.Unlock()
+	if e.in == nil {
+		return nil
+	}
+	var err error
+	if dr, ok := e.src.(*snd.Drain); ok {
+		err = dr.Close(ctx)
+	}
+	if cerr := e.drv.close(); cerr != nil && err == nil {
+		err = fmt.Errorf("snd/mobile: close stream: %v", cerr)
+	}
+	e.mu.Lock()
+	e.in, e.src, e.inputs = nil, nil, nil
+	e.mu.Unlock()
+	return err
+}
+
+// Dispatcher returns the Dispatcher preparing output, such as to add hooks.
+func (e *Engine) Dispatcher() *snd.Dispatcher { return &e.dp }
+
+// Latency returns output latency reported by the device, until frames
+// prepared now are heard, such as for snd.NewPlayhead. It includes latency
+// of Bluetooth and other outputs where the platform reports it.
+func (e *Engine) Latency() time.Duration { return e.drv.latency() }
+
+// Route returns the name of the device played through, as reported by the
+// platform.
+func (e *Engine) Route() string { return e.drv.route() }
+
+// DeviceRate returns the sample rate of the device as reported by the
+// platform, which resamples the graph if it differs.
+func (e *Engine) DeviceRate() float64 { return e.drv.rate() }
+
+// Underruns returns underruns reported by the device, or if it reports none,
+// as on iOS, times the graph took longer to prepare than the frames it
+// filled last.
+func (e *Engine) Underruns() uint64 {
+	if n, ok := e.drv.xruns(); ok {
+		return n
+	}
+	return atomic.LoadUint64(&e.late)
+}
+
+// render fills out with interleaved frames of the graph, preparing buffers as
+// needed. It is called by the driver on the device's thread.
+func (e *Engine) render(out []float32) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.in == nil || e.silent || len(e.inputs) == 0 {
+		for i := range out {
+			out[i] = 0
+		}
+		return
+	}
+	start := time.Now()
+	dur := snd.Ftod(len(out)/e.in.Channels(), e.in.SampleRate())
+	defer func() {
+		if time.Since(start) > dur {
+			atomic.AddUint64(&e.late, 1)
+		}
+	}()
+	for len(out) > 0 {
+		samples := e.in.Samples()
+		if e.pos == len(samples) {
+			e.tc++
+			e.dp.Dispatch(e.tc, e.inputs...)
+			e.pos = 0
+		}
+		n := copy32(out, samples[e.pos:])
+		out, e.pos = out[n:], e.pos+n
+	}
+}
+
+// copy32 copies xs to out as float32, clipped to [-1..1], returning the number
+// copied.
+func copy32(out []float32, xs snd.Discrete) int {
+	n := len(out)
+	if len(xs) < n {
+		n = len(xs)
+	}
+	for i, x := range xs[:n] {
+		out[i] = float32(math.Max(-1, math.Min(1, x)))
+	}
+	return n
+}
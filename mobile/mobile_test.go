@@ -0,0 +1,147 @@
+package mobile
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"dasa.cc/snd"
+
+	"golang.org/x/mobile/event/lifecycle"
+)
+
+// fake is a driver recording calls, pulling frames by hand.
+type fake struct {
+	calls []string
+}
+
+func (d *fake) open(float64, int, time.Duration) error { d.calls = append(d.calls, "open"); return nil }
+func (d *fake) start() error                           { d.calls = append(d.calls, "start"); return nil }
+func (d *fake) pause() error                           { d.calls = append(d.calls, "pause"); return nil }
+func (d *fake) close() error                           { d.calls = append(d.calls, "close"); return nil }
+func (d *fake) latency() time.Duration                 { return 5 * time.Millisecond }
+func (d *fake) xruns() (uint64, bool)                  { return 0, false }
+func (d *fake) route() string                          { return "Speaker" }
+func (d *fake) rate() float64                          { return 48000 }
+
+func newfake(t *testing.T) (*Engine, *fake) {
+	drv := new(fake)
+	defer func(f func(*Engine) (driver, error)) { newdriver = f }(newdriver)
+	newdriver = func(*Engine) (driver, error) { return drv, nil }
+	e, err := NewEngine(snd.LatencyLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, drv
+}
+
+func TestRender(t *testing.T) {
+	e, drv := newfake(t)
+	if err := e.Start(snd.NewOscil(snd.Sine(), 440, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Start(snd.NewConst(0)); err == nil {
+		t.Error("started twice")
+	}
+	// frames pulled by the device cross buffers of the graph.
+	var got []float32
+	for i := 0; i < 10; i++ {
+		out := make([]float32, 100)
+		e.render(out)
+		got = append(got, out...)
+	}
+	want := snd.Render(snd.NewOscil(snd.Sine(), 440, nil), len(got))
+	for i := range got {
+		if math.Abs(float64(got[i])-want[i]) > 1e-6 {
+			t.Fatalf("frame %v: have %v, want %v", i, got[i], want[i])
+		}
+	}
+	if err := e.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := strings.Join(drv.calls, " "), "open start close"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	e, drv := newfake(t)
+	if err := e.Start(snd.NewConst(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	e.Lifecycle(lifecycle.Event{From: lifecycle.StageFocused, To: lifecycle.StageAlive})
+	out := make([]float32, 64)
+	e.render(out)
+	for i, x := range out {
+		if x != 0 {
+			t.Fatalf("frame %v: have %v while paused, want 0", i, x)
+		}
+	}
+	e.Lifecycle(lifecycle.Event{From: lifecycle.StageAlive, To: lifecycle.StageVisible})
+	e.render(out)
+	if out[0] != 0.5 {
+		t.Errorf("have %v resumed, want 0.5", out[0])
+	}
+	if have, want := strings.Join(drv.calls, " "), "open start pause start"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	e, drv := newfake(t)
+	var changes []string
+	e.SetOnChange(func(c Change, err error) {
+		if err != nil {
+			t.Error(err)
+		}
+		changes = append(changes, c.String())
+	})
+	if err := e.Start(snd.NewConst(0.5)); err != nil {
+		t.Fatal(err)
+	}
+	out := make([]float32, 64)
+
+	// lost devices reopen the stream, playing on.
+	e.Changed(DeviceLost)
+	if e.render(out); out[0] != 0.5 {
+		t.Errorf("have %v after device lost, want 0.5", out[0])
+	}
+
+	// interruptions stop the device until they end.
+	e.Changed(InterruptBegan)
+	if e.render(out); out[0] != 0 {
+		t.Errorf("have %v interrupted, want 0", out[0])
+	}
+	e.Changed(InterruptEnded)
+	if e.render(out); out[0] != 0.5 {
+		t.Errorf("have %v after interrupt, want 0.5", out[0])
+	}
+
+	// paused while interrupted stays paused, reopened without starting.
+	e.Changed(InterruptBegan)
+	e.Pause()
+	e.Changed(RateChanged)
+	e.Changed(InterruptEnded)
+	if e.render(out); out[0] != 0 {
+		t.Errorf("have %v paused, want 0", out[0])
+	}
+	e.Resume()
+	if e.render(out); out[0] != 0.5 {
+		t.Errorf("have %v resumed, want 0.5", out[0])
+	}
+
+	if have, want := strings.Join(drv.calls, " "), "open start close open start pause start pause close open start"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+	if have, want := strings.Join(changes, ", "), "device lost, interrupt began, interrupt ended, interrupt began, rate changed, interrupt ended"; have != want {
+		t.Errorf("have %v, want %v", have, want)
+	}
+}
+
+func TestUnsupported(t *testing.T) {
+	if _, err := NewEngine(snd.LatencyLow); err != ErrUnsupported {
+		t.Errorf("have %v, want ErrUnsupported", err)
+	}
+}
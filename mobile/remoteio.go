@@ -0,0 +1,100 @@
+//go:build ios
+// +build ios
+
+package mobile
+
+/*
+#cgo CFLAGS: -x objective-c -fobjc-arc
+#cgo LDFLAGS: -framework AudioToolbox -framework AVFoundation -framework Foundation
+#include <stdint.h>
+#include <stdlib.h>
+#include <AudioToolbox/AudioToolbox.h>
+
+int snd_session_activate(double rate, double target);
+void *snd_session_observe(uintptr_t eng);
+void snd_session_unobserve(void *obs);
+char *snd_session_route(void);
+double snd_session_rate(void);
+double snd_session_latency(void);
+OSStatus snd_remoteio_open(uintptr_t id, double rate, int chans, AudioUnit *out);
+*/
+import "C"
+
+import (
+	"fmt"
+	"time"
+	"unsafe"
+)
+
+func init() {
+	newdriver = func(e *Engine) (driver, error) { return &remoteio{e: e}, nil }
+}
+
+// remoteio is the driver of iOS, a RemoteIO audio unit under an
+// AVAudioSession of the playback category, asking for buffers of the target
+// duration. Changes of route and rate, and interruptions, are observed by
+// notifications of the session.
+type remoteio struct {
+	e    *Engine
+	id   uintptr
+	unit C.AudioUnit
+	obs  unsafe.Pointer
+}
+
+func (d *remoteio) open(sr float64, chans int, target time.Duration) error {
+	if r := C.snd_session_activate(C.double(sr), C.double(target.Seconds())); r != 0 {
+		return fmt.Errorf("activate session: error %d", r)
+	}
+	d.id = register(d.e)
+	if r := C.snd_remoteio_open(C.uintptr_t(d.id), C.double(sr), C.int(chans), &d.unit); r != 0 {
+		unregister(d.id)
+		return fmt.Errorf("OSStatus %d", r)
+	}
+	d.obs = C.snd_session_observe(C.uintptr_t(d.id))
+	return nil
+}
+
+func (d *remoteio) start() error {
+	if r := C.AudioOutputUnitStart(d.unit); r != 0 {
+		return fmt.Errorf("OSStatus %d", r)
+	}
+	return nil
+}
+
+func (d *remoteio) pause() error {
+	if r := C.AudioOutputUnitStop(d.unit); r != 0 {
+		return fmt.Errorf("OSStatus %d", r)
+	}
+	return nil
+}
+
+func (d *remoteio) close() error {
+	if d.unit == nil {
+		return nil
+	}
+	C.snd_session_unobserve(d.obs)
+	C.AudioOutputUnitStop(d.unit)
+	C.AudioUnitUninitialize(d.unit)
+	r := C.AudioComponentInstanceDispose(d.unit)
+	d.unit = nil
+	unregister(d.id)
+	if r != 0 {
+		return fmt.Errorf("OSStatus %d", r)
+	}
+	return nil
+}
+
+func (d *remoteio) latency() time.Duration {
+	return time.Duration(float64(C.snd_session_latency()) * float64(time.Second))
+}
+
+func (d *remoteio) route() string {
+	name := C.snd_session_route()
+	defer C.free(unsafe.Pointer(name))
+	return C.GoString(name)
+}
+
+func (d *remoteio) rate() float64 { return float64(C.snd_session_rate()) }
+
+// xruns reports none, as RemoteIO doesn't count them.
+func (d *remoteio) xruns() (uint64, bool) { return 0, false }
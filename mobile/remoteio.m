@@ -0,0 +1,134 @@
+//go:build ios
+// +build ios
+
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+#import <AVFoundation/AVFoundation.h>
+#import <AudioToolbox/AudioToolbox.h>
+#include "_cgo_export.h"
+
+// Changes of Change.
+enum {
+	SND_ROUTE_CHANGED,
+	SND_DEVICE_LOST,
+	SND_RATE_CHANGED,
+	SND_INTERRUPT_BEGAN,
+	SND_INTERRUPT_ENDED,
+};
+
+static OSStatus snd_remoteio_render(void *user, AudioUnitRenderActionFlags *flags, const AudioTimeStamp *ts, UInt32 bus, UInt32 frames, AudioBufferList *bufs) {
+	AudioBuffer *b = &bufs->mBuffers[0];
+	sndMobileRender((uintptr_t)user, (float *)b->mData, (int32_t)(b->mDataByteSize / sizeof(float)));
+	return noErr;
+}
+
+int snd_session_activate(double rate, double target) {
+	AVAudioSession *s = [AVAudioSession sharedInstance];
+	NSError *err = nil;
+	if (![s setCategory:AVAudioSessionCategoryPlayback error:&err]) {
+		return (int)err.code;
+	}
+	[s setPreferredSampleRate:rate error:nil];
+	[s setPreferredIOBufferDuration:target error:nil];
+	if (![s setActive:YES error:&err]) {
+		return (int)err.code;
+	}
+	return 0;
+}
+
+// snd_session_observe observes notifications of the session, reporting them
+// to the engine of eng, and returns the observers to pass to
+// snd_session_unobserve.
+void *snd_session_observe(uintptr_t eng) {
+	AVAudioSession *s = [AVAudioSession sharedInstance];
+	NSNotificationCenter *nc = [NSNotificationCenter defaultCenter];
+	__block double rate = s.sampleRate;
+	id route = [nc addObserverForName:AVAudioSessionRouteChangeNotification object:s queue:nil usingBlock:^(NSNotification *n) {
+		NSUInteger reason = [n.userInfo[AVAudioSessionRouteChangeReasonKey] unsignedIntegerValue];
+		if (reason == AVAudioSessionRouteChangeReasonCategoryChange) {
+			return;
+		}
+		if (s.sampleRate != rate) {
+			rate = s.sampleRate;
+			sndMobileChange(eng, SND_RATE_CHANGED);
+		} else if (reason == AVAudioSessionRouteChangeReasonOldDeviceUnavailable) {
+			sndMobileChange(eng, SND_DEVICE_LOST);
+		} else {
+			sndMobileChange(eng, SND_ROUTE_CHANGED);
+		}
+	}];
+	id interrupt = [nc addObserverForName:AVAudioSessionInterruptionNotification object:s queue:nil usingBlock:^(NSNotification *n) {
+		NSUInteger kind = [n.userInfo[AVAudioSessionInterruptionTypeKey] unsignedIntegerValue];
+		if (kind == AVAudioSessionInterruptionTypeBegan) {
+			sndMobileChange(eng, SND_INTERRUPT_BEGAN);
+			return;
+		}
+		// the session is inactive after an interruption until activated again.
+		[s setActive:YES error:nil];
+		sndMobileChange(eng, SND_INTERRUPT_ENDED);
+	}];
+	id reset = [nc addObserverForName:AVAudioSessionMediaServicesWereResetNotification object:s queue:nil usingBlock:^(NSNotification *n) {
+		sndMobileChange(eng, SND_ROUTE_CHANGED);
+	}];
+	return (__bridge_retained void *)@[route, interrupt, reset];
+}
+
+void snd_session_unobserve(void *obs) {
+	NSArray *a = (__bridge_transfer NSArray *)obs;
+	for (id o in a) {
+		[[NSNotificationCenter defaultCenter] removeObserver:o];
+	}
+}
+
+// snd_session_route returns the name of the output port, to be freed.
+char *snd_session_route(void) {
+	AVAudioSessionRouteDescription *r = [AVAudioSession sharedInstance].currentRoute;
+	if (r.outputs.count == 0) {
+		return strdup("");
+	}
+	return strdup(r.outputs[0].portName.UTF8String);
+}
+
+double snd_session_rate(void) {
+	return [AVAudioSession sharedInstance].sampleRate;
+}
+
+double snd_session_latency(void) {
+	AVAudioSession *s = [AVAudioSession sharedInstance];
+	return s.outputLatency + s.IOBufferDuration;
+}
+
+OSStatus snd_remoteio_open(uintptr_t id, double rate, int chans, AudioUnit *out) {
+	AudioComponentDescription desc = {
+		.componentType = kAudioUnitType_Output,
+		.componentSubType = kAudioUnitSubType_RemoteIO,
+		.componentManufacturer = kAudioUnitManufacturer_Apple,
+	};
+	AudioComponent comp = AudioComponentFindNext(NULL, &desc);
+	if (comp == NULL) {
+		return kAudioUnitErr_InvalidElement;
+	}
+	OSStatus r = AudioComponentInstanceNew(comp, out);
+	if (r != noErr) {
+		return r;
+	}
+	AudioStreamBasicDescription asbd = {
+		.mSampleRate = rate,
+		.mFormatID = kAudioFormatLinearPCM,
+		.mFormatFlags = kAudioFormatFlagIsFloat | kAudioFormatFlagIsPacked,
+		.mBytesPerPacket = chans * sizeof(float),
+		.mFramesPerPacket = 1,
+		.mBytesPerFrame = chans * sizeof(float),
+		.mChannelsPerFrame = chans,
+		.mBitsPerChannel = 32,
+	};
+	AURenderCallbackStruct cb = {.inputProc = snd_remoteio_render, .inputProcRefCon = (void *)id};
+	if ((r = AudioUnitSetProperty(*out, kAudioUnitProperty_StreamFormat, kAudioUnitScope_Input, 0, &asbd, sizeof(asbd))) != noErr ||
+		(r = AudioUnitSetProperty(*out, kAudioUnitProperty_SetRenderCallback, kAudioUnitScope_Input, 0, &cb, sizeof(cb))) != noErr ||
+		(r = AudioUnitInitialize(*out)) != noErr) {
+		AudioComponentInstanceDispose(*out);
+		return r;
+	}
+	return noErr;
+}
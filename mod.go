@@ -0,0 +1,177 @@
+package snd
+
+import "time"
+
+// lfo is a low frequency oscillator over a wavetable shape.
+type lfo struct {
+	shape Discrete
+	rate  float64 // hz
+	phase float64
+}
+
+// next returns the current value of shape and advances phase at sample rate sr.
+func (o *lfo) next(sr float64) float64 {
+	x := o.shape.Interp(o.phase)
+	o.phase += o.rate / sr
+	if o.phase >= 1 {
+		o.phase -= float64(int(o.phase))
+	}
+	return x
+}
+
+// draw fills pts with a period of shape as of Drawer.
+func (o *lfo) draw(pts Discrete) { drawperiod(o.shape.Interp, pts) }
+
+// sync sets rate to one cycle every beats at tempo bpm.
+func (o *lfo) sync(bpm BPM, beats float64) { o.rate = float64(bpm) / 60 / beats }
+
+// Tremolo modulates amplitude of its input by a low frequency oscillator.
+type Tremolo struct {
+	*mono
+	lfo
+	depth float64
+}
+
+// NewTremolo returns Tremolo at rate hz with depth belonging to [0..1], where
+// a depth of 1 swings amplitude fully between silence and unity.
+func NewTremolo(rate, depth float64, in Sound) *Tremolo {
+	sd := newmono(in)
+	return &Tremolo{mono: sd, lfo: lfo{shape: Sine(), rate: rate}, depth: depth}
+}
+
+func (trm *Tremolo) Rate() float64         { return trm.rate }
+func (trm *Tremolo) SetRate(hz float64)    { trm.rate = hz }
+func (trm *Tremolo) Depth() float64        { return trm.depth }
+func (trm *Tremolo) SetDepth(x float64)    { trm.depth = x }
+func (trm *Tremolo) SetShape(sig Discrete) { trm.shape = sig }
+
+// SetSync sets rate to one cycle every beats at tempo bpm.
+func (trm *Tremolo) SetSync(bpm BPM, beats float64) { trm.sync(bpm, beats) }
+
+// Draw draws a period of gain applied to input, between 1-depth and unity.
+func (trm *Tremolo) Draw(pts Discrete) {
+	trm.draw(pts)
+	for i, m := range pts {
+		pts[i] = 1 - trm.depth*(1-m)/2
+	}
+}
+
+func (trm *Tremolo) Params() []*Param {
+	return []*Param{
+		NewParam("rate", trm.Rate, trm.SetRate).Range(0.01, 20, 5).In(UnitHz),
+		NewParam("depth", trm.Depth, trm.SetDepth).Range(0, 1, 0.5).In(UnitPercent),
+	}
+}
+
+func (trm *Tremolo) Prepare(uint64) {
+	for i := range trm.out {
+		m := trm.next(trm.sr)
+		if trm.off {
+			trm.out[i] = 0
+			continue
+		}
+		// unity at the peak of shape, 1-depth at the trough.
+		amp := 1 - trm.depth*(1-m)/2
+		trm.out[i] = amp * trm.in.Index(i)
+	}
+}
+
+// Vibrato modulates pitch of its input by reading a delay line at a time
+// varied by a low frequency oscillator.
+type Vibrato struct {
+	*mono
+	lfo
+	interpolators
+	depth float64 // frames of delay swing
+	line  []float64
+	w     int
+}
+
+// maxVibrato is the largest depth of a Vibrato.
+const maxVibrato = 20 * time.Millisecond
+
+// NewVibrato returns Vibrato at rate hz swinging delay by depth either side of
+// center, up to 20ms. Typical depths are a few milliseconds.
+func NewVibrato(rate float64, depth time.Duration, in Sound) *Vibrato {
+	sd := newmono(in)
+	vib := &Vibrato{
+		mono:          sd,
+		lfo:           lfo{shape: Sine(), rate: rate},
+		interpolators: newreaders(1),
+		line:          make([]float64, 2*Dtof(maxVibrato, sd.sr)+4),
+	}
+	vib.SetDepth(depth)
+	return vib
+}
+
+func (vib *Vibrato) Rate() float64         { return vib.rate }
+func (vib *Vibrato) SetRate(hz float64)    { vib.rate = hz }
+func (vib *Vibrato) SetShape(sig Discrete) { vib.shape = sig }
+
+// SetSync sets rate to one cycle every beats at tempo bpm.
+func (vib *Vibrato) SetSync(bpm BPM, beats float64) { vib.sync(bpm, beats) }
+
+// Depth returns the delay swing.
+func (vib *Vibrato) Depth() time.Duration {
+	return time.Duration(vib.depth / vib.sr * float64(time.Second))
+}
+
+// SetDepth sets the delay swing, limited to 20ms.
+func (vib *Vibrato) SetDepth(d time.Duration) {
+	if d > maxVibrato {
+		d = maxVibrato
+	} else if d < 0 {
+		d = 0
+	}
+	vib.depth = float64(d) / float64(time.Second) * vib.sr
+}
+
+// Draw draws a period of the shape swinging delay, belonging to [-1..1] from
+// least to most delayed.
+func (vib *Vibrato) Draw(pts Discrete) { vib.draw(pts) }
+
+// Params returns rate in hertz and depth in milliseconds.
+func (vib *Vibrato) Params() []*Param {
+	return []*Param{
+		NewParam("rate", vib.Rate, vib.SetRate).Range(0.01, 20, 5).In(UnitHz),
+		NewParam("depth",
+			func() float64 { return vib.depth / vib.sr * 1000 },
+			func(x float64) { vib.SetDepth(time.Duration(x * float64(time.Millisecond))) }).Range(0, maxVibrato.Seconds()*1000, 2).In(UnitMilliseconds),
+	}
+}
+
+func (vib *Vibrato) Prepare(uint64) {
+	vib.limit(vib.st)
+	for i := range vib.out {
+		vib.line[vib.w] = vib.in.Index(i)
+		// delay swings about center, never reading the sample just written.
+		x := vib.interpolators[0].read(vib.line, vib.w, 1+vib.depth*(1+vib.next(vib.sr)))
+		vib.w++
+		if vib.w == len(vib.line) {
+			vib.w = 0
+		}
+
+		if vib.off {
+			vib.out[i] = 0
+		} else {
+			vib.out[i] = x
+		}
+	}
+}
+
+// readfrac returns the linear interpolation of circular buffer line read
+// d frames behind write position w.
+func readfrac(line []float64, w int, d float64) float64 {
+	n := len(line)
+	r := float64(w) - d
+	for r < 0 {
+		r += float64(n)
+	}
+	j := int(r)
+	frac := r - float64(j)
+	k := j + 1
+	if k == n {
+		k = 0
+	}
+	return (1-frac)*line[j] + frac*line[k]
+}
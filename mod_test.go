@@ -0,0 +1,66 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTremolo(t *testing.T) {
+	trm := NewTremolo(4, 1, newunit())
+	out := Render(trm, Dtof(time.Second, DefaultSampleRate))
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, x := range out {
+		min, max = math.Min(min, x), math.Max(max, x)
+	}
+	if !equals(max, DefaultAmpFac) || min > 0.001 {
+		t.Fatalf("have range [%v..%v], want [0..%v]", min, max, DefaultAmpFac)
+	}
+
+	trm.SetSync(120, 1)
+	if trm.Rate() != 2 {
+		t.Fatalf("have rate %v, want 2hz at 120bpm per beat", trm.Rate())
+	}
+}
+
+func TestTremoloDraw(t *testing.T) {
+	trm := NewTremolo(4, 0.5, NewConst(1))
+	pts := make(Discrete, 4)
+	trm.Draw(pts)
+	// a sine of half depth, from unity at its peak to 0.5 at its trough.
+	for i, want := range []float64{0.75, 1, 0.75, 0.5} {
+		if math.Abs(pts[i]-want) > 1e-9 {
+			t.Errorf("point %v: have %v, want %v", i, pts[i], want)
+		}
+	}
+}
+
+func TestVibrato(t *testing.T) {
+	// pitch of a vibrato'd sine should vary yet average the same frequency.
+	osc := NewOscil(Sine(), 441, nil)
+	vib := NewVibrato(5, 2*time.Millisecond, osc)
+	out := Render(vib, Dtof(time.Second, DefaultSampleRate))
+
+	var crossings int
+	var minp, maxp = math.Inf(1), 0.0
+	last := 0
+	for i := 1; i < len(out); i++ {
+		if out[i-1] < 0 && out[i] >= 0 {
+			if crossings > 0 {
+				p := float64(i - last)
+				minp, maxp = math.Min(minp, p), math.Max(maxp, p)
+			}
+			crossings++
+			last = i
+		}
+	}
+	if crossings < 435 || crossings > 445 {
+		t.Fatalf("have %v cycles, want about 441", crossings)
+	}
+	if maxp-minp < 4 {
+		t.Fatalf("have periods [%v..%v], want varying pitch", minp, maxp)
+	}
+	if d := vib.Depth(); d != 2*time.Millisecond {
+		t.Fatalf("have depth %v, want 2ms", d)
+	}
+}
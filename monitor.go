@@ -0,0 +1,318 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Capture is a source of mono samples written by an audio input backend,
+// such as a microphone callback, for use in a graph. Samples are buffered to
+// a latency so that jitter between input and output callbacks does not
+// starve the graph; buffering beyond that is dropped to keep latency bounded.
+type Capture struct {
+	*mono
+	mu      sync.Mutex
+	buf     Discrete
+	latency int // frames
+	primed  bool
+
+	underruns uint64 // atomic
+}
+
+// NewCapture returns Capture buffering input to latency.
+func NewCapture(latency time.Duration) *Capture {
+	cp := &Capture{mono: newmono(nil)}
+	cp.SetLatency(latency)
+	return cp
+}
+
+func (cp *Capture) Inputs() []Sound { return nil }
+
+// Latency returns the duration input is buffered ahead of the graph.
+func (cp *Capture) Latency() time.Duration { return Ftod(cp.latency, cp.sr) }
+
+// SetLatency sets the duration input is buffered, trading delay for
+// robustness against underruns.
+func (cp *Capture) SetLatency(d time.Duration) {
+	cp.mu.Lock()
+	cp.latency = Dtof(d, cp.sr)
+	cp.primed = false
+	cp.mu.Unlock()
+}
+
+// Underruns returns the number of buffers output as silence for lack of input.
+func (cp *Capture) Underruns() uint64 { return atomic.LoadUint64(&cp.underruns) }
+
+// Write appends input samples; it is safe to call from another goroutine.
+func (cp *Capture) Write(samples []float64) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.buf = append(cp.buf, samples...)
+	if max := cp.latency + 2*len(cp.out); len(cp.buf) > max {
+		n := copy(cp.buf, cp.buf[len(cp.buf)-cp.latency-len(cp.out):])
+		cp.buf = cp.buf[:n]
+	}
+}
+
+func (cp *Capture) Prepare(uint64) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if !cp.primed && len(cp.buf) >= cp.latency+len(cp.out) {
+		cp.primed = true
+	}
+	if !cp.primed || len(cp.buf) < len(cp.out) || cp.off {
+		if cp.primed {
+			// refill to latency before resuming.
+			cp.primed = false
+			atomic.AddUint64(&cp.underruns, 1)
+		}
+		for i := range cp.out {
+			cp.out[i] = 0
+		}
+		return
+	}
+	copy(cp.out, cp.buf)
+	n := copy(cp.buf, cp.buf[len(cp.out):])
+	cp.buf = cp.buf[:n]
+}
+
+// Monitor routes an input, such as a Capture, through effects to the output
+// at a level, muting itself when it detects acoustic feedback.
+//
+// Feedback is detected as howl, a loud and nearly pure tone sustained for a
+// duration. Once muted, Monitor stays muted until Unmute is called.
+type Monitor struct {
+	*mono
+	chans int
+	wet   Sound
+	level float64
+
+	yin       *yin
+	fol       float64 // envelope of input
+	cr        float64 // envelope release coefficient
+	threshold Decibel
+	hold      int // frames of howl before muting
+	howl      int // frames of howl so far
+	muted     int32
+}
+
+// NewMonitor returns Monitor of in through effects fx applied in order, at
+// unity level, muting on howl above -12dB sustained for 300ms.
+func NewMonitor(in Sound, fx ...ProcFunc) *Monitor {
+	wet := in
+	for _, fn := range fx {
+		wet = fn(wet)
+	}
+	sd := newmono(nil)
+	sd.sr = wet.SampleRate()
+	sd.out = make(Discrete, len(wet.Samples()))
+	mn := &Monitor{
+		mono:  sd,
+		chans: wet.Channels(),
+		wet:   wet,
+		level: 1,
+		yin:   newyin(100, 5000, sd.sr),
+		cr:    smoothcoef(50*time.Millisecond, sd.sr),
+	}
+	mn.SetHowl(-12, 300*time.Millisecond)
+	return mn
+}
+
+func (mn *Monitor) Channels() int   { return mn.chans }
+func (mn *Monitor) Inputs() []Sound { return []Sound{mn.wet} }
+
+func (mn *Monitor) Level() float64     { return mn.level }
+func (mn *Monitor) SetLevel(x float64) { mn.level = x }
+
+func (mn *Monitor) Params() []*Param {
+	return []*Param{NewParam("level", mn.Level, mn.SetLevel).Range(0, 4, 1)}
+}
+
+// SetHowl sets the level a pure tone must exceed for a duration to be
+// considered feedback.
+func (mn *Monitor) SetHowl(threshold Decibel, d time.Duration) {
+	mn.threshold, mn.hold = threshold, Dtof(d, mn.sr)
+}
+
+// Muted reports whether output is muted; it is safe to call from another
+// goroutine, such as to show a warning.
+func (mn *Monitor) Muted() bool { return atomic.LoadInt32(&mn.muted) == 1 }
+
+// Mute silences output.
+func (mn *Monitor) Mute() { atomic.StoreInt32(&mn.muted, 1) }
+
+// Unmute resumes output after muting, such as once a microphone is moved.
+func (mn *Monitor) Unmute() { atomic.StoreInt32(&mn.muted, 0) }
+
+func (mn *Monitor) Prepare(uint64) {
+	thr := mn.threshold.Amp()
+	for i, x := range mn.wet.Samples() {
+		if i%mn.chans == 0 {
+			// detect on the first channel.
+			if a := math.Abs(x); a > mn.fol {
+				mn.fol = a
+			} else {
+				mn.fol += mn.cr * (a - mn.fol)
+			}
+			mn.yin.push(x, mn.sr)
+			if mn.Muted() {
+				mn.howl = 0
+			} else if mn.fol > thr && mn.yin.clarity > 0.9 {
+				if mn.howl++; mn.howl >= mn.hold {
+					mn.Mute()
+				}
+			} else {
+				mn.howl = 0
+			}
+		}
+		if mn.off || mn.Muted() {
+			mn.out[i] = 0
+		} else {
+			mn.out[i] = mn.level * x
+		}
+	}
+}
+
+// MonitorControl is the last node before output of a mixing application,
+// the controls of a monitor section: volume relative to a calibrated
+// reference level, dim, cut, a mono check, and flipping or soloing channels.
+// Changes of gain are smoothed so they don't click.
+type MonitorControl struct {
+	*mono
+	chans int
+
+	vol, ref, dimdb Decibel
+	dim, cut, sum   bool
+	flip            bool
+	solo            int // channel soloed, or -1
+
+	g, cg float64 // gain and its smoothing coefficient
+}
+
+// NewMonitorControl returns MonitorControl of in at its reference level, 0dB,
+// dimming by 20dB.
+func NewMonitorControl(in Sound) *MonitorControl {
+	sd := newmono(in)
+	sd.sr = in.SampleRate()
+	sd.out = make(Discrete, len(in.Samples()))
+	return &MonitorControl{
+		mono:  sd,
+		chans: in.Channels(),
+		dimdb: -20,
+		solo:  -1,
+		g:     1,
+		cg:    smoothcoef(20*time.Millisecond, sd.sr),
+	}
+}
+
+func (mc *MonitorControl) Channels() int { return mc.chans }
+
+// Volume returns gain of output.
+func (mc *MonitorControl) Volume() Decibel     { return mc.vol }
+func (mc *MonitorControl) SetVolume(x Decibel) { mc.vol = x }
+
+// SetReference sets the gain calibrated as reference, such as that at which
+// pink noise at -20dBFS plays at 83dB SPL in the room, and moves volume to it.
+func (mc *MonitorControl) SetReference(x Decibel) { mc.ref, mc.vol = x, x }
+
+func (mc *MonitorControl) Reference() Decibel { return mc.ref }
+
+// ToReference returns volume to the reference level, such as to judge a mix
+// at the level it was calibrated for.
+func (mc *MonitorControl) ToReference() { mc.vol = mc.ref }
+
+// AtReference reports whether volume is at the reference level.
+func (mc *MonitorControl) AtReference() bool { return mc.vol == mc.ref }
+
+// SetDim lowers output by the dim level, such as to talk over playback.
+func (mc *MonitorControl) SetDim(b bool) { mc.dim = b }
+func (mc *MonitorControl) Dim() bool     { return mc.dim }
+
+// SetDimLevel sets gain while dimmed relative to volume, -20dB by default.
+func (mc *MonitorControl) SetDimLevel(x Decibel) { mc.dimdb = x }
+func (mc *MonitorControl) DimLevel() Decibel     { return mc.dimdb }
+
+// SetCut silences output.
+func (mc *MonitorControl) SetCut(b bool) { mc.cut = b }
+func (mc *MonitorControl) Cut() bool     { return mc.cut }
+
+// SetMono sums channels, averaged so content common to all keeps its level,
+// into every channel, such as to check a mix for phase cancellation.
+func (mc *MonitorControl) SetMono(b bool) { mc.sum = b }
+func (mc *MonitorControl) Mono() bool     { return mc.sum }
+
+// SetFlip reverses the order of channels, swapping left and right.
+func (mc *MonitorControl) SetFlip(b bool) { mc.flip = b }
+func (mc *MonitorControl) Flip() bool     { return mc.flip }
+
+// Solo plays only channel c of input in its own place, or with c of -1 all
+// channels. Solo applies before mono and flip, so a channel soloed and summed
+// to mono plays from every speaker.
+func (mc *MonitorControl) Solo(c int) {
+	if c >= mc.chans {
+		c = -1
+	}
+	mc.solo = c
+}
+
+// Soloed returns the channel soloed, or -1.
+func (mc *MonitorControl) Soloed() int { return mc.solo }
+
+// Params returns volume in dB, and dim, mono, and flip as switches.
+func (mc *MonitorControl) Params() []*Param {
+	flag := func(name string, b *bool) *Param {
+		return NewParam(name,
+			func() float64 {
+				if *b {
+					return 1
+				}
+				return 0
+			},
+			func(x float64) { *b = x >= 0.5 }).Range(0, 1, 0)
+	}
+	return []*Param{
+		NewParam("volume",
+			func() float64 { return float64(mc.vol) },
+			func(x float64) { mc.vol = Decibel(x) }).Range(-80, 12, 0).In(UnitDecibel),
+		flag("dim", &mc.dim),
+		flag("mono", &mc.sum),
+		flag("flip", &mc.flip),
+	}
+}
+
+func (mc *MonitorControl) Prepare(uint64) {
+	want := mc.vol
+	if mc.dim {
+		want += mc.dimdb
+	}
+	target := want.Amp()
+	if mc.cut || mc.off {
+		target = 0
+	}
+	in := mc.in.Samples()
+	chans := mc.chans
+	for f := 0; f < len(in)/chans; f++ {
+		frame := in[f*chans : (f+1)*chans]
+		out := mc.out[f*chans : (f+1)*chans]
+		mc.g += mc.cg * (target - mc.g)
+		var sum float64
+		for c, x := range frame {
+			if mc.solo >= 0 && c != mc.solo {
+				x = 0
+			}
+			sum += x
+			k := c
+			if mc.flip {
+				k = chans - 1 - c
+			}
+			out[k] = mc.g * x
+		}
+		if mc.sum {
+			for c := range out {
+				out[c] = mc.g * sum / float64(chans)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCapture(t *testing.T) {
+	cp := NewCapture(10 * time.Millisecond)
+	n := len(cp.Samples())
+	lat := Dtof(10*time.Millisecond, cp.SampleRate())
+
+	in := make([]float64, lat+n)
+	for i := range in {
+		in[i] = float64(i + 1)
+	}
+	cp.Write(in[:n])
+	cp.Prepare(1)
+	if x := cp.Samples()[0]; x != 0 {
+		t.Fatalf("have %v, want silence before latency filled", x)
+	}
+	cp.Write(in[n:])
+	cp.Prepare(2)
+	if x := cp.Samples()[0]; x != 1 {
+		t.Fatalf("have %v, want 1", x)
+	}
+	cp.Prepare(3)
+	cp.Prepare(4)
+	if cp.Underruns() != 1 {
+		t.Fatalf("have %v underruns, want 1", cp.Underruns())
+	}
+
+	// input far ahead of output is dropped to latency.
+	cp.Write(make([]float64, 10*(lat+n)))
+	cp.mu.Lock()
+	have := len(cp.buf)
+	cp.mu.Unlock()
+	if have != lat+n {
+		t.Fatalf("have %v frames buffered, want %v", have, lat+n)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	howl := NewMonitor(NewOscil(Sine(), 1000, nil), func(in Sound) Sound { return NewGain(0.9, in) })
+	Render(howl, Dtof(time.Second, howl.SampleRate()))
+	if !howl.Muted() {
+		t.Fatal("loud tone not muted")
+	}
+	howl.Prepare(1)
+	if p := Peak(howl.Samples()); p != 0 {
+		t.Fatalf("have peak %v, want 0 when muted", p)
+	}
+
+	quiet := NewMonitor(NewGain(0.1, NewOscil(Sine(), 1000, nil)))
+	Render(quiet, Dtof(time.Second, quiet.SampleRate()))
+	if quiet.Muted() {
+		t.Fatal("quiet tone muted")
+	}
+
+	rnd := rand.New(rand.NewSource(1))
+	cp := NewCapture(0)
+	noise := NewMonitor(cp)
+	noise.SetLevel(0.5)
+	for i := 0; i < 200; i++ {
+		buf := make([]float64, len(cp.Samples()))
+		for j := range buf {
+			buf[j] = 1.8*rnd.Float64() - 0.9
+		}
+		cp.Write(buf)
+		cp.Prepare(uint64(i))
+		noise.Prepare(uint64(i))
+		if noise.Muted() {
+			t.Fatal("loud noise muted")
+		}
+	}
+	if p := Peak(noise.Samples()); p > 0.45 || p < 0.3 {
+		t.Fatalf("have peak %v, want noise at level", p)
+	}
+}
+
+func TestMonitorControl(t *testing.T) {
+	// a frame of left 1 and right 0.5, held.
+	lr := NewPlayer(Discrete{1, 0.5}, 2, DefaultSampleRate)
+	lr.SetLoop(true)
+	mc := NewMonitorControl(lr)
+	last := func() (l, r float64) {
+		out := Render(mc, 16384)
+		return out[len(out)-2], out[len(out)-1]
+	}
+	if l, r := last(); !equaleps(l, 1, 1e-6) || !equaleps(r, 0.5, 1e-6) {
+		t.Fatalf("have %v %v at reference, want 1 0.5", l, r)
+	}
+
+	mc.SetReference(-6)
+	mc.SetDim(true)
+	if l, _ := last(); !equaleps(l, Decibel(-26).Amp(), 1e-6) {
+		t.Fatalf("have %v dimmed from reference, want -26dB", l)
+	}
+	mc.SetDim(false)
+	mc.SetVolume(0)
+	if mc.AtReference() {
+		t.Fatal("have at reference after volume changed")
+	}
+	mc.ToReference()
+	mc.SetReference(0)
+
+	mc.SetFlip(true)
+	if l, r := last(); !equaleps(l, 0.5, 1e-6) || !equaleps(r, 1, 1e-6) {
+		t.Fatalf("have %v %v flipped, want 0.5 1", l, r)
+	}
+	mc.SetFlip(false)
+	mc.SetMono(true)
+	if l, r := last(); !equaleps(l, 0.75, 1e-6) || l != r {
+		t.Fatalf("have %v %v in mono, want 0.75 both", l, r)
+	}
+	mc.Solo(1)
+	if l, r := last(); !equaleps(l, 0.25, 1e-6) || l != r {
+		t.Fatalf("have %v %v of right soloed in mono, want 0.25 both", l, r)
+	}
+	mc.SetMono(false)
+	if l, r := last(); l != 0 || !equaleps(r, 0.5, 1e-6) {
+		t.Fatalf("have %v %v of right soloed, want 0 0.5", l, r)
+	}
+	mc.SetCut(true)
+	if l, r := last(); math.Abs(l)+math.Abs(r) > 1e-6 {
+		t.Fatalf("have %v %v cut, want silence", l, r)
+	}
+}
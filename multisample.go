@@ -0,0 +1,691 @@
+package snd
+
+import (
+	"container/list"
+	"io"
+	"math"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SampleSource is sample data read on demand, such as a *wav.File streamed
+// from disk.
+type SampleSource interface {
+	Channels() int
+	SampleRate() float64
+
+	// Len returns frames of the source.
+	Len() int
+
+	// ReadAt reads interleaved samples into xs from frame on, returning
+	// samples read, fewer than len(xs) only at the end with io.EOF.
+	ReadAt(xs []float64, frame int) (int, error)
+}
+
+// SampleCache holds sample data of sources in memory within a budget, loading
+// sources on a goroutine of their own when first acquired and evicting those
+// least recently used that aren't playing once over budget. Sources not yet
+// loaded, evicted, or too large for the budget alone are streamed from their
+// source instead, so large libraries play without loading everything upfront.
+type SampleCache struct {
+	mu     sync.Mutex
+	budget int64 // bytes
+	used   int64
+	bysrc  map[SampleSource]*cached
+	lru    list.List // of *cached loaded, most recently used first
+
+	loads uint64 // atomic
+}
+
+// cached is the sample data of a source.
+type cached struct {
+	src      SampleSource
+	sig      Discrete // nil until loaded
+	resident int32    // atomic; 1 while sig is loaded, read by voices
+	loading  bool
+	users    int
+	elem     *list.Element
+}
+
+// size returns bytes of sample data of c, as loaded, or as declared by its
+// source until then.
+func (c *cached) size() int64 {
+	if c.sig != nil {
+		return int64(len(c.sig)) * 8
+	}
+	return int64(c.src.Len()*c.src.Channels()) * 8
+}
+
+// NewSampleCache returns SampleCache of budget bytes, counting eight a sample.
+func NewSampleCache(budget int64) *SampleCache {
+	return &SampleCache{budget: budget, bysrc: make(map[SampleSource]*cached)}
+}
+
+// Budget returns bytes sample data may use.
+func (sc *SampleCache) Budget() int64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.budget
+}
+
+// SetBudget sets bytes sample data may use, evicting sources not playing
+// until within it.
+func (sc *SampleCache) SetBudget(n int64) {
+	sc.mu.Lock()
+	sc.budget = n
+	sc.evict()
+	sc.mu.Unlock()
+}
+
+// Used returns bytes of sample data loaded.
+func (sc *SampleCache) Used() int64 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.used
+}
+
+// Loads returns the number of sources loaded so far, counting those loaded
+// again after eviction.
+func (sc *SampleCache) Loads() uint64 { return atomic.LoadUint64(&sc.loads) }
+
+// Resident reports whether sample data of src is loaded.
+func (sc *SampleCache) Resident(src SampleSource) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	c, ok := sc.bysrc[src]
+	return ok && c.sig != nil
+}
+
+// Acquire returns sample data of src if loaded, marking it most recently
+// used, and otherwise starts loading it and returns nil. Either way src is
+// kept from eviction until released as often as acquired.
+func (sc *SampleCache) Acquire(src SampleSource) Discrete {
+	_, sig := sc.acquire(src)
+	return sig
+}
+
+// acquire is Acquire also returning the entry of src, whose resident flag a
+// voice streaming may poll without locking.
+func (sc *SampleCache) acquire(src SampleSource) (*cached, Discrete) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	c, ok := sc.bysrc[src]
+	if !ok {
+		c = &cached{src: src}
+		sc.bysrc[src] = c
+	}
+	c.users++
+	if c.sig != nil {
+		sc.lru.MoveToFront(c.elem)
+		return c, c.sig
+	}
+	if !c.loading && c.size() <= sc.budget {
+		c.loading = true
+		go sc.load(c)
+	}
+	return c, nil
+}
+
+// Release releases src acquired.
+func (sc *SampleCache) Release(src SampleSource) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if c, ok := sc.bysrc[src]; ok && c.users > 0 {
+		c.users--
+		sc.evict()
+	}
+}
+
+// Load loads src now, such as to preload sources sure to be played, returning
+// any error reading it.
+func (sc *SampleCache) Load(src SampleSource) error {
+	sig, err := readall(src)
+	if err != nil {
+		return err
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	c, ok := sc.bysrc[src]
+	if !ok {
+		c = &cached{src: src}
+		sc.bysrc[src] = c
+	}
+	sc.store(c, sig)
+	return nil
+}
+
+func (sc *SampleCache) load(c *cached) {
+	sig, err := readall(c.src)
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	c.loading = false
+	if err == nil {
+		sc.store(c, sig)
+	}
+}
+
+// store keeps sig as loaded for c, evicting others as needed.
+func (sc *SampleCache) store(c *cached, sig Discrete) {
+	if c.sig != nil {
+		return
+	}
+	c.sig = sig
+	c.elem = sc.lru.PushFront(c)
+	sc.used += c.size()
+	atomic.StoreInt32(&c.resident, 1)
+	atomic.AddUint64(&sc.loads, 1)
+	sc.evict()
+}
+
+// evict evicts sources loaded and not in use, least recently used first,
+// until within budget.
+func (sc *SampleCache) evict() {
+	for e := sc.lru.Back(); e != nil && sc.used > sc.budget; {
+		prev := e.Prev()
+		if c := e.Value.(*cached); c.users == 0 {
+			sc.lru.Remove(e)
+			sc.used -= c.size()
+			atomic.StoreInt32(&c.resident, 0)
+			c.sig, c.elem = nil, nil
+		}
+		e = prev
+	}
+}
+
+// readall reads all of src a chunk at a time, so memory is bounded by data
+// actually read rather than the length src declares, which a truncated or
+// malformed file may overstate.
+func readall(src SampleSource) (Discrete, error) {
+	chans := src.Channels()
+	var sig Discrete
+	for frame := 0; frame < src.Len(); {
+		n := src.Len() - frame
+		if n > readChunk {
+			n = readChunk
+		}
+		if need := len(sig) + n*chans; need > cap(sig) {
+			grown := make(Discrete, len(sig), 2*need)
+			copy(grown, sig)
+			sig = grown
+		}
+		m, err := src.ReadAt(sig[len(sig):len(sig)+n*chans], frame)
+		sig = sig[:len(sig)+m]
+		if err == io.EOF || err == nil && m == 0 {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		frame += m / chans
+	}
+	return sig, nil
+}
+
+// readChunk is frames read at a time when loading.
+const readChunk = 1 << 16
+
+// Zone is a sample played over a range of keys and velocities, pitched from
+// the key it was recorded at.
+type Zone struct {
+	Source SampleSource
+	Lo, Hi int // keys played, inclusive
+	Root   int // key recorded
+
+	// VelLo and VelHi are velocities played belonging to [0..1], inclusive;
+	// both zero plays all.
+	VelLo, VelHi float64
+
+	// Alternates are other takes of Source, such as repeated hits of a drum,
+	// selected with Source by Select each time the zone is played.
+	Alternates []SampleSource
+	Select     Selection
+
+	// Trigger is when the zone plays. Zones triggered by release play once
+	// through, such as a damper falling or a harpsichord jack returning, at
+	// the velocity the key was pressed, quieter by Decay per second it was
+	// held as strings ring down.
+	Trigger Trigger
+	Decay   Decibel
+
+	// Attack and Release are durations of the envelope of the zone fading in
+	// when played and out when released; zero fades in at once and out over
+	// 10ms.
+	Attack, Release time.Duration
+
+	// Soft zones play in place of others while the soft pedal is down, such
+	// as samples una corda; notes without soft zones play others quieter and
+	// darker.
+	Soft bool
+
+	take int // 1 + index of take last played, 0 if none
+}
+
+// Selection is how a Zone selects among its takes.
+type Selection int
+
+const (
+	// SelectRoundRobin plays takes in turn.
+	SelectRoundRobin Selection = iota
+
+	// SelectRandom plays takes at random, never the same twice in a row.
+	SelectRandom
+)
+
+// Trigger is when a Zone plays.
+type Trigger int
+
+const (
+	TriggerAttack  Trigger = iota // key pressed
+	TriggerRelease                // key released
+)
+
+// takes returns the number of takes of z.
+func (z *Zone) takes() int { return 1 + len(z.Alternates) }
+
+// source returns take i of z.
+func (z *Zone) source(i int) SampleSource {
+	if i == 0 {
+		return z.Source
+	}
+	return z.Alternates[i-1]
+}
+
+// next selects the take played next.
+func (z *Zone) next(rnd *rand.Rand) SampleSource {
+	n, last, i := z.takes(), z.take-1, 0
+	switch {
+	case n == 1:
+	case z.Select == SelectRandom && last < 0:
+		i = rnd.Intn(n)
+	case z.Select == SelectRandom:
+		i = (last + 1 + rnd.Intn(n-1)) % n
+	default:
+		i = (last + 1) % n
+	}
+	z.take = i + 1
+	return z.source(i)
+}
+
+func (z *Zone) plays(key int, vel float64) bool {
+	if key < z.Lo || key > z.Hi {
+		return false
+	}
+	return z.VelLo == 0 && z.VelHi == 0 || vel >= z.VelLo && vel <= z.VelHi
+}
+
+// streamChunk is frames read ahead a chunk at a time when streaming.
+const streamChunk = 4096
+
+// stream reads a source ahead of a voice on its own goroutine.
+type stream struct {
+	chunks chan Discrete
+	quit   chan struct{}
+}
+
+func newstream(src SampleSource, frame int) *stream {
+	st := &stream{chunks: make(chan Discrete, 4), quit: make(chan struct{})}
+	go func() {
+		defer close(st.chunks)
+		for {
+			buf := make(Discrete, streamChunk*src.Channels())
+			n, err := src.ReadAt(buf, frame)
+			if n > 0 {
+				select {
+				case st.chunks <- buf[:n]:
+				case <-st.quit:
+					return
+				}
+			}
+			// a read of no whole frame ends the stream, as in readall.
+			if err != nil || n < src.Channels() {
+				return
+			}
+			frame += n / src.Channels()
+		}
+	}()
+	return st
+}
+
+func (st *stream) close() { close(st.quit) }
+
+// multisampleVoice plays a take of a zone from memory, or streamed until
+// loaded.
+type multisampleVoice struct {
+	src  SampleSource
+	c    *cached
+	key  int
+	head Discrete // frames preloaded
+	sig  Discrete // loaded, or nil while streaming
+	st   *stream
+	win  Discrete // frames streamed from base on, of fixed capacity
+	base int
+	eof  bool
+	pos  float64 // in frames of the source
+	step float64
+	amp  float64
+	env  float64 // of attack, belonging to [0..1]
+	atk  float64 // change of env per frame
+	fade float64 // per frame once released, or zero while held
+	rel  int     // frames of release
+	once bool    // triggered by release, ignoring note off
+
+	lpc float64   // coefficient of lowpass, or zero if none
+	lp  []float64 // last output of lowpass of each channel
+}
+
+// frame returns sample c of frame i of the source, and whether it is
+// available.
+func (vc *multisampleVoice) frame(i, c int) (float64, bool) {
+	chans := vc.src.Channels()
+	c %= chans
+	if vc.sig != nil {
+		if i*chans+c < len(vc.sig) {
+			return vc.sig[i*chans+c], true
+		}
+		return 0, true
+	}
+	if i*chans+c < len(vc.head) {
+		return vc.head[i*chans+c], true
+	}
+	for (i-vc.base+1)*chans > len(vc.win) && !vc.eof {
+		if len(vc.win)+streamChunk*chans > cap(vc.win) {
+			// discard frames behind the one before i, in place.
+			if drop := i - 1 - vc.base; drop > 0 {
+				if drop*chans > len(vc.win) {
+					drop = len(vc.win) / chans
+				}
+				n := copy(vc.win, vc.win[drop*chans:])
+				vc.win = vc.win[:n]
+				vc.base += drop
+			}
+		}
+		select {
+		case buf, ok := <-vc.st.chunks:
+			if !ok {
+				vc.eof = true
+				break
+			}
+			vc.win = append(vc.win, buf...)
+		default:
+			return 0, false
+		}
+	}
+	if j := (i-vc.base)*chans + c; j >= 0 && j < len(vc.win) {
+		return vc.win[j], true
+	}
+	return 0, vc.eof
+}
+
+// Variation is ranges of random variation of each note played, so repeated
+// notes, especially of drums, don't sound identical.
+type Variation struct {
+	Pitch float64 // cents either way
+	Gain  Decibel // either way
+
+	// Cutoff is octaves below 20kHz a lowpass may cut, darkening notes;
+	// zero filters none.
+	Cutoff float64
+}
+
+// Multisampler plays zones of samples by note, such as a multisampled piano,
+// loading sample data through a SampleCache when first played and streaming
+// it from its source meanwhile. Preload heads of zones so streams have time
+// to start before their first frames are needed. Every zone matching a note
+// plays, so zones may be layered.
+type Multisampler struct {
+	*mono
+	chans  int
+	cache  *SampleCache
+	zones  []*Zone
+	heads  map[SampleSource]Discrete
+	voices []*multisampleVoice
+	vary   Variation
+	tuning *Tuning
+	frame  uint64 // of the next buffer
+	held   map[int]heldKey
+	soft   bool
+	rnd    *rand.Rand
+
+	underruns uint64 // atomic
+}
+
+// NewMultisampler returns Multisampler of chans channels of up to n voices
+// playing zones held by cache.
+func NewMultisampler(chans int, cache *SampleCache, n int, zones ...*Zone) *Multisampler {
+	sd := newmono(nil)
+	sd.out = make(Discrete, len(sd.out)*chans)
+	return &Multisampler{
+		mono: sd, chans: chans, cache: cache, zones: zones,
+		heads:  make(map[SampleSource]Discrete),
+		voices: make([]*multisampleVoice, n),
+		held:   make(map[int]heldKey),
+		rnd:    rand.New(rand.NewSource(1)),
+	}
+}
+
+func (sm *Multisampler) Channels() int   { return sm.chans }
+func (sm *Multisampler) Inputs() []Sound { return nil }
+
+// Underruns returns frames played as silence waiting on streams.
+func (sm *Multisampler) Underruns() uint64 { return atomic.LoadUint64(&sm.underruns) }
+
+// SetVariation sets random variation of notes played from the next.
+func (sm *Multisampler) SetVariation(v Variation) { sm.vary = v }
+
+// Variation returns random variation of notes played.
+func (sm *Multisampler) Variation() Variation { return sm.vary }
+
+// SetTuning tunes keys by tn from the next note, pitching zones from their
+// root as recorded in equal temperament; a nil tn plays keys as recorded.
+func (sm *Multisampler) SetTuning(tn *Tuning) { sm.tuning = tn }
+
+// Tuning returns the tuning of keys, nil for equal temperament.
+func (sm *Multisampler) Tuning() *Tuning { return sm.tuning }
+
+// Soft reports whether the soft pedal is down.
+func (sm *Multisampler) Soft() bool { return sm.soft }
+
+// SetSoft sets the soft pedal down or up, playing soft zones from the next
+// note.
+func (sm *Multisampler) SetSoft(down bool) { sm.soft = down }
+
+// Seed seeds random selection of takes and variation of notes, so a
+// performance renders the same every time.
+func (sm *Multisampler) Seed(seed int64) { sm.rnd.Seed(seed) }
+
+// PreloadHeads loads the first frames of every take of every zone, so streams
+// of takes not loaded start without underrun.
+func (sm *Multisampler) PreloadHeads(frames int) error {
+	for _, z := range sm.zones {
+		for i := 0; i < z.takes(); i++ {
+			src := z.source(i)
+			n := frames
+			if n > src.Len() {
+				n = src.Len()
+			}
+			head := make(Discrete, n*src.Channels())
+			if _, err := src.ReadAt(head, 0); err != nil && err != io.EOF {
+				return err
+			}
+			sm.heads[src] = head
+		}
+	}
+	return nil
+}
+
+// heldKey is a key pressed, for zones triggered by its release.
+type heldKey struct {
+	vel   float64
+	frame uint64
+}
+
+func (sm *Multisampler) NoteOn(key int, vel float64) {
+	if tunedfreq(sm.tuning, key, sm.st.concertPitch()) == 0 {
+		return
+	}
+	sm.held[key] = heldKey{vel, sm.frame}
+	soft := sm.softzones(TriggerAttack, key, vel)
+	for _, z := range sm.zones {
+		if z.Trigger == TriggerAttack && z.Soft == soft && z.plays(key, vel) {
+			sm.play(z, key, vel, sm.soft && !soft)
+		}
+	}
+}
+
+// softzones reports whether soft zones triggered by tr play key at vel, the
+// soft pedal being down.
+func (sm *Multisampler) softzones(tr Trigger, key int, vel float64) bool {
+	if !sm.soft {
+		return false
+	}
+	for _, z := range sm.zones {
+		if z.Trigger == tr && z.Soft && z.plays(key, vel) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sm *Multisampler) NoteOff(key int) {
+	for _, vc := range sm.voices {
+		if vc != nil && vc.key == key && !vc.once && vc.fade == 0 {
+			vc.fade = vc.amp / float64(vc.rel+1)
+		}
+	}
+	hk, ok := sm.held[key]
+	if !ok {
+		return
+	}
+	delete(sm.held, key)
+	held := float64(sm.frame-hk.frame) / sm.sr
+	soft := sm.softzones(TriggerRelease, key, hk.vel)
+	for _, z := range sm.zones {
+		if z.Trigger == TriggerRelease && z.Soft == soft && z.plays(key, hk.vel) {
+			sm.play(z, key, hk.vel*(z.Decay*Decibel(held)).Amp(), sm.soft && !soft)
+		}
+	}
+}
+
+// play plays a take of z at key and velocity vel, softened as una corda.
+func (sm *Multisampler) play(z *Zone, key int, vel float64, soften bool) {
+	idx := -1
+	for i, vc := range sm.voices {
+		if vc == nil {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		// steal the oldest, the first.
+		sm.stop(0)
+		copy(sm.voices, sm.voices[1:])
+		idx = len(sm.voices) - 1
+	}
+	src := z.next(sm.rnd)
+	vc := &multisampleVoice{src: src, key: key, head: sm.heads[src], amp: vel, env: 1, once: z.Trigger == TriggerRelease}
+	if n := Dtof(z.Attack, sm.sr); n > 0 {
+		vc.env, vc.atk = 0, 1/float64(n)
+	}
+	vc.rel = Dtof(z.Release, sm.sr)
+	if z.Release == 0 {
+		vc.rel = Dtof(10*time.Millisecond, sm.sr)
+	}
+	cents := sm.vary.Pitch * (2*sm.rnd.Float64() - 1)
+	a4 := sm.st.concertPitch()
+	ratio := tunedfreq(sm.tuning, key, a4) / keyfreq(z.Root, a4)
+	vc.step = ratio * math.Pow(2, cents/1200) * src.SampleRate() / sm.sr
+	vc.amp *= (sm.vary.Gain * Decibel(2*sm.rnd.Float64()-1)).Amp()
+	fc := sm.sr
+	if sm.vary.Cutoff > 0 {
+		fc = 20000 * math.Pow(2, -sm.vary.Cutoff*sm.rnd.Float64())
+	}
+	if soften {
+		vc.amp *= unaCordaGain.Amp()
+		fc = math.Min(fc, unaCordaCutoff)
+	}
+	if fc < sm.sr/2 {
+		vc.lpc = 1 - math.Exp(-2*math.Pi*fc/sm.sr)
+		vc.lp = make([]float64, sm.chans)
+	}
+	if vc.c, vc.sig = sm.cache.acquire(src); vc.sig == nil {
+		vc.base = len(vc.head) / src.Channels()
+		vc.st = newstream(src, vc.base)
+		vc.win = make(Discrete, 0, 2*streamChunk*src.Channels())
+	}
+	sm.voices[idx] = vc
+}
+
+// stop frees voice i.
+func (sm *Multisampler) stop(i int) {
+	vc := sm.voices[i]
+	if vc.st != nil {
+		vc.st.close()
+	}
+	sm.cache.Release(vc.src)
+	sm.voices[i] = nil
+}
+
+// Panic stops all voices, forgetting keys held without playing zones
+// triggered by their release.
+func (sm *Multisampler) Panic() {
+	sm.held = make(map[int]heldKey)
+	for i, vc := range sm.voices {
+		if vc != nil {
+			sm.stop(i)
+		}
+	}
+}
+
+func (sm *Multisampler) Prepare(uint64) {
+	for i := range sm.out {
+		sm.out[i] = 0
+	}
+	frames := len(sm.out) / sm.chans
+	for i, vc := range sm.voices {
+		if vc == nil {
+			continue
+		}
+		if vc.sig == nil && atomic.LoadInt32(&vc.c.resident) == 1 {
+			// loaded while streaming; play on from memory.
+			vc.sig = sm.cache.Acquire(vc.src)
+			sm.cache.Release(vc.src)
+			if vc.sig != nil {
+				vc.st.close()
+				vc.st, vc.win = nil, nil
+			}
+		}
+		for f := 0; f < frames; f++ {
+			n := int(vc.pos)
+			if n >= vc.src.Len() || vc.amp <= 0 {
+				sm.stop(i)
+				break
+			}
+			t := vc.pos - float64(n)
+			ok := true
+			for c := 0; c < sm.chans; c++ {
+				a, ok1 := vc.frame(n, c)
+				b, ok2 := vc.frame(n+1, c)
+				if ok = ok1 && ok2; !ok {
+					break
+				}
+				x := a + t*(b-a)
+				if vc.lp != nil {
+					vc.lp[c] += vc.lpc * (x - vc.lp[c])
+					x = vc.lp[c]
+				}
+				if !sm.off {
+					sm.out[f*sm.chans+c] += vc.env * vc.amp * x
+				}
+			}
+			if !ok {
+				atomic.AddUint64(&sm.underruns, 1)
+				continue
+			}
+			vc.pos += vc.step
+			vc.amp -= vc.fade
+			vc.env = math.Min(vc.env+vc.atk, 1)
+		}
+	}
+	sm.frame += uint64(frames)
+}
@@ -0,0 +1,287 @@
+package snd
+
+import (
+	"io"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// memsource is a SampleSource in memory counting reads.
+type memsource struct {
+	sig   Discrete
+	reads int32
+}
+
+func (ms *memsource) Channels() int       { return 1 }
+func (ms *memsource) SampleRate() float64 { return DefaultSampleRate }
+func (ms *memsource) Len() int            { return len(ms.sig) }
+
+func (ms *memsource) ReadAt(xs []float64, frame int) (int, error) {
+	atomic.AddInt32(&ms.reads, 1)
+	if frame >= len(ms.sig) {
+		return 0, io.EOF
+	}
+	n := copy(xs, ms.sig[frame:])
+	if n < len(xs) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func newmemsource(frames int, x float64) *memsource {
+	ms := &memsource{sig: make(Discrete, frames)}
+	for i := range ms.sig {
+		ms.sig[i] = x
+	}
+	return ms
+}
+
+func TestSampleCacheEvict(t *testing.T) {
+	a, b, c := newmemsource(100, 1), newmemsource(100, 1), newmemsource(100, 1)
+	sc := NewSampleCache(2 * 100 * 8)
+	for _, src := range []*memsource{a, b} {
+		if err := sc.Load(src); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sc.Acquire(a) // most recently used
+	sc.Release(a)
+	if err := sc.Load(c); err != nil {
+		t.Fatal(err)
+	}
+	if !sc.Resident(a) || sc.Resident(b) || !sc.Resident(c) {
+		t.Fatalf("have resident %v %v %v, want b evicted", sc.Resident(a), sc.Resident(b), sc.Resident(c))
+	}
+	if have, want := sc.Used(), int64(2*100*8); have != want {
+		t.Fatalf("have used %v, want %v", have, want)
+	}
+
+	// sources playing aren't evicted.
+	sc.Acquire(a)
+	sc.SetBudget(100 * 8)
+	if !sc.Resident(a) || sc.Resident(c) {
+		t.Fatal("evicted source playing")
+	}
+	sc.Release(a)
+	sc.SetBudget(0)
+	if sc.Resident(a) || sc.Used() != 0 {
+		t.Fatal("kept source over budget")
+	}
+}
+
+func TestMultisamplerStream(t *testing.T) {
+	src := newmemsource(4*DefaultBufferLen, 0.5)
+	sc := NewSampleCache(1 << 20)
+	sm := NewMultisampler(1, sc, 4, &Zone{Source: src, Lo: 0, Hi: 127, Root: 60})
+	if err := sm.PreloadHeads(2 * DefaultBufferLen); err != nil {
+		t.Fatal(err)
+	}
+	sm.NoteOn(60, 1)
+	if sc.Resident(src) {
+		t.Fatal("loaded before played")
+	}
+	sm.Prepare(1)
+	if x := sm.Samples()[0]; x != 0.5 {
+		t.Fatalf("have %v streaming from head, want 0.5", x)
+	}
+	for i := 0; i < 100 && !sc.Resident(src); i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if !sc.Resident(src) {
+		t.Fatal("not loaded on first play")
+	}
+	for i := 0; i < 3; i++ {
+		sm.Prepare(1)
+		if x := sm.Samples()[DefaultBufferLen-1]; x != 0.5 {
+			t.Fatalf("have %v in buffer %v, want 0.5", x, i+1)
+		}
+	}
+	sm.Prepare(1)
+	if sm.Samples()[DefaultBufferLen-1] != 0 || sm.voices[0] != nil {
+		t.Fatal("voice playing past end")
+	}
+
+	// played again from memory.
+	reads := atomic.LoadInt32(&src.reads)
+	sm.NoteOn(72, 1)
+	sm.Prepare(1)
+	if sm.Samples()[0] != 0.5 || atomic.LoadInt32(&src.reads) != reads {
+		t.Fatal("read source loaded")
+	}
+	if sm.Underruns() != 0 {
+		t.Fatalf("have %v underruns, want 0", sm.Underruns())
+	}
+}
+
+// liar is a memsource declaring far more frames than it has, as a truncated
+// file may.
+type liar struct{ memsource }
+
+func (l *liar) Len() int { return 1 << 40 }
+
+// stall is a memsource reading nothing past its end without an error.
+type stall struct{ memsource }
+
+func (st *stall) ReadAt(xs []float64, frame int) (int, error) {
+	if frame >= len(st.sig) {
+		return 0, nil
+	}
+	return st.memsource.ReadAt(xs, frame)
+}
+
+func TestStreamStall(t *testing.T) {
+	src := &stall{*newmemsource(streamChunk, 0.5)}
+	st := newstream(src, 0)
+	defer st.close()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-st.chunks:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			t.Fatal("stream did not end on reading nothing")
+		}
+	}
+}
+
+func TestMultisamplerWindow(t *testing.T) {
+	// streamed throughout, never loaded, through a window of fixed capacity.
+	src := newmemsource(5*streamChunk, 0)
+	for i := range src.sig {
+		src.sig[i] = float64(i)
+	}
+	sm := NewMultisampler(1, NewSampleCache(0), 1, &Zone{Source: src, Lo: 0, Hi: 127, Root: 60})
+	sm.NoteOn(60, 1)
+	vc := sm.voices[0]
+	win := cap(vc.win)
+	for f := 0; sm.voices[0] != nil; f += DefaultBufferLen {
+		// waits on the stream as a slow realtime graph would.
+		time.Sleep(100 * time.Microsecond)
+		sm.Prepare(1)
+		for i, x := range sm.Samples() {
+			if f+i < len(src.sig)-1 && sm.Underruns() == 0 && x != float64(f+i) {
+				t.Fatalf("have %v at frame %v", x, f+i)
+			}
+		}
+		if cap(vc.win) != win {
+			t.Fatalf("have window of %v, want %v", cap(vc.win), win)
+		}
+	}
+
+	l := &liar{*newmemsource(100, 1)}
+	if sig, err := readall(l); err != nil || len(sig) != 100 {
+		t.Fatalf("have %v samples, %v, of a source of 100", len(sig), err)
+	}
+}
+
+func TestMultisamplerTakes(t *testing.T) {
+	takes := []*memsource{newmemsource(64, 0.1), newmemsource(64, 0.2), newmemsource(64, 0.3)}
+	z := &Zone{Source: takes[0], Alternates: []SampleSource{takes[1], takes[2]}, Lo: 0, Hi: 127, Root: 60}
+	sc := NewSampleCache(1 << 20)
+	for _, src := range takes {
+		sc.Load(src)
+	}
+	sm := NewMultisampler(1, sc, 1, z)
+	var played []float64
+	for i := 0; i < 4; i++ {
+		sm.NoteOn(60, 1)
+		sm.Prepare(1)
+		played = append(played, sm.Samples()[0])
+	}
+	if want := []float64{0.1, 0.2, 0.3, 0.1}; !reflect.DeepEqual(played, want) {
+		t.Fatalf("have %v round robin, want %v", played, want)
+	}
+
+	z.Select = SelectRandom
+	last := played[3]
+	for i := 0; i < 20; i++ {
+		sm.NoteOn(60, 1)
+		sm.Prepare(1)
+		if x := sm.Samples()[0]; x == last {
+			t.Fatalf("have %v twice in a row", x)
+		} else {
+			last = x
+		}
+	}
+
+	sm.SetVariation(Variation{Pitch: 50, Gain: 3})
+	seen := make(map[float64]bool)
+	for i := 0; i < 8; i++ {
+		sm.NoteOn(60, 1)
+		sm.Prepare(1)
+		x := sm.Samples()[0]
+		if x < 0.1*Decibel(-3).Amp()-1e-9 || x > 0.3*Decibel(3).Amp()+1e-9 {
+			t.Fatalf("have %v out of range of variation", x)
+		}
+		seen[x] = true
+	}
+	if len(seen) < 4 {
+		t.Fatalf("have %v distinct of 8 notes varied, want more", len(seen))
+	}
+}
+
+func TestMultisamplerRelease(t *testing.T) {
+	body, thump := newmemsource(int(DefaultSampleRate), 1), newmemsource(64, 0.5)
+	sc := NewSampleCache(1 << 20)
+	sc.Load(body)
+	sc.Load(thump)
+	sm := NewMultisampler(1, sc, 4,
+		&Zone{Source: body, Lo: 0, Hi: 127, Root: 60, Attack: time.Millisecond},
+		&Zone{Source: thump, Lo: 0, Hi: 127, Root: 60, Trigger: TriggerRelease, Decay: -6},
+	)
+	sm.NoteOn(60, 1)
+	sm.Prepare(1)
+	if x := sm.Samples()[0]; x >= 0.1 {
+		t.Fatalf("have %v, want attack fading in", x)
+	}
+	if x := sm.Samples()[DefaultBufferLen-1]; x != 1 {
+		t.Fatalf("have %v after attack, want 1", x)
+	}
+	n := 64
+	for i := 1; i < n; i++ {
+		sm.Prepare(1)
+	}
+	sm.NoteOff(60)
+	sm.Prepare(1)
+	held := float64(n*DefaultBufferLen) / DefaultSampleRate
+	want := 1 + 0.5*(-6*Decibel(held)).Amp()
+	if x := sm.Samples()[0]; !equaleps(x, want, 1e-9) {
+		t.Fatalf("have %v releasing, want body and thump %v", x, want)
+	}
+	for i := 0; i < 2; i++ {
+		sm.Prepare(1)
+	}
+	if x := sm.Samples()[0]; x != 0 {
+		t.Fatalf("have %v, want silence after release", x)
+	}
+}
+
+func TestMultisamplerSoft(t *testing.T) {
+	full, soft := newmemsource(64, 0.5), newmemsource(64, 0.2)
+	sc := NewSampleCache(1 << 20)
+	sc.Load(full)
+	sc.Load(soft)
+	sm := NewMultisampler(1, sc, 1,
+		&Zone{Source: full, Lo: 0, Hi: 127, Root: 60},
+		&Zone{Source: soft, Lo: 0, Hi: 62, Root: 60, Soft: true},
+	)
+	play := func(key int) float64 {
+		sm.NoteOn(key, 1)
+		sm.Prepare(1)
+		return sm.Samples()[0]
+	}
+	if x := play(60); x != 0.5 {
+		t.Fatalf("have %v, want 0.5 without soft pedal", x)
+	}
+	sm.SetSoft(true)
+	if x := play(60); x != 0.2 {
+		t.Fatalf("have %v, want 0.2 of soft zone", x)
+	}
+	if x := play(64); x <= 0 || x > 0.5*unaCordaGain.Amp() {
+		t.Fatalf("have %v without soft zone, want softened", x)
+	}
+}
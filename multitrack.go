@@ -0,0 +1,141 @@
+package snd
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"dasa.cc/snd/wav"
+)
+
+// Multitrack records several points of a graph at once as separate stems, such
+// as each voice of a performance to mix later in a DAW. Stems start on the
+// same frame and are of equal length.
+type Multitrack struct {
+	stems []*Stem
+
+	rec   int32  // atomic
+	start uint64 // atomic; cycle recording starts, or zero if not yet known
+}
+
+func NewMultitrack() *Multitrack { return &Multitrack{} }
+
+// Stem returns a Stem of in recorded by name. The Stem passes in through
+// unaltered and must be part of the graph in place of in.
+func (mt *Multitrack) Stem(name string, in Sound) *Stem {
+	sd := newmono(in)
+	sd.sr = in.SampleRate()
+	sd.out = make(Discrete, len(in.Samples()))
+	sm := &Stem{mono: sd, mt: mt, name: name, chans: in.Channels()}
+	mt.stems = append(mt.stems, sm)
+	return sm
+}
+
+// Stems returns the stems of mt in the order created.
+func (mt *Multitrack) Stems() []*Stem { return mt.stems }
+
+// Record discards anything captured and starts recording all stems from
+// the same buffer, shortly after Record is called.
+func (mt *Multitrack) Record() {
+	atomic.StoreInt32(&mt.rec, 0)
+	for _, sm := range mt.stems {
+		sm.mu.Lock()
+		sm.buf = nil
+		sm.mu.Unlock()
+	}
+	atomic.StoreUint64(&mt.start, 0)
+	atomic.StoreInt32(&mt.rec, 1)
+}
+
+func (mt *Multitrack) Recording() bool { return atomic.LoadInt32(&mt.rec) == 1 }
+
+// Stop stops recording and returns captured samples of each stem, trimmed to
+// the length of the shortest.
+func (mt *Multitrack) Stop() []Discrete {
+	atomic.StoreInt32(&mt.rec, 0)
+	stems := make([]Discrete, len(mt.stems))
+	frames := -1
+	for i, sm := range mt.stems {
+		sm.mu.Lock()
+		stems[i], sm.buf = sm.buf, nil
+		sm.mu.Unlock()
+		if n := len(stems[i]) / sm.chans; frames < 0 || n < frames {
+			frames = n
+		}
+	}
+	for i, sm := range mt.stems {
+		stems[i] = stems[i][:frames*sm.chans]
+	}
+	return stems
+}
+
+// WriteStems stops recording and writes each stem to dir as a WAVE file named
+// by its name, with sample depth as given to wav.NewWriter.
+func (mt *Multitrack) WriteStems(dir string, depth int) error {
+	for i, sig := range mt.Stop() {
+		sm := mt.stems[i]
+		if err := writestem(filepath.Join(dir, sm.name+".wav"), sig, sm.chans, int(sm.sr), depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writestem(name string, sig Discrete, chans, sr, depth int) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	w, err := wav.NewWriter(f, chans, sr, depth)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Write(sig); err != nil {
+		f.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Stem passes its input through unaltered, recording it for a Multitrack.
+type Stem struct {
+	*mono
+	mt    *Multitrack
+	name  string
+	chans int
+
+	mu  sync.Mutex
+	buf Discrete
+}
+
+func (sm *Stem) Channels() int { return sm.chans }
+
+// Name returns the name of sm, as written by WriteStems.
+func (sm *Stem) Name() string { return sm.name }
+
+func (sm *Stem) Prepare(tc uint64) {
+	for i, x := range sm.in.Samples() {
+		if sm.off {
+			sm.out[i] = 0
+		} else {
+			sm.out[i] = x
+		}
+	}
+	if !sm.mt.Recording() {
+		return
+	}
+	// the first stem to see recording started begins all stems on the next
+	// cycle, so that stems prepared earlier this cycle start on the same buffer.
+	atomic.CompareAndSwapUint64(&sm.mt.start, 0, tc+1)
+	if !FrameBefore(tc, atomic.LoadUint64(&sm.mt.start)) {
+		sm.mu.Lock()
+		sm.buf = append(sm.buf, sm.out...)
+		sm.mu.Unlock()
+	}
+}
@@ -0,0 +1,84 @@
+package snd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dasa.cc/snd/wav"
+)
+
+func TestMultitrack(t *testing.T) {
+	mt := NewMultitrack()
+	a := mt.Stem("a", &counter{mono: newmono(nil)})
+	b := mt.Stem("b", &counter{mono: newmono(nil)})
+	prepare := func(tc uint64) {
+		a.in.Prepare(tc)
+		a.Prepare(tc)
+		b.in.Prepare(tc)
+		b.Prepare(tc)
+	}
+	prepare(1)
+
+	// recording starts between stems of a cycle; both start next cycle.
+	a.in.Prepare(2)
+	a.Prepare(2)
+	mt.Record()
+	b.in.Prepare(2)
+	b.Prepare(2)
+	for tc := uint64(3); tc < 6; tc++ {
+		prepare(tc)
+	}
+	// b is prepared once more before stopping.
+	b.in.Prepare(6)
+	b.Prepare(6)
+
+	stems := mt.Stop()
+	n := len(a.Samples())
+	for i, sig := range stems {
+		if len(sig) != 3*n {
+			t.Fatalf("stem %v: have %v samples, want %v", i, len(sig), 3*n)
+		}
+		if sig[0] != float64(2*n) {
+			t.Fatalf("stem %v: have start %v, want %v", i, sig[0], 2*n)
+		}
+	}
+}
+
+func TestMultitrackWriteStems(t *testing.T) {
+	mt := NewMultitrack()
+	mono := mt.Stem("mono", NewConst(0.5))
+	stereo := mt.Stem("stereo", NewPan(-1, NewConst(0.25)))
+	mt.Record()
+	Render(mono, 1000)
+	Render(stereo, 1000)
+
+	dir := t.TempDir()
+	if err := mt.WriteStems(dir, 32); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name  string
+		chans int
+		x     float64
+	}{{"mono", 1, 0.5}, {"stereo", 2, 0.25}} {
+		f, err := os.Open(filepath.Join(dir, tc.name+".wav"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig, format, err := wav.Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format.Chans != tc.chans {
+			t.Errorf("%s: have %v channels, want %v", tc.name, format.Chans, tc.chans)
+		}
+		if len(sig) == 0 {
+			t.Fatalf("%s: have no samples", tc.name)
+		}
+		if sig[0] != tc.x {
+			t.Errorf("%s: have %v, want %v", tc.name, sig[0], tc.x)
+		}
+	}
+}
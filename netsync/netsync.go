@@ -0,0 +1,412 @@
+// Package netsync shares a snd.Transport between engines on a local network
+// over multicast UDP, so several machines, such as of rooms of an
+// installation or of performers, play from the same position and start
+// together, aligned to the frame within the precision of the network.
+//
+// One engine leads, holding the state of the transport shared; others follow,
+// measuring the offset of their clock to that of the leader by round trips
+// as of NTP, and setting their transports to where the leader's is as each
+// buffer is heard. Any engine may start, stop, or change tempo, followers by
+// asking the leader.
+package netsync // import "dasa.cc/snd/netsync"
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// DefaultGroup is a multicast group of the local network.
+const DefaultGroup = "239.255.77.77:7777"
+
+const (
+	// beacon is the interval the leader sends state and followers ping.
+	beacon = 200 * time.Millisecond
+
+	// samples is the number of round trips kept, the offset of the quickest
+	// taken as that least skewed by queueing.
+	samples = 8
+
+	// peerTimeout is how long a peer not heard from is counted.
+	peerTimeout = 2 * time.Second
+)
+
+// State is the state of a transport shared, playing from Beat at Time of the
+// leader's clock in nanoseconds, or stopped at Beat.
+type State struct {
+	BPM     snd.BPM `json:"bpm"`
+	Playing bool    `json:"playing"`
+	Beat    float64 `json:"beat"`
+	Time    int64   `json:"time"`
+	Version uint64  `json:"version"` // counts changes
+}
+
+// beatAt returns the beat at time t of the leader's clock.
+func (st State) beatAt(t int64) float64 {
+	if !st.Playing {
+		return st.Beat
+	}
+	return st.Beat + float64(t-st.Time)/float64(time.Minute)*float64(st.BPM)
+}
+
+// message is sent to the group, of kind "state" by the leader, "ping" and
+// "pong" of round trips, and "req" of changes followers ask of the leader.
+type message struct {
+	Kind  string `json:"kind"`
+	From  uint64 `json:"from"`
+	To    uint64 `json:"to,omitempty"`
+	State *State `json:"state,omitempty"`
+
+	// round trip sent at T1, received at T2 and answered at T3.
+	T1 int64 `json:"t1,omitempty"`
+	T2 int64 `json:"t2,omitempty"`
+	T3 int64 `json:"t3,omitempty"`
+
+	// change asked of the leader.
+	Op    string  `json:"op,omitempty"` // "start", "stop", or "bpm"
+	Beat  float64 `json:"beat,omitempty"`
+	After int64   `json:"after,omitempty"`
+	BPM   snd.BPM `json:"bpm,omitempty"`
+}
+
+// conn is a connection to the group, delivering messages sent to every
+// member including the sender.
+type conn interface {
+	ReadFrom(b []byte) (int, net.Addr, error)
+	WriteTo(b []byte, addr net.Addr) (int, error)
+	Close() error
+}
+
+// trip is a round trip measured.
+type trip struct{ offset, delay int64 }
+
+// Session is an engine of a session driving its transport. Session outputs
+// silence and must be part of a graph to be prepared, e.g. appended to a
+// mixer, as link.Link.
+//
+// On each prepare, the transport's tempo, play state, and position are set
+// from the session for the next buffer as heard. Transports play from the
+// buffer a start falls in, at beats before the start beat, so beats at and
+// after it fall on the same frames of every engine; thereafter they are
+// corrected once off by more than Tolerance, rather than every buffer, not
+// to follow jitter of timing of buffers. Changes made to the transport
+// directly are overridden.
+type Session struct {
+	// Latency is added to the time at which beats are computed to account
+	// for output buffering of the audio backend, as of snd.Playhead.
+	Latency time.Duration
+
+	// Tolerance is how far the transport may drift from the session before
+	// it is corrected, 1ms by default.
+	Tolerance time.Duration
+
+	tp     *snd.Transport
+	out    snd.Discrete
+	c      conn
+	group  net.Addr
+	id     uint64
+	leader bool
+	epoch  time.Time
+	clock  func() int64 // local clock in nanoseconds
+
+	mu       sync.Mutex
+	state    State
+	known    bool   // of state
+	leaderID uint64 // followed
+	trips    []trip
+	offset   int64 // leader's clock less local clock
+	delay    int64 // of the round trip of offset
+	peers    map[uint64]time.Time
+
+	quit chan struct{}
+	done sync.WaitGroup
+}
+
+// New returns Session of tp joined to the multicast group, such as
+// DefaultGroup, leading it if leader. Close must be called to leave the
+// session.
+func New(tp *snd.Transport, group string, leader bool) (*Session, error) {
+	addr, err := net.ResolveUDPAddr("udp4", group)
+	if err != nil {
+		return nil, err
+	}
+	c, err := net.ListenMulticastUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	return newSession(tp, c, addr, leader, nil)
+}
+
+// newSession returns Session over c to group, of clock if not nil.
+func newSession(tp *snd.Transport, c conn, group net.Addr, leader bool, clock func() int64) (*Session, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		c.Close()
+		return nil, err
+	}
+	s := &Session{
+		Tolerance: time.Millisecond,
+		tp:        tp,
+		out:       make(snd.Discrete, len(tp.Samples())),
+		c:         c,
+		group:     group,
+		id:        binary.BigEndian.Uint64(b[:]),
+		leader:    leader,
+		epoch:     time.Now(),
+		clock:     clock,
+		peers:     make(map[uint64]time.Time),
+		quit:      make(chan struct{}),
+	}
+	if s.clock == nil {
+		s.clock = func() int64 { return int64(time.Since(s.epoch)) }
+	}
+	if leader {
+		s.state = State{BPM: tp.BPM(), Playing: tp.Playing(), Beat: tp.Beat(), Time: s.clock()}
+		s.known, s.leaderID = true, s.id
+	}
+	s.done.Add(2)
+	go s.read()
+	go s.tick()
+	return s, nil
+}
+
+// Close leaves the session.
+func (s *Session) Close() error {
+	close(s.quit)
+	err := s.c.Close()
+	s.done.Wait()
+	return err
+}
+
+// Leader reports whether s leads the session.
+func (s *Session) Leader() bool { return s.leader }
+
+// Synced reports whether s knows the state of the session and the offset to
+// the leader's clock, always true of the leader.
+func (s *Session) Synced() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.synced()
+}
+
+func (s *Session) synced() bool { return s.leader || s.known && len(s.trips) != 0 }
+
+// Offset returns the offset of the leader's clock to the local clock, and
+// the round trip measuring it, bounding its error to half of it.
+func (s *Session) Offset() (offset, rtt time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Duration(s.offset), time.Duration(s.delay)
+}
+
+// State returns the state of the session as last known.
+func (s *Session) State() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Peers returns the number of followers heard from in the last 2 seconds, of
+// the leader, or 1 of a follower hearing the leader.
+func (s *Session) Peers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for id, t := range s.peers {
+		if time.Since(t) < peerTimeout {
+			n++
+		} else {
+			delete(s.peers, id)
+		}
+	}
+	return n
+}
+
+// Start plays from beat after a delay, such as 200ms, longer than the
+// latency of the network and of output so every engine starts on time. A
+// follower asks the leader, the delay counted from when the leader hears it.
+func (s *Session) Start(beat float64, after time.Duration) {
+	s.change(message{Op: "start", Beat: beat, After: int64(after)})
+}
+
+// Stop stops at once.
+func (s *Session) Stop() { s.change(message{Op: "stop"}) }
+
+// SetBPM changes tempo at once, keeping the beat.
+func (s *Session) SetBPM(bpm snd.BPM) { s.change(message{Op: "bpm", BPM: bpm}) }
+
+// change changes state as of op of m, or asks the leader to.
+func (s *Session) change(m message) {
+	if !s.leader {
+		s.mu.Lock()
+		m.Kind, m.To = "req", s.leaderID
+		s.mu.Unlock()
+		s.send(m)
+		return
+	}
+	s.mu.Lock()
+	now := s.clock()
+	st := s.state
+	beat := st.beatAt(now)
+	switch m.Op {
+	case "start":
+		st.Playing, st.Beat, st.Time = true, m.Beat, now+m.After
+	case "stop":
+		st.Playing, st.Beat, st.Time = false, beat, now
+	case "bpm":
+		st.BPM, st.Beat, st.Time = m.BPM, beat, now
+	default:
+		s.mu.Unlock()
+		return
+	}
+	st.Version++
+	s.state = st
+	s.mu.Unlock()
+	s.send(message{Kind: "state", State: &st})
+}
+
+func (s *Session) send(m message) {
+	m.From = s.id
+	b, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	s.c.WriteTo(b, s.group)
+}
+
+// tick sends state of the leader, or pings of a follower, at each beacon.
+func (s *Session) tick() {
+	defer s.done.Done()
+	t := time.NewTicker(beacon)
+	defer t.Stop()
+	for {
+		s.mu.Lock()
+		st, to := s.state, s.leaderID
+		s.mu.Unlock()
+		if s.leader {
+			s.send(message{Kind: "state", State: &st})
+		} else if to != 0 {
+			s.send(message{Kind: "ping", To: to, T1: s.clock()})
+		}
+		select {
+		case <-t.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Session) read() {
+	defer s.done.Done()
+	buf := make([]byte, 1<<12)
+	for {
+		n, _, err := s.c.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.quit:
+				return
+			default:
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		var m message
+		if json.Unmarshal(buf[:n], &m) != nil || m.From == s.id || (m.To != 0 && m.To != s.id) {
+			continue
+		}
+		s.handle(m, s.clock())
+	}
+}
+
+// handle handles m received at local time now.
+func (s *Session) handle(m message, now int64) {
+	switch {
+	case s.leader && m.Kind == "ping":
+		s.mu.Lock()
+		s.peers[m.From] = time.Now()
+		s.mu.Unlock()
+		s.send(message{Kind: "pong", To: m.From, T1: m.T1, T2: now, T3: s.clock()})
+	case s.leader && m.Kind == "req":
+		s.change(m)
+	case !s.leader && m.Kind == "state" && m.State != nil:
+		s.mu.Lock()
+		if m.From != s.leaderID {
+			// a leader new to us, whose clock is not ours.
+			s.leaderID, s.trips = m.From, s.trips[:0]
+			s.send(message{Kind: "ping", To: m.From, T1: s.clock()})
+		}
+		s.state, s.known = *m.State, true
+		s.peers = map[uint64]time.Time{m.From: time.Now()}
+		s.mu.Unlock()
+	case !s.leader && m.Kind == "pong":
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if m.From != s.leaderID {
+			return
+		}
+		tr := trip{offset: ((m.T2 - m.T1) + (m.T3 - now)) / 2, delay: (now - m.T1) - (m.T3 - m.T2)}
+		if len(s.trips) == samples {
+			s.trips = append(s.trips[:0], s.trips[1:]...)
+		}
+		s.trips = append(s.trips, tr)
+		best := s.trips[0]
+		for _, tr := range s.trips {
+			if tr.delay < best.delay {
+				best = tr
+			}
+		}
+		s.offset, s.delay = best.offset, best.delay
+	}
+}
+
+func (s *Session) Channels() int            { return 1 }
+func (s *Session) SampleRate() float64      { return s.tp.SampleRate() }
+func (s *Session) Inputs() []snd.Sound      { return []snd.Sound{s.tp} }
+func (s *Session) Samples() snd.Discrete    { return s.out }
+func (s *Session) Interp(t float64) float64 { return 0 }
+func (s *Session) At(t float64) float64     { return 0 }
+func (s *Session) Index(i int) float64      { return 0 }
+
+func (s *Session) Prepare(uint64) {
+	s.mu.Lock()
+	st, offset, ok := s.state, s.offset, s.synced()
+	s.mu.Unlock()
+	if !ok {
+		return
+	}
+	sr := s.tp.SampleRate()
+	buf := snd.Ftod(len(s.out), sr)
+
+	// the next buffer as heard, by the leader's clock.
+	at := s.clock() + offset + int64(buf+s.Latency)
+	if s.tp.BPM() != st.BPM {
+		s.tp.SetBPM(st.BPM)
+	}
+	switch {
+	case !st.Playing:
+		if s.tp.Playing() || s.tp.Beat() != st.Beat {
+			s.tp.Stop()
+			s.tp.Seek(st.Beat)
+		}
+	case at+int64(buf) <= st.Time:
+		// start not yet in the next buffer.
+		if s.tp.Playing() {
+			s.tp.Stop()
+		}
+	default:
+		beat := st.beatAt(at)
+		tol := s.Tolerance.Minutes() * float64(st.BPM)
+		if !s.tp.Playing() || math.Abs(s.tp.Beat()-beat) > tol {
+			s.tp.Seek(beat)
+		}
+		s.tp.Play()
+	}
+}
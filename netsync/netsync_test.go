@@ -0,0 +1,125 @@
+package netsync
+
+import (
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// bus is a group delivering messages to every end, as multicast does.
+type bus struct {
+	mu   sync.Mutex
+	ends []*end
+}
+
+type end struct {
+	b      *bus
+	c      chan []byte
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (b *bus) join() *end {
+	e := &end{b: b, c: make(chan []byte, 64), closed: make(chan struct{})}
+	b.mu.Lock()
+	b.ends = append(b.ends, e)
+	b.mu.Unlock()
+	return e
+}
+
+func (e *end) ReadFrom(p []byte) (int, net.Addr, error) {
+	select {
+	case m := <-e.c:
+		return copy(p, m), nil, nil
+	case <-e.closed:
+		return 0, nil, net.ErrClosed
+	}
+}
+
+func (e *end) WriteTo(p []byte, addr net.Addr) (int, error) {
+	e.b.mu.Lock()
+	defer e.b.mu.Unlock()
+	for _, x := range e.b.ends {
+		select {
+		case x.c <- append([]byte(nil), p...):
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+func (e *end) Close() error {
+	e.once.Do(func() { close(e.closed) })
+	return nil
+}
+
+// wait waits up to 2 seconds for cond.
+func wait(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("have not %s", what)
+		}
+	}
+}
+
+func TestSession(t *testing.T) {
+	var b bus
+	epoch := time.Now()
+	ltp, ftp := snd.NewTransport(120), snd.NewTransport(90)
+	ld, err := newSession(ltp, b.join(), nil, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ld.Close()
+	// the follower's clock is 3 seconds ahead.
+	fl, err := newSession(ftp, b.join(), nil, false, func() int64 { return int64(time.Since(epoch) + 3*time.Second) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fl.Close()
+
+	wait(t, "synced", fl.Synced)
+	if off, rtt := fl.Offset(); math.Abs(float64(off+3*time.Second)) > float64(5*time.Millisecond) || rtt < 0 {
+		t.Errorf("have offset %v of rtt %v, want -3s", off, rtt)
+	}
+	wait(t, "peers", func() bool { return ld.Peers() == 1 })
+
+	prepare := func() {
+		for _, s := range []*Session{ld, fl} {
+			s.tp.Prepare(0)
+			s.Prepare(0)
+		}
+	}
+	prepare()
+	if ftp.BPM() != 120 || ftp.Playing() {
+		t.Errorf("have follower at %v playing %v, want 120 stopped", ftp.BPM(), ftp.Playing())
+	}
+
+	ld.Start(0, 50*time.Millisecond)
+	wait(t, "started", func() bool { return fl.State().Playing })
+	for start := time.Now(); time.Since(start) < 150*time.Millisecond; time.Sleep(time.Millisecond) {
+		prepare()
+	}
+	if !ltp.Playing() || !ftp.Playing() {
+		t.Fatalf("have playing %v %v, want both", ltp.Playing(), ftp.Playing())
+	}
+	// within a millisecond and the time between preparing each.
+	if d := math.Abs(ltp.Beat() - ftp.Beat()); d > 0.005 || ltp.Beat() <= 0 {
+		t.Errorf("have beats %v and %v, want aligned", ltp.Beat(), ftp.Beat())
+	}
+
+	// followers ask the leader.
+	fl.SetBPM(100)
+	wait(t, "tempo changed", func() bool { return fl.State().BPM == 100 })
+	fl.Stop()
+	wait(t, "stopped", func() bool { return !ld.State().Playing && !fl.State().Playing })
+	prepare()
+	if ltp.Playing() || ftp.Playing() || ltp.Beat() != ftp.Beat() {
+		t.Errorf("have playing %v %v at %v %v, want stopped together", ltp.Playing(), ftp.Playing(), ltp.Beat(), ftp.Beat())
+	}
+}
@@ -1,6 +1,10 @@
 package snd
 
-import "math"
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
 
 const DefaultNotesLen = 128
 
@@ -34,3 +38,124 @@ func EqualTempermant(tones int, freq float64, pos int) Notes {
 	ns.Eval(tones, freq, pos, EqualTempermantFunc)
 	return ns
 }
+
+// ConcertPitch returns the frequency of A4 that sounds prepared by dp tune
+// keys to, 440Hz unless set otherwise.
+func (dp *Dispatcher) ConcertPitch() float64 { return dp.settings().concertPitch() }
+
+// SetConcertPitch tunes keys of sounds prepared by dp to A4 at hz, e.g. 432
+// or 442, leaving other Dispatchers, such as of other engines, as they are.
+// It is safe to call while playing; Poly and Mono glide sounding notes of a
+// LegatoVoice to the new tuning.
+func (dp *Dispatcher) SetConcertPitch(hz float64) {
+	atomic.StoreUint64(&dp.settings().pitch, math.Float64bits(hz))
+}
+
+// KeyFreq returns the equal tempered frequency of MIDI key number key,
+// where key 69 is A4 at 440Hz.
+func KeyFreq(key int) float64 { return keyfreq(key, 440) }
+
+// keyfreq returns the frequency of key with A4 at ref.
+func keyfreq(key int, ref float64) float64 {
+	return ref * math.Pow(2, float64(key-69)/12)
+}
+
+// KeyOf returns the fractional MIDI key number of frequency hz, the inverse
+// of KeyFreq.
+func KeyOf(hz float64) float64 { return keyof(hz, 440) }
+
+// keyof returns the fractional key of hz with A4 at ref.
+func keyof(hz, ref float64) float64 {
+	return 69 + 12*math.Log2(hz/ref)
+}
+
+// retune glides a reference toward the concert pitch of a Dispatcher, so
+// notes may follow a change of tuning without a jump in pitch.
+type retune struct {
+	ref     float64
+	started bool // once prepared, before which ref jumps
+}
+
+func newretune() retune { return retune{ref: 440} }
+
+// next advances the glide toward want by frames of sr and reports whether
+// ref changed.
+func (rt *retune) next(want float64, frames int, sr float64) bool {
+	if rt.ref == want {
+		rt.started = true
+		return false
+	}
+	if !rt.started {
+		rt.ref, rt.started = want, true
+		return true
+	}
+	const glide = 50 * time.Millisecond
+	rt.ref += (1 - math.Exp(-float64(frames)/(glide.Seconds()*sr))) * (want - rt.ref)
+	if math.Abs(want-rt.ref) < 1e-3 {
+		rt.ref = want
+	}
+	return true
+}
+
+// Mode is a musical scale given as ascending semitones from its root within
+// an octave, starting with 0.
+type Mode []int
+
+var (
+	ModeMajor         = Mode{0, 2, 4, 5, 7, 9, 11}
+	ModeMinor         = Mode{0, 2, 3, 5, 7, 8, 10}
+	ModeHarmonicMinor = Mode{0, 2, 3, 5, 7, 8, 11}
+	ModeDorian        = Mode{0, 2, 3, 5, 7, 9, 10}
+	ModeMixolydian    = Mode{0, 2, 4, 5, 7, 9, 10}
+	ModePentatonic    = Mode{0, 2, 4, 7, 9}
+	ModeChromatic     = Mode{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+)
+
+// locate returns octave and index in md of the nearest key of md at or
+// below key, for md rooted at root.
+func (md Mode) locate(root, key int) (oct, idx int) {
+	rel := key - root
+	oct = rel / 12
+	if rel%12 < 0 {
+		oct--
+	}
+	pc := rel - oct*12
+	for i, x := range md {
+		if x <= pc {
+			idx = i
+		}
+	}
+	return oct, idx
+}
+
+// Snap returns key of md rooted at root nearest to key, preferring the
+// lower of two equally near.
+func (md Mode) Snap(root, key int) int { return md.Nearest(root, float64(key)) }
+
+// Nearest returns key of md rooted at root nearest to a fractional key, such
+// as one found by KeyOf, preferring the lower of two equally near.
+func (md Mode) Nearest(root int, key float64) int {
+	oct, idx := md.locate(root, int(math.Floor(key)))
+	lo := root + oct*12 + md[idx]
+	hi := root + (oct+1)*12 + md[0]
+	if idx+1 < len(md) {
+		hi = root + oct*12 + md[idx+1]
+	}
+	if float64(hi)-key < key-float64(lo) {
+		return hi
+	}
+	return lo
+}
+
+// Transpose returns key snapped to md rooted at root and then moved by a
+// number of scale degrees, e.g. 2 for a diatonic third or -3 for a fourth below.
+func (md Mode) Transpose(root, key, degrees int) int {
+	oct, idx := md.locate(root, md.Snap(root, key))
+	idx += degrees
+	oct += idx / len(md)
+	if idx %= len(md); idx < 0 {
+		idx += len(md)
+		oct--
+	}
+	return root + oct*12 + md[idx]
+}
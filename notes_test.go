@@ -28,3 +28,30 @@ func TestNotes(t *testing.T) {
 		}
 	}
 }
+
+func TestMode(t *testing.T) {
+	tests := []struct {
+		root    int
+		mode    Mode
+		key     int
+		degrees int
+		want    int
+	}{
+		{60, ModeMajor, 60, 2, 64},  // C to E
+		{60, ModeMajor, 64, 2, 67},  // E to G
+		{60, ModeMajor, 71, 1, 72},  // B to C, next octave
+		{60, ModeMajor, 55, 2, 59},  // G below root to B
+		{60, ModeMajor, 61, 0, 60},  // C# snaps down to C
+		{60, ModeMajor, 60, -2, 57}, // C to A below
+		{57, ModeMinor, 55, 4, 62},  // G to D in A minor
+		{60, ModePentatonic, 62, 3, 69},
+	}
+	for _, tt := range tests {
+		if have := tt.mode.Transpose(tt.root, tt.key, tt.degrees); have != tt.want {
+			t.Errorf("transpose %v by %v from root %v: have %v, want %v", tt.key, tt.degrees, tt.root, have, tt.want)
+		}
+	}
+	if have := KeyOf(KeyFreq(57)); !equaleps(have, 57, 1e-9) {
+		t.Errorf("have key %v, want 57", have)
+	}
+}
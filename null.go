@@ -0,0 +1,86 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"dasa.cc/snd/wav"
+)
+
+// Null is the difference of two renders of equal length, such as of a graph
+// before and after a refactor that should not change its output. Identical
+// renders null to a Peak and RMS of negative infinity.
+type Null struct {
+	Peak   Decibel // largest absolute difference
+	RMS    Decibel // root mean square of the difference
+	At     int     // frame of the largest difference
+	Frames int
+}
+
+// Transparent reports whether the difference peaks no higher than floor,
+// such as -120dB for float rounding only.
+func (nl Null) Transparent(floor Decibel) bool { return nl.Peak <= floor }
+
+func (nl Null) String() string {
+	return fmt.Sprintf("peak %.1fdB at frame %v, rms %.1fdB over %v frames", nl.Peak, nl.At, nl.RMS, nl.Frames)
+}
+
+// NullDiff subtracts interleaved samples b of chans channels from a, which
+// must be of the same length, and returns the difference.
+func NullDiff(a, b Discrete, chans int) Null {
+	if len(a) != len(b) {
+		panic(fmt.Errorf("snd: null of %v samples against %v", len(a), len(b)))
+	}
+	var peak, sum float64
+	var at int
+	for i, x := range a {
+		d := math.Abs(x - b[i])
+		if d > peak {
+			peak, at = d, i/chans
+		}
+		sum += d * d
+	}
+	nl := Null{Peak: DecibelOf(peak), RMS: Decibel(math.Inf(-1)), At: at, Frames: len(a) / chans}
+	if len(a) != 0 {
+		nl.RMS = DecibelOf(math.Sqrt(sum / float64(len(a))))
+	}
+	return nl
+}
+
+// NullTest renders a and b offline for n frames and returns their difference.
+// Graphs must be of the same channels and must not share sounds, as each is
+// rendered in turn from its current state, so fresh graphs are compared.
+func NullTest(a, b Sound, n int) Null {
+	if a.Channels() != b.Channels() {
+		panic(fmt.Errorf("snd: null of %v channels against %v", a.Channels(), b.Channels()))
+	}
+	return NullDiff(Render(a, n), Render(b, n), a.Channels())
+}
+
+// NullFile renders sd offline for the length of the WAVE file of name, a
+// golden render such as written by Bounce.WriteFile, and returns their
+// difference. Files of 32 bit float null an unchanged graph; integer files
+// leave a difference of their depth.
+func NullFile(sd Sound, name string) (Null, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Null{}, err
+	}
+	defer f.Close()
+	want, format, err := wav.Decode(f)
+	if err != nil {
+		return Null{}, fmt.Errorf("snd: %s: %v", name, err)
+	}
+	if format.Chans != sd.Channels() || float64(format.Rate) != sd.SampleRate() {
+		return Null{}, fmt.Errorf("snd: %s: %v channels at %vHz, sound has %v at %vHz",
+			name, format.Chans, format.Rate, sd.Channels(), sd.SampleRate())
+	}
+	have := Render(sd, len(want)/format.Chans)
+	if format.Float && format.Depth == 32 {
+		for i, x := range have {
+			have[i] = float64(float32(x))
+		}
+	}
+	return NullDiff(have, want, format.Chans), nil
+}
@@ -0,0 +1,53 @@
+package snd
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNullTest(t *testing.T) {
+	a := NewGain(0.5, NewOscil(Sine(), 440, nil))
+	b := NewGain(0.5, NewOscil(Sine(), 440, nil))
+	nl := NullTest(a, b, 4096)
+	if !math.IsInf(float64(nl.Peak), -1) || !math.IsInf(float64(nl.RMS), -1) {
+		t.Fatalf("have %v of identical graphs, want nulled", nl)
+	}
+	if nl.Frames != 4096 {
+		t.Fatalf("have %v frames, want 4096", nl.Frames)
+	}
+
+	// half a dB louder leaves the tone about 25dB down.
+	a = NewGain(0.5, NewOscil(Sine(), 440, nil))
+	c := NewGain(0.5*Decibel(0.5).Amp(), NewOscil(Sine(), 440, nil))
+	nl = NullTest(a, c, 4096)
+	if nl.Transparent(-120) {
+		t.Fatalf("have %v of louder graph, want not transparent", nl)
+	}
+	if want := DecibelOf(0.5 * (Decibel(0.5).Amp() - 1)); !equaleps(float64(nl.Peak), float64(want), 0.01) {
+		t.Fatalf("have peak %v, want %v", nl.Peak, want)
+	}
+}
+
+func TestNullFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "golden.wav")
+	osc := NewOscil(Sine(), 440, nil)
+	bc := Bounce{Sound: osc, Dur: 100 * time.Millisecond}
+	if err := bc.WriteFile(name, 32); err != nil {
+		t.Fatal(err)
+	}
+	nl, err := NullFile(NewOscil(Sine(), 440, nil), name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !nl.Transparent(-300) {
+		t.Fatalf("have %v against golden file, want nulled", nl)
+	}
+	if nl, _ = NullFile(NewOscil(Sine(), 441, nil), name); nl.Transparent(-60) {
+		t.Fatalf("have %v of detuned against golden file, want not transparent", nl)
+	}
+	if _, err := NullFile(NewPan(0, osc), name); err == nil {
+		t.Fatal("have nil error of stereo against mono file")
+	}
+}
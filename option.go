@@ -0,0 +1,196 @@
+package snd
+
+import "time"
+
+// Option configures a sound created by an option constructor, named for the
+// type it returns followed by With, such as NewReverbWith. Option
+// constructors are offered for the common building blocks Oscil, ADSR, Gain,
+// LowPass, Pan, Delay, Comb, Mixer and Reverb, defaulting what is not given;
+// other sounds are created by their positional constructors only. Options not
+// relevant to a constructor are ignored.
+type Option func(*options)
+
+type options struct {
+	in  Sound
+	off bool
+
+	harm     Discrete
+	freq     float64
+	freqmod  Sound
+	amp      float64
+	ampmod   Sound
+	phasemod Sound
+
+	attack, decay, sustain, release time.Duration
+	susamp, maxamp                  float64
+
+	ins        []Sound
+	pan        float64
+	delay      time.Duration
+	feedback   float64
+	size, damp float64
+	mix        float64
+}
+
+// defaults for all option constructors.
+func newoptions(opts []Option) *options {
+	ms := time.Millisecond
+	o := &options{
+		harm:   fundamental,
+		freq:   440,
+		amp:    1,
+		attack: 10 * ms, decay: 100 * ms, sustain: 200 * ms, release: 300 * ms,
+		susamp: 0.5, maxamp: 1,
+		delay: 250 * ms, feedback: 0.5,
+		size: 0.5, damp: 0.5, mix: 0.5,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithIn sets the input of a sound.
+func WithIn(in Sound) Option { return func(o *options) { o.in = in } }
+
+// WithOff creates a sound turned off.
+func WithOff() Option { return func(o *options) { o.off = true } }
+
+// WithHarm sets the signal an oscillator samples; default Sine.
+func WithHarm(sig Discrete) Option { return func(o *options) { o.harm = sig } }
+
+// WithFreq sets frequency in Hz; default 440.
+func WithFreq(hz float64) Option { return func(o *options) { o.freq = hz } }
+
+// WithFreqMod sets a frequency modulator.
+func WithFreqMod(mod Sound) Option { return func(o *options) { o.freqmod = mod } }
+
+// WithAmp sets amplitude multiplier; default 1.
+func WithAmp(fac float64) Option { return func(o *options) { o.amp = fac } }
+
+// WithAmpMod sets an amplitude modulator.
+func WithAmpMod(mod Sound) Option { return func(o *options) { o.ampmod = mod } }
+
+// WithPhaseMod sets a phase modulator.
+func WithPhaseMod(mod Sound) Option { return func(o *options) { o.phasemod = mod } }
+
+// WithADSR sets envelope periods; default 10ms, 100ms, 200ms, 300ms.
+func WithADSR(attack, decay, sustain, release time.Duration) Option {
+	return func(o *options) {
+		o.attack, o.decay, o.sustain, o.release = attack, decay, sustain, release
+	}
+}
+
+// WithLevels sets envelope sustain and max amplitudes; default 0.5 and 1.
+func WithLevels(susamp, maxamp float64) Option {
+	return func(o *options) { o.susamp, o.maxamp = susamp, maxamp }
+}
+
+// WithIns appends inputs of a Mixer.
+func WithIns(ins ...Sound) Option { return func(o *options) { o.ins = append(o.ins, ins...) } }
+
+// WithPan sets the position of a Pan belonging to [-1..1]; default 0, center.
+func WithPan(xf float64) Option { return func(o *options) { o.pan = xf } }
+
+// WithDelay sets the time of a Delay or Comb; default 250ms.
+func WithDelay(d time.Duration) Option { return func(o *options) { o.delay = d } }
+
+// WithFeedback sets the gain fed back by a Comb; default 0.5.
+func WithFeedback(gain float64) Option { return func(o *options) { o.feedback = gain } }
+
+// WithRoom sets room size and damping of a Reverb, both belonging to [0..1];
+// default 0.5 and 0.5.
+func WithRoom(size, damp float64) Option { return func(o *options) { o.size, o.damp = size, damp } }
+
+// WithMix sets the level of wet signal relative to dry of a Reverb, where 0
+// is dry only and 1 is wet only; default 0.5.
+func WithMix(x float64) Option { return func(o *options) { o.mix = x } }
+
+// NewOsc returns an Oscil configured by opts.
+//
+//  osc := NewOscilWith(WithHarm(Sawtooth()), WithFreq(220), WithAmp(Decibel(-10).Amp()))
+func NewOscilWith(opts ...Option) *Oscil {
+	o := newoptions(opts)
+	osc := NewOscil(o.harm, o.freq, o.freqmod)
+	osc.SetAmp(o.amp, o.ampmod)
+	osc.SetPhase(o.phasemod)
+	osc.off = o.off
+	return osc
+}
+
+// NewEnv returns an ADSR configured by opts.
+func NewADSRWith(opts ...Option) *ADSR {
+	o := newoptions(opts)
+	adsr := NewADSR(o.attack, o.decay, o.sustain, o.release, o.susamp, o.maxamp, o.in)
+	adsr.off = o.off
+	return adsr
+}
+
+// NewGainWith returns Gain of the input of WithIn, required, by the factor of
+// WithAmp, configured by opts.
+func NewGainWith(opts ...Option) *Gain {
+	o := newoptions(opts)
+	gn := NewGain(o.amp, o.in)
+	gn.off = o.off
+	return gn
+}
+
+// NewLowPassWith returns LowPass of the input of WithIn, required, at the
+// cutoff of WithFreq, configured by opts.
+func NewLowPassWith(opts ...Option) *LowPass {
+	o := newoptions(opts)
+	lp := NewLowPass(o.freq, o.in)
+	lp.off = o.off
+	return lp
+}
+
+// NewPanWith returns Pan of the input of WithIn, required, configured by opts.
+func NewPanWith(opts ...Option) *Pan {
+	o := newoptions(opts)
+	pan := NewPan(o.pan, o.in)
+	pan.l.off, pan.r.off = o.off, o.off
+	return pan
+}
+
+// NewDelayWith returns Delay of the input of WithIn, required, configured by
+// opts.
+func NewDelayWith(opts ...Option) *Delay {
+	o := newoptions(opts)
+	dly := NewDelay(o.delay, o.in)
+	dly.off = o.off
+	return dly
+}
+
+// NewCombWith returns Comb of the input of WithIn, required, configured by
+// opts.
+func NewCombWith(opts ...Option) *Comb {
+	o := newoptions(opts)
+	cmb := NewComb(o.feedback, o.delay, o.in)
+	cmb.off = o.off
+	return cmb
+}
+
+// NewMixerWith returns Mixer of the inputs of WithIn and WithIns, configured
+// by opts.
+func NewMixerWith(opts ...Option) *Mixer {
+	o := newoptions(opts)
+	ins := o.ins
+	if o.in != nil {
+		ins = append([]Sound{o.in}, ins...)
+	}
+	mix := NewMixer(ins...)
+	mix.off = o.off
+	return mix
+}
+
+// NewReverbWith returns Reverb of the input of WithIn, required, configured
+// by opts.
+//
+//  rv := NewReverbWith(WithIn(osc), WithRoom(0.8, 0.2), WithMix(0.3))
+func NewReverbWith(opts ...Option) *Reverb {
+	o := newoptions(opts)
+	rv := NewReverb(o.size, o.damp, o.in)
+	rv.SetMix(o.mix)
+	rv.off = o.off
+	return rv
+}
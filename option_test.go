@@ -0,0 +1,50 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewOscilWith(t *testing.T) {
+	mod := NewOscil(Sine(), 2, nil)
+	osc := NewOscilWith(WithHarm(Sawtooth()), WithFreq(220), WithAmpMod(mod), WithOff())
+	if osc.Freq() != 220 || osc.Amp() != 1 || osc.ampmod != mod || !osc.IsOff() {
+		t.Fatalf("options not applied %+v", osc)
+	}
+	if osc := NewOscilWith(); osc.Freq() != 440 {
+		t.Fatalf("default freq have %v, want 440", osc.Freq())
+	}
+}
+
+func TestNewADSRWith(t *testing.T) {
+	ms := time.Millisecond
+	env := NewADSRWith(WithIn(newunit()), WithADSR(ms, ms, ms, ms), WithLevels(0.3, 0.9))
+	if env.in == nil || env.susamp != 0.3 || env.maxamp != 0.9 || env.Dur() > 5*ms {
+		t.Fatalf("options not applied %+v", env)
+	}
+}
+
+func TestOptionEffects(t *testing.T) {
+	osc := NewOscilWith()
+	if gn := NewGainWith(WithIn(osc), WithAmp(0.5)); gn.Amp() != 0.5 || gn.in != osc {
+		t.Errorf("gain options not applied %+v", gn)
+	}
+	if lp := NewLowPassWith(WithIn(osc), WithFreq(1000), WithOff()); lp.Freq() != 1000 || !lp.IsOff() {
+		t.Errorf("low pass options not applied %+v", lp)
+	}
+	if pan := NewPanWith(WithIn(osc), WithPan(-1)); pan.xf != -1 || pan.Channels() != 2 {
+		t.Errorf("pan options not applied %+v", pan)
+	}
+	if dly := NewDelayWith(WithIn(osc), WithDelay(10*time.Millisecond)); len(dly.line.xs) != 441 {
+		t.Errorf("have delay of %v frames, want 441", len(dly.line.xs))
+	}
+	if cmb := NewCombWith(WithIn(osc), WithFeedback(0.3)); cmb.Gain() != 0.3 || len(cmb.line.xs) != Dtof(250*time.Millisecond, DefaultSampleRate) {
+		t.Errorf("comb options not applied %+v", cmb)
+	}
+	if mix := NewMixerWith(WithIn(osc), WithIns(NewOscilWith(), NewOscilWith())); len(mix.ins) != 3 || mix.ins[0] != osc {
+		t.Errorf("have mixer of %v inputs, want 3", len(mix.ins))
+	}
+	if rv := NewReverbWith(WithIn(osc), WithRoom(0.8, 0.2), WithMix(0.3)); rv.Size() != 0.8 || rv.Damp() != 0.2 || rv.Mix() != 0.3 {
+		t.Errorf("reverb options not applied %+v", rv)
+	}
+}
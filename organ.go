@@ -0,0 +1,330 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// drawbars are harmonics of the fundamental sounded by each drawbar of a
+// tonewheel organ, from the 16' sub octave to 1'.
+var drawbars = [9]float64{0.5, 1.5, 1, 2, 3, 4, 5, 6, 8}
+
+// Registration is the setting of nine drawbars from 16' to 1', each belonging
+// to [0..8] where 8 is fully out.
+type Registration [9]int
+
+// Common registrations.
+var (
+	RegistrationFull   = Registration{8, 8, 8, 8, 8, 8, 8, 8, 8}
+	RegistrationJazz   = Registration{8, 8, 8, 0, 0, 0, 0, 0, 0}
+	RegistrationGospel = Registration{8, 8, 8, 8, 0, 0, 0, 0, 8}
+	RegistrationFlute  = Registration{0, 0, 8, 4, 0, 0, 0, 0, 0}
+)
+
+// wave writes the sum of sines of reg to sig over a cycle of the 16' drawbar.
+// Three drawbars fully out reach about full level and more are louder still,
+// as on the original.
+func (reg Registration) wave(sig Discrete) {
+	for i := range sig {
+		t := float64(i) / float64(len(sig))
+		sig[i] = 0
+		for j, lvl := range reg {
+			sig[i] += float64(lvl) / 8 / 3 * SineFunc(2*drawbars[j]*t)
+		}
+	}
+}
+
+// Organ is a tonewheel organ of additive voices set by drawbars, with
+// percussion, key click, and a Rotary speaker. Output is stereo.
+//
+// Percussion sounds a decaying harmonic only on notes struck while no other
+// key is held, as on the original, so it accents the first note of a phrase
+// played legato.
+type Organ struct {
+	*mono
+	poly  *Poly
+	rot   *Rotary
+	reg   Registration
+	table Discrete
+	held  map[int]bool
+
+	percharm  float64 // harmonic of percussion, zero if off
+	percdecay time.Duration
+	perc      bool // percussion sounds on next note
+	click     float64
+}
+
+// NewOrgan returns Organ of voices with jazz registration, third harmonic
+// percussion, and the Rotary slow.
+func NewOrgan(voices int) *Organ {
+	org := &Organ{
+		reg:       RegistrationJazz,
+		table:     make(Discrete, 4096),
+		held:      make(map[int]bool),
+		percharm:  3,
+		percdecay: 300 * time.Millisecond,
+		click:     0.3,
+	}
+	org.reg.wave(org.table)
+	org.poly = NewPoly(voices, func() Voice { return newOrganVoice(org) })
+	org.poly.SetGain(VoiceGain(voices, CrestSine, DefaultHeadroom))
+	org.rot = NewRotary(org.poly)
+	org.mono = newmono(org.rot)
+	org.out = make(Discrete, len(org.rot.Samples()))
+	return org
+}
+
+func (org *Organ) Channels() int { return 2 }
+
+// Rotary returns the speaker of org.
+func (org *Organ) Rotary() *Rotary { return org.rot }
+
+func (org *Organ) Drawbars() Registration { return org.reg }
+
+// SetDrawbars sets drawbars, changing notes already sounding.
+func (org *Organ) SetDrawbars(reg Registration) {
+	for i, lvl := range reg {
+		if lvl < 0 {
+			reg[i] = 0
+		} else if lvl > 8 {
+			reg[i] = 8
+		}
+	}
+	org.reg = reg
+	org.reg.wave(org.table)
+}
+
+// Percussion returns the harmonic of percussion, zero if off, and its decay.
+func (org *Organ) Percussion() (harmonic int, decay time.Duration) {
+	return int(org.percharm), org.percdecay
+}
+
+// SetPercussion sets percussion to harmonic 2 or 3, or off if zero, decaying
+// over decay, such as 200ms for fast and 500ms for slow.
+func (org *Organ) SetPercussion(harmonic int, decay time.Duration) {
+	org.percharm, org.percdecay = float64(harmonic), decay
+}
+
+// Click returns the level of key click belonging to [0..1].
+func (org *Organ) Click() float64     { return org.click }
+func (org *Organ) SetClick(x float64) { org.click = x }
+
+// NoteOn plays key; velocity is ignored as on a tonewheel organ.
+func (org *Organ) NoteOn(key int, vel float64) {
+	org.perc = len(org.held) == 0 && org.percharm > 0
+	org.held[key] = true
+	org.poly.NoteOn(key, 1)
+}
+
+func (org *Organ) NoteOff(key int) {
+	delete(org.held, key)
+	org.poly.NoteOff(key)
+}
+
+func (org *Organ) Pressure(x float64)     { org.poly.Pressure(x) }
+func (org *Organ) Bend(semitones float64) { org.poly.Bend(semitones) }
+
+func (org *Organ) Prepare(uint64) {
+	for i, x := range org.rot.Samples() {
+		if org.off {
+			x = 0
+		}
+		org.out[i] = x
+	}
+}
+
+// organVoice is a Voice of an Organ.
+type organVoice struct {
+	*mono
+	org   *Organ
+	tone  *OscVoice
+	perc  *OscVoice
+	decay time.Duration // of percussion envelope
+	rnd   *rand.Rand
+	click float64 // level of click sounding
+	cc    float64 // click decay coefficient
+}
+
+func newOrganVoice(org *Organ) *organVoice {
+	const ms = time.Millisecond
+	return &organVoice{
+		mono:  newmono(nil),
+		org:   org,
+		tone:  NewOscVoice(org.table, NewADSR(5*ms, ms, ms, 10*ms, 1, 1, nil)),
+		perc:  NewOscVoice(Sine(), NewADSR(ms, org.percdecay, ms, 50*ms, 0, 1, nil)),
+		decay: org.percdecay,
+		rnd:   rand.New(rand.NewSource(1)),
+		cc:    smoothcoef(2*ms, DefaultSampleRate),
+	}
+}
+
+func (vc *organVoice) Inputs() []Sound { return []Sound{vc.tone, vc.perc} }
+
+func (vc *organVoice) NoteOn(hz, vel float64) {
+	// the table holds a cycle of the 16' drawbar, an octave below.
+	vc.tone.NoteOn(hz/2, 1)
+	if vc.org.perc {
+		if vc.decay != vc.org.percdecay {
+			vc.decay = vc.org.percdecay
+			vc.perc.Env().Params()[1].Set(vc.decay.Seconds())
+		}
+		vc.perc.NoteOn(hz*vc.org.percharm, 0.5)
+	}
+	vc.click = vc.org.click
+}
+
+func (vc *organVoice) NoteOff() {
+	vc.tone.NoteOff()
+	vc.perc.NoteOff()
+	vc.click = vc.org.click / 2
+}
+
+func (vc *organVoice) Done() bool { return vc.tone.Done() && vc.perc.Done() && vc.click < 1e-4 }
+
+func (vc *organVoice) SetFreq(hz float64) {
+	vc.tone.SetFreq(hz / 2)
+	vc.perc.SetFreq(hz * vc.org.percharm)
+}
+
+func (vc *organVoice) Prepare(uint64) {
+	for i := range vc.out {
+		x := vc.tone.Index(i) + vc.perc.Index(i)
+		if vc.click > 1e-4 {
+			x += vc.click * (2*vc.rnd.Float64() - 1)
+			vc.click -= vc.cc * vc.click
+		}
+		if vc.off {
+			x = 0
+		}
+		vc.out[i] = x
+	}
+}
+
+// rotor is a rotating horn or drum of a Rotary, modulating amplitude and
+// delay, the latter heard as doppler shift.
+type rotor struct {
+	phase float64
+	rate  float64 // hz
+	slow  float64 // rates, hz
+	fast  float64
+	accel float64 // coefficient of change in rate
+	depth float64 // of amplitude
+	swing float64 // frames of delay either way
+	line  []float64
+	w     int
+	heads interpolators // left and right
+	l, r  float64       // output of each side
+}
+
+func newrotor(slow, fast float64, accel time.Duration, depth float64, swing time.Duration, sr float64) *rotor {
+	sw := Dtof(swing, sr)
+	return &rotor{
+		rate:  slow,
+		slow:  slow,
+		fast:  fast,
+		accel: smoothcoef(accel, sr),
+		depth: depth,
+		swing: float64(sw),
+		line:  make([]float64, 2*sw+4),
+		heads: newreaders(2),
+	}
+}
+
+// process passes x through the rotor, setting l and r as heard by
+// microphones on either side.
+func (rt *rotor) process(x float64, fast bool, sr float64) {
+	want := rt.slow
+	if fast {
+		want = rt.fast
+	}
+	rt.rate += rt.accel * (want - rt.rate)
+	rt.phase += rt.rate / sr
+	if rt.phase >= 1 {
+		rt.phase--
+	}
+	s, c := math.Sincos(twopi * rt.phase)
+
+	rt.line[rt.w] = x
+	// sides hear the rotor approach while the other hears it recede.
+	dl := rt.heads[0].read(rt.line, rt.w, 1+rt.swing*(1+s))
+	dr := rt.heads[1].read(rt.line, rt.w, 1+rt.swing*(1-s))
+	if rt.w++; rt.w == len(rt.line) {
+		rt.w = 0
+	}
+	rt.l = dl * (1 - rt.depth*(1+c)/2)
+	rt.r = dr * (1 - rt.depth*(1-c)/2)
+}
+
+// Rotary simulates a rotating speaker of a treble horn and bass drum, each
+// modulating amplitude and pitch as it turns, and switching between slow
+// chorale and fast tremolo speeds with the inertia of the real rotors. Input
+// is mono and output is stereo.
+type Rotary struct {
+	*mono
+	interpolators
+	lp   svf // crossover to the drum
+	horn *rotor
+	drum *rotor
+	fast bool
+	mix  float64
+}
+
+// NewRotary returns Rotary of in at slow speed, fully wet.
+func NewRotary(in Sound) *Rotary {
+	sd := newmono(in)
+	sd.out = make(Discrete, 2*len(in.Samples()))
+	rot := &Rotary{
+		mono: sd,
+		horn: newrotor(0.8, 6.7, 800*time.Millisecond, 0.5, 300*time.Microsecond, sd.sr),
+		drum: newrotor(0.7, 5.9, 3*time.Second, 0.3, 100*time.Microsecond, sd.sr),
+		mix:  1,
+	}
+	rot.interpolators = append(rot.horn.heads, rot.drum.heads...)
+	rot.lp.set(800, 0.707, sd.sr)
+	return rot
+}
+
+func (rot *Rotary) Channels() int { return 2 }
+
+// Fast reports whether rotors are set to tremolo speed, as opposed to chorale.
+func (rot *Rotary) Fast() bool { return rot.fast }
+
+// SetFast sets rotors to speed up to tremolo or slow down to chorale, taking
+// about a second for the horn and several for the drum.
+func (rot *Rotary) SetFast(b bool) { rot.fast = b }
+
+// Mix returns balance of dry and rotary where 0 is dry and 1 is only rotary.
+func (rot *Rotary) Mix() float64     { return rot.mix }
+func (rot *Rotary) SetMix(x float64) { rot.mix = x }
+
+// Params returns speed, zero for slow and one for fast, and mix.
+func (rot *Rotary) Params() []*Param {
+	return []*Param{
+		NewParam("fast",
+			func() float64 {
+				if rot.fast {
+					return 1
+				}
+				return 0
+			},
+			func(x float64) { rot.SetFast(x >= 0.5) }).Range(0, 1, 0),
+		NewParam("mix", rot.Mix, rot.SetMix).Range(0, 1, 1).In(UnitPercent),
+	}
+}
+
+func (rot *Rotary) Prepare(uint64) {
+	rot.limit(rot.st)
+	for i, x := range rot.in.Samples() {
+		lo := rot.lp.filter(x, FilterLowPass)
+		rot.horn.process(x-lo, rot.fast, rot.sr)
+		rot.drum.process(lo, rot.fast, rot.sr)
+		if rot.off {
+			rot.out[2*i], rot.out[2*i+1] = 0, 0
+			continue
+		}
+		dry := (1 - rot.mix) * onesqrt2 * x
+		rot.out[2*i] = dry + rot.mix*(rot.horn.l+rot.drum.l)
+		rot.out[2*i+1] = dry + rot.mix*(rot.horn.r+rot.drum.r)
+	}
+}
@@ -0,0 +1,55 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOrgan(t *testing.T) {
+	sr := DefaultSampleRate
+	org := NewOrgan(4)
+	org.Rotary().SetMix(0)
+	org.SetClick(0)
+	org.SetPercussion(0, 0)
+	org.SetDrawbars(Registration{0, 0, 8, 0, 0, 0, 0, 0, 0})
+	org.NoteOn(69, 1)
+	out := Render(org, Dtof(200*time.Millisecond, sr))
+	left := make(Discrete, len(out)/2)
+	for i := range left {
+		left[i] = out[2*i]
+	}
+	if a := goertzel(left[len(left)/2:], 440, sr); a < 0.02 {
+		t.Fatalf("have %v at 440Hz with 8' drawbar, want fundamental", a)
+	}
+	if a := goertzel(left[len(left)/2:], 220, sr); a > 1e-3 {
+		t.Fatalf("have %v at 220Hz with 16' drawbar in, want none", a)
+	}
+
+	org.SetDrawbars(Registration{8, 0, 0, 0, 0, 0, 0, 0, 0})
+	out = Render(org, Dtof(200*time.Millisecond, sr))
+	for i := range left {
+		left[i] = out[2*i]
+	}
+	if a := goertzel(left[len(left)/2:], 220, sr); a < 0.02 {
+		t.Fatalf("have %v at 220Hz with 16' drawbar out, want sub octave", a)
+	}
+}
+
+func TestRotary(t *testing.T) {
+	sr := DefaultSampleRate
+	rot := NewRotary(NewConst(1))
+	rot.SetFast(true)
+	out := Render(rot, Dtof(3*time.Second, sr))
+	lo, hi := 1.0, 0.0
+	for _, x := range out[len(out)/2:] {
+		if x < lo {
+			lo = x
+		}
+		if x > hi {
+			hi = x
+		}
+	}
+	if hi-lo < 0.1 {
+		t.Fatalf("have swing %v..%v, want amplitude modulation", lo, hi)
+	}
+}
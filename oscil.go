@@ -1,6 +1,15 @@
 package snd
 
-// TODO consider functional options
+// Pitched is a Sound whose frequency may be set as a static value or driven
+// by a signal. Frequency at each frame is hz times the value of mod, so mod
+// may be a ratio, such as vibrato or FM, or with hz of 1 a frequency in hertz,
+// such as Pitch of a Sequencer or a PitchTrack. A nil mod is a static hz.
+type Pitched interface {
+	Sound
+	Freq() float64
+	FreqMod() Sound
+	SetFreq(hz float64, mod Sound)
+}
 
 type Oscil struct {
 	*mono
@@ -13,6 +22,9 @@ type Oscil struct {
 	ampmod   Sound
 	freqmod  Sound
 	phasemod Sound
+
+	sync Sound
+	edge
 }
 
 func NewOscil(in Discrete, freq float64, freqmod Sound) *Oscil {
@@ -39,12 +51,49 @@ func (osc *Oscil) SetPhase(mod Sound) {
 	osc.phasemod = mod
 }
 
+// SetSync resets phase on each trigger of trig, hard syncing osc to the
+// source of trig. A nil trig stops syncing.
+func (osc *Oscil) SetSync(trig Sound) {
+	osc.sync = trig
+}
+
+func (osc *Oscil) Freq() float64 { return osc.freq }
+func (osc *Oscil) Amp() float64  { return osc.amp }
+
+// FreqMod returns the signal frequency is multiplied by, or nil.
+func (osc *Oscil) FreqMod() Sound { return osc.freqmod }
+
+// Draw draws a period of the waveform at amplitude, such as of an Oscil used
+// as a low frequency oscillator, without modulation.
+func (osc *Oscil) Draw(pts Discrete) {
+	drawperiod(osc.in.At, pts)
+	for i := range pts {
+		pts[i] *= osc.amp
+	}
+}
+
+func (osc *Oscil) Params() []*Param {
+	return []*Param{
+		NewParam("freq", osc.Freq, func(x float64) { osc.freq = x }).Range(0, 20000, 440).In(UnitHz),
+		NewParam("amp", osc.Amp, func(x float64) { osc.amp = x }).Range(0, 1, 1),
+	}
+}
+
 func (osc *Oscil) Inputs() []Sound {
-	return []Sound{osc.freqmod, osc.ampmod, osc.phasemod}
+	return []Sound{osc.freqmod, osc.ampmod, osc.phasemod, osc.sync}
 }
 
 func (osc *Oscil) Prepare(tc uint64) {
-	frame := int(tc-1) * len(osc.out)
+	if osc.off {
+		for i := range osc.out {
+			osc.out[i] = 0
+		}
+		return
+	}
+
+	// modulators are indexed modulo their power of two length, so the
+	// position is kept within an int on any platform.
+	frame := int(TickFrame(tc, len(osc.out)) & (1<<30 - 1))
 	nfreq := osc.freq / osc.sr
 
 	// phase := float64(frame) * nfreq
@@ -65,6 +114,12 @@ func (osc *Oscil) Prepare(tc uint64) {
 			amp *= osc.ampmod.Index(frame + i)
 		}
 
+		if osc.sync != nil {
+			if rise, _ := osc.step(osc.sync.Index(frame + i)); rise {
+				osc.phase = 0
+			}
+		}
+
 		osc.out[i] = amp * osc.in.At(osc.phase+offset)
 		osc.phase += interval
 	}
@@ -1,6 +1,9 @@
 package snd
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func BenchmarkOscil(b *testing.B) {
 	osc := NewOscil(Sine(), 440, nil)
@@ -77,3 +80,25 @@ func BenchmarkOscilReuse(b *testing.B) {
 		}
 	}
 }
+
+func TestPitched(t *testing.T) {
+	var _ Pitched = (*Oscil)(nil)
+
+	// frequency in hertz from a signal.
+	osc := NewOscil(Sine(), 1, NewConst(1000))
+	out := Render(osc, 4410)
+	if a := goertzel(out, 1000, DefaultSampleRate); a < 0.45 {
+		t.Fatalf("have magnitude %v at 1000Hz", a)
+	}
+
+	// modulation set on a voice's oscillator is kept across notes.
+	env := NewADSR(time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, 0.5, 1, nil)
+	vc := NewOscVoice(Sine(), env)
+	mod := NewConst(2)
+	vc.Osc().SetFreq(440, mod)
+	vc.NoteOn(500, 1)
+	vc.SetFreq(550)
+	if vc.Osc().FreqMod() != mod || vc.Osc().Freq() != 550 {
+		t.Fatalf("have freq %v and mod %v, want 550 and kept", vc.Osc().Freq(), vc.Osc().FreqMod())
+	}
+}
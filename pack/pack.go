@@ -0,0 +1,197 @@
+// Package pack reads and writes packs, single files distributing an
+// instrument or effect built with package snd: a zip archive of a patch of
+// package patch, a preset of its params, the samples and impulse responses
+// it plays, and a manifest describing it.
+//
+// The manifest is the file manifest.json at the root of the archive; other
+// files are named by it and by the patch, relative to the root:
+//
+//  manifest.json   {"name": "Breaks", "patch": "patch.txt", "preset": "preset.json"}
+//  patch.txt       slicer out file=samples/amen.wav slices=16
+//                  out out
+//  preset.json     {"out.gain": 0.8}
+//  samples/amen.wav
+package pack // import "dasa.cc/snd/pack"
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/patch"
+	"dasa.cc/snd/wav"
+)
+
+// ManifestName is the name of the manifest in a pack.
+const ManifestName = "manifest.json"
+
+// Manifest describes a pack.
+type Manifest struct {
+	Name        string            `json:"name"`
+	Author      string            `json:"author,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Patch       string            `json:"patch"`            // file of the patch
+	Preset      string            `json:"preset,omitempty"` // file of a preset loaded after the patch
+	Meta        map[string]string `json:"meta,omitempty"`   // such as license or tags
+}
+
+// Pack is a pack opened for reading. Pack is an fs.FS of the files of the
+// pack.
+type Pack struct {
+	Manifest
+	zr     *zip.Reader
+	closer io.Closer
+}
+
+// Open opens the pack of file name. Close must be called once done.
+func Open(name string) (*Pack, error) {
+	zc, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, fmt.Errorf("pack: %v", err)
+	}
+	pk, err := read(&zc.Reader)
+	if err != nil {
+		zc.Close()
+		return nil, err
+	}
+	pk.closer = zc
+	return pk, nil
+}
+
+// NewReader returns Pack read from r of size bytes, such as of a pack
+// embedded in a program or downloaded to memory.
+func NewReader(r io.ReaderAt, size int64) (*Pack, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("pack: %v", err)
+	}
+	return read(zr)
+}
+
+func read(zr *zip.Reader) (*Pack, error) {
+	pk := &Pack{zr: zr}
+	b, err := fs.ReadFile(zr, ManifestName)
+	if err != nil {
+		return nil, fmt.Errorf("pack: %v", err)
+	}
+	if err := json.Unmarshal(b, &pk.Manifest); err != nil {
+		return nil, fmt.Errorf("pack: read manifest failed: %v", err)
+	}
+	if pk.Patch == "" {
+		return nil, errors.New("pack: manifest missing patch")
+	}
+	return pk, nil
+}
+
+// Close closes a pack opened by Open.
+func (pk *Pack) Close() error {
+	if pk.closer == nil {
+		return nil
+	}
+	return pk.closer.Close()
+}
+
+// Open opens file name of the pack.
+func (pk *Pack) Open(name string) (fs.File, error) { return pk.zr.Open(name) }
+
+// Load builds the graph of the patch of the pack, reading samples from the
+// pack, and loads the preset, if any, into its params.
+func (pk *Pack) Load() (*patch.Patch, error) {
+	p, err := patch.ParseFS(pk, pk.Manifest.Patch)
+	if err != nil {
+		return nil, err
+	}
+	if pk.Preset == "" {
+		return p, nil
+	}
+	f, err := pk.Open(pk.Preset)
+	if err != nil {
+		return nil, fmt.Errorf("pack: %v", err)
+	}
+	defer f.Close()
+	pre, err := snd.ReadPreset(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Params.Load(pre); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Sample returns interleaved samples and format of the WAVE file name of the
+// pack, such as an impulse response.
+func (pk *Pack) Sample(name string) (snd.Discrete, wav.Format, error) {
+	f, err := pk.Open(name)
+	if err != nil {
+		return nil, wav.Format{}, fmt.Errorf("pack: %v", err)
+	}
+	defer f.Close()
+	sig, format, err := wav.Decode(bufio.NewReader(f))
+	if err != nil {
+		return nil, format, fmt.Errorf("pack: %s: %v", name, err)
+	}
+	return sig, format, nil
+}
+
+// Write writes a pack of m and every file of files to w, such as files of
+// os.DirFS of a directory. Files must include the patch and preset named by
+// m; any manifest of files is replaced by m.
+func Write(w io.Writer, m Manifest, files fs.FS) error {
+	if m.Patch == "" {
+		return errors.New("pack: manifest missing patch")
+	}
+	for _, name := range []string{m.Patch, m.Preset} {
+		if name == "" {
+			continue
+		}
+		if _, err := fs.Stat(files, name); err != nil {
+			return fmt.Errorf("pack: %v", err)
+		}
+	}
+
+	zw := zip.NewWriter(w)
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	fw, err := zw.Create(ManifestName)
+	if err != nil {
+		return err
+	}
+	if _, err := fw.Write(b); err != nil {
+		return err
+	}
+	err = fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || name == ManifestName {
+			return err
+		}
+		f, err := files.Open(name)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		method := zip.Deflate
+		if strings.HasSuffix(strings.ToLower(name), ".wav") {
+			// samples compress little; store them to read quickly.
+			method = zip.Store
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(fw, f)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("pack: %v", err)
+	}
+	return zw.Close()
+}
@@ -0,0 +1,90 @@
+package pack
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/wav"
+)
+
+// wavfile returns a WAVE file of sig.
+func wavfile(t *testing.T, sig snd.Discrete, chans int) []byte {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "x.wav")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := wav.NewWriter(f, chans, int(snd.DefaultSampleRate), 16)
+	if err == nil {
+		err = w.Write(sig)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestPack(t *testing.T) {
+	sig := make(snd.Discrete, 4*snd.DefaultBufferLen)
+	for i := range sig {
+		sig[i] = 0.5
+	}
+	files := fstest.MapFS{
+		"patch.txt":       {Data: []byte("slicer keys file=samples/hit.wav slices=4 base=60\ngain out in=keys amp=1\nout out\n")},
+		"preset.json":     {Data: []byte(`{"out.amp": 0.5}`)},
+		"samples/hit.wav": {Data: wavfile(t, sig, 1)},
+		"irs/room.wav":    {Data: wavfile(t, sig[:8], 1)},
+		"manifest.json":   {Data: []byte("replaced")},
+	}
+	m := Manifest{Name: "hits", Author: "snd", Patch: "patch.txt", Preset: "preset.json", Meta: map[string]string{"license": "CC0"}}
+	var buf bytes.Buffer
+	if err := Write(&buf, m, files); err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pk.Close()
+	if pk.Name != "hits" || pk.Meta["license"] != "CC0" {
+		t.Fatalf("have manifest %+v", pk.Manifest)
+	}
+	p, err := pk.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := p.Params.Lookup("out.amp").Value(); x != 0.5 {
+		t.Fatalf("have out.amp %v, want 0.5 of preset", x)
+	}
+	p.Nodes["keys"].(snd.Noter).NoteOn(61, 1)
+	out := snd.Render(p.Out, snd.DefaultBufferLen)
+	if x := out[10]; x < 0.24 || x > 0.26 {
+		t.Fatalf("have %v of slice, want 0.25", x)
+	}
+
+	ir, format, err := pk.Sample("irs/room.wav")
+	if err != nil || len(ir) != 8 || format.Chans != 1 {
+		t.Fatalf("have %v samples of %+v, err %v", len(ir), format, err)
+	}
+	if _, _, err := pk.Sample("irs/none.wav"); err == nil {
+		t.Fatal("have sample of missing file")
+	}
+
+	if err := Write(&buf, Manifest{Patch: "none.txt"}, files); err == nil {
+		t.Fatal("have pack of missing patch")
+	}
+}
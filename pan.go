@@ -5,50 +5,95 @@ import "math"
 var (
 	onesqrt2 = 1 / math.Sqrt(2)
 
+	// panfac is gain of a channel by PanLaw.
 	panres float64 = 512
-	panfac [1024]float64
+	panfac [3][1024]float64
+)
+
+// PanLaw selects the level of a sound panned center relative to hard left or
+// right, compensating for how channels sum in the room.
+type PanLaw int
+
+const (
+	// PanConstantPower is -3dB at center, keeping loudness steady across the
+	// field as heard on speakers. It is the default.
+	PanConstantPower PanLaw = iota
+
+	// PanCompromise is -4.5dB at center, between constant power and linear.
+	PanCompromise
+
+	// PanLinear is -6dB at center, keeping amplitude steady where channels sum
+	// coherently, such as when downmixed to mono or heard on headphones.
+	PanLinear
 )
 
 func init() {
-	for i := range panfac {
+	for i := range panfac[0] {
 		n := float64(i)/panres - 1
-		panfac[i] = onesqrt2 * (1 - n) / math.Sqrt(1+(n*n))
+		power := onesqrt2 * (1 - n) / math.Sqrt(1+(n*n))
+		linear := (1 - n) / 2
+		panfac[PanConstantPower][i] = power
+		panfac[PanCompromise][i] = math.Sqrt(power * linear)
+		panfac[PanLinear][i] = linear
 	}
 }
 
-func getpanfac(xf float64) float64 {
+func getpanfac(xf float64) float64 { return lawpanfac(PanConstantPower, xf) }
+
+// lawpanfac returns gain of a channel by law for xf belonging to [-1..1],
+// where -1 is panned wholly to that channel.
+func lawpanfac(law PanLaw, xf float64) float64 {
 	if xf > 1 {
 		xf = 1
 	} else if xf < -1 {
 		xf = -1
 	}
-	return panfac[int(panres*(1+xf))]
+	if law < 0 || int(law) >= len(panfac) {
+		law = PanConstantPower
+	}
+	i := int(panres * (1 + xf))
+	if i == len(panfac[law]) {
+		i--
+	}
+	return panfac[law][i]
 }
 
 type Pan struct {
 	*stereo
-	xf float64
+	xf  float64
+	law PanLaw
 }
 
 func NewPan(xf float64, in Sound) *Pan {
-	return &Pan{newstereo(in), xf}
+	return &Pan{stereo: newstereo(in), xf: xf}
 }
 
+// SetLaw sets the level of the input panned center, PanConstantPower by default.
+func (pan *Pan) SetLaw(law PanLaw) { pan.law = law }
+
+func (pan *Pan) Law() PanLaw { return pan.law }
+
 // SetAmount sets amount an input is panned across two outputs where amt belongs to [-1..1].
 func (pan *Pan) SetAmount(xf float64) { pan.xf = xf }
 
+func (pan *Pan) Amount() float64 { return pan.xf }
+
+func (pan *Pan) Params() []*Param {
+	return []*Param{NewParam("amount", pan.Amount, pan.SetAmount).Range(-1, 1, 0)}
+}
+
 // Prepare interleaves the left and right channels.
 func (pan *Pan) Prepare(uint64) {
 	for i, x := range pan.in.Samples() {
 		if pan.l.off {
 			pan.l.out[i] = 0
 		} else {
-			pan.l.out[i] = x * getpanfac(pan.xf)
+			pan.l.out[i] = x * lawpanfac(pan.law, pan.xf)
 		}
 		if pan.r.off {
 			pan.r.out[i] = 0
 		} else {
-			pan.r.out[i] = x * getpanfac(-pan.xf)
+			pan.r.out[i] = x * lawpanfac(pan.law, -pan.xf)
 		}
 		pan.out[i*2] = pan.l.out[i]
 		pan.out[i*2+1] = pan.r.out[i]
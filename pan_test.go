@@ -10,3 +10,35 @@ func BenchmarkPan(b *testing.B) {
 		pan.Prepare(uint64(n))
 	}
 }
+
+func TestPanLaw(t *testing.T) {
+	tests := []struct {
+		law    PanLaw
+		center Decibel
+	}{
+		{PanConstantPower, -3.01},
+		{PanCompromise, -4.52},
+		{PanLinear, -6.02},
+	}
+	for _, tt := range tests {
+		pan := NewPan(0, newunit())
+		mix := NewMixer()
+		mix.SetPanLaw(tt.law)
+		mix.Append(pan)
+		if pan.Law() != tt.law {
+			t.Fatalf("have law %v, want %v", pan.Law(), tt.law)
+		}
+		out := Render(mix, 1)
+		if db := DecibelOf(out[0] / DefaultAmpFac); !equaleps(float64(db), float64(tt.center), 0.01) {
+			t.Errorf("law %v: have %v at center, want %v", tt.law, db, tt.center)
+		}
+		if out[0] != out[1] {
+			t.Errorf("law %v: have left %v right %v at center", tt.law, out[0], out[1])
+		}
+		pan.SetAmount(-1)
+		out = Render(mix, 1)
+		if !equaleps(out[0], DefaultAmpFac, 1e-12) || !equaleps(out[1], 0, 1e-3) {
+			t.Errorf("law %v: have %v panned hard left, want [%v 0]", tt.law, out[:2], DefaultAmpFac)
+		}
+	}
+}
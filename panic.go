@@ -0,0 +1,36 @@
+package snd
+
+import "sync/atomic"
+
+// Panicker is a Sound holding state that may sound on after input stops, such
+// as a voice, envelope, or delay line, that it can drop at once.
+type Panicker interface {
+	Sound
+
+	// Panic silences sound at once, releasing notes, idling envelopes, and
+	// clearing buffers of tails.
+	Panic()
+}
+
+// Panic silences everything played through dp, such as after stuck notes
+// from MIDI input or feedback blowing up a patch, leaving other Dispatchers,
+// such as of other engines in one process, playing. Before preparing the next
+// buffer, dp calls Panic on every input that is a Panicker, then hooks of
+// OnPanic, such as zeroing Metrics. It is safe to call from any goroutine.
+func (dp *Dispatcher) Panic() { atomic.AddUint64(&dp.local, 1) }
+
+// checkpanic calls Panic on inps that are a Panicker, then hooks of OnPanic,
+// if Panic was called since last checked.
+func (dp *Dispatcher) checkpanic(tc uint64, inps []*Input) {
+	n := atomic.LoadUint64(&dp.local)
+	if n == dp.panics {
+		return
+	}
+	dp.panics = n
+	for _, inp := range inps {
+		if p, ok := inp.sd.(Panicker); ok {
+			p.Panic()
+		}
+	}
+	dp.hooks.call(&dp.hooks.panic, tc, inps)
+}
@@ -0,0 +1,101 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPanic(t *testing.T) {
+	p := NewPoly(4, func() Voice {
+		env := NewADSR(10*time.Millisecond, 10*time.Millisecond, 10*time.Millisecond, time.Second, 0.8, 1, nil)
+		return NewOscVoice(Sine(), env)
+	})
+	p.SetShared(func(in Sound) Sound {
+		return NewReverb(0.9, 0.2, NewComb(0.8, 50*time.Millisecond, in))
+	})
+	inps := GetInputs(p)
+	dp := new(Dispatcher)
+	render := func(n int) (peak float64) {
+		dp.DispatchN(1, n, func(uint64) {
+			if x := Peak(p.Samples()); x > peak {
+				peak = x
+			}
+		}, inps...)
+		return
+	}
+
+	p.NoteOn(60, 1)
+	p.NoteOn(64, 1)
+	if peak := render(20); peak < 0.1 {
+		t.Fatalf("have peak %v playing, want sound", peak)
+	}
+	dp.Panic()
+	if peak := render(1); peak != 0 {
+		t.Fatalf("have peak %v after panic, want silence", peak)
+	}
+	if n := p.Active(); n != 0 {
+		t.Fatalf("have %v voices active after panic, want 0", n)
+	}
+
+	p.NoteOn(60, 1)
+	if peak := render(20); peak < 0.1 {
+		t.Fatalf("have peak %v playing after panic, want sound", peak)
+	}
+}
+
+func TestDispatcherPanic(t *testing.T) {
+	// of two engines in one process, only the one panicked is silenced.
+	newengine := func() (*Dispatcher, *Poly, func() float64) {
+		p := NewPoly(2, func() Voice {
+			return NewOscVoice(Sine(), NewADSR(time.Millisecond, time.Millisecond, time.Second, time.Second, 0.8, 1, nil))
+		})
+		inps := GetInputs(p)
+		dp := new(Dispatcher)
+		return dp, p, func() (peak float64) {
+			dp.DispatchN(1, 4, func(uint64) {
+				if x := Peak(p.Samples()); x > peak {
+					peak = x
+				}
+			}, inps...)
+			return
+		}
+	}
+	cue, cuep, rendercue := newengine()
+	_, mainp, rendermain := newengine()
+	cuep.NoteOn(60, 1)
+	mainp.NoteOn(60, 1)
+	rendercue()
+	rendermain()
+
+	cue.Panic()
+	if peak := rendercue(); peak != 0 {
+		t.Fatalf("have peak %v after panic, want silence", peak)
+	}
+	if peak := rendermain(); peak < 0.1 {
+		t.Fatalf("have peak %v of engine not panicked, want sound", peak)
+	}
+}
+
+func TestPanicMeters(t *testing.T) {
+	p := NewPoly(2, func() Voice {
+		return NewOscVoice(Sine(), NewADSR(time.Millisecond, time.Millisecond, time.Second, time.Second, 0.8, 1, nil))
+	})
+	dp := new(Dispatcher)
+	m := NewMetrics(dp, p)
+	defer m.Close()
+	var panicked int
+	dp.OnPanic(func(uint64, uint64) { panicked++ })
+
+	p.NoteOn(60, 1)
+	dp.Render(p, 4*DefaultBufferLen)
+	if st := m.Stats(); st.Peak < -30 {
+		t.Fatalf("have peak %v playing, want sound", st.Peak)
+	}
+	// held for a second unless panicked.
+	dp.Panic()
+	dp.Render(p, DefaultBufferLen)
+	if st := m.Stats(); !math.IsInf(float64(st.Peak), -1) || panicked != 1 {
+		t.Fatalf("have peak %v after panic of %v hooks called, want silence", st.Peak, panicked)
+	}
+}
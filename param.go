@@ -0,0 +1,165 @@
+package snd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Param is a named value of a sound that may be stored and recalled.
+//
+// A param may declare a range of valid values, Min to Max, and a Default
+// within it, such as for a UI to draw a knob or a fuzz test to explore.
+// Values set outside the range are clamped to it. A param may also declare
+// the Unit of its value, for presenting it as such.
+type Param struct {
+	Name string
+
+	Min, Max, Default float64 // valid range, if Min < Max
+	Unit              Unit
+
+	get func() float64
+	set func(float64)
+}
+
+// NewParam returns a Param named name that reads and writes through get and set.
+func NewParam(name string, get func() float64, set func(float64)) *Param {
+	return &Param{Name: name, get: get, set: set}
+}
+
+// Range declares values of p belong to [min..max] with default def and
+// returns p.
+func (p *Param) Range(min, max, def float64) *Param {
+	p.Min, p.Max, p.Default = min, max, def
+	return p
+}
+
+// In declares values of p are in unit u and returns p.
+func (p *Param) In(u Unit) *Param {
+	p.Unit = u
+	return p
+}
+
+// String returns the value of p formatted in its unit.
+func (p *Param) String() string { return p.Unit.Format(p.Value()) }
+
+// SetString sets p to s parsed in its unit, such as "250 ms" or "50%".
+func (p *Param) SetString(s string) error {
+	x, err := p.Unit.parse(s)
+	if err != nil {
+		return fmt.Errorf("snd: param %q: %v", p.Name, err)
+	}
+	p.Set(x)
+	return nil
+}
+
+// Ranged reports whether p declares a range of valid values.
+func (p *Param) Ranged() bool { return p.Min < p.Max }
+
+func (p *Param) Value() float64 { return p.get() }
+
+// Set sets p to x, clamped to any range declared. NaN is ignored.
+func (p *Param) Set(x float64) {
+	if math.IsNaN(x) {
+		return
+	}
+	if p.Ranged() {
+		x = math.Max(p.Min, math.Min(p.Max, x))
+	}
+	p.set(x)
+}
+
+// Reset sets p to its default, if a range is declared.
+func (p *Param) Reset() {
+	if p.Ranged() {
+		p.set(p.Default)
+	}
+}
+
+// Parameterized is implemented by sounds exposing parameters for presets.
+type Parameterized interface {
+	Params() []*Param
+}
+
+// Params is a registry of named parameters, typically describing an instrument patch.
+type Params struct {
+	ps     []*Param
+	byname map[string]*Param
+}
+
+// Add registers p; it panics if a param of the same name is already registered.
+func (ps *Params) Add(p *Param) {
+	if ps.byname == nil {
+		ps.byname = make(map[string]*Param)
+	}
+	if _, ok := ps.byname[p.Name]; ok {
+		panic(fmt.Errorf("snd: param %q already registered", p.Name))
+	}
+	ps.ps = append(ps.ps, p)
+	ps.byname[p.Name] = p
+}
+
+// Register adds all params of sd with names prefixed as "prefix.name".
+func (ps *Params) Register(prefix string, sd Parameterized) {
+	for _, p := range sd.Params() {
+		q := *p
+		q.Name = prefix + "." + p.Name
+		ps.Add(&q)
+	}
+}
+
+// Lookup returns the param registered by name or nil.
+func (ps *Params) Lookup(name string) *Param { return ps.byname[name] }
+
+// List returns all params in order of registration.
+func (ps *Params) List() []*Param { return ps.ps }
+
+// Save returns the current value of all params.
+func (ps *Params) Save() Preset {
+	pre := make(Preset, len(ps.ps))
+	for _, p := range ps.ps {
+		pre[p.Name] = p.Value()
+	}
+	return pre
+}
+
+// Load sets params from pre. Values for names not registered are skipped and
+// reported in the returned error after all known values are set.
+func (ps *Params) Load(pre Preset) error {
+	var unknown []string
+	for name, x := range pre {
+		if p := ps.Lookup(name); p != nil {
+			p.Set(x)
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) != 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("snd: preset has unknown params %q", unknown)
+	}
+	return nil
+}
+
+// Preset is a stored set of param values by name.
+//
+// Presets are encoded as a JSON object mapping names to numbers.
+type Preset map[string]float64
+
+// ReadPreset decodes a Preset from r.
+func ReadPreset(r io.Reader) (Preset, error) {
+	var pre Preset
+	if err := json.NewDecoder(r).Decode(&pre); err != nil {
+		return nil, fmt.Errorf("snd: read preset failed: %v", err)
+	}
+	return pre, nil
+}
+
+// Write encodes pre to w.
+func (pre Preset) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(pre)
+}
@@ -0,0 +1,211 @@
+package snd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestPresetRoundTrip(t *testing.T) {
+	ms := time.Millisecond
+	osc := NewOscil(Sine(), 440, nil)
+	adsr := NewADSR(10*ms, 20*ms, 30*ms, 40*ms, 0.5, 1, osc)
+	lp := NewLowPass(1000, adsr)
+
+	var ps Params
+	ps.Register("osc", osc)
+	ps.Register("env", adsr)
+	ps.Register("lp", lp)
+
+	var buf bytes.Buffer
+	if err := ps.Save().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	osc.SetFreq(220, nil)
+	ps.Lookup("env.attack").Set(0.5)
+	lp.SetFreq(200)
+
+	pre, err := ReadPreset(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ps.Load(pre); err != nil {
+		t.Fatal(err)
+	}
+
+	if osc.Freq() != 440 {
+		t.Errorf("osc freq have %v, want 440", osc.Freq())
+	}
+	if x := ps.Lookup("env.attack").Value(); !equals(x, 0.01) {
+		t.Errorf("env attack have %v, want 0.01", x)
+	}
+	if lp.Freq() != 1000 {
+		t.Errorf("lp freq have %v, want 1000", lp.Freq())
+	}
+}
+
+func TestPresetUnknown(t *testing.T) {
+	var ps Params
+	ps.Register("gain", NewGain(1, newunit()))
+	if err := ps.Load(Preset{"gain.amp": 0.5, "nope": 1}); err == nil {
+		t.Fatal("expected error for unknown param")
+	}
+	if x := ps.Lookup("gain.amp").Value(); x != 0.5 {
+		t.Fatalf("known param not loaded, have %v", x)
+	}
+}
+
+func TestFuzzParams(t *testing.T) {
+	// random chains of effects with random params, including values out of
+	// range, never panic or output NaN.
+	ms := time.Millisecond
+	tp := NewTransport(120)
+	tp.Play()
+	effects := []func(in Sound) Sound{
+		func(in Sound) Sound { return NewGain(1, in) },
+		func(in Sound) Sound { return NewLowPass(1000, in) },
+		func(in Sound) Sound { return NewSVF(FilterBandPass, 1000, 2, in) },
+		func(in Sound) Sound { return NewAutoWah(1, 200, 2000, 4, in) },
+		func(in Sound) Sound { return NewReverb(0.5, 0.5, in) },
+		func(in Sound) Sound { return NewShimmer(12, 0.5, in) },
+		func(in Sound) Sound { return NewGatedReverb(0.1, 200*ms, 50*ms, in) },
+		func(in Sound) Sound { return NewPitchShift(7, in) },
+		func(in Sound) Sound { return NewExciter(3000, 0.5, in) },
+		func(in Sound) Sound { return NewFreqShift(100, in) },
+		func(in Sound) Sound { return NewComb(0.5, 10*ms, in) },
+		func(in Sound) Sound { return NewDeEsser(6000, 1, -20, 4, in) },
+		func(in Sound) Sound { return NewTremolo(5, 0.5, in) },
+		func(in Sound) Sound { return NewVibrato(5, 2*ms, in) },
+		func(in Sound) Sound { return NewSpectralFreeze(in) },
+		func(in Sound) Sound { return NewScale(1, in) },
+		func(in Sound) Sound { return NewOffset(0, in) },
+		func(in Sound) Sound { return NewClamp(-1, 1, in) },
+		func(in Sound) Sound { return NewTape(1, in) },
+		func(in Sound) Sound { return NewBeatRepeat(tp, 0.25, 0.5, in) },
+	}
+	tails := []func(in Sound) Sound{
+		func(in Sound) Sound { return NewPan(0, in) },
+		func(in Sound) Sound { return NewPingPong(250*ms, 0.5, in) },
+		func(in Sound) Sound { return NewRotary(in) },
+	}
+	extremes := []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e300, -1e300, 0}
+
+	rnd := rand.New(rand.NewSource(1))
+	for run := 0; run < 50; run++ {
+		var ps Params
+		var sd Sound = NewOscil(Sawtooth(), 20+rnd.Float64()*2000, nil)
+		for i, n := 0, 1+rnd.Intn(4); i < n; i++ {
+			sd = effects[rnd.Intn(len(effects))](sd)
+			if pz, ok := sd.(Parameterized); ok {
+				ps.Register(fmt.Sprintf("fx%v", i), pz)
+			}
+		}
+		if rnd.Intn(2) == 0 {
+			sd = tails[rnd.Intn(len(tails))](sd)
+		}
+		for _, p := range ps.List() {
+			if !p.Ranged() {
+				// signals of any unit, such as an Offset, are not clamped.
+				p.Set(-1 + 2*rnd.Float64())
+				continue
+			}
+			x := p.Min + rnd.Float64()*(p.Max-p.Min)
+			if rnd.Intn(4) == 0 {
+				x = extremes[rnd.Intn(len(extremes))]
+			}
+			p.Set(x)
+			if v := p.Value(); v < p.Min-1e-9 || v > p.Max+1e-9 {
+				t.Fatalf("have %q of %v set to %v, want within [%v..%v]", p.Name, x, v, p.Min, p.Max)
+			}
+		}
+		func() {
+			defer func() {
+				if e := recover(); e != nil {
+					t.Fatalf("run %v: have panic %v of params %v", run, e, ps.Save())
+				}
+			}()
+			for i, x := range Render(sd, 8192) {
+				if math.IsNaN(x) || math.IsInf(x, 0) {
+					t.Fatalf("run %v: have %v at %v of params %v", run, x, i, ps.Save())
+				}
+			}
+		}()
+	}
+}
+
+func TestFuzzConstructors(t *testing.T) {
+	// constructors given random durations, counts, and frequencies, including
+	// zero and negative ones, never panic building or playing a graph.
+	rnd := rand.New(rand.NewSource(1))
+	dur := func() time.Duration {
+		switch rnd.Intn(4) {
+		case 0:
+			return 0
+		case 1:
+			return -time.Duration(rnd.Int63n(int64(time.Second)))
+		}
+		return time.Duration(rnd.Int63n(int64(2 * time.Second)))
+	}
+	count := func() int { return rnd.Intn(10) - 3 }
+	freq := func() float64 { return []float64{0, -100, 1e-3, 440, 1e6}[rnd.Intn(5)] }
+	osc := func() Sound { return NewOscil(Sawtooth(), 220, nil) }
+	ctors := map[string]func() Sound{
+		"Delay":        func() Sound { return NewDelay(dur(), osc()) },
+		"Comb":         func() Sound { return NewComb(0.5, dur(), osc()) },
+		"Freeze":       func() Sound { return NewFreeze(dur(), osc()) },
+		"PitchTrack":   func() Sound { return NewPitchTrack(freq(), freq(), osc()) },
+		"Loop":         func() Sound { return NewLoop(dur(), osc()) },
+		"LoopFrames":   func() Sound { return NewLoopFrames(count(), osc()) },
+		"PingPong":     func() Sound { return NewPingPong(dur(), 0.5, osc()) },
+		"Drain":        func() Sound { return NewDrain(dur(), dur(), osc()) },
+		"ADSR":         func() Sound { return NewADSR(dur(), dur(), dur(), dur(), 0.5, 1, osc()) },
+		"Damp":         func() Sound { return NewDamp(dur(), osc()) },
+		"Drive":        func() Sound { return NewDrive(dur(), osc()) },
+		"Follower":     func() Sound { return NewFollower(dur(), dur(), osc()) },
+		"AutoOff":      func() Sound { return NewAutoOff(-60, dur(), osc()) },
+		"Vibrato":      func() Sound { return NewVibrato(5, dur(), osc()) },
+		"GatedReverb":  func() Sound { return NewGatedReverb(0.1, dur(), dur(), osc()) },
+		"Threshold":    func() Sound { return NewThreshold(0.5, dur(), osc()) },
+		"TrigDelay":    func() Sound { return NewTrigDelay(dur(), osc()) },
+		"TrigDivide":   func() Sound { return NewTrigDivide(count(), osc()) },
+		"TrigMultiply": func() Sound { return NewTrigMultiply(count(), osc()) },
+		"Clock":        func() Sound { return NewClock(dur()) },
+		"LatencyProbe": func() Sound { return NewLatencyProbe(dur(), osc()) },
+		"Dither":       func() Sound { return NewDither(count()*4, osc()) },
+		"Tape":         func() Sound { return NewTape(freq(), osc()) },
+		"LowPass":      func() Sound { return NewLowPass(freq(), osc()) },
+		"Oscil":        func() Sound { return NewOscil(Sine(), freq(), nil) },
+		"Poly":         func() Sound { return playing(NewPoly(count(), func() Voice { return NewSynth(1).Voices()[0] })) },
+		"Additive":     func() Sound { return playing(NewAdditive(count(), []float64{1, 0.5}, dur())) },
+		"Karplus":      func() Sound { return playing(NewKarplus(count(), dur())) },
+		"Mallet":       func() Sound { return playing(NewMallet(count(), MaterialMarimba, dur())) },
+		"FM":           func() Sound { return playing(NewFM(count(), FMPatchBell)) },
+		"Organ":        func() Sound { return playing(NewOrgan(count())) },
+		"Synth":        func() Sound { return playing(NewSynth(count())) },
+	}
+	for name, ctor := range ctors {
+		for run := 0; run < 20; run++ {
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						t.Errorf("%s run %v: have panic %v", name, run, e)
+					}
+				}()
+				Render(ctor(), 1024)
+			}()
+		}
+	}
+}
+
+// playing returns nt with a note played.
+func playing(nt interface {
+	Sound
+	Noter
+}) Sound {
+	nt.NoteOn(69, 1)
+	return nt
+}
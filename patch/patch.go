@@ -0,0 +1,937 @@
+// Package patch parses a text description of a sound graph.
+//
+// Each line declares a node as a kind, a unique name, and key=value arguments.
+// Nodes may only reference nodes declared on previous lines. The out directive
+// names the node returned as the patch output.
+//
+//  # two detuned saws through an envelope and lowpass
+//  osc lfo harm=sine freq=2
+//  osc a harm=saw freq=440 freqmod=lfo amp=-6dB
+//  osc b harm=saw freq=442 amp=-6dB
+//  mixer mix in=a,b
+//  adsr env in=mix attack=10ms decay=100ms sustain=200ms release=300ms susamp=0.5
+//  lowpass lp in=env freq=800
+//  pan out in=lp amount=-0.3
+//  out out
+//
+// Poly and slicer nodes are played by notes rather than inputs, such as by
+// MIDI of command sndinst. Player and slicer nodes play WAVE files named by
+// their file argument, read from Files of the patch.
+//
+// Values are numbers, durations as understood by time.ParseDuration, or
+// decibels with a dB suffix converted to an amplitude multiplier. Inputs that
+// accept multiple sounds take a comma separated list of names.
+package patch // import "dasa.cc/snd/patch"
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/wav"
+)
+
+// Patch is a parsed sound graph.
+type Patch struct {
+	// Out is the sound named by the out directive.
+	Out snd.Sound
+
+	// Nodes are all declared sounds by name.
+	Nodes map[string]snd.Sound
+
+	// Params has the params of every node registered by node name.
+	Params snd.Params
+
+	// Files are read by nodes naming files, such as samples of a player, or
+	// if nil, files of the working directory, rooted so that names may not
+	// be absolute or lead out of it. Set Files before executing lines, such
+	// as to files of a pack.
+	Files fs.FS
+
+	mu    sync.Mutex
+	lines []string    // declarations and out directives executed
+	live  chan func() // edits applied between buffers, if Live
+}
+
+// New returns an empty Patch ready for Exec.
+func New() *Patch {
+	return &Patch{Nodes: make(map[string]snd.Sound)}
+}
+
+// Parse reads a patch description from r and builds its graph.
+func Parse(r io.Reader) (*Patch, error) { return New().parse(r) }
+
+// ParseFS reads the patch description of file name of fsys and builds its
+// graph, reading files named by nodes from fsys.
+func ParseFS(fsys fs.FS, name string) (*Patch, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("patch: %v", err)
+	}
+	defer f.Close()
+	p := New()
+	p.Files = fsys
+	return p.parse(f)
+}
+
+func (p *Patch) parse(r io.Reader) (*Patch, error) {
+	sc := bufio.NewScanner(r)
+	for ln := 1; sc.Scan(); ln++ {
+		if err := p.Exec(sc.Text()); err != nil {
+			return nil, fmt.Errorf("patch: line %v: %v", ln, err)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("patch: %v", err)
+	}
+	if p.Out == nil {
+		return nil, fmt.Errorf("patch: missing out directive")
+	}
+	return p, nil
+}
+
+// Exec executes a single line against p, adding nodes to an existing graph.
+// In addition to node declarations and the out directive, Exec understands
+//
+//  set name.param value
+//  connect name key=value...
+//
+// to set a registered param while the graph is running, applied by Apply, or
+// to rewire a declared node, replacing arguments of its declaration such as
+// its inputs. Connect rebuilds the graph from Lines, keeping current param
+// values, so Out changes. As with a new out directive, callers must play the
+// new Out, such as by Swap of a snd.Hotswap.
+func (p *Patch) Exec(line string) error { return p.ExecContext(context.Background(), line) }
+
+// ExecContext executes line as Exec, giving up once ctx is done on waiting for
+// a Live graph to play a buffer, such as to read params of a connect line
+// while the graph is not playing.
+func (p *Patch) ExecContext(ctx context.Context, line string) error {
+	if i := strings.IndexByte(line, '#'); i != -1 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := p.decl(ctx, fields); err != nil {
+		return err
+	}
+	if fields[0] != "set" && fields[0] != "connect" {
+		p.mu.Lock()
+		p.lines = append(p.lines, strings.Join(fields, " "))
+		p.mu.Unlock()
+	}
+	return nil
+}
+
+// Lines returns declarations and out directives executed so far, without
+// comments or set lines, such as for a snd.Journal. Parsing them rebuilds the
+// graph with params at their initial values.
+func (p *Patch) Lines() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.lines...)
+}
+
+// Live has edits of the running graph, such as of set lines, applied by dp
+// before its next buffer instead of at once, so they never race the audio
+// thread preparing the graph. New nodes are still built by Exec off the audio
+// thread. It returns a func that stops, applying edits queued at once.
+func (p *Patch) Live(dp *snd.Dispatcher) (remove func()) {
+	c := make(chan func(), 64)
+	p.mu.Lock()
+	p.live = c
+	p.mu.Unlock()
+	rm := dp.BeforeDispatch(func(uint64, uint64) { drain(c) })
+	return func() {
+		p.mu.Lock()
+		p.live = nil
+		p.mu.Unlock()
+		rm()
+		drain(c)
+	}
+}
+
+// drain calls edits of c until none are queued.
+func drain(c chan func()) {
+	for {
+		select {
+		case fn := <-c:
+			fn()
+		default:
+			return
+		}
+	}
+}
+
+// ErrFull is returned by Apply when edits of a Live patch are queued faster
+// than buffers are played, such as while the graph is not playing.
+var ErrFull = errors.New("patch: too many edits queued")
+
+// Apply calls fn editing the running graph, such as to set a param, before
+// the next buffer if p is Live, or else at once, returning a channel closed
+// once called. Edits are applied in order. Apply never blocks; if p is Live
+// and too many edits are queued, fn is dropped and ErrFull returned.
+func (p *Patch) Apply(fn func()) (<-chan struct{}, error) {
+	done := make(chan struct{})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.live == nil {
+		fn()
+		close(done)
+		return done, nil
+	}
+	// sent holding mu, so none is queued once Live is removed.
+	select {
+	case p.live <- func() {
+		fn()
+		close(done)
+	}:
+		return done, nil
+	default:
+		return nil, ErrFull
+	}
+}
+
+// Loaded is the result of LoadAsync.
+type Loaded struct {
+	Patch *Patch
+	Err   error
+}
+
+// LoadAsync builds a patch by load on a new goroutine, such as by ParseFS or
+// Load of a pack, so files are read and the graph allocated off the audio
+// thread, then swaps its output into hs to crossfade in from the next buffer.
+// The returned channel receives the patch once swapped in, or the error of
+// load with hs left playing as it was.
+func LoadAsync(hs *snd.Hotswap, load func() (*Patch, error)) <-chan Loaded {
+	c := make(chan Loaded, 1)
+	go func() {
+		p, err := load()
+		if err == nil {
+			hs.Swap(p.Out)
+		}
+		c <- Loaded{p, err}
+	}()
+	return c
+}
+
+func (p *Patch) decl(ctx context.Context, fields []string) error {
+	kind := fields[0]
+	if len(fields) < 2 {
+		return fmt.Errorf("%s missing name", kind)
+	}
+	name := fields[1]
+
+	switch kind {
+	case "out":
+		sd, ok := p.Nodes[name]
+		if !ok {
+			return fmt.Errorf("undefined node %q", name)
+		}
+		p.Out = sd
+		return nil
+	case "set":
+		prm := p.Params.Lookup(name)
+		if prm == nil {
+			return fmt.Errorf("undefined param %q", name)
+		}
+		if len(fields) != 3 {
+			return fmt.Errorf("set %s missing value", name)
+		}
+		x, err := args{"value": fields[2]}.float("value", 0)
+		if err != nil {
+			return err
+		}
+		_, err = p.Apply(func() { prm.Set(x) })
+		return err
+	case "connect":
+		return p.connect(ctx, name, fields[2:])
+	}
+
+	if _, ok := p.Nodes[name]; ok {
+		return fmt.Errorf("node %q already declared", name)
+	}
+	args := make(args)
+	for _, kv := range fields[2:] {
+		i := strings.IndexByte(kv, '=')
+		if i == -1 {
+			return fmt.Errorf("argument %q not of form key=value", kv)
+		}
+		args[kv[:i]] = kv[i+1:]
+	}
+
+	fn, ok := kinds[kind]
+	if !ok {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	sd, err := fn(p, args)
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", kind, name, err)
+	}
+	for key := range args {
+		return fmt.Errorf("%s %s: unknown argument %q", kind, name, key)
+	}
+
+	p.Nodes[name] = sd
+	if ps, ok := sd.(snd.Parameterized); ok {
+		p.Params.Register(name, ps)
+	}
+	return nil
+}
+
+// connect rewires node name, replacing arguments of its declaration by kvs,
+// and rebuilds the graph from the edited lines. Nodes may still only reference
+// nodes declared before them. On error, p is left as it was.
+func (p *Patch) connect(ctx context.Context, name string, kvs []string) error {
+	if len(kvs) == 0 {
+		return fmt.Errorf("connect %s missing arguments", name)
+	}
+	lines := p.Lines()
+	i := len(lines) - 1
+	for ; i >= 0; i-- {
+		if f := strings.Fields(lines[i]); f[0] != "out" && f[1] == name {
+			break
+		}
+	}
+	if i == -1 {
+		return fmt.Errorf("undefined node %q", name)
+	}
+	fields := strings.Fields(lines[i])
+	for _, kv := range kvs {
+		j := strings.IndexByte(kv, '=')
+		if j == -1 {
+			return fmt.Errorf("argument %q not of form key=value", kv)
+		}
+		k := 2
+		for ; k < len(fields) && !strings.HasPrefix(fields[k], kv[:j+1]); k++ {
+		}
+		if k == len(fields) {
+			fields = append(fields, kv)
+		} else {
+			fields[k] = kv
+		}
+	}
+	lines[i] = strings.Join(fields, " ")
+
+	q := New()
+	q.Files = p.Files
+	for _, line := range lines {
+		if err := q.Exec(line); err != nil {
+			return fmt.Errorf("connect %s: %v", name, err)
+		}
+	}
+	// params of the running graph are read between buffers if Live.
+	var pre snd.Preset
+	done, err := p.Apply(func() { pre = p.Params.Save() })
+	if err != nil {
+		return err
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("connect %s: %v", name, ctx.Err())
+	}
+	q.Params.Load(pre) // params of nodes no longer declared are dropped
+
+	p.Out, p.Nodes, p.Params = q.Out, q.Nodes, q.Params
+	p.mu.Lock()
+	p.lines = lines
+	p.mu.Unlock()
+	return nil
+}
+
+// args are key=value arguments of a declaration; values are deleted
+// as they are consumed so unknown arguments can be reported.
+type args map[string]string
+
+func (a args) str(key, def string) string {
+	s, ok := a[key]
+	if !ok {
+		return def
+	}
+	delete(a, key)
+	return s
+}
+
+func (a args) float(key string, def float64) (float64, error) {
+	s := a.str(key, "")
+	if s == "" {
+		return def, nil
+	}
+	if strings.HasSuffix(s, "dB") {
+		x, err := strconv.ParseFloat(strings.TrimSuffix(s, "dB"), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %v", key, err)
+		}
+		return snd.Decibel(x).Amp(), nil
+	}
+	x, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", key, err)
+	}
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return 0, fmt.Errorf("%s: %v not finite", key, x)
+	}
+	return x, nil
+}
+
+func (a args) dur(key string, def time.Duration) (time.Duration, error) {
+	s := a.str(key, "")
+	if s == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", key, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s: %v negative", key, d)
+	}
+	return d, nil
+}
+
+// line returns the duration of a delay line by key, bounded by maxLine.
+func (a args) line(key string, def time.Duration) (time.Duration, error) {
+	d, err := a.dur(key, def)
+	if err == nil && d > maxLine {
+		err = fmt.Errorf("%s: %v longer than %v", key, d, maxLine)
+	}
+	return d, err
+}
+
+// maxLine is the longest delay line of a node, bounding the memory a patch
+// may allocate.
+const maxLine = 10 * time.Second
+
+// maxVoices is the most voices of a poly node.
+const maxVoices = 256
+
+// sound returns the node named by key or nil if key is not set.
+func (p *Patch) sound(a args, key string) (snd.Sound, error) {
+	name := a.str(key, "")
+	if name == "" {
+		return nil, nil
+	}
+	sd, ok := p.Nodes[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: undefined node %q", key, name)
+	}
+	return sd, nil
+}
+
+// input returns the required input named by key "in".
+func (p *Patch) input(a args) (snd.Sound, error) {
+	sd, err := p.sound(a, "in")
+	if err == nil && sd == nil {
+		err = fmt.Errorf("in: required")
+	}
+	return sd, err
+}
+
+var harms = map[string]func() snd.Discrete{
+	"sine":     snd.Sine,
+	"triangle": snd.Triangle,
+	"square":   snd.Square,
+	"saw":      snd.Sawtooth,
+	"sawtooth": snd.Sawtooth,
+}
+
+type kindFunc func(p *Patch, a args) (snd.Sound, error)
+
+var kinds map[string]kindFunc
+
+func init() {
+	kinds = map[string]kindFunc{
+		"osc":      mkosc,
+		"adsr":     mkadsr,
+		"lowpass":  mklowpass,
+		"gain":     mkgain,
+		"pan":      mkpan,
+		"mixer":    mkmixer,
+		"ring":     mkring,
+		"delay":    mkdelay,
+		"comb":     mkcomb,
+		"tremolo":  mktremolo,
+		"vibrato":  mkvibrato,
+		"autowah":  mkautowah,
+		"tape":     mktape,
+		"pingpong": mkpingpong,
+		"reverb":   mkreverb,
+		"shimmer":  mkshimmer,
+		"pitch":    mkpitch,
+		"gated":    mkgated,
+		"deesser":  mkdeesser,
+		"fshift":   mkfshift,
+		"spectral": mkspectral,
+		"rotary":   mkrotary,
+		"poly":     mkpoly,
+		"player":   mkplayer,
+		"slicer":   mkslicer,
+	}
+}
+
+func mkosc(p *Patch, a args) (snd.Sound, error) {
+	h := a.str("harm", "sine")
+	harm, ok := harms[h]
+	if !ok {
+		return nil, fmt.Errorf("harm: unknown signal %q", h)
+	}
+	freq, err := a.float("freq", 440)
+	if err != nil {
+		return nil, err
+	}
+	amp, err := a.float("amp", 1)
+	if err != nil {
+		return nil, err
+	}
+	var mods [3]snd.Sound
+	for i, key := range []string{"freqmod", "ampmod", "phasemod"} {
+		if mods[i], err = p.sound(a, key); err != nil {
+			return nil, err
+		}
+	}
+	osc := snd.NewOscil(harm(), freq, mods[0])
+	osc.SetAmp(amp, mods[1])
+	osc.SetPhase(mods[2])
+	return osc, nil
+}
+
+func mkadsr(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.sound(a, "in")
+	if err != nil {
+		return nil, err
+	}
+	ms := time.Millisecond
+	var ds [4]time.Duration
+	for i, key := range []string{"attack", "decay", "sustain", "release"} {
+		if ds[i], err = a.dur(key, 100*ms); err != nil {
+			return nil, err
+		}
+	}
+	susamp, err := a.float("susamp", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	maxamp, err := a.float("maxamp", 1)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewADSR(ds[0], ds[1], ds[2], ds[3], susamp, maxamp, in), nil
+}
+
+// sample returns the samples of the WAVE file named by key.
+func (p *Patch) sample(a args, key string) (sig snd.Discrete, f wav.Format, err error) {
+	name := a.str(key, "")
+	if name == "" {
+		return nil, f, fmt.Errorf("%s: required", key)
+	}
+	fsys := p.Files
+	if fsys == nil {
+		fsys = os.DirFS(".")
+	}
+	r, err := fsys.Open(name)
+	if err != nil {
+		return nil, f, err
+	}
+	defer r.Close()
+	sig, f, err = wav.Decode(bufio.NewReader(r))
+	if err != nil {
+		return nil, f, fmt.Errorf("%s: %v", name, err)
+	}
+	return sig, f, nil
+}
+
+func mkplayer(p *Patch, a args) (snd.Sound, error) {
+	sig, f, err := p.sample(a, "file")
+	if err != nil {
+		return nil, err
+	}
+	loop, err := a.float("loop", 0)
+	if err != nil {
+		return nil, err
+	}
+	pl := snd.NewPlayer(sig, f.Chans, float64(f.Rate))
+	pl.SetLoop(loop != 0)
+	return pl, nil
+}
+
+// mkslicer returns a Slicer of equal slices played by notes.
+func mkslicer(p *Patch, a args) (snd.Sound, error) {
+	sig, f, err := p.sample(a, "file")
+	if err != nil {
+		return nil, err
+	}
+	var xs [3]float64
+	for i, key := range []string{"slices", "base", "gate"} {
+		def := []float64{1, 36, 0}[i]
+		if xs[i], err = a.float(key, def); err != nil {
+			return nil, err
+		}
+	}
+	if xs[0] < 1 {
+		return nil, fmt.Errorf("slices: %v not positive", xs[0])
+	}
+	pl := snd.NewPlayer(sig, f.Chans, float64(f.Rate))
+	sl := snd.NewSlicer(pl, snd.GridSlices(pl.Len(), int(xs[0])), int(xs[1]))
+	sl.SetGate(xs[2] != 0)
+	return sl, nil
+}
+
+// mkpoly returns a Poly of oscillator voices played by notes, such as of MIDI.
+func mkpoly(p *Patch, a args) (snd.Sound, error) {
+	h := a.str("harm", "saw")
+	harm, ok := harms[h]
+	if !ok {
+		return nil, fmt.Errorf("harm: unknown signal %q", h)
+	}
+	n, err := a.float("voices", 8)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 || n > maxVoices {
+		return nil, fmt.Errorf("voices: %v not between 1 and %v", n, maxVoices)
+	}
+	ms := time.Millisecond
+	var ds [3]time.Duration
+	for i, key := range []string{"attack", "decay", "release"} {
+		def := []time.Duration{10 * ms, 100 * ms, 300 * ms}[i]
+		if ds[i], err = a.dur(key, def); err != nil {
+			return nil, err
+		}
+	}
+	susamp, err := a.float("susamp", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	sig := harm()
+	poly := snd.NewPoly(int(n), func() snd.Voice {
+		// sustained by the gate of the voice while a key is held.
+		return snd.NewOscVoice(sig, snd.NewADSR(ds[0], ds[1], 8*time.Second, ds[2], susamp, 1, nil))
+	})
+	poly.SetGain(snd.VoiceGain(int(n), snd.CrestSaw, snd.DefaultHeadroom))
+	return poly, nil
+}
+
+func mklowpass(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	freq, err := a.float("freq", 1000)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewLowPass(freq, in), nil
+}
+
+func mkgain(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	amp, err := a.float("amp", 1)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewGain(amp, in), nil
+}
+
+func mkpan(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	amt, err := a.float("amount", 0)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewPan(amt, in), nil
+}
+
+func mkmixer(p *Patch, a args) (snd.Sound, error) {
+	mix := snd.NewMixer()
+	for _, name := range strings.Split(a.str("in", ""), ",") {
+		if name == "" {
+			continue
+		}
+		sd, ok := p.Nodes[name]
+		if !ok {
+			return nil, fmt.Errorf("in: undefined node %q", name)
+		}
+		mix.Append(sd)
+	}
+	return mix, nil
+}
+
+func mkring(p *Patch, a args) (snd.Sound, error) {
+	names := strings.Split(a.str("in", ""), ",")
+	if len(names) != 2 {
+		return nil, fmt.Errorf("in: requires two nodes")
+	}
+	var ins [2]snd.Sound
+	for i, name := range names {
+		sd, ok := p.Nodes[name]
+		if !ok {
+			return nil, fmt.Errorf("in: undefined node %q", name)
+		}
+		ins[i] = sd
+	}
+	return snd.NewRing(ins[0], ins[1]), nil
+}
+
+func mkdelay(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	d, err := a.line("dur", 100*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewDelay(d, in), nil
+}
+
+func mkcomb(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	d, err := a.line("dur", 10*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	gain, err := a.float("gain", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewComb(gain, d, in), nil
+}
+
+func mktremolo(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := a.float("rate", 5)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := a.float("depth", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewTremolo(rate, depth, in), nil
+}
+
+func mkvibrato(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := a.float("rate", 5)
+	if err != nil {
+		return nil, err
+	}
+	depth, err := a.dur("depth", 2*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewVibrato(rate, depth, in), nil
+}
+
+func mkautowah(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [4]float64
+	for i, key := range []string{"sensitivity", "min", "max", "q"} {
+		def := []float64{2, 300, 3000, 3}[i]
+		if xs[i], err = a.float(key, def); err != nil {
+			return nil, err
+		}
+	}
+	return snd.NewAutoWah(xs[0], xs[1], xs[2], xs[3], in), nil
+}
+
+func mktape(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	drive, err := a.float("drive", 1)
+	if err != nil {
+		return nil, err
+	}
+	hiss, err := a.float("hiss", 0)
+	if err != nil {
+		return nil, err
+	}
+	tp := snd.NewTape(drive, in)
+	tp.SetHiss(hiss)
+	return tp, nil
+}
+
+func mkpingpong(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	d, err := a.dur("dur", 250*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	fb, err := a.float("feedback", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	mix, err := a.float("mix", 0.5)
+	if err != nil {
+		return nil, err
+	}
+	pp := snd.NewPingPong(d, fb, in)
+	pp.SetMix(mix)
+	return pp, nil
+}
+
+func mkreverb(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [3]float64
+	for i, key := range []string{"size", "damp", "mix"} {
+		if xs[i], err = a.float(key, []float64{0.8, 0.3, 0.5}[i]); err != nil {
+			return nil, err
+		}
+	}
+	rv := snd.NewReverb(xs[0], xs[1], in)
+	rv.SetMix(xs[2])
+	return rv, nil
+}
+
+func mkshimmer(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [3]float64
+	for i, key := range []string{"interval", "feedback", "mix"} {
+		if xs[i], err = a.float(key, []float64{12, 0.5, 0.5}[i]); err != nil {
+			return nil, err
+		}
+	}
+	sh := snd.NewShimmer(xs[0], xs[1], in)
+	sh.SetMix(xs[2])
+	return sh, nil
+}
+
+func mkpitch(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	semis, err := a.float("semitones", 12)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewPitchShift(semis, in), nil
+}
+
+func mkgated(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := a.float("threshold", 0.05)
+	if err != nil {
+		return nil, err
+	}
+	hold, err := a.dur("hold", 150*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	release, err := a.dur("release", 50*time.Millisecond)
+	if err != nil {
+		return nil, err
+	}
+	return snd.NewGatedReverb(threshold, hold, release, in), nil
+}
+
+func mkdeesser(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [4]float64
+	for i, key := range []string{"freq", "q", "threshold", "ratio"} {
+		if xs[i], err = a.float(key, []float64{6000, 2, -30, 4}[i]); err != nil {
+			return nil, err
+		}
+	}
+	return snd.NewDeEsser(xs[0], xs[1], snd.Decibel(xs[2]), xs[3], in), nil
+}
+
+func mkfshift(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [3]float64
+	for i, key := range []string{"shift", "feedback", "mix"} {
+		if xs[i], err = a.float(key, []float64{100, 0, 1}[i]); err != nil {
+			return nil, err
+		}
+	}
+	fs := snd.NewFreqShift(xs[0], in)
+	fs.SetFeedback(xs[1])
+	fs.SetMix(xs[2])
+	return fs, nil
+}
+
+func mkspectral(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [3]float64
+	for i, key := range []string{"blur", "mix", "freeze"} {
+		if xs[i], err = a.float(key, []float64{0, 1, 0}[i]); err != nil {
+			return nil, err
+		}
+	}
+	sf := snd.NewSpectralFreeze(in)
+	sf.SetBlur(xs[0])
+	sf.SetMix(xs[1])
+	sf.SetFrozen(xs[2] != 0)
+	return sf, nil
+}
+
+func mkrotary(p *Patch, a args) (snd.Sound, error) {
+	in, err := p.input(a)
+	if err != nil {
+		return nil, err
+	}
+	var xs [2]float64
+	for i, key := range []string{"mix", "fast"} {
+		if xs[i], err = a.float(key, []float64{1, 0}[i]); err != nil {
+			return nil, err
+		}
+	}
+	rot := snd.NewRotary(in)
+	rot.SetMix(xs[0])
+	rot.SetFast(xs[1] != 0)
+	return rot, nil
+}
@@ -0,0 +1,237 @@
+package patch
+
+import (
+	"strings"
+	"testing"
+
+	"dasa.cc/snd"
+)
+
+const src = `
+# two detuned saws through an envelope and lowpass
+osc lfo harm=sine freq=2
+osc a harm=saw freq=440 freqmod=lfo amp=-6dB
+osc b harm=saw freq=442 amp=-6dB
+mixer mix in=a,b
+adsr env in=mix attack=10ms decay=100ms sustain=200ms release=300ms susamp=0.5
+lowpass lp in=env freq=800
+pan out in=lp amount=-0.3
+out out
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse(strings.NewReader(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Out.Channels() != 2 {
+		t.Fatalf("out has channels %v, want 2", p.Out.Channels())
+	}
+	if n := len(p.Nodes); n != 7 {
+		t.Fatalf("have %v nodes, want 7", n)
+	}
+	if x := p.Params.Lookup("lp.freq").Value(); x != 800 {
+		t.Fatalf("lp.freq have %v, want 800", x)
+	}
+	if x, want := p.Params.Lookup("a.amp").Value(), snd.Decibel(-6).Amp(); x != want {
+		t.Fatalf("a.amp have %v, want %v", x, want)
+	}
+	new(snd.Dispatcher).Dispatch(1, snd.GetInputs(p.Out)...)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, src := range []string{
+		"osc a freq=x\nout a",
+		"osc a\nosc a\nout a",
+		"gain g in=nope\nout g",
+		"osc a bogus=1\nout a",
+		"wat a\nout a",
+		"osc a",
+		"osc a\ndelay d in=a dur=-1s\nout d",
+		"osc a\ncomb c in=a dur=1000h\nout c",
+		"osc a\nadsr e in=a attack=-5ms\nout e",
+		"osc a freq=NaN\nout a",
+		"poly k voices=1e12\nout k",
+	} {
+		if _, err := Parse(strings.NewReader(src)); err == nil {
+			t.Errorf("expected error for %q", src)
+		} else {
+			t.Log(err)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	p := New()
+	for _, line := range []string{
+		"osc lfo freq=4",
+		"osc a harm=saw freq=220 freqmod=lfo amp=-12dB",
+		"out a",
+		"set lfo.freq 0.5",
+	} {
+		if err := p.Exec(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if x := p.Params.Lookup("lfo.freq").Value(); x != 0.5 {
+		t.Fatalf("lfo.freq have %v, want 0.5", x)
+	}
+	if err := p.Exec("set nope.freq 1"); err == nil {
+		t.Fatal("expected error for undefined param")
+	}
+	if err := p.Exec("osc a freq=1"); err == nil {
+		t.Fatal("expected error for redeclared node")
+	}
+
+	lines := p.Lines()
+	if len(lines) != 3 || lines[1] != "osc a harm=saw freq=220 freqmod=lfo amp=-12dB" {
+		t.Fatalf("have lines %q, want declarations and out", lines)
+	}
+	q, err := Parse(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Nodes) != 2 || q.Out != q.Nodes["a"] {
+		t.Fatalf("have %v nodes rebuilt from lines, want 2 and out a", len(q.Nodes))
+	}
+}
+
+func TestFilesRooted(t *testing.T) {
+	// without Files, names may not leave the working directory.
+	for _, name := range []string{"/etc/passwd", "../patch/testdata/x.wav", "./x.wav"} {
+		if err := New().Exec("player a file=" + name); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("have error %v of %q, want invalid", err, name)
+		}
+	}
+}
+
+func TestPoly(t *testing.T) {
+	p, err := Parse(strings.NewReader("poly keys voices=4 harm=square release=50ms\nout keys"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nt, ok := p.Out.(snd.Noter)
+	if !ok {
+		t.Fatal("poly not a Noter")
+	}
+	nt.NoteOn(69, 1)
+	if snd.Peak(snd.Render(p.Out, 4096)) == 0 {
+		t.Fatal("have silence playing a note")
+	}
+}
+
+func TestLoadAsync(t *testing.T) {
+	hs := snd.NewHotswap(2, nil)
+	if r := <-LoadAsync(hs, func() (*Patch, error) { return Parse(strings.NewReader("nope")) }); r.Err == nil {
+		t.Fatal("have no error loading bad patch")
+	}
+	if hs.Current() != nil {
+		t.Fatal("swapped in bad patch")
+	}
+	r := <-LoadAsync(hs, func() (*Patch, error) { return Parse(strings.NewReader(src)) })
+	if r.Err != nil {
+		t.Fatal(r.Err)
+	}
+	if hs.Current() != r.Patch.Out {
+		t.Fatal("patch not swapped in")
+	}
+	if snd.Peak(snd.Render(hs, 4096)) == 0 {
+		t.Fatal("have silence after load")
+	}
+}
+
+func TestLive(t *testing.T) {
+	p := New()
+	for _, line := range []string{"osc lfo freq=4", "osc a freq=220 freqmod=lfo", "out a"} {
+		if err := p.Exec(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dp := new(snd.Dispatcher)
+	remove := p.Live(dp)
+	if err := p.Exec("set lfo.freq 0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if x := p.Params.Lookup("lfo.freq").Value(); x != 4 {
+		t.Fatalf("have lfo.freq %v before a buffer, want 4", x)
+	}
+	dp.Render(p.Out, snd.DefaultBufferLen)
+	if x := p.Params.Lookup("lfo.freq").Value(); x != 0.5 {
+		t.Fatalf("have lfo.freq %v after a buffer, want 0.5", x)
+	}
+
+	// queued edits apply once removed, and later ones at once.
+	p.Exec("set lfo.freq 1")
+	remove()
+	done, err := p.Apply(func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if x := p.Params.Lookup("lfo.freq").Value(); x != 1 {
+		t.Fatalf("have lfo.freq %v once removed, want 1", x)
+	}
+
+	// edits queued without buffers played are dropped rather than block.
+	remove = p.Live(dp)
+	defer remove()
+	for i := 0; ; i++ {
+		err := p.Exec("set lfo.freq 2")
+		if err == ErrFull {
+			break
+		}
+		if err != nil || i == 1000 {
+			t.Fatalf("have %v after %v edits, want ErrFull", err, i)
+		}
+	}
+	dp.Render(p.Out, snd.DefaultBufferLen)
+	if err := p.Exec("set lfo.freq 3"); err != nil {
+		t.Fatalf("have %v once played, want edits queued again", err)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	p := New()
+	for _, line := range []string{
+		"osc a freq=220",
+		"osc b freq=330",
+		"mixer mix in=a",
+		"lowpass lp in=mix freq=800",
+		"out lp",
+		"set a.freq 110",
+	} {
+		if err := p.Exec(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := p.Out
+	if err := p.Exec("connect mix in=a,b"); err != nil {
+		t.Fatal(err)
+	}
+	if p.Out == out || p.Out != p.Nodes["lp"] {
+		t.Fatal("have out unchanged, want rebuilt lp")
+	}
+	if n := len(p.Nodes["mix"].Inputs()); n != 2 {
+		t.Fatalf("have %v inputs of mix, want 2", n)
+	}
+	if x := p.Params.Lookup("a.freq").Value(); x != 110 {
+		t.Fatalf("have a.freq %v, want 110 kept", x)
+	}
+	if lines := p.Lines(); lines[2] != "mixer mix in=a,b" {
+		t.Fatalf("have line %q, want rewired declaration", lines[2])
+	}
+
+	for _, line := range []string{
+		"connect nope in=a",
+		"connect mix",
+		"connect a freq",
+		"connect mix in=lp", // lp is declared after mix
+	} {
+		if err := p.Exec(line); err == nil {
+			t.Fatalf("%q: expected error", line)
+		}
+	}
+	if p.Out != p.Nodes["lp"] || len(p.Lines()) != 5 {
+		t.Fatal("failed connect changed patch")
+	}
+}
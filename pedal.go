@@ -0,0 +1,210 @@
+package snd
+
+import "math"
+
+// Noter plays notes by MIDI key number, such as Poly and Mono.
+type Noter interface {
+	// NoteOn presses key at velocity vel belonging to [0..1].
+	NoteOn(key int, vel float64)
+
+	// NoteOff releases key.
+	NoteOff(key int)
+}
+
+// Expressive is a Noter also played by channel pressure and pitch bend, such
+// as Poly and Mono, so that MIDI input, a Sequencer, and a computer keyboard
+// all play an instrument the same way.
+type Expressive interface {
+	Noter
+
+	// Pressure sets pressure on all notes, belonging to [0..1].
+	Pressure(x float64)
+
+	// Bend shifts pitch of all notes by semitones.
+	Bend(semitones float64)
+}
+
+// NoteExpressive is an Expressive also played by pressure and pitch bend of
+// single notes, such as Poly, as by MIDI 2.0 per-note controllers or
+// polyphonic aftertouch.
+type NoteExpressive interface {
+	Expressive
+
+	// NotePressure sets pressure on notes of key, belonging to [0..1].
+	NotePressure(key int, x float64)
+
+	// NoteBend shifts pitch of notes of key by semitones.
+	NoteBend(key int, semitones float64)
+}
+
+// Controller numbers of pedals handled by Pedals.Control.
+const (
+	CtrlSustain   = 64
+	CtrlSostenuto = 66
+	CtrlSoft      = 67
+)
+
+// Una corda, the soft pedal, shifts hammers to strike fewer strings with a
+// softer part of their felt, quieter and darker; instruments without samples
+// of it lower notes by unaCordaGain and filter them above unaCordaCutoff.
+const (
+	unaCordaGain   Decibel = -4
+	unaCordaCutoff         = 3000 // Hz
+)
+
+// unacorda returns the gain of una corda of a partial at hz.
+func unacorda(hz float64) float64 {
+	return unaCordaGain.Amp() / math.Sqrt(1+(hz/unaCordaCutoff)*(hz/unaCordaCutoff))
+}
+
+// Positions of the sustain pedal belonging to [0..1] between which its
+// dampers rise from resting fully on the strings to clear of them.
+const (
+	halfPedalLo = 0.25
+	halfPedalHi = 0.75
+)
+
+// halfdamping returns damping belonging to [0..1] of a sustain pedal at
+// position x, 1 up and 0 down.
+func halfdamping(x float64) float64 {
+	return math.Max(0, math.Min(1, (halfPedalHi-x)/(halfPedalHi-halfPedalLo)))
+}
+
+// Pedals applies sustain, sostenuto, and soft pedals to notes played on a
+// Noter.
+//
+// While sustain is down, releasing a key holds its note until the pedal is up.
+// Pressing sostenuto holds only notes sounding at that moment until the pedal
+// is up; notes played after are unaffected. Striking a key that is still
+// sounding releases and plays it again.
+//
+// Sustain is continuous, as of MIDI controller 64 from a half pedal: dampers
+// lift from a quarter of the way down and clear the strings from three
+// quarters. In between, keys released are released on the Noter, which is
+// told damping if it has a SetDamping method, such as Poly, so notes release
+// slower the further down the pedal. Damping applies to notes releasing too,
+// so pressing the pedal again catches notes still ringing.
+//
+// The soft pedal is passed on to the Noter if it has a SetSoft method, such
+// as Karplus, Additive, and Multisampler, which soften notes played while down
+// as una corda; other Noters are played at lower velocity instead.
+type Pedals struct {
+	nt        Noter
+	down      map[int]bool // keys held
+	sounding  map[int]bool // keys not yet released to nt
+	sost      map[int]bool // keys held by sostenuto
+	level     float64      // of sustain
+	sustain   bool
+	sostenuto bool
+	soft      bool
+}
+
+func NewPedals(nt Noter) *Pedals {
+	return &Pedals{
+		nt:       nt,
+		down:     make(map[int]bool),
+		sounding: make(map[int]bool),
+		sost:     make(map[int]bool),
+	}
+}
+
+func (pd *Pedals) NoteOn(key int, vel float64) {
+	if pd.sounding[key] {
+		pd.nt.NoteOff(key)
+	}
+	pd.down[key], pd.sounding[key] = true, true
+	if _, ok := pd.nt.(interface{ SetSoft(bool) }); pd.soft && !ok {
+		vel *= unaCordaGain.Amp()
+	}
+	pd.nt.NoteOn(key, vel)
+}
+
+func (pd *Pedals) NoteOff(key int) {
+	delete(pd.down, key)
+	pd.release(key)
+}
+
+// release releases key to nt unless held by key or pedal.
+func (pd *Pedals) release(key int) {
+	if !pd.sounding[key] || pd.down[key] || pd.sustain || pd.sost[key] {
+		return
+	}
+	delete(pd.sounding, key)
+	pd.nt.NoteOff(key)
+}
+
+// Sustain reports whether the sustain pedal is down far enough to hold notes.
+func (pd *Pedals) Sustain() bool   { return pd.sustain }
+func (pd *Pedals) Sostenuto() bool { return pd.sostenuto }
+func (pd *Pedals) Soft() bool      { return pd.soft }
+
+// SetSustain sets sustain pedal down or up.
+func (pd *Pedals) SetSustain(down bool) {
+	if down {
+		pd.SetSustainLevel(1)
+	} else {
+		pd.SetSustainLevel(0)
+	}
+}
+
+// SustainLevel returns the position of the sustain pedal belonging to [0..1].
+func (pd *Pedals) SustainLevel() float64 { return pd.level }
+
+// SetSustainLevel sets the sustain pedal to position x belonging to [0..1],
+// from up to down, damping notes released partway.
+func (pd *Pedals) SetSustainLevel(x float64) {
+	pd.level = math.Max(0, math.Min(1, x))
+	pd.sustain = pd.level >= halfPedalHi
+	if dm, ok := pd.nt.(interface{ SetDamping(float64) }); ok {
+		dm.SetDamping(halfdamping(pd.level))
+	}
+	if !pd.sustain {
+		for key := range pd.sounding {
+			pd.release(key)
+		}
+	}
+}
+
+// SetSostenuto sets sostenuto pedal down or up.
+func (pd *Pedals) SetSostenuto(down bool) {
+	if down == pd.sostenuto {
+		return
+	}
+	pd.sostenuto = down
+	if down {
+		for key := range pd.sounding {
+			pd.sost[key] = true
+		}
+		return
+	}
+	sost := pd.sost
+	pd.sost = make(map[int]bool)
+	for key := range sost {
+		pd.release(key)
+	}
+}
+
+// SetSoft sets soft pedal down or up, softening notes played while down.
+func (pd *Pedals) SetSoft(down bool) {
+	pd.soft = down
+	if sf, ok := pd.nt.(interface{ SetSoft(bool) }); ok {
+		sf.SetSoft(down)
+	}
+}
+
+// Control handles a MIDI control change of sustain, continuous from 0 up to
+// 127 down, or sostenuto or soft, where values of 64 and above are down, and
+// reports whether cc was handled.
+func (pd *Pedals) Control(cc, val int) bool {
+	switch cc {
+	case CtrlSustain:
+		pd.SetSustainLevel(float64(val) / 127)
+	case CtrlSostenuto:
+		pd.SetSostenuto(val >= 64)
+	case CtrlSoft:
+		pd.SetSoft(val >= 64)
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,130 @@
+package snd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noteLog records notes played as a string.
+type noteLog struct{ strings.Builder }
+
+func (nl *noteLog) NoteOn(key int, vel float64) { fmt.Fprintf(nl, "+%v ", key) }
+func (nl *noteLog) NoteOff(key int)             { fmt.Fprintf(nl, "-%v ", key) }
+
+func TestPedalsSustain(t *testing.T) {
+	var nl noteLog
+	pd := NewPedals(&nl)
+	pd.NoteOn(60, 1)
+	pd.Control(CtrlSustain, 127)
+	pd.NoteOff(60)
+	pd.NoteOn(64, 1)
+	pd.NoteOff(64)
+	pd.NoteOn(60, 1) // restrike
+	pd.Control(CtrlSustain, 0)
+	pd.NoteOff(60)
+	if s, want := nl.String(), "+60 +64 -60 +60 -64 -60 "; s != want {
+		t.Fatalf("have %q, want %q", s, want)
+	}
+}
+
+func TestPedalsSostenuto(t *testing.T) {
+	var nl noteLog
+	pd := NewPedals(&nl)
+	pd.NoteOn(60, 1)
+	pd.SetSostenuto(true)
+	pd.NoteOff(60) // held
+	pd.NoteOn(64, 1)
+	pd.NoteOff(64) // not held
+	pd.SetSostenuto(false)
+	if s, want := nl.String(), "+60 +64 -64 -60 "; s != want {
+		t.Fatalf("have %q, want %q", s, want)
+	}
+}
+
+// dampLog records notes played and damping set.
+type dampLog struct {
+	noteLog
+	damping float64
+}
+
+func (dl *dampLog) SetDamping(x float64) { dl.damping = x }
+
+func TestPedalsHalf(t *testing.T) {
+	var dl dampLog
+	pd := NewPedals(&dl)
+	pd.NoteOn(60, 1)
+	pd.Control(CtrlSustain, 64)
+	if dl.damping <= 0 || dl.damping >= 1 {
+		t.Fatalf("have damping %v at half pedal, want partial", dl.damping)
+	}
+	pd.NoteOff(60) // released, damped partly
+	pd.Control(CtrlSustain, 127)
+	if dl.damping != 0 {
+		t.Fatalf("have damping %v with pedal down, want 0", dl.damping)
+	}
+	pd.NoteOn(62, 1)
+	pd.NoteOff(62) // held
+	pd.Control(CtrlSustain, 0)
+	if dl.damping != 1 {
+		t.Fatalf("have damping %v with pedal up, want 1", dl.damping)
+	}
+	if s, want := dl.String(), "+60 -60 +62 -62 "; s != want {
+		t.Fatalf("have %q, want %q", s, want)
+	}
+}
+
+func TestPedalsRepedal(t *testing.T) {
+	p := NewPoly(1, func() Voice {
+		env := NewADSR(time.Millisecond, time.Millisecond, time.Millisecond, 100*time.Millisecond, 0.5, 1, nil)
+		return NewOscVoice(Sine(), env)
+	})
+	pd := NewPedals(p)
+	pd.NoteOn(69, 1)
+	Render(p, DefaultBufferLen)
+	pd.NoteOff(69)
+	Render(p, Dtof(20*time.Millisecond, DefaultSampleRate))
+	pd.SetSustain(true) // catches the note still ringing
+	Render(p, Dtof(time.Second, DefaultSampleRate))
+	if pk := Peak(Render(p, DefaultBufferLen)); pk < 0.01 {
+		t.Fatalf("have peak %v repedaled, want ringing", pk)
+	}
+	pd.SetSustainLevel(0.5)
+	Render(p, Dtof(100*time.Millisecond, DefaultSampleRate))
+	if n := p.Active(); n != 1 {
+		t.Fatalf("have %v active half damped, want 1", n)
+	}
+	pd.SetSustain(false)
+	Render(p, Dtof(200*time.Millisecond, DefaultSampleRate))
+	if n := p.Active(); n != 0 {
+		t.Fatalf("have %v active damped, want 0", n)
+	}
+}
+
+// velLog records the velocity of the last note played.
+type velLog struct{ vel float64 }
+
+func (vl *velLog) NoteOn(key int, vel float64) { vl.vel = vel }
+func (vl *velLog) NoteOff(key int)             {}
+
+func TestPedalsSoft(t *testing.T) {
+	var vl velLog
+	pd := NewPedals(&vl)
+	pd.Control(CtrlSoft, 127)
+	pd.NoteOn(60, 1)
+	if want := unaCordaGain.Amp(); vl.vel != want {
+		t.Fatalf("have velocity %v una corda, want %v", vl.vel, want)
+	}
+	pd.Control(CtrlSoft, 0)
+	pd.NoteOn(60, 1)
+	if vl.vel != 1 {
+		t.Fatalf("have velocity %v, want 1", vl.vel)
+	}
+
+	ks := NewKarplus(1, time.Second)
+	NewPedals(ks).SetSoft(true)
+	if !ks.Soft() {
+		t.Fatal("soft pedal not passed on to Karplus")
+	}
+}
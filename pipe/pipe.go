@@ -0,0 +1,152 @@
+// Package pipe presents a graph as an external instrument over a pair of
+// streams: raw MIDI read from one plays notes, and audio is written to the
+// other as raw interleaved PCM, such as stdin and stdout of a process run by
+// a DAW, or bridged to JACK with tools such as a2jmidid and jack-stdin.
+//
+// The graph is prepared as fast as its audio is read, so a reader consuming
+// audio in real time paces it, and notes take effect at the start of the next
+// buffer prepared after they are read.
+package pipe // import "dasa.cc/snd/pipe"
+
+import (
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/midi"
+)
+
+// Format is the encoding of samples written.
+type Format int
+
+const (
+	S16 Format = iota // signed 16-bit little endian
+	F32               // 32-bit float little endian
+)
+
+// Instrument plays a graph by MIDI over a pipe.
+type Instrument struct {
+	// Format of samples written.
+	Format Format
+
+	// Tail is the duration rendered after MIDI input ends, such as for
+	// releases and reverb to ring out, before Serve returns.
+	Tail time.Duration
+
+	out snd.Sound
+	nt  snd.Noter
+	dp  snd.Dispatcher
+}
+
+// New returns Instrument of out played by notes on nt, which may also handle
+// control changes, program changes, pressure, and bend as of midi.Play.
+func New(out snd.Sound, nt snd.Noter) *Instrument {
+	return &Instrument{out: out, nt: nt}
+}
+
+// Dispatcher returns the Dispatcher preparing the graph, such as to add hooks.
+func (in *Instrument) Dispatcher() *snd.Dispatcher { return &in.dp }
+
+// Serve reads MIDI from r and writes audio to w until r ends and Tail is
+// written, or until an error reading or writing, such as when the reader of
+// w has gone. Notes held when r ends are released.
+//
+// MIDI is read on a goroutine that ends with r. Serve does not close r, so
+// if Serve returns on an error writing, callers should close r to stop the
+// goroutine blocked reading it.
+func (in *Instrument) Serve(r io.Reader, w io.Writer) error {
+	msgs := make(chan midi.Message, 256)
+	errc := make(chan error, 1)
+	quit := make(chan struct{})
+	defer close(quit)
+	go func() {
+		rd := midi.NewReader(r)
+		for {
+			m, err := rd.Read()
+			if err != nil {
+				if err != io.EOF {
+					errc <- err
+				}
+				close(msgs)
+				return
+			}
+			select {
+			case msgs <- m:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	held := make(map[int]bool)
+	play := func(m midi.Message) {
+		switch {
+		case m.IsNoteOn():
+			held[int(m.Data1)] = true
+		case m.IsNoteOff():
+			delete(held, int(m.Data1))
+		}
+		midi.Play(in.nt, m)
+	}
+
+	inps := snd.GetInputs(in.out)
+	buf := make([]byte, 0, 4*len(in.out.Samples()))
+	tail := -1 // frames left once input ends
+	for tc := uint64(1); tail != 0; tc++ {
+	drain:
+		for tail < 0 {
+			select {
+			case m, ok := <-msgs:
+				if !ok {
+					for key := range held {
+						in.nt.NoteOff(key)
+					}
+					tail = snd.Dtof(in.Tail, in.out.SampleRate())
+					break drain
+				}
+				play(m)
+			default:
+				break drain
+			}
+		}
+		select {
+		case err := <-errc:
+			return err
+		default:
+		}
+
+		in.dp.Dispatch(tc, inps...)
+		samples := in.out.Samples()
+		if tail >= 0 {
+			n := len(samples) / in.out.Channels()
+			if n > tail {
+				n = tail
+			}
+			samples = samples[:n*in.out.Channels()]
+			tail -= n
+		}
+		buf = in.encode(buf[:0], samples)
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (in *Instrument) encode(b []byte, xs snd.Discrete) []byte {
+	var tmp [4]byte
+	for _, x := range xs {
+		switch in.Format {
+		case F32:
+			binary.LittleEndian.PutUint32(tmp[:], math.Float32bits(float32(x)))
+			b = append(b, tmp[:4]...)
+		default:
+			x = math.Max(-1, math.Min(1, x))
+			binary.LittleEndian.PutUint16(tmp[:], uint16(int16(math.Round(x*math.MaxInt16))))
+			b = append(b, tmp[:2]...)
+		}
+	}
+	return b
+}
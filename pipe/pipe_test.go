@@ -0,0 +1,99 @@
+package pipe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"runtime"
+	"testing"
+	"time"
+
+	"dasa.cc/snd"
+	"dasa.cc/snd/midi"
+)
+
+// noter plays a constant while any key is held.
+type noter struct {
+	*snd.Const
+	keys map[int]bool
+}
+
+func (nt *noter) NoteOn(key int, vel float64) { nt.keys[key] = true; nt.SetValue(vel) }
+func (nt *noter) NoteOff(key int) {
+	delete(nt.keys, key)
+	if len(nt.keys) == 0 {
+		nt.SetValue(0)
+	}
+}
+
+func TestServe(t *testing.T) {
+	nt := &noter{snd.NewConst(0), make(map[int]bool)}
+	in := New(nt, nt)
+	in.Format = F32
+	in.Tail = 10 * time.Millisecond
+
+	var r bytes.Buffer
+	r.Write(midi.NoteOnMsg(0, 60, 127).Bytes())
+	r.Write(midi.NoteOnMsg(0, 64, 127).Bytes())
+	r.Write(midi.NoteOffMsg(0, 60, 0).Bytes())
+	var w bytes.Buffer
+	if err := in.Serve(&r, &w); err != nil {
+		t.Fatal(err)
+	}
+
+	n := w.Len() / 4
+	tail := snd.Dtof(in.Tail, snd.DefaultSampleRate)
+	if n < tail || (n-tail)%snd.DefaultBufferLen != 0 {
+		t.Fatalf("have %v frames, want buffers and a tail of %v", n, tail)
+	}
+	if len(nt.keys) != 0 {
+		t.Fatalf("have keys %v held after input ended", nt.keys)
+	}
+	last := math.Float32frombits(binary.LittleEndian.Uint32(w.Bytes()[w.Len()-4:]))
+	if last != 0 {
+		t.Fatalf("have %v at end, want silence once released", last)
+	}
+}
+
+// flood reads note ons without end.
+type flood struct{}
+
+func (flood) Read(p []byte) (int, error) {
+	msg := midi.NoteOnMsg(0, 60, 127).Bytes()
+	n := len(p) / len(msg) * len(msg)
+	for i := 0; i < n; i += len(msg) {
+		copy(p[i:], msg)
+	}
+	return n, nil
+}
+
+// broken fails every write.
+type broken struct{}
+
+func (broken) Write([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestServeWriteError(t *testing.T) {
+	nt := &noter{snd.NewConst(0), make(map[int]bool)}
+	before := runtime.NumGoroutine()
+	if err := New(nt, nt).Serve(flood{}, broken{}); err == nil {
+		t.Fatal("have nil error writing to a broken pipe")
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("have %v goroutines after Serve, want %v", n, before)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	in := &Instrument{}
+	b := in.encode(nil, snd.Discrete{1, -1, 2, 0.5})
+	want := []int16{math.MaxInt16, -math.MaxInt16, math.MaxInt16, 16384}
+	for i, x := range want {
+		if have := int16(binary.LittleEndian.Uint16(b[2*i:])); have != x {
+			t.Errorf("sample %v: have %v, want %v", i, have, x)
+		}
+	}
+}
@@ -0,0 +1,389 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// shiftWindow is the length of delay swept by read heads of a shifter.
+const shiftWindow = 50 * time.Millisecond
+
+// shifter shifts pitch of a single channel by reading a delay line with two
+// heads moving at a rate other than the write head, each faded out as it
+// wraps around the window while the other, half a window apart, is faded in.
+type shifter struct {
+	line  []float64
+	w     int
+	win   float64 // frames
+	semis float64
+	rate  float64 // phase advance per frame
+	phase float64
+	heads interpolators
+}
+
+func newshifter(semis, sr float64) *shifter {
+	win := shiftWindow.Seconds() * sr
+	s := &shifter{line: make([]float64, int(win)+4), win: win, heads: newreaders(2)}
+	s.set(semis)
+	return s
+}
+
+func (s *shifter) set(semis float64) {
+	s.semis = semis
+	ratio := math.Pow(2, semis/12)
+	s.rate = (1 - ratio) / s.win
+}
+
+func (s *shifter) process(x float64) float64 {
+	s.line[s.w] = x
+	p0 := s.phase
+	p1 := p0 + 0.5
+	if p1 >= 1 {
+		p1--
+	}
+	// squared sine windows half a cycle apart sum to unity.
+	g0 := math.Sin(math.Pi * p0)
+	g1 := math.Sin(math.Pi * p1)
+	y := g0*g0*s.heads[0].read(s.line, s.w, 1+p0*s.win) + g1*g1*s.heads[1].read(s.line, s.w, 1+p1*s.win)
+
+	s.phase += s.rate
+	s.phase -= math.Floor(s.phase)
+	if s.w++; s.w == len(s.line) {
+		s.w = 0
+	}
+	return y
+}
+
+// PitchShift changes pitch of its input without changing its duration.
+//
+// Shifting is by delay line and suits effects such as shimmer and harmonies.
+// Pure tones near an odd number of half cycles in 25ms, half the window
+// between read heads, may partially cancel and warble as heads wrap.
+type PitchShift struct {
+	*mono
+	ps *shifter
+}
+
+// NewPitchShift returns PitchShift of in by semitones, positive or negative.
+func NewPitchShift(semitones float64, in Sound) *PitchShift {
+	sd := newmono(in)
+	return &PitchShift{mono: sd, ps: newshifter(semitones, sd.sr)}
+}
+
+// Interpolation returns how read heads read the delay line.
+func (ps *PitchShift) Interpolation() Interpolation { return ps.ps.heads.Interpolation() }
+
+// SetInterpolation sets how read heads read the delay line, such as
+// InterpCubic for less dulling of highs.
+func (ps *PitchShift) SetInterpolation(ip Interpolation) { ps.ps.heads.SetInterpolation(ip) }
+
+func (ps *PitchShift) Semitones() float64     { return ps.ps.semis }
+func (ps *PitchShift) SetSemitones(x float64) { ps.ps.set(x) }
+
+func (ps *PitchShift) Params() []*Param {
+	return []*Param{NewParam("semitones", ps.Semitones, ps.SetSemitones).Range(-24, 24, 0).In(UnitSemitones)}
+}
+
+func (ps *PitchShift) Prepare(uint64) {
+	ps.ps.heads.limit(ps.st)
+	for i, x := range ps.in.Samples() {
+		y := ps.ps.process(x)
+		if ps.off {
+			ps.out[i] = 0
+		} else {
+			ps.out[i] = y
+		}
+	}
+}
+
+// yin estimates the fundamental frequency of a single channel by the YIN
+// algorithm of de Cheveigné and Kawahara, once every hop of frames.
+type yin struct {
+	hist    []float64 // ring of recent input
+	w       int
+	win     int // frames integrated per lag
+	min     int // shortest lag
+	hop, n  int
+	lin, d  []float64
+	freq    float64 // last estimate, or 0 if unvoiced
+	clarity float64 // 1 minus aperiodicity of last estimate
+}
+
+// newyin returns yin detecting frequencies between lo and hi hertz.
+func newyin(lo, hi, sr float64) *yin {
+	max := int(sr / lo)
+	win := max
+	y := &yin{
+		win: win,
+		min: int(sr / hi),
+		hop: win / 2,
+		lin: make([]float64, win+max),
+		d:   make([]float64, max+1),
+	}
+	y.hist = make([]float64, len(y.lin))
+	return y
+}
+
+// push adds x and reports whether a new estimate was made.
+func (y *yin) push(x, sr float64) bool {
+	y.hist[y.w] = x
+	if y.w++; y.w == len(y.hist) {
+		y.w = 0
+	}
+	if y.n++; y.n < y.hop {
+		return false
+	}
+	y.n = 0
+	n := copy(y.lin, y.hist[y.w:])
+	copy(y.lin[n:], y.hist[:y.w])
+
+	// cumulative mean normalized difference.
+	var sum float64
+	y.d[0] = 1
+	for tau := 1; tau < len(y.d); tau++ {
+		var dt float64
+		for j := 0; j < y.win; j++ {
+			e := y.lin[j] - y.lin[j+tau]
+			dt += e * e
+		}
+		sum += dt
+		if sum == 0 {
+			y.d[tau] = 1
+		} else {
+			y.d[tau] = dt * float64(tau) / sum
+		}
+	}
+
+	const threshold = 0.15
+	tau := -1
+	for t := y.min; t < len(y.d)-1; t++ {
+		if y.d[t] < threshold {
+			for t+1 < len(y.d)-1 && y.d[t+1] < y.d[t] {
+				t++
+			}
+			tau = t
+			break
+		}
+	}
+	if tau < 0 {
+		y.freq, y.clarity = 0, 0
+		return true
+	}
+	// parabolic interpolation of the minimum.
+	a, b, c := y.d[tau-1], y.d[tau], y.d[tau+1]
+	frac := 0.0
+	if den := a - 2*b + c; den != 0 {
+		frac = (a - c) / (2 * den)
+	}
+	y.freq, y.clarity = sr/(float64(tau)+frac), 1-b
+	return true
+}
+
+// Harmonizer mixes its input with pitch shifted copies at intervals diatonic
+// to a key, e.g. a third and fifth above in C major. The pitch of the input
+// is tracked so intervals follow the scale; pitch of monophonic input such as
+// a voice or a melody line works best.
+type Harmonizer struct {
+	*mono
+	yin     *yin
+	root    int
+	mode    Mode
+	degrees []int
+	voices  []*shifter
+	mix     float64
+	key     int // last detected key, or -1
+}
+
+// NewHarmonizer returns Harmonizer in mode rooted at MIDI key root, adding a
+// voice for each number of scale degrees, positive above and negative below.
+func NewHarmonizer(root int, mode Mode, degrees []int, in Sound) *Harmonizer {
+	sd := newmono(in)
+	hm := &Harmonizer{
+		mono: sd,
+		yin:  newyin(70, 1000, sd.sr),
+		root: root,
+		mode: mode,
+		mix:  0.5,
+		key:  -1,
+	}
+	hm.SetDegrees(degrees...)
+	return hm
+}
+
+// SetKey sets root and mode of the scale followed.
+func (hm *Harmonizer) SetKey(root int, mode Mode) {
+	hm.root, hm.mode = root, mode
+	hm.retune()
+}
+
+func (hm *Harmonizer) Key() (root int, mode Mode) { return hm.root, hm.mode }
+
+// Degrees returns a copy of the scale degrees of each voice.
+func (hm *Harmonizer) Degrees() []int { return append([]int(nil), hm.degrees...) }
+
+// SetDegrees replaces all voices, one for each number of scale degrees.
+func (hm *Harmonizer) SetDegrees(degrees ...int) {
+	hm.degrees = append(hm.degrees[:0], degrees...)
+	hm.voices = make([]*shifter, len(degrees))
+	for i := range hm.voices {
+		hm.voices[i] = newshifter(0, hm.sr)
+	}
+	hm.retune()
+}
+
+// Detected returns the frequency of input last detected, or 0 if unvoiced.
+func (hm *Harmonizer) Detected() float64 { return hm.yin.freq }
+
+// Mix is the level of voices relative to input, where 0 is input only and
+// 1 is voices only.
+func (hm *Harmonizer) Mix() float64     { return hm.mix }
+func (hm *Harmonizer) SetMix(x float64) { hm.mix = x }
+
+func (hm *Harmonizer) Params() []*Param {
+	return []*Param{NewParam("mix", hm.Mix, hm.SetMix).Range(0, 1, 0.5).In(UnitPercent)}
+}
+
+// retune sets the interval of each voice from the last detected key.
+func (hm *Harmonizer) retune() {
+	if hm.key < 0 {
+		return
+	}
+	for i, deg := range hm.degrees {
+		hm.voices[i].set(float64(hm.mode.Transpose(hm.root, hm.key, deg) - hm.key))
+	}
+}
+
+func (hm *Harmonizer) Prepare(uint64) {
+	for _, s := range hm.voices {
+		s.heads.limit(hm.st)
+	}
+	for i, x := range hm.in.Samples() {
+		// keep last intervals through unvoiced input.
+		if hm.yin.push(x, hm.sr) && hm.yin.freq > 0 {
+			if key := int(math.Floor(keyof(hm.yin.freq, hm.st.concertPitch()) + 0.5)); key != hm.key {
+				hm.key = key
+				hm.retune()
+			}
+		}
+		var wet float64
+		for _, v := range hm.voices {
+			wet += v.process(x)
+		}
+		if n := len(hm.voices); n > 0 {
+			wet /= float64(n)
+		}
+		if hm.off {
+			hm.out[i] = 0
+		} else {
+			hm.out[i] = (1-hm.mix)*x + hm.mix*wet
+		}
+	}
+}
+
+// PitchTrack outputs the tracked frequency of its input in hertz, times a
+// ratio, such as to drive the frequency of an Oscil from a guitar or voice.
+// Frequency glides between estimates and holds through unvoiced input.
+//
+// Sync outputs a trigger at the start of each period of input while voiced;
+// pass it to Oscil.SetSync to also lock phase, with a ratio of a whole number.
+type PitchTrack struct {
+	*mono
+	yin   *yin
+	ratio float64
+	glide time.Duration
+	coef  float64
+	freq  float64 // glided frequency of input
+	sync  *tracksync
+
+	prev float64
+	n    int // frames since last sync
+}
+
+// tracksync is the Sync output of a PitchTrack, written during its Prepare.
+type tracksync struct {
+	*mono
+	pt *PitchTrack
+}
+
+func (ts *tracksync) Inputs() []Sound { return []Sound{ts.pt} }
+func (ts *tracksync) Prepare(uint64)  {}
+
+// NewPitchTrack returns PitchTrack of in detecting frequencies between lo and
+// hi hertz, with a ratio of 1 and a glide of 10ms. Frequencies are limited to
+// between 20Hz, bounding the frames analysed, and nyquist.
+func NewPitchTrack(lo, hi float64, in Sound) *PitchTrack {
+	sd := newmono(in)
+	if !(lo >= 20) {
+		lo = 20
+	} else if lo > sd.sr/4 {
+		lo = sd.sr / 4
+	}
+	if !(hi >= lo) {
+		hi = lo
+	} else if hi > sd.sr/2 {
+		hi = sd.sr / 2
+	}
+	pt := &PitchTrack{mono: sd, yin: newyin(lo, hi, sd.sr), ratio: 1}
+	pt.sync = &tracksync{mono: newmono(nil), pt: pt}
+	pt.SetGlide(10 * time.Millisecond)
+	return pt
+}
+
+// Ratio returns the multiple of tracked frequency output.
+func (pt *PitchTrack) Ratio() float64 { return pt.ratio }
+
+// SetRatio sets the multiple of tracked frequency output, e.g. 0.5 for an
+// octave below.
+func (pt *PitchTrack) SetRatio(x float64) { pt.ratio = x }
+
+func (pt *PitchTrack) Glide() time.Duration { return pt.glide }
+
+// SetGlide sets the time to glide to a new estimate.
+func (pt *PitchTrack) SetGlide(d time.Duration) {
+	pt.glide, pt.coef = d, smoothcoef(d, pt.sr)
+}
+
+// Detected returns the frequency of input last detected, or 0 if unvoiced.
+func (pt *PitchTrack) Detected() float64 { return pt.yin.freq }
+
+// Sync returns a trigger at the start of each period of input.
+func (pt *PitchTrack) Sync() Sound { return pt.sync }
+
+func (pt *PitchTrack) Params() []*Param {
+	return []*Param{
+		NewParam("ratio", pt.Ratio, pt.SetRatio).Range(0.125, 8, 1),
+		NewParam("glide",
+			func() float64 { return float64(pt.glide) / float64(time.Millisecond) },
+			func(x float64) { pt.SetGlide(time.Duration(x * float64(time.Millisecond))) }).Range(0, 1000, 20).In(UnitMilliseconds),
+	}
+}
+
+func (pt *PitchTrack) Prepare(uint64) {
+	for i, x := range pt.in.Samples() {
+		if pt.yin.push(x, pt.sr) && pt.yin.freq > 0 && pt.freq == 0 {
+			// first estimate is taken at once rather than glided up from zero.
+			pt.freq = pt.yin.freq
+		}
+		if pt.yin.freq > 0 {
+			pt.freq += pt.coef * (pt.yin.freq - pt.freq)
+		}
+
+		// a rising zero crossing at least most of a period since the last
+		// begins a period; closer crossings are of harmonics.
+		pt.n++
+		pt.sync.out[i] = 0
+		if pt.prev <= 0 && x > 0 && pt.yin.freq > 0 && float64(pt.n) >= 0.75*pt.sr/pt.yin.freq {
+			pt.n = 0
+			if !pt.off {
+				pt.sync.out[i] = 1
+			}
+		}
+		pt.prev = x
+
+		if pt.off {
+			pt.out[i] = 0
+		} else {
+			pt.out[i] = pt.ratio * pt.freq
+		}
+	}
+}
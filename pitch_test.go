@@ -0,0 +1,72 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// goertzel returns magnitude of sig at frequency hz.
+func goertzel(sig Discrete, hz, sr float64) float64 {
+	w := 2 * math.Pi * hz / sr
+	c := 2 * math.Cos(w)
+	var s1, s2 float64
+	for _, x := range sig {
+		s1, s2 = x+c*s1-s2, s1
+	}
+	return math.Sqrt(s1*s1+s2*s2-c*s1*s2) / float64(len(sig))
+}
+
+func TestPitchShift(t *testing.T) {
+	n := Dtof(time.Second, DefaultSampleRate)
+	for _, semis := range []float64{12, 7, -12} {
+		// 200Hz keeps read heads, half a window apart, in phase.
+		ps := NewPitchShift(semis, NewOscil(Sine(), 200, nil))
+		out := Render(ps, n)[n/2:]
+		want := 200 * math.Pow(2, semis/12)
+		if a, b := goertzel(out, want, DefaultSampleRate), goertzel(out, 200, DefaultSampleRate); a < 0.2 || a < 10*b {
+			t.Errorf("shift %v: have magnitude %.4f at %.1fHz and %.4f at 200Hz", semis, a, want, b)
+		}
+	}
+}
+
+func TestHarmonizer(t *testing.T) {
+	n := Dtof(time.Second, DefaultSampleRate)
+	// 200Hz is nearest G3, a major third in C to B3, up 4 semitones.
+	hm := NewHarmonizer(60, ModeMajor, []int{2}, NewOscil(Sine(), 200, nil))
+	hm.SetMix(1)
+	out := Render(hm, n)[n/2:]
+	if f := hm.Detected(); math.Abs(f-200) > 1 {
+		t.Fatalf("have detected %vHz, want 200Hz", f)
+	}
+	want := 200 * math.Pow(2, 4.0/12)
+	if a, b := goertzel(out, want, DefaultSampleRate), goertzel(out, 200, DefaultSampleRate); a < 0.2 || a < 10*b {
+		t.Errorf("have magnitude %.4f at %.1fHz and %.4f at 200Hz", a, want, b)
+	}
+}
+
+func TestPitchTrack(t *testing.T) {
+	n := Dtof(time.Second, DefaultSampleRate)
+	pt := NewPitchTrack(70, 1000, NewOscil(Sine(), 220, nil))
+	pt.SetRatio(2)
+	osc := NewOscil(Sawtooth(), 1, pt)
+	osc.SetSync(pt.Sync())
+	out := Render(osc, n)[n/2:]
+	if f := pt.Detected(); math.Abs(f-220) > 1 {
+		t.Fatalf("have detected %vHz, want 220Hz", f)
+	}
+	if a, b := goertzel(out, 440, DefaultSampleRate), goertzel(out, 220, DefaultSampleRate); a < 0.1 || a < 10*b {
+		t.Errorf("have magnitude %.4f at 440Hz and %.4f at 220Hz", a, b)
+	}
+
+	// phase resets on every period of input.
+	idx := trigs(pt.Sync().Samples())
+	if len(idx) == 0 {
+		t.Fatal("have no sync triggers")
+	}
+	for _, i := range idx {
+		if x := osc.Samples()[i]; x != Sawtooth().At(0) {
+			t.Fatalf("have %v at sync, want %v", x, Sawtooth().At(0))
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// LoopMode selects what a Player does on reaching the end of its region.
+type LoopMode int
+
+const (
+	LoopOff      LoopMode = iota // stop
+	LoopForward                  // restart from the beginning
+	LoopPingPong                 // reverse direction at either end
+)
+
+// Player plays back interleaved samples, such as a decoded audio file.
+//
+// Samples recorded at a sample rate other than the player's are converted
+// during playback by linear interpolation.
+//
+// Playback may be limited to a region of samples, reversed, and looped
+// forward or back and forth. Forward loops may crossfade the end of the
+// region into its beginning to hide a seam.
+type Player struct {
+	*mono
+	sig   Discrete
+	chans int
+	nfr   int
+	srcsr float64
+
+	start, end int // region in frames
+	xf         int // crossfade in frames
+	rev        bool
+	mode       LoopMode
+
+	pos   float64 // read position in frames from the start of region, or end if reversed
+	dir   float64 // direction of ping-pong, 1 or -1
+	step  float64 // frames advanced per output frame
+	speed float64
+	mod   Sound
+	done  bool
+}
+
+// NewPlayer returns a Player of sig with chans channels recorded at sample rate sr.
+func NewPlayer(sig Discrete, chans int, sr float64) *Player {
+	sd := newmono(nil)
+	sd.out = make(Discrete, DefaultBufferLen*chans)
+	nfr := len(sig) / chans
+	return &Player{
+		mono:  sd,
+		sig:   sig,
+		chans: chans,
+		nfr:   nfr,
+		srcsr: sr,
+		end:   nfr,
+		dir:   1,
+		step:  sr / sd.sr,
+		speed: 1,
+	}
+}
+
+func (pl *Player) Channels() int   { return pl.chans }
+func (pl *Player) Inputs() []Sound { return []Sound{pl.mod} }
+
+// Len returns the number of frames of the played samples.
+func (pl *Player) Len() int { return pl.nfr }
+
+// Pos returns the current frame position.
+func (pl *Player) Pos() int {
+	if pl.rev {
+		if p := pl.end - 1 - int(pl.pos); p > pl.start {
+			return p
+		}
+		return pl.start
+	}
+	return pl.start + int(pl.pos)
+}
+
+// Seek sets the frame position, clamped to the region; playback resumes if finished.
+func (pl *Player) Seek(frame int) {
+	if frame < pl.start {
+		frame = pl.start
+	} else if frame > pl.end {
+		frame = pl.end
+	}
+	if pl.rev {
+		pl.pos = float64(pl.end - frame - 1)
+		if pl.pos < 0 {
+			pl.pos = 0
+		}
+	} else {
+		pl.pos = float64(frame - pl.start)
+	}
+	pl.dir = 1
+	pl.done = !pl.rev && frame == pl.end
+}
+
+// SetLoop sets whether playback restarts from the beginning when finished.
+func (pl *Player) SetLoop(b bool) {
+	if b {
+		pl.mode = LoopForward
+	} else {
+		pl.mode = LoopOff
+	}
+}
+
+func (pl *Player) Loop() LoopMode           { return pl.mode }
+func (pl *Player) SetLoopMode(m LoopMode)   { pl.mode = m }
+func (pl *Player) Reverse() bool            { return pl.rev }
+func (pl *Player) Region() (start, end int) { return pl.start, pl.end }
+
+// SetReverse sets whether playback runs from the end of region to its start,
+// and seeks to where reversed playback begins.
+func (pl *Player) SetReverse(b bool) {
+	pl.rev = b
+	pl.pos, pl.dir, pl.done = 0, 1, false
+}
+
+// SetRegion limits playback to frames [start, end) and seeks to its
+// beginning. An end of zero or less plays to the end of samples.
+func (pl *Player) SetRegion(start, end int) {
+	if end <= 0 || end > pl.nfr {
+		end = pl.nfr
+	}
+	if start < 0 {
+		start = 0
+	} else if start > end {
+		start = end
+	}
+	pl.start, pl.end = start, end
+	pl.SetCrossfade(pl.xf)
+	pl.pos, pl.dir, pl.done = 0, 1, false
+}
+
+// SetRegionTime is SetRegion with offsets as durations into the samples.
+func (pl *Player) SetRegionTime(start, end time.Duration) {
+	pl.SetRegion(Dtof(start, pl.srcsr), Dtof(end, pl.srcsr))
+}
+
+// Crossfade returns the length of crossfade of forward loops in frames.
+func (pl *Player) Crossfade() int { return pl.xf }
+
+// SetCrossfade sets the number of frames at the end of region faded into the
+// beginning of region when looping forward, limited to half the region.
+func (pl *Player) SetCrossfade(frames int) {
+	if max := (pl.end - pl.start) / 2; frames > max {
+		frames = max
+	}
+	if frames < 0 {
+		frames = 0
+	}
+	pl.xf = frames
+}
+
+// SetCrossfadeTime is SetCrossfade as a duration of samples.
+func (pl *Player) SetCrossfadeTime(d time.Duration) { pl.SetCrossfade(Dtof(d, pl.srcsr)) }
+
+// Speed returns the rate of playback relative to the recorded rate.
+func (pl *Player) Speed() float64 { return pl.speed }
+
+// SetSpeed sets the rate of playback, changing duration and pitch alike,
+// where 2 plays twice as fast and an octave higher. Negative speeds play
+// toward the beginning of region, wrapping to its end if looping forward.
+func (pl *Player) SetSpeed(x float64) {
+	pl.speed = x
+	pl.step = pl.srcsr / pl.sr * x
+}
+
+// SpeedMod returns the signal speed is multiplied by, or nil.
+func (pl *Player) SpeedMod() Sound { return pl.mod }
+
+// SetSpeedMod multiplies speed by the value of mod at each frame, such as
+// vibrato or a glide between pitches, as mod of a Pitched Sound does. A nil
+// mod plays at a static speed.
+func (pl *Player) SetSpeedMod(mod Sound) { pl.mod = mod }
+
+// Conform sets speed so samples at tempo from play at tempo to, such as a
+// loop of tempo found by DetectBPM played along with a Transport. Tempos of
+// zero or less, as DetectBPM returns on finding none, leave speed as is.
+func (pl *Player) Conform(from, to BPM) {
+	if from <= 0 || to <= 0 {
+		return
+	}
+	pl.SetSpeed(float64(to / from))
+}
+
+// Done reports whether playback reached the end without looping.
+func (pl *Player) Done() bool { return pl.done }
+
+// read returns channel c at read position pos by linear interpolation.
+func (pl *Player) read(pos float64, c int) float64 {
+	n := pl.end - pl.start
+	j := int(pos)
+	frac := pos - float64(j)
+	k := j + 1
+	if k >= n {
+		k = j
+		if pl.mode == LoopForward {
+			k = pl.xf
+		}
+	}
+	at := func(p int) float64 {
+		if pl.rev {
+			return pl.sig[(pl.end-1-p)*pl.chans+c]
+		}
+		return pl.sig[(pl.start+p)*pl.chans+c]
+	}
+	return (1-frac)*at(j) + frac*at(k)
+}
+
+func (pl *Player) Prepare(uint64) {
+	n := float64(pl.end - pl.start)
+	for i := 0; i < len(pl.out); i += pl.chans {
+		if pl.off || pl.done || n == 0 {
+			for c := 0; c < pl.chans; c++ {
+				pl.out[i+c] = 0
+			}
+			continue
+		}
+
+		// within crossfade, blend toward the frame xf into the loop, where
+		// playback continues on wrapping.
+		xf := float64(pl.xf)
+		t := 0.0
+		if pl.mode == LoopForward && xf > 0 && pl.pos >= n-xf {
+			t = (pl.pos - (n - xf)) / xf
+		}
+		for c := 0; c < pl.chans; c++ {
+			x := pl.read(pl.pos, c)
+			if t > 0 {
+				x = (1-t)*x + t*pl.read(pl.pos-(n-xf), c)
+			}
+			pl.out[i+c] = x
+		}
+
+		step := pl.step
+		if pl.mod != nil {
+			step *= pl.mod.Index(i / pl.chans)
+		}
+		pl.pos += step * pl.dir
+		switch pl.mode {
+		case LoopForward:
+			// steps may span the loop many times, either way; xf is at most
+			// half of n, and the sum is kept below n against rounding.
+			if pl.pos >= n || pl.pos < 0 {
+				r := math.Mod(pl.pos-xf, n-xf)
+				if r < 0 {
+					r += n - xf
+				}
+				pl.pos = math.Min(xf+r, math.Nextafter(n, 0))
+			}
+		case LoopPingPong:
+			if m := n - 1; m == 0 {
+				pl.pos = 0
+			} else if pl.pos >= m || pl.pos < 0 {
+				// reflect off either end as many times as the step spans.
+				k := math.Floor(pl.pos / m)
+				pl.pos -= k * m
+				if math.Mod(k, 2) != 0 {
+					pl.pos, pl.dir = m-pl.pos, -pl.dir
+				}
+			}
+		default:
+			if pl.pos >= n {
+				pl.pos, pl.done = n, true
+			} else if pl.pos < 0 {
+				pl.pos, pl.done = 0, true
+			}
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPlayer(t *testing.T) {
+	sig := Discrete{1, -1, 2, -2, 3, -3}
+	pl := NewPlayer(sig, 2, DefaultSampleRate)
+	pl.Prepare(1)
+	out := pl.Samples()
+	for i, x := range sig {
+		if out[i] != x {
+			t.Fatalf("out[%v] have %v, want %v", i, out[i], x)
+		}
+	}
+	if out[len(sig)] != 0 || !pl.Done() {
+		t.Fatal("player did not finish")
+	}
+
+	pl.SetLoop(true)
+	pl.Seek(2)
+	pl.Prepare(2)
+	if out[0] != 3 || out[2] != 1 {
+		t.Fatalf("loop have %v, want [3 -3 1 -1 ...]", out[:4])
+	}
+}
+
+func TestPlayerSpeedMod(t *testing.T) {
+	sig := make(Discrete, 100)
+	for i := range sig {
+		sig[i] = float64(i)
+	}
+	pl := NewPlayer(sig, 1, DefaultSampleRate)
+	pl.SetSpeed(0.5)
+	pl.SetSpeedMod(NewConst(4))
+	out := Render(pl, 10)
+	if out[1] != 2 || out[5] != 10 {
+		t.Fatalf("have %v, want steps of 2", out)
+	}
+}
+
+func BenchmarkPlayer(b *testing.B) {
+	sig := make(Discrete, 44100*2)
+	pl := NewPlayer(sig, 2, 48000)
+	pl.SetLoop(true)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		pl.Prepare(uint64(n))
+	}
+}
+
+func TestPlayerModes(t *testing.T) {
+	sig := Discrete{0, 1, 2, 3, 4, 5}
+	tests := []struct {
+		name  string
+		setup func(pl *Player)
+		want  []float64
+	}{
+		{"reverse", func(pl *Player) { pl.SetReverse(true) }, []float64{5, 4, 3, 2, 1, 0, 0}},
+		{"region", func(pl *Player) { pl.SetRegion(1, 4) }, []float64{1, 2, 3, 0}},
+		{"reverse loop", func(pl *Player) {
+			pl.SetRegion(1, 4)
+			pl.SetReverse(true)
+			pl.SetLoop(true)
+		}, []float64{3, 2, 1, 3, 2}},
+		{"pingpong", func(pl *Player) {
+			pl.SetRegion(1, 4)
+			pl.SetLoopMode(LoopPingPong)
+		}, []float64{1, 2, 3, 2, 1, 2, 3}},
+		{"crossfade", func(pl *Player) {
+			pl.SetLoop(true)
+			pl.SetCrossfade(2)
+			// frame 5 is half of frame 1, and the loop resumes at frame 2.
+		}, []float64{0, 1, 2, 3, 4, 3, 2, 3, 4, 3}},
+	}
+	for _, tt := range tests {
+		pl := NewPlayer(sig, 1, DefaultSampleRate)
+		tt.setup(pl)
+		pl.Prepare(1)
+		out := pl.Samples()
+		for i, x := range tt.want {
+			if out[i] != x {
+				t.Errorf("%s: have %v, want %v", tt.name, out[:len(tt.want)], tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestPlayerRegionTime(t *testing.T) {
+	pl := NewPlayer(make(Discrete, 44100), 1, 44100)
+	pl.SetRegionTime(250*time.Millisecond, 750*time.Millisecond)
+	if start, end := pl.Region(); start != 11025 || end != 33075 {
+		t.Fatalf("have region [%v, %v), want [11025, 33075)", start, end)
+	}
+	if pl.Pos() != 11025 {
+		t.Fatalf("have pos %v, want 11025", pl.Pos())
+	}
+}
+
+func TestPlayerStereoLoop(t *testing.T) {
+	// left and right are a sine and cosine, a quarter cycle apart, looped
+	// with a crossfade and mixed with a mono sound; each channel keeps its
+	// own samples and phase.
+	const n, period = 1000, 100
+	sig := make(Discrete, 2*n)
+	for f := 0; f < n; f++ {
+		s, c := math.Sincos(2 * math.Pi * float64(f) / period)
+		sig[2*f], sig[2*f+1] = s, c
+	}
+	pl := NewPlayer(sig, 2, DefaultSampleRate)
+	pl.SetLoop(true)
+	pl.SetCrossfade(period)
+	mix := NewMixer(pl, NewConst(0.5))
+	if mix.Channels() != 2 {
+		t.Fatalf("have %v channels of mix, want 2", mix.Channels())
+	}
+	out := Render(mix, 3*n)
+	for f := 0; f < 3*n; f++ {
+		// the crossfade blends frames a whole number of periods apart.
+		s, c := math.Sincos(2 * math.Pi * float64(f) / period)
+		if l, r := out[2*f]-0.5, out[2*f+1]-0.5; !equaleps(l, s, 1e-9) || !equaleps(r, c, 1e-9) {
+			t.Fatalf("have %v, %v at frame %v, want %v, %v", l, r, f, s, c)
+		}
+	}
+}
+
+func TestPlayerLargeStep(t *testing.T) {
+	// steps spanning the region many times wrap or reflect within it.
+	sig := make(Discrete, 1000)
+	for i := range sig {
+		sig[i] = float64(i)
+	}
+	for _, mode := range []LoopMode{LoopForward, LoopPingPong} {
+		pl := NewPlayer(sig, 1, DefaultSampleRate)
+		pl.SetRegion(0, 2)
+		pl.SetLoopMode(mode)
+		pl.SetSpeed(8.5)
+		for _, x := range Render(pl, 100) {
+			if x < 0 || x > 1 {
+				t.Fatalf("mode %v: have %v, want within region", mode, x)
+			}
+		}
+	}
+}
+
+func TestPlayerNegativeSpeed(t *testing.T) {
+	sig := Discrete{0, 1, 2, 3, 4, 5}
+	tests := []struct {
+		mode LoopMode
+		want []float64
+	}{
+		{LoopOff, []float64{0, 0, 0}},
+		{LoopForward, []float64{0, 5, 4, 3, 2, 1, 0, 5}},
+		{LoopPingPong, []float64{0, 1, 2, 3, 4, 5, 4, 3}},
+	}
+	for _, tt := range tests {
+		pl := NewPlayer(sig, 1, DefaultSampleRate)
+		pl.SetLoopMode(tt.mode)
+		pl.SetSpeed(-1)
+		out := Render(pl, len(tt.want))
+		for i, x := range tt.want {
+			if out[i] != x {
+				t.Errorf("mode %v: have %v, want %v", tt.mode, out, tt.want)
+				break
+			}
+		}
+		if tt.mode == LoopOff && !pl.Done() {
+			t.Errorf("mode %v: player did not finish", tt.mode)
+		}
+	}
+
+	pl := NewPlayer(sig, 1, DefaultSampleRate)
+	pl.Conform(DetectBPM(make(Discrete, 1000), 1, DefaultSampleRate), 90)
+	if pl.Speed() != 1 {
+		t.Fatalf("have speed %v conforming from no tempo, want 1", pl.Speed())
+	}
+}
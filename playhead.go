@@ -0,0 +1,103 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// playheadJump is how far a measure of when frames are heard may stray from
+// the smoothed before it is taken outright, as when output moves to a
+// Bluetooth device and latency grows.
+const playheadJump = 50 * time.Millisecond
+
+// Playhead tells when frames prepared by a Dispatcher are heard from the
+// speaker, to sync visuals to sound, such as lighting keys of a keyboard as
+// their notes sound: a buffer prepared now is heard once the output's latency
+// has passed, which on Bluetooth may be a quarter second or more.
+//
+// Each buffer prepared is timestamped by the latency reported by the output
+// at the time, such as Engine.Latency of package mobile or
+// Engine.SoftLatency of package al, and timestamps are smoothed of jitter
+// of scheduling. Latency may change while playing; a change greater than
+// 50ms is taken at once.
+type Playhead struct {
+	sr     float64
+	remove func()
+	now    func() time.Time
+
+	mu      sync.Mutex
+	latency func() time.Duration
+	lat     time.Duration // as last reported
+	base    time.Time     // when frame zero is heard, smoothed
+	ok      bool
+}
+
+// NewPlayhead returns Playhead of out, the output of a graph prepared by dp,
+// heard latency after each buffer is prepared, until Close. A nil latency is
+// none, of output heard as prepared.
+func NewPlayhead(dp *Dispatcher, out Sound, latency func() time.Duration) *Playhead {
+	ph := &Playhead{sr: out.SampleRate(), now: time.Now, latency: latency}
+	ph.remove = dp.AfterDispatch(ph.after)
+	return ph
+}
+
+// SetLatency sets fn reporting latency of the output, such as after moving
+// to another output. It is called on the audio thread after each buffer.
+func (ph *Playhead) SetLatency(fn func() time.Duration) {
+	ph.mu.Lock()
+	ph.latency = fn
+	ph.mu.Unlock()
+}
+
+// Latency returns latency of the output as last reported.
+func (ph *Playhead) Latency() time.Duration {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	return ph.lat
+}
+
+// Close stops timestamping buffers.
+func (ph *Playhead) Close() { ph.remove() }
+
+func (ph *Playhead) after(tc, frame uint64) {
+	now := ph.now()
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.lat = 0
+	if ph.latency != nil {
+		ph.lat = ph.latency()
+	}
+	base := now.Add(ph.lat - Ftod(int(frame), ph.sr))
+	d := base.Sub(ph.base)
+	if !ph.ok || math.Abs(float64(d)) > float64(playheadJump) {
+		ph.base, ph.ok = base, true
+		return
+	}
+	ph.base = ph.base.Add(d / 16)
+}
+
+// At returns when frame is heard, counting frames as of Hook, or zero time
+// if no buffer was prepared yet.
+func (ph *Playhead) At(frame uint64) time.Time {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	if !ph.ok {
+		return time.Time{}
+	}
+	return ph.base.Add(Ftod(int(frame), ph.sr))
+}
+
+// Frame returns the frame heard at t, counting frames as of Hook, and false
+// if t is before the first frame is heard or no buffer was prepared yet.
+func (ph *Playhead) Frame(t time.Time) (frame uint64, ok bool) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	if !ph.ok || t.Before(ph.base) {
+		return 0, false
+	}
+	return uint64(Dtof(t.Sub(ph.base), ph.sr)), true
+}
+
+// Heard returns the frame heard now, as of Frame.
+func (ph *Playhead) Heard() (frame uint64, ok bool) { return ph.Frame(ph.now()) }
@@ -0,0 +1,48 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlayhead(t *testing.T) {
+	osc := NewOscil(Sine(), 440, nil)
+	var dp Dispatcher
+	lat := 20 * time.Millisecond
+	ph := NewPlayhead(&dp, osc, func() time.Duration { return lat })
+	defer ph.Close()
+
+	// buffers are prepared a buffer apart, late by up to a millisecond.
+	start := time.Unix(0, 0)
+	n := len(osc.Samples())
+	tc := 0
+	ph.now = func() time.Time {
+		jitter := time.Duration(tc%3) * 500 * time.Microsecond
+		return start.Add(Ftod(tc*n, osc.SampleRate()) + jitter)
+	}
+	if _, ok := ph.Heard(); ok {
+		t.Error("heard before any buffer prepared")
+	}
+	for ; tc < 100; tc++ {
+		dp.Dispatch(uint64(tc+1), GetInputs(osc)...)
+	}
+	frame := uint64(50 * n)
+	want := start.Add(lat + Ftod(int(frame), osc.SampleRate()))
+	if d := ph.At(frame).Sub(want); d < 0 || d > time.Millisecond {
+		t.Errorf("have frame heard %v late, want within 1ms", d)
+	}
+	if f, ok := ph.Frame(want); !ok || f < frame-48 || f > frame {
+		t.Errorf("have frame %v heard, want about %v", f, frame)
+	}
+
+	// output moving to Bluetooth is taken at once.
+	lat = 250 * time.Millisecond
+	dp.Dispatch(uint64(tc+1), GetInputs(osc)...)
+	if have := ph.Latency(); have != lat {
+		t.Errorf("have latency %v, want %v", have, lat)
+	}
+	want = want.Add(230 * time.Millisecond)
+	if d := ph.At(frame).Sub(want); d < 0 || d > time.Millisecond {
+		t.Errorf("have frame heard %v late after latency changed, want within 1ms", d)
+	}
+}
@@ -0,0 +1,626 @@
+package snd
+
+import "math"
+
+// Voice is a single note of a Poly.
+type Voice interface {
+	Sound
+
+	// NoteOn starts a note at frequency hz and velocity belonging to [0..1].
+	NoteOn(hz, vel float64)
+
+	// NoteOff releases the note.
+	NoteOff()
+
+	// Done reports whether the voice is silent after its note was released,
+	// such as when an envelope completes its release period.
+	Done() bool
+}
+
+// PressureVoice is a Voice whose note responds to pressure belonging to [0..1].
+type PressureVoice interface {
+	Voice
+	SetPressure(x float64)
+}
+
+// DampedVoice is a Voice whose release is damped by damping belonging to
+// [0..1], releasing at full damping as usual, slower with less, and holding
+// without, as of piano strings under dampers lifted by a sustain pedal.
+type DampedVoice interface {
+	Voice
+	SetDamping(x float64)
+}
+
+// VoiceFunc returns a new voice for a Poly. Everything built within a voice,
+// such as an envelope and filter, runs once per voice.
+type VoiceFunc func() Voice
+
+// Poly plays notes over a fixed number of voices. Voices are mixed and passed
+// through shared processing, such as reverb or chorus, that runs once on the
+// mix instead of once per voice.
+//
+// Voices are reclaimed once done after release. A new note takes the voice done
+// the longest, then the voice released the longest, and when all voices are
+// busy the voice with the oldest note is stolen.
+type Poly struct {
+	*mono
+	chans  int
+	voices []Voice
+	keys   []int    // key of each voice or -1 if released
+	done   []bool   // released voice reported done
+	ages   []uint64 // note count of each voice when last changed
+	count  uint64
+	limit  int // voices notes are played on
+	ondone func(Voice)
+
+	mix    *Mixer
+	shared ProcFunc
+	last   Sound
+	gain   float64
+	tune   retune
+	tuning *Tuning
+	bend   float64   // semitones
+	bends  []float64 // semitones of each voice's note
+}
+
+// NewPoly returns Poly of n voices, at least one, built by fn without shared
+// processing, of gain VoiceGain of n sawtooth voices so that playing all of
+// them does not clip.
+func NewPoly(n int, fn VoiceFunc) *Poly {
+	if n < 1 {
+		n = 1
+	}
+	p := &Poly{
+		mono:   newmono(nil),
+		voices: make([]Voice, n),
+		keys:   make([]int, n),
+		done:   make([]bool, n),
+		ages:   make([]uint64, n),
+		bends:  make([]float64, n),
+		mix:    NewMixer(),
+		gain:   VoiceGain(n, CrestSaw, DefaultHeadroom),
+		tune:   newretune(),
+		limit:  n,
+	}
+	for i := range p.voices {
+		p.voices[i] = fn()
+		p.keys[i], p.done[i] = -1, true
+		p.mix.Append(p.voices[i])
+	}
+	p.SetShared(nil)
+	return p
+}
+
+// SetShared sets processing applied to the mix of voices; a nil fn clears it.
+// Backends must be notified after changing shared processing while running.
+func (p *Poly) SetShared(fn ProcFunc) {
+	p.shared = fn
+	p.last = p.mix
+	if fn != nil {
+		p.last = fn(p.mix)
+	}
+	p.chans = p.last.Channels()
+	p.out = make(Discrete, len(p.last.Samples()))
+}
+
+// Shared returns the sound built for shared processing or nil if not set.
+func (p *Poly) Shared() Sound {
+	if p.shared == nil {
+		return nil
+	}
+	return p.last
+}
+
+// Gain returns the gain applied to the output of p.
+func (p *Poly) Gain() float64 { return p.gain }
+
+// SetGain sets the gain applied to the output of p, such as VoiceGain for its
+// number of voices so that playing all of them does not clip.
+func (p *Poly) SetGain(x float64) { p.gain = x }
+
+// Voices returns all voices of p.
+func (p *Poly) Voices() []Voice { return p.voices }
+
+// Limit returns the number of voices notes are played on, all by default,
+// fewer by Quality.
+func (p *Poly) Limit() int { return p.limit }
+
+// SetLimit limits notes to the first n voices of p, at least one, such as to
+// lower CPU use on a slow device; notes held on other voices are released.
+func (p *Poly) SetLimit(n int) {
+	if n < 1 {
+		n = 1
+	} else if n > len(p.voices) {
+		n = len(p.voices)
+	}
+	p.limit = n
+	p.release(p.voicelimit())
+}
+
+// voicelimit returns the number of voices notes are played on by limit and
+// Quality.
+func (p *Poly) voicelimit() int {
+	if n := p.st.voicesOf(len(p.voices)); n < p.limit {
+		return n
+	}
+	return p.limit
+}
+
+// release releases notes held on voices from n.
+func (p *Poly) release(n int) {
+	for i := n; i < len(p.voices); i++ {
+		if p.keys[i] != -1 {
+			p.count++
+			p.keys[i], p.ages[i] = -1, p.count
+			p.voices[i].NoteOff()
+		}
+	}
+}
+
+// Active returns the number of voices playing or releasing a note.
+func (p *Poly) Active() int {
+	var n int
+	for _, done := range p.done {
+		if !done {
+			n++
+		}
+	}
+	return n
+}
+
+// SetOnDone sets fn to be called from Prepare when a released voice is done
+// and reclaimed; a nil fn clears it.
+func (p *Poly) SetOnDone(fn func(Voice)) { p.ondone = fn }
+
+func (p *Poly) Channels() int   { return p.chans }
+func (p *Poly) Inputs() []Sound { return []Sound{p.last} }
+
+// NoteOn plays MIDI key number key at velocity vel belonging to [0..1].
+// Keys unmapped by the tuning are ignored.
+func (p *Poly) NoteOn(key int, vel float64) {
+	hz := p.freq(key)
+	if hz == 0 {
+		return
+	}
+	i := p.alloc()
+	p.count++
+	p.keys[i], p.done[i], p.ages[i], p.bends[i] = key, false, p.count, 0
+	p.voices[i].NoteOn(hz, vel)
+}
+
+// SetTuning tunes keys by tn from the next note, changing notes held on
+// voices that are a LegatoVoice; a nil tn tunes to equal temperament.
+func (p *Poly) SetTuning(tn *Tuning) {
+	p.tuning = tn
+	p.setfreqs()
+}
+
+// Tuning returns the tuning of keys, nil for equal temperament.
+func (p *Poly) Tuning() *Tuning { return p.tuning }
+
+// freq returns the frequency of key tuned and bent.
+func (p *Poly) freq(key int) float64 {
+	return tunedfreq(p.tuning, key, p.tune.ref) * math.Pow(2, p.bend/12)
+}
+
+// Pressure sets pressure of all voices that are a PressureVoice, held until
+// set again.
+func (p *Poly) Pressure(x float64) {
+	for _, vc := range p.voices {
+		if pv, ok := vc.(PressureVoice); ok {
+			pv.SetPressure(x)
+		}
+	}
+}
+
+// SetDamping sets damping of all voices that are a DampedVoice, including
+// voices releasing, so that raising a sustain pedal partway lengthens release
+// of notes and pressing it again catches notes still ringing.
+func (p *Poly) SetDamping(x float64) {
+	for _, vc := range p.voices {
+		if dv, ok := vc.(DampedVoice); ok {
+			dv.SetDamping(x)
+		}
+	}
+}
+
+// Bend shifts pitch of all notes by semitones, changing notes held on voices
+// that are a LegatoVoice without restarting them.
+func (p *Poly) Bend(semitones float64) {
+	p.bend = semitones
+	p.setfreqs()
+}
+
+// NotePressure sets pressure of voices playing key that are a
+// PressureVoice, such as by polyphonic aftertouch.
+func (p *Poly) NotePressure(key int, x float64) {
+	for i, vc := range p.voices {
+		if pv, ok := vc.(PressureVoice); ok && p.keys[i] == key {
+			pv.SetPressure(x)
+		}
+	}
+}
+
+// NoteBend shifts pitch of notes of key by semitones, on top of Bend, on
+// voices that are a LegatoVoice. The shift lasts until the key is played
+// again.
+func (p *Poly) NoteBend(key int, semitones float64) {
+	for i, vc := range p.voices {
+		if p.keys[i] != key {
+			continue
+		}
+		p.bends[i] = semitones
+		if lv, ok := vc.(LegatoVoice); ok {
+			lv.SetFreq(p.freq(key) * math.Pow(2, semitones/12))
+		}
+	}
+}
+
+// setfreqs changes frequency of held keys on voices that are a LegatoVoice.
+func (p *Poly) setfreqs() {
+	for i, vc := range p.voices {
+		if lv, ok := vc.(LegatoVoice); ok && p.keys[i] != -1 {
+			lv.SetFreq(p.freq(p.keys[i]) * math.Pow(2, p.bends[i]/12))
+		}
+	}
+}
+
+// NoteOff releases all voices playing key.
+func (p *Poly) NoteOff(key int) {
+	for i, k := range p.keys {
+		if k == key {
+			p.count++
+			p.keys[i], p.ages[i] = -1, p.count
+			p.voices[i].NoteOff()
+		}
+	}
+}
+
+// Panic releases all keys and silences voices that are a Panicker at once,
+// reclaiming every voice.
+func (p *Poly) Panic() {
+	for i, vc := range p.voices {
+		if p.keys[i] != -1 {
+			vc.NoteOff()
+		}
+		if pv, ok := vc.(Panicker); ok {
+			pv.Panic()
+		}
+		p.count++
+		p.keys[i], p.done[i], p.ages[i] = -1, true, p.count
+	}
+}
+
+// alloc returns the index of the voice to play the next note.
+func (p *Poly) alloc() int {
+	rank := func(i int) int {
+		switch {
+		case p.done[i]:
+			return 0
+		case p.keys[i] == -1:
+			return 1
+		default:
+			return 2
+		}
+	}
+	j := 0
+	for i := 0; i < p.voicelimit(); i++ {
+		if ri, rj := rank(i), rank(j); ri < rj || (ri == rj && p.ages[i] < p.ages[j]) {
+			j = i
+		}
+	}
+	return j
+}
+
+func (p *Poly) Prepare(uint64) {
+	if p.tune.next(p.st.concertPitch(), len(p.out)/p.chans, p.sr) {
+		p.setfreqs()
+	}
+	p.release(p.voicelimit())
+	for i, vc := range p.voices {
+		if p.keys[i] == -1 && !p.done[i] && vc.Done() {
+			p.done[i] = true
+			if p.ondone != nil {
+				p.ondone(vc)
+			}
+		}
+	}
+	if p.off {
+		for i := range p.out {
+			p.out[i] = 0
+		}
+		return
+	}
+	for i, x := range p.last.Samples() {
+		p.out[i] = p.gain * x
+	}
+}
+
+// level is a constant output that may be changed between buffers. Setting
+// a level while high drops output to zero for the first frame so a gate
+// driven by it rises again.
+type level struct {
+	*mono
+	x      float64
+	retrig bool
+}
+
+func newlevel() *level { return &level{mono: newmono(nil)} }
+
+func (lvl *level) set(x float64) {
+	lvl.retrig = lvl.x > 0 && x > 0
+	lvl.x = x
+}
+
+func (lvl *level) Inputs() []Sound { return nil }
+
+func (lvl *level) Prepare(uint64) {
+	for i := range lvl.out {
+		lvl.out[i] = lvl.x
+	}
+	if lvl.retrig {
+		lvl.out[0] = 0
+		lvl.retrig = false
+	}
+}
+
+// OscVoice is a Voice of an oscillator shaped by a gated envelope and scaled by
+// velocity, followed by any per-voice processing. The oscillator is turned off
+// while the voice is done so it does not consume CPU.
+type OscVoice struct {
+	*mono
+	osc   *Oscil
+	env   *ADSR
+	gate  *level
+	last  Sound
+	vel   float64
+	press float64
+}
+
+// NewOscVoice returns OscVoice oscillating over in, shaped by env and passed
+// through fns in order. The envelope's gate is set by the voice.
+func NewOscVoice(in Discrete, env *ADSR, fns ...ProcFunc) *OscVoice {
+	vc := &OscVoice{
+		osc:  NewOscil(in, 440, nil),
+		env:  env,
+		gate: newlevel(),
+	}
+	env.SetGate(vc.gate)
+	vc.osc.SetAmp(0, env)
+	vc.osc.Off()
+	vc.last = vc.osc
+	for _, fn := range fns {
+		vc.last = fn(vc.last)
+	}
+	vc.mono = newmono(nil)
+	vc.out = make(Discrete, len(vc.last.Samples()))
+	return vc
+}
+
+// Osc returns the oscillator of the voice.
+func (vc *OscVoice) Osc() *Oscil { return vc.osc }
+
+// Env returns the envelope of the voice.
+func (vc *OscVoice) Env() *ADSR { return vc.env }
+
+func (vc *OscVoice) Channels() int   { return vc.last.Channels() }
+func (vc *OscVoice) Inputs() []Sound { return []Sound{vc.last} }
+
+func (vc *OscVoice) NoteOn(hz, vel float64) {
+	vc.vel = vel
+	vc.osc.SetFreq(hz, vc.osc.FreqMod())
+	vc.osc.SetAmp(vc.amp(), vc.env)
+	vc.osc.On()
+	vc.gate.set(1)
+}
+
+// SetPressure swells amplitude from velocity toward full as x rises to 1.
+func (vc *OscVoice) SetPressure(x float64) {
+	vc.press = x
+	vc.osc.SetAmp(vc.amp(), vc.env)
+}
+
+// amp returns velocity raised by pressure.
+func (vc *OscVoice) amp() float64 { return vc.vel + (1-vc.vel)*vc.press }
+
+func (vc *OscVoice) NoteOff() { vc.gate.set(0) }
+
+// SetDamping sets damping of the release period of the envelope.
+func (vc *OscVoice) SetDamping(x float64) { vc.env.SetDamping(x) }
+
+func (vc *OscVoice) Done() bool { return vc.gate.x == 0 && vc.env.Idle() }
+
+// Panic drops the gate and idles the envelope so the voice is done at once.
+func (vc *OscVoice) Panic() {
+	vc.gate.set(0)
+	vc.env.Panic()
+	vc.osc.Off()
+}
+
+func (vc *OscVoice) Prepare(uint64) {
+	if vc.Done() {
+		vc.osc.Off()
+	}
+	if vc.off {
+		for i := range vc.out {
+			vc.out[i] = 0
+		}
+		return
+	}
+	copy(vc.out, vc.last.Samples())
+}
+
+// SetFreq changes the frequency of a sounding note without restarting it.
+// Modulation of frequency set on Osc is kept.
+func (vc *OscVoice) SetFreq(hz float64) { vc.osc.SetFreq(hz, vc.osc.FreqMod()) }
+
+// LegatoVoice is a Voice that may change frequency without restarting its note.
+type LegatoVoice interface {
+	Voice
+	SetFreq(hz float64)
+}
+
+// Priority selects which held key a Mono plays.
+type Priority int
+
+const (
+	PriorityLast Priority = iota // most recently pressed
+	PriorityLow                  // lowest
+	PriorityHigh                 // highest
+)
+
+type heldkey struct {
+	key int
+	vel float64
+}
+
+// Mono plays one note at a time over a single voice, choosing among held keys
+// by priority. Releasing a key returns to the next held key by priority.
+//
+// Changing notes restarts the voice unless legato is set, in which case a new
+// note only changes frequency while any key is held. Legato requires the
+// voice be a LegatoVoice and otherwise restarts.
+type Mono struct {
+	*mono
+	vc     Voice
+	held   []heldkey
+	cur    int // key playing or -1
+	prio   Priority
+	legato bool
+	tune   retune
+	tuning *Tuning
+	bend   float64 // semitones
+}
+
+func NewMono(vc Voice) *Mono {
+	sd := newmono(nil)
+	sd.out = make(Discrete, len(vc.Samples()))
+	return &Mono{mono: sd, vc: vc, cur: -1, tune: newretune()}
+}
+
+func (m *Mono) SetPriority(prio Priority) { m.prio = prio }
+func (m *Mono) SetLegato(b bool)          { m.legato = b }
+
+func (m *Mono) Voice() Voice    { return m.vc }
+func (m *Mono) Channels() int   { return m.vc.Channels() }
+func (m *Mono) Inputs() []Sound { return []Sound{m.vc} }
+
+// NoteOn presses MIDI key number key at velocity vel belonging to [0..1].
+// Keys unmapped by the tuning are ignored.
+func (m *Mono) NoteOn(key int, vel float64) {
+	if m.freq(key) == 0 {
+		return
+	}
+	m.remove(key)
+	m.held = append(m.held, heldkey{key, vel})
+	m.update()
+}
+
+// NoteOff releases key, returning to another held key if any.
+func (m *Mono) NoteOff(key int) {
+	m.remove(key)
+	if len(m.held) == 0 {
+		if m.cur != -1 {
+			m.vc.NoteOff()
+			m.cur = -1
+		}
+		return
+	}
+	m.update()
+}
+
+// Panic releases all keys and silences the voice at once if a Panicker.
+func (m *Mono) Panic() {
+	m.held = m.held[:0]
+	if m.cur != -1 {
+		m.vc.NoteOff()
+		m.cur = -1
+	}
+	if pv, ok := m.vc.(Panicker); ok {
+		pv.Panic()
+	}
+}
+
+func (m *Mono) remove(key int) {
+	for i, h := range m.held {
+		if h.key == key {
+			m.held = append(m.held[:i], m.held[i+1:]...)
+			return
+		}
+	}
+}
+
+// update plays the held key of highest priority if not already playing.
+func (m *Mono) update() {
+	h := m.held[len(m.held)-1]
+	for _, x := range m.held {
+		if (m.prio == PriorityLow && x.key < h.key) || (m.prio == PriorityHigh && x.key > h.key) {
+			h = x
+		}
+	}
+	if h.key == m.cur {
+		return
+	}
+	lv, ok := m.vc.(LegatoVoice)
+	if m.legato && ok && m.cur != -1 {
+		lv.SetFreq(m.freq(h.key))
+	} else {
+		m.vc.NoteOn(m.freq(h.key), h.vel)
+	}
+	m.cur = h.key
+}
+
+// SetTuning tunes keys by tn, changing a held note without restarting it if
+// the voice is a LegatoVoice; a nil tn tunes to equal temperament.
+func (m *Mono) SetTuning(tn *Tuning) {
+	m.tuning = tn
+	if lv, ok := m.vc.(LegatoVoice); ok && m.cur != -1 {
+		lv.SetFreq(m.freq(m.cur))
+	}
+}
+
+// Tuning returns the tuning of keys, nil for equal temperament.
+func (m *Mono) Tuning() *Tuning { return m.tuning }
+
+// freq returns the frequency of key tuned and bent.
+func (m *Mono) freq(key int) float64 {
+	return tunedfreq(m.tuning, key, m.tune.ref) * math.Pow(2, m.bend/12)
+}
+
+// Pressure sets pressure of the voice if a PressureVoice.
+func (m *Mono) Pressure(x float64) {
+	if pv, ok := m.vc.(PressureVoice); ok {
+		pv.SetPressure(x)
+	}
+}
+
+// SetDamping sets damping of the voice if it is a DampedVoice.
+func (m *Mono) SetDamping(x float64) {
+	if dv, ok := m.vc.(DampedVoice); ok {
+		dv.SetDamping(x)
+	}
+}
+
+// Bend shifts pitch by semitones, changing a held note without restarting it
+// if the voice is a LegatoVoice.
+func (m *Mono) Bend(semitones float64) {
+	m.bend = semitones
+	if lv, ok := m.vc.(LegatoVoice); ok && m.cur != -1 {
+		lv.SetFreq(m.freq(m.cur))
+	}
+}
+
+func (m *Mono) Prepare(uint64) {
+	if m.tune.next(m.st.concertPitch(), len(m.out)/m.Channels(), m.sr) && m.cur != -1 {
+		if lv, ok := m.vc.(LegatoVoice); ok {
+			lv.SetFreq(m.freq(m.cur))
+		}
+	}
+	if m.off {
+		for i := range m.out {
+			m.out[i] = 0
+		}
+		return
+	}
+	copy(m.out, m.vc.Samples())
+}
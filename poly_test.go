@@ -0,0 +1,245 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func testVoice() Voice {
+	env := NewADSR(time.Millisecond, time.Millisecond, time.Millisecond, time.Millisecond, 0.5, 1, nil)
+	return NewOscVoice(Sine(), env)
+}
+
+func TestPolySteal(t *testing.T) {
+	p := NewPoly(2, testVoice)
+	p.NoteOn(60, 1)
+	p.NoteOn(64, 1)
+	p.NoteOn(67, 1) // steals 60
+	if p.keys[0] != 67 || p.keys[1] != 64 {
+		t.Fatalf("have keys %v, want [67 64]", p.keys)
+	}
+	p.NoteOff(64)
+	p.NoteOn(72, 1) // reuses released voice over oldest note
+	if p.keys[0] != 67 || p.keys[1] != 72 {
+		t.Fatalf("have keys %v, want [67 72]", p.keys)
+	}
+}
+
+func TestPolyShared(t *testing.T) {
+	var n int
+	p := NewPoly(4, testVoice)
+	p.SetShared(func(in Sound) Sound {
+		n++
+		return NewPan(0, in)
+	})
+	if n != 1 {
+		t.Fatalf("shared built %v times, want 1", n)
+	}
+	if p.Channels() != 2 {
+		t.Fatalf("have %v channels, want 2", p.Channels())
+	}
+
+	p.NoteOn(69, 1)
+	out := Render(p, 2*DefaultBufferLen)
+	if Peak(out) == 0 {
+		t.Fatal("no output after note on")
+	}
+
+	p.NoteOff(69)
+	out = Render(p, 4*DefaultBufferLen)
+	if x := Peak(out[len(out)-DefaultBufferLen:]); x != 0 {
+		t.Fatalf("have %v after release, want 0", x)
+	}
+}
+
+func TestPolyGain(t *testing.T) {
+	p := NewPoly(16, testVoice)
+	if g, want := p.Gain(), VoiceGain(16, CrestSaw, DefaultHeadroom); g != want || g >= 1 {
+		t.Errorf("have gain %v of 16 voices, want %v", g, want)
+	}
+}
+
+func TestPolyDone(t *testing.T) {
+	p := NewPoly(2, testVoice)
+	var done []Voice
+	p.SetOnDone(func(vc Voice) { done = append(done, vc) })
+
+	p.NoteOn(60, 1)
+	p.NoteOn(64, 1)
+	Render(p, DefaultBufferLen)
+	if n := p.Active(); n != 2 {
+		t.Fatalf("have %v active, want 2", n)
+	}
+
+	p.NoteOff(60)
+	Render(p, 4*DefaultBufferLen)
+	if n := p.Active(); n != 1 {
+		t.Fatalf("have %v active after release, want 1", n)
+	}
+	if len(done) != 1 || done[0] != p.Voices()[0] {
+		t.Fatalf("have done %v, want first voice", done)
+	}
+	if !p.Voices()[0].(*OscVoice).Osc().IsOff() {
+		t.Fatal("oscillator of done voice still on")
+	}
+
+	p.NoteOff(64)
+	p.NoteOn(67, 1) // takes voice done longest over one still releasing
+	if p.keys[0] != 67 {
+		t.Fatalf("have keys %v, want 67 on first voice", p.keys)
+	}
+}
+
+func TestMonoPriority(t *testing.T) {
+	tests := []struct {
+		prio Priority
+		want int
+	}{
+		{PriorityLast, 62},
+		{PriorityLow, 60},
+		{PriorityHigh, 64},
+	}
+	for _, tt := range tests {
+		m := NewMono(testVoice())
+		m.SetPriority(tt.prio)
+		m.NoteOn(60, 1)
+		m.NoteOn(64, 1)
+		m.NoteOn(62, 1)
+		if m.cur != tt.want {
+			t.Errorf("priority %v: have %v, want %v", tt.prio, m.cur, tt.want)
+		}
+	}
+}
+
+func TestMonoLegato(t *testing.T) {
+	for _, legato := range []bool{false, true} {
+		m := NewMono(testVoice())
+		m.SetLegato(legato)
+		vc := m.Voice().(*OscVoice)
+		m.NoteOn(60, 1)
+		Render(m, DefaultBufferLen)
+		m.NoteOn(67, 1)
+		if retrig := vc.gate.retrig; retrig == legato {
+			t.Errorf("legato %v: have retrigger %v", legato, retrig)
+		}
+		if hz := vc.Osc().Freq(); !equals(hz, KeyFreq(67)) {
+			t.Errorf("legato %v: have %vHz, want %vHz", legato, hz, KeyFreq(67))
+		}
+
+		m.NoteOff(67) // return to held key
+		if m.cur != 60 {
+			t.Errorf("legato %v: have key %v after release, want 60", legato, m.cur)
+		}
+		m.NoteOff(60)
+		if m.cur != -1 || vc.gate.x != 0 {
+			t.Errorf("legato %v: voice not released", legato)
+		}
+	}
+}
+
+func TestPolyRetune(t *testing.T) {
+	p := NewPoly(2, testVoice)
+	dp := new(Dispatcher)
+	dp.Render(p, DefaultBufferLen)
+	p.NoteOn(69, 1)
+	p.NoteOn(81, 1)
+	p.NoteOff(81)
+	dp.SetConcertPitch(442)
+	if hz := dp.ConcertPitch(); hz != 442 {
+		t.Fatalf("have %vHz for A4, want 442Hz", hz)
+	}
+
+	osc := p.Voices()[0].(*OscVoice).Osc()
+	dp.Render(p, DefaultBufferLen)
+	if hz := osc.Freq(); hz <= 440 || hz >= 442 {
+		t.Fatalf("have %vHz after a buffer, want gliding to 442Hz", hz)
+	}
+	dp.Render(p, 44100)
+	if hz := osc.Freq(); hz != 442 {
+		t.Fatalf("have %vHz, want 442Hz", hz)
+	}
+	if hz := p.Voices()[1].(*OscVoice).Osc().Freq(); hz != 880 {
+		t.Fatalf("have %vHz for released voice, want 880Hz", hz)
+	}
+	p.NoteOn(57, 1)
+	if hz := p.Voices()[1].(*OscVoice).Osc().Freq(); hz != 221 {
+		t.Fatalf("have %vHz for new note, want 221Hz", hz)
+	}
+
+	// tuned from the first buffer without a glide, apart from other engines.
+	p2 := NewPoly(1, testVoice)
+	p2.NoteOn(69, 1)
+	dp2 := new(Dispatcher)
+	dp2.SetConcertPitch(432)
+	dp2.Render(p2, DefaultBufferLen)
+	if hz := p2.Voices()[0].(*OscVoice).Osc().Freq(); hz != 432 {
+		t.Fatalf("have %vHz, want 432Hz at once", hz)
+	}
+	if hz := dp.ConcertPitch(); hz != 442 {
+		t.Fatalf("have %vHz of another dispatcher, want 442Hz", hz)
+	}
+}
+
+func TestPolyExpressive(t *testing.T) {
+	var _ Expressive = (*Poly)(nil)
+	var _ Expressive = (*Mono)(nil)
+
+	p := NewPoly(2, testVoice)
+	p.NoteOn(69, 0.5)
+	osc := p.Voices()[0].(*OscVoice).Osc()
+	p.Bend(12)
+	if hz := osc.Freq(); !equaleps(hz, 880, 1e-9) {
+		t.Fatalf("have %vHz bent an octave, want 880Hz", hz)
+	}
+	p.Pressure(1)
+	if amp := osc.Amp(); amp != 1 {
+		t.Fatalf("have amp %v at full pressure, want 1", amp)
+	}
+	p.Pressure(0)
+	if amp := osc.Amp(); amp != 0.5 {
+		t.Fatalf("have amp %v without pressure, want velocity 0.5", amp)
+	}
+	p.NoteOn(57, 1)
+	if hz := p.Voices()[1].(*OscVoice).Osc().Freq(); !equaleps(hz, 440, 1e-9) {
+		t.Fatalf("have %vHz for new note bent, want 440Hz", hz)
+	}
+}
+
+func TestPolyNoteExpressive(t *testing.T) {
+	var _ NoteExpressive = (*Poly)(nil)
+
+	p := NewPoly(2, testVoice)
+	p.NoteOn(69, 0.5)
+	p.NoteOn(57, 0.5)
+	a4, a3 := p.Voices()[0].(*OscVoice).Osc(), p.Voices()[1].(*OscVoice).Osc()
+	p.NoteBend(69, 12)
+	p.NotePressure(57, 1)
+	if a4.Freq() != 880 || a3.Freq() != 220 {
+		t.Fatalf("have %vHz and %vHz, want only A4 bent an octave", a4.Freq(), a3.Freq())
+	}
+	if a4.Amp() != 0.5 || a3.Amp() != 1 {
+		t.Fatalf("have amps %v and %v, want only A3 at full pressure", a4.Amp(), a3.Amp())
+	}
+	// bends of notes add to bend of all.
+	p.Bend(-12)
+	if !equaleps(a4.Freq(), 440, 1e-9) || !equaleps(a3.Freq(), 110, 1e-9) {
+		t.Fatalf("have %vHz and %vHz bent down, want 440Hz and 110Hz", a4.Freq(), a3.Freq())
+	}
+}
+
+func TestPolyLimit(t *testing.T) {
+	p := NewPoly(4, testVoice)
+	for key := 60; key < 64; key++ {
+		p.NoteOn(key, 1)
+	}
+	p.SetLimit(2)
+	if p.keys[2] != -1 || p.keys[3] != -1 {
+		t.Fatalf("have keys %v, want voices beyond limit released", p.keys)
+	}
+	p.NoteOn(64, 1)
+	p.NoteOn(65, 1)
+	p.NoteOn(66, 1)
+	if p.keys[2] != -1 || p.keys[3] != -1 {
+		t.Fatalf("have keys %v, want notes on first two voices", p.keys)
+	}
+}
@@ -0,0 +1,87 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+)
+
+// FreqResponse is the frequency response of a chain of sounds, in bins evenly
+// spaced from zero to the nyquist frequency.
+type FreqResponse struct {
+	SampleRate float64
+	Gain       []Decibel // magnitude of each bin
+	Phase      []float64 // phase of each bin in radians, belonging to [-pi..pi]
+}
+
+// Hz returns the frequency of bin i.
+func (rs FreqResponse) Hz(i int) float64 {
+	return float64(i) * rs.SampleRate / float64(2*(len(rs.Gain)-1))
+}
+
+// At returns gain and phase at hz, interpolated between bins.
+func (rs FreqResponse) At(hz float64) (gain Decibel, phase float64) {
+	pos := hz / rs.Hz(1)
+	i := int(pos)
+	if i < 0 {
+		return rs.Gain[0], rs.Phase[0]
+	}
+	if i >= len(rs.Gain)-1 {
+		n := len(rs.Gain) - 1
+		return rs.Gain[n], rs.Phase[n]
+	}
+	t := pos - float64(i)
+	gain = rs.Gain[i] + Decibel(t)*(rs.Gain[i+1]-rs.Gain[i])
+	// phase is interpolated the short way round.
+	d := math.Remainder(rs.Phase[i+1]-rs.Phase[i], 2*math.Pi)
+	phase = math.Remainder(rs.Phase[i]+t*d, 2*math.Pi)
+	return gain, phase
+}
+
+// impulse outputs a single sample of 1 on the first frame.
+type impulse struct {
+	*mono
+	done bool
+}
+
+func newimpulse() *impulse { return &impulse{mono: newmono(nil)} }
+
+func (im *impulse) Prepare(uint64) {
+	for i := range im.out {
+		im.out[i] = 0
+	}
+	if !im.done && !im.off {
+		im.out[0] = 1
+	}
+	im.done = true
+}
+
+// Probe measures the frequency response of the chain returned by fn of its
+// input, such as a filter or EQ, by rendering it offline from an impulse for
+// n frames, a power of two long enough for the response to ring out. The
+// response has n/2+1 bins and is of the first channel of a chain with more.
+//
+// A chain is linear if its response describes it; of others, such as
+// distortion or dynamics, the response is only that to an impulse.
+func Probe(n int, fn func(in Sound) Sound) FreqResponse {
+	if n < 2 || n&(n-1) != 0 {
+		panic(fmt.Errorf("snd: probe length %v not a power of two", n))
+	}
+	sd := fn(newimpulse())
+	chans := sd.Channels()
+	out := Render(sd, n)
+	re, im := make([]float64, n), make([]float64, n)
+	for i := range re {
+		re[i] = out[i*chans]
+	}
+	fft(re, im, false)
+	rs := FreqResponse{
+		SampleRate: sd.SampleRate(),
+		Gain:       make([]Decibel, n/2+1),
+		Phase:      make([]float64, n/2+1),
+	}
+	for i := range rs.Gain {
+		rs.Gain[i] = DecibelOf(math.Hypot(re[i], im[i]))
+		rs.Phase[i] = math.Atan2(im[i], re[i])
+	}
+	return rs
+}
@@ -0,0 +1,38 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	rs := Probe(4096, func(in Sound) Sound { return NewGain(0.5, in) })
+	for _, hz := range []float64{50, 1000, 15000} {
+		if g, ph := rs.At(hz); !equaleps(float64(g), -6.02, 0.01) || !equaleps(ph, 0, 1e-9) {
+			t.Fatalf("gain: have %v and phase %v at %vHz, want -6dB flat", g, ph, hz)
+		}
+	}
+
+	rs = Probe(4096, func(in Sound) Sound { return NewLowPass(1000, in) })
+	lo, _ := rs.At(100)
+	hi, _ := rs.At(10000)
+	if lo < -1 || hi > -30 {
+		t.Fatalf("lowpass: have %v at 100Hz and %v at 10kHz, want pass and stop", lo, hi)
+	}
+
+	// a delay of a few frames turns phase linearly with frequency.
+	var dly *Delay
+	rs = Probe(4096, func(in Sound) Sound {
+		dly = NewDelay(Ftod(8, DefaultSampleRate), in)
+		return dly
+	})
+	// a delay line reads a frame ahead of where it writes.
+	d := len(dly.line.xs) - 1
+	for _, hz := range []float64{500, 2000} {
+		_, ph := rs.At(hz)
+		want := math.Remainder(-2*math.Pi*hz*float64(d)/DefaultSampleRate, 2*math.Pi)
+		if !equaleps(ph, want, 0.01) {
+			t.Fatalf("delay: have phase %v at %vHz, want %v", ph, hz, want)
+		}
+	}
+}
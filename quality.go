@@ -0,0 +1,121 @@
+package snd
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Quality trades fidelity of sounds against work per frame, so the same patch
+// plays on weaker devices, such as phones, scaled down instead of dropping
+// out. Sounds respect the quality set by Dispatcher.SetQuality of the
+// Dispatcher preparing them, from the next buffer: Poly, reverbs, delay lines,
+// and wavefolders. Graphs of other Dispatchers, such as of other engines, play
+// at their own quality.
+type Quality struct {
+	// Voices is the fraction of voices of each Poly notes are played on,
+	// belonging to (0..1]; notes held on others are released.
+	Voices float64
+
+	// Antialias antialiases waveshaping, such as of Wavefolder and
+	// ComplexOsc, in place of oversampling; off, shapes are computed
+	// directly at half the work, aliasing at high drive.
+	Antialias bool
+
+	// Interpolation is the finest interpolation delay lines are read by;
+	// those set finer, such as InterpCubic, read by it instead.
+	Interpolation Interpolation
+
+	// ReverbDensity is the fraction of feedback combs of reverbs run,
+	// belonging to (0..1]; fewer thin and color the tail.
+	ReverbDensity float64
+}
+
+// Qualities from most to least work; QualityHigh is the default.
+var (
+	QualityHigh   = Quality{Voices: 1, Antialias: true, Interpolation: InterpCubic, ReverbDensity: 1}
+	QualityMedium = Quality{Voices: 0.75, Antialias: true, Interpolation: InterpAllpass, ReverbDensity: 0.75}
+	QualityLow    = Quality{Voices: 0.5, Antialias: false, Interpolation: InterpLinear, ReverbDensity: 0.5}
+)
+
+func (st *settings) qualityset() bool {
+	return st != nil && atomic.LoadInt32(&st.qset) == 1
+}
+
+func (st *settings) load() Quality {
+	if !st.qualityset() {
+		return QualityHigh
+	}
+	return st.q.Load().(Quality)
+}
+
+func (st *settings) store(q Quality) {
+	q.Voices = math.Max(0, math.Min(1, q.Voices))
+	q.ReverbDensity = math.Max(0, math.Min(1, q.ReverbDensity))
+	st.q.Store(q)
+	atomic.StoreUint64(&st.voices, math.Float64bits(q.Voices))
+	var aa int32
+	if q.Antialias {
+		aa = 1
+	}
+	atomic.StoreInt32(&st.antialias, aa)
+	atomic.StoreInt32(&st.interp, int32(q.Interpolation))
+	combs := int32(math.Round(q.ReverbDensity * float64(len(fvcombs))))
+	if combs < 2 {
+		combs = 2
+	}
+	atomic.StoreInt32(&st.combs, combs)
+	atomic.StoreInt32(&st.qset, 1)
+}
+
+// voicesOf returns voices of n notes are played on.
+func (st *settings) voicesOf(n int) int {
+	if !st.qualityset() {
+		return n
+	}
+	x := math.Float64frombits(atomic.LoadUint64(&st.voices))
+	if v := int(math.Ceil(x * float64(n))); v < n {
+		if v < 1 {
+			return 1
+		}
+		return v
+	}
+	return n
+}
+
+// interpOf returns interpolation ip as read at the quality set.
+func (st *settings) interpOf(ip Interpolation) Interpolation {
+	if !st.qualityset() {
+		return ip
+	}
+	if q := Interpolation(atomic.LoadInt32(&st.interp)); ip > q {
+		return q
+	}
+	return ip
+}
+
+// antialiased reports whether waveshaping is antialiased.
+func (st *settings) antialiased() bool {
+	return !st.qualityset() || atomic.LoadInt32(&st.antialias) == 1
+}
+
+// reverbCombs returns the feedback combs of freeverb run.
+func (st *settings) reverbCombs() int {
+	if !st.qualityset() {
+		return len(fvcombs)
+	}
+	return int(atomic.LoadInt32(&st.combs))
+}
+
+// Quality returns the quality set by SetQuality, QualityHigh by default.
+func (dp *Dispatcher) Quality() Quality { return dp.settings().load() }
+
+// SetQuality sets the quality sounds prepared by dp play at, such as
+// QualityLow on a slow device, or by a Watchdog with DegradeQuality. Other
+// Dispatchers are unaffected, such as of other engines. It is safe to call
+// from any goroutine while playing.
+func (dp *Dispatcher) SetQuality(q Quality) { dp.settings().store(q) }
+
+// DegradeQuality returns a step of a Watchdog setting quality of dp to q.
+func (dp *Dispatcher) DegradeQuality(q Quality) func() {
+	return func() { dp.SetQuality(q) }
+}
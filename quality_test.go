@@ -0,0 +1,62 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuality(t *testing.T) {
+	p := NewPoly(4, testVoice)
+	reverb := func(dp *Dispatcher) Discrete {
+		return dp.Render(NewReverb(0.5, 0.5, NewOscil(Sine(), 440, nil)), 40*DefaultBufferLen)
+	}
+	high := reverb(new(Dispatcher))
+	for key := 60; key < 64; key++ {
+		p.NoteOn(key, 1)
+	}
+
+	dp := new(Dispatcher)
+	if q := dp.Quality(); q != QualityHigh {
+		t.Fatalf("have %+v by default, want %+v", q, QualityHigh)
+	}
+	dp.SetQuality(QualityLow)
+	if q := dp.Quality(); q != QualityLow {
+		t.Fatalf("have %+v, want %+v", q, QualityLow)
+	}
+	dp.Render(p, DefaultBufferLen)
+	if p.keys[2] != -1 || p.keys[3] != -1 {
+		t.Fatalf("have keys %v, want half the voices", p.keys)
+	}
+	if ip := dp.settings().interpOf(InterpCubic); ip != InterpLinear {
+		t.Fatalf("have %v reading cubic, want linear", ip)
+	}
+	low := reverb(dp)
+	var diff float64
+	for i := range low {
+		diff = math.Max(diff, math.Abs(low[i]-high[i]))
+	}
+	if pl, ph := Peak(low), Peak(high); diff == 0 || math.IsNaN(pl) || pl > 1.5*ph {
+		t.Fatalf("have reverb of peak %v differing by %v at low quality, want sparser tail near peak %v", pl, diff, ph)
+	}
+
+	// other dispatchers, such as of other engines, play at their own.
+	if other := reverb(new(Dispatcher)); Peak(other) != Peak(high) {
+		t.Fatalf("have peak %v of another dispatcher, want %v at high quality", Peak(other), Peak(high))
+	}
+
+	// graphs nested within follow.
+	hs := NewHotswap(1, NewReverb(0.5, 0.5, NewOscil(Sine(), 440, nil)))
+	dp.Render(hs, DefaultBufferLen)
+	if q := hs.dp.Quality(); q != QualityLow {
+		t.Fatalf("have %+v of a hotswap, want %+v", q, QualityLow)
+	}
+
+	dp.SetQuality(QualityHigh)
+	dp.Render(p, DefaultBufferLen)
+	for key := 64; key < 68; key++ {
+		p.NoteOn(key, 1)
+	}
+	if p.keys[2] == -1 || p.keys[3] == -1 {
+		t.Fatalf("have keys %v, want all voices", p.keys)
+	}
+}
@@ -0,0 +1,89 @@
+package snd
+
+import "math"
+
+// Quantizer snaps a control signal of pitch to the nearest note of a mode,
+// so random or LFO modulation of pitch plays in key. Input and output are
+// MIDI key numbers, or frequencies in hertz when set, suitable as freqmod of
+// an Oscil of frequency 1.
+//
+// In hertz, a tuning of Notes may be set in place of a mode to snap to the
+// nearest of arbitrary frequencies.
+type Quantizer struct {
+	*mono
+	root  int
+	mode  Mode
+	hertz bool
+	notes Notes
+	key   float64 // last output key or frequency
+}
+
+// NewQuantizer returns Quantizer of in to mode rooted at MIDI key root,
+// taking key numbers.
+func NewQuantizer(root int, mode Mode, in Sound) *Quantizer {
+	return &Quantizer{mono: newmono(in), root: root, mode: mode}
+}
+
+func (qz *Quantizer) Key() (root int, mode Mode) { return qz.root, qz.mode }
+func (qz *Quantizer) SetKey(root int, mode Mode) { qz.root, qz.mode = root, mode }
+func (qz *Quantizer) Hertz() bool                { return qz.hertz }
+
+// SetHertz sets whether input and output are in hertz rather than key numbers.
+func (qz *Quantizer) SetHertz(b bool) { qz.hertz = b }
+
+// SetTuning snaps input in hertz to the nearest of ns, sorted ascending,
+// instead of mode. A nil ns returns to mode.
+func (qz *Quantizer) SetTuning(ns Notes) { qz.notes = ns }
+
+// Value returns the last output.
+func (qz *Quantizer) Value() float64 { return qz.key }
+
+// nearest returns the note of qz.notes nearest to hz in pitch.
+func (qz *Quantizer) nearest(hz float64) float64 {
+	ns := qz.notes
+	i, j := 0, len(ns)
+	for i < j {
+		h := (i + j) / 2
+		if ns[h] < hz {
+			i = h + 1
+		} else {
+			j = h
+		}
+	}
+	if i == len(ns) {
+		return ns[i-1]
+	}
+	if i > 0 && hz*hz < ns[i-1]*ns[i] {
+		// below the geometric mean of neighbors is nearer the lower.
+		return ns[i-1]
+	}
+	return ns[i]
+}
+
+func (qz *Quantizer) quantize(x float64) float64 {
+	switch {
+	case !qz.hertz:
+		return float64(qz.mode.Nearest(qz.root, x))
+	case x <= 0:
+		return 0
+	case len(qz.notes) > 0:
+		return qz.nearest(x)
+	default:
+		a4 := qz.st.concertPitch()
+		return keyfreq(qz.mode.Nearest(qz.root, keyof(x, a4)), a4)
+	}
+}
+
+func (qz *Quantizer) Prepare(uint64) {
+	for i, x := range qz.in.Samples() {
+		if math.IsNaN(x) {
+			x = 0
+		}
+		qz.key = qz.quantize(x)
+		if qz.off {
+			qz.out[i] = 0
+		} else {
+			qz.out[i] = qz.key
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package snd
+
+import "testing"
+
+func TestQuantizer(t *testing.T) {
+	in := newlevel()
+	qz := NewQuantizer(60, ModeMajor, in)
+	for _, tt := range []struct{ in, want float64 }{
+		{60.9, 60}, {61.6, 62}, {65.9, 65}, {66.2, 67}, {71.9, 72}, {58.6, 59},
+	} {
+		in.set(tt.in)
+		if have := Render(qz, 4)[2]; have != tt.want {
+			t.Errorf("key %v: have %v, want %v", tt.in, have, tt.want)
+		}
+	}
+
+	qz.SetHertz(true)
+	in.set(450)
+	if have, want := Render(qz, 4)[2], KeyFreq(69); !equaleps(have, want, 1e-9) {
+		t.Errorf("have %vHz, want %vHz", have, want)
+	}
+	qz.SetTuning(Notes{100, 200, 300})
+	in.set(240)
+	if have := Render(qz, 4)[2]; have != 200 {
+		t.Errorf("tuned have %vHz, want 200Hz", have)
+	}
+	in.set(250)
+	if have := Render(qz, 4)[2]; have != 300 {
+		t.Errorf("tuned have %vHz, want 300Hz", have)
+	}
+}
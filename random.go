@@ -0,0 +1,246 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+)
+
+// randclock steps random modulation sources at a rate in hertz or, when
+// given a clock, on each of its triggers. Sources output values belonging to
+// [-1..1] that are deterministic for a given seed.
+type randclock struct {
+	clk Sound
+	edge
+	rate  float64
+	phase float64
+	n     int // frames since last step
+	last  int // frames between last two steps
+}
+
+// tick returns whether to step at frame i of sr.
+func (rc *randclock) tick(i int, sr float64) bool {
+	rc.n++
+	if rc.clk != nil {
+		if rise, _ := rc.step(rc.clk.Index(i)); rise {
+			rc.last, rc.n = rc.n, 0
+			return true
+		}
+		return false
+	}
+	if rc.phase += rc.rate / sr; rc.phase >= 1 {
+		rc.phase -= math.Floor(rc.phase)
+		rc.last, rc.n = rc.n, 0
+		return true
+	}
+	return false
+}
+
+func (rc *randclock) inputs() []Sound {
+	if rc.clk == nil {
+		return nil
+	}
+	return []Sound{rc.clk}
+}
+
+// SmoothRandom glides between random values with cosine interpolation, a
+// slowly wandering modulation like one dimensional value noise.
+type SmoothRandom struct {
+	*mono
+	randclock
+	rnd      *rand.Rand
+	from, to float64
+}
+
+// NewSmoothRandom returns SmoothRandom reaching a new value rate times per second.
+func NewSmoothRandom(rate float64, seed int64) *SmoothRandom {
+	smr := &SmoothRandom{mono: newmono(nil), rnd: rand.New(rand.NewSource(seed))}
+	smr.rate = rate
+	smr.last = int(smr.sr / rate)
+	smr.to = 2*smr.rnd.Float64() - 1
+	return smr
+}
+
+func (smr *SmoothRandom) Inputs() []Sound { return smr.inputs() }
+
+// SetClock steps on triggers of clk instead of rate, gliding over the
+// interval between the last two triggers. A nil clk returns to rate.
+func (smr *SmoothRandom) SetClock(clk Sound) { smr.clk = clk }
+
+func (smr *SmoothRandom) Rate() float64     { return smr.rate }
+func (smr *SmoothRandom) SetRate(x float64) { smr.rate = x }
+
+// Seed restarts the sequence of values.
+func (smr *SmoothRandom) Seed(seed int64) { smr.rnd.Seed(seed) }
+
+func (smr *SmoothRandom) Params() []*Param {
+	return []*Param{NewParam("rate", smr.Rate, smr.SetRate).Range(0.01, 100, 1).In(UnitHz)}
+}
+
+func (smr *SmoothRandom) Prepare(uint64) {
+	for i := range smr.out {
+		if smr.tick(i, smr.sr) {
+			smr.from, smr.to = smr.to, 2*smr.rnd.Float64()-1
+		}
+		t := 1.0
+		if smr.last > 0 && smr.n < smr.last {
+			t = float64(smr.n) / float64(smr.last)
+		}
+		w := (1 - math.Cos(math.Pi*t)) / 2
+		if smr.off {
+			smr.out[i] = 0
+		} else {
+			smr.out[i] = smr.from + w*(smr.to-smr.from)
+		}
+	}
+}
+
+// RandomWalk moves by a random step each time it steps, reflecting off of -1
+// and 1, a drifting modulation like brownian motion.
+type RandomWalk struct {
+	*mono
+	randclock
+	rnd  *rand.Rand
+	size float64
+	x    float64
+}
+
+// NewRandomWalk returns RandomWalk stepping rate times per second by normally
+// distributed steps of standard deviation size.
+func NewRandomWalk(rate, size float64, seed int64) *RandomWalk {
+	rw := &RandomWalk{mono: newmono(nil), rnd: rand.New(rand.NewSource(seed)), size: size}
+	rw.rate = rate
+	return rw
+}
+
+func (rw *RandomWalk) Inputs() []Sound { return rw.inputs() }
+
+// SetClock steps on triggers of clk instead of rate. A nil clk returns to rate.
+func (rw *RandomWalk) SetClock(clk Sound) { rw.clk = clk }
+
+func (rw *RandomWalk) Rate() float64     { return rw.rate }
+func (rw *RandomWalk) SetRate(x float64) { rw.rate = x }
+func (rw *RandomWalk) Size() float64     { return rw.size }
+func (rw *RandomWalk) SetSize(x float64) { rw.size = x }
+
+// Seed restarts the sequence of steps.
+func (rw *RandomWalk) Seed(seed int64) { rw.rnd.Seed(seed) }
+
+func (rw *RandomWalk) Params() []*Param {
+	return []*Param{
+		NewParam("rate", rw.Rate, rw.SetRate).Range(0.01, 100, 1).In(UnitHz),
+		NewParam("size", rw.Size, rw.SetSize).Range(0, 1, 0.1),
+	}
+}
+
+func (rw *RandomWalk) Prepare(uint64) {
+	for i := range rw.out {
+		if rw.tick(i, rw.sr) {
+			rw.x += rw.size * rw.rnd.NormFloat64()
+			for rw.x > 1 || rw.x < -1 {
+				if rw.x > 1 {
+					rw.x = 2 - rw.x
+				} else {
+					rw.x = -2 - rw.x
+				}
+			}
+		}
+		if rw.off {
+			rw.out[i] = 0
+		} else {
+			rw.out[i] = rw.x
+		}
+	}
+}
+
+// Lorenz outputs the x coordinate of the Lorenz attractor, a chaotic
+// modulation that orbits two centers and switches between them unpredictably.
+type Lorenz struct {
+	*mono
+	randclock
+	speed   float64
+	x, y, z float64
+	held    float64
+}
+
+// NewLorenz returns Lorenz integrated at speed, where 1 orbits about once per
+// second. The seed perturbs the starting point.
+func NewLorenz(speed float64, seed int64) *Lorenz {
+	rnd := rand.New(rand.NewSource(seed))
+	lz := &Lorenz{mono: newmono(nil), speed: speed}
+	lz.x, lz.y, lz.z = 1+rnd.Float64(), 1+rnd.Float64(), 20+rnd.Float64()
+	return lz
+}
+
+func (lz *Lorenz) Inputs() []Sound { return lz.inputs() }
+
+// SetClock samples the attractor on triggers of clk and holds the value
+// until the next. A nil clk outputs continuously.
+func (lz *Lorenz) SetClock(clk Sound) { lz.clk = clk }
+
+func (lz *Lorenz) Speed() float64     { return lz.speed }
+func (lz *Lorenz) SetSpeed(x float64) { lz.speed = x }
+
+// Point returns the current position of the attractor.
+func (lz *Lorenz) Point() (x, y, z float64) { return lz.x, lz.y, lz.z }
+
+func (lz *Lorenz) Params() []*Param {
+	return []*Param{NewParam("speed", lz.Speed, lz.SetSpeed).Range(0.01, 100, 1)}
+}
+
+func (lz *Lorenz) Prepare(uint64) {
+	const (
+		sigma  = 10
+		rho    = 28
+		beta   = 8.0 / 3
+		scale  = 1.0 / 20 // x stays within about 20 of the origin
+		period = 0.7      // time units of an orbit, about
+	)
+	dt := lz.speed * period / lz.sr
+	for i := range lz.out {
+		lz.x, lz.y, lz.z = lz.x+dt*sigma*(lz.y-lz.x),
+			lz.y+dt*(lz.x*(rho-lz.z)-lz.y),
+			lz.z+dt*(lz.x*lz.y-beta*lz.z)
+		x := math.Max(-1, math.Min(1, lz.x*scale))
+		if lz.clk == nil || lz.tick(i, lz.sr) {
+			lz.held = x
+		}
+		if lz.off {
+			lz.out[i] = 0
+		} else {
+			lz.out[i] = lz.held
+		}
+	}
+}
+
+// ProbGate passes each trigger of its input with a probability, outputting
+// triggers, such as to randomly thin out a clock driving drums.
+type ProbGate struct {
+	*mono
+	edge
+	rnd *rand.Rand
+	p   float64
+}
+
+// NewProbGate returns ProbGate passing triggers of in with probability p.
+func NewProbGate(p float64, seed int64, in Sound) *ProbGate {
+	return &ProbGate{mono: newmono(in), rnd: rand.New(rand.NewSource(seed)), p: p}
+}
+
+func (pg *ProbGate) Probability() float64     { return pg.p }
+func (pg *ProbGate) SetProbability(x float64) { pg.p = x }
+
+// Seed restarts the sequence of decisions.
+func (pg *ProbGate) Seed(seed int64) { pg.rnd.Seed(seed) }
+
+func (pg *ProbGate) Params() []*Param {
+	return []*Param{NewParam("probability", pg.Probability, pg.SetProbability).Range(0, 1, 0.5).In(UnitPercent)}
+}
+
+func (pg *ProbGate) Prepare(uint64) {
+	for i, x := range pg.in.Samples() {
+		pg.out[i] = 0
+		if rise, _ := pg.step(x); rise && pg.rnd.Float64() < pg.p && !pg.off {
+			pg.out[i] = 1
+		}
+	}
+}
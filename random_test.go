@@ -0,0 +1,70 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRandomSources(t *testing.T) {
+	n := Dtof(2*time.Second, DefaultSampleRate)
+	tests := []struct {
+		name string
+		mk   func(seed int64) Sound
+	}{
+		{"smooth", func(seed int64) Sound { return NewSmoothRandom(5, seed) }},
+		{"walk", func(seed int64) Sound { return NewRandomWalk(50, 0.2, seed) }},
+		{"lorenz", func(seed int64) Sound { return NewLorenz(2, seed) }},
+	}
+	for _, tt := range tests {
+		a, b := Render(tt.mk(1), n), Render(tt.mk(1), n)
+		c := Render(tt.mk(2), n)
+		same, moved := true, false
+		for i := range a {
+			if a[i] < -1 || a[i] > 1 {
+				t.Fatalf("%s: have %v, want within [-1..1]", tt.name, a[i])
+			}
+			same = same && a[i] == b[i]
+			moved = moved || a[i] != c[i]
+		}
+		if !same || !moved {
+			t.Errorf("%s: have repeatable %v and seeded %v, want both", tt.name, same, moved)
+		}
+	}
+}
+
+func TestRandomClock(t *testing.T) {
+	rw := NewRandomWalk(0, 0.1, 1)
+	rw.SetClock(NewClock(100 * time.Millisecond))
+	out := Render(rw, Dtof(time.Second, DefaultSampleRate))
+	steps := 0
+	for i := 1; i < len(out); i++ {
+		if out[i] != out[i-1] {
+			steps++
+		}
+	}
+	// the first trigger on the first frame moves away from zero unseen.
+	if steps != 9 {
+		t.Fatalf("have %v steps, want 9", steps)
+	}
+}
+
+func TestProbGate(t *testing.T) {
+	clk := NewClock(time.Millisecond)
+	count := func(p float64) (n int) {
+		for _, x := range Render(NewProbGate(p, 1, clk), Dtof(time.Second, DefaultSampleRate)) {
+			if x > 0 {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(1); n < 999 {
+		t.Fatalf("have %v triggers at probability 1, want 1000", n)
+	}
+	if n := count(0); n != 0 {
+		t.Fatalf("have %v triggers at probability 0, want 0", n)
+	}
+	if n := count(0.5); n < 400 || n > 600 {
+		t.Fatalf("have %v triggers at probability 0.5, want about 500", n)
+	}
+}
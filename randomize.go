@@ -0,0 +1,115 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"path"
+)
+
+// paramrange is a range of random values of a param.
+type paramrange struct {
+	lo, hi float64
+	c      Curve
+}
+
+// Randomizer assigns random values to params of a Params for sound designers
+// to explore a patch. Params it has no range for are given one from their
+// value when first randomized:
+//
+//	- a value belonging to [0..1], such as a mix or level, ranges over [0..1]
+//	- a value over one, such as a frequency or time, ranges two octaves
+//	  either way, evenly to the ear
+//	- a negative value, such as detune, ranges over twice its size either way
+//
+// Locked params are never changed.
+type Randomizer struct {
+	ps     *Params
+	rnd    *rand.Rand
+	ranges map[string]paramrange
+	locks  []string
+}
+
+// NewRandomizer returns Randomizer of ps with values deterministic for seed.
+func NewRandomizer(ps *Params, seed int64) *Randomizer {
+	return &Randomizer{ps: ps, rnd: rand.New(rand.NewSource(seed)), ranges: make(map[string]paramrange)}
+}
+
+// SetRange sets values of param name to belong to [lo..hi], spread by c, such
+// as CurveExp for more low values than high, or linearly if c is nil.
+func (rz *Randomizer) SetRange(name string, lo, hi float64, c Curve) {
+	if c == nil {
+		c = CurveLinear
+	}
+	rz.ranges[name] = paramrange{lo, hi, c}
+}
+
+// Lock keeps params matching pattern, as of path.Match such as "lp.*" or
+// "osc.freq", from being randomized.
+func (rz *Randomizer) Lock(pattern string) {
+	for _, p := range rz.locks {
+		if p == pattern {
+			return
+		}
+	}
+	rz.locks = append(rz.locks, pattern)
+}
+
+// Unlock removes pattern given to Lock.
+func (rz *Randomizer) Unlock(pattern string) {
+	for i, p := range rz.locks {
+		if p == pattern {
+			rz.locks = append(rz.locks[:i], rz.locks[i+1:]...)
+			return
+		}
+	}
+}
+
+// Locked reports whether param name matches a locked pattern.
+func (rz *Randomizer) Locked(name string) bool {
+	for _, p := range rz.locks {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Randomize sets all unlocked params to random values in their range and
+// returns prior values of all params, which may be loaded to undo.
+func (rz *Randomizer) Randomize() Preset { return rz.Mutate(1) }
+
+// Mutate moves all unlocked params toward random values in their range by
+// amount belonging to [0..1], small amounts varying a patch that is close,
+// and returns prior values of all params, which may be loaded to undo.
+func (rz *Randomizer) Mutate(amount float64) Preset {
+	prior := rz.ps.Save()
+	for _, p := range rz.ps.List() {
+		if rz.Locked(p.Name) {
+			continue
+		}
+		x := p.Value()
+		r, ok := rz.ranges[p.Name]
+		if !ok {
+			r = defaultrange(x)
+			rz.ranges[p.Name] = r
+		}
+		y := r.lo + r.c(rz.rnd.Float64())*(r.hi-r.lo)
+		p.Set(x + amount*(y-x))
+	}
+	return prior
+}
+
+// defaultrange returns the range of a param of value x without one set.
+func defaultrange(x float64) paramrange {
+	switch {
+	case x >= 0 && x <= 1:
+		return paramrange{0, 1, CurveLinear}
+	case x > 1:
+		// four octaves spread evenly in log.
+		return paramrange{x / 4, x * 4, func(t float64) float64 {
+			return (math.Pow(16, t) - 1) / 15
+		}}
+	default:
+		return paramrange{2 * x, -2 * x, CurveLinear}
+	}
+}
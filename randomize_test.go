@@ -0,0 +1,45 @@
+package snd
+
+import "testing"
+
+func TestRandomizer(t *testing.T) {
+	osc := NewOscil(Sine(), 440, nil)
+	lp := NewLowPass(1000, osc)
+	gain := NewGain(0.5, lp)
+	var ps Params
+	ps.Register("osc", osc)
+	ps.Register("lp", lp)
+	ps.Register("gain", gain)
+
+	rz := NewRandomizer(&ps, 1)
+	rz.Lock("osc.*")
+	rz.SetRange("lp.freq", 200, 400, nil)
+	for i := 0; i < 20; i++ {
+		prior := rz.Randomize()
+		if osc.Freq() != 440 {
+			t.Fatalf("have locked freq %v, want 440", osc.Freq())
+		}
+		if x := lp.Freq(); x < 200 || x > 400 {
+			t.Fatalf("have lp freq %v, want within range", x)
+		}
+		if x := ps.Lookup("gain.amp").Value(); x < 0 || x > 1 {
+			t.Fatalf("have amp %v, want default range of [0..1]", x)
+		}
+		if i == 0 && prior["lp.freq"] != 1000 {
+			t.Fatalf("have prior freq %v, want 1000", prior["lp.freq"])
+		}
+	}
+
+	rz.Unlock("osc.*")
+	before := ps.Save()
+	rz.Mutate(0)
+	for name, x := range ps.Save() {
+		if x != before[name] {
+			t.Fatalf("have %s changed to %v by no mutation, want %v", name, x, before[name])
+		}
+	}
+	rz.Randomize()
+	if x := osc.Freq(); x == 440 || x < 110 || x > 1760 {
+		t.Fatalf("have freq %v, want random within two octaves", x)
+	}
+}
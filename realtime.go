@@ -0,0 +1,58 @@
+package snd
+
+import (
+	"errors"
+	"runtime"
+	"time"
+)
+
+// ErrRealtimeUnsupported is reported by Realtime.Lock where the platform has
+// no real time scheduling, or processors can't be pinned.
+var ErrRealtimeUnsupported = errors.New("snd: real time scheduling not supported")
+
+// Realtime schedules the goroutine preparing buffers for playback at real time
+// priority, locked to its OS thread and optionally pinned to processors, so
+// other work under load doesn't preempt it into dropouts: SCHED_FIFO on Linux
+// and Android, and time constraint scheduling on macOS and iOS. Backends lock
+// the goroutine they prepare output on, such as by al.Engine.SetRealtime.
+//
+// Goroutines the Dispatcher starts for inputs of a level aren't raised, but
+// the last input of each level is prepared on the calling goroutine, all of
+// them with a single processor or Exact rendering.
+type Realtime struct {
+	// Period is the duration of each buffer prepared; the thread is given up
+	// to half each period by time constraint scheduling. Zero is
+	// DefaultBufferLen frames at DefaultSampleRate.
+	Period time.Duration
+
+	// Priority is the SCHED_FIFO priority from 1 to 99; zero is 70, above
+	// most kernel threads and below those handling interrupts.
+	Priority int
+
+	// CPUs are processors the thread is pinned to, on Linux only; empty runs
+	// on any.
+	CPUs []int
+}
+
+// Lock locks the calling goroutine to its OS thread and schedules the thread
+// as rt, returning a function restoring the thread and unlocking the
+// goroutine, to be called on the same goroutine when done.
+//
+// Failures leave the thread scheduled as far as permitted and unlock must
+// still be called. Lacking permission, such as without CAP_SYS_NICE or an
+// rtprio limit on Linux, is reported as an error matching os.ErrPermission by
+// errors.Is, so callers may carry on at normal priority.
+func (rt Realtime) Lock() (unlock func(), err error) {
+	runtime.LockOSThread()
+	if rt.Period == 0 {
+		rt.Period = Ftod(DefaultBufferLen, DefaultSampleRate)
+	}
+	if rt.Priority == 0 {
+		rt.Priority = 70
+	}
+	restore, err := rt.lock()
+	return func() {
+		restore()
+		runtime.UnlockOSThread()
+	}, err
+}
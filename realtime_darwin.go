@@ -0,0 +1,52 @@
+//go:build darwin && cgo
+// +build darwin,cgo
+
+package snd
+
+/*
+#include <pthread.h>
+#include <mach/mach.h>
+#include <mach/mach_time.h>
+#include <mach/thread_policy.h>
+
+// snd_timeconstraint schedules the calling thread each period for up to
+// computation, finished within constraint, all in nanoseconds.
+static kern_return_t snd_timeconstraint(uint64_t period, uint64_t computation, uint64_t constraint) {
+	mach_timebase_info_data_t tb;
+	mach_timebase_info(&tb);
+	thread_time_constraint_policy_data_t p;
+	p.period = (uint32_t)(period * tb.denom / tb.numer);
+	p.computation = (uint32_t)(computation * tb.denom / tb.numer);
+	p.constraint = (uint32_t)(constraint * tb.denom / tb.numer);
+	p.preemptible = 1;
+	return thread_policy_set(pthread_mach_thread_np(pthread_self()), THREAD_TIME_CONSTRAINT_POLICY,
+		(thread_policy_t)&p, THREAD_TIME_CONSTRAINT_POLICY_COUNT);
+}
+
+static kern_return_t snd_standard(void) {
+	thread_standard_policy_data_t p;
+	return thread_policy_set(pthread_mach_thread_np(pthread_self()), THREAD_STANDARD_POLICY,
+		(thread_policy_t)&p, THREAD_STANDARD_POLICY_COUNT);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"os"
+)
+
+// lock schedules the calling thread as rt, returning a function restoring it.
+// Priority is left to the time constraint, and processors can't be pinned.
+func (rt Realtime) lock() (restore func(), err error) {
+	period := C.uint64_t(rt.Period.Nanoseconds())
+	if kr := C.snd_timeconstraint(period, period/2, period); kr != C.KERN_SUCCESS {
+		err = fmt.Errorf("snd: time constraint scheduling: %w (kern_return_t %v)", os.ErrPermission, int(kr))
+		return func() {}, err
+	}
+	restore = func() { C.snd_standard() }
+	if len(rt.CPUs) != 0 {
+		err = fmt.Errorf("snd: pin to cpus %v: %w", rt.CPUs, ErrRealtimeUnsupported)
+	}
+	return restore, err
+}
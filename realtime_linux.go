@@ -0,0 +1,82 @@
+package snd
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	schedOther = 0
+	schedFIFO  = 1
+)
+
+// schedParam is struct sched_param.
+type schedParam struct{ priority int32 }
+
+// cpuMask is cpu_set_t of up to 1024 processors.
+type cpuMask [16]uint64
+
+// lock schedules the calling thread as rt, returning a function restoring it.
+func (rt Realtime) lock() (restore func(), err error) {
+	var undo []func()
+	restore = func() {
+		for _, fn := range undo {
+			fn()
+		}
+	}
+
+	// pid 0 is the calling thread.
+	policy, _, e := syscall.RawSyscall(syscall.SYS_SCHED_GETSCHEDULER, 0, 0, 0)
+	var old schedParam
+	if e == 0 {
+		_, _, e = syscall.RawSyscall(syscall.SYS_SCHED_GETPARAM, 0, uintptr(unsafe.Pointer(&old)), 0)
+	}
+	if e != 0 {
+		policy, old = schedOther, schedParam{}
+	}
+	prio := rt.Priority
+	if prio < 1 {
+		prio = 1
+	} else if prio > 99 {
+		prio = 99
+	}
+	param := schedParam{int32(prio)}
+	if _, _, e := syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, schedFIFO, uintptr(unsafe.Pointer(&param))); e != 0 {
+		err = fmt.Errorf("snd: real time priority %v: %w", prio, e)
+	} else {
+		undo = append(undo, func() {
+			syscall.RawSyscall(syscall.SYS_SCHED_SETSCHEDULER, 0, policy, uintptr(unsafe.Pointer(&old)))
+		})
+	}
+
+	if len(rt.CPUs) == 0 {
+		return restore, err
+	}
+	var was, mask cpuMask
+	if _, _, e := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(was), uintptr(unsafe.Pointer(&was))); e != 0 {
+		if err == nil {
+			err = fmt.Errorf("snd: pin to cpus %v: %w", rt.CPUs, e)
+		}
+		return restore, err
+	}
+	for _, cpu := range rt.CPUs {
+		if cpu < 0 || cpu >= 64*len(mask) {
+			if err == nil {
+				err = fmt.Errorf("snd: pin to cpu %v: out of range", cpu)
+			}
+			return restore, err
+		}
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+	if _, _, e := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask))); e != 0 {
+		if err == nil {
+			err = fmt.Errorf("snd: pin to cpus %v: %w", rt.CPUs, e)
+		}
+		return restore, err
+	}
+	undo = append(undo, func() {
+		syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(was), uintptr(unsafe.Pointer(&was)))
+	})
+	return restore, err
+}
@@ -0,0 +1,10 @@
+//go:build !linux && (!darwin || !cgo)
+// +build !linux
+// +build !darwin !cgo
+
+package snd
+
+// lock reports real time scheduling unsupported.
+func (rt Realtime) lock() (restore func(), err error) {
+	return func() {}, ErrRealtimeUnsupported
+}
@@ -0,0 +1,20 @@
+package snd
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestRealtimeLock(t *testing.T) {
+	unlock, err := Realtime{CPUs: []int{0}}.Lock()
+	unlock()
+	if err != nil && !errors.Is(err, os.ErrPermission) && !errors.Is(err, ErrRealtimeUnsupported) {
+		t.Fatalf("have %v, want nil, permission denied, or unsupported", err)
+	}
+	unlock, err = Realtime{CPUs: []int{-1}}.Lock()
+	unlock()
+	if err == nil {
+		t.Fatal("have nil error pinning to cpu -1")
+	}
+}
@@ -0,0 +1,293 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Recorder passes its input through unaltered, capturing interleaved samples
+// while recording. Frames counted since recording started serve as a timestamp
+// base for events recorded alongside audio, such as notes played.
+//
+// Recording may be limited to punch-in and punch-out points of a Transport,
+// with playback starting a pre-roll before the punch-in and stopping a
+// post-roll after the punch-out.
+//
+// When overdubbing over material played by the graph, what is recorded
+// arrives late by the round-trip latency of the audio interface, as measured
+// by a LatencyProbe. With that latency set, recorded material is shifted
+// earlier so it lines up with what was played.
+//
+// Samples are written on the audio thread to a ring of a second, drained by
+// a goroutine started by Record, so preparing never locks or allocates.
+// Frames the drain falls too far behind of are dropped, and recording stops
+// once a limit is reached, by default ten minutes.
+type Recorder struct {
+	*mono
+	chans int
+	rec   int32  // atomic
+	frame uint64 // atomic
+
+	ring    Discrete
+	w, r    uint64 // atomic; samples written and drained
+	dropped uint64 // atomic; frames lost to a full ring
+	wake    chan struct{}
+	quit    chan struct{} // of the drain running, if any
+	done    chan struct{}
+
+	mu    sync.Mutex // of buf, between the drain and Stop
+	buf   Discrete
+	limit int // samples
+
+	tp        *Transport
+	pin, pout float64 // punch points in beats
+	pre, post float64 // roll in beats
+
+	latency int // frames input arrives late
+	skip    int // frames left to discard while recording
+}
+
+func NewRecorder(in Sound) *Recorder {
+	sd := newmono(in)
+	sd.sr = in.SampleRate()
+	sd.out = make(Discrete, len(in.Samples()))
+	rc := &Recorder{
+		mono:  sd,
+		chans: in.Channels(),
+		ring:  make(Discrete, Dtof(time.Second, sd.sr)*in.Channels()),
+		wake:  make(chan struct{}, 1),
+	}
+	rc.SetLimit(10 * time.Minute)
+	return rc
+}
+
+func (rc *Recorder) Channels() int { return rc.chans }
+
+func (rc *Recorder) Inputs() []Sound {
+	if rc.tp == nil {
+		return []Sound{rc.in}
+	}
+	return []Sound{rc.in, rc.tp}
+}
+
+// SetPunch records only from beat in until beat out of tp. An out not after
+// in records until stopped. A nil tp records whenever recording.
+func (rc *Recorder) SetPunch(tp *Transport, in, out float64) {
+	rc.tp, rc.pin, rc.pout = tp, in, out
+}
+
+// Punch returns punch-in and punch-out points in beats.
+func (rc *Recorder) Punch() (in, out float64) { return rc.pin, rc.pout }
+
+// SetRoll sets beats played before the punch-in and after the punch-out.
+// Play a Metronome until the punch-in to count in during pre-roll.
+func (rc *Recorder) SetRoll(pre, post float64) { rc.pre, rc.post = pre, post }
+
+// Roll returns pre-roll and post-roll in beats.
+func (rc *Recorder) Roll() (pre, post float64) { return rc.pre, rc.post }
+
+// SetLatency shifts recorded material earlier by n frames, the round-trip
+// latency of the audio interface, such as measured by a LatencyProbe.
+// Without punch points, the last n frames played before stopping are still
+// in flight and aren't recorded; with punch points, recording continues past
+// the punch-out until they arrive, extending post-roll if shorter.
+func (rc *Recorder) SetLatency(n int) {
+	if n < 0 {
+		n = 0
+	}
+	rc.latency = n
+}
+
+// Latency returns frames recorded material is shifted earlier.
+func (rc *Recorder) Latency() int { return rc.latency }
+
+// Limit returns the longest duration recorded.
+func (rc *Recorder) Limit() time.Duration { return Ftod(rc.limit/rc.chans, rc.sr) }
+
+// SetLimit sets the longest duration recorded, after which recording stops
+// on its own. It must not be called while recording.
+func (rc *Recorder) SetLimit(d time.Duration) { rc.limit = Dtof(d, rc.sr) * rc.chans }
+
+// Dropped returns frames not recorded since the drain of the ring fell too
+// far behind, such as when rendering much faster than realtime.
+func (rc *Recorder) Dropped() uint64 { return atomic.LoadUint64(&rc.dropped) }
+
+// Record discards anything captured and starts recording from the next buffer.
+// With punch points set, the transport is moved to the pre-roll and played;
+// recording stops on its own, with the transport, at the end of post-roll, or
+// at the limit. Stop must be called to collect what was recorded and end the
+// drain.
+func (rc *Recorder) Record() {
+	rc.halt()
+	rc.mu.Lock()
+	rc.buf = nil
+	rc.mu.Unlock()
+	// the drain is not running, so anything left in the ring is discarded.
+	atomic.StoreUint64(&rc.r, atomic.LoadUint64(&rc.w))
+	atomic.StoreUint64(&rc.dropped, 0)
+	rc.quit, rc.done = make(chan struct{}), make(chan struct{})
+	go rc.drain(rc.quit, rc.done)
+	atomic.StoreUint64(&rc.frame, 0)
+	rc.skip = rc.latency
+	if rc.tp != nil {
+		rc.tp.Seek(rc.pin - rc.pre)
+		rc.tp.Play()
+	}
+	atomic.StoreInt32(&rc.rec, 1)
+}
+
+// Stop stops recording and returns captured samples.
+func (rc *Recorder) Stop() Discrete {
+	rc.halt()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	buf := rc.buf
+	rc.buf = nil
+	return buf
+}
+
+// halt stops recording and waits for the drain, if running, to collect what
+// is left in the ring.
+func (rc *Recorder) halt() {
+	atomic.StoreInt32(&rc.rec, 0)
+	if rc.quit != nil {
+		close(rc.quit)
+		<-rc.done
+		rc.quit, rc.done = nil, nil
+	}
+}
+
+// drain moves samples from the ring to buf until quit is closed.
+func (rc *Recorder) drain(quit, done chan struct{}) {
+	defer close(done)
+	tick := time.NewTicker(20 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-rc.wake:
+		case <-tick.C:
+		case <-quit:
+			rc.flush()
+			return
+		}
+		rc.flush()
+	}
+}
+
+// flush moves samples written to the ring to buf, stopping recording once
+// buf is at the limit.
+func (rc *Recorder) flush() {
+	w, r := atomic.LoadUint64(&rc.w), atomic.LoadUint64(&rc.r)
+	n := uint64(len(rc.ring))
+	rc.mu.Lock()
+	for r < w && len(rc.buf) < rc.limit {
+		i := r % n
+		j := i + (w - r)
+		if j > n {
+			j = n
+		}
+		if room := uint64(rc.limit - len(rc.buf)); j-i > room {
+			j = i + room
+		}
+		rc.buf = append(rc.buf, rc.ring[i:j]...)
+		r += j - i
+	}
+	if len(rc.buf) >= rc.limit {
+		atomic.StoreInt32(&rc.rec, 0)
+		r = w
+	}
+	rc.mu.Unlock()
+	atomic.StoreUint64(&rc.r, r)
+}
+
+// write writes xs to the ring on the audio thread, dropping them if there is
+// no room, and wakes the drain once the ring is half full.
+func (rc *Recorder) write(xs Discrete) {
+	w, r := atomic.LoadUint64(&rc.w), atomic.LoadUint64(&rc.r)
+	n := uint64(len(rc.ring))
+	if uint64(len(xs)) > n-(w-r) {
+		atomic.AddUint64(&rc.dropped, uint64(len(xs)/rc.chans))
+		return
+	}
+	for _, x := range xs {
+		rc.ring[w%n] = x
+		w++
+	}
+	atomic.StoreUint64(&rc.w, w)
+	if w-r > n/2 {
+		select {
+		case rc.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (rc *Recorder) Recording() bool { return atomic.LoadInt32(&rc.rec) == 1 }
+
+// Frame returns frames recorded, or without punch points, frames since
+// recording started, which index recorded material once shifted by latency.
+// Frame is safe to call from other goroutines to timestamp events against the
+// recording.
+func (rc *Recorder) Frame() uint64 { return atomic.LoadUint64(&rc.frame) }
+
+func (rc *Recorder) Prepare(uint64) {
+	for i, x := range rc.in.Samples() {
+		if rc.off {
+			rc.out[i] = 0
+		} else {
+			rc.out[i] = x
+		}
+	}
+	if !rc.Recording() {
+		return
+	}
+	if rc.tp == nil {
+		frames := len(rc.out) / rc.chans
+		lo := rc.skip
+		if lo > frames {
+			lo = frames
+		}
+		rc.skip -= lo
+		rc.write(rc.out[lo*rc.chans:])
+		atomic.AddUint64(&rc.frame, uint64(frames))
+		return
+	}
+	rc.punch()
+}
+
+// punch records frames of the last buffer between punch points.
+func (rc *Recorder) punch() {
+	if !rc.tp.Playing() {
+		return
+	}
+	pout := rc.pout
+	if pout <= rc.pin {
+		pout = math.Inf(1)
+	}
+	frames := len(rc.out) / rc.chans
+	step := float64(rc.tp.BPM()) / (60 * rc.sr)
+	// frames arrive late by latency, played at an earlier beat.
+	lat := float64(rc.latency) * step
+	beat := rc.tp.Beat() - float64(frames)*step - lat
+	// eps absorbs round-off accumulated from summing fractional beats.
+	const eps = 1e-9
+	lo, hi := frames, frames
+	for i := 0; i < frames; i++ {
+		if b := beat + float64(i)*step + eps; b >= rc.pin && b < pout {
+			if lo == frames {
+				lo = i
+			}
+			hi = i + 1
+		}
+	}
+	if lo < hi {
+		rc.write(rc.out[lo*rc.chans : hi*rc.chans])
+		atomic.AddUint64(&rc.frame, uint64(hi-lo))
+	}
+	if rc.tp.Beat()+eps >= pout+math.Max(rc.post, lat) {
+		rc.tp.Stop()
+		atomic.StoreInt32(&rc.rec, 0)
+	}
+}
@@ -0,0 +1,98 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	rc := NewRecorder(NewPan(0, newunit()))
+	Render(rc, DefaultBufferLen)
+	if rc.Frame() != 0 {
+		t.Fatalf("have frame %v before record, want 0", rc.Frame())
+	}
+
+	rc.Record()
+	out := Render(rc, 2*DefaultBufferLen)
+	if rc.Frame() != 2*DefaultBufferLen {
+		t.Fatalf("have frame %v, want %v", rc.Frame(), 2*DefaultBufferLen)
+	}
+	buf := rc.Stop()
+	if len(buf) != len(out) || buf[0] != out[0] {
+		t.Fatalf("have %v samples, want %v", len(buf), len(out))
+	}
+	Render(rc, DefaultBufferLen)
+	if rc.Recording() || rc.Frame() != 2*DefaultBufferLen {
+		t.Fatal("recording after stop")
+	}
+}
+
+func TestRecorderPunch(t *testing.T) {
+	tp := NewTransport(120) // 22050 frames per beat
+	rc := NewRecorder(&counter{mono: newmono(nil)})
+	rc.SetPunch(tp, 1, 2)
+	rc.SetRoll(1, 0.5)
+	rc.Record()
+	if !tp.Playing() || tp.Beat() != 0 {
+		t.Fatalf("have beat %v, want pre-roll from 0", tp.Beat())
+	}
+	Render(rc, 22050*4)
+	if rc.Recording() || tp.Playing() {
+		t.Fatal("recording after post-roll")
+	}
+	if b := tp.Beat(); b < 2.5 || b > 2.6 {
+		t.Fatalf("have beat %v, want stop after post-roll", b)
+	}
+	buf := rc.Stop()
+	if len(buf) != 22050 || buf[0] != 22050 || buf[len(buf)-1] != 44099 {
+		t.Fatalf("have %v frames from %v", len(buf), buf[0])
+	}
+}
+
+func TestRecorderLatency(t *testing.T) {
+	rc := NewRecorder(&counter{mono: newmono(nil)})
+	rc.SetLatency(100)
+	rc.Record()
+	Render(rc, 2*DefaultBufferLen)
+	if rc.Frame() != 2*DefaultBufferLen {
+		t.Fatalf("have frame %v, want %v", rc.Frame(), 2*DefaultBufferLen)
+	}
+	buf := rc.Stop()
+	if len(buf) != 2*DefaultBufferLen-100 || buf[0] != 100 {
+		t.Fatalf("have %v frames from %v, want %v from 100", len(buf), buf[0], 2*DefaultBufferLen-100)
+	}
+
+	tp := NewTransport(120) // 22050 frames per beat
+	rc = NewRecorder(&counter{mono: newmono(nil)})
+	rc.SetPunch(tp, 1, 2)
+	rc.SetRoll(1, 0)
+	rc.SetLatency(1000)
+	rc.Record()
+	Render(rc, 22050*4)
+	if rc.Recording() {
+		t.Fatal("recording after latency past punch-out")
+	}
+	buf = rc.Stop()
+	if len(buf) != 22050 || buf[0] != 23050 || buf[len(buf)-1] != 45099 {
+		t.Fatalf("have %v frames from %v to %v", len(buf), buf[0], buf[len(buf)-1])
+	}
+}
+
+func TestRecorderLimit(t *testing.T) {
+	rc := NewRecorder(&counter{mono: newmono(nil)})
+	rc.SetLimit(100 * time.Millisecond) // 4410 frames
+	rc.Record()
+	if n := testing.AllocsPerRun(1, func() { rc.Prepare(1) }); n != 0 {
+		t.Fatalf("have %v allocations a buffer recording, want 0", n)
+	}
+	for i := 0; i < 100 && rc.Recording(); i++ {
+		Render(rc, DefaultBufferLen)
+		time.Sleep(time.Millisecond)
+	}
+	if rc.Recording() {
+		t.Fatal("recording past the limit")
+	}
+	if buf := rc.Stop(); len(buf) != 4410 || rc.Dropped() != 0 {
+		t.Fatalf("have %v frames of %v dropped, want 4410", len(buf), rc.Dropped())
+	}
+}
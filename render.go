@@ -0,0 +1,17 @@
+package snd
+
+// Render prepares sd offline for n frames and returns its interleaved samples.
+func Render(sd Sound, n int) Discrete { return new(Dispatcher).Render(sd, n) }
+
+// Render prepares sd offline for n frames with dp, calling its hooks such as
+// of an Automation, and returns its interleaved samples. Buffers start at tc 1.
+func (dp *Dispatcher) Render(sd Sound, n int) Discrete {
+	inps := GetInputs(sd)
+	want := n * sd.Channels()
+	buflen := len(sd.Samples())
+	out := make(Discrete, 0, want+buflen)
+	dp.DispatchN(1, (want+buflen-1)/buflen, func(uint64) {
+		out = append(out, sd.Samples()...)
+	}, inps...)
+	return out[:want]
+}
@@ -0,0 +1,41 @@
+package snd
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	const n = 1000
+	out := Render(NewPan(0, newunit()), n)
+	if len(out) != 2*n {
+		t.Fatalf("have length %v, want %v", len(out), 2*n)
+	}
+	if want := DefaultAmpFac * getpanfac(0); !equals(out[2*n-1], want) {
+		t.Fatalf("have %v, want %v", out[2*n-1], want)
+	}
+}
+
+func TestResample(t *testing.T) {
+	const from, to = 44100, 48000
+	sig := make(Discrete, from)
+	sig.Sample(SineFunc, 1000./from, 0)
+	out := Resample(sig, 1, from, to)
+	if len(out) != to {
+		t.Fatalf("have length %v, want %v", len(out), to)
+	}
+	want := make(Discrete, to)
+	want.Sample(SineFunc, 1000./to, 0)
+	// skip kernel edges
+	for i := 100; i < to-100; i++ {
+		if !equaleps(out[i], want[i], 0.001) {
+			t.Fatalf("out[%v] have %v, want %v", i, out[i], want[i])
+		}
+	}
+}
+
+func BenchmarkRender(b *testing.B) {
+	sd := mksound()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		Render(sd, DefaultBufferLen)
+	}
+}
@@ -0,0 +1,339 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Freeverb tunings in frames at 44.1kHz.
+var (
+	fvcombs  = [...]int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	fvallpas = [...]int{556, 441, 341, 225}
+)
+
+// fvcomb is a lowpass feedback comb filter of freeverb.
+type fvcomb struct {
+	buf   []float64
+	i     int
+	store float64
+}
+
+func (c *fvcomb) process(x, feedback, damp float64) float64 {
+	y := c.buf[c.i]
+	c.store = y*(1-damp) + c.store*damp
+	c.buf[c.i] = x + c.store*feedback
+	if c.i++; c.i == len(c.buf) {
+		c.i = 0
+	}
+	return y
+}
+
+func (c *fvcomb) clear() {
+	for j := range c.buf {
+		c.buf[j] = 0
+	}
+	c.store = 0
+}
+
+// fvallpass is a schroeder allpass filter of freeverb.
+type fvallpass struct {
+	buf []float64
+	i   int
+}
+
+func (a *fvallpass) process(x float64) float64 {
+	b := a.buf[a.i]
+	a.buf[a.i] = x + b*0.5
+	if a.i++; a.i == len(a.buf) {
+		a.i = 0
+	}
+	return b - x
+}
+
+// freeverb is the reverb of Jezar at Dreampoint, parallel combs into series
+// allpasses, processing a single channel.
+type freeverb struct {
+	combs      [len(fvcombs)]fvcomb
+	allpas     [len(fvallpas)]fvallpass
+	size, damp float64
+	feedback   float64
+	dampfac    float64
+	ncombs     int // run, by Quality
+}
+
+func newfreeverb(size, damp, sr float64) *freeverb {
+	fv := &freeverb{ncombs: len(fvcombs)}
+	scale := sr / 44100
+	for i, n := range fvcombs {
+		fv.combs[i].buf = make([]float64, int(float64(n)*scale))
+	}
+	for i, n := range fvallpas {
+		fv.allpas[i].buf = make([]float64, int(float64(n)*scale))
+	}
+	fv.set(size, damp)
+	return fv
+}
+
+// set sets room size and damping, both belonging to [0..1].
+func (fv *freeverb) set(size, damp float64) {
+	fv.size, fv.damp = size, damp
+	fv.feedback = 0.7 + 0.28*size
+	fv.dampfac = 0.4 * damp
+}
+
+// limit runs the feedback combs of the quality of st, at the start of each
+// buffer.
+func (fv *freeverb) limit(st *settings) {
+	if n := st.reverbCombs(); n != fv.ncombs {
+		// combs resuming start silent.
+		for i := fv.ncombs; i < n; i++ {
+			fv.combs[i].clear()
+		}
+		fv.ncombs = n
+	}
+}
+
+// process returns the reverberation of x.
+func (fv *freeverb) process(x float64) float64 {
+	const gain = 0.015 * 3 // fixed input gain and wet scale of freeverb
+	// fewer combs are raised to the power of all of them.
+	x *= gain * math.Sqrt(float64(len(fv.combs))/float64(fv.ncombs))
+	var y float64
+	for i := range fv.combs[:fv.ncombs] {
+		y += fv.combs[i].process(x, fv.feedback, fv.dampfac)
+	}
+	for i := range fv.allpas {
+		y = fv.allpas[i].process(y)
+	}
+	return y
+}
+
+// clear silences any tail.
+func (fv *freeverb) clear() {
+	for i := range fv.combs {
+		fv.combs[i].clear()
+	}
+	for i := range fv.allpas {
+		a := &fv.allpas[i]
+		for j := range a.buf {
+			a.buf[j] = 0
+		}
+	}
+}
+
+// Reverb simulates a room by a network of feedback combs and allpasses.
+type Reverb struct {
+	*mono
+	fv  *freeverb
+	mix float64
+}
+
+// NewReverb returns Reverb of room size and damping of high frequencies,
+// both belonging to [0..1], mixing half dry and half wet.
+func NewReverb(size, damp float64, in Sound) *Reverb {
+	sd := newmono(in)
+	return &Reverb{mono: sd, fv: newfreeverb(size, damp, sd.sr), mix: 0.5}
+}
+
+func (rv *Reverb) Size() float64     { return rv.fv.size }
+func (rv *Reverb) SetSize(x float64) { rv.fv.set(x, rv.fv.damp) }
+func (rv *Reverb) Damp() float64     { return rv.fv.damp }
+func (rv *Reverb) SetDamp(x float64) { rv.fv.set(rv.fv.size, x) }
+func (rv *Reverb) Mix() float64      { return rv.mix }
+func (rv *Reverb) SetMix(x float64)  { rv.mix = x }
+
+// Clear silences the reverb tail.
+func (rv *Reverb) Clear() { rv.fv.clear() }
+
+// Panic silences the reverb tail.
+func (rv *Reverb) Panic() { rv.Clear() }
+
+func (rv *Reverb) Params() []*Param {
+	return []*Param{
+		NewParam("size", rv.Size, rv.SetSize).Range(0, 1, 0.5),
+		NewParam("damp", rv.Damp, rv.SetDamp).Range(0, 1, 0.5),
+		NewParam("mix", rv.Mix, rv.SetMix).Range(0, 1, 0.3).In(UnitPercent),
+	}
+}
+
+func (rv *Reverb) Prepare(uint64) {
+	rv.fv.limit(rv.st)
+	for i, x := range rv.in.Samples() {
+		wet := rv.fv.process(x)
+		if rv.off {
+			rv.out[i] = 0
+		} else {
+			rv.out[i] = (1-rv.mix)*x + rv.mix*wet
+		}
+	}
+}
+
+// Shimmer is a reverb whose tail is pitch shifted and fed back into itself,
+// so each repeat of the tail rises by an interval, typically an octave or fifth.
+type Shimmer struct {
+	*mono
+	fv       *freeverb
+	ps       *shifter
+	feedback float64
+	mix      float64
+	last     float64 // shifted tail of last frame
+}
+
+// NewShimmer returns Shimmer shifting its tail by semitones, e.g. 12 or 7,
+// and feeding it back by feedback belonging to [0..1).
+func NewShimmer(semitones, feedback float64, in Sound) *Shimmer {
+	sd := newmono(in)
+	return &Shimmer{
+		mono:     sd,
+		fv:       newfreeverb(0.85, 0.3, sd.sr),
+		ps:       newshifter(semitones, sd.sr),
+		feedback: feedback,
+		mix:      0.5,
+	}
+}
+
+func (sh *Shimmer) Interval() float64     { return sh.ps.semis }
+func (sh *Shimmer) SetInterval(x float64) { sh.ps.set(x) }
+func (sh *Shimmer) Feedback() float64     { return sh.feedback }
+func (sh *Shimmer) SetFeedback(x float64) { sh.feedback = x }
+func (sh *Shimmer) Mix() float64          { return sh.mix }
+func (sh *Shimmer) SetMix(x float64)      { sh.mix = x }
+func (sh *Shimmer) Size() float64         { return sh.fv.size }
+func (sh *Shimmer) SetSize(x float64)     { sh.fv.set(x, sh.fv.damp) }
+func (sh *Shimmer) Damp() float64         { return sh.fv.damp }
+func (sh *Shimmer) SetDamp(x float64)     { sh.fv.set(sh.fv.size, x) }
+
+// Params returns interval in semitones, feedback, mix, size, and damp.
+func (sh *Shimmer) Params() []*Param {
+	return []*Param{
+		NewParam("interval", sh.Interval, sh.SetInterval).Range(-24, 24, 12).In(UnitSemitones),
+		NewParam("feedback", sh.Feedback, sh.SetFeedback).Range(0, 0.95, 0.5).In(UnitPercent),
+		NewParam("mix", sh.Mix, sh.SetMix).Range(0, 1, 0.3).In(UnitPercent),
+		NewParam("size", sh.Size, sh.SetSize).Range(0, 1, 0.8),
+		NewParam("damp", sh.Damp, sh.SetDamp).Range(0, 1, 0.5),
+	}
+}
+
+// Panic silences the reverb tail and its feedback.
+func (sh *Shimmer) Panic() {
+	sh.fv.clear()
+	sh.last = 0
+}
+
+func (sh *Shimmer) Prepare(uint64) {
+	sh.fv.limit(sh.st)
+	sh.ps.heads.limit(sh.st)
+	for i, x := range sh.in.Samples() {
+		// soft limit what's fed back so repeats can't run away.
+		wet := sh.fv.process(x + math.Tanh(sh.feedback*sh.last))
+		sh.last = sh.ps.process(wet)
+		if sh.off {
+			sh.out[i] = 0
+		} else {
+			sh.out[i] = (1-sh.mix)*x + sh.mix*wet
+		}
+	}
+}
+
+// GatedReverb is a reverb whose tail is cut by a gate keyed from its dry
+// input, the abrupt drum reverb of the 80s. The gate opens when the envelope
+// of input crosses a threshold, stays open for a hold time after it falls
+// back below, and then closes over a release time.
+type GatedReverb struct {
+	*mono
+	fv        *freeverb
+	threshold float64
+	hold, rel time.Duration
+	mix       float64
+
+	env  float64 // envelope of input
+	ca   float64 // envelope attack coefficient
+	cr   float64 // envelope release coefficient
+	left int     // frames of hold remaining
+	gain float64 // gain of gate applied to tail
+}
+
+// NewGatedReverb returns GatedReverb opening above threshold, holding for
+// hold and closing linearly over release, with a large room fully wet.
+func NewGatedReverb(threshold float64, hold, release time.Duration, in Sound) *GatedReverb {
+	sd := newmono(in)
+	return &GatedReverb{
+		mono:      sd,
+		fv:        newfreeverb(0.9, 0.2, sd.sr),
+		threshold: threshold,
+		hold:      hold,
+		rel:       release,
+		mix:       1,
+		ca:        smoothcoef(time.Millisecond, sd.sr),
+		cr:        smoothcoef(20*time.Millisecond, sd.sr),
+	}
+}
+
+func (gr *GatedReverb) Threshold() float64         { return gr.threshold }
+func (gr *GatedReverb) SetThreshold(x float64)     { gr.threshold = x }
+func (gr *GatedReverb) Hold() time.Duration        { return gr.hold }
+func (gr *GatedReverb) SetHold(d time.Duration)    { gr.hold = d }
+func (gr *GatedReverb) Release() time.Duration     { return gr.rel }
+func (gr *GatedReverb) SetRelease(d time.Duration) { gr.rel = d }
+func (gr *GatedReverb) Mix() float64               { return gr.mix }
+func (gr *GatedReverb) SetMix(x float64)           { gr.mix = x }
+func (gr *GatedReverb) Size() float64              { return gr.fv.size }
+func (gr *GatedReverb) SetSize(x float64)          { gr.fv.set(x, gr.fv.damp) }
+func (gr *GatedReverb) Damp() float64              { return gr.fv.damp }
+func (gr *GatedReverb) SetDamp(x float64)          { gr.fv.set(gr.fv.size, x) }
+
+// Open reports whether the gate is open at the last prepared frame,
+// including while it is releasing.
+func (gr *GatedReverb) Open() bool { return gr.gain > 0 }
+
+// Params returns threshold, hold and release in milliseconds, mix, size, and damp.
+func (gr *GatedReverb) Params() []*Param {
+	return []*Param{
+		NewParam("threshold", gr.Threshold, gr.SetThreshold).Range(0, 1, 0.1),
+		NewParam("hold",
+			func() float64 { return float64(gr.hold) / float64(time.Millisecond) },
+			func(x float64) { gr.hold = time.Duration(x * float64(time.Millisecond)) }).Range(0, 2000, 200).In(UnitMilliseconds),
+		NewParam("release",
+			func() float64 { return float64(gr.rel) / float64(time.Millisecond) },
+			func(x float64) { gr.rel = time.Duration(x * float64(time.Millisecond)) }).Range(0, 2000, 50).In(UnitMilliseconds),
+		NewParam("mix", gr.Mix, gr.SetMix).Range(0, 1, 0.5).In(UnitPercent),
+		NewParam("size", gr.Size, gr.SetSize).Range(0, 1, 0.8),
+		NewParam("damp", gr.Damp, gr.SetDamp).Range(0, 1, 0.5),
+	}
+}
+
+// Panic silences the reverb tail and closes the gate.
+func (gr *GatedReverb) Panic() {
+	gr.fv.clear()
+	gr.env, gr.gain, gr.left = 0, 0, 0
+}
+
+func (gr *GatedReverb) Prepare(uint64) {
+	gr.fv.limit(gr.st)
+	rel := gr.rel.Seconds() * gr.sr
+	for i, x := range gr.in.Samples() {
+		if a := math.Abs(x); a > gr.env {
+			gr.env += gr.ca * (a - gr.env)
+		} else {
+			gr.env += gr.cr * (a - gr.env)
+		}
+		switch {
+		case gr.env > gr.threshold:
+			gr.gain, gr.left = 1, Dtof(gr.hold, gr.sr)
+		case gr.left > 0:
+			gr.left--
+		case rel < 1:
+			gr.gain = 0
+		case gr.gain > 0:
+			if gr.gain -= 1 / rel; gr.gain < 0 {
+				gr.gain = 0
+			}
+		}
+		wet := gr.gain * gr.fv.process(x)
+		if gr.off {
+			gr.out[i] = 0
+		} else {
+			gr.out[i] = (1-gr.mix)*x + gr.mix*wet
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReverbTail(t *testing.T) {
+	rv := NewReverb(0.8, 0.3, newimpulse())
+	rv.SetMix(1)
+	out := Render(rv, Dtof(time.Second, DefaultSampleRate))
+	if x := Peak(out[len(out)/2:]); x == 0 || x > 0.1 {
+		t.Fatalf("have tail peak %v, want decaying tail", x)
+	}
+	rv.Clear()
+	if x := Peak(Render(rv, DefaultBufferLen)); x != 0 {
+		t.Fatalf("have %v after clear, want 0", x)
+	}
+}
+
+func TestShimmer(t *testing.T) {
+	// with feedback the tail lasts longer and remains bounded.
+	tail := func(fb float64) float64 {
+		sh := NewShimmer(12, fb, newimpulse())
+		sh.SetMix(1)
+		out := Render(sh, Dtof(3*time.Second, DefaultSampleRate))
+		for _, x := range out {
+			if math.IsNaN(x) || math.Abs(x) > 1 {
+				t.Fatalf("feedback %v: unbounded output %v", fb, x)
+			}
+		}
+		return Peak(out[len(out)-DefaultBufferLen*8:])
+	}
+	if dry, fb := tail(0), tail(0.6); fb <= dry {
+		t.Fatalf("have tail %v with feedback, %v without; want longer", fb, dry)
+	}
+}
+
+func TestGatedReverb(t *testing.T) {
+	gr := NewGatedReverb(0.01, 100*time.Millisecond, 50*time.Millisecond, newimpulse())
+	out := Render(gr, Dtof(300*time.Millisecond, DefaultSampleRate))
+	ms := func(n int) int { return Dtof(time.Duration(n)*time.Millisecond, DefaultSampleRate) }
+	if x := Peak(out[ms(50):ms(100)]); x == 0 {
+		t.Fatal("have silence while gate holds, want tail")
+	}
+	// envelope of impulse falls below threshold within 20ms, then hold and release.
+	if x := Peak(out[ms(200):]); x != 0 {
+		t.Fatalf("have %v after gate closes, want 0", x)
+	}
+	if gr.Open() {
+		t.Fatal("have gate open after release")
+	}
+}
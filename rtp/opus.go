@@ -0,0 +1,98 @@
+//go:build opus
+// +build opus
+
+package rtp
+
+/*
+#cgo pkg-config: opus
+#include <opus.h>
+
+static int snd_opus_set_bitrate(OpusEncoder *enc, opus_int32 bitrate) {
+	return opus_encoder_ctl(enc, OPUS_SET_BITRATE(bitrate));
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// opusMaxFrames is the most frames of a packet of Opus, 120ms at 48kHz.
+const opusMaxFrames = 5760
+
+// Opus is the codec of Opus of RFC 7587, of dynamic payload type 111, tuned
+// for low delay. Packets must be of 2.5, 5, 10, 20, 40, or 60ms, and the
+// sample rate 8, 12, 16, 24, or 48kHz.
+type Opus struct {
+	enc   *C.OpusEncoder
+	dec   *C.OpusDecoder
+	chans int
+	in    []float32
+	out   []float32
+	buf   []byte
+}
+
+// NewOpus returns Opus of chans channels at sample rate sr encoding at
+// bitrate bits per second, such as 128000 of stereo music. Close must be
+// called to release it.
+func NewOpus(sr float64, chans, bitrate int) (*Opus, error) {
+	var errc C.int
+	enc := C.opus_encoder_create(C.opus_int32(sr), C.int(chans), C.OPUS_APPLICATION_RESTRICTED_LOWDELAY, &errc)
+	if errc != C.OPUS_OK {
+		return nil, fmt.Errorf("snd/rtp: opus encoder: %s", C.GoString(C.opus_strerror(errc)))
+	}
+	dec := C.opus_decoder_create(C.opus_int32(sr), C.int(chans), &errc)
+	if errc != C.OPUS_OK {
+		C.opus_encoder_destroy(enc)
+		return nil, fmt.Errorf("snd/rtp: opus decoder: %s", C.GoString(C.opus_strerror(errc)))
+	}
+	C.snd_opus_set_bitrate(enc, C.opus_int32(bitrate))
+	return &Opus{
+		enc:   enc,
+		dec:   dec,
+		chans: chans,
+		out:   make([]float32, opusMaxFrames*chans),
+		buf:   make([]byte, 1500),
+	}, nil
+}
+
+// Close releases the encoder and decoder.
+func (op *Opus) Close() {
+	C.opus_encoder_destroy(op.enc)
+	C.opus_decoder_destroy(op.dec)
+}
+
+func (op *Opus) PayloadType() uint8 { return 111 }
+func (op *Opus) Channels() int      { return op.chans }
+
+func (op *Opus) Encode(b []byte, pcm []float64) ([]byte, error) {
+	op.in = op.in[:0]
+	for _, x := range pcm {
+		op.in = append(op.in, float32(x))
+	}
+	if len(op.in) == 0 {
+		return b, nil
+	}
+	n := C.opus_encode_float(op.enc, (*C.float)(&op.in[0]), C.int(len(pcm)/op.chans),
+		(*C.uchar)(&op.buf[0]), C.opus_int32(len(op.buf)))
+	if n < 0 {
+		return b, fmt.Errorf("snd/rtp: opus encode: %s", C.GoString(C.opus_strerror(n)))
+	}
+	return append(b, op.buf[:n]...), nil
+}
+
+func (op *Opus) Decode(pcm []float64, payload []byte) ([]float64, error) {
+	var data *C.uchar
+	if len(payload) != 0 {
+		data = (*C.uchar)(unsafe.Pointer(&payload[0]))
+	}
+	n := C.opus_decode_float(op.dec, data, C.opus_int32(len(payload)), (*C.float)(&op.out[0]), opusMaxFrames, 0)
+	if n < 0 {
+		return pcm, fmt.Errorf("snd/rtp: opus decode: %s", C.GoString(C.opus_strerror(n)))
+	}
+	for _, x := range op.out[:int(n)*op.chans] {
+		pcm = append(pcm, float64(x))
+	}
+	return pcm, nil
+}
@@ -0,0 +1,368 @@
+package rtp
+
+import (
+	"math"
+	"net"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// maxDrift bounds how far a Receiver resamples from the rate of its stream,
+// 2000ppm, beyond any drift of clocks of sound cards but not heard as pitch.
+const maxDrift = 0.002
+
+// queueLen is the capacity in packets of the queue from the network to the
+// audio thread, and maxPackets of the jitter buffer; 256 packets are 1.28s of
+// 5ms packets. Packets beyond either are dropped.
+const (
+	queueLen   = 256
+	maxPackets = 512
+)
+
+// Stats are counts of a Receiver.
+type Stats struct {
+	Received  uint64 // packets queued to the audio thread
+	Lost      uint64 // packets missing from the sequence
+	Late      uint64 // packets arriving after they were due
+	Invalid   uint64 // packets failing to parse or decode
+	Underruns uint64 // times the jitter buffer ran dry
+	Dropped   uint64 // packets beyond the capacity of the buffers
+
+	Buffered time.Duration // in the jitter buffer, smoothed
+	Ratio    float64       // of rates of playing to the stream
+}
+
+// packet is a packet received.
+type packet struct {
+	ts  int64 // extended timestamp of the first frame
+	pcm []float64
+}
+
+// queue is a ring of packets received, written by the network goroutine and
+// read by the audio thread without locking either.
+type queue struct {
+	w, r uint64 // atomic
+	buf  [queueLen]struct {
+		h   Header
+		pcm []float64
+	}
+}
+
+// put adds a packet, false if full.
+func (q *queue) put(h Header, pcm []float64) bool {
+	w := atomic.LoadUint64(&q.w)
+	if w-atomic.LoadUint64(&q.r) == queueLen {
+		return false
+	}
+	q.buf[w%queueLen].h, q.buf[w%queueLen].pcm = h, pcm
+	atomic.StoreUint64(&q.w, w+1)
+	return true
+}
+
+// get removes a packet, false if empty.
+func (q *queue) get() (Header, []float64, bool) {
+	r := atomic.LoadUint64(&q.r)
+	if r == atomic.LoadUint64(&q.w) {
+		return Header{}, nil, false
+	}
+	e := &q.buf[r%queueLen]
+	h, pcm := e.h, e.pcm
+	e.pcm = nil
+	atomic.StoreUint64(&q.r, r+1)
+	return h, pcm, true
+}
+
+// Receiver plays a stream of packets received over UDP, such as of a Sender,
+// at its own sample rate, expected to be that of the stream.
+//
+// Packets are held in a jitter buffer, reordered, and played once a latency
+// of 20ms is buffered; lost packets are played silent. The clocks of sender
+// and receiver drift apart, so the stream is resampled slightly faster as the
+// buffer fills beyond the latency and slower as it empties. The first stream
+// received is played until another takes its place, once the buffer has run
+// dry.
+//
+// The network goroutine only decodes packets and queues them to the audio
+// thread, which keeps the jitter buffer; no more than four times the latency
+// and 50ms is buffered, the oldest packets dropped beyond it.
+type Receiver struct {
+	// counts of Stats, atomic.
+	received, lost, late, invalid, underruns, dropped uint64
+	buffered, ratio64                                 uint64 // bits of fill and ratio
+	target                                            int64  // frames buffered to play
+
+	conn  net.PacketConn
+	codec Codec
+	sr    float64
+	out   snd.Discrete
+	q     queue
+
+	// of the audio thread.
+	pkts    []packet // by timestamp
+	ssrc    uint32
+	started bool   // of a stream
+	last    int64  // extended timestamp received latest
+	seq     uint16 // received latest
+	playing bool
+	pos     float64 // extended timestamp playing
+	fill    float64 // frames buffered, smoothed
+	ratio   float64
+
+	done chan struct{}
+}
+
+// NewReceiver returns Receiver of packets read from conn, decoded by codec,
+// at sample rate sr, until Close.
+func NewReceiver(conn net.PacketConn, codec Codec, sr float64) *Receiver {
+	rc := &Receiver{
+		conn:  conn,
+		codec: codec,
+		sr:    sr,
+		out:   make(snd.Discrete, snd.DefaultBufferLen*codec.Channels()),
+		pkts:  make([]packet, 0, maxPackets),
+		ratio: 1,
+		done:  make(chan struct{}),
+	}
+	rc.target = int64(snd.Dtof(20*time.Millisecond, sr))
+	rc.ratio64 = math.Float64bits(1)
+	go rc.read()
+	return rc
+}
+
+// Latency returns the duration buffered before playing.
+func (rc *Receiver) Latency() time.Duration {
+	return snd.Ftod(int(atomic.LoadInt64(&rc.target)), rc.sr)
+}
+
+// SetLatency sets the duration buffered before playing, more riding out
+// greater jitter of the network.
+func (rc *Receiver) SetLatency(d time.Duration) {
+	atomic.StoreInt64(&rc.target, int64(snd.Dtof(d, rc.sr)))
+}
+
+// Stats returns counts of packets and the state of the jitter buffer.
+func (rc *Receiver) Stats() Stats {
+	return Stats{
+		Received:  atomic.LoadUint64(&rc.received),
+		Lost:      atomic.LoadUint64(&rc.lost),
+		Late:      atomic.LoadUint64(&rc.late),
+		Invalid:   atomic.LoadUint64(&rc.invalid),
+		Underruns: atomic.LoadUint64(&rc.underruns),
+		Dropped:   atomic.LoadUint64(&rc.dropped),
+		Buffered:  snd.Ftod(int(math.Float64frombits(atomic.LoadUint64(&rc.buffered))), rc.sr),
+		Ratio:     math.Float64frombits(atomic.LoadUint64(&rc.ratio64)),
+	}
+}
+
+// Close stops receiving and closes the connection.
+func (rc *Receiver) Close() error {
+	err := rc.conn.Close()
+	<-rc.done
+	return err
+}
+
+func (rc *Receiver) read() {
+	defer close(rc.done)
+	buf := make([]byte, 1<<16)
+	for {
+		n, _, err := rc.conn.ReadFrom(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return
+		}
+		h, payload, err := ParseHeader(buf[:n])
+		var pcm []float64
+		if err == nil && h.PayloadType == rc.codec.PayloadType() {
+			pcm, err = rc.codec.Decode(nil, payload)
+		}
+		if err != nil || h.PayloadType != rc.codec.PayloadType() || len(pcm) == 0 {
+			atomic.AddUint64(&rc.invalid, 1)
+			continue
+		}
+		rc.push(h, pcm)
+	}
+}
+
+// end returns the extended timestamp past the last frame buffered.
+func (rc *Receiver) end() int64 {
+	p := rc.pkts[len(rc.pkts)-1]
+	return p.ts + int64(len(p.pcm)/rc.codec.Channels())
+}
+
+// push queues pcm of a packet of h to the audio thread.
+func (rc *Receiver) push(h Header, pcm []float64) {
+	if !rc.q.put(h, pcm) {
+		atomic.AddUint64(&rc.dropped, 1)
+		return
+	}
+	atomic.AddUint64(&rc.received, 1)
+}
+
+// limit returns the most frames buffered, beyond which the oldest are
+// dropped, or skipped once playing.
+func (rc *Receiver) limit(target int) int64 {
+	return int64(4*target + snd.Dtof(50*time.Millisecond, rc.sr))
+}
+
+// drain buffers packets queued, on the audio thread.
+func (rc *Receiver) drain(target int) {
+	for {
+		h, pcm, ok := rc.q.get()
+		if !ok {
+			return
+		}
+		rc.insert(h, pcm, target)
+	}
+}
+
+// insert buffers pcm of a packet of h.
+func (rc *Receiver) insert(h Header, pcm []float64, target int) {
+	if !rc.started || (h.SSRC != rc.ssrc && !rc.playing && len(rc.pkts) == 0) {
+		rc.ssrc, rc.started = h.SSRC, true
+		rc.last, rc.seq = int64(h.Timestamp), h.Seq-1
+	}
+	if h.SSRC != rc.ssrc {
+		return
+	}
+	d := int16(h.Seq - rc.seq)
+	if d > 0 {
+		atomic.AddUint64(&rc.lost, uint64(d-1))
+		rc.seq = h.Seq
+	}
+	ts := rc.last + int64(int32(h.Timestamp-uint32(rc.last)))
+	if ts > rc.last {
+		rc.last = ts
+	}
+	if rc.playing && ts+int64(len(pcm)/rc.codec.Channels()) <= int64(rc.pos) {
+		atomic.AddUint64(&rc.late, 1)
+		return
+	}
+	i := sort.Search(len(rc.pkts), func(i int) bool { return rc.pkts[i].ts >= ts })
+	if i < len(rc.pkts) && rc.pkts[i].ts == ts {
+		return // duplicate
+	}
+	if d <= 0 && atomic.LoadUint64(&rc.lost) > 0 {
+		atomic.AddUint64(&rc.lost, ^uint64(0)) // reordered, not lost
+	}
+	if len(rc.pkts) == maxPackets {
+		atomic.AddUint64(&rc.dropped, 1)
+		if i == 0 {
+			return
+		}
+		rc.forget(1)
+		i--
+	}
+	rc.pkts = rc.pkts[:len(rc.pkts)+1]
+	copy(rc.pkts[i+1:], rc.pkts[i:])
+	rc.pkts[i] = packet{ts, pcm}
+
+	// drop the oldest beyond the limit, such as of a stream not yet playing.
+	k, end := 0, rc.end()
+	for k < len(rc.pkts)-1 && end-rc.pkts[k+1].ts >= rc.limit(target) {
+		k++
+	}
+	if k > 0 {
+		rc.forget(k)
+		atomic.AddUint64(&rc.dropped, uint64(k))
+		if rc.playing && rc.pos < float64(rc.pkts[0].ts) {
+			rc.pos = float64(rc.pkts[0].ts)
+		}
+	}
+}
+
+// forget drops the first k packets buffered.
+func (rc *Receiver) forget(k int) {
+	n := copy(rc.pkts, rc.pkts[k:])
+	for i := n; i < len(rc.pkts); i++ {
+		rc.pkts[i] = packet{}
+	}
+	rc.pkts = rc.pkts[:n]
+}
+
+// frame returns sample ch of frame at extended timestamp ts, zero if lost,
+// searching packets from index *k on.
+func (rc *Receiver) frame(ts int64, ch int, k *int) float64 {
+	chans := rc.codec.Channels()
+	for ; *k < len(rc.pkts); *k++ {
+		p := rc.pkts[*k]
+		if ts < p.ts {
+			return 0
+		}
+		if i := int(ts - p.ts); i < len(p.pcm)/chans {
+			return p.pcm[i*chans+ch]
+		}
+	}
+	return 0
+}
+
+func (rc *Receiver) Channels() int            { return rc.codec.Channels() }
+func (rc *Receiver) SampleRate() float64      { return rc.sr }
+func (rc *Receiver) Inputs() []snd.Sound      { return nil }
+func (rc *Receiver) Samples() snd.Discrete    { return rc.out }
+func (rc *Receiver) Interp(t float64) float64 { return rc.out.Interp(t) }
+func (rc *Receiver) At(t float64) float64     { return rc.out.At(t) }
+func (rc *Receiver) Index(i int) float64      { return rc.out.Index(i) }
+
+func (rc *Receiver) Prepare(uint64) {
+	for i := range rc.out {
+		rc.out[i] = 0
+	}
+	itarget := int(atomic.LoadInt64(&rc.target))
+	rc.drain(itarget)
+	defer rc.publish()
+	target := float64(itarget)
+	if !rc.playing {
+		if len(rc.pkts) == 0 || rc.end()-rc.pkts[0].ts < int64(itarget) {
+			return
+		}
+		rc.playing, rc.pos, rc.fill = true, float64(rc.end())-target, target
+	}
+
+	// follow the clock of the sender by the fill of the buffer.
+	rc.fill += 0.05 * (float64(rc.end()) - rc.pos - rc.fill)
+	if rc.fill > float64(rc.limit(itarget)) {
+		// far behind, such as after a stall of the graph, so skip ahead.
+		rc.pos, rc.fill = float64(rc.end())-target, target
+	}
+	rc.ratio = 1 + math.Max(-maxDrift, math.Min(maxDrift, 0.01*(rc.fill-target)/math.Max(1, target)))
+
+	chans := rc.codec.Channels()
+	for f := 0; f < len(rc.out)/chans; f++ {
+		if rc.pos+1 >= float64(rc.end()) {
+			rc.playing = false
+			atomic.AddUint64(&rc.underruns, 1)
+			break
+		}
+		i := int64(rc.pos)
+		frac := rc.pos - float64(i)
+		k := 0
+		for ch := 0; ch < chans; ch++ {
+			x0 := rc.frame(i, ch, &k)
+			k1 := k
+			x1 := rc.frame(i+1, ch, &k1)
+			rc.out[f*chans+ch] = x0 + frac*(x1-x0)
+		}
+		rc.pos += rc.ratio
+	}
+
+	// forget packets played.
+	k := 0
+	for k < len(rc.pkts) && rc.pkts[k].ts+int64(len(rc.pkts[k].pcm)/chans) <= int64(rc.pos) {
+		k++
+	}
+	rc.forget(k)
+	if !rc.playing {
+		rc.fill = 0
+	}
+}
+
+// publish stores the fill and ratio for Stats.
+func (rc *Receiver) publish() {
+	atomic.StoreUint64(&rc.buffered, math.Float64bits(rc.fill))
+	atomic.StoreUint64(&rc.ratio64, math.Float64bits(rc.ratio))
+}
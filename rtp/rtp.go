@@ -0,0 +1,129 @@
+// Package rtp sends and receives audio between graphs over RTP on UDP, such
+// as between two engines on a LAN. A Sender passes its input through,
+// sending it in packets of a few milliseconds, and a Receiver plays packets
+// received through a jitter buffer, resampling slightly to follow the clock
+// of the sender.
+//
+// Audio is sent uncompressed as L16 of RFC 3551, or compressed as Opus when
+// built with the opus tag and libopus:
+//
+//  go build -tags opus
+package rtp // import "dasa.cc/snd/rtp"
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// headerLen is the length of a header without CSRCs or extension.
+const headerLen = 12
+
+// Header is the header of an RTP packet of RFC 3550.
+type Header struct {
+	PayloadType uint8
+	Marker      bool
+	Seq         uint16
+	Timestamp   uint32 // of the first frame, in frames
+	SSRC        uint32 // identifies the stream
+}
+
+// Append appends h encoded to b.
+func (h Header) Append(b []byte) []byte {
+	b2 := h.PayloadType & 0x7f
+	if h.Marker {
+		b2 |= 0x80
+	}
+	var buf [headerLen]byte
+	buf[0], buf[1] = 2<<6, b2
+	binary.BigEndian.PutUint16(buf[2:], h.Seq)
+	binary.BigEndian.PutUint32(buf[4:], h.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:], h.SSRC)
+	return append(b, buf[:]...)
+}
+
+var errShort = errors.New("snd/rtp: packet too short")
+
+// ParseHeader returns the header of packet b and its payload, skipping CSRCs,
+// extension, and padding.
+func ParseHeader(b []byte) (Header, []byte, error) {
+	if len(b) < headerLen {
+		return Header{}, nil, errShort
+	}
+	if v := b[0] >> 6; v != 2 {
+		return Header{}, nil, fmt.Errorf("snd/rtp: version %v not supported", v)
+	}
+	h := Header{
+		PayloadType: b[1] & 0x7f,
+		Marker:      b[1]&0x80 != 0,
+		Seq:         binary.BigEndian.Uint16(b[2:]),
+		Timestamp:   binary.BigEndian.Uint32(b[4:]),
+		SSRC:        binary.BigEndian.Uint32(b[8:]),
+	}
+	n := headerLen + 4*int(b[0]&0xf)
+	if b[0]&0x10 != 0 {
+		if len(b) < n+4 {
+			return Header{}, nil, errShort
+		}
+		n += 4 + 4*int(binary.BigEndian.Uint16(b[n+2:]))
+	}
+	end := len(b)
+	if b[0]&0x20 != 0 && end > 0 {
+		end -= int(b[end-1])
+	}
+	if n > end {
+		return Header{}, nil, errShort
+	}
+	return h, b[n:end], nil
+}
+
+// Codec encodes and decodes payloads of interleaved samples.
+type Codec interface {
+	// PayloadType returns the type of payloads in headers.
+	PayloadType() uint8
+
+	Channels() int
+
+	// Encode appends a payload of samples of whole frames to b.
+	Encode(b []byte, pcm []float64) ([]byte, error)
+
+	// Decode appends samples decoded of payload to pcm.
+	Decode(pcm []float64, payload []byte) ([]float64, error)
+}
+
+// l16 is the codec of L16.
+type l16 struct{ chans int }
+
+// L16 returns the codec of L16 of RFC 3551, samples of 16 bits big endian,
+// of payload type 11 of mono or 10 of stereo, as of 44.1kHz. A payload of
+// 1400 bytes, fitting a packet of ethernet, holds about 7ms of stereo.
+func L16(chans int) Codec { return l16{chans} }
+
+func (c l16) Channels() int { return c.chans }
+
+func (c l16) PayloadType() uint8 {
+	if c.chans == 2 {
+		return 10
+	}
+	return 11
+}
+
+func (c l16) Encode(b []byte, pcm []float64) ([]byte, error) {
+	for _, x := range pcm {
+		x = math.Max(-1, math.Min(1, x))
+		v := uint16(int16(math.Round(x * math.MaxInt16)))
+		b = append(b, byte(v>>8), byte(v))
+	}
+	return b, nil
+}
+
+func (c l16) Decode(pcm []float64, payload []byte) ([]float64, error) {
+	if len(payload)%(2*c.chans) != 0 {
+		return pcm, fmt.Errorf("snd/rtp: L16 payload of %v bytes not of whole frames", len(payload))
+	}
+	for i := 0; i < len(payload); i += 2 {
+		pcm = append(pcm, float64(int16(binary.BigEndian.Uint16(payload[i:])))/math.MaxInt16)
+	}
+	return pcm, nil
+}
@@ -0,0 +1,197 @@
+package rtp
+
+import (
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+func TestHeader(t *testing.T) {
+	h := Header{PayloadType: 10, Marker: true, Seq: 65535, Timestamp: 1 << 31, SSRC: 0xdeadbeef}
+	b := h.Append(nil)
+	b = append(b, 1, 2, 3, 4)
+	have, payload, err := ParseHeader(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have != h || len(payload) != 4 {
+		t.Errorf("have %+v payload %v, want %+v of 4 bytes", have, payload, h)
+	}
+
+	// a CSRC, an extension of a word, and padding of 2 bytes are skipped.
+	b = h.Append(nil)
+	b[0] |= 0x20 | 0x10 | 1
+	b = append(b, 0, 0, 0, 1)
+	b = append(b, 0xbe, 0xde, 0, 1, 9, 9, 9, 9)
+	b = append(b, 5, 6, 0, 2)
+	if _, payload, err = ParseHeader(b); err != nil || len(payload) != 2 || payload[0] != 5 {
+		t.Errorf("have payload %v err %v, want [5 6]", payload, err)
+	}
+	if _, _, err = ParseHeader(b[:8]); err == nil {
+		t.Error("have no error of a short packet")
+	}
+}
+
+func TestL16(t *testing.T) {
+	c := L16(2)
+	if c.PayloadType() != 10 || L16(1).PayloadType() != 11 {
+		t.Errorf("have payload types %v %v, want 10 11", c.PayloadType(), L16(1).PayloadType())
+	}
+	pcm := []float64{0, 0.5, -0.5, 1, -1, 2}
+	b, _ := c.Encode(nil, pcm)
+	have, err := c.Decode(nil, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, x := range []float64{0, 0.5, -0.5, 1, -1, 1} {
+		if math.Abs(have[i]-x) > 1e-4 {
+			t.Errorf("have %v at %v, want %v", have[i], i, x)
+		}
+	}
+	if _, err := c.Decode(nil, b[:3]); err == nil {
+		t.Error("have no error of a partial frame")
+	}
+}
+
+func TestSendReceive(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	osc := snd.NewOscil(snd.Sine(), 441, nil)
+	osc.SetAmp(0.5, nil)
+	sd, err := NewSender(conn, L16(1), 5*time.Millisecond, osc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sd.Close()
+	rc := NewReceiver(pc, L16(1), osc.SampleRate())
+	defer rc.Close()
+	rc.SetLatency(80 * time.Millisecond)
+
+	const bufs = 20
+	for i := 0; i < bufs; i++ {
+		osc.Prepare(uint64(i + 1))
+		sd.Prepare(uint64(i + 1))
+		time.Sleep(time.Millisecond)
+	}
+	want := uint64(bufs * snd.DefaultBufferLen / snd.Dtof(5*time.Millisecond, osc.SampleRate()))
+	for start := time.Now(); rc.Stats().Received < want; time.Sleep(time.Millisecond) {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("have %+v of %v packets sent", rc.Stats(), want)
+		}
+	}
+
+	// the sine plays on without gaps.
+	var out snd.Discrete
+	for i := 0; i < 4; i++ {
+		rc.Prepare(uint64(i + 1))
+		out = append(out, rc.Samples()...)
+	}
+	if pk := snd.Peak(out); math.Abs(pk-0.5) > 0.01 {
+		t.Errorf("have peak %v, want 0.5", pk)
+	}
+	step := 0.5 * 2 * math.Pi * 441 / osc.SampleRate() * 1.1
+	for i := 1; i < len(out); i++ {
+		if d := math.Abs(out[i] - out[i-1]); d > step {
+			t.Fatalf("have step %v at %v, want up to %v", d, i, step)
+		}
+	}
+	if st := rc.Stats(); st.Lost != 0 || st.Underruns != 0 || sd.Dropped() != 0 {
+		t.Errorf("have %+v, dropped %v", st, sd.Dropped())
+	}
+}
+
+func TestJitterBuffer(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	rc := NewReceiver(pc, L16(1), snd.DefaultSampleRate)
+	defer rc.Close()
+	const frames = 100
+	push := func(seq uint16) {
+		pcm := make([]float64, frames)
+		for i := range pcm {
+			pcm[i] = 0.25
+		}
+		rc.push(Header{PayloadType: 11, Seq: seq, Timestamp: uint32(seq) * frames, SSRC: 1}, pcm)
+		rc.drain(int(rc.target))
+	}
+
+	// out of order and lost.
+	push(0)
+	push(2)
+	if st := rc.Stats(); st.Lost != 1 {
+		t.Errorf("have lost %v, want 1", st.Lost)
+	}
+	push(1)
+	if st := rc.Stats(); st.Lost != 0 || st.Received != 3 {
+		t.Errorf("have lost %v received %v, want 0 3", st.Lost, st.Received)
+	}
+
+	// buffered well beyond the latency once playing, so played faster.
+	for seq := uint16(3); seq < 12; seq++ {
+		push(seq)
+	}
+	rc.Prepare(1)
+	if pk := snd.Peak(rc.Samples()); pk != 0.25 {
+		t.Errorf("have peak %v, want 0.25", pk)
+	}
+	for seq := uint16(12); seq < 80; seq++ {
+		push(seq)
+	}
+	for i := 0; i < 5; i++ {
+		rc.Prepare(uint64(i + 2))
+	}
+	if st := rc.Stats(); st.Ratio <= 1 {
+		t.Errorf("have ratio %v buffered %v, want faster", st.Ratio, st.Buffered)
+	}
+
+	// played dry.
+	for i := 0; i < 60; i++ {
+		rc.Prepare(uint64(i + 7))
+	}
+	if st := rc.Stats(); st.Underruns != 1 {
+		t.Errorf("have underruns %v, want 1", st.Underruns)
+	}
+	if pk := snd.Peak(rc.Samples()); pk != 0 {
+		t.Errorf("have peak %v once dry, want silent", pk)
+	}
+}
+
+func TestJitterBufferBounded(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skip(err)
+	}
+	rc := NewReceiver(pc, L16(1), snd.DefaultSampleRate)
+	defer rc.Close()
+	pcm := make([]float64, 10)
+
+	// flooded while not playing, such as with the latency beyond the stream.
+	rc.SetLatency(time.Hour)
+	for seq := 0; seq < 10*queueLen; seq++ {
+		rc.push(Header{PayloadType: 11, Seq: uint16(seq), Timestamp: uint32(seq) * 10, SSRC: 1}, pcm)
+		if seq%queueLen == queueLen-1 {
+			rc.Prepare(uint64(seq))
+		}
+	}
+	rc.push(Header{PayloadType: 11, Seq: 0, SSRC: 1}, pcm)
+	if st := rc.Stats(); st.Dropped == 0 || len(rc.pkts) > maxPackets {
+		t.Errorf("have %v packets buffered, %+v", len(rc.pkts), st)
+	}
+
+	rc.SetLatency(10 * time.Millisecond)
+	rc.Prepare(0)
+	if n := int64(len(rc.pkts)) * 10; n > rc.limit(int(rc.target))+10 {
+		t.Errorf("have %v frames buffered, want at most %v", n, rc.limit(int(rc.target)))
+	}
+}
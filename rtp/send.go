@@ -0,0 +1,166 @@
+package rtp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// sendQueue is the number of packets queued to send.
+const sendQueue = 16
+
+// outpkt is a packet queued to send.
+type outpkt struct {
+	hdr Header
+	pcm []float64
+}
+
+// Sender passes its input through, sending it in packets over a connection
+// of UDP, such as one dialed to a Receiver. Packets are encoded and written
+// on a goroutine of their own, not the audio thread, and dropped if the
+// goroutine falls behind.
+type Sender struct {
+	conn   net.Conn
+	codec  Codec
+	in     snd.Sound
+	frames int // of a packet
+
+	hdr  Header
+	cur  *outpkt // filling
+	skip int     // samples left of a packet dropped
+	free chan *outpkt
+	pkts chan *outpkt
+
+	dropped, errs uint64 // atomic
+	quit, done    chan struct{}
+}
+
+// NewSender returns Sender of in to conn by codec, in packets of ptime, such
+// as 5ms, until Close. The input must be of the channels of codec.
+func NewSender(conn net.Conn, codec Codec, ptime time.Duration, in snd.Sound) (*Sender, error) {
+	if in.Channels() != codec.Channels() {
+		return nil, fmt.Errorf("snd/rtp: input of %v channels sent by codec of %v", in.Channels(), codec.Channels())
+	}
+	frames := snd.Dtof(ptime, in.SampleRate())
+	if frames < 1 {
+		return nil, fmt.Errorf("snd/rtp: packets of %v too short", ptime)
+	}
+	// streams start at random, as of RFC 3550.
+	var rnd [10]byte
+	if _, err := rand.Read(rnd[:]); err != nil {
+		return nil, err
+	}
+	sd := &Sender{
+		conn:   conn,
+		codec:  codec,
+		in:     in,
+		frames: frames,
+		hdr: Header{
+			PayloadType: codec.PayloadType(),
+			Seq:         binary.BigEndian.Uint16(rnd[0:]),
+			Timestamp:   binary.BigEndian.Uint32(rnd[2:]),
+			SSRC:        binary.BigEndian.Uint32(rnd[6:]),
+		},
+		free: make(chan *outpkt, sendQueue),
+		pkts: make(chan *outpkt, sendQueue),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	for i := 0; i < sendQueue; i++ {
+		sd.free <- &outpkt{pcm: make([]float64, 0, frames*codec.Channels())}
+	}
+	go sd.send()
+	return sd, nil
+}
+
+// SSRC returns the identifier of the stream sent.
+func (sd *Sender) SSRC() uint32 { return sd.hdr.SSRC }
+
+// Dropped returns the number of packets dropped, not sent in time.
+func (sd *Sender) Dropped() uint64 { return atomic.LoadUint64(&sd.dropped) }
+
+// Errors returns the number of packets failing to encode or write.
+func (sd *Sender) Errors() uint64 { return atomic.LoadUint64(&sd.errs) }
+
+// Close stops sending and closes the connection.
+func (sd *Sender) Close() error {
+	close(sd.quit)
+	<-sd.done
+	return sd.conn.Close()
+}
+
+func (sd *Sender) send() {
+	defer close(sd.done)
+	var b []byte
+	for {
+		select {
+		case p := <-sd.pkts:
+			var err error
+			b, err = sd.codec.Encode(p.hdr.Append(b[:0]), p.pcm)
+			if err == nil {
+				_, err = sd.conn.Write(b)
+			}
+			if err != nil {
+				atomic.AddUint64(&sd.errs, 1)
+			}
+			sd.free <- p
+		case <-sd.quit:
+			return
+		}
+	}
+}
+
+func (sd *Sender) Channels() int            { return sd.in.Channels() }
+func (sd *Sender) SampleRate() float64      { return sd.in.SampleRate() }
+func (sd *Sender) Inputs() []snd.Sound      { return []snd.Sound{sd.in} }
+func (sd *Sender) Samples() snd.Discrete    { return sd.in.Samples() }
+func (sd *Sender) Interp(t float64) float64 { return sd.in.Interp(t) }
+func (sd *Sender) At(t float64) float64     { return sd.in.At(t) }
+func (sd *Sender) Index(i int) float64      { return sd.in.Index(i) }
+
+func (sd *Sender) Prepare(uint64) {
+	xs := sd.in.Samples()
+	n := sd.frames * sd.codec.Channels()
+	for len(xs) > 0 {
+		if sd.cur == nil && sd.skip == 0 {
+			select {
+			case sd.cur = <-sd.free:
+				sd.cur.hdr, sd.cur.pcm = sd.hdr, sd.cur.pcm[:0]
+			default:
+				// none free, so the packet is dropped, leaving a gap of
+				// sequence and timestamp the receiver plays silent.
+				sd.skip = n
+			}
+		}
+		var full bool
+		if sd.cur != nil {
+			k := n - len(sd.cur.pcm)
+			if k > len(xs) {
+				k = len(xs)
+			}
+			sd.cur.pcm, xs = append(sd.cur.pcm, xs[:k]...), xs[k:]
+			if full = len(sd.cur.pcm) == n; full {
+				sd.pkts <- sd.cur
+				sd.cur = nil
+			}
+		} else {
+			k := sd.skip
+			if k > len(xs) {
+				k = len(xs)
+			}
+			sd.skip, xs = sd.skip-k, xs[k:]
+			if full = sd.skip == 0; full {
+				atomic.AddUint64(&sd.dropped, 1)
+			}
+		}
+		if full {
+			sd.hdr.Seq++
+			sd.hdr.Timestamp += uint32(sd.frames)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package snd
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Curve shapes position t of a Morph belonging to [0..1] into the blend of
+// scene B over scene A, also belonging to [0..1].
+type Curve func(t float64) float64
+
+// Curves of a Morph.
+var (
+	// CurveLinear blends evenly.
+	CurveLinear Curve = func(t float64) float64 { return t }
+
+	// CurveSmooth eases in and out of each scene.
+	CurveSmooth Curve = func(t float64) float64 { return t * t * (3 - 2*t) }
+
+	// CurveExp blends slowly at first and quickly near B, as is even to the
+	// ear for params such as frequency and time.
+	CurveExp Curve = func(t float64) float64 { return (math.Pow(64, t) - 1) / 63 }
+
+	// CurveStep holds scene A until half way, then jumps to B, as for params
+	// that select a wave or mode.
+	CurveStep Curve = func(t float64) float64 {
+		if t < 0.5 {
+			return 0
+		}
+		return 1
+	}
+)
+
+// Morph captures all params of a Params as scenes A and B and morphs between
+// them by a single position, where 0 is A and 1 is B, for transitions in
+// performance. Params blend linearly unless given a Curve of their own.
+//
+// Morph glides position over time when Tick is added as a hook of the
+// Dispatcher playing the params, such as by Dispatcher.BeforeDispatch, so
+// params change on buffer boundaries. All methods are safe to call from any
+// goroutine.
+//
+// Morph is itself Parameterized by its position, so a controller may drive
+// it, but must not be registered in the Params it morphs.
+type Morph struct {
+	mu     sync.Mutex
+	ps     *Params
+	sr     float64
+	a, b   Preset
+	curves map[string]Curve
+	pos    float64
+
+	// glide in progress
+	gliding  bool
+	from, to float64
+	frames   uint64 // duration of glide
+	start    uint64 // frame glide started
+	started  bool
+}
+
+// NewMorph returns Morph of ps at sample rate sr with current values of ps
+// as both scenes, at position 0.
+func NewMorph(ps *Params, sr float64) *Morph {
+	m := &Morph{ps: ps, sr: sr, curves: make(map[string]Curve)}
+	m.a, m.b = ps.Save(), ps.Save()
+	return m
+}
+
+// CaptureA stores current values of params as scene A.
+func (m *Morph) CaptureA() {
+	m.mu.Lock()
+	m.a = m.ps.Save()
+	m.mu.Unlock()
+}
+
+// CaptureB stores current values of params as scene B.
+func (m *Morph) CaptureB() {
+	m.mu.Lock()
+	m.b = m.ps.Save()
+	m.mu.Unlock()
+}
+
+// A returns a copy of scene A.
+func (m *Morph) A() Preset {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copypreset(m.a)
+}
+
+// B returns a copy of scene B.
+func (m *Morph) B() Preset {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return copypreset(m.b)
+}
+
+// SetA sets scene A to pre, such as one loaded by ReadPreset.
+func (m *Morph) SetA(pre Preset) {
+	m.mu.Lock()
+	m.a = copypreset(pre)
+	m.mu.Unlock()
+}
+
+// SetB sets scene B to pre, such as one loaded by ReadPreset.
+func (m *Morph) SetB(pre Preset) {
+	m.mu.Lock()
+	m.b = copypreset(pre)
+	m.mu.Unlock()
+}
+
+func copypreset(pre Preset) Preset {
+	cp := make(Preset, len(pre))
+	for name, x := range pre {
+		cp[name] = x
+	}
+	return cp
+}
+
+// SetCurve sets the curve of param name, or linear if c is nil.
+func (m *Morph) SetCurve(name string, c Curve) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c == nil {
+		delete(m.curves, name)
+	} else {
+		m.curves[name] = c
+	}
+}
+
+// Position returns the current position between scenes.
+func (m *Morph) Position() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.pos
+}
+
+// SetPosition stops any glide and sets params at once to position x
+// belonging to [0..1].
+func (m *Morph) SetPosition(x float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gliding = false
+	m.apply(x)
+}
+
+// MorphTo glides from the current position to x over d, starting at the next
+// Tick. A glide of zero duration sets params at the next Tick.
+func (m *Morph) MorphTo(x float64, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gliding, m.started = true, false
+	m.from, m.to = m.pos, math.Max(0, math.Min(1, x))
+	m.frames = uint64(Dtof(d, m.sr))
+}
+
+// Morphing reports whether a glide is in progress.
+func (m *Morph) Morphing() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.gliding
+}
+
+// Tick advances a glide to frame, and is a Hook.
+func (m *Morph) Tick(tc, frame uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.gliding {
+		return
+	}
+	if !m.started {
+		m.start, m.started = frame, true
+	}
+	t := 1.0
+	if m.frames > 0 {
+		t = math.Min(1, float64(frame-m.start)/float64(m.frames))
+	}
+	m.apply(m.from + t*(m.to-m.from))
+	if t == 1 {
+		m.gliding = false
+	}
+}
+
+// apply sets params to position x; m.mu must be held.
+func (m *Morph) apply(x float64) {
+	m.pos = math.Max(0, math.Min(1, x))
+	for name, a := range m.a {
+		b, ok := m.b[name]
+		if !ok || a == b {
+			continue
+		}
+		p := m.ps.Lookup(name)
+		if p == nil {
+			continue
+		}
+		t := m.pos
+		if c, ok := m.curves[name]; ok {
+			t = c(t)
+		}
+		p.Set(a + t*(b-a))
+	}
+}
+
+// Params returns "position" between scenes, set at once.
+func (m *Morph) Params() []*Param {
+	return []*Param{NewParam("position", m.Position, m.SetPosition).Range(0, 1, 0).In(UnitPercent)}
+}
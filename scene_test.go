@@ -0,0 +1,43 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMorph(t *testing.T) {
+	osc := NewOscil(Sine(), 200, nil)
+	lp := NewLowPass(1000, osc)
+	var ps Params
+	ps.Register("osc", osc)
+	ps.Register("lp", lp)
+
+	m := NewMorph(&ps, DefaultSampleRate)
+	osc.SetFreq(400, nil)
+	lp.SetFreq(5000)
+	m.CaptureB()
+	m.SetCurve("lp.freq", CurveStep)
+
+	m.SetPosition(0.25)
+	if osc.Freq() != 250 || lp.Freq() != 1000 {
+		t.Fatalf("have freqs %v and %v, want 250 and 1000", osc.Freq(), lp.Freq())
+	}
+	m.SetPosition(0.75)
+	if osc.Freq() != 350 || lp.Freq() != 5000 {
+		t.Fatalf("have freqs %v and %v, want 350 and 5000", osc.Freq(), lp.Freq())
+	}
+
+	var dp Dispatcher
+	dp.BeforeDispatch(m.Tick)
+	m.MorphTo(0, time.Second)
+	inps := GetInputs(lp)
+	n := Dtof(time.Second, DefaultSampleRate) / len(lp.Samples())
+	dp.DispatchN(1, n/2, nil, inps...)
+	if !m.Morphing() || !equaleps(m.Position(), 0.375, 0.01) {
+		t.Fatalf("have position %v half way, want about 0.375", m.Position())
+	}
+	dp.DispatchN(1+uint64(n/2), n, nil, inps...)
+	if m.Morphing() || m.Position() != 0 || osc.Freq() != 200 {
+		t.Fatalf("have position %v and freq %v after morph, want scene A", m.Position(), osc.Freq())
+	}
+}
@@ -0,0 +1,94 @@
+package snd
+
+import (
+	"sync"
+	"time"
+)
+
+// schedule holds frames until a Sound is turned on or off by a Dispatcher,
+// counted from the start of the next buffer prepared.
+type schedule struct {
+	mu      sync.Mutex
+	on, off int // frames until, or -1 if none
+}
+
+func newschedule() *schedule { return &schedule{on: -1, off: -1} }
+
+func (sd *mono) base() *mono { return sd }
+
+// OnAt turns sd on d after the start of the next buffer prepared, accurate to
+// the frame. Frames of that buffer before d are silent. OnAt is safe to call
+// from any goroutine and replaces any time set before.
+func (sd *mono) OnAt(d time.Duration) {
+	sd.sched.mu.Lock()
+	sd.sched.on = Dtof(d, sd.sr)
+	sd.sched.mu.Unlock()
+}
+
+// OffAt turns sd off d after the start of the next buffer prepared, accurate
+// to the frame. Frames of that buffer from d on are silent. OffAt is safe to
+// call from any goroutine and replaces any time set before.
+func (sd *mono) OffAt(d time.Duration) {
+	sd.sched.mu.Lock()
+	sd.sched.off = Dtof(d, sd.sr)
+	sd.sched.mu.Unlock()
+}
+
+// prepare prepares sd, turning it on or off at any frames scheduled within the
+// buffer by OnAt and OffAt.
+func prepare(sd Sound, tc uint64) {
+	b, ok := sd.(interface{ base() *mono })
+	if !ok {
+		sd.Prepare(tc)
+		return
+	}
+	m := b.base()
+	sc := m.sched
+	sc.mu.Lock()
+	on, off := sc.on, sc.off
+	sc.mu.Unlock()
+	if on < 0 && off < 0 {
+		sd.Prepare(tc)
+		return
+	}
+
+	out, chans := sd.Samples(), sd.Channels()
+	n := len(out) / chans
+	state := !m.off
+	if on >= 0 && on < n {
+		m.off = false
+	}
+	sd.Prepare(tc)
+	if (on >= 0 && on < n) || (off >= 0 && off < n) {
+		for f := 0; f < n; f++ {
+			if f == on {
+				state = true
+			}
+			if f == off {
+				state = false
+			}
+			if !state {
+				for i := f * chans; i < (f+1)*chans; i++ {
+					out[i] = 0
+				}
+			}
+		}
+		m.off = !state
+	}
+
+	// times set while preparing are kept as given for the next buffer.
+	next := func(x int) int {
+		if x -= n; x < 0 {
+			return -1
+		}
+		return x
+	}
+	sc.mu.Lock()
+	if sc.on == on && on >= 0 {
+		sc.on = next(on)
+	}
+	if sc.off == off && off >= 0 {
+		sc.off = next(off)
+	}
+	sc.mu.Unlock()
+}
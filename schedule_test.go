@@ -0,0 +1,28 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOnAt(t *testing.T) {
+	sr := DefaultSampleRate
+	c := NewConst(1)
+	c.Off()
+	on, off := 3*time.Millisecond, 30*time.Millisecond
+	c.OnAt(on)
+	c.OffAt(off)
+	out := Render(c, Dtof(50*time.Millisecond, sr))
+	for i, x := range out {
+		want := 0.0
+		if i >= Dtof(on, sr) && i < Dtof(off, sr) {
+			want = 1
+		}
+		if x != want {
+			t.Fatalf("frame %v: have %v, want %v", i, x, want)
+		}
+	}
+	if !c.IsOff() {
+		t.Fatal("have on after OffAt, want off")
+	}
+}
@@ -0,0 +1,128 @@
+package snd
+
+import "time"
+
+// hermite returns the value between y1 and y2 at t in [0..1] by a cubic
+// hermite spline through four consecutive samples.
+func hermite(y0, y1, y2, y3, t float64) float64 {
+	c1 := 0.5 * (y2 - y0)
+	c2 := y0 - 2.5*y1 + 2*y2 - 0.5*y3
+	c3 := 0.5*(y3-y0) + 1.5*(y1-y2)
+	return ((c3*t+c2)*t+c1)*t + y1
+}
+
+// Scrub plays back interleaved samples from a read position that may be moved
+// freely, like a record under hand or tape on a reel. Position is driven by a
+// control signal or by method calls and is smoothed so that sudden moves
+// sweep rather than click, with samples read by cubic interpolation.
+//
+// Without a control signal, position advances at a speed that may ramp down
+// to a halt and back up for tape stop and start effects.
+type Scrub struct {
+	*mono
+	sig   Discrete
+	chans int
+	nfr   int
+	srcsr float64
+	ctl   Sound
+
+	target float64 // position in frames before smoothing
+	pos    float64 // position in frames read
+	cs     float64 // smoothing coefficient
+	speed  float64
+	goal   float64 // speed ramped toward
+	ramp   float64 // speed change per frame
+}
+
+// NewScrub returns a stopped Scrub of sig with chans channels recorded at sample rate sr.
+func NewScrub(sig Discrete, chans int, sr float64) *Scrub {
+	sd := newmono(nil)
+	sd.out = make(Discrete, DefaultBufferLen*chans)
+	return &Scrub{
+		mono:  sd,
+		sig:   sig,
+		chans: chans,
+		nfr:   len(sig) / chans,
+		srcsr: sr,
+		cs:    smoothcoef(10*time.Millisecond, sd.sr),
+	}
+}
+
+func (sc *Scrub) Channels() int { return sc.chans }
+
+func (sc *Scrub) Inputs() []Sound {
+	if sc.ctl == nil {
+		return nil
+	}
+	return []Sound{sc.ctl}
+}
+
+// SetControl drives position by ctl, where 0 is the first frame and 1 is the
+// end of samples. A nil ctl returns position to method calls and speed.
+func (sc *Scrub) SetControl(ctl Sound) { sc.ctl = ctl }
+
+// SetSmoothing sets the time constant by which position follows its target.
+func (sc *Scrub) SetSmoothing(d time.Duration) { sc.cs = smoothcoef(d, sc.sr) }
+
+// Pos returns the frame position read.
+func (sc *Scrub) Pos() float64 { return sc.pos }
+
+// SetPos moves position to frame, sweeping there by smoothing.
+func (sc *Scrub) SetPos(frame float64) { sc.target = frame }
+
+// Speed returns the rate at which position advances, where 1 is the rate
+// recorded and negative values play backwards.
+func (sc *Scrub) Speed() float64 { return sc.speed }
+
+// SetSpeed sets speed immediately.
+func (sc *Scrub) SetSpeed(x float64) { sc.speed, sc.goal, sc.ramp = x, x, 0 }
+
+// RampSpeed changes speed linearly to x over d, e.g. to 0 for a tape stop.
+func (sc *Scrub) RampSpeed(x float64, d time.Duration) {
+	sc.goal = x
+	if n := Dtof(d, sc.sr); n > 0 {
+		sc.ramp = (x - sc.speed) / float64(n)
+	} else {
+		sc.SetSpeed(x)
+	}
+}
+
+// at returns channel c of frame f, silent outside of samples.
+func (sc *Scrub) at(f, c int) float64 {
+	if f < 0 || f >= sc.nfr {
+		return 0
+	}
+	return sc.sig[f*sc.chans+c]
+}
+
+func (sc *Scrub) Prepare(uint64) {
+	for i := 0; i < len(sc.out); i += sc.chans {
+		if sc.ctl != nil {
+			sc.target = sc.ctl.Index(i/sc.chans) * float64(sc.nfr)
+		} else {
+			if sc.ramp != 0 {
+				sc.speed += sc.ramp
+				if (sc.ramp > 0) == (sc.speed >= sc.goal) {
+					sc.speed, sc.ramp = sc.goal, 0
+				}
+			}
+			sc.target += sc.speed * sc.srcsr / sc.sr
+		}
+		if sc.target < 0 {
+			sc.target = 0
+		} else if max := float64(sc.nfr); sc.target > max {
+			sc.target = max
+		}
+		sc.pos += sc.cs * (sc.target - sc.pos)
+
+		j := int(sc.pos)
+		t := sc.pos - float64(j)
+		for c := 0; c < sc.chans; c++ {
+			if sc.off {
+				sc.out[i+c] = 0
+				continue
+			}
+			sc.out[i+c] = hermite(sc.at(j-1, c), sc.at(j, c), sc.at(j+1, c), sc.at(j+2, c), t)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScrub(t *testing.T) {
+	sig := make(Discrete, 44100)
+	for i := range sig {
+		sig[i] = float64(i)
+	}
+	sc := NewScrub(sig, 1, DefaultSampleRate)
+	sc.SetSpeed(1)
+	out := Render(sc, 2*DefaultBufferLen*8)
+	if d := out[len(out)-1] - out[len(out)-2]; !equaleps(d, 1, 1e-3) {
+		t.Fatalf("have advance %v per frame, want 1", d)
+	}
+
+	// tape stop halts position.
+	sc.RampSpeed(0, 100*time.Millisecond)
+	out = Render(sc, Dtof(300*time.Millisecond, DefaultSampleRate))
+	if sc.Speed() != 0 {
+		t.Fatalf("have speed %v after ramp, want 0", sc.Speed())
+	}
+	if d := out[len(out)-1] - out[len(out)-2]; !equaleps(d, 0, 1e-3) {
+		t.Fatalf("have advance %v per frame after stop, want 0", d)
+	}
+
+	// control moves position to half of samples.
+	ctl := newlevel()
+	ctl.set(0.5)
+	sc.SetControl(ctl)
+	out = Render(sc, Dtof(200*time.Millisecond, DefaultSampleRate))
+	if x := out[len(out)-1]; !equaleps(x, 22050, 1) {
+		t.Fatalf("have %v under control, want 22050", x)
+	}
+}
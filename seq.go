@@ -0,0 +1,396 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Rest is the key of a step that plays no note.
+const Rest = -1
+
+// Step is a single step of a Pattern.
+type Step struct {
+	Key  int     `json:"key"`            // MIDI key number, or Rest
+	Vel  float64 `json:"vel"`            // velocity in [0..1]
+	Gate float64 `json:"gate,omitempty"` // fraction of step the note is held, or zero for half
+
+	// Auto holds values of automation lanes set by this step, by lane name.
+	// Lanes not set hold their last value.
+	Auto map[string]float64 `json:"auto,omitempty"`
+
+	// Slide interpolates automation from this step's values to those of the
+	// next step over the length of this step.
+	Slide bool `json:"slide,omitempty"`
+
+	// Prob is the probability the note plays each time the step is reached,
+	// or zero to always play. Automation applies either way.
+	Prob float64 `json:"prob,omitempty"`
+
+	// Ratchet retriggers the note this many times evenly within the step,
+	// each held for Gate of its length. Zero or one plays once.
+	Ratchet int `json:"ratchet,omitempty"`
+}
+
+// Pattern is a loop of steps of equal length.
+//
+// Patterns encode to JSON by encoding/json as a portable format for sharing
+// between applications.
+type Pattern struct {
+	Steps []Step  `json:"steps"`
+	Div   float64 `json:"div"` // steps per beat, e.g. 4 for sixteenth notes
+}
+
+// Section is a part of a Song playing a pattern a number of times.
+type Section struct {
+	Pattern *Pattern
+	Repeat  int // times pattern plays, or zero for once
+	BPM     BPM // tempo from the next buffer on entering the section, or zero to keep tempo
+
+	// Scene, if not nil, is called on entering the section, such as to change
+	// sounds, mutes, or parameters for the section.
+	Scene func()
+}
+
+// beats returns the length of sec in beats.
+func (sec Section) beats() float64 {
+	pat := sec.Pattern
+	if pat == nil || pat.Div <= 0 {
+		return 0
+	}
+	n := sec.Repeat
+	if n < 1 {
+		n = 1
+	}
+	return float64(n*len(pat.Steps)) / pat.Div
+}
+
+// Song is an arrangement of sections played in order.
+type Song struct {
+	Sections []Section
+	Loop     bool // restart from the first section after the last
+}
+
+// Beats returns the length of s in beats.
+func (s *Song) Beats() (n float64) {
+	for _, sec := range s.Sections {
+		n += sec.beats()
+	}
+	return n
+}
+
+// Duration returns the time s plays for when started at tempo bpm, with
+// tempo changed by sections.
+func (s *Song) Duration(bpm BPM) (d time.Duration) {
+	for _, sec := range s.Sections {
+		if sec.BPM > 0 {
+			bpm = sec.BPM
+		}
+		d += time.Duration(sec.beats() * float64(bpm.Dur()))
+	}
+	return d
+}
+
+// seqlane is an output of a Sequencer, written during the Sequencer's Prepare.
+type seqlane struct {
+	*mono
+	seq       *Sequencer
+	cur, next float64
+	slide     bool
+}
+
+func (ln *seqlane) Inputs() []Sound { return []Sound{ln.seq} }
+func (ln *seqlane) Prepare(uint64)  {}
+
+// set writes frame i at fraction t through the current step.
+func (ln *seqlane) set(i int, t float64) {
+	x := ln.cur
+	if ln.slide {
+		x += t * (ln.next - ln.cur)
+	}
+	if ln.off {
+		x = 0
+	}
+	ln.out[i] = x
+}
+
+// Sequencer steps through a Pattern in time with a Transport, outputting a
+// trigger on each step playing a note.
+//
+// Notes are also output as control signals of gate, pitch in hertz, and
+// velocity, and each automation lane of steps, such as a filter cutoff or pan,
+// is output as a control signal of its own. Signals change on the exact frame
+// of a step, so they may drive sounds sample accurately. Notes may also be
+// played on a Noter, such as a Poly, at the resolution of a buffer.
+type Sequencer struct {
+	*mono
+	tp    *Transport
+	pat   *Pattern
+	nt    Noter
+	gate  *seqlane
+	pitch *seqlane
+	vel   *seqlane
+	lanes map[string]*seqlane
+
+	rnd     *rand.Rand
+	idx     float64 // index of current step since start
+	step    int     // step of pattern playing, or -1
+	key     int     // key held on nt, or Rest
+	gatelen float64
+	ratchet int
+	sub     int // ratchet of step playing
+
+	song    *Song
+	starts  []float64 // beats of sections
+	section int       // section playing, or -1
+}
+
+// NewSequencer returns Sequencer of pat following tp.
+func NewSequencer(tp *Transport, pat *Pattern) *Sequencer {
+	sq := &Sequencer{
+		mono:  newmono(nil),
+		tp:    tp,
+		pat:   pat,
+		lanes: make(map[string]*seqlane),
+		rnd:   rand.New(rand.NewSource(1)),
+		step:  -1,
+		key:   Rest,
+	}
+	sq.gate, sq.pitch, sq.vel = sq.newlane(), sq.newlane(), sq.newlane()
+	return sq
+}
+
+func (sq *Sequencer) newlane() *seqlane {
+	return &seqlane{mono: newmono(nil), seq: sq}
+}
+
+func (sq *Sequencer) Inputs() []Sound { return []Sound{sq.tp} }
+
+// Pattern returns the pattern played.
+func (sq *Sequencer) Pattern() *Pattern { return sq.pat }
+
+// SetPattern sets the pattern played from its step at the current position.
+func (sq *Sequencer) SetPattern(pat *Pattern) { sq.pat = pat }
+
+// SetNoter plays notes of steps on nt. A nil nt stops playing notes on it.
+func (sq *Sequencer) SetNoter(nt Noter) {
+	if sq.nt != nil && sq.key != Rest {
+		sq.nt.NoteOff(sq.key)
+	}
+	sq.nt, sq.key = nt, Rest
+}
+
+// SetSong plays s from its first section, one pattern after another, in place
+// of a single pattern. Beat zero of the transport is the beginning of s.
+// Sections must not be changed while playing; call SetSong again instead.
+// A nil s returns to looping the pattern last played.
+func (sq *Sequencer) SetSong(s *Song) {
+	sq.song, sq.starts, sq.section = s, sq.starts[:0], -1
+	if s == nil {
+		return
+	}
+	var beat float64
+	for _, sec := range s.Sections {
+		sq.starts = append(sq.starts, beat)
+		beat += sec.beats()
+	}
+	if len(s.Sections) > 0 {
+		sq.pat = s.Sections[0].Pattern
+	}
+}
+
+// Section returns the index of the section of song playing, or -1.
+func (sq *Sequencer) Section() int { return sq.section }
+
+// locate returns the section of song at beat and the beat within it. If the
+// song ended without looping, ok is false.
+func (sq *Sequencer) locate(beat float64) (sec int, local float64, ok bool) {
+	if n := sq.song.Beats(); sq.song.Loop && n > 0 {
+		beat -= math.Floor(beat/n) * n
+	}
+	for i := len(sq.starts) - 1; i >= 0; i-- {
+		if beat >= sq.starts[i] {
+			if beat-sq.starts[i] >= sq.song.Sections[i].beats() {
+				return 0, 0, false
+			}
+			return i, beat - sq.starts[i], true
+		}
+	}
+	return 0, 0, false
+}
+
+// enter starts section i of song.
+func (sq *Sequencer) enter(i int) {
+	sec := sq.song.Sections[i]
+	sq.section, sq.pat = i, sec.Pattern
+	sq.idx = math.NaN()
+	if sec.BPM > 0 {
+		sq.tp.SetBPM(sec.BPM)
+	}
+	if sec.Scene != nil {
+		sec.Scene()
+	}
+}
+
+// Seed restarts the sequence of random decisions of step probabilities.
+func (sq *Sequencer) Seed(seed int64) { sq.rnd.Seed(seed) }
+
+// Step returns the index of the step of pattern playing, or -1 if stopped.
+func (sq *Sequencer) Step() int { return sq.step }
+
+// Gate returns a signal high while a note is held.
+func (sq *Sequencer) Gate() Sound { return sq.gate }
+
+// Pitch returns a signal of the frequency of the last note played.
+func (sq *Sequencer) Pitch() Sound { return sq.pitch }
+
+// Velocity returns a signal of the velocity of the last note played.
+func (sq *Sequencer) Velocity() Sound { return sq.vel }
+
+// Lane returns a signal of automation values of steps for name.
+func (sq *Sequencer) Lane(name string) Sound {
+	ln, ok := sq.lanes[name]
+	if !ok {
+		ln = sq.newlane()
+		sq.lanes[name] = ln
+	}
+	return ln
+}
+
+// begin starts step i of pattern.
+func (sq *Sequencer) begin(i int) {
+	steps := sq.pat.Steps
+	st, nx := steps[i], steps[(i+1)%len(steps)]
+	sq.step = i
+	for name, ln := range sq.lanes {
+		if x, ok := st.Auto[name]; ok {
+			ln.cur = x
+		} else if ln.slide {
+			ln.cur = ln.next
+		}
+		ln.next, ln.slide = ln.cur, false
+		if x, ok := nx.Auto[name]; ok && st.Slide {
+			ln.next, ln.slide = x, true
+		}
+	}
+	sq.gatelen, sq.sub = 0, 0
+	if st.Key == Rest || (st.Prob > 0 && sq.rnd.Float64() >= st.Prob) {
+		return
+	}
+	sq.gatelen = st.Gate
+	if sq.gatelen == 0 {
+		sq.gatelen = 0.5
+	}
+	sq.ratchet = st.Ratchet
+	if sq.ratchet < 1 {
+		sq.ratchet = 1
+	}
+	sq.pitch.cur, sq.vel.cur = keyfreq(st.Key, sq.st.concertPitch()), st.Vel
+	sq.strike()
+}
+
+// strike plays the note of the current step on nt.
+func (sq *Sequencer) strike() {
+	st := sq.pat.Steps[sq.step]
+	if sq.nt != nil {
+		if sq.key != Rest {
+			sq.nt.NoteOff(sq.key)
+		}
+		sq.nt.NoteOn(st.Key, st.Vel)
+		sq.key = st.Key
+	}
+}
+
+// release ends the note held on nt, if any.
+func (sq *Sequencer) release() {
+	if sq.nt != nil && sq.key != Rest {
+		sq.nt.NoteOff(sq.key)
+	}
+	sq.key = Rest
+}
+
+// Panic releases the note held on the Noter and closes the gate until the
+// next step.
+func (sq *Sequencer) Panic() {
+	sq.release()
+	sq.gatelen = 0
+}
+
+// cue returns the beat within the pattern to play at beat, entering sections
+// of song as reached. If there is nothing to play, ok is false.
+func (sq *Sequencer) cue(beat float64) (local float64, ok bool) {
+	if sq.song != nil {
+		const eps = 1e-9
+		sec, x, ok := sq.locate(beat + eps)
+		if !ok {
+			sq.section = -1
+			return 0, false
+		}
+		if sec != sq.section {
+			sq.enter(sec)
+		}
+		beat = x - eps
+	}
+	return beat, sq.pat != nil && len(sq.pat.Steps) > 0 && sq.pat.Div > 0
+}
+
+func (sq *Sequencer) Prepare(uint64) {
+	playing := sq.tp.Playing()
+	var beat, step float64
+	if playing {
+		step = float64(sq.tp.BPM()) / (60 * sq.sr)
+		beat = sq.tp.Beat() - float64(len(sq.out))*step
+	}
+	for i := range sq.out {
+		sq.out[i] = 0
+		t := 0.0
+		gate := false
+		local, ok := 0.0, false
+		if playing {
+			local, ok = sq.cue(beat)
+		}
+		if ok {
+			const eps = 1e-9
+			pos := local*sq.pat.Div + eps
+			idx := math.Floor(pos)
+			t = pos - idx
+			if idx != sq.idx || sq.step < 0 {
+				sq.idx = idx
+				n := len(sq.pat.Steps)
+				sq.begin((int(idx)%n + n) % n)
+				if sq.gatelen > 0 && !sq.off {
+					sq.out[i] = 1
+				}
+			}
+			if sq.gatelen > 0 {
+				// position within the current ratchet.
+				r := t * float64(sq.ratchet)
+				if sub := int(r); sub != sq.sub && sub < sq.ratchet {
+					sq.sub = sub
+					sq.strike()
+					if !sq.off {
+						sq.out[i] = 1
+					}
+				}
+				gate = r-float64(sq.sub) < sq.gatelen
+			}
+		} else {
+			sq.step = -1
+		}
+		if !gate && sq.key != Rest {
+			sq.release()
+		}
+		beat += step
+
+		sq.gate.cur = 0
+		if gate {
+			sq.gate.cur = 1
+		}
+		sq.gate.set(i, t)
+		sq.pitch.set(i, t)
+		sq.vel.set(i, t)
+		for _, ln := range sq.lanes {
+			ln.set(i, t)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package snd
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// noterlog records calls of a Noter.
+type noterlog []string
+
+func (nl *noterlog) NoteOn(key int, vel float64) { *nl = append(*nl, fmt.Sprintf("on %v", key)) }
+func (nl *noterlog) NoteOff(key int)             { *nl = append(*nl, fmt.Sprintf("off %v", key)) }
+
+func TestSequencer(t *testing.T) {
+	tp := NewTransport(150)
+	tp.Play()
+	pat := &Pattern{Div: 4, Steps: []Step{
+		{Key: 60, Vel: 1, Auto: map[string]float64{"cutoff": 100}, Slide: true},
+		{Key: Rest, Auto: map[string]float64{"cutoff": 300}},
+		{Key: 67, Vel: 0.5, Gate: 1},
+	}}
+	sq := NewSequencer(tp, pat)
+	var nl noterlog
+	sq.SetNoter(&nl)
+	cutoff := sq.Lane("cutoff")
+
+	// steps of a sixteenth at 150bpm are 100ms.
+	d := Dtof(100*time.Millisecond, DefaultSampleRate)
+	n := 4 * d
+	trig, gate, pitch, lane := make(Discrete, n), make(Discrete, n), make(Discrete, n), make(Discrete, n)
+	for i := 0; i < n; i += DefaultBufferLen {
+		tc := uint64(i/DefaultBufferLen + 1)
+		tp.Prepare(tc)
+		sq.Prepare(tc)
+		copy(trig[i:], sq.Samples())
+		copy(gate[i:], sq.Gate().Samples())
+		copy(pitch[i:], sq.Pitch().Samples())
+		copy(lane[i:], cutoff.Samples())
+	}
+
+	var trigs []int
+	for i, x := range trig {
+		if x > 0 {
+			trigs = append(trigs, i)
+		}
+	}
+	if want := []int{0, 2 * d, 3 * d}; fmt.Sprint(trigs) != fmt.Sprint(want) {
+		t.Fatalf("have triggers at %v, want %v", trigs, want)
+	}
+	if gate[d/4] != 1 || gate[3*d/4] != 0 || gate[2*d+3*d/4] != 1 {
+		t.Fatal("have gate not held for fraction of step")
+	}
+	if pitch[2*d] != KeyFreq(67) {
+		t.Fatalf("have pitch %v, want %v", pitch[2*d], KeyFreq(67))
+	}
+	if x := lane[d/2]; !equaleps(x, 200, 0.1) {
+		t.Fatalf("have cutoff %v halfway through slide, want 200", x)
+	}
+	if lane[d+d/2] != 300 || lane[2*d+1] != 300 {
+		t.Fatalf("have cutoff %v and %v, want held 300", lane[d+d/2], lane[2*d+1])
+	}
+	if want := "[on 60 off 60 on 67 off 67 on 60 off 60]"; fmt.Sprint(nl) != want {
+		t.Fatalf("have notes %v, want %v", nl, want)
+	}
+}
+
+func TestSequencerRatchetProb(t *testing.T) {
+	tp := NewTransport(150)
+	tp.Play()
+	sq := NewSequencer(tp, &Pattern{Div: 4, Steps: []Step{{Key: 60, Vel: 1, Ratchet: 3}}})
+	d := Dtof(100*time.Millisecond, DefaultSampleRate)
+	var trigs []int
+	out := Render(sq, d)
+	for i, x := range out[:d] {
+		if x > 0 {
+			trigs = append(trigs, i)
+		}
+	}
+	if want := []int{0, d / 3, 2 * d / 3}; fmt.Sprint(trigs) != fmt.Sprint(want) {
+		t.Fatalf("have ratchets at %v, want %v", trigs, want)
+	}
+
+	tp.Seek(0)
+	sq = NewSequencer(tp, &Pattern{Div: 4, Steps: []Step{{Key: 60, Vel: 1, Prob: 0.5}}})
+	n := 0
+	for _, x := range Render(sq, 200*d) {
+		if x > 0 {
+			n++
+		}
+	}
+	if n < 70 || n > 130 {
+		t.Fatalf("have %v of 200 steps played at probability 0.5, want about 100", n)
+	}
+}
+
+func TestSong(t *testing.T) {
+	entered := false
+	song := &Song{Sections: []Section{
+		{Pattern: &Pattern{Div: 1, Steps: []Step{{Key: 60, Vel: 1}}}, Repeat: 2, BPM: 120},
+		{Pattern: &Pattern{Div: 1, Steps: []Step{{Key: 72, Vel: 1}}}, BPM: 60, Scene: func() { entered = true }},
+	}}
+	if have := song.Duration(90); have != 2*time.Second {
+		t.Fatalf("have duration %v, want 2s", have)
+	}
+	tp := NewTransport(120)
+	tp.Play()
+	sq := NewSequencer(tp, nil)
+	sq.SetSong(song)
+
+	var trigs []int
+	pitch := make(Discrete, 0, 3*44100)
+	for i := 0; i < Dtof(2500*time.Millisecond, DefaultSampleRate); i += DefaultBufferLen {
+		tc := uint64(i/DefaultBufferLen + 1)
+		tp.Prepare(tc)
+		sq.Prepare(tc)
+		for j, x := range sq.Samples() {
+			if x > 0 {
+				trigs = append(trigs, i+j)
+			}
+		}
+		pitch = append(pitch, sq.Pitch().Samples()...)
+	}
+	if want := []int{0, 22050, 44100}; fmt.Sprint(trigs) != fmt.Sprint(want) {
+		t.Fatalf("have triggers at %v, want %v", trigs, want)
+	}
+	if pitch[44100] != KeyFreq(72) || !entered || tp.BPM() != 60 {
+		t.Fatalf("have pitch %v, scene %v, tempo %v in second section", pitch[44100], entered, tp.BPM())
+	}
+	if sq.Section() != -1 || sq.Step() != -1 {
+		t.Fatalf("have section %v step %v after song, want -1", sq.Section(), sq.Step())
+	}
+}
@@ -0,0 +1,58 @@
+package snd
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// settings are those of a Dispatcher read by sounds it prepares, so each
+// engine of a process plays as set of its own. Nil or unset are defaults.
+type settings struct {
+	// atomic, 64 bit first for alignment.
+	voices    uint64 // bits of Quality.Voices
+	pitch     uint64 // bits of concert pitch, zero for 440Hz
+	qset      int32  // one once a Quality is stored
+	antialias int32
+	interp    int32
+	combs     int32 // of freeverb
+	exact     int32
+
+	q atomic.Value // Quality
+}
+
+// settler is a sound playing as set of the Dispatcher preparing it.
+type settler interface {
+	setSettings(st *settings)
+}
+
+// settings returns the settings of sounds prepared by dp, those of the
+// Dispatcher preparing dp if nested, such as of a Hotswap.
+func (dp *Dispatcher) settings() *settings {
+	if dp.up != nil {
+		return dp.up
+	}
+	return &dp.st
+}
+
+// settle has inps play as set of dp.
+func (dp *Dispatcher) settle(inps []*Input) {
+	st := dp.settings()
+	for _, inp := range inps {
+		if s, ok := inp.sd.(settler); ok {
+			s.setSettings(st)
+		}
+	}
+}
+
+// concertPitch returns the frequency of A4, 440Hz unless set.
+func (st *settings) concertPitch() float64 {
+	if st == nil {
+		return 440
+	}
+	if x := atomic.LoadUint64(&st.pitch); x != 0 {
+		return math.Float64frombits(x)
+	}
+	return 440
+}
+
+func (st *settings) isexact() bool { return st != nil && atomic.LoadInt32(&st.exact) == 1 }
@@ -179,11 +179,52 @@ func (sig Discrete) AdditiveSynthesis(fd Discrete, pth int) {
 	}
 }
 
-// SineFunc is the continuous signal of a sine wave.
-func SineFunc(t float64) float64 {
-	return math.Sin(twopi * t)
+// Resample returns interleaved sig of chans channels converted from sample rate
+// from to sample rate to by windowed sinc interpolation.
+func Resample(sig Discrete, chans int, from, to float64) Discrete {
+	ratio := to / from
+	nin := len(sig) / chans
+	out := make(Discrete, int(float64(nin)*ratio)*chans)
+	if ratio == 1 {
+		copy(out, sig)
+		return out
+	}
+
+	const zeros = 16 // zero crossings of kernel each side
+	cutoff := math.Min(1, ratio)
+	half := zeros / cutoff
+	for j := 0; j < len(out)/chans; j++ {
+		t := float64(j) / ratio
+		lo, hi := int(math.Ceil(t-half)), int(math.Floor(t+half))
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= nin {
+			hi = nin - 1
+		}
+		for i := lo; i <= hi; i++ {
+			d := t - float64(i)
+			u := (d + half) / (2 * half)
+			w := cutoff * sinc(cutoff*d) * (0.42 - 0.5*math.Cos(twopi*u) + 0.08*math.Cos(2*twopi*u))
+			for c := 0; c < chans; c++ {
+				out[j*chans+c] += w * sig[i*chans+c]
+			}
+		}
+	}
+	return out
 }
 
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	x *= math.Pi
+	return math.Sin(x) / x
+}
+
+// SineFunc is the continuous signal of a sine wave.
+func SineFunc(t float64) float64 { return math.Sin(twopi * t) }
+
 // Sine returns a discrete sample of SineFunc.
 func Sine() Discrete {
 	sig := make(Discrete, 1024)
@@ -191,6 +232,18 @@ func Sine() Discrete {
 	return sig
 }
 
+// ExactSineFunc is SineFunc computed without fused multiply-add or assembly
+// of the math package, the same on every platform.
+func ExactSineFunc(t float64) float64 { return sinexact(twopi * t) }
+
+// ExactSine returns a discrete sample of ExactSineFunc, for a Dispatcher
+// rendering Exact.
+func ExactSine() Discrete {
+	sig := make(Discrete, 1024)
+	sig.Sample(ExactSineFunc, 1./1024, 0)
+	return sig
+}
+
 // TriangleFunc is the continuous signal of a triangle wave.
 func TriangleFunc(t float64) float64 {
 	// return 2*math.Abs(SawtoothFunc(t)) - 1
@@ -0,0 +1,176 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// levels returns the level in dB of each block of hop frames of interleaved
+// samples sig with chans channels.
+func levels(sig Discrete, chans, hop int) []float64 {
+	nfr := len(sig) / chans
+	lvls := make([]float64, (nfr+hop-1)/hop)
+	for b := range lvls {
+		var sum float64
+		n := 0
+		for f := b * hop; f < (b+1)*hop && f < nfr; f++ {
+			for c := 0; c < chans; c++ {
+				x := sig[f*chans+c]
+				sum += x * x
+				n++
+			}
+		}
+		lvls[b] = 10 * math.Log10(sum/float64(n)+1e-12)
+	}
+	return lvls
+}
+
+// onsetHop is the number of frames of each block analyzed for onsets.
+const onsetHop = 256
+
+// Onsets returns frames of interleaved samples sig with chans channels where
+// notes or hits begin, found by rises in short-term energy. A rise must reach
+// sens decibels over the previous block, e.g. 6, and onsets are at least
+// 50ms apart. The first frame is always an onset.
+func Onsets(sig Discrete, chans int, sr float64, sens float64) []int {
+	gap := Dtof(50*time.Millisecond, sr) / onsetHop
+	onsets := []int{0}
+	prev := -gap
+	lvls := levels(sig, chans, onsetHop)
+	for b := 1; b < len(lvls); b++ {
+		// ignore rises out of near silence caused by noise.
+		if lvls[b]-lvls[b-1] >= sens && lvls[b] > -60 && b-prev >= gap {
+			onsets = append(onsets, b*onsetHop)
+			prev = b
+		}
+	}
+	return onsets
+}
+
+// DetectBPM estimates tempo of a loop of interleaved samples sig with chans
+// channels, between 70 and 180 beats per minute, from periodicity of its
+// onsets. As a loop is expected to hold a whole number of beats, an estimate
+// near one is adjusted to fit the loop exactly. Zero is returned if no
+// periodicity is found.
+func DetectBPM(sig Discrete, chans int, sr float64) BPM {
+	lvls := levels(sig, chans, onsetHop)
+	nov := make([]float64, len(lvls))
+	for b := 1; b < len(lvls); b++ {
+		if d := lvls[b] - lvls[b-1]; d > 0 && lvls[b] > -60 {
+			nov[b] = d
+		}
+	}
+	bps := sr / onsetHop // blocks per second
+	lo, hi := int(bps*60/180), int(math.Ceil(bps*60/70))
+	ac := func(lag int) float64 {
+		var sum float64
+		for b := lag; b < len(nov); b++ {
+			sum += nov[b] * nov[b-lag]
+		}
+		return sum / float64(len(nov)-lag)
+	}
+	best, score := 0, 0.0
+	for lag := lo; lag <= hi && lag < len(nov)-1; lag++ {
+		if s := ac(lag); s > score {
+			best, score = lag, s
+		}
+	}
+	if best == 0 {
+		return 0
+	}
+	// parabolic interpolation of the peak.
+	period := float64(best)
+	if a, b, c := ac(best-1), score, ac(best+1); a-2*b+c != 0 {
+		period += (a - c) / (2 * (a - 2*b + c))
+	}
+	bpm := 60 * bps / period
+
+	nfr := float64(len(sig) / chans)
+	beats := nfr / sr * bpm / 60
+	if n := math.Round(beats); n > 0 && math.Abs(beats-n)/n < 0.05 {
+		bpm = 60 * n * sr / nfr
+	}
+	return BPM(bpm)
+}
+
+// GridSlices returns frames dividing nfr frames into n equal slices.
+func GridSlices(nfr, n int) []int {
+	starts := make([]int, n)
+	for i := range starts {
+		starts[i] = i * nfr / n
+	}
+	return starts
+}
+
+// Slicer plays slices of a sample, such as a drum break, mapping each slice to
+// consecutive keys from a base key so slices may be played and resequenced
+// from a keyboard, pads, or a sequencer.
+//
+// Slices are played one at a time; a new note cuts off the slice playing.
+type Slicer struct {
+	*mono
+	pl     *Player
+	starts []int
+	base   int
+	gate   bool
+	key    int // key playing or -1
+	amp    float64
+}
+
+// NewSlicer returns Slicer of pl with slices beginning at frames starts,
+// sorted ascending, where the first slice is played by key base.
+func NewSlicer(pl *Player, starts []int, base int) *Slicer {
+	sd := newmono(nil)
+	sd.out = make(Discrete, len(pl.Samples()))
+	sl := &Slicer{mono: sd, pl: pl, starts: starts, base: base, key: -1}
+	pl.Seek(pl.Len())
+	return sl
+}
+
+func (sl *Slicer) Channels() int   { return sl.pl.Channels() }
+func (sl *Slicer) Inputs() []Sound { return []Sound{sl.pl} }
+
+// Len returns the number of slices.
+func (sl *Slicer) Len() int { return len(sl.starts) }
+
+// Slice returns region of slice i in frames.
+func (sl *Slicer) Slice(i int) (start, end int) {
+	start, end = sl.starts[i], sl.pl.Len()
+	if i+1 < len(sl.starts) {
+		end = sl.starts[i+1]
+	}
+	return start, end
+}
+
+// SetGate sets whether NoteOff stops the slice playing; otherwise slices play
+// to their end.
+func (sl *Slicer) SetGate(b bool) { sl.gate = b }
+
+// NoteOn plays the slice mapped to key at velocity vel in [0..1]. Keys
+// outside of slices are ignored.
+func (sl *Slicer) NoteOn(key int, vel float64) {
+	i := key - sl.base
+	if i < 0 || i >= len(sl.starts) {
+		return
+	}
+	sl.pl.SetRegion(sl.Slice(i))
+	sl.key, sl.amp = key, vel
+}
+
+// NoteOff stops key if playing and gated.
+func (sl *Slicer) NoteOff(key int) {
+	if sl.gate && key == sl.key {
+		sl.pl.Seek(sl.pl.Len())
+		sl.key = -1
+	}
+}
+
+func (sl *Slicer) Prepare(uint64) {
+	for i, x := range sl.pl.Samples() {
+		if sl.off {
+			sl.out[i] = 0
+		} else {
+			sl.out[i] = sl.amp * x
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package snd
+
+import (
+	"math"
+	"testing"
+)
+
+// hits returns mono samples of decaying tones beginning at frames.
+func hits(nfr int, frames ...int) Discrete {
+	sig := make(Discrete, nfr)
+	for _, f := range frames {
+		for i := 0; f+i < nfr && i < 4000; i++ {
+			sig[f+i] += 0.5 * math.Exp(-float64(i)/800) * math.Sin(float64(i)*0.3)
+		}
+	}
+	return sig
+}
+
+func TestOnsets(t *testing.T) {
+	want := []int{0, 11025, 22050, 33075}
+	onsets := Onsets(hits(44100, want...), 1, 44100, 6)
+	if len(onsets) != len(want) {
+		t.Fatalf("have onsets %v, want near %v", onsets, want)
+	}
+	for i, f := range onsets {
+		if d := f - want[i]; d < -256 || d > 256 {
+			t.Errorf("have onset %v, want near %v", f, want[i])
+		}
+	}
+}
+
+func TestSlicer(t *testing.T) {
+	sig := make(Discrete, 8*DefaultBufferLen)
+	for i := range sig {
+		sig[i] = float64(i)
+	}
+	starts := GridSlices(len(sig), 8)
+	sl := NewSlicer(NewPlayer(sig, 1, DefaultSampleRate), starts, 36)
+	sl.SetGate(true)
+
+	prepare := func() Discrete {
+		sl.pl.Prepare(1)
+		sl.Prepare(1)
+		return sl.Samples()
+	}
+	if x := Peak(prepare()); x != 0 {
+		t.Fatalf("have %v before any note, want silence", x)
+	}
+	sl.NoteOn(38, 0.5)
+	out := prepare()
+	if have, want := out[1], 0.5*sig[starts[2]+1]; have != want {
+		t.Fatalf("have %v, want %v", have, want)
+	}
+	sl.NoteOn(36, 1)
+	sl.NoteOff(36)
+	if x := Peak(prepare()); x != 0 {
+		t.Fatalf("have %v after note off, want silence", x)
+	}
+}
+
+func TestDetectBPM(t *testing.T) {
+	for _, bpm := range []BPM{90, 120, 140} {
+		beat := Dtof(bpm.Dur(), 44100)
+		var frames []int
+		for i := 0; i < 8; i++ {
+			frames = append(frames, i*beat)
+		}
+		sig := hits(8*beat, frames...)
+		if have := DetectBPM(sig, 1, 44100); math.Abs(float64(have-bpm)) > 0.5 {
+			t.Errorf("have %v, want %v", have, bpm)
+		}
+	}
+	pl := NewPlayer(nil, 1, DefaultSampleRate)
+	pl.Conform(100, 120)
+	if pl.Speed() != 1.2 {
+		t.Errorf("have speed %v, want 1.2", pl.Speed())
+	}
+}
@@ -4,16 +4,16 @@
 // dispatching of sound synthesis via golang.org/x/mobile/audio/al. Start
 // the dispatcher as follows:
 //
-//  const buffers = 1
-//  if err := al.OpenDevice(buffers); err != nil {
-//      log.Fatal(err)
-//  }
-//  al.Start()
+//	const buffers = 1
+//	if err := al.OpenDevice(buffers); err != nil {
+//	    log.Fatal(err)
+//	}
+//	al.Start()
 //
 // Once running, add a source for sound synthesis. For example:
 //
-//  osc := snd.NewOscil(snd.Sine(), 440, nil)
-//  al.AddSource(osc)
+//	osc := snd.NewOscil(snd.Sine(), 440, nil)
+//	al.AddSource(osc)
 //
 // This results in a 440Hz tone being played back through the audio hardware.
 //
@@ -21,14 +21,14 @@
 // methods accept a Sound argument that can affect sampling. For example, one
 // may modulate an oscillator by passing in a third argument to NewOscil.
 //
-//  sine := snd.Sine()
-//  mod := snd.NewOscil(sine, 2, nil)
-//  osc := snd.NewOscil(sine, 440, mod)
+//	sine := snd.Sine()
+//	mod := snd.NewOscil(sine, 2, nil)
+//	osc := snd.NewOscil(sine, 440, mod)
 //
 // The above results in a lower frequency sound that may require decent speakers
 // to hear properly.
 //
-// Signals
+// # Signals
 //
 // Note the sine argument in the previous example. There are two conceptual types
 // of sounds, ContinuousFunc and Discrete. ContinuousFunc represents an indefinite
@@ -45,7 +45,7 @@
 // such as SquareSynthesis(int) to return an approximation of a square signal
 // based on a sinusoidal.
 //
-// Time Approximation
+// # Time Approximation
 //
 // Functions that take a time.Duration argument approximate the value to the
 // closest number of frames. For example, if sample rate is 44.1kHz and duration
@@ -79,18 +79,53 @@ const (
 	DefaultSampleRate     float64 = 44100
 	DefaultSampleBitDepth         = 16 // TODO not currently used for anything
 	DefaultBufferLen              = 256
-	DefaultAmpFac         float64 = 0.31622776601683794 // -10dB
+	DefaultAmpFac         float64 = 0.31622776601683794 // -10dB; see VoiceGain for gain by polyphony
+
+	// DefaultHeadroom is the margin below full scale VoiceGain leaves for peaks.
+	DefaultHeadroom Decibel = -3
 )
 
 // Decibel is relative to full scale; anything over 0dB will clip.
+// Silence is negative infinity, as returned by DecibelOf(0).
 type Decibel float64
 
+// DecibelOf converts amplitude multiplier to dB, or negative infinity for zero.
+func DecibelOf(amp float64) Decibel {
+	return Decibel(20 * math.Log10(math.Abs(amp)))
+}
+
 // Amp converts dB to amplitude multiplier.
 func (db Decibel) Amp() float64 {
 	return math.Pow(10, float64(db)/20)
 }
 
+// IsInf reports whether db is negative infinity, silence.
+func (db Decibel) IsInf() bool { return math.IsInf(float64(db), -1) }
+
+// SumPower returns the level of uncorrelated signals mixed together, such as
+// voices of noise, e.g. two at -6dB sum to about -3dB.
+func SumPower(dbs ...Decibel) Decibel {
+	var p float64
+	for _, db := range dbs {
+		p += math.Pow(10, float64(db)/10)
+	}
+	return Decibel(10 * math.Log10(p))
+}
+
+// SumAmp returns the peak level of correlated signals mixed together in
+// phase, e.g. two at -6dB sum to about 0dB.
+func SumAmp(dbs ...Decibel) Decibel {
+	var a float64
+	for _, db := range dbs {
+		a += db.Amp()
+	}
+	return DecibelOf(a)
+}
+
 func (db Decibel) String() string {
+	if db.IsInf() {
+		return "-infdB"
+	}
 	return fmt.Sprintf("%vdB", float64(db))
 }
 
@@ -168,17 +203,20 @@ type Sound interface {
 // TODO rename as buffer?
 // TODO see work on System type
 type mono struct {
-	sr  float64
-	in  Sound
-	out Discrete
-	off bool
+	sr    float64
+	in    Sound
+	out   Discrete
+	off   bool
+	sched *schedule
+	st    *settings // of the Dispatcher preparing it
 }
 
 func newmono(in Sound) *mono {
 	return &mono{
-		sr:  DefaultSampleRate,
-		in:  in,
-		out: make(Discrete, DefaultBufferLen),
+		sr:    DefaultSampleRate,
+		in:    in,
+		out:   make(Discrete, DefaultBufferLen),
+		sched: newschedule(),
 	}
 }
 
@@ -193,6 +231,12 @@ func (sd *mono) Off()                     { sd.off = true }
 func (sd *mono) On()                      { sd.off = false }
 func (sd *mono) Inputs() []Sound          { return []Sound{sd.in} }
 
+func (sd *mono) setSettings(st *settings) {
+	if sd.st != st { // written once, read by NoteOn off the audio thread
+		sd.st = st
+	}
+}
+
 type stereo struct {
 	l, r *mono
 	in   Sound
@@ -154,3 +154,18 @@ func TestDecibel(t *testing.T) {
 		}
 	}
 }
+
+func TestDecibelArith(t *testing.T) {
+	if db := DecibelOf(0); !db.IsInf() || db.String() != "-infdB" || db.Amp() != 0 {
+		t.Fatalf("have %v for silence", db)
+	}
+	if db := DecibelOf(Decibel(-6).Amp()); !equaleps(float64(db), -6, 1e-9) {
+		t.Fatalf("have %v, want -6dB", db)
+	}
+	if db := SumPower(-6, -6); !equaleps(float64(db), -2.99, 0.01) {
+		t.Fatalf("power sum have %v, want -3dB", db)
+	}
+	if db := SumAmp(-6, -6); !equaleps(float64(db), 0.02, 0.01) {
+		t.Fatalf("amplitude sum have %v, want 0dB", db)
+	}
+}
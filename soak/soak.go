@@ -0,0 +1,393 @@
+// Package soak plays a graph for hours without a device, driving random
+// notes and changes of params through it, to find what breaks only in the
+// long run: buffers late for their deadline, allocations on the audio
+// thread, NaNs, and drift of timing and level. It validates realtime work on
+// the package, such as of its TODOs, on any platform, and is run by the
+// command sndsoak.
+package soak // import "dasa.cc/snd/soak"
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime"
+	"strings"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+// Config configures a run.
+type Config struct {
+	// Duration is of audio played; zero plays until the context is done.
+	Duration time.Duration
+
+	// Realtime paces buffers as a device would, each due Ahead after it is
+	// started, counting underruns of those late; otherwise buffers are
+	// prepared as fast as they can be.
+	Realtime bool
+	Ahead    time.Duration
+
+	// Seed seeds notes and changes of params, so a run without Realtime is
+	// the same every time.
+	Seed int64
+
+	// Notes is the number of notes started per second, over all noters, each
+	// held between 50ms and 2s. Changes is the number of times per second
+	// params are moved, each by Churn of their range.
+	Notes   float64
+	Changes float64
+	Churn   float64
+
+	// Report is the interval of audio between reports.
+	Report time.Duration
+
+	// Allocs is the number of buffers between those whose allocations are
+	// counted, reading memory stats stopping the world.
+	Allocs int
+}
+
+// DefaultConfig plays for an hour as fast as possible.
+var DefaultConfig = Config{
+	Duration: time.Hour,
+	Ahead:    20 * time.Millisecond,
+	Seed:     1,
+	Notes:    20,
+	Changes:  10,
+	Churn:    0.1,
+	Report:   time.Minute,
+	Allocs:   64,
+}
+
+// Report is a report of an interval of a run, or of the run so far.
+type Report struct {
+	Played  time.Duration // audio of the interval
+	Buffers uint64
+
+	// Underruns counts buffers prepared after they were due, of Realtime,
+	// and Overruns buffers taking longer than their duration to prepare.
+	Underruns uint64
+	Overruns  uint64
+	TickMax   time.Duration
+
+	// Allocs is the mean number of allocations preparing a buffer, of
+	// buffers counted.
+	Allocs float64
+
+	// NaNs counts buffers of output not finite, each silenced by a panic of
+	// the dispatcher; NaNFrom is the type of the node found first not
+	// finite, such as "*snd.Reverb".
+	NaNs    uint64
+	NaNFrom string
+
+	// Lag is how long after it was due the last buffer was prepared, of
+	// Realtime, negative if before, growing if buffers are prepared slower
+	// than they play.
+	Lag time.Duration
+
+	// Level is RMS of output, Peak its peak, and DC its mean, drifting if
+	// state of the graph runs away or decays over time.
+	Level, Peak snd.Decibel
+	DC          float64
+
+	Notes, Changes uint64
+	GCs            int64
+}
+
+func (r Report) String() string {
+	s := fmt.Sprintf("played=%v buffers=%v underruns=%v overruns=%v tickmax=%v allocs=%.2f nans=%v level=%.1fdB peak=%.1fdB dc=%.2g notes=%v changes=%v gcs=%v",
+		r.Played, r.Buffers, r.Underruns, r.Overruns, r.TickMax, r.Allocs, r.NaNs, float64(r.Level), float64(r.Peak), r.DC, r.Notes, r.Changes, r.GCs)
+	if r.NaNFrom != "" {
+		s += " nanfrom=" + r.NaNFrom
+	}
+	if r.Lag != 0 {
+		s += fmt.Sprintf(" lag=%v", r.Lag)
+	}
+	return s
+}
+
+// Failed reports whether r has underruns or NaNs.
+func (r Report) Failed() bool { return r.Underruns != 0 || r.NaNs != 0 }
+
+// Graph returns a large graph of instruments of voices each, played by
+// noters returned, through filters, modulation, delays, and reverb with
+// params of them all.
+func Graph(voices int) (out snd.Sound, ps *snd.Params, nts []snd.Noter) {
+	ps = new(snd.Params)
+	syn := snd.NewSynth(voices)
+	fm := snd.NewFM(voices, snd.FMPatchEPiano)
+	ks := snd.NewKarplus(voices, 2*time.Second)
+	ml := snd.NewMallet(voices, snd.MaterialVibraphone, time.Second)
+	org := snd.NewOrgan(voices)
+
+	svf := snd.NewSVF(snd.FilterLowPass, 2000, 0.707, syn)
+	trm := snd.NewTremolo(5, 0.3, fm)
+	cmb := snd.NewComb(0.5, 30*time.Millisecond, ks)
+	tape := snd.NewTape(0.5, ml)
+	rv := snd.NewReverb(0.6, 0.4, snd.NewMixer(svf, trm, cmb, tape))
+	pp := snd.NewPingPong(250*time.Millisecond, 0.4, rv)
+
+	ps.Register("synth", syn)
+	ps.Register("karplus", ks)
+	ps.Register("mallet", ml)
+	ps.Register("svf", svf)
+	ps.Register("tremolo", trm)
+	ps.Register("comb", cmb)
+	ps.Register("tape", tape)
+	ps.Register("reverb", rv)
+	ps.Register("pingpong", pp)
+	return snd.NewMaster(snd.NewMixer(pp, org)), ps, []snd.Noter{syn, fm, ks, ml, org}
+}
+
+// note is a note held.
+type note struct {
+	nt   snd.Noter
+	key  int
+	left int // frames
+}
+
+// Harness plays a graph of a run.
+type Harness struct {
+	cfg Config
+	out snd.Sound
+	nts []snd.Noter
+	rz  *snd.Randomizer
+	rnd *rand.Rand
+
+	dp   snd.Dispatcher
+	inps []*snd.Input
+	held []note
+}
+
+// New returns Harness of cfg playing out, moving params of ps and playing
+// notes on nts, such as of Graph.
+func New(cfg Config, out snd.Sound, ps *snd.Params, nts ...snd.Noter) *Harness {
+	h := &Harness{cfg: cfg, out: out, nts: nts, rnd: rand.New(rand.NewSource(cfg.Seed)), inps: snd.GetInputs(out)}
+	if ps != nil {
+		h.rz = snd.NewRandomizer(ps, cfg.Seed)
+	}
+	return h
+}
+
+// Dispatcher returns the Dispatcher preparing the graph, such as to add hooks.
+func (h *Harness) Dispatcher() *snd.Dispatcher { return &h.dp }
+
+// events plays notes and moves params due over a buffer of n frames, between
+// buffers as a UI or MIDI input would.
+func (h *Harness) events(n int, r *Report) {
+	sr := h.out.SampleRate()
+	kept := h.held[:0]
+	for _, nt := range h.held {
+		if nt.left -= n; nt.left <= 0 {
+			nt.nt.NoteOff(nt.key)
+			continue
+		}
+		kept = append(kept, nt)
+	}
+	h.held = kept
+
+	dt := float64(n) / sr
+	if len(h.nts) != 0 {
+		for k := h.poisson(h.cfg.Notes * dt); k > 0; k-- {
+			nt := note{nt: h.nts[h.rnd.Intn(len(h.nts))], key: 36 + h.rnd.Intn(48)}
+			nt.left = snd.Dtof(50*time.Millisecond+time.Duration(h.rnd.Int63n(int64(1950*time.Millisecond))), sr)
+			nt.nt.NoteOn(nt.key, 0.2+0.8*h.rnd.Float64())
+			h.held = append(h.held, nt)
+			r.Notes++
+		}
+	}
+	if h.rz != nil {
+		for k := h.poisson(h.cfg.Changes * dt); k > 0; k-- {
+			h.rz.Mutate(h.cfg.Churn)
+			r.Changes++
+		}
+	}
+}
+
+// poisson returns a count of events of a Poisson process of mean m.
+func (h *Harness) poisson(m float64) int {
+	l, k, p := math.Exp(-m), 0, h.rnd.Float64()
+	for p > l {
+		k++
+		p *= h.rnd.Float64()
+	}
+	return k
+}
+
+// nanFrom returns the type of the first node found from out whose samples
+// are not finite.
+func nanFrom(out snd.Sound) string {
+	seen := make(map[snd.Sound]bool)
+	var find func(sd snd.Sound) string
+	find = func(sd snd.Sound) string {
+		if sd == nil || seen[sd] {
+			return ""
+		}
+		seen[sd] = true
+		// inputs first, so the node found is where NaNs start.
+		for _, in := range sd.Inputs() {
+			if s := find(in); s != "" {
+				return s
+			}
+		}
+		if !finite(sd.Samples()) {
+			return fmt.Sprintf("%T", sd)
+		}
+		return ""
+	}
+	return find(out)
+}
+
+func finite(xs snd.Discrete) bool {
+	for _, x := range xs {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// Run plays until the duration of cfg is played or ctx is done, calling fn,
+// if not nil, with a report of each interval, and returns a report of the
+// whole run.
+func (h *Harness) Run(ctx context.Context, fn func(Report)) Report {
+	sr := h.out.SampleRate()
+	chans := h.out.Channels()
+	frames := len(h.out.Samples()) / chans
+	bufdur := snd.Ftod(frames, sr)
+	total := snd.Dtof(h.cfg.Duration, sr)
+	every := snd.Dtof(h.cfg.Report, sr)
+
+	all := Report{Peak: snd.DecibelOf(0)}
+	var cur Report
+	var allocs, counted, aallocs, acounted uint64
+	var sum, sumsq, peak float64
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	gcs := int64(ms.NumGC)
+
+	flush := func(played int) {
+		n := float64(played * chans)
+		cur.Played = snd.Ftod(played, sr)
+		cur.Level, cur.Peak, cur.DC = snd.DecibelOf(math.Sqrt(sumsq/n)), snd.DecibelOf(peak), sum/n
+		if counted != 0 {
+			cur.Allocs = float64(allocs) / float64(counted)
+		}
+		runtime.ReadMemStats(&ms)
+		cur.GCs, gcs = int64(ms.NumGC)-gcs, int64(ms.NumGC)
+
+		all.Played += cur.Played
+		all.Buffers += cur.Buffers
+		all.Underruns += cur.Underruns
+		all.Overruns += cur.Overruns
+		if cur.TickMax > all.TickMax {
+			all.TickMax = cur.TickMax
+		}
+		all.NaNs += cur.NaNs
+		if all.NaNFrom == "" {
+			all.NaNFrom = cur.NaNFrom
+		}
+		all.Lag = cur.Lag
+		all.Peak = snd.Decibel(math.Max(float64(all.Peak), float64(cur.Peak)))
+		all.Level, all.DC = cur.Level, cur.DC
+		all.Notes += cur.Notes
+		all.Changes += cur.Changes
+		all.GCs += cur.GCs
+		aallocs, acounted = aallocs+allocs, acounted+counted
+		if acounted != 0 {
+			all.Allocs = float64(aallocs) / float64(acounted)
+		}
+		if fn != nil {
+			fn(cur)
+		}
+		cur, allocs, counted, sum, sumsq, peak = Report{}, 0, 0, 0, 0, 0
+	}
+
+	start := time.Now()
+	played, since := 0, 0
+	for tc := uint64(1); total == 0 || played < total; tc++ {
+		if ctx.Err() != nil {
+			break
+		}
+		h.events(frames, &cur)
+		// started once the device has room for it, due Ahead after.
+		due := start.Add(time.Duration(tc-1)*bufdur + h.cfg.Ahead)
+		if h.cfg.Realtime {
+			if d := time.Until(due.Add(-h.cfg.Ahead)); d > 0 {
+				time.Sleep(d)
+			}
+		}
+
+		count := h.cfg.Allocs > 0 && tc%uint64(h.cfg.Allocs) == 0
+		var before uint64
+		if count {
+			runtime.ReadMemStats(&ms)
+			before = ms.Mallocs
+		}
+		t := time.Now()
+		h.dp.Dispatch(tc, h.inps...)
+		tick := time.Since(t)
+		if count {
+			runtime.ReadMemStats(&ms)
+			allocs += ms.Mallocs - before
+			counted++
+		}
+
+		cur.Buffers++
+		if tick > cur.TickMax {
+			cur.TickMax = tick
+		}
+		if tick > bufdur {
+			cur.Overruns++
+		}
+		if h.cfg.Realtime {
+			if cur.Lag = time.Since(due); cur.Lag > 0 {
+				cur.Underruns++
+			}
+		}
+
+		xs := h.out.Samples()
+		if !finite(xs) {
+			cur.NaNs++
+			if cur.NaNFrom == "" {
+				cur.NaNFrom = nanFrom(h.out)
+			}
+			h.dp.Panic()
+		} else {
+			for _, x := range xs {
+				sum += x
+				sumsq += x * x
+				peak = math.Max(peak, math.Abs(x))
+			}
+		}
+
+		played += frames
+		if since += frames; every > 0 && since >= every {
+			flush(since)
+			since = 0
+		}
+	}
+	if since > 0 {
+		flush(since)
+	}
+	return all
+}
+
+// Summary returns a line of each noteworthy finding of r, none if r is clean.
+func Summary(r Report) string {
+	var lines []string
+	if r.Underruns != 0 {
+		lines = append(lines, fmt.Sprintf("%v buffers late of %v", r.Underruns, r.Buffers))
+	}
+	if r.NaNs != 0 {
+		lines = append(lines, fmt.Sprintf("%v buffers not finite, first of %s", r.NaNs, r.NaNFrom))
+	}
+	if r.Allocs > 0 {
+		lines = append(lines, fmt.Sprintf("%.2f allocations a buffer", r.Allocs))
+	}
+	if r.Lag > 0 {
+		lines = append(lines, fmt.Sprintf("last buffer %v late", r.Lag))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,65 @@
+package soak
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"dasa.cc/snd"
+)
+
+func TestRun(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Duration = 2 * time.Second
+	cfg.Report = 500 * time.Millisecond
+	cfg.Notes, cfg.Changes = 50, 50
+	out, ps, nts := Graph(4)
+	h := New(cfg, out, ps, nts...)
+
+	var reports []Report
+	r := h.Run(context.Background(), func(r Report) { reports = append(reports, r) })
+	if len(reports) != 4 {
+		t.Errorf("have %v reports, want 4", len(reports))
+	}
+	if r.Played < cfg.Duration || r.Played > cfg.Duration+50*time.Millisecond {
+		t.Errorf("have played %v, want %v", r.Played, cfg.Duration)
+	}
+	if r.NaNs != 0 || r.Underruns != 0 || r.Notes == 0 || r.Changes == 0 {
+		t.Errorf("have %v", r)
+	}
+	if r.Peak <= -60 || math.IsNaN(float64(r.Level)) {
+		t.Errorf("have level %v peak %v, want playing", r.Level, r.Peak)
+	}
+
+	// the same seed plays the same.
+	out2, ps2, nts2 := Graph(4)
+	r2 := New(cfg, out2, ps2, nts2...).Run(context.Background(), nil)
+	if r2.Notes != r.Notes || r2.Changes != r.Changes || r2.Level != r.Level {
+		t.Errorf("have %v, want %v", r2, r)
+	}
+}
+
+func TestNaN(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Duration = 100 * time.Millisecond
+	out := snd.NewGain(0.5, snd.NewConst(math.NaN()))
+	r := New(cfg, out, nil).Run(context.Background(), nil)
+	if r.NaNs == 0 || r.NaNFrom != "*snd.Const" || !r.Failed() {
+		t.Errorf("have %v, want NaNs of *snd.Const", r)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Duration = 0
+	cfg.Realtime = true
+	out, ps, nts := Graph(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r := New(cfg, out, ps, nts...).Run(ctx, nil)
+	// paced, so about as much audio as time, and Ahead of it.
+	if r.Played < 50*time.Millisecond || r.Played > 200*time.Millisecond {
+		t.Errorf("have played %v in 100ms", r.Played)
+	}
+}
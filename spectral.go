@@ -0,0 +1,318 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+)
+
+// fft computes the discrete fourier transform of re and im in place, or the
+// unnormalized inverse if inv. The length of re and im must be a power of two.
+func fft(re, im []float64, inv bool) {
+	n := len(re)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			re[i], re[j] = re[j], re[i]
+			im[i], im[j] = im[j], im[i]
+		}
+	}
+	sign := -1.0
+	if inv {
+		sign = 1
+	}
+	for size := 2; size <= n; size <<= 1 {
+		w := sign * 2 * math.Pi / float64(size)
+		wr, wi := math.Cos(w), math.Sin(w)
+		for start := 0; start < n; start += size {
+			cr, ci := 1.0, 0.0
+			for k := 0; k < size/2; k++ {
+				a, b := start+k, start+k+size/2
+				tr := re[b]*cr - im[b]*ci
+				ti := re[b]*ci + im[b]*cr
+				re[b], im[b] = re[a]-tr, im[a]-ti
+				re[a], im[a] = re[a]+tr, im[a]+ti
+				cr, ci = cr*wr-ci*wi, cr*wi+ci*wr
+			}
+		}
+	}
+}
+
+// Spectrum is a frame of short-time fourier analysis of a Spectral, bins
+// evenly spaced from zero to nyquist. A SpectralFunc modifies it in place
+// before it is resynthesized.
+type Spectrum struct {
+	SampleRate float64
+	Size, Hop  int // frames analyzed and between analyses
+
+	// Mag and Phase, in radians, of each bin resynthesized.
+	Mag, Phase []float64
+
+	// Freq is the frequency in hertz of the partial in each bin, measured
+	// from the advance of its phase since the last frame, finer than the
+	// spacing of bins. It is only read by Advance.
+	Freq []float64
+
+	prev []float64 // phase of each bin last resynthesized
+}
+
+// Hz returns the center frequency of bin k.
+func (sp *Spectrum) Hz(k int) float64 { return float64(k) * sp.SampleRate / float64(sp.Size) }
+
+// Bin returns the bin nearest hz.
+func (sp *Spectrum) Bin(hz float64) int {
+	k := int(math.Round(hz * float64(sp.Size) / sp.SampleRate))
+	if k < 0 {
+		return 0
+	} else if k >= len(sp.Mag) {
+		return len(sp.Mag) - 1
+	}
+	return k
+}
+
+// Advance sets the phase of each bin to that last resynthesized advanced by
+// its Freq over a hop, as a phase vocoder does so partials stay continuous
+// when their frequency is changed, such as by pitch shifting, or when a
+// spectrum is held.
+func (sp *Spectrum) Advance() {
+	step := 2 * math.Pi * float64(sp.Hop) / sp.SampleRate
+	for k, hz := range sp.Freq {
+		sp.Phase[k] = math.Remainder(sp.prev[k]+step*hz, 2*math.Pi)
+	}
+}
+
+// SpectralFunc modifies a frame of spectrum in place. It is called on the
+// audio thread and must not retain sp.
+type SpectralFunc func(sp *Spectrum)
+
+// stft is short-time fourier analysis of a signal by hann windows, calling a
+// func on each spectrum and resynthesizing the result by overlap-add.
+type stft struct {
+	sp                   Spectrum
+	win                  []float64
+	infifo, outfifo, acc Discrete
+	dryfifo              Discrete // input aligned with outfifo
+	rover                int
+	re, im               []float64
+	last                 []float64 // phase of each bin last analyzed
+}
+
+// newstft returns stft of frames of n at sample rate sr every hop, where n
+// is a power of two and hop divides it at least four times.
+func newstft(n, hop int, sr float64) *stft {
+	if n < 4 || n&(n-1) != 0 || hop < 1 || n%hop != 0 || n/hop < 4 {
+		panic(fmt.Errorf("snd: spectral frame %v and hop %v not a power of two overlapping at least four times", n, hop))
+	}
+	st := &stft{
+		sp: Spectrum{
+			SampleRate: sr,
+			Size:       n,
+			Hop:        hop,
+			Mag:        make([]float64, n/2+1),
+			Phase:      make([]float64, n/2+1),
+			Freq:       make([]float64, n/2+1),
+			prev:       make([]float64, n/2+1),
+		},
+		win:     make([]float64, n),
+		infifo:  make(Discrete, n),
+		outfifo: make(Discrete, hop),
+		dryfifo: make(Discrete, hop),
+		acc:     make(Discrete, n),
+		rover:   n - hop,
+		re:      make([]float64, n),
+		im:      make([]float64, n),
+		last:    make([]float64, n/2+1),
+	}
+	for i := range st.win {
+		st.win[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+	}
+	return st
+}
+
+// latency returns frames output is delayed, the frame size as the first hop
+// of a frame is output once the whole frame is analyzed.
+func (st *stft) latency() int { return st.sp.Size }
+
+func (st *stft) clear() {
+	for _, xs := range [][]float64{st.infifo, st.outfifo, st.dryfifo, st.acc, st.last, st.sp.prev} {
+		for i := range xs {
+			xs[i] = 0
+		}
+	}
+}
+
+// process adds x to analysis and returns input and output delayed by latency,
+// calling fn, if not nil, on each spectrum.
+func (st *stft) process(x float64, fn SpectralFunc) (dry, wet float64) {
+	lat := st.sp.Size - st.sp.Hop
+	st.infifo[st.rover] = x
+	dry, wet = st.dryfifo[st.rover-lat], st.outfifo[st.rover-lat]
+	if st.rover++; st.rover == st.sp.Size {
+		st.rover = lat
+		st.frame(fn)
+	}
+	return dry, wet
+}
+
+// frame analyzes a frame of input, calls fn, and adds its resynthesis to
+// output.
+func (st *stft) frame(fn SpectralFunc) {
+	sp := &st.sp
+	n := sp.Size
+	for i := range st.re {
+		st.re[i], st.im[i] = st.infifo[i]*st.win[i], 0
+	}
+	fft(st.re, st.im, false)
+	bin := sp.SampleRate / float64(n)
+	for k := range sp.Mag {
+		ph := math.Atan2(st.im[k], st.re[k])
+		// deviation of phase advance from that of the bin center.
+		want := 2 * math.Pi * float64(k*sp.Hop) / float64(n)
+		dev := math.Remainder(ph-st.last[k]-want, 2*math.Pi)
+		sp.Freq[k] = bin * (float64(k) + dev*float64(n)/(2*math.Pi*float64(sp.Hop)))
+		sp.Mag[k], sp.Phase[k], st.last[k] = math.Hypot(st.re[k], st.im[k]), ph, ph
+	}
+	if fn != nil {
+		fn(sp)
+	}
+	for k := range sp.Mag {
+		st.re[k], st.im[k] = sp.Mag[k]*math.Cos(sp.Phase[k]), sp.Mag[k]*math.Sin(sp.Phase[k])
+		if k > 0 && k < n/2 {
+			st.re[n-k], st.im[n-k] = st.re[k], -st.im[k]
+		}
+		sp.prev[k] = sp.Phase[k]
+	}
+	st.im[0], st.im[n/2] = 0, 0
+	fft(st.re, st.im, true)
+
+	// hann windows squared overlapping by o sum to 3o/8.
+	scale := 1 / (0.375 * float64(n/sp.Hop) * float64(n))
+	for i := range st.acc {
+		st.acc[i] += st.win[i] * st.re[i] * scale
+	}
+	copy(st.outfifo, st.acc[:sp.Hop])
+	copy(st.dryfifo, st.infifo[:sp.Hop])
+	copy(st.acc, st.acc[sp.Hop:])
+	for i := n - sp.Hop; i < n; i++ {
+		st.acc[i] = 0
+	}
+	copy(st.infifo, st.infifo[sp.Hop:])
+}
+
+// Spectral is a custom spectral effect, analyzing its input in frames by
+// short-time fourier transform, modifying each spectrum by a SpectralFunc,
+// and resynthesizing it by overlap-add, so effects need not window, transform
+// and track phase themselves. A nil func passes input through.
+//
+// Output is delayed by the frame size.
+type Spectral struct {
+	*mono
+	st  *stft
+	fn  SpectralFunc
+	mix float64
+}
+
+// NewSpectral returns Spectral of in modified by fn, analyzing frames of
+// size, a power of two such as 2048, every hop, dividing size at least four
+// times such as size/4, fully wet.
+func NewSpectral(size, hop int, fn SpectralFunc, in Sound) *Spectral {
+	sd := newmono(in)
+	return &Spectral{mono: sd, st: newstft(size, hop, sd.sr), fn: fn, mix: 1}
+}
+
+// Latency returns frames output is delayed.
+func (spc *Spectral) Latency() int { return spc.st.latency() }
+
+func (spc *Spectral) Mix() float64     { return spc.mix }
+func (spc *Spectral) SetMix(x float64) { spc.mix = x }
+
+func (spc *Spectral) Params() []*Param {
+	return []*Param{NewParam("mix", spc.Mix, spc.SetMix).Range(0, 1, 1).In(UnitPercent)}
+}
+
+// Panic silences any spectrum being output.
+func (spc *Spectral) Panic() { spc.st.clear() }
+
+func (spc *Spectral) Prepare(uint64) {
+	for i, x := range spc.in.Samples() {
+		dry, wet := spc.st.process(x, spc.fn)
+		if spc.off {
+			spc.out[i] = 0
+		} else {
+			spc.out[i] = dry + spc.mix*(wet-dry)
+		}
+	}
+}
+
+// SpectralFreeze processes its input in the frequency domain, holding the
+// current spectrum indefinitely while frozen and blurring spectra over time,
+// for ambient pads and washes made from any sound.
+//
+// Output is delayed by the frame size, about 46ms at 44.1kHz.
+type SpectralFreeze struct {
+	*Spectral
+	mag, freq []float64 // held
+
+	frozen bool
+	blur   float64
+}
+
+// NewSpectralFreeze returns SpectralFreeze of in, unfrozen and without blur,
+// wet only.
+func NewSpectralFreeze(in Sound) *SpectralFreeze {
+	const n, hop = 2048, 512
+	sf := &SpectralFreeze{
+		mag:  make([]float64, n/2+1),
+		freq: make([]float64, n/2+1),
+	}
+	sf.Spectral = NewSpectral(n, hop, sf.process, in)
+	return sf
+}
+
+// Frozen reports whether the spectrum is held.
+func (sf *SpectralFreeze) Frozen() bool { return sf.frozen }
+
+// SetFrozen holds the current spectrum, sustaining it with its partials
+// advancing at their measured rates, or resumes following input.
+func (sf *SpectralFreeze) SetFrozen(b bool) { sf.frozen = b }
+
+// Blur returns the amount spectra are smeared over time, in [0..1).
+func (sf *SpectralFreeze) Blur() float64 { return sf.blur }
+
+// SetBlur sets the amount spectra are smeared over time, where zero follows
+// input exactly and values near 1 change slowly.
+func (sf *SpectralFreeze) SetBlur(x float64) {
+	sf.blur = math.Max(0, math.Min(0.999, x))
+}
+
+func (sf *SpectralFreeze) Params() []*Param {
+	return []*Param{
+		NewParam("blur", sf.Blur, sf.SetBlur).Range(0, 0.999, 0),
+		NewParam("mix", sf.Mix, sf.SetMix).Range(0, 1, 1).In(UnitPercent),
+	}
+}
+
+// Panic unfreezes and silences any spectrum held or being output.
+func (sf *SpectralFreeze) Panic() {
+	sf.frozen = false
+	sf.Spectral.Panic()
+	for i := range sf.mag {
+		sf.mag[i] = 0
+	}
+}
+
+func (sf *SpectralFreeze) process(sp *Spectrum) {
+	if !sf.frozen {
+		for k, mag := range sp.Mag {
+			sf.mag[k] = sf.blur*sf.mag[k] + (1-sf.blur)*mag
+		}
+		copy(sf.freq, sp.Freq)
+	} else {
+		copy(sp.Freq, sf.freq)
+		sp.Advance()
+	}
+	copy(sp.Mag, sf.mag)
+}
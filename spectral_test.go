@@ -0,0 +1,96 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestFFT(t *testing.T) {
+	re, im := make([]float64, 16), make([]float64, 16)
+	for i := range re {
+		re[i] = math.Cos(2 * math.Pi * 3 * float64(i) / 16)
+	}
+	fft(re, im, false)
+	for k := range re {
+		want := 0.0
+		if k == 3 || k == 13 {
+			want = 8
+		}
+		if !equaleps(math.Hypot(re[k], im[k]), want, 1e-9) {
+			t.Fatalf("bin %v: have %v, want %v", k, math.Hypot(re[k], im[k]), want)
+		}
+	}
+	fft(re, im, true)
+	for i := range re {
+		if want := 16 * math.Cos(2*math.Pi*3*float64(i)/16); !equaleps(re[i], want, 1e-9) {
+			t.Fatalf("frame %v: have %v, want %v", i, re[i], want)
+		}
+	}
+}
+
+func TestSpectralFreeze(t *testing.T) {
+	sr := DefaultSampleRate
+	osc := NewOscil(Sine(), 1000, nil)
+	sf := NewSpectralFreeze(osc)
+	n := Dtof(500*time.Millisecond, sr)
+
+	out := Render(sf, n)
+	if a := 2 * goertzel(out[n/2:], 1000, sr); !equaleps(a, 1, 0.05) {
+		t.Fatalf("have amplitude %v passing through, want 1", a)
+	}
+
+	sf.SetFrozen(true)
+	osc.Off()
+	out = Render(sf, n)
+	if a := 2 * goertzel(out[n/2:], 1000, sr); a < 0.5 {
+		t.Fatalf("have amplitude %v frozen, want sustained", a)
+	}
+
+	sf.SetFrozen(false)
+	out = Render(sf, n)
+	if p := Peak(out[n/2:]); p > 1e-6 {
+		t.Fatalf("have peak %v unfrozen without input, want silence", p)
+	}
+}
+
+func TestSpectral(t *testing.T) {
+	const sr, n = DefaultSampleRate, 16384
+	src := func() Sound {
+		return NewMixer(NewOscil(Sine(), 441, nil), NewOscil(Sine(), 5000, nil))
+	}
+
+	// passed through, output is input delayed by latency.
+	spc := NewSpectral(1024, 256, nil, src())
+	out, in := Render(spc, n), Render(src(), n)
+	lat := spc.Latency()
+	if snr := SNR(out[lat:], in[:n-lat]); snr < 100 {
+		t.Fatalf("have SNR %v passed through, want at least 100dB", snr)
+	}
+
+	// bins above 1kHz dropped, the upper tone is gone.
+	spc = NewSpectral(1024, 256, func(sp *Spectrum) {
+		for k := sp.Bin(1000); k < len(sp.Mag); k++ {
+			sp.Mag[k] = 0
+		}
+	}, src())
+	out = Render(spc, n)[n/2:]
+	if lo, hi := goertzel(out, 441, sr), goertzel(out, 5000, sr); hi > lo/1000 {
+		t.Fatalf("have level %v of upper tone and %v of lower", hi, lo)
+	}
+
+	// partials at twice their frequency, advanced, sound an octave up.
+	spc = NewSpectral(2048, 256, func(sp *Spectrum) {
+		for k := len(sp.Mag) - 1; k >= 0; k-- {
+			sp.Mag[k], sp.Freq[k] = 0, 0
+			if j := k / 2; k%2 == 0 {
+				sp.Mag[k], sp.Freq[k] = sp.Mag[j], 2*sp.Freq[j]
+			}
+		}
+		sp.Advance()
+	}, NewOscil(Sine(), 441, nil))
+	out = Render(spc, n)[n/2:]
+	if up, at := goertzel(out, 882, sr), goertzel(out, 441, sr); up < 10*at {
+		t.Fatalf("have level %v an octave up and %v at pitch", up, at)
+	}
+}
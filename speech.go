@@ -0,0 +1,255 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Formant is a resonance of the vocal tract.
+type Formant struct {
+	Freq, BW float64 // Hz
+	Gain     Decibel
+}
+
+// Phoneme is a sound of speech by source and filter: a glottal pulse and
+// noise, of aspiration or frication, through three formants.
+type Phoneme struct {
+	Formants [3]Formant
+	Voice    float64 // amplitude of the glottal pulse
+	Noise    float64 // amplitude of noise
+}
+
+// formants returns formants at f1, f2, and f3 of typical bandwidths, falling
+// by 6dB each.
+func formants(f1, f2, f3 float64) [3]Formant {
+	return [3]Formant{{f1, 80, 0}, {f2, 100, -6}, {f3, 150, -12}}
+}
+
+// Phonemes are presets of an adult male voice by names as of ARPAbet, with
+// "_" silent. Stops have no presets; a rest then a short burst of noise
+// stands in for them, such as "_:40ms s:20ms" for "t".
+var Phonemes = map[string]Phoneme{
+	"_": {Formants: formants(500, 1500, 2500)},
+
+	// vowels, by the formants of Peterson and Barney.
+	"iy": {Formants: formants(270, 2290, 3010), Voice: 1},
+	"ih": {Formants: formants(390, 1990, 2550), Voice: 1},
+	"eh": {Formants: formants(530, 1840, 2480), Voice: 1},
+	"ae": {Formants: formants(660, 1720, 2410), Voice: 1},
+	"aa": {Formants: formants(730, 1090, 2440), Voice: 1},
+	"ao": {Formants: formants(570, 840, 2410), Voice: 1},
+	"uh": {Formants: formants(440, 1020, 2240), Voice: 1},
+	"uw": {Formants: formants(300, 870, 2240), Voice: 1},
+	"ah": {Formants: formants(640, 1190, 2390), Voice: 1},
+	"er": {Formants: formants(490, 1350, 1690), Voice: 1},
+
+	// nasals and approximants, quieter than vowels.
+	"m": {Formants: formants(280, 1300, 2300), Voice: 0.4},
+	"n": {Formants: formants(280, 1700, 2600), Voice: 0.4},
+	"l": {Formants: formants(360, 1300, 2700), Voice: 0.6},
+	"r": {Formants: formants(420, 1300, 1600), Voice: 0.6},
+	"w": {Formants: formants(300, 610, 2200), Voice: 0.6},
+	"y": {Formants: formants(260, 2070, 3020), Voice: 0.6},
+
+	// fricatives, of noise through wide formants, voiced of some voice.
+	"hh": {Formants: formants(500, 1500, 2500), Noise: 0.3},
+	"s":  {Formants: [3]Formant{{4500, 1500, -6}, {6500, 2000, 0}, {8500, 2500, -6}}, Noise: 0.6},
+	"z":  {Formants: [3]Formant{{4500, 1500, -6}, {6500, 2000, 0}, {8500, 2500, -6}}, Voice: 0.3, Noise: 0.4},
+	"sh": {Formants: [3]Formant{{2500, 800, 0}, {3500, 1200, -3}, {5000, 2000, -6}}, Noise: 0.6},
+	"f":  {Formants: [3]Formant{{1500, 1500, -12}, {5000, 3000, -6}, {8000, 4000, -6}}, Noise: 0.3},
+	"v":  {Formants: [3]Formant{{1500, 1500, -12}, {5000, 3000, -6}, {8000, 4000, -6}}, Voice: 0.3, Noise: 0.2},
+}
+
+// lerp returns the phoneme t of the way from a to b.
+func (a Phoneme) lerp(b Phoneme, t float64) Phoneme {
+	l := func(x, y float64) float64 { return x + t*(y-x) }
+	p := Phoneme{Voice: l(a.Voice, b.Voice), Noise: l(a.Noise, b.Noise)}
+	for i := range p.Formants {
+		fa, fb := a.Formants[i], b.Formants[i]
+		p.Formants[i] = Formant{l(fa.Freq, fb.Freq), l(fa.BW, fb.BW), Decibel(l(float64(fa.Gain), float64(fb.Gain)))}
+	}
+	return p
+}
+
+// SpeechStep is a phoneme spoken over Dur at Pitch in Hz, zero keeping the
+// pitch of the step before.
+type SpeechStep struct {
+	Phoneme Phoneme
+	Dur     time.Duration
+	Pitch   float64
+}
+
+// ParseSpeech returns steps of Phonemes named in s separated by spaces, each
+// over dur, such as "hh eh l ao" of "hello"; a name may be followed by a
+// duration, such as "eh:200ms".
+func ParseSpeech(s string, dur time.Duration) ([]SpeechStep, error) {
+	var steps []SpeechStep
+	for _, f := range strings.Fields(s) {
+		st := SpeechStep{Dur: dur}
+		if i := strings.IndexByte(f, ':'); i >= 0 {
+			d, err := time.ParseDuration(f[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("snd: speech %q: %v", f, err)
+			}
+			f, st.Dur = f[:i], d
+		}
+		p, ok := Phonemes[f]
+		if !ok {
+			return nil, fmt.Errorf("snd: undefined phoneme %q", f)
+		}
+		st.Phoneme = p
+		steps = append(steps, st)
+	}
+	return steps, nil
+}
+
+// Speech is a voice of source and filter synthesis, a glottal pulse and noise
+// through formants, speaking phonemes over time with glides between them, for
+// retro speech and robots. Its pitch is constant, unless set otherwise.
+type Speech struct {
+	*mono
+	rnd   *rand.Rand
+	amp   float64
+	glide int // frames
+
+	cur, from, to     Phoneme
+	pitch, pfrom, pto float64
+	t                 int // frames into glide
+	fs                [3]svf
+	amps              [3]float64
+	phase, g1         float64 // of glottal pulse and its last value
+
+	steps []SpeechStep
+	step  int
+	at    int // frames into step
+}
+
+// NewSpeech returns Speech silent at pitch in Hz, gliding between phonemes
+// over 30ms.
+func NewSpeech(pitch float64) *Speech {
+	sp := &Speech{mono: newmono(nil), rnd: rand.New(rand.NewSource(1)), amp: 0.5, pitch: pitch, pto: pitch}
+	sp.glide = Dtof(30*time.Millisecond, sp.sr)
+	sp.cur, sp.to = Phonemes["_"], Phonemes["_"]
+	sp.t = sp.glide
+	sp.tune()
+	return sp
+}
+
+func (sp *Speech) Inputs() []Sound { return nil }
+
+func (sp *Speech) Pitch() float64 { return sp.pitch }
+
+// SetPitch sets pitch in Hz at once.
+func (sp *Speech) SetPitch(hz float64) { sp.pitch, sp.pfrom, sp.pto = hz, hz, hz }
+
+func (sp *Speech) Amp() float64     { return sp.amp }
+func (sp *Speech) SetAmp(x float64) { sp.amp = x }
+
+// Glide returns how long phonemes and pitch glide from those before.
+func (sp *Speech) Glide() time.Duration     { return Ftod(sp.glide, sp.sr) }
+func (sp *Speech) SetGlide(d time.Duration) { sp.glide = Dtof(d, sp.sr) }
+
+// SetPhoneme glides to p and holds it, cancelling steps speaking.
+func (sp *Speech) SetPhoneme(p Phoneme) {
+	sp.steps = nil
+	sp.target(p, 0)
+}
+
+// Say speaks steps in order from the next buffer, cancelling steps speaking,
+// then glides to silence.
+func (sp *Speech) Say(steps ...SpeechStep) {
+	sp.steps = append([]SpeechStep(nil), steps...)
+	sp.step, sp.at = 0, 0
+}
+
+// Speaking reports whether steps are left to speak.
+func (sp *Speech) Speaking() bool { return sp.step < len(sp.steps) }
+
+func (sp *Speech) Params() []*Param {
+	return []*Param{
+		NewParam("pitch", sp.Pitch, sp.SetPitch).Range(50, 500, 110).In(UnitHz),
+	}
+}
+
+// target glides to p and pitch, zero keeping pitch.
+func (sp *Speech) target(p Phoneme, pitch float64) {
+	sp.from, sp.to, sp.t = sp.cur, p, 0
+	sp.pfrom, sp.pto = sp.pitch, sp.pitch
+	if pitch > 0 {
+		sp.pto = pitch
+	}
+	if sp.glide == 0 {
+		sp.cur, sp.pitch, sp.t = p, sp.pto, 0
+		sp.tune()
+	}
+}
+
+// tune sets formant filters to the current phoneme.
+func (sp *Speech) tune() {
+	for i, f := range sp.cur.Formants {
+		sp.fs[i].set(f.Freq, f.Freq/math.Max(1, f.BW), sp.sr)
+		sp.amps[i] = f.Gain.Amp()
+	}
+}
+
+// advance advances steps and glides a frame.
+func (sp *Speech) advance() {
+	if sp.step < len(sp.steps) {
+		st := sp.steps[sp.step]
+		if sp.at == 0 {
+			sp.target(st.Phoneme, st.Pitch)
+		}
+		if sp.at++; sp.at >= Dtof(st.Dur, sp.sr) {
+			sp.step, sp.at = sp.step+1, 0
+			if sp.step == len(sp.steps) {
+				sp.target(Phonemes["_"], 0)
+			}
+		}
+	}
+	if sp.t < sp.glide {
+		sp.t++
+		x := float64(sp.t) / float64(sp.glide)
+		sp.cur = sp.from.lerp(sp.to, x)
+		sp.pitch = sp.pfrom + x*(sp.pto-sp.pfrom)
+		sp.tune()
+	}
+}
+
+// glottal returns the Rosenberg glottal pulse at phase ph belonging to [0..1),
+// opening over 40% of a period and closing over 16%.
+func glottal(ph float64) float64 {
+	const open, closing = 0.4, 0.16
+	switch {
+	case ph < open:
+		return 0.5 - 0.5*math.Cos(math.Pi*ph/open)
+	case ph < open+closing:
+		return math.Cos(math.Pi / 2 * (ph - open) / closing)
+	default:
+		return 0
+	}
+}
+
+func (sp *Speech) Prepare(uint64) {
+	for i := range sp.out {
+		sp.advance()
+		// the pulse differentiated, as radiated from the lips, of peak near 1.
+		g := glottal(sp.phase)
+		dg := (g - sp.g1) * sp.sr / sp.pitch * 2 * 0.16 / math.Pi
+		sp.g1 = g
+		sp.phase += sp.pitch / sp.sr
+		sp.phase -= math.Floor(sp.phase)
+
+		x := sp.cur.Voice*dg + sp.cur.Noise*(2*sp.rnd.Float64()-1)
+		var y float64
+		for j := range sp.fs {
+			y += sp.amps[j] * sp.fs[j].filter(x, FilterBandPass)
+		}
+		if sp.off {
+			y = 0
+		}
+		sp.out[i] = sp.amp * y
+	}
+}
@@ -0,0 +1,60 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSpeech(t *testing.T) {
+	sp := NewSpeech(100)
+	if pk := Peak(Render(sp, DefaultBufferLen)); pk != 0 {
+		t.Errorf("have peak %v before speaking, want silent", pk)
+	}
+
+	// the second formant of "iy" is high, of "uw" low.
+	second := func(name string) (hi, lo float64) {
+		sp := NewSpeech(100)
+		sp.SetPhoneme(Phonemes[name])
+		out := Render(sp, Dtof(500*time.Millisecond, sp.SampleRate()))[Dtof(100*time.Millisecond, sp.SampleRate()):]
+		if pk := Peak(out); pk < 0.05 || pk > 1 {
+			t.Errorf("%s: have peak %v, want within (0.05..1]", name, pk)
+		}
+		return goertzel(out, 2300, sp.SampleRate()), goertzel(out, 900, sp.SampleRate())
+	}
+	ihi, ilo := second("iy")
+	uhi, ulo := second("uw")
+	if ihi/ilo < 2*uhi/ulo {
+		t.Errorf("have 2300Hz to 900Hz of iy %v and of uw %v, want iy higher", ihi/ilo, uhi/ulo)
+	}
+
+	steps, err := ParseSpeech("hh eh:100ms l ao", 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 4 || steps[1].Dur != 100*time.Millisecond || steps[2].Dur != 50*time.Millisecond {
+		t.Fatalf("have steps %+v", steps)
+	}
+	steps[1].Pitch = 150
+	sp.Say(steps...)
+	out := Render(sp, Dtof(200*time.Millisecond, sp.SampleRate()))
+	if !sp.Speaking() {
+		t.Error("have done, want speaking")
+	}
+	if pk := Peak(out); pk < 0.05 {
+		t.Errorf("have peak %v speaking, want louder", pk)
+	}
+	if sp.Pitch() != 150 {
+		t.Errorf("have pitch %v, want 150 of the second step", sp.Pitch())
+	}
+	Render(sp, Dtof(100*time.Millisecond, sp.SampleRate()))
+	if sp.Speaking() {
+		t.Error("have speaking, want done")
+	}
+	if pk := Peak(Render(sp, DefaultBufferLen)); pk > 0.001 {
+		t.Errorf("have peak %v once done, want silent", pk)
+	}
+
+	if _, err := ParseSpeech("hh zz", time.Second); err == nil {
+		t.Error("have no error of undefined phoneme")
+	}
+}
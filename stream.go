@@ -0,0 +1,250 @@
+package snd
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Latency targets for the delay between preparing sound and hearing it, from
+// most responsive to most robust against glitches.
+const (
+	LatencyLow    = 10 * time.Millisecond // live playing on a fast, idle machine
+	LatencyNormal = 20 * time.Millisecond
+	LatencySafe   = 50 * time.Millisecond // busy machines and background playback
+)
+
+// LatencyBuffers returns the number of buffers of DefaultBufferLen frames at
+// sr to queue ahead of output for a latency target, such as LatencyNormal.
+// There are at least two, so one may be prepared while another plays.
+//
+// Backends and a Stream size their queues from the same target so that one
+// setting trades latency for glitches coherently.
+func LatencyBuffers(target time.Duration, sr float64) int {
+	n := (Dtof(target, sr) + DefaultBufferLen - 1) / DefaultBufferLen
+	if n < 2 {
+		n = 2
+	}
+	return n
+}
+
+// RingBuffer is a lock-free queue of samples between a single writer and a
+// single reader on different goroutines, such as a goroutine preparing a graph
+// and an audio callback that must never block.
+type RingBuffer struct {
+	buf  []float64
+	mask uint64
+	r, w uint64 // atomic; total samples read and written
+}
+
+// NewRingBuffer returns RingBuffer holding at least n samples.
+func NewRingBuffer(n int) *RingBuffer {
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	return &RingBuffer{buf: make([]float64, size), mask: uint64(size - 1)}
+}
+
+// Cap returns the number of samples rb holds.
+func (rb *RingBuffer) Cap() int { return len(rb.buf) }
+
+// Len returns the number of samples ready to read.
+func (rb *RingBuffer) Len() int {
+	return int(atomic.LoadUint64(&rb.w) - atomic.LoadUint64(&rb.r))
+}
+
+// Write writes as many samples of xs as there is room for and returns the
+// number written. Only one goroutine may write.
+func (rb *RingBuffer) Write(xs []float64) int {
+	w, r := rb.w, atomic.LoadUint64(&rb.r)
+	n := len(rb.buf) - int(w-r)
+	if n > len(xs) {
+		n = len(xs)
+	}
+	for i := 0; i < n; i++ {
+		rb.buf[(w+uint64(i))&rb.mask] = xs[i]
+	}
+	atomic.StoreUint64(&rb.w, w+uint64(n))
+	return n
+}
+
+// Read reads as many samples into xs as are ready and returns the number
+// read. Only one goroutine may read.
+func (rb *RingBuffer) Read(xs []float64) int {
+	r, w := rb.r, atomic.LoadUint64(&rb.w)
+	n := int(w - r)
+	if n > len(xs) {
+		n = len(xs)
+	}
+	for i := 0; i < n; i++ {
+		xs[i] = rb.buf[(r+uint64(i))&rb.mask]
+	}
+	atomic.StoreUint64(&rb.r, r+uint64(n))
+	return n
+}
+
+// Stream prepares a graph on its own goroutine, running ahead of an output
+// backend by a duration of buffered samples. The backend reads samples from
+// its audio callback without waiting on the graph, so a slow buffer, such as
+// one interrupted by garbage collection, is absorbed by those ahead instead
+// of causing an underrun.
+//
+// A Stream is a Router of one output.
+type Stream struct {
+	*Router
+	*Output
+}
+
+// NewStream returns Stream of sd preparing up to ahead of the backend, in
+// buffers as given by LatencyBuffers.
+func NewStream(sd Sound, ahead time.Duration) *Stream {
+	rt := NewRouter(ahead, sd)
+	return &Stream{rt, rt.Output(0)}
+}
+
+// Router prepares a graph of several outputs together on its own goroutine,
+// such as a click bus sent to headphones and a music bus to the main out that
+// share a Transport, queueing each output for its own sink as a Stream does.
+// Outputs are prepared in step, so a Router runs ahead only as far as the
+// output read least; sinks are expected to read at the same sample rate.
+type Router struct {
+	root  *joint
+	outs  []*Output
+	ahead int // frames prepared ahead
+
+	inps   []*Input
+	dp     Dispatcher
+	tc     uint64
+	wake   chan struct{}
+	quit   chan struct{}
+	done   chan struct{}
+	notify int32 // atomic
+}
+
+// Output is one output of a Router read by a sink.
+type Output struct {
+	sd    Sound
+	rb    *RingBuffer
+	wake  chan struct{}
+	under uint64 // atomic
+}
+
+// joint is the root of a graph of several outputs.
+type joint struct {
+	*mono
+	outs []Sound
+}
+
+func (jt *joint) Inputs() []Sound { return jt.outs }
+func (jt *joint) Prepare(uint64)  {}
+
+// NewRouter returns Router of outs, such as buses of one graph, preparing up
+// to ahead of sinks, in buffers as given by LatencyBuffers. Outputs must have
+// buffers of the same number of frames.
+func NewRouter(ahead time.Duration, outs ...Sound) *Router {
+	rt := &Router{
+		root: &joint{newmono(nil), outs},
+		wake: make(chan struct{}, 1),
+	}
+	if len(outs) != 0 {
+		sd := outs[0]
+		rt.ahead = LatencyBuffers(ahead, sd.SampleRate()) * len(sd.Samples()) / sd.Channels()
+	}
+	for _, sd := range outs {
+		rt.outs = append(rt.outs, &Output{
+			sd:   sd,
+			rb:   NewRingBuffer(rt.ahead * sd.Channels()),
+			wake: rt.wake,
+		})
+	}
+	rt.inps = GetInputs(rt.root)
+	return rt
+}
+
+// Output returns output i, in order given to NewRouter.
+func (rt *Router) Output(i int) *Output { return rt.outs[i] }
+
+// Ahead returns the duration prepared ahead of sinks when full.
+func (rt *Router) Ahead() time.Duration {
+	return Ftod(rt.ahead, rt.outs[0].sd.SampleRate())
+}
+
+// Dispatcher returns the Dispatcher preparing the graph, such as to add hooks.
+func (rt *Router) Dispatcher() *Dispatcher { return &rt.dp }
+
+// Panic silences the graph of rt alone, as Dispatcher.Panic.
+func (rt *Router) Panic() { rt.dp.Panic() }
+
+// Notify finds inputs of the graph again before the next buffer, such as
+// after a sound is added, as Dispatch requires of inputs changed while running.
+func (rt *Router) Notify() { atomic.StoreInt32(&rt.notify, 1) }
+
+// Start prepares ahead before returning, so the first read finds samples
+// ready, and continues preparing on a new goroutine.
+func (rt *Router) Start() {
+	rt.quit, rt.done = make(chan struct{}), make(chan struct{})
+	rt.refill()
+	go rt.run()
+}
+
+// Stop stops preparing and waits for the buffer in progress to finish.
+func (rt *Router) Stop() {
+	close(rt.quit)
+	<-rt.done
+}
+
+func (rt *Router) run() {
+	defer close(rt.done)
+	for {
+		rt.refill()
+		select {
+		case <-rt.quit:
+			return
+		case <-rt.wake:
+		}
+	}
+}
+
+// room reports whether every output has room for another buffer.
+func (rt *Router) room() bool {
+	for _, out := range rt.outs {
+		if rt.ahead*out.sd.Channels()-out.rb.Len() < len(out.sd.Samples()) {
+			return false
+		}
+	}
+	return len(rt.outs) != 0
+}
+
+// refill prepares buffers of the graph until ahead of the sinks.
+func (rt *Router) refill() {
+	for rt.room() {
+		if atomic.CompareAndSwapInt32(&rt.notify, 1, 0) {
+			rt.inps = GetInputs(rt.root)
+		}
+		rt.tc++
+		rt.dp.Dispatch(rt.tc, rt.inps...)
+		for _, out := range rt.outs {
+			out.rb.Write(out.sd.Samples())
+		}
+	}
+}
+
+// Underruns returns the number of reads that found too few samples ready.
+func (out *Output) Underruns() uint64 { return atomic.LoadUint64(&out.under) }
+
+// Read fills xs with interleaved samples prepared ahead, and silence for any
+// not yet prepared. Read never blocks and is meant to be called from the
+// audio callback of a sink.
+func (out *Output) Read(xs []float64) {
+	n := out.rb.Read(xs)
+	if n < len(xs) {
+		for i := n; i < len(xs); i++ {
+			xs[i] = 0
+		}
+		atomic.AddUint64(&out.under, 1)
+	}
+	select {
+	case out.wake <- struct{}{}:
+	default:
+	}
+}
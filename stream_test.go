@@ -0,0 +1,97 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyBuffers(t *testing.T) {
+	for _, tc := range []struct {
+		target time.Duration
+		want   int
+	}{{0, 2}, {LatencyLow, 2}, {LatencyNormal, 4}, {LatencySafe, 9}} {
+		if n := LatencyBuffers(tc.target, 44100); n != tc.want {
+			t.Errorf("%v: have %v buffers, want %v", tc.target, n, tc.want)
+		}
+	}
+}
+
+func TestRingBuffer(t *testing.T) {
+	rb := NewRingBuffer(5)
+	if rb.Cap() != 8 {
+		t.Fatalf("have cap %v, want 8", rb.Cap())
+	}
+	xs := make([]float64, 6)
+	for i := 0; i < 20; i++ {
+		if n := rb.Write([]float64{1, 2, 3, 4, 5, 6}); n != 6 {
+			t.Fatalf("have %v written, want 6", n)
+		}
+		if n := rb.Write([]float64{7, 8, 9}); n != 2 {
+			t.Fatalf("have %v written to full buffer, want 2", n)
+		}
+		if n := rb.Read(xs); n != 6 || xs[0] != 1 || xs[5] != 6 {
+			t.Fatalf("have %v read %v", n, xs)
+		}
+		if n := rb.Read(xs); n != 2 || xs[1] != 8 || rb.Len() != 0 {
+			t.Fatalf("have %v read %v", n, xs)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := &counter{mono: newmono(nil)}
+	st := NewStream(c, 20*time.Millisecond)
+	if d := st.Ahead(); d < 20*time.Millisecond || d > 30*time.Millisecond {
+		t.Fatalf("have %v ahead, want whole buffers of 20ms", d)
+	}
+	st.Start()
+	defer st.Stop()
+
+	// reads of any size up to a buffer less than ahead continue where the
+	// last left off.
+	var want float64
+	for _, n := range []int{100, 441, 256, 700, 3} {
+		xs := make([]float64, n)
+		for deadline := time.Now().Add(time.Second); ; {
+			if st.rb.Len() >= n || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		st.Read(xs)
+		for i, x := range xs {
+			if x != want {
+				t.Fatalf("read of %v: have %v at %v, want %v", n, x, i, want)
+			}
+			want++
+		}
+	}
+	if st.Underruns() != 0 {
+		t.Fatalf("have %v underruns, want 0", st.Underruns())
+	}
+}
+
+func TestRouter(t *testing.T) {
+	// a click bus and a music bus share a counter prepared once a buffer.
+	c := &counter{mono: newmono(nil)}
+	click, music := NewGain(1, c), NewPan(0, c)
+	rt := NewRouter(20*time.Millisecond, click, music)
+	rt.Start()
+	defer rt.Stop()
+
+	mono, stereo := make([]float64, 300), make([]float64, 600)
+	rt.Output(0).Read(mono)
+	rt.Output(1).Read(stereo)
+	g := stereo[2] / mono[1] // pan law of center
+	for i, x := range mono {
+		if x != float64(i) {
+			t.Fatalf("have %v at %v of click, want %v", x, i, i)
+		}
+		if l, r := stereo[2*i], stereo[2*i+1]; !equaleps(l, g*x, 1e-9) || l != r {
+			t.Fatalf("have %v, %v at %v of music, want %v", l, r, i, g*x)
+		}
+	}
+	if n := rt.Output(0).Underruns() + rt.Output(1).Underruns(); n != 0 {
+		t.Fatalf("have %v underruns, want 0", n)
+	}
+}
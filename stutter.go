@@ -0,0 +1,146 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BeatRepeat captures slices of its input on a beat grid of a transport and,
+// at random by probability, repeats the last captured slice in place of
+// input, possibly reversed or pitched. Repeating a slice over several grid
+// steps produces the stutters and glitches of electronic performance.
+//
+// Input passes through unchanged while the transport is stopped.
+type BeatRepeat struct {
+	*mono
+	tp   *Transport
+	rnd  *rand.Rand
+	grid float64 // beats per slice
+
+	chance  float64 // probability a slice repeats
+	reverse float64 // probability a repeat plays reversed
+	pitched float64 // probability a repeat is pitched
+	semis   float64
+
+	buf   []float64
+	n     int     // frames captured in buf
+	slice float64 // index of current slice
+	rep   bool    // repeating current slice
+	rev   bool
+	rate  float64
+	pos   float64 // read position in buf
+	g, cg float64 // smoothed mix of repeat over input
+}
+
+// NewBeatRepeat returns BeatRepeat of in on a grid of beats of tp, e.g. 0.25
+// for sixteenth notes, repeating a slice with probability chance.
+func NewBeatRepeat(tp *Transport, grid, chance float64, in Sound) *BeatRepeat {
+	sd := newmono(in)
+	return &BeatRepeat{
+		mono:   sd,
+		tp:     tp,
+		rnd:    rand.New(rand.NewSource(1)),
+		grid:   grid,
+		chance: chance,
+		semis:  12,
+		buf:    make([]float64, Dtof(maxDelay, sd.sr)),
+		slice:  -1,
+		cg:     smoothcoef(time.Millisecond, sd.sr),
+	}
+}
+
+func (br *BeatRepeat) Inputs() []Sound { return []Sound{br.in, br.tp} }
+
+func (br *BeatRepeat) Grid() float64     { return br.grid }
+func (br *BeatRepeat) SetGrid(x float64) { br.grid = x }
+
+// Chance is the probability of a slice repeating the last captured slice.
+func (br *BeatRepeat) Chance() float64     { return br.chance }
+func (br *BeatRepeat) SetChance(x float64) { br.chance = x }
+
+// Reverse is the probability of a repeat playing backwards.
+func (br *BeatRepeat) Reverse() float64     { return br.reverse }
+func (br *BeatRepeat) SetReverse(x float64) { br.reverse = x }
+
+// Pitched is the probability of a repeat playing shifted by Semitones,
+// changing its speed as well as pitch.
+func (br *BeatRepeat) Pitched() float64     { return br.pitched }
+func (br *BeatRepeat) SetPitched(x float64) { br.pitched = x }
+
+func (br *BeatRepeat) Semitones() float64     { return br.semis }
+func (br *BeatRepeat) SetSemitones(x float64) { br.semis = x }
+
+// Seed resets the source of random decisions so a performance may be repeated.
+func (br *BeatRepeat) Seed(seed int64) { br.rnd.Seed(seed) }
+
+// Repeating reports whether the last prepared frame was of a repeat.
+func (br *BeatRepeat) Repeating() bool { return br.rep }
+
+func (br *BeatRepeat) Params() []*Param {
+	return []*Param{
+		NewParam("grid", br.Grid, br.SetGrid).Range(0.015625, 4, 0.25).In(UnitBeats),
+		NewParam("chance", br.Chance, br.SetChance).Range(0, 1, 0.5).In(UnitPercent),
+		NewParam("reverse", br.Reverse, br.SetReverse).Range(0, 1, 0).In(UnitPercent),
+		NewParam("pitched", br.Pitched, br.SetPitched).Range(0, 1, 0).In(UnitPercent),
+		NewParam("semitones", br.Semitones, br.SetSemitones).Range(-24, 24, 0).In(UnitSemitones),
+	}
+}
+
+// next starts a slice, deciding whether it repeats.
+func (br *BeatRepeat) next() {
+	br.rep = br.n > 0 && br.rnd.Float64() < br.chance
+	br.rev = br.rnd.Float64() < br.reverse
+	br.rate = 1
+	if br.rnd.Float64() < br.pitched {
+		br.rate = math.Pow(2, br.semis/12)
+	}
+	br.pos = 0
+	if !br.rep {
+		br.n = 0
+	}
+}
+
+func (br *BeatRepeat) Prepare(uint64) {
+	in := br.in.Samples()
+	playing := br.tp.Playing() && br.grid > 0
+	step := float64(br.tp.BPM()) / (60 * br.sr)
+	beat := br.tp.Beat() - float64(len(in))*step
+	for i, x := range in {
+		y := x
+		if playing {
+			const eps = 1e-9
+			if s := math.Floor((beat+eps)/br.grid + eps); s != br.slice {
+				br.slice = s
+				br.next()
+			}
+			beat += step
+			if br.rep {
+				j := int(br.pos)
+				if br.rev {
+					j = br.n - 1 - j
+				}
+				y = br.buf[j]
+				if br.pos += br.rate; br.pos >= float64(br.n) {
+					br.pos -= float64(br.n)
+				}
+			} else if br.n < len(br.buf) {
+				br.buf[br.n] = x
+				br.n++
+			}
+		} else {
+			br.rep = false
+		}
+
+		target := 0.0
+		if br.rep {
+			target = 1
+		}
+		br.g += br.cg * (target - br.g)
+		if br.off {
+			br.out[i] = 0
+		} else {
+			br.out[i] = (1-br.g)*x + br.g*y
+		}
+	}
+}
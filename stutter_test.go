@@ -0,0 +1,43 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+// counter outputs the number of frames prepared before each frame.
+type counter struct {
+	*mono
+	n float64
+}
+
+func (c *counter) Prepare(uint64) {
+	for i := range c.out {
+		c.out[i] = c.n
+		c.n++
+	}
+}
+
+func TestBeatRepeat(t *testing.T) {
+	tp := NewTransport(150)
+	tp.Play()
+	br := NewBeatRepeat(tp, 0.25, 1, &counter{mono: newmono(nil)})
+	out := Render(br, Dtof(time.Second, DefaultSampleRate))
+
+	// slices of a sixteenth at 150bpm are 100ms; the first is captured and
+	// every following slice repeats it.
+	d := Dtof(100*time.Millisecond, DefaultSampleRate)
+	for _, i := range []int{d / 2, d + d/2, 5*d + 10} {
+		if have, want := out[i], float64(i%d); !equaleps(have, want, 1e-6) {
+			t.Errorf("frame %v: have %v, want %v", i, have, want)
+		}
+	}
+
+	br = NewBeatRepeat(tp, 0.25, 1, &counter{mono: newmono(nil)})
+	br.SetReverse(1)
+	tp.Seek(0)
+	out = Render(br, 2*d)
+	if have, want := out[d+d/2], float64(d-1-d/2); !equaleps(have, want, 1e-6) {
+		t.Errorf("reversed: have %v, want %v", have, want)
+	}
+}
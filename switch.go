@@ -0,0 +1,114 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// ABSwitch outputs one of two inputs, A or B, such as two versions of a
+// patch, and switches between them by an equal power crossfade. With a
+// Transport, switching waits for the next bar line so comparisons and
+// transitions land in time.
+//
+// Both inputs are prepared continuously so either is ready when switched to.
+type ABSwitch struct {
+	*mono
+	a, b  Sound
+	chans int
+	tp    *Transport
+	bar   float64 // beats per bar
+
+	sel     int     // input switched to, 0 for A and 1 for B
+	pending bool    // waiting for bar line
+	g       float64 // position of fade from A to B
+	dg      float64 // fade change per frame
+}
+
+// NewABSwitch returns ABSwitch playing a, fading over 20ms when switched.
+// Inputs must have the same number of channels.
+func NewABSwitch(a, b Sound) *ABSwitch {
+	sd := newmono(nil)
+	sd.sr = a.SampleRate()
+	sd.out = make(Discrete, len(a.Samples()))
+	sw := &ABSwitch{mono: sd, a: a, b: b, chans: a.Channels(), bar: 4}
+	sw.SetFade(20 * time.Millisecond)
+	return sw
+}
+
+func (sw *ABSwitch) Channels() int { return sw.chans }
+
+func (sw *ABSwitch) Inputs() []Sound {
+	if sw.tp == nil {
+		return []Sound{sw.a, sw.b}
+	}
+	return []Sound{sw.a, sw.b, sw.tp}
+}
+
+// Sync delays switching to the next bar of beatsPerBar beats of tp while tp
+// is playing. A nil tp switches immediately.
+func (sw *ABSwitch) Sync(tp *Transport, beatsPerBar float64) {
+	sw.tp, sw.bar = tp, beatsPerBar
+}
+
+// SetFade sets the duration of crossfades.
+func (sw *ABSwitch) SetFade(d time.Duration) {
+	sw.dg = math.Inf(1)
+	if n := Dtof(d, sw.sr); n > 0 {
+		sw.dg = 1 / float64(n)
+	}
+}
+
+// Selected returns the input switched to, 0 for A and 1 for B, even if
+// waiting for a bar line.
+func (sw *ABSwitch) Selected() int { return sw.sel }
+
+// Select switches to input 0 for A or 1 for B.
+func (sw *ABSwitch) Select(i int) {
+	if i != 0 {
+		i = 1
+	}
+	sw.sel = i
+	sw.pending = sw.tp != nil
+}
+
+// Toggle switches to the input not selected.
+func (sw *ABSwitch) Toggle() { sw.Select(1 - sw.sel) }
+
+// Pending reports whether switching waits for a bar line.
+func (sw *ABSwitch) Pending() bool { return sw.pending }
+
+func (sw *ABSwitch) Prepare(uint64) {
+	a, b := sw.a.Samples(), sw.b.Samples()
+	var beat, step float64
+	playing := sw.tp != nil && sw.tp.Playing() && sw.bar > 0
+	if playing {
+		step = float64(sw.tp.BPM()) / (60 * sw.sr)
+		beat = sw.tp.Beat() - float64(len(sw.out)/sw.chans)*step
+	}
+	for i := 0; i < len(sw.out); i += sw.chans {
+		if playing {
+			const eps = 1e-9
+			if math.Floor((beat+eps)/sw.bar) != math.Floor((beat-step+eps)/sw.bar) {
+				sw.pending = false
+			}
+			beat += step
+		} else {
+			sw.pending = false
+		}
+		if !sw.pending {
+			if target := float64(sw.sel); sw.g < target {
+				sw.g = math.Min(sw.g+sw.dg, target)
+			} else if sw.g > target {
+				sw.g = math.Max(sw.g-sw.dg, target)
+			}
+		}
+		ga, gb := math.Cos(sw.g*math.Pi/2), math.Sin(sw.g*math.Pi/2)
+		for c := 0; c < sw.chans; c++ {
+			if sw.off {
+				sw.out[i+c] = 0
+			} else {
+				sw.out[i+c] = ga*a[i+c] + gb*b[i+c]
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestABSwitch(t *testing.T) {
+	a, b := newlevel(), newlevel()
+	a.set(1)
+	b.set(-1)
+	sw := NewABSwitch(a, b)
+	sw.Select(1)
+	out := Render(sw, Dtof(50*time.Millisecond, DefaultSampleRate))
+	if x := out[len(out)-1]; !equaleps(x, -1, 1e-9) {
+		t.Fatalf("have %v after switch, want -1", x)
+	}
+
+	tp := NewTransport(120)
+	tp.Seek(1)
+	tp.Play()
+	sw.Sync(tp, 4)
+	sw.Toggle()
+	// bar line at beat 4 is 1.5s away at 120bpm.
+	out = Render(sw, Dtof(time.Second, DefaultSampleRate))
+	if x := out[len(out)-1]; !equaleps(x, -1, 1e-9) || !sw.Pending() {
+		t.Fatalf("have %v before bar line, want -1 and pending", x)
+	}
+	out = Render(sw, Dtof(time.Second, DefaultSampleRate))
+	if x := out[len(out)-1]; !equaleps(x, 1, 1e-9) || sw.Pending() {
+		t.Fatalf("have %v after bar line, want 1", x)
+	}
+}
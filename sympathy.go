@@ -0,0 +1,256 @@
+package snd
+
+import (
+	"math"
+	"math/cmplx"
+	"time"
+)
+
+// Keys of the strings of a Sympathy, those of a piano from A0 to C8.
+const (
+	sympathyLo = 21
+	sympathyHi = 108
+)
+
+// sympathyPartials is the number of partials of each string of a Sympathy.
+const sympathyPartials = 4
+
+// sympString is a string of a Sympathy, a resonator for each partial.
+type sympString struct {
+	key     int
+	hz      float64
+	res     [sympathyPartials]resonator
+	damping float64 // 0 with damper raised, 1 resting on the string
+	ringing bool    // not fully damped or ringing out
+}
+
+// Sympathy adds sympathetic resonance of the strings of a piano to in, the
+// output of an instrument. Strings whose dampers are raised ring in sympathy
+// with notes played near their partials, through tuned resonators; dampers
+// of keys held are raised, and of every string while the sustain pedal is
+// down, so a chord played with the pedal down blooms and keys held silently
+// ring when others are struck. Between up and down, the sustain pedal half
+// damps strings, which ring shorter and take up less.
+//
+// Sympathy is a Noter passing notes on to the instrument, if any, so it
+// knows the keys held; set the sustain pedal by SetSustain or Control, which
+// pass it on to the instrument if it has pedals, such as Pedals.
+type Sympathy struct {
+	*mono
+	nt      Noter
+	strings []*sympString
+	held    map[int]bool
+	level   float64 // of sustain
+	amount  float64
+	decay   time.Duration // of strings undamped
+	tuning  *Tuning
+	a4      float64 // concert pitch strings are tuned to
+}
+
+// NewSympathy returns Sympathy of in played by notes passed on to nt, which
+// may be nil, at an amount of 0.2 and strings ringing over 4s.
+func NewSympathy(in Sound, nt Noter) *Sympathy {
+	sy := &Sympathy{mono: newmono(in), nt: nt, held: make(map[int]bool), amount: 0.2, decay: 4 * time.Second, a4: 440}
+	sy.sr = in.SampleRate()
+	sy.out = make(Discrete, len(in.Samples()))
+	for key := sympathyLo; key <= sympathyHi; key++ {
+		sy.strings = append(sy.strings, &sympString{key: key, damping: 1})
+	}
+	sy.tune()
+	return sy
+}
+
+func (sy *Sympathy) Channels() int { return sy.in.Channels() }
+
+// Amount returns the level of resonance belonging to [0..1].
+func (sy *Sympathy) Amount() float64     { return sy.amount }
+func (sy *Sympathy) SetAmount(x float64) { sy.amount = math.Max(0, math.Min(1, x)) }
+
+// Decay returns the time undamped strings of A0 ring over; higher strings
+// ring shorter.
+func (sy *Sympathy) Decay() time.Duration { return sy.decay }
+
+func (sy *Sympathy) SetDecay(d time.Duration) {
+	sy.decay = d
+	sy.tune()
+}
+
+// SetTuning tunes strings by tn, such as the tuning of the instrument; a nil
+// tn tunes to equal temperament.
+func (sy *Sympathy) SetTuning(tn *Tuning) {
+	sy.tuning = tn
+	sy.tune()
+}
+
+func (sy *Sympathy) Params() []*Param {
+	return []*Param{
+		NewParam("amount", sy.Amount, sy.SetAmount).Range(0, 1, 0.2).In(UnitPercent),
+		NewParam("decay",
+			func() float64 { return sy.decay.Seconds() },
+			func(x float64) { sy.SetDecay(time.Duration(x * float64(time.Second))) }).Range(0.1, 20, 4).In(UnitSeconds),
+	}
+}
+
+func (sy *Sympathy) NoteOn(key int, vel float64) {
+	sy.held[key] = true
+	sy.damp(key)
+	if sy.nt != nil {
+		sy.nt.NoteOn(key, vel)
+	}
+}
+
+func (sy *Sympathy) NoteOff(key int) {
+	delete(sy.held, key)
+	sy.damp(key)
+	if sy.nt != nil {
+		sy.nt.NoteOff(key)
+	}
+}
+
+// Sustain reports whether the sustain pedal is down far enough to raise
+// dampers clear of all strings.
+func (sy *Sympathy) Sustain() bool { return sy.level >= halfPedalHi }
+
+// SetSustain raises dampers of all strings while down, passing the pedal on
+// to the instrument if it has a sustain pedal.
+func (sy *Sympathy) SetSustain(down bool) {
+	if down {
+		sy.SetSustainLevel(1)
+	} else {
+		sy.SetSustainLevel(0)
+	}
+}
+
+// SustainLevel returns the position of the sustain pedal belonging to [0..1].
+func (sy *Sympathy) SustainLevel() float64 { return sy.level }
+
+// SetSustainLevel sets the sustain pedal to position x belonging to [0..1],
+// from up to down, half damping strings partway as Pedals does notes, and
+// passes it on to the instrument if it has a sustain pedal, continuous or not.
+func (sy *Sympathy) SetSustainLevel(x float64) {
+	sy.level = math.Max(0, math.Min(1, x))
+	for _, s := range sy.strings {
+		sy.damp(s.key)
+	}
+	switch pd := sy.nt.(type) {
+	case interface{ SetSustainLevel(float64) }:
+		pd.SetSustainLevel(sy.level)
+	case interface{ SetSustain(bool) }:
+		pd.SetSustain(sy.Sustain())
+	}
+}
+
+// Control handles a MIDI control change of the sustain pedal as
+// SetSustainLevel, from 0 up to 127 down, passing others on to the
+// instrument if it handles control changes, and reports whether cc was
+// handled.
+func (sy *Sympathy) Control(cc, val int) bool {
+	if cc == CtrlSustain {
+		sy.SetSustainLevel(float64(val) / 127)
+		return true
+	}
+	if ct, ok := sy.nt.(interface{ Control(cc, val int) bool }); ok {
+		return ct.Control(cc, val)
+	}
+	return false
+}
+
+// damp raises or lowers the damper of the string of key.
+func (sy *Sympathy) damp(key int) {
+	if key < sympathyLo || key > sympathyHi {
+		return
+	}
+	s := sy.strings[key-sympathyLo]
+	d := halfdamping(sy.level)
+	if sy.held[key] {
+		d = 0
+	}
+	if d != s.damping {
+		s.damping = d
+		s.ringing = s.ringing || d < 1
+		sy.set(s)
+	}
+}
+
+// tune tunes all strings.
+func (sy *Sympathy) tune() {
+	for _, s := range sy.strings {
+		s.hz = tunedfreq(sy.tuning, s.key, sy.a4)
+		sy.set(s)
+	}
+}
+
+// set sets resonators of s to its partials, ringing as damped.
+func (sy *Sympathy) set(s *sympString) {
+	// strings shorten up the keyboard, ringing shorter, and dampers shorten
+	// them geometrically toward 80ms resting on the string.
+	t60 := float64(sy.decay) * math.Pow(2, -float64(s.key-sympathyLo)/24)
+	t60 *= math.Pow(float64(80*time.Millisecond)/t60, s.damping)
+	for i := range s.res {
+		n := float64(i + 1)
+		hz := s.hz * n
+		res := &s.res[i]
+		res.set(hz, time.Duration(t60/n), sy.sr)
+		if hz >= sy.sr/2 {
+			res.g = 0
+			continue
+		}
+		// unity gain at resonance for a steady tone, less for upper partials.
+		w := twopi * hz / sy.sr
+		z := cmplx.Exp(complex(0, -w))
+		if h := cmplx.Abs(complex(res.g, 0) / (1 - complex(res.a1, 0)*z - complex(res.a2, 0)*z*z)); h > 0 {
+			res.g /= h * n
+		}
+	}
+}
+
+func (sy *Sympathy) Prepare(uint64) {
+	if a4 := sy.st.concertPitch(); a4 != sy.a4 {
+		sy.a4 = a4
+		sy.tune()
+	}
+	in := sy.in.Samples()
+	chans := sy.in.Channels()
+	gain := sy.amount / sympathyPartials
+	for i := 0; i < len(sy.out); i += chans {
+		var x float64
+		for c := 0; c < chans; c++ {
+			x += in[i+c]
+		}
+		x /= float64(chans)
+		var y float64
+		for _, s := range sy.strings {
+			if !s.ringing {
+				continue
+			}
+			// dampers take up energy, those resting on strings all of it.
+			xs := x * (1 - s.damping)
+			for k := range s.res {
+				y += s.res[k].process(xs)
+			}
+		}
+		for c := 0; c < chans; c++ {
+			if sy.off {
+				sy.out[i+c] = 0
+			} else {
+				sy.out[i+c] = in[i+c] + gain*y
+			}
+		}
+	}
+	// strings damped stop ringing once quiet.
+	for _, s := range sy.strings {
+		if !s.ringing || s.damping < 1 {
+			continue
+		}
+		quiet := true
+		for k := range s.res {
+			quiet = quiet && math.Abs(s.res[k].y1)+math.Abs(s.res[k].y2) < 1e-6
+		}
+		if quiet {
+			s.ringing = false
+			for k := range s.res {
+				s.res[k].y1, s.res[k].y2 = 0, 0
+			}
+		}
+	}
+}
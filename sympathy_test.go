@@ -0,0 +1,55 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSympathy(t *testing.T) {
+	sr := DefaultSampleRate
+	// residue returns peak of output of sy less that of its input.
+	residue := func(sy *Sympathy, d time.Duration) float64 {
+		var pk float64
+		for n := Dtof(d, sr); n > 0; n -= len(sy.out) {
+			sy.in.Prepare(1)
+			sy.Prepare(1)
+			for i, x := range sy.Samples() {
+				pk = math.Max(pk, math.Abs(x-sy.in.Samples()[i]))
+			}
+		}
+		return pk
+	}
+	osc := NewOscil(Sine(), 440, nil)
+	sy := NewSympathy(osc, nil)
+	if pk := residue(sy, 100*time.Millisecond); pk != 0 {
+		t.Fatalf("have residue %v with dampers down, want 0", pk)
+	}
+	sy.NoteOn(69, 1)
+	held := residue(sy, time.Second)
+	if held < 0.01 {
+		t.Fatalf("have residue %v holding A4, want resonance", held)
+	}
+	sy.NoteOff(69)
+	sy.NoteOn(70, 1)
+	residue(sy, 500*time.Millisecond) // A4 damped
+	if pk := residue(sy, time.Second); pk > held/4 {
+		t.Fatalf("have residue %v holding A#4 out of tune, want less than %v", pk, held/4)
+	}
+	sy.NoteOff(70)
+	sy.SetSustain(true)
+	full := residue(sy, time.Second)
+	if full < held {
+		t.Fatalf("have residue %v with sustain, want more than holding A4 alone %v", full, held)
+	}
+	sy.Control(CtrlSustain, 64)
+	residue(sy, 500*time.Millisecond)
+	if pk := residue(sy, time.Second); pk == 0 || pk >= full {
+		t.Fatalf("have residue %v at half pedal, want less than %v", pk, full)
+	}
+	sy.SetSustain(false)
+	residue(sy, time.Second)
+	if pk := residue(sy, 100*time.Millisecond); pk != 0 {
+		t.Fatalf("have residue %v after damping, want 0", pk)
+	}
+}
@@ -0,0 +1,259 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Waves selectable for oscillators of a Synth by index.
+const (
+	WaveSaw = iota
+	WaveSquare
+	WaveTriangle
+	WaveSine
+)
+
+var synthWaves = []func() Discrete{Sawtooth, Square, Triangle, Sine}
+
+// SynthPresets are presets of a Synth by name, loaded by Synth.Load.
+var SynthPresets = map[string]Preset{
+	"init": {},
+	"bass": {
+		"osc1": WaveSaw, "osc2": WaveSquare, "detune": -12, "mix": 0.4,
+		"cutoff": 200, "reso": 2, "envamt": 3, "keytrack": 0.5,
+		"attack": 0.002, "decay": 0.3, "sustain": 0.6, "release": 0.08,
+		"fattack": 0.001, "fdecay": 0.2, "fsustain": 0.1, "frelease": 0.08,
+	},
+	"lead": {
+		"osc1": WaveSaw, "osc2": WaveSaw, "detune": 0.08, "mix": 0.5,
+		"cutoff": 1200, "reso": 1.5, "envamt": 2, "keytrack": 1,
+		"attack": 0.01, "decay": 0.2, "sustain": 0.8, "release": 0.2,
+		"fattack": 0.01, "fdecay": 0.4, "fsustain": 0.4, "frelease": 0.2,
+		"lforate": 5.5, "lfopitch": 0.15,
+	},
+	"pad": {
+		"osc1": WaveSaw, "osc2": WaveSaw, "detune": 0.12, "mix": 0.5,
+		"cutoff": 800, "reso": 0.8, "envamt": 1.5, "keytrack": 0.5,
+		"attack": 0.8, "decay": 1, "sustain": 0.8, "release": 1.5,
+		"fattack": 1.5, "fdecay": 2, "fsustain": 0.5, "frelease": 1.5,
+		"lforate": 0.3, "lfocut": 0.5,
+	},
+	"brass": {
+		"osc1": WaveSaw, "osc2": WaveSaw, "detune": 0.05, "mix": 0.5,
+		"cutoff": 500, "reso": 1, "envamt": 3, "keytrack": 0.7,
+		"attack": 0.05, "decay": 0.3, "sustain": 0.8, "release": 0.2,
+		"fattack": 0.08, "fdecay": 0.5, "fsustain": 0.5, "frelease": 0.2,
+	},
+	"pluck": {
+		"osc1": WaveSquare, "osc2": WaveSaw, "detune": 12, "mix": 0.3,
+		"cutoff": 300, "reso": 3, "envamt": 4, "keytrack": 1,
+		"attack": 0.001, "decay": 0.6, "sustain": 0, "release": 0.3,
+		"fattack": 0.001, "fdecay": 0.25, "fsustain": 0, "frelease": 0.3,
+	},
+}
+
+// synthParams are settings shared by all voices of a Synth.
+type synthParams struct {
+	wave1, wave2 int
+	detune       float64 // semitones of osc2
+	mix          float64 // of osc2, belonging to [0..1]
+
+	cutoff   float64 // hz
+	reso     float64 // q
+	envamt   float64 // octaves of cutoff swept by filter envelope
+	keytrack float64 // belonging to [0..1]
+
+	amp, filt [4]float64 // attack, decay, and release seconds with sustain level third
+
+	lforate, lfopitch, lfocut float64 // hz, semitones, and octaves
+}
+
+// Synth is a classic subtractive polyphonic synthesizer. Each voice mixes two
+// oscillators into a resonant lowpass filter, shaped by an amplitude envelope
+// and a filter envelope, with an LFO modulating pitch and cutoff. Velocity
+// scales amplitude.
+//
+// All settings are params, so Synth loads presets such as those of
+// SynthPresets.
+type Synth struct {
+	*Poly
+	sp     synthParams
+	tables [4]Discrete
+}
+
+// NewSynth returns Synth of voices with the "init" preset, a single
+// sawtooth through an open filter.
+func NewSynth(voices int) *Synth {
+	syn := &Synth{}
+	for i, fn := range synthWaves {
+		syn.tables[i] = fn()
+	}
+	syn.Poly = NewPoly(voices, func() Voice { return newSynthVoice(syn) })
+	syn.Poly.SetGain(VoiceGain(voices, CrestSaw, DefaultHeadroom))
+	syn.Load(SynthPresets["init"])
+	return syn
+}
+
+// synthDefaults are param values of the "init" preset, used for any param a
+// preset leaves out.
+var synthDefaults = Preset{
+	"osc1": WaveSaw, "osc2": WaveSaw, "detune": 0, "mix": 0,
+	"cutoff": 8000, "reso": 0.707, "envamt": 0, "keytrack": 0,
+	"attack": 0.005, "decay": 0.1, "sustain": 1, "release": 0.1,
+	"fattack": 0.005, "fdecay": 0.1, "fsustain": 1, "frelease": 0.1,
+	"lforate": 5, "lfopitch": 0, "lfocut": 0,
+}
+
+// Load sets params from pre, with params it leaves out set as in the "init"
+// preset so that presets sound the same whatever was loaded before.
+func (syn *Synth) Load(pre Preset) error {
+	var ps Params
+	for _, p := range syn.Params() {
+		ps.Add(p)
+	}
+	full := make(Preset, len(synthDefaults))
+	for name, x := range synthDefaults {
+		full[name] = x
+	}
+	for name, x := range pre {
+		full[name] = x
+	}
+	return ps.Load(full)
+}
+
+// Params returns oscillator waves "osc1" and "osc2" by index such as WaveSaw,
+// "detune" of osc2 in semitones, "mix" of osc2, filter "cutoff" in hertz,
+// "reso" as q, "envamt" in octaves, "keytrack", amplitude envelope "attack",
+// "decay", "sustain", and "release", filter envelope of the same prefixed by
+// "f", and "lforate" in hertz modulating "lfopitch" in semitones and "lfocut"
+// in octaves. Times are in seconds.
+func (syn *Synth) Params() []*Param {
+	sp := &syn.sp
+	wave := func(name string, w *int) *Param {
+		return NewParam(name,
+			func() float64 { return float64(*w) },
+			func(x float64) {
+				if i := int(x); i >= 0 && i < len(synthWaves) {
+					*w = i
+				}
+			})
+	}
+	num := func(name string, x *float64) *Param {
+		return NewParam(name, func() float64 { return *x }, func(v float64) { *x = v })
+	}
+	env := func(name string, x *float64) *Param {
+		return NewParam(name, func() float64 { return *x }, func(v float64) {
+			*x = v
+			syn.build()
+		})
+	}
+	return []*Param{
+		wave("osc1", &sp.wave1).Range(0, 3, synthDefaults["osc1"]),
+		wave("osc2", &sp.wave2).Range(0, 3, synthDefaults["osc2"]),
+		num("detune", &sp.detune).Range(-24, 24, synthDefaults["detune"]).In(UnitSemitones),
+		num("mix", &sp.mix).Range(0, 1, synthDefaults["mix"]).In(UnitPercent),
+		num("cutoff", &sp.cutoff).Range(20, 20000, synthDefaults["cutoff"]).In(UnitHz),
+		num("reso", &sp.reso).Range(0.5, 20, synthDefaults["reso"]),
+		num("envamt", &sp.envamt).Range(-8, 8, synthDefaults["envamt"]),
+		num("keytrack", &sp.keytrack).Range(0, 1, synthDefaults["keytrack"]),
+		env("attack", &sp.amp[0]).Range(0, 10, synthDefaults["attack"]).In(UnitSeconds),
+		env("decay", &sp.amp[1]).Range(0, 10, synthDefaults["decay"]).In(UnitSeconds),
+		env("sustain", &sp.amp[2]).Range(0, 1, synthDefaults["sustain"]).In(UnitPercent),
+		env("release", &sp.amp[3]).Range(0, 10, synthDefaults["release"]).In(UnitSeconds),
+		env("fattack", &sp.filt[0]).Range(0, 10, synthDefaults["fattack"]).In(UnitSeconds),
+		env("fdecay", &sp.filt[1]).Range(0, 10, synthDefaults["fdecay"]).In(UnitSeconds),
+		env("fsustain", &sp.filt[2]).Range(0, 1, synthDefaults["fsustain"]).In(UnitPercent),
+		env("frelease", &sp.filt[3]).Range(0, 10, synthDefaults["frelease"]).In(UnitSeconds),
+		num("lforate", &sp.lforate).Range(0.01, 20, synthDefaults["lforate"]).In(UnitHz),
+		num("lfopitch", &sp.lfopitch).Range(-12, 12, synthDefaults["lfopitch"]).In(UnitSemitones),
+		num("lfocut", &sp.lfocut).Range(-4, 4, synthDefaults["lfocut"]),
+	}
+}
+
+// build rebuilds envelopes of all voices from params.
+func (syn *Synth) build() {
+	for _, vc := range syn.Voices() {
+		vc := vc.(*synthVoice)
+		setenv(vc.aenv, syn.sp.amp)
+		setenv(vc.fenv, syn.sp.filt)
+	}
+}
+
+// setenv sets attack, decay, sustain level, and release of env from x.
+func setenv(env *ADSR, x [4]float64) {
+	sec := func(s float64) time.Duration { return time.Duration(s * float64(time.Second)) }
+	env.atk, env.dcy, env.susamp, env.rel = sec(x[0]), sec(x[1]), x[2], sec(x[3])
+	env.build()
+}
+
+// synthVoice is a Voice of a Synth.
+type synthVoice struct {
+	*mono
+	syn        *Synth
+	gate       *level
+	aenv, fenv *ADSR
+	hz, vel    float64
+	p1, p2     float64 // phases of oscillators
+	f          svf
+	lfo        lfo
+}
+
+func newSynthVoice(syn *Synth) *synthVoice {
+	const ms = time.Millisecond
+	vc := &synthVoice{
+		mono: newmono(nil),
+		syn:  syn,
+		gate: newlevel(),
+		aenv: NewADSR(5*ms, 100*ms, ms, 100*ms, 1, 1, nil),
+		fenv: NewADSR(5*ms, 100*ms, ms, 100*ms, 1, 1, nil),
+		lfo:  lfo{shape: Sine()},
+	}
+	vc.aenv.SetGate(vc.gate)
+	vc.fenv.SetGate(vc.gate)
+	return vc
+}
+
+func (vc *synthVoice) Inputs() []Sound { return []Sound{vc.aenv, vc.fenv} }
+
+func (vc *synthVoice) NoteOn(hz, vel float64) {
+	vc.hz, vc.vel = hz, vel
+	vc.gate.set(1)
+}
+
+func (vc *synthVoice) NoteOff()           { vc.gate.set(0) }
+func (vc *synthVoice) Done() bool         { return vc.gate.x == 0 && vc.aenv.Idle() }
+func (vc *synthVoice) SetFreq(hz float64) { vc.hz = hz }
+
+func (vc *synthVoice) Prepare(uint64) {
+	if vc.off || vc.Done() {
+		for i := range vc.out {
+			vc.out[i] = 0
+		}
+		return
+	}
+	sp := &vc.syn.sp
+	t1, t2 := vc.syn.tables[sp.wave1], vc.syn.tables[sp.wave2]
+	det := math.Pow(2, sp.detune/12)
+	// cutoff follows key relative to middle C.
+	track := math.Pow(vc.hz/261.63, sp.keytrack)
+	vc.lfo.rate = sp.lforate
+	for i := range vc.out {
+		m := vc.lfo.next(vc.sr)
+		hz := vc.hz
+		if sp.lfopitch != 0 {
+			hz *= math.Pow(2, m*sp.lfopitch/12)
+		}
+		x := (1-sp.mix)*t1.At(vc.p1) + sp.mix*t2.At(vc.p2)
+		vc.p1 += hz / vc.sr
+		vc.p2 += hz * det / vc.sr
+		vc.p1 -= math.Floor(vc.p1)
+		vc.p2 -= math.Floor(vc.p2)
+
+		// filter coefficients are updated every few frames to save work.
+		if i%8 == 0 {
+			oct := sp.envamt*vc.fenv.Index(i) + sp.lfocut*m
+			vc.f.set(sp.cutoff*track*math.Pow(2, oct), math.Max(0.1, sp.reso), vc.sr)
+		}
+		vc.out[i] = vc.vel * vc.aenv.Index(i) * vc.f.filter(x, FilterLowPass)
+	}
+}
@@ -0,0 +1,44 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSynthPresets(t *testing.T) {
+	sr := DefaultSampleRate
+	for name, pre := range SynthPresets {
+		syn := NewSynth(4)
+		if err := syn.Load(pre); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		syn.NoteOn(48, 1)
+		syn.NoteOn(55, 1)
+		syn.NoteOn(64, 1)
+		out := Render(syn, Dtof(time.Second, sr))
+		if pk := Peak(out); pk < 0.02 || pk > 1 {
+			t.Fatalf("%s: have peak %v, want sound without clipping", name, pk)
+		}
+		for _, key := range []int{48, 55, 64} {
+			syn.NoteOff(key)
+		}
+		Render(syn, Dtof(3*time.Second, sr))
+		if n := syn.Active(); n != 0 {
+			t.Fatalf("%s: have %v voices active after release, want 0", name, n)
+		}
+	}
+}
+
+func TestSynthFilter(t *testing.T) {
+	sr := DefaultSampleRate
+	harmonic := func(cutoff float64) float64 {
+		syn := NewSynth(1)
+		syn.Load(Preset{"cutoff": cutoff})
+		syn.NoteOn(45, 1) // 110Hz
+		out := Render(syn, Dtof(200*time.Millisecond, sr))
+		return goertzel(out[len(out)/2:], 1100, sr) / goertzel(out[len(out)/2:], 110, sr)
+	}
+	if open, closed := harmonic(8000), harmonic(300); closed > open/10 {
+		t.Fatalf("have tenth harmonic %v closed and %v open, want filtered", closed, open)
+	}
+}
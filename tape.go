@@ -0,0 +1,143 @@
+package snd
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Tape emulates recording to analog tape with soft saturation, pitch drift of
+// wow and flutter, high frequency rolloff, and optional hiss, in that order.
+// Tape processes every channel of its input and is suitable as a Master insert.
+type Tape struct {
+	*mono
+	interpolators
+	chans int
+
+	drive        float64
+	wow, flutter lfo
+	wowd, flutd  float64 // frames of delay swing
+	lines        [][]float64
+	w            int
+
+	rolloff float64
+	svfs    []svf
+
+	hiss float64
+	rnd  *rand.Rand
+}
+
+// maxTapeDrift is the largest delay swing of each of wow and flutter.
+const maxTapeDrift = 5 * time.Millisecond
+
+// NewTape returns Tape saturating in by drive, where a drive of 1 is subtle
+// and higher values are increasingly dirty. Wow and flutter default to a slight
+// drift, rolloff to 12kHz, and hiss to off.
+func NewTape(drive float64, in Sound) *Tape {
+	sd := newmono(in)
+	sd.out = make(Discrete, len(in.Samples()))
+	tp := &Tape{
+		mono:    sd,
+		chans:   in.Channels(),
+		drive:   drive,
+		wow:     lfo{shape: Sine(), rate: 0.5},
+		flutter: lfo{shape: Sine(), rate: 7.3},
+		rnd:     rand.New(rand.NewSource(1)),
+	}
+	n := 2*Dtof(2*maxTapeDrift, sd.sr) + 4
+	tp.lines = make([][]float64, tp.chans)
+	for i := range tp.lines {
+		tp.lines[i] = make([]float64, n)
+	}
+	tp.svfs = make([]svf, tp.chans)
+	tp.interpolators = newreaders(tp.chans)
+	tp.SetWow(500 * time.Microsecond)
+	tp.SetFlutter(50 * time.Microsecond)
+	tp.SetRolloff(12000)
+	return tp
+}
+
+func (tp *Tape) Channels() int { return tp.chans }
+
+func (tp *Tape) Drive() float64     { return tp.drive }
+func (tp *Tape) SetDrive(x float64) { tp.drive = x }
+
+// SetWow sets the delay swing of slow drift, limited to 5ms.
+func (tp *Tape) SetWow(d time.Duration) { tp.wowd = tp.swing(d) }
+
+// SetFlutter sets the delay swing of fast drift, limited to 5ms.
+func (tp *Tape) SetFlutter(d time.Duration) { tp.flutd = tp.swing(d) }
+
+func (tp *Tape) swing(d time.Duration) float64 {
+	if d > maxTapeDrift {
+		d = maxTapeDrift
+	} else if d < 0 {
+		d = 0
+	}
+	return d.Seconds() * tp.sr
+}
+
+func (tp *Tape) Rolloff() float64 { return tp.rolloff }
+
+// SetRolloff sets cutoff frequency of high frequency loss.
+func (tp *Tape) SetRolloff(hz float64) {
+	tp.rolloff = hz
+	for i := range tp.svfs {
+		tp.svfs[i].set(hz, 0.707, tp.sr)
+	}
+}
+
+func (tp *Tape) Hiss() float64 { return tp.hiss }
+
+// SetHiss sets amplitude of hiss added, e.g. Decibel(-60).Amp(); zero is off.
+func (tp *Tape) SetHiss(amp float64) { tp.hiss = amp }
+
+// Params returns drive, wow and flutter in milliseconds, rolloff in hertz,
+// and hiss amplitude.
+func (tp *Tape) Params() []*Param {
+	ms := func(x *float64) (func() float64, func(float64)) {
+		return func() float64 { return *x / tp.sr * 1000 },
+			func(v float64) { *x = tp.swing(time.Duration(v * float64(time.Millisecond))) }
+	}
+	wget, wset := ms(&tp.wowd)
+	fget, fset := ms(&tp.flutd)
+	return []*Param{
+		NewParam("drive", tp.Drive, tp.SetDrive).Range(0, 10, 1),
+		NewParam("wow", wget, wset).Range(0, 10, 1).In(UnitMilliseconds),
+		NewParam("flutter", fget, fset).Range(0, 1, 0.1).In(UnitMilliseconds),
+		NewParam("rolloff", tp.Rolloff, tp.SetRolloff).Range(1000, 20000, 12000).In(UnitHz),
+		NewParam("hiss", tp.Hiss, tp.SetHiss).Range(0, 0.1, 0),
+	}
+}
+
+func (tp *Tape) Prepare(uint64) {
+	tp.limit(tp.st)
+	in := tp.in.Samples()
+	for i := 0; i < len(in); i += tp.chans {
+		// all channels drift together as on a single tape.
+		d := 1 + tp.wowd*(1+tp.wow.next(tp.sr)) + tp.flutd*(1+tp.flutter.next(tp.sr))
+		for c := 0; c < tp.chans; c++ {
+			line := tp.lines[c]
+			line[tp.w] = in[i+c]
+			x := tp.interpolators[c].read(line, tp.w, d)
+
+			if tp.drive > 0 {
+				x = math.Tanh(tp.drive*x) / tp.drive
+			}
+			x = tp.svfs[c].filter(x, FilterLowPass)
+			if tp.hiss > 0 {
+				x += tp.hiss * (2*tp.rnd.Float64() - 1)
+			}
+
+			if tp.off {
+				tp.out[i+c] = 0
+			} else {
+				tp.out[i+c] = x
+			}
+		}
+		tp.w++
+		if tp.w == len(tp.lines[0]) {
+			tp.w = 0
+		}
+	}
+}
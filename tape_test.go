@@ -0,0 +1,43 @@
+package snd
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTape(t *testing.T) {
+	n := Dtof(time.Second, DefaultSampleRate)
+
+	// saturation limits peaks while leaving quiet signals near unity.
+	for _, tt := range []struct{ amp, min, max float64 }{
+		{0.1, 0.09, 0.11},
+		{4, 0.4, 0.6},
+	} {
+		tp := NewTape(2, NewGain(tt.amp, NewOscil(Sine(), 441, nil)))
+		if x := Peak(Render(tp, n)[n/2:]); x < tt.min || x > tt.max {
+			t.Errorf("amp %v: have peak %.3f, want [%v..%v]", tt.amp, x, tt.min, tt.max)
+		}
+	}
+
+	// rolloff attenuates highs.
+	tp := NewTape(0, NewOscil(Sine(), 15000, nil))
+	tp.SetRolloff(3000)
+	if x := Peak(Render(tp, n)[n/2:]); x > 0.1 {
+		t.Errorf("have peak %.3f above rolloff, want < 0.1", x)
+	}
+
+	// hiss on silence, stereo preserved.
+	tp = NewTape(1, NewPan(0, NewGain(0, newunit())))
+	tp.SetHiss(Decibel(-60).Amp())
+	out := Render(tp, n)
+	if tp.Channels() != 2 {
+		t.Fatalf("have %v channels, want 2", tp.Channels())
+	}
+	if x := Peak(out); x == 0 || x > Decibel(-50).Amp() {
+		t.Errorf("have hiss peak %v, want about -60dB", x)
+	}
+	if math.IsNaN(out[len(out)-1]) {
+		t.Fatal("NaN output")
+	}
+}
@@ -0,0 +1,294 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ToneStep is a step of a Cadence: tones of Freqs mixed over Dur, silent of
+// no Freqs, and holding until stopped of Dur zero.
+type ToneStep struct {
+	Freqs []float64 // Hz
+	Dur   time.Duration
+}
+
+// Cadence is a sequence of tones of telephony, such as a busy signal or a
+// number dialed, repeating if Repeat.
+type Cadence struct {
+	Name   string
+	Steps  []ToneStep
+	Gain   Decibel // of each tone
+	Repeat bool
+}
+
+// tone returns a step of freqs over ms milliseconds.
+func tone(ms int, freqs ...float64) ToneStep {
+	return ToneStep{Freqs: freqs, Dur: time.Duration(ms) * time.Millisecond}
+}
+
+// Cadences of call progress by the North American Precise Tone Plan, and of
+// Europe by ITU-T E.180 at 425Hz.
+var (
+	CadenceDial     = Cadence{Name: "dial", Steps: []ToneStep{tone(0, 350, 440)}, Gain: -13}
+	CadenceRingback = Cadence{Name: "ringback", Steps: []ToneStep{tone(2000, 440, 480), tone(4000)}, Gain: -19, Repeat: true}
+	CadenceBusy     = Cadence{Name: "busy", Steps: []ToneStep{tone(500, 480, 620), tone(500)}, Gain: -24, Repeat: true}
+	CadenceReorder  = Cadence{Name: "reorder", Steps: []ToneStep{tone(250, 480, 620), tone(250)}, Gain: -24, Repeat: true}
+	CadenceWaiting  = Cadence{Name: "waiting", Steps: []ToneStep{tone(300, 440)}, Gain: -13}
+
+	CadenceDialEU     = Cadence{Name: "dial", Steps: []ToneStep{tone(0, 425)}, Gain: -13}
+	CadenceRingbackEU = Cadence{Name: "ringback", Steps: []ToneStep{tone(1000, 425), tone(4000)}, Gain: -13, Repeat: true}
+	CadenceBusyEU     = Cadence{Name: "busy", Steps: []ToneStep{tone(500, 425), tone(500)}, Gain: -13, Repeat: true}
+
+	// CadenceSIT is the special information tone preceding an intercept
+	// message, such as of a number disconnected.
+	CadenceSIT = Cadence{Name: "sit", Steps: []ToneStep{tone(274, 913.8), tone(274, 1370.6), tone(380, 1776.7), tone(4000)}, Gain: -13}
+)
+
+// DTMF keys by row and column.
+var dtmfKeys = [4]string{"123A", "456B", "789C", "*0#D"}
+
+// Frequencies of DTMF of rows and of columns in Hz.
+var (
+	dtmfRows = [4]float64{697, 770, 852, 941}
+	dtmfCols = [4]float64{1209, 1336, 1477, 1633}
+)
+
+// DTMFFreqs returns frequencies of the row and column of digit, such as
+// '5' of 770Hz and 1336Hz, and false if digit is not of DTMF.
+func DTMFFreqs(digit byte) (lo, hi float64, ok bool) {
+	for i, row := range dtmfKeys {
+		if j := strings.IndexByte(row, digit); j >= 0 {
+			return dtmfRows[i], dtmfCols[j], true
+		}
+	}
+	return 0, 0, false
+}
+
+// DTMFCadence returns a cadence dialing digits of DTMF, each sounding over on
+// and followed by silence over off, commonly 70ms each and no less than 40ms.
+// A comma pauses for two seconds, as of dialers.
+func DTMFCadence(digits string, on, off time.Duration) (Cadence, error) {
+	c := Cadence{Name: "dtmf", Gain: -9}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] == ',' {
+			c.Steps = append(c.Steps, ToneStep{Dur: 2 * time.Second})
+			continue
+		}
+		lo, hi, ok := DTMFFreqs(digits[i])
+		if !ok {
+			return Cadence{}, fmt.Errorf("snd: undefined DTMF digit %q", digits[i])
+		}
+		c.Steps = append(c.Steps, ToneStep{Freqs: []float64{lo, hi}, Dur: on}, ToneStep{Dur: off})
+	}
+	return c, nil
+}
+
+// toneRamp is how long tones rise and fall, not to click.
+const toneRamp = time.Millisecond
+
+// Tones plays a Cadence of telephony as a Sound of a single channel.
+type Tones struct {
+	*mono
+	c       Cadence
+	playing bool
+	step    int
+	at      int // frames into step
+	phases  []float64
+}
+
+// NewTones returns Tones silent at the default sample rate.
+func NewTones() *Tones { return &Tones{mono: newmono(nil)} }
+
+func (ts *Tones) Inputs() []Sound { return nil }
+
+// Play plays c from its start, cutting off any playing.
+func (ts *Tones) Play(c Cadence) {
+	ts.c, ts.playing, ts.step, ts.at = c, len(c.Steps) != 0, 0, 0
+}
+
+func (ts *Tones) Stop() { ts.playing = false }
+
+// Playing reports whether a cadence is playing, false once a cadence not
+// repeating is done.
+func (ts *Tones) Playing() bool { return ts.playing }
+
+// next returns the next frame of the cadence.
+func (ts *Tones) next() float64 {
+	if !ts.playing {
+		return 0
+	}
+	st := ts.c.Steps[ts.step]
+	n := Dtof(st.Dur, ts.sr)
+	if st.Dur != 0 && ts.at >= n {
+		ts.step, ts.at = ts.step+1, 0
+		if ts.step == len(ts.c.Steps) {
+			if !ts.c.Repeat {
+				ts.playing = false
+				return 0
+			}
+			ts.step = 0
+		}
+		return ts.next()
+	}
+	if ts.at == 0 {
+		// tones start at zero phase, not to click.
+		ts.phases = append(ts.phases[:0], make([]float64, len(st.Freqs))...)
+	}
+	var x float64
+	for i, hz := range st.Freqs {
+		x += math.Sin(twopi * ts.phases[i])
+		ts.phases[i] += hz / ts.sr
+		ts.phases[i] -= math.Floor(ts.phases[i])
+	}
+	ramp := float64(Dtof(toneRamp, ts.sr))
+	g := math.Min(1, float64(ts.at+1)/ramp)
+	if st.Dur != 0 {
+		g = math.Min(g, float64(n-ts.at)/ramp)
+	}
+	ts.at++
+	return g * ts.c.Gain.Amp() * x
+}
+
+func (ts *Tones) Prepare(uint64) {
+	for i := range ts.out {
+		x := ts.next()
+		if ts.off {
+			x = 0
+		}
+		ts.out[i] = x
+	}
+}
+
+// dtmfBlock is the length of blocks DTMFDecoder detects over, resolving 50Hz
+// apart, finer than the 73Hz between the nearest frequencies of DTMF.
+const dtmfBlock = 20 * time.Millisecond
+
+// DTMFDecoder passes its input through, detecting digits of DTMF in it by the
+// Goertzel algorithm over blocks of 20ms. A digit is detected once both of its
+// tones dominate two blocks in a row, within 8dB of each other, and again only
+// after a block without it; tones of 40ms, the least of the standard, may be
+// missed, while those of 50ms or more are not.
+type DTMFDecoder struct {
+	*mono
+	block      int
+	coefs      [8]float64
+	s1, s2     [8]float64
+	energy     float64
+	n          int
+	floor      float64 // of mean power
+	last, held byte    // digit of the last block, and digit detected held
+	count      int     // of blocks in a row of last
+
+	mu      sync.Mutex
+	digits  []byte
+	ondigit func(byte)
+}
+
+// NewDTMFDecoder returns DTMFDecoder of in, ignoring tones quieter than
+// -40dB.
+func NewDTMFDecoder(in Sound) *DTMFDecoder {
+	dd := &DTMFDecoder{mono: newmono(in), floor: math.Pow(Decibel(-40).Amp(), 2) / 2}
+	dd.block = Dtof(dtmfBlock, dd.sr)
+	for i := 0; i < 4; i++ {
+		dd.coefs[i] = 2 * math.Cos(twopi*dtmfRows[i]/dd.sr)
+		dd.coefs[4+i] = 2 * math.Cos(twopi*dtmfCols[i]/dd.sr)
+	}
+	return dd
+}
+
+// SetOnDigit sets fn called with each digit detected, on the audio thread.
+func (dd *DTMFDecoder) SetOnDigit(fn func(digit byte)) {
+	dd.mu.Lock()
+	dd.ondigit = fn
+	dd.mu.Unlock()
+}
+
+// Digits returns digits detected so far, in order.
+func (dd *DTMFDecoder) Digits() string {
+	dd.mu.Lock()
+	defer dd.mu.Unlock()
+	return string(dd.digits)
+}
+
+// Reset forgets digits detected.
+func (dd *DTMFDecoder) Reset() {
+	dd.mu.Lock()
+	dd.digits = dd.digits[:0]
+	dd.mu.Unlock()
+}
+
+// detect returns the digit dominating the block just ended, or zero.
+func (dd *DTMFDecoder) detect() byte {
+	n := float64(dd.n)
+	mean := dd.energy / n
+	if mean < dd.floor {
+		return 0
+	}
+	// power of each tone as of its mean power, that of a sine of amplitude a
+	// being a*a/2.
+	var pow [8]float64
+	for i := range pow {
+		s1, s2 := dd.s1[i], dd.s2[i]
+		pow[i] = (s1*s1 + s2*s2 - dd.coefs[i]*s1*s2) * 2 / (n * n)
+	}
+	best := func(ps []float64) (int, bool) {
+		k := 0
+		for i := range ps {
+			if ps[i] > ps[k] {
+				k = i
+			}
+		}
+		// others of the group at least 8dB down.
+		for i := range ps {
+			if i != k && ps[i]*6.3 > ps[k] {
+				return k, false
+			}
+		}
+		return k, true
+	}
+	r, rok := best(pow[:4])
+	c, cok := best(pow[4:])
+	pr, pc := pow[r], pow[4+c]
+	if !rok || !cok || pr+pc < 0.7*mean || pr > 6.3*pc || pc > 6.3*pr {
+		return 0
+	}
+	return dtmfKeys[r][c]
+}
+
+func (dd *DTMFDecoder) Prepare(uint64) {
+	for i, x := range dd.in.Samples() {
+		for j, c := range dd.coefs {
+			dd.s1[j], dd.s2[j] = x+c*dd.s1[j]-dd.s2[j], dd.s1[j]
+		}
+		dd.energy += x * x
+		if dd.n++; dd.n == dd.block {
+			d := dd.detect()
+			if d == dd.last {
+				dd.count++
+			} else {
+				dd.last, dd.count = d, 1
+			}
+			if d == 0 {
+				dd.held = 0
+			} else if d != dd.held && dd.count >= 2 {
+				dd.held = d
+				dd.mu.Lock()
+				dd.digits = append(dd.digits, d)
+				fn := dd.ondigit
+				dd.mu.Unlock()
+				if fn != nil {
+					fn(d)
+				}
+			}
+			dd.s1, dd.s2, dd.energy, dd.n = [8]float64{}, [8]float64{}, 0, 0
+		}
+		if dd.off {
+			dd.out[i] = 0
+		} else {
+			dd.out[i] = x
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTones(t *testing.T) {
+	ts := NewTones()
+	ts.Play(CadenceBusy)
+	n := Dtof(500*time.Millisecond, ts.SampleRate())
+	out := Render(ts, 4*n)
+	if on, off := Peak(out[n/4:3*n/4]), Peak(out[5*n/4:7*n/4]); on < CadenceBusy.Gain.Amp() || off != 0 {
+		t.Errorf("have peak %v on and %v off, want at least %v and silent", on, off, CadenceBusy.Gain.Amp())
+	}
+	if Peak(out[9*n/4:11*n/4]) == 0 || !ts.Playing() {
+		t.Error("have busy silent after a second, want repeating")
+	}
+
+	ts.Play(CadenceWaiting)
+	Render(ts, n)
+	if ts.Playing() {
+		t.Error("have waiting playing after 500ms, want done")
+	}
+}
+
+func TestDTMF(t *testing.T) {
+	if lo, hi, ok := DTMFFreqs('5'); !ok || lo != 770 || hi != 1336 {
+		t.Errorf("have 5 of %v %v %v, want 770 1336 true", lo, hi, ok)
+	}
+	if _, err := DTMFCadence("12x", 70*time.Millisecond, 70*time.Millisecond); err == nil {
+		t.Error("have no error of undefined digit")
+	}
+
+	const digits = "159#*0AD77"
+	c, err := DTMFCadence(digits, 70*time.Millisecond, 70*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := NewTones()
+	ts.Play(c)
+	hum := NewOscil(Sine(), 60, nil)
+	hum.SetAmp(0.05, nil)
+	dd := NewDTMFDecoder(NewMixer(ts, hum))
+	var called []byte
+	dd.SetOnDigit(func(d byte) { called = append(called, d) })
+	Render(dd, Dtof(1500*time.Millisecond, dd.SampleRate()))
+	if have := dd.Digits(); have != digits {
+		t.Errorf("have digits %q, want %q", have, digits)
+	}
+	if string(called) != digits {
+		t.Errorf("have called with %q, want %q", called, digits)
+	}
+
+	// a chord of other tones is no digit.
+	dd = NewDTMFDecoder(NewMixer(NewOscil(Sine(), 697, nil), NewOscil(Sine(), 1000, nil)))
+	Render(dd, Dtof(500*time.Millisecond, dd.SampleRate()))
+	if have := dd.Digits(); have != "" {
+		t.Errorf("have digits %q of no DTMF, want none", have)
+	}
+}
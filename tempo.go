@@ -0,0 +1,241 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// Transport tracks tempo and musical position while playing. Output is a
+// trigger on every beat so a Transport may be used anywhere a clock is.
+//
+// Tempo may be set directly or follow an external source of triggers, such as
+// an audio click passed through Threshold or pulses derived from MIDI clock.
+type Transport struct {
+	*mono
+	bpm     BPM
+	beat    float64 // position in beats
+	frame   uint64  // frames played
+	playing bool
+
+	// beat is counted from an anchor, moved on any change of tempo or
+	// position, rather than summed each frame, so it does not drift.
+	anchorbeat  float64
+	anchorframe uint64
+
+	ext    Sound
+	extppq int
+	edge
+	extlast  uint64
+	extbeats int
+}
+
+// NewTransport returns a stopped Transport at tempo bpm.
+func NewTransport(bpm BPM) *Transport {
+	return &Transport{mono: newmono(nil), bpm: bpm}
+}
+
+func (tp *Transport) Inputs() []Sound { return []Sound{tp.ext} }
+
+func (tp *Transport) BPM() BPM { return tp.bpm }
+
+func (tp *Transport) SetBPM(bpm BPM) {
+	tp.anchor()
+	tp.bpm = bpm
+}
+
+// Play starts or continues from the current position.
+func (tp *Transport) Play() { tp.playing = true }
+
+// Stop pauses at the current position.
+func (tp *Transport) Stop() { tp.playing = false }
+
+func (tp *Transport) Playing() bool { return tp.playing }
+
+// Beat returns position in beats.
+func (tp *Transport) Beat() float64 { return tp.beat }
+
+// Frame returns the number of frames played.
+func (tp *Transport) Frame() uint64 { return tp.frame }
+
+// Seek sets position in beats.
+func (tp *Transport) Seek(beat float64) {
+	tp.beat = beat
+	tp.anchor()
+}
+
+// anchor counts beats on from the current position.
+func (tp *Transport) anchor() { tp.anchorbeat, tp.anchorframe = tp.beat, tp.frame }
+
+// Follow slaves tempo and phase to triggers of ext arriving ppq times per beat.
+// Position is advanced by whole pulses on each trigger and tempo is measured
+// from the interval between triggers. A nil ext returns to internal tempo.
+func (tp *Transport) Follow(ext Sound, ppq int) {
+	tp.ext, tp.extppq = ext, ppq
+	tp.extlast, tp.extbeats = 0, 0
+}
+
+func (tp *Transport) Prepare(uint64) {
+	for i := range tp.out {
+		tp.out[i] = 0
+		if tp.ext != nil {
+			if rise, _ := tp.step(tp.ext.Index(i)); rise && tp.playing {
+				tp.pulse()
+			}
+		}
+		if !tp.playing {
+			continue
+		}
+
+		// trigger on the first frame at or after a beat boundary; eps
+		// absorbs round-off accumulated from summing fractional beats.
+		const eps = 1e-9
+		step := float64(tp.bpm) / (60 * tp.sr)
+		if math.Floor(tp.beat+eps) != math.Floor(tp.beat-step+eps) && !tp.off {
+			tp.out[i] = 1
+		}
+		tp.frame++
+		tp.beat = tp.anchorbeat + float64(FrameSince(tp.frame, tp.anchorframe))*step
+	}
+}
+
+// pulse handles an external clock pulse.
+func (tp *Transport) pulse() {
+	if tp.extlast != 0 {
+		if n := FrameSince(tp.frame, tp.extlast); n > 0 {
+			tp.bpm = BPM(60 * tp.sr / float64(n*int64(tp.extppq)))
+		}
+		// snap phase to pulse grid.
+		tp.beat = math.Round(tp.beat*float64(tp.extppq)) / float64(tp.extppq)
+		tp.anchor()
+	}
+	tp.extlast = tp.frame
+}
+
+// Metronome clicks on each beat of a Transport, accenting the first beat of
+// each bar, such as to count in before recording.
+type Metronome struct {
+	*mono
+	tp     *Transport
+	bar    int
+	until  float64
+	phase  float64
+	freq   float64
+	amp    float64
+	decay  float64
+	accent float64
+}
+
+// NewMetronome returns Metronome of tp with beatsPerBar beats to a bar.
+func NewMetronome(tp *Transport, beatsPerBar int) *Metronome {
+	m := &Metronome{mono: newmono(nil), tp: tp, bar: beatsPerBar, until: math.Inf(1), accent: 1}
+	m.decay = smoothcoef(20*time.Millisecond, m.sr)
+	return m
+}
+
+func (m *Metronome) Inputs() []Sound { return []Sound{m.tp} }
+
+// Until returns the beat clicks stop at.
+func (m *Metronome) Until() float64 { return m.until }
+
+// SetUntil clicks only before beat, such as the punch-in of a Recorder to
+// count in during pre-roll. Infinity always clicks.
+func (m *Metronome) SetUntil(beat float64) { m.until = beat }
+
+// Accent returns the amplitude of the first beat of a bar relative to others.
+func (m *Metronome) Accent() float64 { return m.accent }
+
+// SetAccent sets the relative amplitude of the first beat of a bar; others click at half.
+func (m *Metronome) SetAccent(x float64) { m.accent = x }
+
+func (m *Metronome) Prepare(uint64) {
+	var beat, step float64
+	if m.tp.Playing() {
+		step = float64(m.tp.BPM()) / (60 * m.sr)
+		beat = m.tp.Beat() - float64(len(m.out))*step
+	}
+	const eps = 1e-9
+	for i := range m.out {
+		if m.tp.Index(i) == 1 && beat+eps < m.until {
+			m.amp, m.freq, m.phase = 0.5, 1000, 0
+			if n := int(math.Floor(beat + eps)); m.bar > 0 && (n%m.bar+m.bar)%m.bar == 0 {
+				m.amp, m.freq = m.accent, 1500
+			}
+		}
+		beat += step
+		if m.off {
+			m.out[i] = 0
+		} else {
+			m.out[i] = m.amp * math.Sin(2*math.Pi*m.phase)
+		}
+		m.phase += m.freq / m.sr
+		if m.amp -= m.decay * m.amp; m.amp < 1e-6 {
+			m.amp = 0
+		}
+	}
+}
+
+// TapTempo estimates tempo from the average interval between taps,
+// such as presses of a button.
+type TapTempo struct {
+	taps []time.Time
+	max  int
+	rst  time.Duration
+}
+
+// NewTapTempo averages up to n taps; a pause longer than reset starts over.
+func NewTapTempo(n int, reset time.Duration) *TapTempo {
+	return &TapTempo{max: n, rst: reset}
+}
+
+// Tap records a tap at t and returns the current estimate, or zero until
+// there are at least two taps.
+func (tt *TapTempo) Tap(t time.Time) BPM {
+	if n := len(tt.taps); n > 0 && t.Sub(tt.taps[n-1]) > tt.rst {
+		tt.taps = tt.taps[:0]
+	}
+	tt.taps = append(tt.taps, t)
+	if len(tt.taps) > tt.max {
+		tt.taps = tt.taps[1:]
+	}
+	return tt.BPM()
+}
+
+// BPM returns the current estimate or zero.
+func (tt *TapTempo) BPM() BPM {
+	n := len(tt.taps)
+	if n < 2 {
+		return 0
+	}
+	avg := tt.taps[n-1].Sub(tt.taps[0]) / time.Duration(n-1)
+	return BPM(float64(time.Minute) / float64(avg))
+}
+
+// Threshold detects clicks in audio, outputting a trigger when the absolute
+// value of its input rises above a level and ignoring further input until
+// a holdoff period passes.
+type Threshold struct {
+	*mono
+	level float64
+	hold  int
+	n     int
+}
+
+func NewThreshold(level float64, holdoff time.Duration, in Sound) *Threshold {
+	return &Threshold{mono: newmono(in), level: level, hold: Dtof(holdoff, in.SampleRate())}
+}
+
+func (th *Threshold) Prepare(uint64) {
+	for i, x := range th.in.Samples() {
+		th.out[i] = 0
+		if th.n > 0 {
+			th.n--
+			continue
+		}
+		if math.Abs(x) > th.level {
+			th.n = th.hold
+			if !th.off {
+				th.out[i] = 1
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	tp := NewTransport(120) // 22050 frames per beat
+	tp.Play()
+	out := Render(tp, 44100*2)
+	if idx := trigs(out); len(idx) != 4 || idx[0] != 0 || idx[1] != 22050 {
+		t.Fatalf("have beats at %v", idx)
+	}
+	if want := float64(tp.Frame()) / 22050; !equaleps(tp.Beat(), want, 1e-6) {
+		t.Fatalf("have beat %v, want %v", tp.Beat(), want)
+	}
+}
+
+func TestTransportLongRunning(t *testing.T) {
+	// a counter about to wrap after running for ages keeps counting beats.
+	tp := NewTransport(120)
+	tp.frame = 1<<64 - 22050
+	tp.Seek(1e6)
+	tp.Play()
+	out := Render(tp, 44100)
+	if idx := trigs(out); len(idx) != 2 || idx[0] != 0 || idx[1] != 22050 {
+		t.Fatalf("have beats at %v", idx)
+	}
+	// render is of whole buffers, past 44100 frames.
+	want := 1e6 + float64(tp.Frame()+22050)/22050
+	if have := tp.Beat(); !equaleps(have, want, 1e-9) {
+		t.Fatalf("have beat %v, want %v", have, want)
+	}
+}
+
+func TestMetronome(t *testing.T) {
+	tp := NewTransport(120) // 22050 frames per beat
+	tp.Seek(-2)
+	tp.Play()
+	m := NewMetronome(tp, 4)
+	m.SetUntil(1)
+	out := Render(m, 22050*4)
+	peak := func(beat int) float64 { return Peak(out[beat*22050 : beat*22050+441]) }
+	if !equaleps(peak(2), 1, 0.02) || !equaleps(peak(0), 0.5, 0.02) {
+		t.Fatalf("have peaks %v at bar and %v at beat, want accent", peak(2), peak(0))
+	}
+	if p := peak(3); p != 0 {
+		t.Fatalf("have peak %v after until, want 0", p)
+	}
+}
+
+func TestTransportFollow(t *testing.T) {
+	ext := NewClock(250 * time.Millisecond) // 240 bpm at one pulse per beat
+	tp := NewTransport(100)
+	tp.Follow(ext, 1)
+	tp.Play()
+	Render(tp, 44100)
+	if !equaleps(float64(tp.BPM()), 240, 0.01) {
+		t.Fatalf("have %v, want 240bpm", tp.BPM())
+	}
+}
+
+func TestTrigMultiply(t *testing.T) {
+	clk := NewClock(10 * time.Millisecond)
+	mul := NewTrigMultiply(3, clk)
+	idx := trigs(Render(mul, 1000))
+	// first interval is measured before multiplying.
+	if len(idx) != 4 || idx[0] != 441 || idx[1] != 588 || idx[3] != 882 {
+		t.Fatalf("have triggers at %v", idx)
+	}
+}
+
+func TestTapTempo(t *testing.T) {
+	tt := NewTapTempo(4, 2*time.Second)
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		tt.Tap(now.Add(time.Duration(i) * 500 * time.Millisecond))
+	}
+	if bpm := tt.BPM(); !equals(float64(bpm), 120) {
+		t.Fatalf("have %v, want 120", bpm)
+	}
+	if bpm := tt.Tap(now.Add(time.Minute)); bpm != 0 {
+		t.Fatalf("have %v after reset, want 0", bpm)
+	}
+}
+
+func TestThreshold(t *testing.T) {
+	clicks := NewGain(0.8, NewClock(10*time.Millisecond))
+	th := NewThreshold(0.5, 5*time.Millisecond, clicks)
+	if idx := trigs(Render(th, 44100)); len(idx) != 100 {
+		t.Fatalf("have %v clicks, want 100", len(idx))
+	}
+}
@@ -0,0 +1,190 @@
+package snd
+
+import "time"
+
+// Gates and Triggers
+//
+// A gate is any Sound whose samples are high when greater than zero and low
+// otherwise. A trigger is the rising edge of a gate, a low sample followed by
+// a high sample. Sounds in this package that produce triggers output a single
+// sample of 1 followed by 0, and sounds that accept a gate respond to edges so
+// that any gate, such as a square oscillator, may drive them.
+
+// edge detects transitions of a gate.
+type edge struct{ high bool }
+
+// step returns whether x is a rising or falling edge of the gate.
+func (e *edge) step(x float64) (rise, fall bool) {
+	high := x > 0
+	rise, fall = high && !e.high, !high && e.high
+	e.high = high
+	return
+}
+
+// Clock outputs triggers at a fixed period.
+type Clock struct {
+	*mono
+	step  float64
+	phase float64
+}
+
+// NewClock returns a Clock triggering every d, the first trigger on the first frame.
+func NewClock(d time.Duration) *Clock {
+	clk := &Clock{mono: newmono(nil), phase: 1}
+	clk.SetPeriod(d)
+	return clk
+}
+
+// SetPeriod sets the duration between triggers. Fractional frames are carried
+// between triggers so the clock does not drift.
+func (clk *Clock) SetPeriod(d time.Duration) {
+	clk.step = 1 / (float64(d) / float64(time.Second) * clk.sr)
+}
+
+// Reset triggers on the next frame and continues from there.
+func (clk *Clock) Reset() { clk.phase = 1 }
+
+func (clk *Clock) Inputs() []Sound { return nil }
+
+func (clk *Clock) Prepare(uint64) {
+	for i := range clk.out {
+		clk.out[i] = 0
+		if clk.phase >= 1 {
+			clk.phase -= float64(int(clk.phase))
+			if !clk.off {
+				clk.out[i] = 1
+			}
+		}
+		clk.phase += clk.step
+	}
+}
+
+// TrigDelay outputs triggers of its input delayed by a fixed duration.
+type TrigDelay struct {
+	*mono
+	edge
+	n  int
+	at int // frames prepared
+
+	// ring of frames pending triggers are due, oldest first; rising edges
+	// are at least two frames apart, so no more than n/2+1 are pending.
+	due         []int
+	head, count int
+}
+
+// NewTrigDelay returns TrigDelay delaying triggers of in by d.
+func NewTrigDelay(d time.Duration, in Sound) *TrigDelay {
+	n := atleast(0, Dtof(d, in.SampleRate()))
+	return &TrigDelay{mono: newmono(in), n: n, due: make([]int, n/2+2)}
+}
+
+func (td *TrigDelay) Prepare(uint64) {
+	for i := range td.out {
+		td.out[i] = 0
+		if rise, _ := td.step(td.in.Index(i)); rise && td.count < len(td.due) {
+			td.due[(td.head+td.count)%len(td.due)] = td.at + td.n
+			td.count++
+		}
+		for td.count > 0 && td.due[td.head] <= td.at {
+			if !td.off {
+				td.out[i] = 1
+			}
+			td.head = (td.head + 1) % len(td.due)
+			td.count--
+		}
+		td.at++
+	}
+}
+
+// TrigDivide outputs every nth trigger of its input.
+type TrigDivide struct {
+	*mono
+	edge
+	n, count int
+}
+
+// NewTrigDivide returns TrigDivide passing the first of every n triggers of in.
+func NewTrigDivide(n int, in Sound) *TrigDivide {
+	return &TrigDivide{mono: newmono(in), n: n}
+}
+
+// Reset restarts counting so the next trigger passes.
+func (tdv *TrigDivide) Reset() { tdv.count = 0 }
+
+func (tdv *TrigDivide) Prepare(uint64) {
+	for i := range tdv.out {
+		tdv.out[i] = 0
+		if rise, _ := tdv.step(tdv.in.Index(i)); rise {
+			if tdv.count == 0 && !tdv.off {
+				tdv.out[i] = 1
+			}
+			tdv.count++
+			if tdv.count == tdv.n {
+				tdv.count = 0
+			}
+		}
+	}
+}
+
+// SampleHold samples its input on each trigger and holds the value until the next.
+type SampleHold struct {
+	*mono
+	edge
+	trig Sound
+	x    float64
+}
+
+func NewSampleHold(trig, in Sound) *SampleHold {
+	return &SampleHold{mono: newmono(in), trig: trig}
+}
+
+func (sh *SampleHold) Inputs() []Sound { return []Sound{sh.in, sh.trig} }
+
+func (sh *SampleHold) Prepare(uint64) {
+	for i := range sh.out {
+		if rise, _ := sh.step(sh.trig.Index(i)); rise {
+			sh.x = sh.in.Index(i)
+		}
+		if sh.off {
+			sh.out[i] = 0
+		} else {
+			sh.out[i] = sh.x
+		}
+	}
+}
+
+// TrigMultiply outputs n evenly spaced triggers for each trigger of its input,
+// spaced by the interval measured between the last two input triggers.
+type TrigMultiply struct {
+	*mono
+	edge
+	n        int
+	interval float64 // frames between input triggers
+	since    float64 // frames since last input trigger
+	next     float64 // frames since last input trigger of next output
+	count    int
+}
+
+func NewTrigMultiply(n int, in Sound) *TrigMultiply {
+	return &TrigMultiply{mono: newmono(in), n: n}
+}
+
+func (tm *TrigMultiply) Prepare(uint64) {
+	for i := range tm.out {
+		tm.out[i] = 0
+		if rise, _ := tm.step(tm.in.Index(i)); rise {
+			if tm.since > 0 {
+				tm.interval = tm.since
+			}
+			tm.since, tm.next, tm.count = 0, 0, 0
+		}
+		if tm.interval > 0 && tm.count < tm.n && tm.since >= tm.next {
+			if !tm.off {
+				tm.out[i] = 1
+			}
+			tm.count++
+			tm.next = float64(tm.count) * tm.interval / float64(tm.n)
+		}
+		tm.since++
+	}
+}
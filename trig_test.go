@@ -0,0 +1,78 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func trigs(sig Discrete) (idx []int) {
+	for i, x := range sig {
+		if x > 0 {
+			idx = append(idx, i)
+		}
+	}
+	return
+}
+
+func TestClock(t *testing.T) {
+	clk := NewClock(10 * time.Millisecond) // 441 frames
+	out := Render(clk, 4000)
+	idx := trigs(out)
+	if len(idx) != 10 || idx[0] != 0 || idx[9] != 3969 {
+		t.Fatalf("have triggers at %v", idx)
+	}
+
+	div := NewTrigDivide(3, clk)
+	dly := NewTrigDelay(time.Millisecond, div) // 44 frames
+	clk.Reset()
+	if idx := trigs(Render(dly, 4000)); len(idx) != 3 || idx[0] != 44 || idx[1] != 1367 {
+		t.Fatalf("divided and delayed have triggers at %v", idx)
+	}
+}
+
+func TestTrigDelayDense(t *testing.T) {
+	// a trigger every 4 frames, many pending over the delay.
+	sq := NewOscil(Square(), DefaultSampleRate/4, nil)
+	dly := NewTrigDelay(10*time.Millisecond, sq) // 441 frames
+	out := trigs(Render(dly, 2000))
+	if want := (2000 - 441 + 3) / 4; len(out) != want || out[0] != 441 || out[1] != 445 {
+		t.Fatalf("have %v triggers from %v, want %v from 441", len(out), out[:2], want)
+	}
+	if n := testing.AllocsPerRun(10, func() { dly.Prepare(1) }); n != 0 {
+		t.Fatalf("have %v allocations a buffer, want 0", n)
+	}
+}
+
+func TestSampleHold(t *testing.T) {
+	clk := NewClock(10 * time.Millisecond)
+	osc := NewOscil(Sawtooth(), 30, nil)
+	sh := NewSampleHold(clk, NewGain(1, osc))
+	out := Render(sh, 1000)
+	if out[1] != out[440] || out[440] == out[441] {
+		t.Fatalf("not held between triggers %v %v %v", out[1], out[440], out[441])
+	}
+}
+
+func TestADSRGate(t *testing.T) {
+	ms := time.Millisecond
+	gate := NewOscil(Square(), DefaultSampleRate/1024, nil)
+	adsr := NewADSR(ms, ms, ms, ms, 0.5, 1, nil)
+	adsr.SetGate(gate)
+	out := Render(adsr, 1024)
+	// held at sustain while high, released to zero while low.
+	if !equals(out[500], 0.5) {
+		t.Fatalf("have %v during gate, want sustain 0.5", out[500])
+	}
+	if out[1000] != 0 {
+		t.Fatalf("have %v after gate, want released", out[1000])
+	}
+}
+
+func BenchmarkClock(b *testing.B) {
+	clk := NewClock(100 * time.Millisecond)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		clk.Prepare(uint64(n))
+	}
+}
@@ -0,0 +1,66 @@
+package snd
+
+import (
+	"math"
+	"time"
+)
+
+// loud reports whether any channel of frame f of interleaved samples sig with
+// chans channels reaches amplitude thr.
+func loud(sig Discrete, chans, f int, thr float64) bool {
+	for _, x := range sig[f*chans : (f+1)*chans] {
+		if math.Abs(x) >= thr {
+			return true
+		}
+	}
+	return false
+}
+
+// TrimSilence returns interleaved samples sig with chans channels less leading
+// and trailing frames below threshold in every channel, such as -60dB for a
+// recording with little noise. The result shares sig and is empty if all of
+// sig is below threshold.
+func TrimSilence(sig Discrete, chans int, threshold Decibel) Discrete {
+	thr := threshold.Amp()
+	nfr := len(sig) / chans
+	start, end := 0, nfr
+	for start < end && !loud(sig, chans, start, thr) {
+		start++
+	}
+	for end > start && !loud(sig, chans, end-1, thr) {
+		end--
+	}
+	return sig[start*chans : end*chans]
+}
+
+// SplitSilence splits interleaved samples sig with chans channels at sample
+// rate sr into clips wherever it stays below threshold for at least gap, such
+// as a recording of a sampled instrument played note by note, returning each
+// clip trimmed of silence. Clips share sig.
+func SplitSilence(sig Discrete, chans int, sr float64, threshold Decibel, gap time.Duration) []Discrete {
+	thr := threshold.Amp()
+	mingap := Dtof(gap, sr)
+	if mingap < 1 {
+		mingap = 1
+	}
+	var clips []Discrete
+	nfr := len(sig) / chans
+	start, quiet := -1, 0
+	for f := 0; f < nfr; f++ {
+		if loud(sig, chans, f, thr) {
+			if start < 0 {
+				start = f
+			}
+			quiet = 0
+			continue
+		}
+		if quiet++; start >= 0 && quiet >= mingap {
+			clips = append(clips, sig[start*chans:(f+1-quiet)*chans])
+			start = -1
+		}
+	}
+	if start >= 0 {
+		clips = append(clips, TrimSilence(sig[start*chans:nfr*chans], chans, threshold))
+	}
+	return clips
+}
@@ -0,0 +1,38 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrimSilence(t *testing.T) {
+	sig := Discrete{0, 0, 0.001, 0, 0.5, -0.5, 0, 0.2, 0.0001, 0, 0, 0}
+	trim := TrimSilence(sig, 2, -40)
+	if len(trim) != 4 || trim[0] != 0.5 || trim[3] != 0.2 {
+		t.Fatalf("have %v, want frames 2 and 3", trim)
+	}
+	if trim := TrimSilence(Discrete{0, 0.001}, 1, -40); len(trim) != 0 {
+		t.Fatalf("have %v of silence, want empty", trim)
+	}
+}
+
+func TestSplitSilence(t *testing.T) {
+	sr := 1000.0
+	// notes of 50 frames with gaps of 20 and 5 frames, and silence around.
+	sig := make(Discrete, 200)
+	for _, note := range [][2]int{{10, 60}, {80, 130}, {135, 185}} {
+		for f := note[0]; f < note[1]; f++ {
+			sig[f] = 0.5
+		}
+	}
+	clips := SplitSilence(sig, 1, sr, -40, 10*time.Millisecond)
+	if len(clips) != 2 {
+		t.Fatalf("have %v clips, want 2", len(clips))
+	}
+	if len(clips[0]) != 50 || len(clips[1]) != 105 {
+		t.Fatalf("have clips of %v and %v frames, want 50 and 105", len(clips[0]), len(clips[1]))
+	}
+	if clips := SplitSilence(sig, 1, sr, -40, 2*time.Millisecond); len(clips) != 3 {
+		t.Fatalf("have %v clips of short gap, want 3", len(clips))
+	}
+}
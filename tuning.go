@@ -0,0 +1,279 @@
+package snd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Tuning tunes keys of an instrument other than to equal temperament: by a
+// scale, such as read from a Scala .scl file, mapped to keys by a KeyMap,
+// such as read from a .kbm file, and by offsets of single keys, such as the
+// stretch tuning of a piano. The zero value is 12 tone equal temperament.
+type Tuning struct {
+	// Steps are cents of degrees of the scale above its root, the last being
+	// its period, 1200 for an octave; nil is 12 tone equal temperament.
+	Steps []float64
+
+	// Map maps keys to degrees of the scale; nil maps key 60 to the root and
+	// each key above and below to the next degree, with key 69 at A4.
+	Map *KeyMap
+
+	// Offsets are cents added to each key by key number; keys beyond are not
+	// offset.
+	Offsets []float64
+}
+
+// KeyMap maps keys to degrees of a scale as a Scala keyboard mapping does.
+type KeyMap struct {
+	First, Last int // keys mapped, inclusive
+	Middle      int // key of degree 0 of the mapping
+	RefKey      int // key tuned to RefFreq
+	RefFreq     float64
+
+	// Degrees are degrees of the keys from Middle up, repeated each
+	// len(Degrees) keys transposed by Octave degrees; -1 leaves a key
+	// unmapped. Empty maps each key to the next degree.
+	Degrees []int
+	Octave  int
+}
+
+// Freq returns the frequency of key, or zero if unmapped; a4 is the
+// frequency of A4 without a KeyMap or if its RefFreq is zero, such as
+// Dispatcher.ConcertPitch.
+func (tn *Tuning) Freq(key int, a4 float64) float64 {
+	km := tn.Map
+	if km == nil {
+		km = &KeyMap{First: 0, Last: 127, Middle: 60, RefKey: 69}
+	}
+	ref := km.RefFreq
+	if ref == 0 {
+		ref = a4
+	}
+	d, ok := km.degree(key)
+	r, rok := km.degree(km.RefKey)
+	if !ok || !rok {
+		return 0
+	}
+	cents := tn.cents(d) - tn.cents(r)
+	if key >= 0 && key < len(tn.Offsets) {
+		cents += tn.Offsets[key]
+	}
+	return ref * math.Pow(2, cents/1200)
+}
+
+// tunedfreq returns the frequency of key by tn with A4 at a4, or equal
+// tempered if tn is nil.
+func tunedfreq(tn *Tuning, key int, a4 float64) float64 {
+	if tn == nil {
+		return keyfreq(key, a4)
+	}
+	return tn.Freq(key, a4)
+}
+
+// degree returns the degree of the scale of key, and whether it is mapped.
+func (km *KeyMap) degree(key int) (int, bool) {
+	if key < km.First || key > km.Last {
+		return 0, false
+	}
+	i := key - km.Middle
+	n := len(km.Degrees)
+	if n == 0 {
+		return i, true
+	}
+	oct, slot := floordiv(i, n)
+	if km.Degrees[slot] < 0 {
+		return 0, false
+	}
+	return km.Degrees[slot] + oct*km.Octave, true
+}
+
+// cents returns cents of degree d above the root.
+func (tn *Tuning) cents(d int) float64 {
+	n := len(tn.Steps)
+	if n == 0 {
+		return 100 * float64(d)
+	}
+	oct, r := floordiv(d, n)
+	c := float64(oct) * tn.Steps[n-1]
+	if r > 0 {
+		c += tn.Steps[r-1]
+	}
+	return c
+}
+
+// floordiv returns the floored quotient and modulus of a by b.
+func floordiv(a, b int) (q, r int) {
+	q, r = a/b, a%b
+	if r < 0 {
+		q, r = q-1, r+b
+	}
+	return q, r
+}
+
+// DefaultInharmonicity is the inharmonicity coefficient of the strings of A4
+// of a typical grand piano.
+const DefaultInharmonicity = 4e-4
+
+// Railsback returns offsets in cents of keys 0 through 127 stretch tuning a
+// piano whose strings of A4 have inharmonicity coefficient b, such as
+// DefaultInharmonicity, for Tuning.Offsets. Partial n of a stiff string is
+// sharp of harmonic by sqrt(1+b*n*n), so tuners match partials of each key
+// to those of the key an octave below, outward from A4: the second partial
+// to the first in the treble, and higher partials in the bass where they are
+// heard over the fundamental. The offsets so found follow the Railsback
+// curve, flat in the bass and sharp in the treble. Larger pianos have less
+// inharmonicity and so less stretch.
+//
+// Inharmonicity along the keyboard is approximated as doubling every 8 keys
+// up from C3 and every 12 keys down as strings shorten toward both ends.
+func Railsback(b float64) []float64 {
+	inharm := func(key int) float64 {
+		if key < 48 {
+			return b * math.Pow(2, float64(48-69)/8+float64(48-key)/12)
+		}
+		return b * math.Pow(2, float64(key-69)/8)
+	}
+	// octave returns cents the octave above key is stretched, matching
+	// partial 2n of key to partial n of the key above.
+	octave := func(key int) float64 {
+		n := 1.0
+		switch {
+		case key < 36:
+			n = 3
+		case key < 48:
+			n = 2
+		}
+		lo, hi := inharm(key), inharm(key+12)
+		// first partials, heard as pitch, are sharp too.
+		return 600 * math.Log2((1+4*n*n*lo)*(1+hi)/((1+n*n*hi)*(1+lo)))
+	}
+	offs := make([]float64, 128)
+	// the temperament octave A3 to A4 is stretched evenly.
+	offs[57] = -octave(57)
+	for key := 58; key < 69; key++ {
+		offs[key] = offs[57] * float64(69-key) / 12
+	}
+	for key := 70; key < len(offs); key++ {
+		offs[key] = offs[key-12] + octave(key-12)
+	}
+	for key := 56; key >= 0; key-- {
+		offs[key] = offs[key+12] - octave(key)
+	}
+	return offs
+}
+
+// scalalines returns lines of r not comments of Scala files, starting with !.
+func scalalines(r io.Reader) ([]string, error) {
+	var lines []string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		if s := strings.TrimRight(sc.Text(), "\r"); !strings.HasPrefix(s, "!") {
+			lines = append(lines, s)
+		}
+	}
+	return lines, sc.Err()
+}
+
+// ReadScala reads a scale of a Scala .scl file from r, returning its
+// description and steps in cents for Tuning.Steps. Steps are given as cents
+// if they contain a period and otherwise as ratios, such as 3/2 or 2.
+func ReadScala(r io.Reader) (desc string, steps []float64, err error) {
+	lines, err := scalalines(r)
+	if err != nil {
+		return "", nil, fmt.Errorf("snd: read scala: %v", err)
+	}
+	if len(lines) < 2 {
+		return "", nil, fmt.Errorf("snd: read scala: missing count of notes")
+	}
+	desc = strings.TrimSpace(lines[0])
+	n, err := strconv.Atoi(firstfield(lines[1]))
+	if err != nil || n < 0 {
+		return "", nil, fmt.Errorf("snd: read scala: bad count of notes %q", lines[1])
+	}
+	if len(lines)-2 < n {
+		return "", nil, fmt.Errorf("snd: read scala: have %v notes, want %v", len(lines)-2, n)
+	}
+	for _, line := range lines[2 : 2+n] {
+		c, err := scalastep(firstfield(line))
+		if err != nil {
+			return "", nil, fmt.Errorf("snd: read scala: %v", err)
+		}
+		steps = append(steps, c)
+	}
+	return desc, steps, nil
+}
+
+func firstfield(s string) string {
+	if fs := strings.Fields(s); len(fs) > 0 {
+		return fs[0]
+	}
+	return ""
+}
+
+// scalastep returns cents of a step of a scale, as cents or a ratio.
+func scalastep(s string) (float64, error) {
+	if strings.Contains(s, ".") {
+		return strconv.ParseFloat(s, 64)
+	}
+	num, den := s, "1"
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		num, den = s[:i], s[i+1:]
+	}
+	a, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad step %q", s)
+	}
+	b, err := strconv.ParseFloat(den, 64)
+	if err != nil || a <= 0 || b <= 0 {
+		return 0, fmt.Errorf("bad step %q", s)
+	}
+	return 1200 * math.Log2(a/b), nil
+}
+
+// ReadKeyMap reads a keyboard mapping of a Scala .kbm file from r.
+func ReadKeyMap(r io.Reader) (*KeyMap, error) {
+	lines, err := scalalines(r)
+	if err != nil {
+		return nil, fmt.Errorf("snd: read keymap: %v", err)
+	}
+	var fields []string
+	for _, line := range lines {
+		if f := firstfield(line); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) < 7 {
+		return nil, fmt.Errorf("snd: read keymap: have %v values of header, want 7", len(fields))
+	}
+	var hdr [7]int
+	for i, f := range fields[:7] {
+		if i == 5 {
+			continue
+		}
+		if hdr[i], err = strconv.Atoi(f); err != nil {
+			return nil, fmt.Errorf("snd: read keymap: bad value %q", f)
+		}
+	}
+	km := &KeyMap{First: hdr[1], Last: hdr[2], Middle: hdr[3], RefKey: hdr[4], Octave: hdr[6]}
+	if km.RefFreq, err = strconv.ParseFloat(fields[5], 64); err != nil {
+		return nil, fmt.Errorf("snd: read keymap: bad reference frequency %q", fields[5])
+	}
+	size := hdr[0]
+	if size < 0 || len(fields)-7 < size {
+		return nil, fmt.Errorf("snd: read keymap: have %v keys mapped, want %v", len(fields)-7, size)
+	}
+	for _, f := range fields[7 : 7+size] {
+		d := -1
+		if f != "x" && f != "X" {
+			if d, err = strconv.Atoi(f); err != nil {
+				return nil, fmt.Errorf("snd: read keymap: bad degree %q", f)
+			}
+		}
+		km.Degrees = append(km.Degrees, d)
+	}
+	return km, nil
+}
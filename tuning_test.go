@@ -0,0 +1,104 @@
+package snd
+
+import (
+	"strings"
+	"testing"
+)
+
+const testScala = `! just.scl
+!
+Just major
+ 7
+!
+9/8
+5/4
+4/3
+3/2
+5/3
+15/8
+1200.0
+`
+
+// testKeyMap maps white keys to the 7 degrees of a scale, with A4 at 440Hz.
+const testKeyMap = `! white.kbm
+12
+0
+127
+60
+69
+440.0
+7
+! mapping
+0
+x
+1
+x
+2
+3
+x
+4
+x
+5
+x
+6
+`
+
+func TestTuning(t *testing.T) {
+	if hz := (&Tuning{}).Freq(81, 440); !equaleps(hz, 880, 1e-9) {
+		t.Fatalf("have %vHz equal tempered, want 880Hz", hz)
+	}
+
+	desc, steps, err := ReadScala(strings.NewReader(testScala))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if desc != "Just major" || len(steps) != 7 || !equaleps(steps[3], 701.955, 1e-3) {
+		t.Fatalf("have %q %v", desc, steps)
+	}
+	km, err := ReadKeyMap(strings.NewReader(testKeyMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tn := &Tuning{Steps: steps, Map: km}
+	// A4 is a major sixth above C4, so C4 is 3/5 of 440Hz.
+	for key, want := range map[int]float64{60: 264, 64: 330, 67: 396, 69: 440, 72: 528, 48: 132} {
+		if hz := tn.Freq(key, 0); !equaleps(hz, want, 1e-9) {
+			t.Errorf("have %vHz for key %v, want %vHz", hz, key, want)
+		}
+	}
+	if hz := tn.Freq(61, 440); hz != 0 {
+		t.Fatalf("have %vHz for unmapped key, want 0", hz)
+	}
+
+	offs := Railsback(DefaultInharmonicity)
+	if offs[69] != 0 || offs[21] > -10 || offs[108] < 10 {
+		t.Fatalf("have offsets %v at A0, %v at A4 and %v at C8", offs[21], offs[69], offs[108])
+	}
+	for key := 1; key < len(offs); key++ {
+		if offs[key] < offs[key-1] {
+			t.Fatalf("have offset %v of key %v less than %v below", offs[key], key, offs[key-1])
+		}
+	}
+
+	p := NewPoly(1, testVoice)
+	p.SetTuning(tn)
+	p.NoteOn(61, 1)
+	if p.Active() != 0 {
+		t.Fatal("played unmapped key")
+	}
+	p.NoteOn(64, 1)
+	if hz := p.Voices()[0].(*OscVoice).Osc().Freq(); !equaleps(hz, 330, 1e-9) {
+		t.Fatalf("have %vHz, want 330Hz", hz)
+	}
+}
+
+func TestReadScalaErrors(t *testing.T) {
+	for _, s := range []string{"", "desc\nx\n", "desc\n2\n9/8\n", "desc\n1\n0/1\n"} {
+		if _, _, err := ReadScala(strings.NewReader(s)); err == nil {
+			t.Errorf("have no error reading %q", s)
+		}
+	}
+	if _, err := ReadKeyMap(strings.NewReader("1\n0\n127\n60\n69\n440\n")); err == nil {
+		t.Error("have no error reading short keymap")
+	}
+}
@@ -0,0 +1,130 @@
+package snd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Unit is the unit of a Param, for presenting its value in musical terms,
+// such as by a UI, OSC, or a preset edited by hand.
+type Unit int
+
+const (
+	UnitNone         Unit = iota
+	UnitHz                // frequency, shown in kHz from 1000
+	UnitDecibel           // level
+	UnitSeconds           // time, shown in ms under a second
+	UnitMilliseconds      // time, shown in s from a second
+	UnitBeats             // musical time at a tempo
+	UnitPercent           // fraction of one, shown out of 100
+	UnitSemitones         // musical interval
+)
+
+// units are suffixes of each unit, and of values in units scaled from another
+// when formatted or parsed.
+var units = [...]struct {
+	suffix string
+	alt    string  // suffix of a scaled unit, if any
+	scale  float64 // of alt to the unit
+}{
+	UnitNone:         {},
+	UnitHz:           {"Hz", "kHz", 1000},
+	UnitDecibel:      {"dB", "", 0},
+	UnitSeconds:      {"s", "ms", 0.001},
+	UnitMilliseconds: {"ms", "s", 1000},
+	UnitBeats:        {"beats", "beat", 1},
+	UnitPercent:      {"%", "", 0},
+	UnitSemitones:    {"st", "", 0},
+}
+
+func (u Unit) String() string {
+	if u < 0 || int(u) >= len(units) {
+		return fmt.Sprintf("Unit(%d)", int(u))
+	}
+	return units[u].suffix
+}
+
+// Format returns x in unit u with its suffix, such as "1.50 kHz", "-6.0 dB",
+// "250 ms", or "50%".
+func (u Unit) Format(x float64) string {
+	num := func(x float64) string { return strconv.FormatFloat(x, 'f', -1, 64) }
+	switch u {
+	case UnitHz:
+		if math.Abs(x) >= 1000 {
+			return fmt.Sprintf("%.2f kHz", x/1000)
+		}
+		return fmt.Sprintf("%.1f Hz", x)
+	case UnitDecibel:
+		if math.IsInf(x, -1) {
+			return "-inf dB"
+		}
+		return fmt.Sprintf("%.1f dB", x)
+	case UnitSeconds:
+		if math.Abs(x) < 1 {
+			return fmt.Sprintf("%.0f ms", x*1000)
+		}
+		return fmt.Sprintf("%.2f s", x)
+	case UnitMilliseconds:
+		if math.Abs(x) >= 1000 {
+			return fmt.Sprintf("%.2f s", x/1000)
+		}
+		return fmt.Sprintf("%.1f ms", x)
+	case UnitBeats:
+		if x == 1 {
+			return "1 beat"
+		}
+		return num(x) + " beats"
+	case UnitPercent:
+		return fmt.Sprintf("%.0f%%", x*100)
+	case UnitSemitones:
+		return fmt.Sprintf("%+.1f st", x)
+	}
+	return fmt.Sprintf("%.3g", x)
+}
+
+// Parse returns the value of s in unit u, such as "1.5 kHz" or "1500" of
+// UnitHz, "50%" of UnitPercent, or "250ms" of UnitSeconds. A number without
+// a suffix is taken as in unit u, though one of UnitPercent is out of 100.
+func (u Unit) Parse(s string) (float64, error) {
+	x, err := u.parse(s)
+	if err != nil {
+		return 0, fmt.Errorf("snd: %v", err)
+	}
+	return x, nil
+}
+
+// parse is Parse with errors not prefixed, for callers adding their own.
+func (u Unit) parse(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if u == UnitDecibel && strings.EqualFold(s, "-inf dB") {
+		return math.Inf(-1), nil
+	}
+	end := len(s)
+	for end > 0 && !strings.ContainsAny(s[end-1:end], "0123456789.") {
+		end--
+	}
+	num, suffix := s[:end], strings.TrimSpace(s[end:])
+	x, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse %q as %v failed: %v", s, u, err)
+	}
+	us := units[UnitNone]
+	if u > UnitNone && int(u) < len(units) {
+		us = units[u]
+	}
+	switch {
+	case u == UnitPercent:
+		if suffix == "" || suffix == "%" {
+			return x / 100, nil
+		}
+	case suffix == "" || suffix == us.suffix:
+		return x, nil
+	case us.alt != "" && strings.EqualFold(suffix, us.alt):
+		return x * us.scale, nil
+	case strings.EqualFold(suffix, us.suffix):
+		return x, nil
+	}
+	return 0, fmt.Errorf("parse %q as %v failed: unknown unit %q", s, u, suffix)
+}
@@ -0,0 +1,76 @@
+package snd
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestUnitFormat(t *testing.T) {
+	for _, tc := range []struct {
+		u    Unit
+		x    float64
+		want string
+	}{
+		{UnitHz, 440, "440.0 Hz"},
+		{UnitHz, 1500, "1.50 kHz"},
+		{UnitDecibel, -6, "-6.0 dB"},
+		{UnitDecibel, math.Inf(-1), "-inf dB"},
+		{UnitSeconds, 0.25, "250 ms"},
+		{UnitSeconds, 2, "2.00 s"},
+		{UnitMilliseconds, 20, "20.0 ms"},
+		{UnitBeats, 0.25, "0.25 beats"},
+		{UnitBeats, 1, "1 beat"},
+		{UnitPercent, 0.5, "50%"},
+		{UnitSemitones, 7, "+7.0 st"},
+		{UnitNone, 0.707, "0.707"},
+	} {
+		if have := tc.u.Format(tc.x); have != tc.want {
+			t.Errorf("have %q, want %q", have, tc.want)
+		}
+	}
+}
+
+func TestUnitParse(t *testing.T) {
+	for _, tc := range []struct {
+		u    Unit
+		s    string
+		want float64
+	}{
+		{UnitHz, "440", 440},
+		{UnitHz, "1.5 kHz", 1500},
+		{UnitHz, "1.5khz", 1500},
+		{UnitDecibel, "-6 dB", -6},
+		{UnitDecibel, "-inf dB", math.Inf(-1)},
+		{UnitSeconds, "250ms", 0.25},
+		{UnitSeconds, "2 s", 2},
+		{UnitMilliseconds, "1.5 s", 1500},
+		{UnitBeats, "1 beat", 1},
+		{UnitPercent, "50%", 0.5},
+		{UnitPercent, "25", 0.25},
+		{UnitSemitones, "-12 st", -12},
+	} {
+		have, err := tc.u.Parse(tc.s)
+		if err != nil || have != tc.want {
+			t.Errorf("%q: have %v, %v, want %v", tc.s, have, err, tc.want)
+		}
+	}
+	if _, err := UnitHz.Parse("3 dB"); err == nil {
+		t.Error("have no error parsing dB as Hz")
+	}
+	if have := Unit(99).String(); have != "Unit(99)" {
+		t.Errorf("have %q of an unknown unit", have)
+	}
+
+	lp := NewLowPass(1000, NewOscil(Sine(), 440, nil))
+	p := lp.Params()[0]
+	if err := p.SetString("2.5 kHz"); err != nil || lp.Freq() != 2500 {
+		t.Fatalf("have freq %v, %v, want 2500", lp.Freq(), err)
+	}
+	if have := p.String(); have != "2.50 kHz" {
+		t.Fatalf("have %q, want 2.50 kHz", have)
+	}
+	if err := p.SetString("3 dB"); err == nil || strings.Count(err.Error(), "snd:") != 1 {
+		t.Errorf("have error %v, want prefixed once", err)
+	}
+}
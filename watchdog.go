@@ -0,0 +1,147 @@
+package snd
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Watchdog watches that each buffer prepared through a Dispatcher finishes
+// within its real time budget, a fraction of the duration it plays for, and
+// acts when overruns are sustained instead of letting output glitch: calling
+// a func given, such as to warn a user, and taking steps degrading quality,
+// such as DegradeVoices and DegradeInterpolation, one at a time.
+//
+// Overruns are sustained once they outnumber buffers on time by a count, so a
+// single slow buffer, such as of garbage collection, is ignored.
+type Watchdog struct {
+	// atomic, first for alignment; written on the audio thread.
+	overruns, taken uint64
+
+	remove []func()
+
+	// read on the audio thread.
+	mu        sync.Mutex
+	dur       time.Duration // of a buffer
+	fraction  float64       // of dur budgeted
+	sustain   int
+	onoverrun func(tick, budget time.Duration)
+	steps     []func()
+
+	start time.Time // of the buffer being prepared
+	debt  int       // overruns less buffers on time
+}
+
+// NewWatchdog returns Watchdog of out, the output of a graph prepared by dp,
+// with a budget of 80% of each buffer, acting after 8 overruns, and watching
+// until Close.
+func NewWatchdog(dp *Dispatcher, out Sound) *Watchdog {
+	wd := &Watchdog{fraction: 0.8, sustain: 8}
+	wd.dur = Ftod(len(out.Samples())/out.Channels(), out.SampleRate())
+	wd.remove = []func(){
+		dp.BeforeDispatch(func(uint64, uint64) { wd.start = time.Now() }),
+		dp.AfterDispatch(wd.after),
+	}
+	return wd
+}
+
+// Budget returns the time a buffer may take to prepare.
+func (wd *Watchdog) Budget() time.Duration {
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	return wd.budgetlocked()
+}
+
+func (wd *Watchdog) budgetlocked() time.Duration {
+	return time.Duration(wd.fraction * float64(wd.dur))
+}
+
+// SetBudget sets the budget to fraction x of the duration of a buffer, less
+// leaving the backend and other work of the process more time.
+func (wd *Watchdog) SetBudget(x float64) {
+	wd.mu.Lock()
+	wd.fraction = x
+	wd.mu.Unlock()
+}
+
+// SetSustain sets the count by which overruns must outnumber buffers on time
+// to act.
+func (wd *Watchdog) SetSustain(n int) {
+	wd.mu.Lock()
+	wd.sustain = n
+	wd.mu.Unlock()
+}
+
+// SetOnOverrun sets fn called each time overruns are sustained, with the time
+// the last buffer took and the budget. It is called on the audio thread and
+// must return quickly, such as by signaling another goroutine.
+func (wd *Watchdog) SetOnOverrun(fn func(tick, budget time.Duration)) {
+	wd.mu.Lock()
+	wd.onoverrun = fn
+	wd.mu.Unlock()
+}
+
+// SetDegrade sets steps degrading quality, the next taken each time overruns
+// are sustained, in order until all are taken, such as fewer voices before
+// cheaper interpolation. Steps are called on the audio thread.
+func (wd *Watchdog) SetDegrade(steps ...func()) {
+	wd.mu.Lock()
+	wd.steps = steps
+	atomic.StoreUint64(&wd.taken, 0)
+	wd.mu.Unlock()
+}
+
+// Overruns returns buffers that overran the budget. It is safe to call from
+// other goroutines.
+func (wd *Watchdog) Overruns() uint64 { return atomic.LoadUint64(&wd.overruns) }
+
+// Degraded returns steps degrading quality taken. It is safe to call from
+// other goroutines.
+func (wd *Watchdog) Degraded() int { return int(atomic.LoadUint64(&wd.taken)) }
+
+// Close stops watching.
+func (wd *Watchdog) Close() {
+	for _, fn := range wd.remove {
+		fn()
+	}
+}
+
+func (wd *Watchdog) after(uint64, uint64) {
+	tick := time.Since(wd.start)
+	wd.mu.Lock()
+	defer wd.mu.Unlock()
+	budget := wd.budgetlocked()
+	if tick <= budget {
+		if wd.debt > 0 {
+			wd.debt--
+		}
+		return
+	}
+	atomic.AddUint64(&wd.overruns, 1)
+	if wd.debt++; wd.debt < wd.sustain {
+		return
+	}
+	wd.debt = 0
+	if wd.onoverrun != nil {
+		wd.onoverrun(tick, budget)
+	}
+	if n := atomic.LoadUint64(&wd.taken); n < uint64(len(wd.steps)) {
+		wd.steps[n]()
+		atomic.StoreUint64(&wd.taken, n+1)
+	}
+}
+
+// DegradeVoices returns a step of a Watchdog limiting p to n voices.
+func DegradeVoices(p *Poly, n int) func() {
+	return func() { p.SetLimit(n) }
+}
+
+// DegradeInterpolation returns a step of a Watchdog setting interpolation of
+// sds to ip, such as InterpLinear in place of InterpCubic.
+func DegradeInterpolation(ip Interpolation, sds ...interface{ SetInterpolation(Interpolation) }) func() {
+	return func() {
+		for _, sd := range sds {
+			sd.SetInterpolation(ip)
+		}
+	}
+}
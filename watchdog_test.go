@@ -0,0 +1,48 @@
+package snd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	p := NewPoly(4, testVoice)
+	dp := new(Dispatcher)
+	wd := NewWatchdog(dp, p)
+	wd.SetSustain(3)
+	var overran int
+	wd.SetOnOverrun(func(tick, budget time.Duration) {
+		if tick <= budget {
+			t.Errorf("have tick %v within budget %v", tick, budget)
+		}
+		overran++
+	})
+	var ip Interpolation = InterpCubic
+	wd.SetDegrade(DegradeVoices(p, 2), func() { ip = InterpLinear })
+
+	slow := false
+	dp.BeforeDispatch(func(uint64, uint64) {
+		if slow {
+			time.Sleep(2 * wd.Budget())
+		}
+	})
+	dp.Render(p, 4*DefaultBufferLen)
+	if n := wd.Overruns(); n != 0 {
+		t.Fatalf("have %v overruns, want 0", n)
+	}
+
+	slow = true
+	dp.Render(p, 2*DefaultBufferLen)
+	if overran != 0 {
+		t.Fatal("acted on overruns not sustained")
+	}
+	dp.Render(p, DefaultBufferLen)
+	if overran != 1 || wd.Degraded() != 1 || p.Limit() != 2 {
+		t.Fatalf("have %v overruns acted on, %v degraded, limit %v; want 1, 1, 2", overran, wd.Degraded(), p.Limit())
+	}
+	dp.Render(p, 6*DefaultBufferLen)
+	if overran != 3 || wd.Degraded() != 2 || ip != InterpLinear {
+		t.Fatalf("have %v overruns acted on, %v degraded, %v; want 3, 2, linear", overran, wd.Degraded(), ip)
+	}
+	wd.Close()
+}
@@ -0,0 +1,126 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// File reads samples of a WAVE file on demand rather than all at once, such
+// as to stream a large sample from disk.
+type File struct {
+	Format
+	frames int
+	data   int64 // offset of data
+	r      io.ReaderAt
+	closer io.Closer
+}
+
+// Open opens the WAVE file name for reading. Close must be called once done.
+func Open(name string) (*File, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	wf, err := NewFile(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%v: %s", err, name)
+	}
+	wf.closer = f
+	return wf, nil
+}
+
+// NewFile returns File of WAVE data read from r.
+func NewFile(r io.ReaderAt) (*File, error) {
+	wf := &File{r: r}
+	var riff [12]byte
+	if _, err := r.ReadAt(riff[:], 0); err != nil {
+		return nil, fmt.Errorf("wav: read header failed: %v", err)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, fmt.Errorf("wav: not a RIFF WAVE file")
+	}
+	for off := int64(12); ; {
+		var hdr [8]byte
+		if _, err := r.ReadAt(hdr[:], off); err != nil {
+			return nil, fmt.Errorf("wav: missing data chunk: %v", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		off += 8
+		switch string(hdr[:4]) {
+		case "fmt ":
+			if size < 16 {
+				return nil, fmt.Errorf("wav: read fmt chunk failed: size %v", size)
+			}
+			// only the extensible format is read of any extension.
+			b := make([]byte, 40)
+			if size < 40 {
+				b = b[:size]
+			}
+			if _, err := r.ReadAt(b, off); err != nil {
+				return nil, fmt.Errorf("wav: read fmt chunk failed: %v", err)
+			}
+			var err error
+			if wf.Format, err = parsefmt(b); err != nil {
+				return nil, err
+			}
+			if wf.Depth%8 != 0 || wf.Depth == 0 || wf.Chans == 0 {
+				return nil, fmt.Errorf("wav: invalid format %+v", wf.Format)
+			}
+		case "data":
+			if wf.Chans == 0 {
+				return nil, fmt.Errorf("wav: data chunk before fmt chunk")
+			}
+			wf.data = off
+			wf.frames = int(size) / (wf.Chans * wf.Depth / 8)
+			return wf, nil
+		}
+		off += size + size&1
+	}
+}
+
+// Channels returns channels of the file.
+func (wf *File) Channels() int { return wf.Chans }
+
+// SampleRate returns the sample rate of the file.
+func (wf *File) SampleRate() float64 { return float64(wf.Rate) }
+
+// Len returns frames of the file.
+func (wf *File) Len() int { return wf.frames }
+
+// ReadAt reads interleaved samples into xs from frame on, returning samples
+// read, fewer than len(xs) only at the end of the file with io.EOF. xs should
+// hold whole frames.
+func (wf *File) ReadAt(xs []float64, frame int) (int, error) {
+	if frame >= wf.frames {
+		return 0, io.EOF
+	}
+	size := wf.Depth / 8
+	n := len(xs)
+	if rest := (wf.frames - frame) * wf.Chans; n > rest {
+		n = rest
+	}
+	b := make([]byte, n*size)
+	if _, err := wf.r.ReadAt(b, wf.data+int64(frame*wf.Chans*size)); err != nil && err != io.EOF {
+		return 0, fmt.Errorf("wav: read data failed: %v", err)
+	}
+	sig, err := decode(b, wf.Format)
+	if err != nil {
+		return 0, err
+	}
+	copy(xs, sig)
+	if n < len(xs) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close closes a file opened by Open.
+func (wf *File) Close() error {
+	if wf.closer == nil {
+		return nil
+	}
+	return wf.closer.Close()
+}
@@ -0,0 +1,225 @@
+// Package wav reads and writes RIFF WAVE audio.
+package wav // import "dasa.cc/snd/wav"
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	formatPCM   = 1
+	formatFloat = 3
+)
+
+// Writer encodes interleaved samples as WAVE data. Header sizes are written on Close.
+type Writer struct {
+	ws    io.WriteSeeker
+	chans int
+	depth int
+	n     int64 // bytes of sample data written
+	buf   []byte
+	err   error
+}
+
+// NewWriter writes a WAVE header to ws for the given channels, sample rate, and bit depth.
+// Bit depth may be 16 or 24 for integer PCM, or 32 for IEEE float.
+func NewWriter(ws io.WriteSeeker, chans int, sr int, depth int) (*Writer, error) {
+	format := uint16(formatPCM)
+	switch depth {
+	case 16, 24:
+	case 32:
+		format = formatFloat
+	default:
+		return nil, fmt.Errorf("wav: unsupported bit depth %v", depth)
+	}
+	blockalign := chans * depth / 8
+	hdr := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'}, uint32(0), [4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '}, uint32(16),
+		format, uint16(chans), uint32(sr), uint32(sr * blockalign), uint16(blockalign), uint16(depth),
+		[4]byte{'d', 'a', 't', 'a'}, uint32(0),
+	}
+	for _, v := range hdr {
+		if err := binary.Write(ws, binary.LittleEndian, v); err != nil {
+			return nil, fmt.Errorf("wav: write header failed: %v", err)
+		}
+	}
+	return &Writer{ws: ws, chans: chans, depth: depth}, nil
+}
+
+// Write encodes interleaved samples, clipping integer formats to [-1..1].
+func (w *Writer) Write(xs []float64) error {
+	if w.err != nil {
+		return w.err
+	}
+	size := w.depth / 8
+	if n := len(xs) * size; cap(w.buf) < n {
+		w.buf = make([]byte, n)
+	}
+	b := w.buf[:len(xs)*size]
+	for i, x := range xs {
+		p := b[i*size:]
+		switch w.depth {
+		case 32:
+			binary.LittleEndian.PutUint32(p, math.Float32bits(float32(x)))
+		case 24:
+			n := int32(clip(x) * (1<<23 - 1))
+			p[0], p[1], p[2] = byte(n), byte(n>>8), byte(n>>16)
+		default:
+			n := int16(clip(x) * math.MaxInt16)
+			p[0], p[1] = byte(n), byte(n>>8)
+		}
+	}
+	if _, err := w.ws.Write(b); err != nil {
+		w.err = fmt.Errorf("wav: write failed: %v", err)
+		return w.err
+	}
+	w.n += int64(len(b))
+	return nil
+}
+
+// Close writes header sizes; it does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	for _, at := range []struct {
+		off  int64
+		size int64
+	}{{4, 36 + w.n}, {40, w.n}} {
+		if _, err := w.ws.Seek(at.off, io.SeekStart); err != nil {
+			return fmt.Errorf("wav: seek failed: %v", err)
+		}
+		if err := binary.Write(w.ws, binary.LittleEndian, uint32(at.size)); err != nil {
+			return fmt.Errorf("wav: write header failed: %v", err)
+		}
+	}
+	_, err := w.ws.Seek(0, io.SeekEnd)
+	return err
+}
+
+func clip(x float64) float64 {
+	if x > 1 {
+		return 1
+	} else if x < -1 {
+		return -1
+	}
+	return x
+}
+
+// Format describes encoding of WAVE data.
+type Format struct {
+	Chans int
+	Rate  int
+	Depth int
+	Float bool
+}
+
+// Decode reads WAVE data from r and returns interleaved samples belonging to [-1..1].
+// Integer PCM of 8, 16, 24, or 32 bits and IEEE float of 32 or 64 bits are supported.
+func Decode(r io.Reader) ([]float64, Format, error) {
+	var f Format
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, f, fmt.Errorf("wav: read header failed: %v", err)
+	}
+	if string(riff[:4]) != "RIFF" || string(riff[8:]) != "WAVE" {
+		return nil, f, fmt.Errorf("wav: not a RIFF WAVE file")
+	}
+
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, f, fmt.Errorf("wav: missing data chunk: %v", err)
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		switch string(hdr[:4]) {
+		case "fmt ":
+			b, err := readchunk(r, size+size&1)
+			if err != nil || size < 16 {
+				return nil, f, fmt.Errorf("wav: read fmt chunk failed: %v", err)
+			}
+			if f, err = parsefmt(b); err != nil {
+				return nil, f, err
+			}
+		case "data":
+			if f.Chans == 0 {
+				return nil, f, fmt.Errorf("wav: data chunk before fmt chunk")
+			}
+			b, err := readchunk(r, size)
+			if err != nil {
+				return nil, f, fmt.Errorf("wav: read data chunk failed: %v", err)
+			}
+			sig, err := decode(b, f)
+			return sig, f, err
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size&1); err != nil {
+				return nil, f, fmt.Errorf("wav: skip chunk failed: %v", err)
+			}
+		}
+	}
+}
+
+// readchunk reads size bytes of a chunk from r. Bytes are read in pieces as
+// they arrive rather than allocated up front, so a size corrupt or crafted in
+// a chunk header allocates no more than r holds.
+func readchunk(r io.Reader, size int64) ([]byte, error) {
+	var buf bytes.Buffer
+	if size < 1<<20 {
+		buf.Grow(int(size))
+	}
+	_, err := io.CopyN(&buf, r, size)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return buf.Bytes(), err
+}
+
+// parsefmt returns the Format of the body b of a fmt chunk.
+func parsefmt(b []byte) (Format, error) {
+	var f Format
+	format := binary.LittleEndian.Uint16(b)
+	if format == 0xFFFE && len(b) >= 40 { // extensible, subformat follows
+		format = binary.LittleEndian.Uint16(b[24:])
+	}
+	f.Chans = int(binary.LittleEndian.Uint16(b[2:]))
+	f.Rate = int(binary.LittleEndian.Uint32(b[4:]))
+	f.Depth = int(binary.LittleEndian.Uint16(b[14:]))
+	f.Float = format == formatFloat
+	if format != formatPCM && format != formatFloat {
+		return f, fmt.Errorf("wav: unsupported format %v", format)
+	}
+	return f, nil
+}
+
+func decode(b []byte, f Format) ([]float64, error) {
+	size := f.Depth / 8
+	if size == 0 {
+		return nil, fmt.Errorf("wav: invalid bit depth %v", f.Depth)
+	}
+	sig := make([]float64, len(b)/size)
+	for i := range sig {
+		p := b[i*size:]
+		switch {
+		case f.Float && f.Depth == 32:
+			sig[i] = float64(math.Float32frombits(binary.LittleEndian.Uint32(p)))
+		case f.Float && f.Depth == 64:
+			sig[i] = math.Float64frombits(binary.LittleEndian.Uint64(p))
+		case !f.Float && f.Depth == 8:
+			sig[i] = (float64(p[0]) - 128) / 128
+		case !f.Float && f.Depth == 16:
+			sig[i] = float64(int16(binary.LittleEndian.Uint16(p))) / (1 << 15)
+		case !f.Float && f.Depth == 24:
+			n := int32(uint32(p[0])<<8|uint32(p[1])<<16|uint32(p[2])<<24) >> 8
+			sig[i] = float64(n) / (1 << 23)
+		case !f.Float && f.Depth == 32:
+			sig[i] = float64(int32(binary.LittleEndian.Uint32(p))) / (1 << 31)
+		default:
+			return nil, fmt.Errorf("wav: unsupported bit depth %v", f.Depth)
+		}
+	}
+	return sig, nil
+}
@@ -0,0 +1,155 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w, err := NewWriter(f, 2, 44100, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write([]float64{0, 0.5, -0.5, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 44+8 {
+		t.Fatalf("have length %v, want %v", len(b), 44+8)
+	}
+	if n := binary.LittleEndian.Uint32(b[40:]); n != 8 {
+		t.Fatalf("data size have %v, want 8", n)
+	}
+	if n := int16(binary.LittleEndian.Uint16(b[50:])); n != 32767 {
+		t.Fatalf("clipped sample have %v, want 32767", n)
+	}
+}
+
+func TestWriterDepth(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := NewWriter(f, 1, 44100, 12); err == nil {
+		t.Fatal("expected error for unsupported depth")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	for _, depth := range []int{16, 24, 32} {
+		f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := NewWriter(f, 2, 48000, depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []float64{0, 0.5, -0.5, 0.25}
+		if err := w.Write(want); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		f.Seek(0, 0)
+
+		sig, format, err := Decode(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format.Chans != 2 || format.Rate != 48000 || format.Depth != depth {
+			t.Fatalf("have format %+v", format)
+		}
+		for i, x := range want {
+			if d := sig[i] - x; d > 1e-4 || d < -1e-4 {
+				t.Fatalf("depth %v: sig[%v] have %v, want %v", depth, i, sig[i], x)
+			}
+		}
+	}
+}
+
+func TestFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "out.wav")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, 2, 48000, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0, 0.5, -0.5, 0.25, 0.125, -0.125}
+	if err := w.Write(want); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	wf, err := Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wf.Close()
+	if wf.Len() != 3 || wf.Channels() != 2 || wf.SampleRate() != 48000 {
+		t.Fatalf("have %v frames of %+v", wf.Len(), wf.Format)
+	}
+	xs := make([]float64, 4)
+	if n, err := wf.ReadAt(xs, 1); n != 4 || err != nil {
+		t.Fatalf("have %v read, err %v", n, err)
+	}
+	if n, err := wf.ReadAt(xs, 2); n != 2 || err != io.EOF {
+		t.Fatalf("have %v read at end, err %v", n, err)
+	}
+	for i, x := range want[4:] {
+		if d := xs[i] - x; d > 1e-6 || d < -1e-6 {
+			t.Fatalf("have %v, want %v", xs[:2], want[4:])
+		}
+	}
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.wav"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, 1, 44100, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]float64{0, 0.5})
+	w.Close()
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// sizes of chunks claiming far more than the file holds.
+	for _, at := range []int{16, 40} {
+		c := append([]byte(nil), b...)
+		binary.LittleEndian.PutUint32(c[at:], 0xFFFFFFF0)
+		if _, _, err := Decode(bytes.NewReader(c)); err == nil {
+			t.Fatalf("size at %v: expected error", at)
+		}
+	}
+}